@@ -0,0 +1,418 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Appender adds rows to the end of one worksheet of an XLSX file on
+// disk, such as a daily log, without reading the rest of the file.
+// Only the worksheet is rewritten, along with the style sheet if the
+// new cells have styles it doesn't hold yet; every other part is
+// copied into the new file as it is, without even being decompressed.
+// The strings of the new cells are written inline, so the shared
+// string table is left alone.  Merged cells in the new rows are not
+// written.
+//
+// Nothing is written until Close is called, which writes the new file
+// next to the old one and renames it into place, so an append that
+// fails leaves the file as it was.
+type Appender struct {
+	path   string
+	reader *zip.ReadCloser
+	// part is the worksheet being appended to; styles and theme
+	// are the package's style sheet and theme, if it has them.
+	part   *zip.File
+	styles *zip.File
+	theme  *zip.File
+	// sheet holds the new rows, as though they were the first of
+	// a sheet of their own.
+	sheet *Sheet
+}
+
+// OpenAppender opens the XLSX file at path to add rows to the end of
+// the worksheet called sheetName.  The file stays open until Close is
+// called.
+func OpenAppender(path, sheetName string) (*Appender, error) {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	a, err := newAppender(reader, sheetName)
+	if err != nil {
+		reader.Close()
+		return nil, err
+	}
+	a.path = path
+	return a, nil
+}
+
+// newAppender finds the worksheet called sheetName in the package.
+func newAppender(reader *zip.ReadCloser, sheetName string) (*Appender, error) {
+	a := &Appender{reader: reader}
+	var workbookFile, workbookRels *zip.File
+	for _, v := range reader.File {
+		switch normalizePartName(v.Name) {
+		case "xl/workbook.xml":
+			workbookFile = v
+		case "xl/_rels/workbook.xml.rels":
+			workbookRels = v
+		case "xl/styles.xml":
+			a.styles = v
+		case "xl/theme/theme1.xml":
+			a.theme = v
+		}
+	}
+	if workbookFile == nil {
+		return nil, &XLSXReaderError{Err: "xl/workbook.xml not found in input xlsx."}
+	}
+	sheetXMLMap := make(WorkBookRels)
+	if workbookRels != nil {
+		var err error
+		sheetXMLMap, err = readWorkbookRelationsFromZipFile(workbookRels)
+		if err != nil {
+			return nil, err
+		}
+	}
+	workbook := new(xlsxWorkbook)
+	if err := decodeZipFile(workbookFile, workbook); err != nil {
+		return nil, err
+	}
+	worksheets := worksheetParts(reader.File, sheetXMLMap)
+	for _, sheet := range workbook.Sheets.Sheet {
+		if sheet.Name == sheetName {
+			a.part = worksheetFileForSheet(sheet, worksheets, sheetXMLMap)
+			break
+		}
+	}
+	if a.part == nil {
+		return nil, fmt.Errorf("no worksheet called %q to append to", sheetName)
+	}
+	file := NewFile()
+	file.Date1904 = workbook.WorkbookPr.Date1904
+	a.sheet, _ = file.AddSheet("Sheet1")
+	return a, nil
+}
+
+// AddRow adds a new row to the end of the worksheet.
+func (a *Appender) AddRow() *Row {
+	return a.sheet.AddRow()
+}
+
+// Close writes the new rows to the file, if there are any, and closes
+// it.
+func (a *Appender) Close() error {
+	if len(a.sheet.Rows) == 0 {
+		return a.reader.Close()
+	}
+	err := a.write()
+	if closeErr := a.reader.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// write writes the file with the new rows to a temporary file next to
+// it, which then replaces it.
+func (a *Appender) write() error {
+	scan, err := scanWorksheet(a.part)
+	if err != nil {
+		return err
+	}
+	if scan.prefixed {
+		return fmt.Errorf("%s: can't append to a worksheet written with a namespace prefix", a.part.Name)
+	}
+
+	styles := newXlsxStyleSheet(nil)
+	if a.styles != nil {
+		var bookTheme *theme
+		if a.theme != nil {
+			if bookTheme, err = readThemeFromZipFile(a.theme); err != nil {
+				return err
+			}
+		}
+		if styles, err = readStylesFromZipFile(a.styles, bookTheme); err != nil {
+			return err
+		}
+	}
+	counts := styles.counts()
+	refTable := NewSharedStringRefTable()
+	refTable.inline = true
+	worksheet := a.sheet.makeXLSXSheet(refTable, styles)
+	restyled := styles.counts() != counts
+	if restyled && a.styles == nil {
+		return fmt.Errorf("can't add the styles of the new cells to a file without a style sheet")
+	}
+	rows := worksheet.SheetData.Row
+	if err := shiftRows(rows, scan.lastRow, scan.maxSi+1); err != nil {
+		return err
+	}
+	dimension, err := scan.extendDimension(worksheet.Dimension.Ref)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(a.path)
+	if err != nil {
+		return err
+	}
+	target, err := ioutil.TempFile(filepath.Dir(a.path), "."+filepath.Base(a.path)+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(target.Name())
+	zipWriter := zip.NewWriter(target)
+	for _, v := range a.reader.File {
+		switch {
+		case v == a.part:
+			w, err := zipWriter.CreateHeader(&zip.FileHeader{Name: v.Name, Method: zip.Deflate, Modified: partModified})
+			if err != nil {
+				target.Close()
+				return err
+			}
+			if err := scan.write(w, v, rows, dimension); err != nil {
+				target.Close()
+				return err
+			}
+		case v == a.styles && restyled:
+			content, err := styles.Marshal()
+			if err != nil {
+				target.Close()
+				return err
+			}
+			w, err := zipWriter.CreateHeader(&zip.FileHeader{Name: v.Name, Method: zip.Deflate, Modified: partModified})
+			if err == nil {
+				_, err = io.WriteString(w, content)
+			}
+			if err != nil {
+				target.Close()
+				return err
+			}
+		default:
+			if err := zipWriter.Copy(v); err != nil {
+				target.Close()
+				return err
+			}
+		}
+	}
+	if err := zipWriter.Close(); err != nil {
+		target.Close()
+		return err
+	}
+	if err := target.Chmod(info.Mode()); err != nil {
+		target.Close()
+		return err
+	}
+	if err := target.Close(); err != nil {
+		return err
+	}
+	return os.Rename(target.Name(), a.path)
+}
+
+// shiftRows moves rows made as the first of a sheet down by offset
+// rows, numbering their shared formulas from si.
+func shiftRows(rows []xlsxRow, offset, si int) error {
+	for i := range rows {
+		row := &rows[i]
+		row.R += offset
+		for j := range row.C {
+			c := &row.C[j]
+			x, y, err := getCoordsFromCellIDString(c.R)
+			if err != nil {
+				return err
+			}
+			c.R = getCellIDStringFromCoords(x, y+offset)
+			if c.F != nil && c.F.T == "shared" {
+				c.F.Si += si
+				if c.F.Ref != "" {
+					c.F.Ref = shiftRange(c.F.Ref, offset)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// shiftRange moves a range such as "A1:A5" down by offset rows.
+func shiftRange(ref string, offset int) string {
+	minx, miny, maxx, maxy, err := getMaxMinFromDimensionRef(ref)
+	if err != nil {
+		return ref
+	}
+	return getCellIDStringFromCoords(minx, miny+offset) + ":" + getCellIDStringFromCoords(maxx, maxy+offset)
+}
+
+// worksheetScan records where the parts of a worksheet that appending
+// rows changes are, as offsets into its XML.
+type worksheetScan struct {
+	// dimensionStart and dimensionEnd bound the dimension element,
+	// if there is one, and dimension is its ref.
+	dimensionStart, dimensionEnd int64
+	dimension                    string
+	// sheetDataStart and sheetDataEnd bound the sheetData element,
+	// and rowsEnd is where its rows end: the start of its end tag,
+	// unless it is an empty element tag.
+	sheetDataStart, sheetDataEnd, rowsEnd int64
+	selfClosing                           bool
+	// prefixed is set if sheetData has a namespace prefix.
+	prefixed bool
+	// lastRow is the number of the last row, and maxSi the largest
+	// shared formula index, or -1 if there are no shared formulas.
+	lastRow int
+	maxSi   int
+}
+
+// scanWorksheet reads through the worksheet part f to find out where
+// new rows go.
+func scanWorksheet(f *zip.File) (*worksheetScan, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	scan := &worksheetScan{dimensionStart: -1, sheetDataStart: -1, maxSi: -1}
+	decoder := xml.NewDecoder(rc)
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "dimension":
+				scan.dimensionStart = offset
+				scan.dimension = rawAttr(t, "ref")
+			case "sheetData":
+				scan.sheetDataStart = offset
+				scan.prefixed = t.Name.Space != ""
+			case "row":
+				if r, err := strconv.Atoi(rawAttr(t, "r")); err == nil {
+					scan.lastRow = r
+				} else {
+					scan.lastRow++
+				}
+			case "f":
+				if si, err := strconv.Atoi(rawAttr(t, "si")); err == nil && si > scan.maxSi {
+					scan.maxSi = si
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "dimension":
+				scan.dimensionEnd = decoder.InputOffset()
+			case "sheetData":
+				scan.rowsEnd = offset
+				scan.sheetDataEnd = decoder.InputOffset()
+				scan.selfClosing = scan.sheetDataEnd == offset
+			}
+		}
+	}
+	if scan.sheetDataStart < 0 {
+		return nil, fmt.Errorf("%s: worksheet has no sheetData element", f.Name)
+	}
+	return scan, nil
+}
+
+// rawAttr returns the value of the attribute called name of a token
+// read with RawToken.
+func rawAttr(start xml.StartElement, name string) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "" && attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// extendDimension returns the ref of the worksheet's dimension once
+// the rows whose own dimension, as the first rows of a sheet, is ref
+// are added.
+func (scan *worksheetScan) extendDimension(ref string) (string, error) {
+	_, _, maxx, maxy, err := getMaxMinFromDimensionRef(ref)
+	if err != nil {
+		return "", err
+	}
+	maxy += scan.lastRow
+	minx, miny := 0, 0
+	if scan.dimension != "" && scan.lastRow > 0 {
+		var oldMaxx int
+		minx, miny, oldMaxx, _, err = getMaxMinFromDimensionRef(scan.dimension)
+		if err != nil {
+			return "", err
+		}
+		if oldMaxx > maxx {
+			maxx = oldMaxx
+		}
+	}
+	return getCellIDStringFromCoords(minx, miny) + ":" + getCellIDStringFromCoords(maxx, maxy), nil
+}
+
+// write writes the worksheet part f to w with rows added to the end
+// of its sheetData, and its dimension set to dimension.
+func (scan *worksheetScan) write(w io.Writer, f *zip.File, rows []xlsxRow, dimension string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	var at int64
+	copyTo := func(offset int64) error {
+		_, err := io.CopyN(w, rc, offset-at)
+		at = offset
+		return err
+	}
+	skipTo := func(offset int64) error {
+		_, err := io.CopyN(ioutil.Discard, rc, offset-at)
+		at = offset
+		return err
+	}
+
+	x := newXMLWriter(w)
+	if scan.dimensionStart >= 0 {
+		if err := copyTo(scan.dimensionStart); err != nil {
+			return err
+		}
+		if err := skipTo(scan.dimensionEnd); err != nil {
+			return err
+		}
+		x.raw("<dimension")
+		x.attr("ref", dimension)
+		x.raw("/>")
+		if err := x.flush(); err != nil {
+			return err
+		}
+	}
+	if scan.selfClosing {
+		if err := copyTo(scan.sheetDataStart); err != nil {
+			return err
+		}
+		if err := skipTo(scan.sheetDataEnd); err != nil {
+			return err
+		}
+		x.raw("<sheetData>")
+	} else if err := copyTo(scan.rowsEnd); err != nil {
+		return err
+	}
+	for i := range rows {
+		x.row(&rows[i])
+	}
+	if scan.selfClosing {
+		x.raw("</sheetData>")
+	}
+	if err := x.flush(); err != nil {
+		return err
+	}
+	_, err = io.Copy(w, rc)
+	return err
+}
@@ -0,0 +1,131 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+type AppendSuite struct{}
+
+var _ = Suite(&AppendSuite{})
+
+// readParts returns the content of each part of the XLSX file at path.
+func readParts(c *C, path string) map[string]string {
+	r, err := zip.OpenReader(path)
+	c.Assert(err, IsNil)
+	defer r.Close()
+	parts := make(map[string]string)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		c.Assert(err, IsNil)
+		content, err := ioutil.ReadAll(rc)
+		rc.Close()
+		c.Assert(err, IsNil)
+		parts[f.Name] = string(content)
+	}
+	return parts
+}
+
+func (s *AppendSuite) TestAppendRows(c *C) {
+	file := NewFile()
+	log, _ := file.AddSheet("Log")
+	row := log.AddRow()
+	row.AddCell().SetString("day")
+	row.AddCell().SetString("count")
+	row.AddCell().SetString("total")
+	for i := 1; i <= 3; i++ {
+		row := log.AddRow()
+		row.AddCell().SetInt(i)
+		row.AddCell().SetInt(i * 10)
+		row.AddCell().SetFormula(fmt.Sprintf("B%d+B%d", i+1, i+2))
+	}
+	other, _ := file.AddSheet("Other")
+	other.AddRow().AddCell().SetString("untouched")
+	path := filepath.Join(c.MkDir(), "log.xlsx")
+	c.Assert(file.Save(path), IsNil)
+	before := readParts(c, path)
+
+	appender, err := OpenAppender(path, "Log")
+	c.Assert(err, IsNil)
+	bold := NewStyle()
+	bold.Font.Bold = true
+	bold.ApplyFont = true
+	for i := 4; i <= 5; i++ {
+		row := appender.AddRow()
+		row.AddCell().SetInt(i)
+		row.AddCell().SetString("new")
+		row.AddCell().SetFormula(fmt.Sprintf("B%d+B%d", i+1, i+2))
+		row.AddCell().SetStyle(bold)
+	}
+	c.Assert(appender.Close(), IsNil)
+
+	after := readParts(c, path)
+	c.Assert(after, HasLen, len(before))
+	for name, content := range before {
+		if name != "xl/worksheets/sheet1.xml" && name != "xl/styles.xml" {
+			c.Assert(after[name], Equals, content, Commentf("part %s", name))
+		}
+	}
+	c.Assert(after["xl/worksheets/sheet1.xml"], Matches, `(?s).*<dimension ref="A1:D6"/>.*`)
+	c.Assert(after["xl/worksheets/sheet1.xml"], Matches, `(?s).*<c r="B5" t="inlineStr"><is><t>new</t></is></c>.*`)
+	c.Assert(after["xl/worksheets/sheet1.xml"], Matches, `(?s).*<c r="C5"><f t="shared" ref="C5:C6" si="1">B5\+B6</f></c>.*`)
+
+	written, err := OpenFile(path)
+	c.Assert(err, IsNil)
+	sheet := written.Sheet["Log"]
+	c.Assert(sheet.MaxRow, Equals, 6)
+	c.Assert(sheet.Cell(0, 1).Value, Equals, "count")
+	c.Assert(sheet.Cell(3, 2).Formula(), Equals, "B4+B5")
+	c.Assert(sheet.Cell(4, 0).Value, Equals, "4")
+	c.Assert(sheet.Cell(5, 1).Value, Equals, "new")
+	// The new column of formulas is shared apart from the old one.
+	c.Assert(sheet.Cell(4, 2).Formula(), Equals, "B5+B6")
+	c.Assert(sheet.Cell(5, 2).Formula(), Equals, "B6+B7")
+	c.Assert(sheet.Cell(5, 3).GetStyle().Font.Bold, Equals, true)
+	c.Assert(written.Sheet["Other"].Cell(0, 0).Value, Equals, "untouched")
+}
+
+func (s *AppendSuite) TestAppendToExcelFile(c *C) {
+	original, err := ioutil.ReadFile("./testdocs/testcelltypes.xlsx")
+	c.Assert(err, IsNil)
+	path := filepath.Join(c.MkDir(), "celltypes.xlsx")
+	c.Assert(ioutil.WriteFile(path, original, 0600), IsNil)
+	file, err := OpenFile(path)
+	c.Assert(err, IsNil)
+	sheet := file.Sheets[0]
+	rows := sheet.MaxRow
+
+	appender, err := OpenAppender(path, sheet.Name)
+	c.Assert(err, IsNil)
+	appender.AddRow().AddCell().SetString("appended")
+	c.Assert(appender.Close(), IsNil)
+
+	file, err = OpenFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(file.Sheets[0].MaxRow, Equals, rows+1)
+	c.Assert(file.Sheets[0].Cell(rows, 0).Value, Equals, "appended")
+	c.Assert(file.Sheets[0].Cell(0, 0).Value, Equals, sheet.Cell(0, 0).Value)
+}
+
+func (s *AppendSuite) TestAppendNothing(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Log")
+	sheet.AddRow().AddCell().SetString("day")
+	path := filepath.Join(c.MkDir(), "log.xlsx")
+	c.Assert(file.Save(path), IsNil)
+	before, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+
+	_, err = OpenAppender(path, "Missing")
+	c.Assert(err, ErrorMatches, `no worksheet called "Missing" to append to`)
+	appender, err := OpenAppender(path, "Log")
+	c.Assert(err, IsNil)
+	c.Assert(appender.Close(), IsNil)
+	after, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(after, DeepEquals, before)
+}
@@ -0,0 +1,111 @@
+package xlsx
+
+import (
+	"fmt"
+	"time"
+)
+
+// WriteBudget limits the work done writing a File, so that one
+// runaway export can't hold up a service writing files for many
+// users.  A zero value for any of the limits means no limit.
+type WriteBudget struct {
+	// MaxCells is the largest number of cells that may be
+	// written, counted over all the Sheets.
+	MaxCells int
+	// MaxDuration is the longest that writing may take.  It is
+	// checked between worksheets and between parts of the
+	// package, so a single worksheet can overrun it.
+	MaxDuration time.Duration
+	// MaxBytes is the largest total size, before compression, of
+	// the parts of the package.
+	MaxBytes int64
+}
+
+// WriteBudgetError is returned by File.Write and File.MarshallParts
+// when writing the File exceeds its WriteBudget.  The write is
+// abandoned as soon as the limit is found to have been passed.
+type WriteBudgetError struct {
+	// Limit is "cells", "duration" or "bytes".
+	Limit string
+	// Max is the limit and Used how much was used by the time
+	// the write was abandoned, in cells, nanoseconds or bytes.
+	Max  int64
+	Used int64
+}
+
+// Error returns a string value from a WriteBudgetError in order that
+// it might comply with the builtin.error interface.
+func (e *WriteBudgetError) Error() string {
+	if e.Limit == "duration" {
+		return fmt.Sprintf("write budget exceeded: took %s, more than the %s allowed",
+			time.Duration(e.Used), time.Duration(e.Max))
+	}
+	return fmt.Sprintf("write budget exceeded: %d %s, more than the %d allowed", e.Used, e.Limit, e.Max)
+}
+
+// writeBudget tracks a write of a File against its WriteBudget.
+type writeBudget struct {
+	WriteBudget
+	start time.Time
+}
+
+// newWriteBudget starts tracking a write of the File.
+func (f *File) newWriteBudget() *writeBudget {
+	return &writeBudget{WriteBudget: f.WriteBudget, start: time.Now()}
+}
+
+// checkCells checks the number of cells in the Sheets to be written.
+func (b *writeBudget) checkCells(sheets []*Sheet) error {
+	if b.MaxCells <= 0 {
+		return nil
+	}
+	cells := 0
+	for _, sheet := range sheets {
+		for _, row := range sheet.Rows {
+			if row != nil {
+				cells += len(row.Cells)
+			}
+		}
+		if cells > b.MaxCells {
+			return &WriteBudgetError{Limit: "cells", Max: int64(b.MaxCells), Used: int64(cells)}
+		}
+	}
+	return nil
+}
+
+// checkTime checks how long the write has taken so far.
+func (b *writeBudget) checkTime() error {
+	if b.MaxDuration <= 0 {
+		return nil
+	}
+	if elapsed := time.Since(b.start); elapsed > b.MaxDuration {
+		return &WriteBudgetError{Limit: "duration", Max: int64(b.MaxDuration), Used: int64(elapsed)}
+	}
+	return nil
+}
+
+// checkBytes checks the size of the parts.  It adds up every part, so
+// it is called once they have all been made rather than as each is;
+// the worksheets, which are written as they are wanted, are counted
+// by checkSize as they are written.
+func (b *writeBudget) checkBytes(parts map[string]string) error {
+	if b.MaxBytes <= 0 {
+		return nil
+	}
+	var size int64
+	for _, part := range parts {
+		size += int64(len(part))
+	}
+	return b.checkSize(size)
+}
+
+// checkSize checks size bytes written so far.
+func (b *writeBudget) checkSize(size int64) error {
+	if b.MaxBytes <= 0 {
+		return nil
+	}
+	if size > b.MaxBytes {
+		return &WriteBudgetError{Limit: "bytes", Max: b.MaxBytes, Used: size}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package xlsx
+
+import (
+	"bytes"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type BudgetSuite struct{}
+
+var _ = Suite(&BudgetSuite{})
+
+func makeBudgetFile() *File {
+	file := NewFile()
+	for _, name := range []string{"One", "Two"} {
+		sheet, _ := file.AddSheet(name)
+		for r := 0; r < 10; r++ {
+			row := sheet.AddRow()
+			for i := 0; i < 10; i++ {
+				row.AddCell().SetInt(r * i)
+			}
+		}
+	}
+	return file
+}
+
+func (b *BudgetSuite) TestMaxCells(c *C) {
+	file := makeBudgetFile()
+	file.WriteBudget.MaxCells = 150
+	var buf bytes.Buffer
+	err := file.Write(&buf)
+	c.Assert(err, ErrorMatches, "write budget exceeded: 200 cells, more than the 150 allowed")
+	budgetErr, ok := err.(*WriteBudgetError)
+	c.Assert(ok, Equals, true)
+	c.Assert(budgetErr.Limit, Equals, "cells")
+	c.Assert(buf.Len(), Equals, 0)
+
+	file.WriteBudget.MaxCells = 200
+	c.Assert(file.Write(&buf), IsNil)
+}
+
+func (b *BudgetSuite) TestMaxBytes(c *C) {
+	file := makeBudgetFile()
+	file.WriteBudget.MaxBytes = 1000
+	_, err := file.MarshallParts()
+	c.Assert(err, FitsTypeOf, &WriteBudgetError{})
+	c.Assert(err.(*WriteBudgetError).Limit, Equals, "bytes")
+	c.Assert(err.(*WriteBudgetError).Used > 1000, Equals, true)
+
+	file.WriteBudget.MaxBytes = 1 << 20
+	_, err = file.MarshallParts()
+	c.Assert(err, IsNil)
+}
+
+func (b *BudgetSuite) TestMaxDuration(c *C) {
+	file := makeBudgetFile()
+	file.WriteBudget.MaxDuration = time.Nanosecond
+	var buf bytes.Buffer
+	err := file.Write(&buf)
+	c.Assert(err, ErrorMatches, "write budget exceeded: took .*, more than the 1ns allowed")
+
+	file.WriteBudget.MaxDuration = time.Minute
+	c.Assert(file.Write(&buf), IsNil)
+}
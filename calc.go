@@ -0,0 +1,140 @@
+package xlsx
+
+// CalcMode is when spreadsheet applications calculate the formulas of
+// a File.
+type CalcMode string
+
+const (
+	// CalcAuto calculates formulas whenever what they depend on
+	// changes.  It is the default.
+	CalcAuto CalcMode = "auto"
+	// CalcAutoNoTable calculates automatically, except for data
+	// tables.
+	CalcAutoNoTable CalcMode = "autoNoTable"
+	// CalcManual calculates formulas only when asked to.
+	CalcManual CalcMode = "manual"
+)
+
+// CalcProperties controls how spreadsheet applications calculate the
+// formulas of a File, as the calcPr element of its workbook records.
+type CalcProperties struct {
+	// Mode is when formulas are calculated; "" is CalcAuto.
+	Mode CalcMode
+	// FullCalcOnLoad makes the formulas be calculated afresh when
+	// the File is opened, rather than their cached values being
+	// shown, which suits files whose formulas were set without
+	// their results.
+	FullCalcOnLoad bool
+	// ForceFullCalc makes every calculation recalculate every
+	// formula, rather than only those that depend on what changed.
+	ForceFullCalc bool
+	// R1C1 makes spreadsheet applications show and take formulas in
+	// R1C1 notation.  Formulas are stored in A1 notation either way;
+	// see Sheet.SetFormulaR1C1 and FormulaToR1C1.
+	R1C1 bool
+	// Iterate calculates formulas with circular references by
+	// iteration, stopping after IterateCount iterations or once no
+	// value changes by more than IterateDelta.  If they are 0, 100
+	// and 0.001 are written.
+	Iterate      bool
+	IterateCount int
+	IterateDelta float64
+}
+
+// The iteration settings written unless CalcProperties give others.
+const (
+	defaultIterateCount = 100
+	defaultIterateDelta = 0.001
+)
+
+// FullCalcOnLoad makes spreadsheet applications calculate the formulas
+// of a File afresh when they open it, as CalcProperties.FullCalcOnLoad
+// does.
+func FullCalcOnLoad() FileOption {
+	return func(f *File) {
+		f.Calc.FullCalcOnLoad = true
+	}
+}
+
+// R1C1ReferenceStyle makes spreadsheet applications show the formulas
+// of a File in R1C1 notation, as CalcProperties.R1C1 does.
+func R1C1ReferenceStyle() FileOption {
+	return func(f *File) {
+		f.Calc.R1C1 = true
+	}
+}
+
+// IterativeCalc makes spreadsheet applications calculate formulas
+// with circular references by iteration, stopping after count
+// iterations or once no value changes by more than delta.
+func IterativeCalc(count int, delta float64) FileOption {
+	return func(f *File) {
+		f.Calc.Iterate = true
+		f.Calc.IterateCount = count
+		f.Calc.IterateDelta = delta
+	}
+}
+
+// defaultCalcPr returns the calculation settings written unless the
+// File's CalcProperties say otherwise.
+func defaultCalcPr() xlsxCalcPr {
+	return xlsxCalcPr{
+		IterateCount: defaultIterateCount,
+		RefMode:      "A1",
+		Iterate:      false,
+		IterateDelta: defaultIterateDelta,
+	}
+}
+
+// makeCalcPr returns the calcPr element written for the File.
+func (f *File) makeCalcPr() xlsxCalcPr {
+	calcPr := defaultCalcPr()
+	calc := f.Calc
+	if calc.Mode != CalcAuto {
+		calcPr.CalcMode = string(calc.Mode)
+	}
+	calcPr.FullCalcOnLoad = calc.FullCalcOnLoad
+	calcPr.ForceFullCalc = calc.ForceFullCalc
+	if calc.R1C1 {
+		calcPr.RefMode = "R1C1"
+	}
+	calcPr.Iterate = calc.Iterate
+	if calc.IterateCount != 0 {
+		calcPr.IterateCount = calc.IterateCount
+	}
+	if calc.IterateDelta != 0 {
+		calcPr.IterateDelta = calc.IterateDelta
+	}
+	return calcPr
+}
+
+// readCalcProperties returns the CalcProperties a workbook's calcPr
+// element records.
+func readCalcProperties(calcPr xlsxCalcPr) CalcProperties {
+	return CalcProperties{
+		Mode:           CalcMode(calcPr.CalcMode),
+		FullCalcOnLoad: calcPr.FullCalcOnLoad,
+		ForceFullCalc:  calcPr.ForceFullCalc,
+		R1C1:           calcPr.RefMode == "R1C1",
+		Iterate:        calcPr.Iterate,
+		IterateCount:   calcPr.IterateCount,
+		IterateDelta:   calcPr.IterateDelta,
+	}
+}
+
+// mergeCalcProperties returns the CalcProperties read from a file, with
+// each setting made by an option in place of the one read.
+func mergeCalcProperties(read, set CalcProperties) CalcProperties {
+	if set.Mode != "" {
+		read.Mode = set.Mode
+	}
+	read.FullCalcOnLoad = read.FullCalcOnLoad || set.FullCalcOnLoad
+	read.ForceFullCalc = read.ForceFullCalc || set.ForceFullCalc
+	read.R1C1 = read.R1C1 || set.R1C1
+	if set.Iterate {
+		read.Iterate = true
+		read.IterateCount = set.IterateCount
+		read.IterateDelta = set.IterateDelta
+	}
+	return read
+}
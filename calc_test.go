@@ -0,0 +1,50 @@
+package xlsx
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type CalcSuite struct{}
+
+var _ = Suite(&CalcSuite{})
+
+func (s *CalcSuite) TestMakeCalcPr(c *C) {
+	f := NewFile()
+	c.Assert(f.makeCalcPr(), Equals, defaultCalcPr())
+
+	f.Calc = CalcProperties{Mode: CalcManual, FullCalcOnLoad: true, ForceFullCalc: true}
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/workbook.xml"], Matches,
+		`(?s).*<calcPr calcMode="manual" fullCalcOnLoad="true" iterateCount="100" refMode="A1" iterateDelta="0.001" forceFullCalc="true"></calcPr>.*`)
+
+	f = NewFileWithOptions(FullCalcOnLoad(), IterativeCalc(5, 0.01))
+	calcPr := f.makeCalcPr()
+	c.Assert(calcPr.FullCalcOnLoad, Equals, true)
+	c.Assert(calcPr.CalcMode, Equals, "")
+	c.Assert(calcPr.Iterate, Equals, true)
+	c.Assert(calcPr.IterateCount, Equals, 5)
+	c.Assert(calcPr.IterateDelta, Equals, 0.01)
+}
+
+func (s *CalcSuite) TestReadCalcProperties(c *C) {
+	parts := makeSheetParts(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData/></worksheet>`)
+	parts["xl/workbook.xml"] = strings.Replace(parts["xl/workbook.xml"], "</sheets>",
+		`</sheets><calcPr calcId="125725" calcMode="manual" refMode="R1C1" iterate="1" iterateCount="20"/>`, 1)
+	f, err := readZipReader(makeZipReader(c, parts), nil)
+	c.Assert(err, IsNil)
+	c.Assert(f.Calc, Equals, CalcProperties{Mode: CalcManual, R1C1: true, Iterate: true, IterateCount: 20})
+
+	// Settings made by options win over those of the file, which
+	// keeps the rest.
+	f, err = readZipReader(makeZipReader(c, parts), []FileOption{FullCalcOnLoad()})
+	c.Assert(err, IsNil)
+	c.Assert(f.Calc, Equals, CalcProperties{Mode: CalcManual, R1C1: true, Iterate: true, IterateCount: 20, FullCalcOnLoad: true})
+	c.Assert(f.makeCalcPr().FullCalcOnLoad, Equals, true)
+
+	f, err = readZipReader(makeZipReader(c, parts), []FileOption{IterativeCalc(5, 0.01)})
+	c.Assert(err, IsNil)
+	c.Assert(f.Calc, Equals, CalcProperties{Mode: CalcManual, R1C1: true, Iterate: true, IterateCount: 5, IterateDelta: 0.01})
+}
@@ -36,6 +36,16 @@ type Cell struct {
 	HMerge   int
 	VMerge   int
 	cellType CellType
+	// resultType is the t attribute of a formula cell's cached
+	// result, or "" if it is to be worked out from the Value.
+	resultType string
+	// meta holds the attributes of the cell read that are written
+	// back as they were.
+	meta cellMetadata
+	// phonetic is the phonetic text of the cell, which belongs to
+	// its value when the value was phoneticOf.
+	phonetic   *Phonetic
+	phoneticOf string
 }
 
 // CellInterface defines the public API of the Cell.
@@ -103,23 +113,80 @@ func (c *Cell) SetFloatWithFormat(n float64, format string) {
 	c.cellType = CellTypeNumeric
 }
 
+// ExcelPrecision is the number of significant digits Excel keeps of a
+// number; see SetFloatWithPrecision and FloatPrecision.
+const ExcelPrecision = 15
+
+// SetFloatWithPrecision sets the value of a cell to a float rounded to
+// digits significant digits, as Excel rounds what is typed into a cell
+// to ExcelPrecision, so that 0.1+0.2 is written as 0.3 rather than
+// 0.30000000000000004.  SetFloat writes the shortest number that reads
+// back as exactly n instead.
+func (c *Cell) SetFloatWithPrecision(n float64, digits int) {
+	c.setGeneral(formatPrecision(n, digits))
+}
+
+// formatPrecision formats n with at most digits significant digits, in
+// the shortest form that reads back the same if digits isn't positive.
+func formatPrecision(n float64, digits int) string {
+	if digits <= 0 {
+		digits = -1
+	}
+	return strconv.FormatFloat(n, 'g', digits, 64)
+}
+
+// roundedValue returns the value of a numeric cell rounded to digits
+// significant digits, or the value as it is if digits isn't positive
+// or it isn't a number.
+func (c *Cell) roundedValue(digits int) string {
+	if digits <= 0 {
+		return c.Value
+	}
+	f, err := strconv.ParseFloat(c.Value, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return c.Value
+	}
+	return formatPrecision(f, digits)
+}
+
 var timeLocationUTC, _ = time.LoadLocation("UTC")
 
 func timeToUTCTime(t time.Time) time.Time {
 	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), timeLocationUTC)
 }
 
-func timeToExcelTime(t time.Time) float64 {
-	return float64(t.Unix())/86400.0 + 25569.0
+// file returns the File the cell belongs to, or nil.
+func (c *Cell) file() *File {
+	if c.Row != nil && c.Row.Sheet != nil {
+		return c.Row.Sheet.File
+	}
+	return nil
+}
+
+// datePolicy returns the DatePolicy of the File the cell belongs to.
+func (c *Cell) datePolicy() DatePolicy {
+	if f := c.file(); f != nil {
+		return f.DatePolicy
+	}
+	return DatePolicyCorrect
+}
+
+// inDate1904 reports whether the cell's serial numbers use the 1904
+// date system.
+func (c *Cell) inDate1904() bool {
+	f := c.file()
+	return c.date1904 || (f != nil && f.Date1904)
 }
 
 // SetDate sets the value of a cell to a float.
 func (c *Cell) SetDate(t time.Time) {
-	c.SetDateTimeWithFormat(float64(int64(timeToExcelTime(timeToUTCTime(t)))), builtInNumFmt[14])
+	serial := ExcelSerialFromTime(timeToUTCTime(t), c.inDate1904(), c.datePolicy())
+	c.SetDateTimeWithFormat(float64(int64(serial)), c.file().dateFormat(false))
 }
 
 func (c *Cell) SetDateTime(t time.Time) {
-	c.SetDateTimeWithFormat(timeToExcelTime(timeToUTCTime(t)), builtInNumFmt[22])
+	serial := ExcelSerialFromTime(timeToUTCTime(t), c.inDate1904(), c.datePolicy())
+	c.SetDateTimeWithFormat(serial, c.file().dateFormat(true))
 }
 
 func (c *Cell) SetDateTimeWithFormat(n float64, format string) {
@@ -163,8 +230,14 @@ func (c *Cell) SetValue(n interface{}) {
 	case time.Time:
 		c.SetDateTime(n.(time.Time))
 		return
-	case int, int8, int16, int32, int64, float32, float64:
+	case float32:
+		c.setGeneral(strconv.FormatFloat(float64(t), 'g', -1, 32))
+	case float64:
+		c.setGeneral(strconv.FormatFloat(t, 'g', -1, 64))
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
 		c.setGeneral(fmt.Sprintf("%v", n))
+	case bool:
+		c.SetBool(t)
 	case string:
 		c.SetString(t)
 	case []byte:
@@ -176,6 +249,71 @@ func (c *Cell) SetValue(n interface{}) {
 	}
 }
 
+// CellValues are the three faces of a cell's value side by side, so
+// that which one is in hand is never in doubt.
+type CellValues struct {
+	// Raw is the value as it is stored, the same as Cell.Value: the
+	// serial number of a date, "1" for TRUE, and the cached result of
+	// a formula.
+	Raw string
+	// Display is the value as Excel displays it: formatted as
+	// Cell.FormattedValue formats it, or the Raw value if the
+	// cell's number format can't be applied, and TRUE or FALSE for
+	// a boolean.
+	Display string
+	// Typed is the value as Sheet.Value gives it: a string, float64,
+	// bool or time.Time, or nil if the cell is empty.
+	Typed interface{}
+}
+
+// Values returns the raw, displayed and typed values of the Cell at
+// once.
+func (c *Cell) Values() CellValues {
+	v := CellValues{Raw: c.Value, Display: cellText(c), Typed: c.typedValue()}
+	if b, ok := v.Typed.(bool); ok {
+		// Excel shows booleans in words, whatever the format.
+		v.Display = "FALSE"
+		if b {
+			v.Display = "TRUE"
+		}
+	}
+	return v
+}
+
+// typedValue returns the value of the cell as the Go type SetValue
+// takes for its type: a string, float64, bool or time.Time, or nil if
+// it is empty.  The value of a formula is its cached result.
+func (c *Cell) typedValue() interface{} {
+	if c.Value == "" {
+		return nil
+	}
+	switch c.cellType {
+	case CellTypeBool:
+		return c.Bool()
+	case CellTypeString, CellTypeError:
+		return c.Value
+	case CellTypeFormula:
+		switch c.formulaResultT() {
+		case "b":
+			return c.Value == "1"
+		case "str", "e":
+			return c.Value
+		}
+	}
+	f, err := strconv.ParseFloat(c.Value, 64)
+	if err != nil {
+		return c.Value
+	}
+	if c.cellType == CellTypeDate || c.cellType != CellTypeFormula && isTimeFormat(c.NumFmt) {
+		if t, err := TimeFromExcelSerial(f, c.inDate1904(), c.datePolicy()); err == nil {
+			// Serials can't hold times any more precisely
+			// than Excel shows them.
+			return t.Round(time.Millisecond)
+		}
+	}
+	return f
+}
+
 // SetInt sets a cell's value to an integer.
 func (c *Cell) setGeneral(s string) {
 	c.Value = s
@@ -210,7 +348,7 @@ func (c *Cell) SetBool(b bool) {
 // appropriate for types other than CellTypeBool.
 func (c *Cell) Bool() bool {
 	// If bool, just return the value.
-	if c.cellType == CellTypeBool {
+	if c.cellType == CellTypeBool || (c.cellType == CellTypeFormula && c.resultType == "b") {
 		return c.Value == "1"
 	}
 	// If numeric, base it on a non-zero.
@@ -224,9 +362,102 @@ func (c *Cell) Bool() bool {
 // SetFormula sets the format string for a cell.
 func (c *Cell) SetFormula(formula string) {
 	c.formula = formula
+	c.resultType = ""
 	c.cellType = CellTypeFormula
 }
 
+// SetFormulaWithValue sets the formula of a cell along with its cached
+// result, as SetFormula and SetFormulaResult do, so that applications
+// that don't calculate formulas show the result.
+func (c *Cell) SetFormulaWithValue(formula string, value interface{}) {
+	c.SetFormula(formula)
+	c.SetFormulaResult(value)
+}
+
+// SetFormulaResult sets the cached result of a formula cell, which is
+// shown until the formula is recalculated.  value may be a string, a
+// bool, a number or a time.Time, which is stored as a date serial and
+// given the File's date and time format if the cell has no format of
+// its own; errors are set with SetFormulaError.  The result
+// is written with its type, so that a text result such as "123" isn't
+// taken for a number.  SetFormula clears the result type, so call it
+// first.
+func (c *Cell) SetFormulaResult(value interface{}) {
+	c.cellType = CellTypeFormula
+	switch v := value.(type) {
+	case bool:
+		c.Value = "0"
+		if v {
+			c.Value = "1"
+		}
+		c.resultType = "b"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		c.Value = fmt.Sprintf("%d", v)
+		c.resultType = "n"
+	case float32:
+		c.Value = strconv.FormatFloat(float64(v), 'f', -1, 32)
+		c.resultType = "n"
+	case float64:
+		c.Value = strconv.FormatFloat(v, 'f', -1, 64)
+		c.resultType = "n"
+	case time.Time:
+		serial := ExcelSerialFromTime(timeToUTCTime(v), c.inDate1904(), c.datePolicy())
+		c.Value = strconv.FormatFloat(serial, 'f', -1, 64)
+		c.resultType = "n"
+		if c.NumFmt == "" || c.NumFmt == "general" || c.NumFmt == "General" {
+			c.NumFmt = c.file().dateFormat(true)
+		}
+	case string:
+		c.Value = v
+		c.resultType = "str"
+	default:
+		c.Value = fmt.Sprint(v)
+		c.resultType = "str"
+	}
+}
+
+// SetFormulaError sets the cached result of a formula cell to an
+// error, such as "#DIV/0!".  The cell's type becomes CellTypeError.
+func (c *Cell) SetFormulaError(code string) {
+	c.Value = code
+	c.resultType = "e"
+	c.cellType = CellTypeError
+}
+
+// FormulaResultType returns the type of the cached result of a cell
+// with a formula: CellTypeNumeric, CellTypeString, CellTypeBool or
+// CellTypeError.
+func (c *Cell) FormulaResultType() CellType {
+	switch c.formulaResultT() {
+	case "str":
+		return CellTypeString
+	case "b":
+		return CellTypeBool
+	case "e":
+		return CellTypeError
+	}
+	return CellTypeNumeric
+}
+
+// formulaResultT returns the t attribute to write for the cached
+// result of a formula cell.  Results set without a type are strings
+// unless they are numbers.
+func (c *Cell) formulaResultT() string {
+	if c.cellType == CellTypeError {
+		return "e"
+	}
+	switch c.resultType {
+	case "n":
+		return ""
+	case "":
+		if _, err := strconv.ParseFloat(c.Value, 64); err != nil && c.Value != "" {
+			return "str"
+		}
+		return ""
+	}
+	return c.resultType
+}
+
 // Formula returns the formula string for the cell.
 func (c *Cell) Formula() string {
 	return c.formula
@@ -235,7 +466,7 @@ func (c *Cell) Formula() string {
 // GetStyle returns the Style associated with a Cell
 func (c *Cell) GetStyle() *Style {
 	if c.style == nil {
-		c.style = NewStyle()
+		c.style = c.file().newStyle()
 	}
 	return c.style
 }
@@ -250,132 +481,26 @@ func (c *Cell) GetNumberFormat() string {
 	return c.NumFmt
 }
 
-func (c *Cell) formatToFloat(format string) (string, error) {
-	f, err := strconv.ParseFloat(c.Value, 64)
-	if err != nil {
-		return c.Value, err
-	}
-	return fmt.Sprintf(format, f), nil
-}
-
-func (c *Cell) formatToInt(format string) (string, error) {
-	f, err := strconv.ParseFloat(c.Value, 64)
-	if err != nil {
-		return c.Value, err
-	}
-	return fmt.Sprintf(format, int(f)), nil
-}
-
 // FormattedValue returns a value, and possibly an error condition
 // from a Cell.  If it is possible to apply a format to the cell
 // value, it will do so, if not then an error will be returned, along
-// with the raw value of the Cell.
+// with the raw value of the Cell.  The value is displayed for the
+// locale given to the File by FormatLocale, if any.
 func (c *Cell) FormattedValue() (string, error) {
-	var numberFormat = c.GetNumberFormat()
-	if isTimeFormat(numberFormat) {
-		return parseTime(c)
-	}
-	switch numberFormat {
-	case builtInNumFmt[builtInNumFmtIndex_GENERAL], builtInNumFmt[builtInNumFmtIndex_STRING]:
-		return c.Value, nil
-	case builtInNumFmt[builtInNumFmtIndex_INT], "#,##0":
-		return c.formatToInt("%d")
-	case builtInNumFmt[builtInNumFmtIndex_FLOAT], "#,##0.00":
-		return c.formatToFloat("%.2f")
-	case "#,##0 ;(#,##0)", "#,##0 ;[red](#,##0)":
-		f, err := strconv.ParseFloat(c.Value, 64)
-		if err != nil {
-			return c.Value, err
-		}
-		if f < 0 {
-			i := int(math.Abs(f))
-			return fmt.Sprintf("(%d)", i), nil
-		}
-		i := int(f)
-		return fmt.Sprintf("%d", i), nil
-	case "#,##0.00;(#,##0.00)", "#,##0.00;[red](#,##0.00)":
-		f, err := strconv.ParseFloat(c.Value, 64)
-		if err != nil {
-			return c.Value, err
-		}
-		if f < 0 {
-			return fmt.Sprintf("(%.2f)", f), nil
-		}
-		return fmt.Sprintf("%.2f", f), nil
-	case "0%":
-		f, err := strconv.ParseFloat(c.Value, 64)
-		if err != nil {
-			return c.Value, err
-		}
-		f = f * 100
-		return fmt.Sprintf("%d%%", int(f)), nil
-	case "0.00%":
-		f, err := strconv.ParseFloat(c.Value, 64)
-		if err != nil {
-			return c.Value, err
-		}
-		f = f * 100
-		return fmt.Sprintf("%.2f%%", f), nil
-	case "0.00e+00", "##0.0e+0":
-		return c.formatToFloat("%e")
-	}
-	return c.Value, nil
-
-}
-
-// parseTime returns a string parsed using time.Time
-func parseTime(c *Cell) (string, error) {
-	f, err := strconv.ParseFloat(c.Value, 64)
-	if err != nil {
-		return c.Value, err
-	}
-	val := TimeFromExcelTime(f, c.date1904)
-	format := c.GetNumberFormat()
-
-	// Replace Excel placeholders with Go time placeholders.
-	// For example, replace yyyy with 2006. These are in a specific order,
-	// due to the fact that m is used in month, minute, and am/pm. It would
-	// be easier to fix that with regular expressions, but if it's possible
-	// to keep this simple it would be easier to maintain.
-	// Full-length month and days (e.g. March, Tuesday) have letters in them that would be replaced
-	// by other characters below (such as the 'h' in March, or the 'd' in Tuesday) below.
-	// First we convert them to arbitrary characters unused in Excel Date formats, and then at the end,
-	// turn them to what they should actually be.
-	// Based off: http://www.ozgrid.com/Excel/CustomFormats.htm
-	replacements := []struct{ xltime, gotime string }{
-		{"yyyy", "2006"},
-		{"yy", "06"},
-		{"mmmm", "%%%%"},
-		{"dddd", "&&&&"},
-		{"dd", "02"},
-		{"d", "2"},
-		{"mmm", "Jan"},
-		{"mmss", "0405"},
-		{"ss", "05"},
-		{"hh", "15"},
-		{"h", "3"},
-		{"mm:", "04:"},
-		{":mm", ":04"},
-		{"mm", "01"},
-		{"am/pm", "pm"},
-		{"m/", "1/"},
-		{"%%%%", "January"},
-		{"&&&&", "Monday"},
-	}
-	for _, repl := range replacements {
-		format = strings.Replace(format, repl.xltime, repl.gotime, 1)
-	}
-	// If the hour is optional, strip it out, along with the
-	// possible dangling colon that would remain.
-	if val.Hour() < 1 {
-		format = strings.Replace(format, "]:", "]", 1)
-		format = strings.Replace(format, "[3]", "", 1)
-		format = strings.Replace(format, "[15]", "", 1)
-	} else {
-		format = strings.Replace(format, "[3]", "3", 1)
-		format = strings.Replace(format, "[15]", "15", 1)
-	}
-	return val.Format(format), nil
+	return c.FormattedValueWithOptions(c.file().formatOptions())
+}
+
+// FormattedValueWithOptions returns the value of the Cell formatted as
+// FormattedValue formats it, but with the given FormatOptions.
+//
+// The whole of Excel's number format codes is understood: the sections
+// for positive and negative numbers, zero and text, conditions such as
+// [>=100], digit placeholders, thousands separators and scaling,
+// percentages, scientific notation, fractions, dates and times, and
+// elapsed times such as [h]:mm.  Colors are recognised but, being no
+// part of the text, leave no mark on it.
+func (c *Cell) FormattedValueWithOptions(options FormatOptions) (string, error) {
+	return c.formatValue(options.Locale)
 }
 
 // isTimeFormat checks whether an Excel format string represents
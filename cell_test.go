@@ -1,6 +1,7 @@
 package xlsx
 
 import (
+	"bytes"
 	"math"
 	"time"
 
@@ -168,40 +169,33 @@ func (l *CellSuite) TestFormattedValue(c *C) {
 	negativeCell.NumFmt = "general"
 	fvc.Equals(negativeCell, "-37947.7500001")
 
-	// TODO: This test is currently broken.  For a string type cell, I
-	// don't think FormattedValue() should be doing a numeric conversion on the value
-	// before returning the string.
 	cell.NumFmt = "0"
-	fvc.Equals(cell, "37947")
+	fvc.Equals(cell, "37948")
 
-	cell.NumFmt = "#,##0" // For the time being we're not doing
-	// this comma formatting, so it'll fall back to the related
-	// non-comma form.
-	fvc.Equals(cell, "37947")
+	cell.NumFmt = "#,##0"
+	fvc.Equals(cell, "37,948")
 
 	cell.NumFmt = "#,##0.00;(#,##0.00)"
-	fvc.Equals(cell, "37947.75")
+	fvc.Equals(cell, "37,947.75")
 
 	cell.NumFmt = "0.00"
 	fvc.Equals(cell, "37947.75")
 
-	cell.NumFmt = "#,##0.00" // For the time being we're not doing
-	// this comma formatting, so it'll fall back to the related
-	// non-comma form.
-	fvc.Equals(cell, "37947.75")
+	cell.NumFmt = "#,##0.00"
+	fvc.Equals(cell, "37,947.75")
 
 	cell.NumFmt = "#,##0 ;(#,##0)"
-	fvc.Equals(cell, "37947")
+	fvc.Equals(cell, "37,948 ")
 	negativeCell.NumFmt = "#,##0 ;(#,##0)"
-	fvc.Equals(negativeCell, "(37947)")
+	fvc.Equals(negativeCell, "(37,948)")
 
 	cell.NumFmt = "#,##0 ;[red](#,##0)"
-	fvc.Equals(cell, "37947")
+	fvc.Equals(cell, "37,948 ")
 	negativeCell.NumFmt = "#,##0 ;[red](#,##0)"
-	fvc.Equals(negativeCell, "(37947)")
+	fvc.Equals(negativeCell, "(37,948)")
 
 	negativeCell.NumFmt = "#,##0.00;(#,##0.00)"
-	fvc.Equals(negativeCell, "(-37947.75)")
+	fvc.Equals(negativeCell, "(37,947.75)")
 
 	cell.NumFmt = "0%"
 	fvc.Equals(cell, "3794775%")
@@ -210,10 +204,10 @@ func (l *CellSuite) TestFormattedValue(c *C) {
 	fvc.Equals(cell, "3794775.00%")
 
 	cell.NumFmt = "0.00e+00"
-	fvc.Equals(cell, "3.794775e+04")
+	fvc.Equals(cell, "3.79e+04")
 
-	cell.NumFmt = "##0.0e+0" // This is wrong, but we'll use it for now.
-	fvc.Equals(cell, "3.794775e+04")
+	cell.NumFmt = "##0.0e+0"
+	fvc.Equals(cell, "37.9e+3")
 
 	cell.NumFmt = "mm-dd-yy"
 	fvc.Equals(cell, "11-22-03")
@@ -232,40 +226,40 @@ func (l *CellSuite) TestFormattedValue(c *C) {
 	fvc.Equals(cell, "Nov-03")
 
 	cell.NumFmt = "h:mm am/pm"
-	fvc.Equals(cell, "6:00 pm")
+	fvc.Equals(cell, "6:00 PM")
 	smallCell.NumFmt = "h:mm am/pm"
-	fvc.Equals(smallCell, "12:10 am")
+	fvc.Equals(smallCell, "12:10 AM")
 
 	cell.NumFmt = "h:mm:ss am/pm"
-	fvc.Equals(cell, "6:00:00 pm")
+	fvc.Equals(cell, "6:00:00 PM")
 	cell.NumFmt = "hh:mm:ss"
 	fvc.Equals(cell, "18:00:00")
 	smallCell.NumFmt = "h:mm:ss am/pm"
-	fvc.Equals(smallCell, "12:10:04 am")
+	fvc.Equals(smallCell, "12:10:05 AM")
 
 	cell.NumFmt = "h:mm"
-	fvc.Equals(cell, "6:00")
+	fvc.Equals(cell, "18:00")
 	smallCell.NumFmt = "h:mm"
-	fvc.Equals(smallCell, "12:10")
+	fvc.Equals(smallCell, "0:10")
 	smallCell.NumFmt = "hh:mm"
 	fvc.Equals(smallCell, "00:10")
 
 	cell.NumFmt = "h:mm:ss"
-	fvc.Equals(cell, "6:00:00")
+	fvc.Equals(cell, "18:00:00")
 	cell.NumFmt = "hh:mm:ss"
 	fvc.Equals(cell, "18:00:00")
 
 	smallCell.NumFmt = "hh:mm:ss"
-	fvc.Equals(smallCell, "00:10:04")
+	fvc.Equals(smallCell, "00:10:05")
 	smallCell.NumFmt = "h:mm:ss"
-	fvc.Equals(smallCell, "12:10:04")
+	fvc.Equals(smallCell, "0:10:05")
 
 	cell.NumFmt = "m/d/yy h:mm"
-	fvc.Equals(cell, "11/22/03 6:00")
+	fvc.Equals(cell, "11/22/03 18:00")
 	cell.NumFmt = "m/d/yy hh:mm"
 	fvc.Equals(cell, "11/22/03 18:00")
 	smallCell.NumFmt = "m/d/yy h:mm"
-	fvc.Equals(smallCell, "12/30/99 12:10")
+	fvc.Equals(smallCell, "12/30/99 0:10")
 	smallCell.NumFmt = "m/d/yy hh:mm"
 	fvc.Equals(smallCell, "12/30/99 00:10")
 	earlyCell.NumFmt = "m/d/yy hh:mm"
@@ -276,32 +270,31 @@ func (l *CellSuite) TestFormattedValue(c *C) {
 	cell.NumFmt = "mm:ss"
 	fvc.Equals(cell, "00:00")
 	smallCell.NumFmt = "mm:ss"
-	fvc.Equals(smallCell, "10:04")
+	fvc.Equals(smallCell, "10:05")
 
 	cell.NumFmt = "[hh]:mm:ss"
-	fvc.Equals(cell, "18:00:00")
+	fvc.Equals(cell, "910746:00:00")
 	cell.NumFmt = "[h]:mm:ss"
-	fvc.Equals(cell, "6:00:00")
+	fvc.Equals(cell, "910746:00:00")
 	smallCell.NumFmt = "[h]:mm:ss"
-	fvc.Equals(smallCell, "10:04")
-
-	const (
-		expect1 = "0000.0086"
-		expect2 = "1004.8000"
-		format  = "mmss.0000"
-		tlen    = len(format)
-	)
-
-	for i := 0; i < 3; i++ {
-		tfmt := format[0 : tlen-i]
-		cell.NumFmt = tfmt
-		fvc.Equals(cell, expect1[0:tlen-i])
-		smallCell.NumFmt = tfmt
-		fvc.Equals(smallCell, expect2[0:tlen-i])
+	fvc.Equals(smallCell, "0:10:05")
+
+	// Times are rounded to the fraction of a second displayed, of
+	// which there are at most three digits.
+	for format, expected := range map[string][2]string{
+		"mmss.0000": {"0000.0090", "1004.8000"},
+		"mmss.000":  {"0000.009", "1004.800"},
+		"mmss.00":   {"0000.01", "1004.80"},
+		"mmss.0":    {"0000.0", "1004.8"},
+	} {
+		cell.NumFmt = format
+		fvc.Equals(cell, expected[0])
+		smallCell.NumFmt = format
+		fvc.Equals(smallCell, expected[1])
 	}
 
 	cell.NumFmt = "yyyy\\-mm\\-dd"
-	fvc.Equals(cell, "2003\\-11\\-22")
+	fvc.Equals(cell, "2003-11-22")
 
 	cell.NumFmt = "dd/mm/yyyy hh:mm:ss"
 	fvc.Equals(cell, "22/11/2003 18:00:00")
@@ -314,10 +307,10 @@ func (l *CellSuite) TestFormattedValue(c *C) {
 	cell.NumFmt = "hh:mm:ss"
 	fvc.Equals(cell, "18:00:00")
 	smallCell.NumFmt = "hh:mm:ss"
-	fvc.Equals(smallCell, "00:10:04")
+	fvc.Equals(smallCell, "00:10:05")
 
 	cell.NumFmt = "dd/mm/yy\\ hh:mm"
-	fvc.Equals(cell, "22/11/03\\ 18:00")
+	fvc.Equals(cell, "22/11/03 18:00")
 
 	cell.NumFmt = "yyyy/mm/dd"
 	fvc.Equals(cell, "2003/11/22")
@@ -347,19 +340,19 @@ func (l *CellSuite) TestFormattedValue(c *C) {
 	fvc.Equals(cell, "22/11/2003")
 
 	cell.NumFmt = "mm/dd/yy hh:mm am/pm"
-	fvc.Equals(cell, "11/22/03 18:00 pm")
+	fvc.Equals(cell, "11/22/03 06:00 PM")
 	cell.NumFmt = "mm/dd/yy h:mm am/pm"
-	fvc.Equals(cell, "11/22/03 6:00 pm")
+	fvc.Equals(cell, "11/22/03 6:00 PM")
 
 	cell.NumFmt = "mm/dd/yyyy hh:mm:ss"
 	fvc.Equals(cell, "11/22/2003 18:00:00")
 	smallCell.NumFmt = "mm/dd/yyyy hh:mm:ss"
-	fvc.Equals(smallCell, "12/30/1899 00:10:04")
+	fvc.Equals(smallCell, "12/30/1899 00:10:05")
 
 	cell.NumFmt = "yyyy-mm-dd hh:mm:ss"
 	fvc.Equals(cell, "2003-11-22 18:00:00")
 	smallCell.NumFmt = "yyyy-mm-dd hh:mm:ss"
-	fvc.Equals(smallCell, "1899-12-30 00:10:04")
+	fvc.Equals(smallCell, "1899-12-30 00:10:05")
 
 	cell.NumFmt = "mmmm d, yyyy"
 	fvc.Equals(cell, "November 22, 2003")
@@ -481,7 +474,120 @@ func (s *CellSuite) TestSetValue(c *C) {
 		c.Assert(cell.Value, Equals, "")
 	}
 
+	// bool and unsigned
+	cell.SetValue(true)
+	c.Assert(cell.Type(), Equals, CellTypeBool)
+	c.Assert(cell.Bool(), Equals, true)
+	cell.SetValue(uint16(3))
+	c.Assert(cell.Value, Equals, "3")
+
 	// others
 	cell.SetValue([]string{"test"})
 	c.Assert(cell.Value, Equals, "[test]")
 }
+
+func (s *CellSuite) TestFloatPrecision(c *C) {
+	// Variables, as constants would be added exactly.
+	a, b := 0.1, 0.2
+	cell := Cell{}
+	cell.SetFloat(a + b)
+	c.Assert(cell.Value, Equals, "0.30000000000000004")
+	cell.SetFloatWithPrecision(a+b, ExcelPrecision)
+	c.Assert(cell.Value, Equals, "0.3")
+	cell.SetFloatWithPrecision(2.0/3, 4)
+	c.Assert(cell.Value, Equals, "0.6667")
+
+	file := NewFileWithOptions(FloatPrecision(ExcelPrecision))
+	sheet, _ := file.AddSheet("Rounded")
+	sheet.Cell(0, 0).SetFloat(a + b)
+	sheet.Cell(0, 1).SetFloatWithFormat(a*b*3, "0.00")
+	sheet.Cell(0, 2).SetString("0.30000000000000004")
+	sheet.Cell(0, 3).SetDateTimeWithFormat(45000.123456789012345, "yyyy-mm-dd")
+	sheet.Cell(0, 4).SetInt(12)
+	xSheet := sheet.makeXLSXSheet(NewSharedStringRefTable(), newXlsxStyleSheet(nil))
+	cells := xSheet.SheetData.Row[0].C
+	c.Assert(cells[0].V, Equals, "0.3")
+	c.Assert(cells[1].V, Equals, "0.06")
+	c.Assert(cells[3].V, Equals, sheet.Cell(0, 3).Value)
+	c.Assert(cells[4].V, Equals, "12")
+	// The cells keep their full values.
+	c.Assert(sheet.Cell(0, 0).Value, Equals, "0.30000000000000004")
+}
+
+func (s *CellSuite) TestFormulaResults(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	row := sheet.AddRow()
+	results := []interface{}{"123", true, false, 4.5, "text"}
+	for _, result := range results {
+		cell := row.AddCell()
+		cell.SetFormula("X1")
+		cell.SetFormulaResult(result)
+	}
+	cell := row.AddCell()
+	cell.SetFormula("1/0")
+	cell.SetFormulaError("#DIV/0!")
+	c.Assert(cell.Type(), Equals, CellTypeError)
+
+	xSheet := sheet.makeXLSXSheet(NewSharedStringRefTable(), newXlsxStyleSheet(nil))
+	var types, values []string
+	for _, xC := range xSheet.SheetData.Row[0].C {
+		types = append(types, xC.T)
+		values = append(values, xC.V)
+	}
+	c.Assert(types, DeepEquals, []string{"str", "b", "b", "", "str", "e"})
+	c.Assert(values, DeepEquals, []string{"123", "1", "0", "4.5", "text", "#DIV/0!"})
+
+	// The types survive a round trip.
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	var buf bytes.Buffer
+	c.Assert(file.Write(&buf), IsNil)
+	c.Assert(parts["xl/worksheets/sheet1.xml"], Matches, `(?s).*<c r="A1" s="1" t="str"><f>X1</f><v>123</v></c>.*`)
+	read, err := OpenBinary(buf.Bytes())
+	c.Assert(err, IsNil)
+	cells := read.Sheets[0].Rows[0].Cells
+	c.Assert(cells[0].Type(), Equals, CellTypeFormula)
+	c.Assert(cells[0].FormulaResultType(), Equals, CellTypeString)
+	c.Assert(cells[0].Value, Equals, "123")
+	c.Assert(cells[1].FormulaResultType(), Equals, CellTypeBool)
+	c.Assert(cells[1].Formula(), Equals, "X1")
+	c.Assert(cells[1].Bool(), Equals, true)
+	c.Assert(cells[2].Bool(), Equals, false)
+	c.Assert(cells[3].FormulaResultType(), Equals, CellTypeNumeric)
+	c.Assert(cells[5].FormulaResultType(), Equals, CellTypeError)
+	c.Assert(cells[5].Formula(), Equals, "1/0")
+
+	// Numbers are written out in full, without an exponent.
+	for result, value := range map[interface{}]string{1e21: "1000000000000000000000", 1e-7: "0.0000001", float32(0.1): "0.1", 7: "7"} {
+		var cell Cell
+		cell.SetFormulaResult(result)
+		c.Assert(cell.Value, Equals, value)
+	}
+}
+
+func (s *CellSuite) TestSetFormulaWithValue(c *C) {
+	file := NewFileWithOptions(FloatPrecision(ExcelPrecision))
+	sheet, _ := file.AddSheet("Sheet1")
+	row := sheet.AddRow()
+	a, b := 0.1, 0.2
+	sum := row.AddCell()
+	sum.SetFormulaWithValue("0.1+0.2", a+b)
+	text := row.AddCell()
+	text.SetFormulaWithValue(`"00"&A1`, "007")
+	date := row.AddCell()
+	date.SetFormulaWithValue("DATE(2024,3,1)", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+	c.Assert(sum.Formula(), Equals, "0.1+0.2")
+	c.Assert(sum.Value, Equals, "0.30000000000000004")
+	c.Assert(date.Value, Equals, "45352")
+	c.Assert(date.NumFmt, Equals, file.dateFormat(true))
+
+	xSheet := sheet.makeXLSXSheet(NewSharedStringRefTable(), newXlsxStyleSheet(nil))
+	cells := xSheet.SheetData.Row[0].C
+	c.Assert(cells[0].F.Content, Equals, "0.1+0.2")
+	c.Assert(cells[0].V, Equals, "0.3")
+	c.Assert(cells[0].T, Equals, "")
+	c.Assert(cells[1].V, Equals, "007")
+	c.Assert(cells[1].T, Equals, "str")
+	c.Assert(cells[2].V, Equals, "45352")
+}
@@ -0,0 +1,519 @@
+package xlsx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ColIndexToLetters converts a zero based column index into the
+// letters used to name that column in a spreadsheet, e.g. 0 becomes
+// "A" and 27 becomes "AB".  Negative indexes yield an empty string.
+func ColIndexToLetters(col int) string {
+	if col < 0 {
+		return ""
+	}
+	var letters []byte
+	for col++; col > 0; col = (col - 1) / 26 {
+		letters = append([]byte{byte('A' + (col-1)%26)}, letters...)
+	}
+	return string(letters)
+}
+
+// ColLettersToIndex converts the letters naming a column, in either
+// case, into a zero based column index, e.g. "A" becomes 0 and "ab"
+// becomes 27.
+func ColLettersToIndex(letters string) (int, error) {
+	if letters == "" {
+		return -1, fmt.Errorf("invalid column '%s'", letters)
+	}
+	col := 0
+	for i := 0; i < len(letters); i++ {
+		c := letters[i]
+		switch {
+		case 'A' <= c && c <= 'Z':
+			col = col*26 + int(c-'A') + 1
+		case 'a' <= c && c <= 'z':
+			col = col*26 + int(c-'a') + 1
+		default:
+			return -1, fmt.Errorf("invalid column '%s'", letters)
+		}
+		if col > SheetColLimit {
+			return -1, fmt.Errorf("column '%s' is out of range", letters)
+		}
+	}
+	return col - 1, nil
+}
+
+// CellRef identifies a single cell by its zero based column and row
+// indexes.  AbsCol and AbsRow record whether the reference was written
+// with a "$", i.e. whether it stays put when a formula is copied.
+type CellRef struct {
+	Col    int
+	Row    int
+	AbsCol bool
+	AbsRow bool
+}
+
+// ParseCellRef parses a reference in A1 notation, such as "B3" or
+// "$B$3".
+func ParseCellRef(ref string) (CellRef, error) {
+	var r CellRef
+	s := ref
+	if strings.HasPrefix(s, "$") {
+		r.AbsCol = true
+		s = s[1:]
+	}
+	i := 0
+	for i < len(s) && (('A' <= s[i] && s[i] <= 'Z') || ('a' <= s[i] && s[i] <= 'z')) {
+		i++
+	}
+	col, err := ColLettersToIndex(s[:i])
+	if err != nil {
+		return CellRef{}, fmt.Errorf("invalid cell reference '%s'", ref)
+	}
+	s = s[i:]
+	if strings.HasPrefix(s, "$") {
+		r.AbsRow = true
+		s = s[1:]
+	}
+	row, err := strconv.Atoi(s)
+	if err != nil || row < 1 || row > SheetRowLimit || s[0] == '+' {
+		return CellRef{}, fmt.Errorf("invalid cell reference '%s'", ref)
+	}
+	r.Col, r.Row = col, row-1
+	return r, nil
+}
+
+// String returns the reference in A1 notation.
+func (r CellRef) String() string {
+	var s string
+	if r.AbsCol {
+		s = "$"
+	}
+	s += ColIndexToLetters(r.Col)
+	if r.AbsRow {
+		s += "$"
+	}
+	return s + strconv.Itoa(r.Row+1)
+}
+
+// Offset returns the reference moved by the given number of columns
+// and rows.
+func (r CellRef) Offset(cols, rows int) CellRef {
+	r.Col += cols
+	r.Row += rows
+	return r
+}
+
+// R1C1 returns the reference in R1C1 notation.  Absolute parts are
+// written as absolute row or column numbers, e.g. "R3C2"; relative
+// parts are written relative to base, e.g. "R[1]C[-1]".
+func (r CellRef) R1C1(base CellRef) string {
+	var row, col string
+	switch {
+	case r.AbsRow:
+		row = strconv.Itoa(r.Row + 1)
+	case r.Row != base.Row:
+		row = fmt.Sprintf("[%d]", r.Row-base.Row)
+	}
+	switch {
+	case r.AbsCol:
+		col = strconv.Itoa(r.Col + 1)
+	case r.Col != base.Col:
+		col = fmt.Sprintf("[%d]", r.Col-base.Col)
+	}
+	return "R" + row + "C" + col
+}
+
+// ParseR1C1 parses a reference in R1C1 notation, such as "R3C2" or
+// "R[-1]C".  Relative parts are resolved against base.
+func ParseR1C1(ref string, base CellRef) (CellRef, error) {
+	s := strings.ToUpper(ref)
+	if !strings.HasPrefix(s, "R") {
+		return CellRef{}, fmt.Errorf("invalid R1C1 reference '%s'", ref)
+	}
+	c := strings.IndexByte(s, 'C')
+	if c < 0 {
+		return CellRef{}, fmt.Errorf("invalid R1C1 reference '%s'", ref)
+	}
+	row, absRow, err := parseR1C1Part(s[1:c], base.Row)
+	if err != nil {
+		return CellRef{}, fmt.Errorf("invalid R1C1 reference '%s'", ref)
+	}
+	col, absCol, err := parseR1C1Part(s[c+1:], base.Col)
+	if err != nil {
+		return CellRef{}, fmt.Errorf("invalid R1C1 reference '%s'", ref)
+	}
+	if row < 0 || col < 0 {
+		return CellRef{}, fmt.Errorf("R1C1 reference '%s' is out of range", ref)
+	}
+	return CellRef{Col: col, Row: row, AbsCol: absCol, AbsRow: absRow}, nil
+}
+
+// parseR1C1Part parses the row or column part of an R1C1 reference:
+// either empty, an absolute one based number, or a relative offset in
+// square brackets.
+func parseR1C1Part(part string, base int) (index int, absolute bool, err error) {
+	switch {
+	case part == "":
+		return base, false, nil
+	case strings.HasPrefix(part, "[") && strings.HasSuffix(part, "]"):
+		offset, err := strconv.Atoi(part[1 : len(part)-1])
+		return base + offset, false, err
+	}
+	n, err := strconv.Atoi(part)
+	if err == nil && n < 1 {
+		err = fmt.Errorf("invalid index %d", n)
+	}
+	return n - 1, true, err
+}
+
+// CellRange is a rectangular block of cells, optionally on a named
+// sheet.  Start is always the top left cell and End the bottom right
+// cell of the range.
+type CellRange struct {
+	Sheet string
+	Start CellRef
+	End   CellRef
+}
+
+// NewCellRange returns the range spanning the given corners, which
+// may be given in any order.
+func NewCellRange(a, b CellRef) CellRange {
+	if a.Col > b.Col {
+		a.Col, b.Col = b.Col, a.Col
+		a.AbsCol, b.AbsCol = b.AbsCol, a.AbsCol
+	}
+	if a.Row > b.Row {
+		a.Row, b.Row = b.Row, a.Row
+		a.AbsRow, b.AbsRow = b.AbsRow, a.AbsRow
+	}
+	return CellRange{Start: a, End: b}
+}
+
+// ParseCellRange parses a range such as "A1:C10", "Sheet1!A1:C10"
+// or "'My Sheet'!B2".  A single cell is treated as a range of one
+// cell.
+func ParseCellRange(ref string) (CellRange, error) {
+	var sheet string
+	s := ref
+	if i := strings.LastIndex(s, "!"); i >= 0 {
+		sheet, s = s[:i], s[i+1:]
+		if strings.HasPrefix(sheet, "'") {
+			if len(sheet) < 2 || !strings.HasSuffix(sheet, "'") {
+				return CellRange{}, fmt.Errorf("invalid range '%s'", ref)
+			}
+			sheet = strings.Replace(sheet[1:len(sheet)-1], "''", "'", -1)
+		}
+		if sheet == "" {
+			return CellRange{}, fmt.Errorf("invalid range '%s'", ref)
+		}
+	}
+	parts := strings.SplitN(s, ":", 2)
+	start, err := ParseCellRef(parts[0])
+	if err != nil {
+		return CellRange{}, fmt.Errorf("invalid range '%s'", ref)
+	}
+	end := start
+	if len(parts) == 2 {
+		end, err = ParseCellRef(parts[1])
+		if err != nil {
+			return CellRange{}, fmt.Errorf("invalid range '%s'", ref)
+		}
+	}
+	r := NewCellRange(start, end)
+	r.Sheet = sheet
+	return r, nil
+}
+
+// String returns the range in A1 notation, prefixed by the sheet
+// name if there is one.  Sheet names are quoted where necessary.
+func (r CellRange) String() string {
+	s := r.Start.String()
+	if r.End != r.Start {
+		s += ":" + r.End.String()
+	}
+	if r.Sheet == "" {
+		return s
+	}
+	return quoteSheetName(r.Sheet) + "!" + s
+}
+
+// quoteSheetName quotes a sheet name for use in a reference if it
+// contains anything other than letters, digits, underscores and dots,
+// starts with a digit or could be taken for a cell reference, such as
+// "A1" or "R1C1".
+func quoteSheetName(name string) string {
+	quoted := "'" + strings.Replace(name, "'", "''", -1) + "'"
+	if name == "" || ('0' <= name[0] && name[0] <= '9') || looksLikeCellRef(name) || looksLikeR1C1(name) {
+		return quoted
+	}
+	for _, c := range name {
+		if !(c == '_' || c == '.' || ('0' <= c && c <= '9') ||
+			('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z')) {
+			return quoted
+		}
+	}
+	return name
+}
+
+// looksLikeR1C1 reports whether name has the shape of a reference in
+// R1C1 notation, such as "R", "C2" or "R1C1".
+func looksLikeR1C1(name string) bool {
+	s := strings.ToUpper(name)
+	if strings.HasPrefix(s, "R") {
+		s = strings.TrimLeft(s[1:], "0123456789")
+	}
+	if strings.HasPrefix(s, "C") {
+		s = strings.TrimLeft(s[1:], "0123456789")
+	}
+	return s == "" && name != ""
+}
+
+// Cols returns the number of columns in the range.
+func (r CellRange) Cols() int {
+	return r.End.Col - r.Start.Col + 1
+}
+
+// Rows returns the number of rows in the range.
+func (r CellRange) Rows() int {
+	return r.End.Row - r.Start.Row + 1
+}
+
+// Contains reports whether the cell lies within the range.
+func (r CellRange) Contains(ref CellRef) bool {
+	return r.Start.Col <= ref.Col && ref.Col <= r.End.Col &&
+		r.Start.Row <= ref.Row && ref.Row <= r.End.Row
+}
+
+// Offset returns the range moved by the given number of columns and
+// rows.
+func (r CellRange) Offset(cols, rows int) CellRange {
+	r.Start = r.Start.Offset(cols, rows)
+	r.End = r.End.Offset(cols, rows)
+	return r
+}
+
+// Intersect returns the cells common to both ranges.  The boolean
+// result is false if the ranges don't overlap.  The sheet of r is
+// kept; ranges on different sheets never overlap.
+func (r CellRange) Intersect(o CellRange) (CellRange, bool) {
+	if r.Sheet != o.Sheet && r.Sheet != "" && o.Sheet != "" {
+		return CellRange{}, false
+	}
+	result := CellRange{Sheet: r.Sheet, Start: r.Start, End: r.End}
+	if o.Start.Col > result.Start.Col {
+		result.Start.Col = o.Start.Col
+	}
+	if o.Start.Row > result.Start.Row {
+		result.Start.Row = o.Start.Row
+	}
+	if o.End.Col < result.End.Col {
+		result.End.Col = o.End.Col
+	}
+	if o.End.Row < result.End.Row {
+		result.End.Row = o.End.Row
+	}
+	if result.Start.Col > result.End.Col || result.Start.Row > result.End.Row {
+		return CellRange{}, false
+	}
+	return result, true
+}
+
+// Union returns the smallest range enclosing both ranges.
+func (r CellRange) Union(o CellRange) CellRange {
+	result := r
+	if o.Start.Col < result.Start.Col {
+		result.Start.Col = o.Start.Col
+	}
+	if o.Start.Row < result.Start.Row {
+		result.Start.Row = o.Start.Row
+	}
+	if o.End.Col > result.End.Col {
+		result.End.Col = o.End.Col
+	}
+	if o.End.Row > result.End.Row {
+		result.End.Row = o.End.Row
+	}
+	return result
+}
+
+// mapFormulaRefs returns the formula with every cell reference in it
+// replaced by the result of calling fn on it.  The rangeEnd argument
+// of fn is true for the second cell of a range such as "A1:B2".
+// String literals, sheet names in quotes and function names are left
+// untouched.
+func mapFormulaRefs(formula string, fn func(ref CellRef, rangeEnd bool) CellRef) string {
+	return mapFormulaRefText(formula, func(ref CellRef, rangeEnd bool) string {
+		return fn(ref, rangeEnd).String()
+	})
+}
+
+// mapFormulaRefText returns the formula with every cell reference in
+// it replaced by the text fn returns for it, as mapFormulaRefs does.
+func mapFormulaRefText(formula string, fn func(ref CellRef, rangeEnd bool) string) string {
+	return mapFormulaSheetRefText(formula, func(sheet string, ref CellRef, rangeEnd bool) string {
+		return fn(ref, rangeEnd)
+	})
+}
+
+// mapFormulaSheetRefs is mapFormulaRefs for an fn that is also given
+// the name of the sheet a reference is qualified with, such as Other in
+// "Other!B4", unquoted, or "" for one to the formula's own sheet.
+func mapFormulaSheetRefs(formula string, fn func(sheet string, ref CellRef, rangeEnd bool) CellRef) string {
+	return mapFormulaSheetRefText(formula, func(sheet string, ref CellRef, rangeEnd bool) string {
+		return fn(sheet, ref, rangeEnd).String()
+	})
+}
+
+func mapFormulaSheetRefText(formula string, fn func(sheet string, ref CellRef, rangeEnd bool) string) string {
+	var res strings.Builder
+	var quote byte
+	// quoteStart is where the quoted text being read starts, quoteEnd
+	// where the last ended, qualifier the sheet name just read and
+	// sheet that of the last reference.
+	quoteStart, quoteEnd := 0, -1
+	var qualifier, sheet string
+	for i := 0; i < len(formula); {
+		c := formula[i]
+		if quote != 0 {
+			if c == quote {
+				quote, quoteEnd = 0, i
+				if c == '\'' && i+1 < len(formula) && formula[i+1] == '!' {
+					qualifier = strings.Replace(formula[quoteStart+1:i], "''", "'", -1)
+				}
+			}
+			res.WriteByte(c)
+			i++
+			continue
+		}
+		if c == '"' || c == '\'' {
+			// A doubled quote is one within the quoted text.
+			if quoteEnd != i-1 {
+				quoteStart = i
+			}
+			quote = c
+			res.WriteByte(c)
+			i++
+			continue
+		}
+		isStart := c == '$' || ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z')
+		if !isStart || (i > 0 && isNameByte(formula[i-1])) {
+			res.WriteByte(c)
+			i++
+			continue
+		}
+		j := i
+		for j < len(formula) && (isNameByte(formula[j]) || formula[j] == '$') {
+			j++
+		}
+		token := formula[i:j]
+		ref, err := ParseCellRef(token)
+		if j < len(formula) && formula[j] == '!' {
+			qualifier = token
+		}
+		if err != nil || (j < len(formula) && (formula[j] == '(' || formula[j] == '!')) {
+			res.WriteString(token)
+			i = j
+			continue
+		}
+		rangeEnd := i > 0 && formula[i-1] == ':'
+		switch {
+		case i > 0 && formula[i-1] == '!':
+			sheet, qualifier = qualifier, ""
+		case !rangeEnd:
+			sheet = ""
+		}
+		res.WriteString(fn(sheet, ref, rangeEnd))
+		i = j
+	}
+	return res.String()
+}
+
+// FormulaToR1C1 returns the formula, written in A1 notation, in R1C1
+// notation for the cell at base, e.g. "SUM(A1:A3)*$B$1" in cell B4
+// becomes "SUM(R[-3]C[-1]:R[-1]C[-1])*R1C2".
+func FormulaToR1C1(formula string, base CellRef) string {
+	return mapFormulaRefText(formula, func(ref CellRef, rangeEnd bool) string {
+		return ref.R1C1(base)
+	})
+}
+
+// FormulaFromR1C1 returns the formula, written in R1C1 notation for the
+// cell at base, in A1 notation, as formulas are stored.  It returns an
+// error if a relative reference falls off the worksheet.  String
+// literals, quoted sheet names and names that aren't references, such
+// as that of the ROUND function, are left as they are.
+func FormulaFromR1C1(formula string, base CellRef) (string, error) {
+	var res strings.Builder
+	var quote byte
+	for i := 0; i < len(formula); {
+		c := formula[i]
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			res.WriteByte(c)
+			i++
+			continue
+		}
+		if c == '"' || c == '\'' {
+			quote = c
+			res.WriteByte(c)
+			i++
+			continue
+		}
+		j := r1c1RefEnd(formula, i)
+		if j < 0 {
+			res.WriteByte(c)
+			i++
+			continue
+		}
+		ref, err := ParseR1C1(formula[i:j], base)
+		if err != nil {
+			return "", err
+		}
+		res.WriteString(ref.String())
+		i = j
+	}
+	return res.String(), nil
+}
+
+// r1c1RefEnd returns the index just past the R1C1 reference starting
+// at i in the formula, or -1 if there isn't one there.
+func r1c1RefEnd(formula string, i int) int {
+	if i > 0 && (isNameByte(formula[i-1]) || formula[i-1] == ']') {
+		return -1
+	}
+	j := i
+	for _, letter := range []byte{'R', 'C'} {
+		if j >= len(formula) || (formula[j] != letter && formula[j] != letter+'a'-'A') {
+			return -1
+		}
+		j++
+		switch {
+		case j < len(formula) && formula[j] == '[':
+			end := strings.IndexByte(formula[j:], ']')
+			if end < 0 {
+				return -1
+			}
+			j += end + 1
+		default:
+			for j < len(formula) && '0' <= formula[j] && formula[j] <= '9' {
+				j++
+			}
+		}
+	}
+	if j < len(formula) && (isNameByte(formula[j]) || formula[j] == '(' || formula[j] == '!') {
+		return -1
+	}
+	return j
+}
+
+// isNameByte reports whether b may appear within a name or reference
+// in a formula.
+func isNameByte(b byte) bool {
+	return b == '_' || b == '.' || ('0' <= b && b <= '9') ||
+		('A' <= b && b <= 'Z') || ('a' <= b && b <= 'z')
+}
@@ -0,0 +1,169 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type CellRefSuite struct{}
+
+var _ = Suite(&CellRefSuite{})
+
+func (s *CellRefSuite) TestColIndexToLetters(c *C) {
+	cases := map[int]string{
+		-1:    "",
+		0:     "A",
+		25:    "Z",
+		26:    "AA",
+		27:    "AB",
+		701:   "ZZ",
+		702:   "AAA",
+		16383: "XFD",
+	}
+	for index, letters := range cases {
+		c.Assert(ColIndexToLetters(index), Equals, letters)
+		if index >= 0 {
+			// Agrees with the older internal conversion.
+			c.Assert(numericToLetters(index), Equals, letters)
+			back, err := ColLettersToIndex(letters)
+			c.Assert(err, IsNil)
+			c.Assert(back, Equals, index)
+		}
+	}
+}
+
+func (s *CellRefSuite) TestColLettersToIndex(c *C) {
+	index, err := ColLettersToIndex("ab")
+	c.Assert(err, IsNil)
+	c.Assert(index, Equals, 27)
+	_, err = ColLettersToIndex("")
+	c.Assert(err, NotNil)
+	_, err = ColLettersToIndex("A1")
+	c.Assert(err, NotNil)
+	_, err = ColLettersToIndex("XFE")
+	c.Assert(err, NotNil)
+}
+
+func (s *CellRefSuite) TestParseCellRef(c *C) {
+	ref, err := ParseCellRef("B3")
+	c.Assert(err, IsNil)
+	c.Assert(ref, Equals, CellRef{Col: 1, Row: 2})
+	c.Assert(ref.String(), Equals, "B3")
+
+	ref, err = ParseCellRef("$AA$10")
+	c.Assert(err, IsNil)
+	c.Assert(ref, Equals, CellRef{Col: 26, Row: 9, AbsCol: true, AbsRow: true})
+	c.Assert(ref.String(), Equals, "$AA$10")
+
+	ref, err = ParseCellRef("c$7")
+	c.Assert(err, IsNil)
+	c.Assert(ref.String(), Equals, "C$7")
+
+	for _, bad := range []string{"", "A", "1", "A0", "A-1", "A+1", "A1B", "$$A1", "A1048577"} {
+		_, err = ParseCellRef(bad)
+		c.Assert(err, NotNil, Commentf(bad))
+	}
+}
+
+func (s *CellRefSuite) TestR1C1(c *C) {
+	base := CellRef{Col: 2, Row: 4}
+	c.Assert(CellRef{Col: 1, Row: 2, AbsCol: true, AbsRow: true}.R1C1(base), Equals, "R3C2")
+	c.Assert(CellRef{Col: 1, Row: 5}.R1C1(base), Equals, "R[1]C[-1]")
+	c.Assert(CellRef{Col: 2, Row: 4}.R1C1(base), Equals, "RC")
+
+	ref, err := ParseR1C1("R3C2", base)
+	c.Assert(err, IsNil)
+	c.Assert(ref.String(), Equals, "$B$3")
+	ref, err = ParseR1C1("r[1]c[-1]", base)
+	c.Assert(err, IsNil)
+	c.Assert(ref.String(), Equals, "B6")
+	ref, err = ParseR1C1("RC", base)
+	c.Assert(err, IsNil)
+	c.Assert(ref, Equals, base)
+
+	for _, bad := range []string{"", "C1", "R1", "R0C1", "RC[-3]", "R[x]C"} {
+		_, err = ParseR1C1(bad, base)
+		c.Assert(err, NotNil, Commentf(bad))
+	}
+}
+
+func (s *CellRefSuite) TestFormulaR1C1(c *C) {
+	base := CellRef{Col: 1, Row: 3}
+	formulas := map[string]string{
+		"SUM(A1:A3)*$B$1":                   "SUM(R[-3]C[-1]:R[-1]C[-1])*R1C2",
+		`'My Sheet'!B4&"A1"`:                `'My Sheet'!RC&"A1"`,
+		"ROUND(C$4,2)+Sheet1!$A5+LOG10(B4)": "ROUND(R4C[1],2)+Sheet1!R[1]C1+LOG10(RC)",
+	}
+	for a1, r1c1 := range formulas {
+		c.Assert(FormulaToR1C1(a1, base), Equals, r1c1)
+		back, err := FormulaFromR1C1(r1c1, base)
+		c.Assert(err, IsNil)
+		c.Assert(back, Equals, a1)
+	}
+	a1, err := FormulaFromR1C1("rc[1]+RCX+RC(1)", base)
+	c.Assert(err, IsNil)
+	c.Assert(a1, Equals, "C4+RCX+RC(1)")
+	_, err = FormulaFromR1C1("R[-4]C", base)
+	c.Assert(err, ErrorMatches, "R1C1 reference 'R\\[-4\\]C' is out of range")
+
+	file := NewFileWithOptions(R1C1ReferenceStyle())
+	sheet, _ := file.AddSheet("Sheet1")
+	c.Assert(sheet.SetFormulaR1C1(3, 1, "SUM(R[-3]C:R[-1]C)"), IsNil)
+	c.Assert(sheet.Cell(3, 1).Formula(), Equals, "SUM(B1:B3)")
+	c.Assert(sheet.FormulaR1C1(3, 1), Equals, "SUM(R[-3]C:R[-1]C)")
+	c.Assert(sheet.FormulaR1C1(9, 9), Equals, "")
+	c.Assert(file.makeCalcPr().RefMode, Equals, "R1C1")
+}
+
+func (s *CellRefSuite) TestParseCellRange(c *C) {
+	r, err := ParseCellRange("Sheet1!A1:C10")
+	c.Assert(err, IsNil)
+	c.Assert(r.Sheet, Equals, "Sheet1")
+	c.Assert(r.Start, Equals, CellRef{})
+	c.Assert(r.End, Equals, CellRef{Col: 2, Row: 9})
+	c.Assert(r.Cols(), Equals, 3)
+	c.Assert(r.Rows(), Equals, 10)
+	c.Assert(r.String(), Equals, "Sheet1!A1:C10")
+
+	r, err = ParseCellRange("'Bob''s data'!C3:A1")
+	c.Assert(err, IsNil)
+	c.Assert(r.Sheet, Equals, "Bob's data")
+	c.Assert(r.String(), Equals, "'Bob''s data'!A1:C3")
+
+	r, err = ParseCellRange("B2")
+	c.Assert(err, IsNil)
+	c.Assert(r.Start, Equals, r.End)
+	c.Assert(r.String(), Equals, "B2")
+
+	// Names that could be taken for references are quoted.
+	for _, name := range []string{"A1", "xfd1048576", "R1C1", "R", "c2", "2024", "1st"} {
+		c.Assert(quoteSheetName(name), Equals, "'"+name+"'")
+	}
+	for _, name := range []string{"Sheet1", "RC1A", "Data_2024"} {
+		c.Assert(quoteSheetName(name), Equals, name)
+	}
+
+	for _, bad := range []string{"", "!A1", "Sheet1!", "A1:", "'Sheet1!A1", "A1:B"} {
+		_, err = ParseCellRange(bad)
+		c.Assert(err, NotNil, Commentf(bad))
+	}
+}
+
+func (s *CellRefSuite) TestRangeArithmetic(c *C) {
+	a, _ := ParseCellRange("A1:C3")
+	b, _ := ParseCellRange("B2:D5")
+
+	c.Assert(a.Contains(CellRef{Col: 2, Row: 2}), Equals, true)
+	c.Assert(a.Contains(CellRef{Col: 3, Row: 2}), Equals, false)
+	c.Assert(a.Offset(1, 2).String(), Equals, "B3:D5")
+
+	i, ok := a.Intersect(b)
+	c.Assert(ok, Equals, true)
+	c.Assert(i.String(), Equals, "B2:C3")
+	_, ok = a.Intersect(a.Offset(3, 0))
+	c.Assert(ok, Equals, false)
+	other, _ := ParseCellRange("Other!A1:C3")
+	_, ok = other.Intersect(CellRange{Sheet: "Sheet1", End: CellRef{Col: 5, Row: 5}})
+	c.Assert(ok, Equals, false)
+
+	c.Assert(a.Union(b).String(), Equals, "A1:D5")
+}
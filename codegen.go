@@ -0,0 +1,286 @@
+package xlsx
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// GoCodeOptions controls the Go source produced by File.WriteGoCode.
+type GoCodeOptions struct {
+	// Package is the name of the generated package.  It defaults
+	// to "main".
+	Package string
+	// FuncName is the name of the generated function.  It defaults
+	// to "BuildWorkbook".
+	FuncName string
+	// ImportPath is the import path of this library.  It defaults
+	// to "github.com/tealeg/xlsx".
+	ImportPath string
+}
+
+// WriteGoCode writes Go source to w declaring a function that builds
+// the File from scratch using this library's API: its sheets, column
+// widths, rows, cell values, formulas, merges and styles.  It's meant
+// to turn a workbook laid out by hand into a starting point for
+// generating it programmatically.  The generated function has the
+// signature
+//
+//	func BuildWorkbook() (*xlsx.File, error)
+func (f *File) WriteGoCode(w io.Writer, options GoCodeOptions) error {
+	if options.Package == "" {
+		options.Package = "main"
+	}
+	if options.FuncName == "" {
+		options.FuncName = "BuildWorkbook"
+	}
+	if options.ImportPath == "" {
+		options.ImportPath = "github.com/tealeg/xlsx"
+	}
+
+	g := &goCodeGenerator{styles: make(map[string]string)}
+	var body bytes.Buffer
+	for _, sheet := range f.Sheets {
+		if err := sheet.Load(); err != nil {
+			return err
+		}
+		g.writeSheet(&body, sheet)
+	}
+	if g.err != nil {
+		return g.err
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by xlsx.WriteGoCode. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&src, "package %s\n\n", options.Package)
+	if g.math {
+		fmt.Fprintf(&src, "import (\n\"math\"\n\n%q\n)\n\n", options.ImportPath)
+	} else {
+		fmt.Fprintf(&src, "import %q\n\n", options.ImportPath)
+	}
+	fmt.Fprintf(&src, "func %s() (*xlsx.File, error) {\n", options.FuncName)
+	if f.Date1904 {
+		// The serial numbers of dates are written as they are.
+		fmt.Fprintf(&src, "file := xlsx.NewFileWithOptions(xlsx.Date1904System())\n")
+	} else {
+		fmt.Fprintf(&src, "file := xlsx.NewFile()\n")
+	}
+	if len(f.Sheets) > 0 {
+		fmt.Fprintf(&src, "var sheet *xlsx.Sheet\nvar row *xlsx.Row\nvar cell *xlsx.Cell\nvar err error\n")
+	}
+	src.Write(g.declarations.Bytes())
+	src.Write(body.Bytes())
+	if g.cells == 0 && len(f.Sheets) > 0 {
+		fmt.Fprintf(&src, "_ = cell\n")
+	}
+	if g.rows == 0 && len(f.Sheets) > 0 {
+		fmt.Fprintf(&src, "_ = row\n")
+	}
+	if !g.sheetUsed && len(f.Sheets) > 0 {
+		fmt.Fprintf(&src, "_ = sheet\n")
+	}
+	fmt.Fprintf(&src, "return file, nil\n}\n")
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(formatted)
+	return err
+}
+
+// goCodeGenerator accumulates the state needed while generating Go
+// code for a File.  Identical styles share a single variable.  The
+// first value that can't be written as Go is recorded in err.
+type goCodeGenerator struct {
+	declarations bytes.Buffer
+	styles       map[string]string
+	rows, cells  int
+	// sheetUsed is whether the code refers to a sheet once it is
+	// added.
+	sheetUsed bool
+	// math is whether the code uses the math package, for NaN or an
+	// infinity.
+	math bool
+	err  error
+}
+
+func (g *goCodeGenerator) writeSheet(w *bytes.Buffer, sheet *Sheet) {
+	fmt.Fprintf(w, "\nsheet, err = file.AddSheet(%q)\n", sheet.Name)
+	fmt.Fprintf(w, "if err != nil {\nreturn nil, err\n}\n")
+	if sheet.Hidden {
+		g.sheetUsed = true
+		fmt.Fprintf(w, "sheet.Hidden = true\n")
+	}
+	for _, col := range sheet.Cols {
+		if col.Width != 0 && col.Min > 0 && col.Max >= col.Min {
+			g.sheetUsed = true
+			fmt.Fprintf(w, "sheet.SetColWidth(%d, %d, %s)\n", col.Min-1, col.Max-1, g.goFloat(col.Width))
+		}
+	}
+	for _, row := range sheet.Rows {
+		g.rows++
+		g.sheetUsed = true
+		fmt.Fprintf(w, "row = sheet.AddRow()\n")
+		if row == nil {
+			continue
+		}
+		if row.isCustom {
+			fmt.Fprintf(w, "row.SetHeight(%s)\n", g.goFloat(row.Height))
+		}
+		if row.Hidden {
+			fmt.Fprintf(w, "row.Hidden = true\n")
+		}
+		if row.OutlineLevel != 0 {
+			fmt.Fprintf(w, "row.OutlineLevel = %d\n", row.OutlineLevel)
+		}
+		for _, cell := range row.Cells {
+			g.writeCell(w, cell)
+		}
+	}
+}
+
+func (g *goCodeGenerator) writeCell(w *bytes.Buffer, cell *Cell) {
+	g.cells++
+	fmt.Fprintf(w, "cell = row.AddCell()\n")
+	if cell == nil {
+		return
+	}
+	if cell.formula != "" {
+		fmt.Fprintf(w, "cell.SetFormula(%q)\n", cell.formula)
+	} else {
+		value, err := strconv.ParseFloat(cell.Value, 64)
+		isNumber := err == nil
+		switch {
+		case cell.cellType == CellTypeBool:
+			fmt.Fprintf(w, "cell.SetBool(%t)\n", cell.Bool())
+		case cell.cellType == CellTypeDate && isNumber:
+			fmt.Fprintf(w, "cell.SetDateTimeWithFormat(%s, %q)\n", g.goFloat(value), cell.NumFmt)
+		case (cell.cellType == CellTypeNumeric || cell.cellType == CellTypeGeneral) && isNumber:
+			if cell.NumFmt == "" || cell.NumFmt == builtInNumFmt[builtInNumFmtIndex_GENERAL] {
+				fmt.Fprintf(w, "cell.SetFloat(%s)\n", g.goFloat(value))
+			} else {
+				fmt.Fprintf(w, "cell.SetFloatWithFormat(%s, %q)\n", g.goFloat(value), cell.NumFmt)
+			}
+		case cell.Value != "":
+			fmt.Fprintf(w, "cell.SetString(%q)\n", cell.Value)
+		}
+	}
+	if cell.HMerge > 0 || cell.VMerge > 0 {
+		fmt.Fprintf(w, "cell.Merge(%d, %d)\n", cell.HMerge, cell.VMerge)
+	}
+	if cell.style != nil {
+		fmt.Fprintf(w, "cell.SetStyle(%s)\n", g.style(cell.style))
+	}
+}
+
+// style returns the name of the variable holding the given style,
+// declaring it on first use.
+func (g *goCodeGenerator) style(style *Style) string {
+	var decl bytes.Buffer
+	fmt.Fprintf(&decl, ".Font = %s\n", g.goValue(reflect.ValueOf(style.Font)))
+	fmt.Fprintf(&decl, ".Fill = %s\n", g.goValue(reflect.ValueOf(style.Fill)))
+	fmt.Fprintf(&decl, ".Border = %s\n", g.goValue(reflect.ValueOf(style.Border)))
+	fmt.Fprintf(&decl, ".Alignment = %s\n", g.goValue(reflect.ValueOf(style.Alignment)))
+	fmt.Fprintf(&decl, ".ApplyFont = %t\n", style.ApplyFont)
+	fmt.Fprintf(&decl, ".ApplyFill = %t\n", style.ApplyFill)
+	fmt.Fprintf(&decl, ".ApplyBorder = %t\n", style.ApplyBorder)
+	fmt.Fprintf(&decl, ".ApplyAlignment = %t\n", style.ApplyAlignment)
+	if style.ApplyProtection {
+		fmt.Fprintf(&decl, ".Protection = %s\n", g.goValue(reflect.ValueOf(style.Protection)))
+		fmt.Fprintf(&decl, ".ApplyProtection = true\n")
+	}
+	key := decl.String()
+	if name, ok := g.styles[key]; ok {
+		return name
+	}
+	name := fmt.Sprintf("style%d", len(g.styles)+1)
+	g.styles[key] = name
+	fmt.Fprintf(&g.declarations, "%s := xlsx.NewStyle()\n", name)
+	for _, line := range bytes.SplitAfter(decl.Bytes(), []byte("\n")) {
+		if len(line) > 0 {
+			fmt.Fprintf(&g.declarations, "%s%s", name, line)
+		}
+	}
+	return name
+}
+
+// goValue returns a Go expression for v, a value of one of the types
+// of a Style's parts, setting the fields of structs, those they point
+// to included, that aren't zero.  A value of any other kind is recorded
+// in g.err.
+func (g *goCodeGenerator) goValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return "nil"
+		}
+		return "&" + g.goValue(v.Elem())
+	case reflect.Struct:
+		fields := make([]string, 0, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.PkgPath != "" || v.Field(i).IsZero() {
+				continue
+			}
+			fields = append(fields, field.Name+": "+g.goValue(v.Field(i)))
+		}
+		return goTypeName(v.Type()) + "{" + strings.Join(fields, ", ") + "}"
+	case reflect.Slice:
+		if v.IsNil() {
+			return "nil"
+		}
+		elems := make([]string, v.Len())
+		for i := range elems {
+			elems[i] = g.goValue(v.Index(i))
+		}
+		return "[]" + goTypeName(v.Type().Elem()) + "{" + strings.Join(elems, ", ") + "}"
+	case reflect.String:
+		return strconv.Quote(v.String())
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return g.goFloat(v.Float())
+	}
+	if g.err == nil {
+		g.err = fmt.Errorf("can't write a %s as Go", v.Type())
+	}
+	return "nil"
+}
+
+// goTypeName returns the name of the type t in generated Go.
+func goTypeName(t reflect.Type) string {
+	switch {
+	case t.Kind() == reflect.Ptr:
+		return "*" + goTypeName(t.Elem())
+	case t.PkgPath() != "":
+		return "xlsx." + t.Name()
+	}
+	return t.String()
+}
+
+// goFloat formats a float64 as a Go expression: a literal, unless it
+// is NaN or an infinity, which have none.
+func (g *goCodeGenerator) goFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		g.math = true
+		return "math.NaN()"
+	case math.IsInf(f, 1):
+		g.math = true
+		return "math.Inf(1)"
+	case math.IsInf(f, -1):
+		g.math = true
+		return "math.Inf(-1)"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,183 @@
+package xlsx
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type CodegenSuite struct{}
+
+var _ = Suite(&CodegenSuite{})
+
+// packageImporter imports this package, for the Go generated by
+// WriteGoCode, by type-checking its source, and the standard library
+// with std.
+type packageImporter struct {
+	fset *token.FileSet
+	std  types.Importer
+	pkg  *types.Package
+}
+
+func (i *packageImporter) Import(path string) (*types.Package, error) {
+	if path != "github.com/tealeg/xlsx" {
+		return i.std.Import(path)
+	}
+	if i.pkg != nil {
+		return i.pkg, nil
+	}
+	pkgs, err := parser.ParseDir(i.fset, ".", func(fi fs.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	var files []*ast.File
+	for _, file := range pkgs["xlsx"].Files {
+		files = append(files, file)
+	}
+	conf := types.Config{Importer: i.std}
+	i.pkg, err = conf.Check(path, i.fset, files, nil)
+	return i.pkg, err
+}
+
+// goCodeImporter is shared by the tests, so that the package is only
+// type-checked once.
+var goCodeImporter = &packageImporter{fset: token.NewFileSet(), std: importer.Default()}
+
+// typeCheckGoCode checks that src, written by WriteGoCode, compiles.
+func typeCheckGoCode(c *C, src string) {
+	fset := goCodeImporter.fset
+	file, err := parser.ParseFile(fset, "generated.go", src, 0)
+	c.Assert(err, IsNil)
+	conf := types.Config{Importer: goCodeImporter}
+	_, err = conf.Check("generated", fset, []*ast.File{file}, nil)
+	c.Assert(err, IsNil, Commentf(src))
+}
+
+func (s *CodegenSuite) TestWriteGoCode(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Report \"Q1\"")
+	sheet.SetColWidth(0, 1, 20)
+	row := sheet.AddRow()
+	title := row.AddCell()
+	title.SetString("Title")
+	title.Merge(1, 0)
+	bold := NewStyle()
+	bold.Font.Bold = true
+	bold.ApplyFont = true
+	title.SetStyle(bold)
+	row.AddCell()
+	row = sheet.AddRow()
+	row.AddCell().SetFloatWithFormat(1.5, "0.00")
+	row.AddCell().SetFormula("A2*2")
+	row.AddCell().SetBool(true)
+	row.AddCell().SetInt(7)
+	row.AddCell().SetStyle(bold)
+
+	var buf bytes.Buffer
+	err := file.WriteGoCode(&buf, GoCodeOptions{Package: "reports", FuncName: "Quarterly"})
+	c.Assert(err, IsNil)
+	src := buf.String()
+
+	typeCheckGoCode(c, src)
+	for _, expected := range []string{
+		"package reports\n",
+		`import "github.com/tealeg/xlsx"`,
+		"func Quarterly() (*xlsx.File, error) {",
+		`sheet, err = file.AddSheet("Report \"Q1\"")`,
+		"sheet.SetColWidth(0, 1, 20)",
+		`cell.SetString("Title")`,
+		"cell.Merge(1, 0)",
+		`cell.SetFloatWithFormat(1.5, "0.00")`,
+		`cell.SetFormula("A2*2")`,
+		"cell.SetBool(true)",
+		"cell.SetFloat(7)",
+		"style1.Font = xlsx.Font{",
+		"style1.ApplyFont = true",
+		"cell.SetStyle(style1)",
+	} {
+		c.Assert(strings.Contains(src, expected), Equals, true, Commentf(expected))
+	}
+	// The bold style is shared, and unstyled cells don't get one.
+	c.Assert(strings.Count(src, "cell.SetStyle(style1)"), Equals, 2)
+	c.Assert(strings.Contains(src, "style2"), Equals, false)
+}
+
+func (s *CodegenSuite) TestWriteGoCodeEmptySheet(c *C) {
+	file := NewFile()
+	file.AddSheet("Empty")
+	var buf bytes.Buffer
+	c.Assert(file.WriteGoCode(&buf, GoCodeOptions{}), IsNil)
+	src := buf.String()
+	c.Assert(strings.Contains(src, "package main\n"), Equals, true)
+	c.Assert(strings.Contains(src, "func BuildWorkbook() (*xlsx.File, error) {"), Equals, true)
+	c.Assert(strings.Contains(src, "_ = row"), Equals, true)
+	typeCheckGoCode(c, src)
+}
+
+func (s *CodegenSuite) TestWriteGoCodeDate1904(c *C) {
+	file := NewFileWithOptions(Date1904System())
+	sheet, _ := file.AddSheet("Dates")
+	sheet.Cell(0, 0).SetDate(time.Date(2017, 3, 14, 0, 0, 0, 0, time.UTC))
+	var buf bytes.Buffer
+	c.Assert(file.WriteGoCode(&buf, GoCodeOptions{}), IsNil)
+	src := buf.String()
+	c.Assert(strings.Contains(src, "file := xlsx.NewFileWithOptions(xlsx.Date1904System())\n"), Equals, true)
+	c.Assert(strings.Contains(src, "cell.SetDateTimeWithFormat(41346, "), Equals, true)
+	typeCheckGoCode(c, src)
+}
+
+func (s *CodegenSuite) TestWriteGoCodeNaNAndInf(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Odd")
+	sheet.Cell(0, 0).SetFloat(math.NaN())
+	sheet.Cell(0, 1).SetFloat(math.Inf(1))
+	sheet.Cell(0, 2).SetFloat(math.Inf(-1))
+	var buf bytes.Buffer
+	c.Assert(file.WriteGoCode(&buf, GoCodeOptions{}), IsNil)
+	src := buf.String()
+	for _, call := range []string{"cell.SetFloat(math.NaN())", "cell.SetFloat(math.Inf(1))", "cell.SetFloat(math.Inf(-1))"} {
+		c.Assert(strings.Contains(src, call), Equals, true, Commentf(src))
+	}
+	typeCheckGoCode(c, src)
+
+	// The math package is only imported when it is used.
+	file = NewFile()
+	file.AddSheet("Empty")
+	buf.Reset()
+	c.Assert(file.WriteGoCode(&buf, GoCodeOptions{}), IsNil)
+	c.Assert(strings.Contains(buf.String(), `"math"`), Equals, false)
+}
+
+func (s *CodegenSuite) TestGoValueUnsupportedKind(c *C) {
+	g := &goCodeGenerator{styles: make(map[string]string)}
+	c.Assert(g.goValue(reflect.ValueOf(map[string]int{})), Equals, "nil")
+	c.Assert(g.err, ErrorMatches, "can't write a map\\[string\\]int as Go")
+}
+
+func (s *CodegenSuite) TestWriteGoCodeGradientFill(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Gradient")
+	style := NewStyle()
+	style.Fill = Fill{Gradient: NewLinearGradient(90, "FF0000FF", "FFFFFFFF")}
+	style.ApplyFill = true
+	sheet.Cell(0, 0).SetStyle(style)
+
+	var buf bytes.Buffer
+	c.Assert(file.WriteGoCode(&buf, GoCodeOptions{}), IsNil)
+	src := buf.String()
+	typeCheckGoCode(c, src)
+	c.Assert(strings.Contains(src, `style1.Fill = xlsx.Fill{Gradient: &xlsx.GradientFill{Degree: 90, Stops: []xlsx.GradientStop{`+
+		`xlsx.GradientStop{Color: "FF0000FF"}, xlsx.GradientStop{Position: 1, Color: "FFFFFFFF"}}}}`), Equals, true, Commentf(src))
+}
@@ -0,0 +1,143 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	relTypeVBAProject           = "http://schemas.microsoft.com/office/2006/relationships/vbaProject"
+	contentTypeMacroEnabledMain = "application/vnd.ms-excel.sheet.macroEnabled.main+xml"
+)
+
+// HasVBAProject reports whether the File holds the VBA project of the
+// macro-enabled workbook it was read from.  The project is written
+// back with the File, which is then macro-enabled too, and should be
+// saved with the .xlsm extension.  Macros refer to sheets by their code
+// names, which should be kept as they were read.
+func (f *File) HasVBAProject() bool {
+	return len(f.vbaProject) > 0
+}
+
+// readVBAProject reads the VBA project the workbook's relationships,
+// rels, refer to, with the parts it refers to, such as its signature.
+func (f *File) readVBAProject(rels map[string]xlsxWorksheetRelationship) error {
+	for _, rel := range rels {
+		if rel.Type != relTypeVBAProject || rel.TargetMode == "External" {
+			continue
+		}
+		parts, err := f.readPartTree("the VBA project", rel.Target)
+		if err != nil {
+			return err
+		}
+		if len(parts) > 0 && parts[0].name == rel.Target {
+			f.vbaProject = parts
+		}
+		return nil
+	}
+	return nil
+}
+
+// makeVBAProjectParts adds the parts of the File's VBA project to
+// packaged, relating the project to the workbook in rels, and makes
+// the workbook a macro-enabled one.
+func (f *File) makeVBAProjectParts(packaged *packageParts, rels *packageRelationships) error {
+	if !f.HasVBAProject() {
+		return nil
+	}
+	names, err := addPreservedParts(packaged, rels, f.vbaProject)
+	if err != nil {
+		return err
+	}
+	rels.of("xl/workbook.xml").add(relTypeVBAProject, names[f.vbaProject[0].name])
+	packaged.setContentType("xl/workbook.xml", contentTypeMacroEnabledMain)
+	return nil
+}
+
+// preservedCodeName returns the code name in the sheetPr of the part of
+// a dialog sheet or macro sheet, data.
+func preservedCodeName(data []byte) string {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	depth := 0
+	for {
+		token, err := d.RawToken()
+		if err != nil {
+			return ""
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth < 2 {
+				continue
+			}
+			if t.Name.Local != "sheetPr" {
+				// The sheetPr comes first, if there is one.
+				return ""
+			}
+			for _, attr := range t.Attr {
+				if attr.Name.Local == "codeName" {
+					return attr.Value
+				}
+			}
+			return ""
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+// SheetCodeName gives the new Sheet the code name VBA refers to it by;
+// see Sheet.CodeName.  Sheets made with SheetFrom otherwise have none,
+// rather than the code name of their template.
+func SheetCodeName(name string) SheetOption {
+	return func(o *sheetOptions) {
+		o.codeName = name
+	}
+}
+
+// validCodeName reports whether name is a code name VBA allows: a
+// letter, followed by up to 30 letters, digits and underscores.
+func validCodeName(name string) bool {
+	for i, r := range []rune(name) {
+		if i >= 31 || !unicode.IsLetter(r) && (i == 0 || r != '_' && !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// checkCodeNames returns an error if the File, or any of the sheets to
+// be written for it, has a code name VBA doesn't allow, or if two of
+// them, or one and a PreservedSheet, have the same one.  VBA compares
+// names without regard to case.
+func (f *File) checkCodeNames(sheets []*Sheet) error {
+	owners := make(map[string]string, len(sheets)+len(f.PreservedSheets)+1)
+	if f.CodeName != "" {
+		if !validCodeName(f.CodeName) {
+			return fmt.Errorf("the workbook's code name '%s' isn't one VBA allows", f.CodeName)
+		}
+		owners[strings.ToLower(f.CodeName)] = "the workbook"
+	}
+	for _, sheet := range f.PreservedSheets {
+		if sheet.CodeName != "" {
+			owners[strings.ToLower(sheet.CodeName)] = fmt.Sprintf("the %s '%s'", sheet.Kind, sheet.Name)
+		}
+	}
+	for _, sheet := range sheets {
+		if sheet.CodeName == "" {
+			continue
+		}
+		if !validCodeName(sheet.CodeName) {
+			return fmt.Errorf("sheet '%s': the code name '%s' isn't one VBA allows", sheet.Name, sheet.CodeName)
+		}
+		key := strings.ToLower(sheet.CodeName)
+		if owner, ok := owners[key]; ok {
+			return fmt.Errorf("sheet '%s' has the code name '%s' of %s", sheet.Name, sheet.CodeName, owner)
+		}
+		owners[key] = fmt.Sprintf("sheet '%s'", sheet.Name)
+	}
+	return nil
+}
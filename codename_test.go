@@ -0,0 +1,122 @@
+package xlsx
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type CodeNameSuite struct{}
+
+var _ = Suite(&CodeNameSuite{})
+
+func (s *CodeNameSuite) TestRoundTrip(c *C) {
+	f := NewFile()
+	f.CodeName = "ThisWorkbook"
+	data, err := f.AddSheet("Data", SheetCodeName("shData"))
+	c.Assert(err, IsNil)
+	data.Cell(0, 0).SetString("x")
+	_, err = f.AddSheet("Notes")
+	c.Assert(err, IsNil)
+	// Copies are given no code name, unless one is asked for.
+	copied, err := f.AddSheet("Copy", SheetFrom(data))
+	c.Assert(err, IsNil)
+	c.Assert(copied.CodeName, Equals, "")
+	copied, err = f.AddSheet("Copy 2", SheetFrom(data), SheetCodeName("shCopy"))
+	c.Assert(err, IsNil)
+	c.Assert(copied.CodeName, Equals, "shCopy")
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/workbook.xml"], Matches, `(?s).*<workbookPr [^>]*codeName="ThisWorkbook"[^>]*>.*`)
+	c.Assert(parts["xl/worksheets/sheet1.xml"], Matches, `(?s).*<sheetPr filterMode="false" codeName="shData">.*`)
+	c.Assert(parts["xl/worksheets/sheet2.xml"], Not(Matches), `(?s).*codeName.*`)
+
+	written, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	c.Assert(written.CodeName, Equals, "ThisWorkbook")
+	c.Assert(written.Sheets[0].CodeName, Equals, "shData")
+	c.Assert(written.Sheets[1].CodeName, Equals, "")
+	c.Assert(written.Sheets[3].CodeName, Equals, "shCopy")
+}
+
+func (s *CodeNameSuite) TestCheck(c *C) {
+	f := NewFile()
+	data, err := f.AddSheet("Data", SheetCodeName("Sheet1"))
+	c.Assert(err, IsNil)
+	notes, err := f.AddSheet("Notes", SheetCodeName("sheet1"))
+	c.Assert(err, IsNil)
+	_, err = f.MarshallParts()
+	c.Assert(err, ErrorMatches, "sheet 'Notes' has the code name 'sheet1' of sheet 'Data'")
+
+	notes.CodeName = "ThisWorkbook"
+	f.CodeName = "ThisWorkbook"
+	_, err = f.MarshallParts()
+	c.Assert(err, ErrorMatches, "sheet 'Notes' has the code name 'ThisWorkbook' of the workbook")
+
+	f.CodeName = "This Workbook"
+	_, err = f.MarshallParts()
+	c.Assert(err, ErrorMatches, "the workbook's code name 'This Workbook' isn't one VBA allows")
+	f.CodeName = ""
+
+	for _, name := range []string{"1Sheet", "_Sheet", "Sheet-1", "S234567890123456789012345678901X"} {
+		data.CodeName = name
+		_, err = f.MarshallParts()
+		c.Assert(err, ErrorMatches, "sheet 'Data': the code name '"+name+"' isn't one VBA allows")
+	}
+	for _, name := range []string{"Sheet_1", "Übersicht", "S234567890123456789012345678901"} {
+		data.CodeName = name
+		_, err = f.MarshallParts()
+		c.Assert(err, IsNil)
+	}
+}
+
+// vbaProjectParts are the parts of preservedSheetParts with a VBA
+// project, which is signed, and code names for the macro sheet.
+func vbaProjectParts() map[string]string {
+	parts := preservedSheetParts()
+	parts["xl/_rels/workbook.xml.rels"] = strings.Replace(parts["xl/_rels/workbook.xml.rels"], `</Relationships>`,
+		`<Relationship Id="rId9" Type="http://schemas.microsoft.com/office/2006/relationships/vbaProject" Target="vbaProject.bin"/></Relationships>`, 1)
+	parts["[Content_Types].xml"] = strings.Replace(parts["[Content_Types].xml"], `</Types>`,
+		`<Default Extension="bin" ContentType="application/vnd.ms-office.vbaProject"/>`+
+			`<Override PartName="/xl/vbaProjectSignature.bin" ContentType="application/vnd.ms-office.vbaProjectSignature"/></Types>`, 1)
+	parts["xl/vbaProject.bin"] = "\xd0\xcf\x11\xe0 project"
+	parts["xl/_rels/vbaProject.bin.rels"] = `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.microsoft.com/office/2006/relationships/vbaProjectSignature" Target="vbaProjectSignature.bin"/></Relationships>`
+	parts["xl/vbaProjectSignature.bin"] = "\x00\x01 signature"
+	parts["xl/macrosheets/sheet1.xml"] = strings.Replace(macroSheetXML, `<sheetData>`, `<sheetPr codeName="Macro1"/><sheetData>`, 1)
+	return parts
+}
+
+func (s *CodeNameSuite) TestVBAProject(c *C) {
+	f, err := ReadZipReader(makeZipReader(c, vbaProjectParts()))
+	c.Assert(err, IsNil)
+	c.Assert(f.HasVBAProject(), Equals, true)
+	c.Assert(f.PreservedSheets[1].CodeName, Equals, "Macro1")
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	// Part names are read in lower case.
+	c.Assert(parts["xl/vbaproject.bin"], Equals, "\xd0\xcf\x11\xe0 project")
+	c.Assert(parts["xl/vbaprojectsignature.bin"], Equals, "\x00\x01 signature")
+	c.Assert(parts["xl/_rels/workbook.xml.rels"], Matches, `(?s).*Type="http://schemas.microsoft.com/office/2006/relationships/vbaProject" Target="vbaproject.bin".*`)
+	c.Assert(parts["xl/_rels/vbaproject.bin.rels"], Matches, `(?s).*Target="/xl/vbaprojectsignature.bin".*`)
+	c.Assert(parts["[Content_Types].xml"], Matches, `(?s).*<Override PartName="/xl/workbook.xml" ContentType="application/vnd.ms-excel.sheet.macroEnabled.main\+xml">.*`)
+	c.Assert(parts["[Content_Types].xml"], Matches, `(?s).*<Override PartName="/xl/vbaprojectsignature.bin" ContentType="application/vnd.ms-office.vbaProjectSignature">.*`)
+
+	written, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	c.Assert(written.HasVBAProject(), Equals, true)
+
+	// A worksheet can't take the code name of the macro sheet.
+	f.Sheets[0].CodeName = "macro1"
+	_, err = f.MarshallParts()
+	c.Assert(err, ErrorMatches, "sheet 'Data' has the code name 'macro1' of the macro sheet 'Macro1'")
+
+	f, err = ReadZipReader(makeZipReader(c, preservedSheetParts()))
+	c.Assert(err, IsNil)
+	c.Assert(f.HasVBAProject(), Equals, false)
+	parts, err = f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["[Content_Types].xml"], Not(Matches), `(?s).*macroEnabled.*`)
+}
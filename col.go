@@ -1,5 +1,10 @@
 package xlsx
 
+import (
+	"reflect"
+	"sort"
+)
+
 // Default column width in excel
 const ColWidth = 9.5
 
@@ -12,9 +17,15 @@ type Col struct {
 	OutlineLevel uint8
 	numFmt       string
 	style        *Style
+	// customStyle is set when the Col has a style or type of its
+	// own, rather than the default, which new cells in it are given.
+	customStyle bool
 }
 
+// SetType sets the number format of the Col to suit cellType, which
+// new cells in the Col are given.
 func (c *Col) SetType(cellType CellType) {
+	c.customStyle = true
 	switch cellType {
 	case CellTypeString:
 		c.numFmt = builtInNumFmt[builtInNumFmtIndex_STRING]
@@ -38,7 +49,252 @@ func (c *Col) GetStyle() *Style {
 	return c.style
 }
 
-// SetStyle sets the style of a Col
+// SetStyle sets the style of a Col, which new cells in the Col are
+// given a copy of.
 func (c *Col) SetStyle(style *Style) {
 	c.style = style
+	c.customStyle = style != nil
+}
+
+// styleCell gives a new cell in the Col the Col's own style and
+// number format, if it has them.
+func (c *Col) styleCell(cell *Cell) {
+	if !c.customStyle {
+		return
+	}
+	if c.style != nil {
+		style := *c.style
+		cell.style = &style
+	}
+	cell.NumFmt = c.numFmt
+}
+
+// equals reports whether two columns are defined the same way, apart
+// from the range they cover, so that adjacent ones can share a range.
+func (c *Col) equals(other *Col) bool {
+	if c.Width != other.Width || c.Hidden != other.Hidden ||
+		c.Collapsed != other.Collapsed || c.OutlineLevel != other.OutlineLevel ||
+		c.numFmt != other.numFmt || c.customStyle != other.customStyle {
+		return false
+	}
+	if c.style == nil || other.style == nil {
+		return c.style == other.style
+	}
+	return reflect.DeepEqual(*c.style, *other.style)
+}
+
+// ColStore holds the column definitions of a Sheet.  Each Col covers
+// the columns Min to Max, counted from 1 as in the file, and its
+// setters keep them in order without overlaps, splitting a Col that
+// is partly changed and merging neighbours that end up the same.
+// Lookups rely on that order, which Cols added to the ColStore some
+// other way are only put in by the next setter, or for writing.
+type ColStore []*Col
+
+// SetColWidth sets the width of the columns from to to, which are
+// zero based and inclusive.
+func (cs *ColStore) SetColWidth(from, to int, width float64) {
+	cs.apply(from, to, func(col *Col) { col.Width = width })
+}
+
+// SetColStyle sets the style of the columns from to to, which are
+// zero based and inclusive.  Each column gets its own copy of style,
+// which cells added to it later are given a copy of in turn.
+func (cs *ColStore) SetColStyle(from, to int, style *Style) {
+	cs.apply(from, to, func(col *Col) {
+		if style == nil {
+			col.SetStyle(nil)
+			return
+		}
+		colStyle := *style
+		col.SetStyle(&colStyle)
+	})
+}
+
+// SetColNumFmt sets the number format of the columns from to to,
+// which are zero based and inclusive, for cells added to them later.
+func (cs *ColStore) SetColNumFmt(from, to int, numFmt string) {
+	cs.apply(from, to, func(col *Col) {
+		col.numFmt = numFmt
+		col.customStyle = true
+	})
+}
+
+// SetColHidden hides or shows the columns from to to, which are zero
+// based and inclusive.
+func (cs *ColStore) SetColHidden(from, to int, hidden bool) {
+	cs.apply(from, to, func(col *Col) { col.Hidden = hidden })
+}
+
+// FindCol returns the Col covering the zero based column col, or nil
+// if it has no definition of its own.
+func (cs *ColStore) FindCol(col int) *Col {
+	if i, ok := cs.index(col + 1); ok {
+		return (*cs)[i]
+	}
+	return nil
+}
+
+// index returns the position of the Col covering the column num,
+// counted from 1, or where one would be inserted.
+func (cs *ColStore) index(num int) (int, bool) {
+	cols := *cs
+	i := sort.Search(len(cols), func(i int) bool { return cols[i].Max >= num })
+	return i, i < len(cols) && cols[i].Min <= num
+}
+
+// normalise puts the columns in order and resolves any overlaps, with
+// later definitions taking precedence, as they do in Excel.  A Col
+// with no Min, as made for the columns of a file without definitions
+// of its own, covers the column at its position.
+func (cs *ColStore) normalise() {
+	if cs.normal() {
+		return
+	}
+	sorted := true
+	for i, col := range *cs {
+		if col.Min == 0 {
+			col.Min = i + 1
+		}
+		if col.Max < col.Min {
+			col.Max = col.Min
+		}
+		if i > 0 && (*cs)[i-1].Max >= col.Min {
+			sorted = false
+		}
+	}
+	if sorted {
+		return
+	}
+	cols := *cs
+	*cs = nil
+	for _, col := range cols {
+		cs.insert(col)
+	}
+}
+
+// normalised returns the columns put in order as normalise does, but
+// leaving the ColStore as it is, so that writing a Sheet doesn't change
+// it.
+func (cs ColStore) normalised() ColStore {
+	if cs.normal() {
+		return cs
+	}
+	cols := make(ColStore, len(cs))
+	for i, col := range cs {
+		c := *col
+		cols[i] = &c
+	}
+	cols.normalise()
+	return cols
+}
+
+// normal reports whether the columns are in order without overlaps,
+// as normalise leaves them.
+func (cs ColStore) normal() bool {
+	for i, col := range cs {
+		if col.Min == 0 || col.Max < col.Min || i > 0 && cs[i-1].Max >= col.Min {
+			return false
+		}
+	}
+	return true
+}
+
+// insert puts col in order, cutting back whatever it overlaps.
+func (cs *ColStore) insert(col *Col) {
+	var cols ColStore
+	for _, existing := range *cs {
+		if existing.Max < col.Min || existing.Min > col.Max {
+			cols = append(cols, existing)
+			continue
+		}
+		if existing.Min < col.Min {
+			cols = append(cols, existing.piece(existing.Min, col.Min-1))
+		}
+		if existing.Max > col.Max {
+			cols = append(cols, existing.piece(col.Max+1, existing.Max))
+		}
+	}
+	cols = append(cols, col)
+	sort.SliceStable(cols, func(i, j int) bool { return cols[i].Min < cols[j].Min })
+	*cs = cols
+}
+
+// apply calls set on a Col for each part of the zero based columns
+// from to to, splitting those that stick out of the range, adding
+// ones for the columns without a definition, and merging the result
+// with its neighbours where they are now the same.
+func (cs *ColStore) apply(from, to int, set func(*Col)) {
+	if from > to {
+		from, to = to, from
+	}
+	min, max := from+1, to+1
+	cs.normalise()
+	var cols ColStore
+	next := min
+	for _, col := range *cs {
+		if col.Max < min || col.Min > max {
+			if col.Min > max && next <= max {
+				cols = append(cols, &Col{Min: next, Max: max})
+				set(cols[len(cols)-1])
+				next = max + 1
+			}
+			cols = append(cols, col)
+			continue
+		}
+		if col.Min < min {
+			cols = append(cols, col.piece(col.Min, min-1))
+		}
+		if col.Min > next {
+			cols = append(cols, &Col{Min: next, Max: col.Min - 1})
+			set(cols[len(cols)-1])
+		}
+		var right *Col
+		if col.Max > max {
+			right = col.piece(max+1, col.Max)
+		}
+		if col.Min < min {
+			col.Min = min
+		}
+		if col.Max > max {
+			col.Max = max
+		}
+		set(col)
+		cols = append(cols, col)
+		next = col.Max + 1
+		if right != nil {
+			cols = append(cols, right)
+		}
+	}
+	if next <= max {
+		cols = append(cols, &Col{Min: next, Max: max})
+		set(cols[len(cols)-1])
+	}
+	*cs = cols
+	cs.merge()
+}
+
+// piece returns a copy of the Col, with its own copy of its style,
+// covering the columns min to max.
+func (c *Col) piece(min, max int) *Col {
+	col := *c
+	col.Min, col.Max = min, max
+	if c.style != nil {
+		style := *c.style
+		col.style = &style
+	}
+	return &col
+}
+
+// merge joins neighbouring columns that are defined the same way.
+func (cs *ColStore) merge() {
+	var cols ColStore
+	for _, col := range *cs {
+		if n := len(cols); n > 0 && cols[n-1].Max+1 == col.Min && cols[n-1].equals(col) {
+			cols[n-1].Max = col.Max
+			continue
+		}
+		cols = append(cols, col)
+	}
+	*cs = cols
 }
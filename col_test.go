@@ -1 +1,151 @@
 package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type ColStoreSuite struct{}
+
+var _ = Suite(&ColStoreSuite{})
+
+func colRanges(cs ColStore) [][2]int {
+	var ranges [][2]int
+	for _, col := range cs {
+		ranges = append(ranges, [2]int{col.Min, col.Max})
+	}
+	return ranges
+}
+
+func (s *ColStoreSuite) TestSplitAndMerge(c *C) {
+	var cs ColStore
+	cs.SetColWidth(0, 9, 12)
+	c.Assert(colRanges(cs), DeepEquals, [][2]int{{1, 10}})
+
+	// Hiding the middle splits the range in three.
+	cs.SetColHidden(3, 5, true)
+	c.Assert(colRanges(cs), DeepEquals, [][2]int{{1, 3}, {4, 6}, {7, 10}})
+	c.Assert(cs[1].Width, Equals, 12.0)
+	c.Assert(cs[1].Hidden, Equals, true)
+	c.Assert(cs.FindCol(6).Hidden, Equals, false)
+
+	// A range running past the end fills the gap.
+	cs.SetColWidth(8, 11, 20)
+	c.Assert(colRanges(cs), DeepEquals, [][2]int{{1, 3}, {4, 6}, {7, 8}, {9, 12}})
+	c.Assert(cs.FindCol(12), IsNil)
+
+	// Showing the columns again merges them back.
+	cs.SetColHidden(3, 5, false)
+	c.Assert(colRanges(cs), DeepEquals, [][2]int{{1, 8}, {9, 12}})
+}
+
+func (s *ColStoreSuite) TestSetColStyle(c *C) {
+	var cs ColStore
+	bold := NewStyle()
+	bold.Font.Bold = true
+	cs.SetColStyle(2, 3, bold)
+	cs.SetColWidth(0, 5, 15)
+	c.Assert(colRanges(cs), DeepEquals, [][2]int{{1, 2}, {3, 4}, {5, 6}})
+	c.Assert(cs.FindCol(2).GetStyle().Font.Bold, Equals, true)
+	c.Assert(cs.FindCol(2).GetStyle(), Not(Equals), bold)
+	c.Assert(cs.FindCol(0).GetStyle(), IsNil)
+}
+
+func (s *ColStoreSuite) TestOverlapsResolved(c *C) {
+	// Cols added directly may overlap; the later one wins once
+	// they are put in order.
+	cs := ColStore{
+		{Min: 1, Max: 5, Width: 10},
+		{Min: 3, Max: 3, Width: 30},
+	}
+	// Lookups leave them as they are.
+	cs.FindCol(2)
+	c.Assert(colRanges(cs), DeepEquals, [][2]int{{1, 5}, {3, 3}})
+	cs.normalise()
+	c.Assert(cs.FindCol(2).Width, Equals, 30.0)
+	c.Assert(colRanges(cs), DeepEquals, [][2]int{{1, 2}, {3, 3}, {4, 5}})
+}
+
+func (s *ColStoreSuite) TestSheetCols(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	row := sheet.AddRow()
+	for i := 0; i < 3; i++ {
+		row.AddCell().SetInt(i)
+	}
+	sheet.SetDefaultColWidth(14)
+	c.Assert(sheet.SetColWidth(0, 4, 20), IsNil)
+	sheet.Col(1).Hidden = true
+
+	xSheet := sheet.makeXLSXSheet(NewSharedStringRefTable(), newXlsxStyleSheet(nil))
+	c.Assert(xSheet.SheetFormatPr.DefaultColWidth, Equals, 14.0)
+	// The columns with cells have a style, those beyond them don't.
+	c.Assert(xSheet.Cols.Col, HasLen, 4)
+	c.Assert(xSheet.Cols.Col[1].Min, Equals, 2)
+	c.Assert(xSheet.Cols.Col[1].Max, Equals, 2)
+	c.Assert(xSheet.Cols.Col[1].Hidden, Equals, true)
+	c.Assert(xSheet.Cols.Col[3].Min, Equals, 4)
+	c.Assert(xSheet.Cols.Col[3].Max, Equals, 5)
+	c.Assert(xSheet.Cols.Col[3].Width, Equals, 20.0)
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	written, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	sheet = written.Sheets[0]
+	c.Assert(sheet.SheetFormat.DefaultColWidth, Equals, 14.0)
+	c.Assert(sheet.Cols.FindCol(0).Width, Equals, 20.0)
+	c.Assert(sheet.Cols.FindCol(1).Hidden, Equals, true)
+	c.Assert(sheet.Cols.FindCol(2).Min, Equals, 3)
+	c.Assert(sheet.Cols.FindCol(2).Max, Equals, 3)
+}
+
+// Writing a Sheet whose Cols were added out of order leaves them as
+// they are.
+func (s *ColStoreSuite) TestWriteLeavesCols(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	sheet.Cols = ColStore{
+		{Min: 1, Max: 5, Width: 10},
+		{Min: 3, Max: 3, Width: 30},
+	}
+	xSheet := sheet.makeXLSXSheet(NewSharedStringRefTable(), newXlsxStyleSheet(nil))
+	c.Assert(xSheet.Cols.Col, HasLen, 3)
+	c.Assert(xSheet.Cols.Col[1].Width, Equals, 30.0)
+	_, err := file.StyleStats()
+	c.Assert(err, IsNil)
+	c.Assert(colRanges(sheet.Cols), DeepEquals, [][2]int{{1, 5}, {3, 3}})
+}
+
+func (s *ColStoreSuite) TestColStyleAppliedToNewCells(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	bold := NewStyle()
+	bold.Font.Bold = true
+	bold.ApplyFont = true
+	c.Assert(sheet.SetColStyle(1, 1, bold), IsNil)
+	sheet.Cols.SetColNumFmt(1, 1, "yyyy-mm-dd")
+	c.Assert(sheet.SetColStyle(2, 1, bold), ErrorMatches, "Could not set style for range 2-1: startcol must be less than endcol.")
+
+	row := sheet.AddRow()
+	plain := row.AddCell()
+	dated := row.AddCell()
+	dated.Value = "43101"
+	c.Assert(plain.GetStyle().Font.Bold, Equals, false)
+	c.Assert(dated.GetStyle().Font.Bold, Equals, true)
+	c.Assert(dated.GetStyle(), Not(Equals), sheet.Col(1).GetStyle())
+	c.Assert(dated.NumFmt, Equals, "yyyy-mm-dd")
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/worksheets/sheet1.xml"], Matches, `(?s).*<col collapsed="false" hidden="false" max="2" min="2" style="[1-9]\d*" width="9.5"></col>.*`)
+	c.Assert(parts["xl/worksheets/sheet1.xml"], Matches, `(?s).*<c r="B1" s="[1-9]\d*" t="s">.*`)
+
+	// A cell added to the column of a file read back gets its style too.
+	written, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	added := written.Sheets[0].AddRow()
+	added.AddCell()
+	cell := added.AddCell()
+	c.Assert(cell.GetStyle().Font.Bold, Equals, true)
+	c.Assert(cell.NumFmt, Equals, "yyyy-mm-dd")
+}
@@ -0,0 +1,299 @@
+package xlsx
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Collator compares strings the way a reader of a particular language
+// expects them to be ordered, rather than by their bytes.  Accents
+// and case only decide the order of strings that are otherwise equal,
+// so "Émile" sorts between "Emil" and "Emma".  The zero value
+// collates in a language neutral way.
+type Collator struct {
+	// Language is a BCP 47 language tag such as "en", "de", "sv" or
+	// "da".  Its primary subtag selects the tailoring applied on top
+	// of the neutral order; in Danish and Norwegian, for example,
+	// "Æ", "Ø" and "Å" sort after "Z".
+	Language string
+	// Numeric makes runs of digits compare by their numeric value,
+	// so that "Item 2" sorts before "Item 10".
+	Numeric bool
+	// IgnoreCase makes strings that differ only by case compare as
+	// equal.
+	IgnoreCase bool
+}
+
+// Collation element classes, in the order they sort.
+const (
+	collateSpace = iota
+	collatePunct
+	collateDigits
+	collateLetter
+	collateOther
+)
+
+// collationElement is the unit strings are compared by: mostly a
+// letter, or a whole run of digits in numeric mode.
+type collationElement struct {
+	class     int
+	primary   int    // the base letter, or the rune for other classes
+	digits    string // the digit run, without leading zeros
+	secondary rune   // the accented letter, lower cased
+	upper     bool
+}
+
+// letterWeight returns the primary weight of a base letter from a to
+// z.  Weights are spaced so that tailored letters can be inserted
+// between them.
+func letterWeight(r rune) int {
+	return int(r-'a') * 4
+}
+
+// afterZ is the primary weight of the nth letter sorting after z.
+func afterZ(n int) int {
+	return letterWeight('z') + 4*n
+}
+
+// neutralFolding maps accented and compound lower case letters to the
+// base letters they sort with in the language neutral order.
+var neutralFolding = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'æ': "ae", 'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ğ': "g", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'į': "i", 'ı': "i",
+	'ł': "l", 'ľ': "l", 'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o", 'œ': "oe",
+	'ŕ': "r", 'ř': "r", 'ß': "ss", 'ś': "s", 'š': "s", 'ş': "s", 'ť': "t", 'ţ': "t", 'þ': "th",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u", 'ų': "u",
+	'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z",
+}
+
+// collationTailorings holds, per language, the letters whose primary
+// weight differs from the neutral order.
+var collationTailorings = map[string]map[rune]int{
+	"da": danishNorwegianTailoring,
+	"nb": danishNorwegianTailoring,
+	"nn": danishNorwegianTailoring,
+	"no": danishNorwegianTailoring,
+	"sv": swedishFinnishTailoring,
+	"fi": swedishFinnishTailoring,
+	"es": {'ñ': letterWeight('n') + 1},
+}
+
+var danishNorwegianTailoring = map[rune]int{
+	'æ': afterZ(1), 'ä': afterZ(1),
+	'ø': afterZ(2), 'ö': afterZ(2),
+	'å': afterZ(3),
+}
+
+var swedishFinnishTailoring = map[rune]int{
+	'å': afterZ(1),
+	'ä': afterZ(2), 'æ': afterZ(2),
+	'ö': afterZ(3), 'ø': afterZ(3),
+}
+
+// tailoring returns the tailoring for the Collator's language.
+func (c *Collator) tailoring() map[rune]int {
+	language := strings.ToLower(c.Language)
+	if i := strings.IndexAny(language, "-_"); i >= 0 {
+		language = language[:i]
+	}
+	return collationTailorings[language]
+}
+
+// elements splits s into collation elements.
+func (c *Collator) elements(s string) []collationElement {
+	tailoring := c.tailoring()
+	runes := []rune(s)
+	elements := make([]collationElement, 0, len(runes))
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		lower := unicode.ToLower(r)
+		upper := lower != r
+		switch {
+		case c.Numeric && '0' <= r && r <= '9':
+			j := i
+			for j < len(runes) && '0' <= runes[j] && runes[j] <= '9' {
+				j++
+			}
+			digits := strings.TrimLeft(string(runes[i:j]), "0")
+			elements = append(elements, collationElement{class: collateDigits, digits: digits, secondary: rune(j - i)})
+			i = j - 1
+		case unicode.IsSpace(r):
+			elements = append(elements, collationElement{class: collateSpace, primary: ' ', secondary: r})
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			elements = append(elements, collationElement{class: collatePunct, primary: int(r), secondary: r})
+		case unicode.IsDigit(r):
+			elements = append(elements, collationElement{class: collateDigits, digits: string(r), secondary: r})
+		default:
+			if weight, ok := tailoring[lower]; ok {
+				elements = append(elements, collationElement{class: collateLetter, primary: weight, secondary: lower, upper: upper})
+				continue
+			}
+			base := string(lower)
+			if folded, ok := neutralFolding[lower]; ok {
+				base = folded
+			}
+			for _, b := range base {
+				if 'a' <= b && b <= 'z' {
+					elements = append(elements, collationElement{class: collateLetter, primary: letterWeight(b), secondary: lower, upper: upper})
+				} else {
+					elements = append(elements, collationElement{class: collateOther, primary: int(b), secondary: lower, upper: upper})
+				}
+			}
+		}
+	}
+	return elements
+}
+
+// comparePrimary compares two elements ignoring accents and case.
+func comparePrimary(a, b collationElement) int {
+	switch {
+	case a.class != b.class:
+		return compareInts(a.class, b.class)
+	case a.class == collateDigits:
+		if len(a.digits) != len(b.digits) {
+			return compareInts(len(a.digits), len(b.digits))
+		}
+		return strings.Compare(a.digits, b.digits)
+	}
+	return compareInts(a.primary, b.primary)
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Compare returns -1, 0 or +1 depending on whether a sorts before,
+// together with, or after b.
+func (c *Collator) Compare(a, b string) int {
+	ea, eb := c.elements(a), c.elements(b)
+	// Primary level: base letters and numbers.
+	for i := 0; i < len(ea) && i < len(eb); i++ {
+		if result := comparePrimary(ea[i], eb[i]); result != 0 {
+			return result
+		}
+	}
+	if len(ea) != len(eb) {
+		return compareInts(len(ea), len(eb))
+	}
+	// Secondary level: accents, and the number of leading zeros.
+	for i := range ea {
+		if ea[i].secondary != eb[i].secondary {
+			return compareInts(int(ea[i].secondary), int(eb[i].secondary))
+		}
+	}
+	if c.IgnoreCase {
+		return 0
+	}
+	// Tertiary level: lower case sorts first.
+	for i := range ea {
+		if ea[i].upper != eb[i].upper {
+			if ea[i].upper {
+				return 1
+			}
+			return -1
+		}
+	}
+	return 0
+}
+
+// CompareCells compares the values of two cells.  Numbers and dates
+// are compared numerically and sort before text, which is compared
+// using the Collator.
+func (c *Collator) CompareCells(a, b *Cell) int {
+	fa, aNumeric := cellNumber(a)
+	fb, bNumeric := cellNumber(b)
+	switch {
+	case aNumeric && bNumeric:
+		return compareFloats(fa, fb)
+	case aNumeric:
+		return -1
+	case bNumeric:
+		return 1
+	}
+	return c.Compare(cellText(a), cellText(b))
+}
+
+// cellNumber returns the numeric value of a cell holding a number or
+// a date.
+func cellNumber(cell *Cell) (float64, bool) {
+	if cell == nil {
+		return 0, false
+	}
+	switch cell.cellType {
+	case CellTypeNumeric, CellTypeDate, CellTypeGeneral, CellTypeFormula:
+		f, err := strconv.ParseFloat(cell.Value, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// cellText returns the text of a cell as it is displayed.
+func cellText(cell *Cell) string {
+	if cell == nil {
+		return ""
+	}
+	value, err := cell.FormattedValue()
+	if err != nil {
+		return cell.Value
+	}
+	return value
+}
+
+// rowCell returns the cell in the given column of a row, or nil.
+func rowCell(row *Row, col int) *Cell {
+	if row == nil || col < 0 || col >= len(row.Cells) {
+		return nil
+	}
+	return row.Cells[col]
+}
+
+// SortRows sorts the rows of the Sheet, starting with row index from -
+// so header rows can be left in place - by the values in column col.
+// Rows with equal values keep their order.  If collator is nil the
+// language neutral Collator is used.
+func (s *Sheet) SortRows(from, col int, collator *Collator) {
+	s.ensureLoaded()
+	if collator == nil {
+		collator = &Collator{}
+	}
+	if from < 0 {
+		from = 0
+	}
+	if from >= len(s.Rows) {
+		return
+	}
+	rows := s.Rows[from:]
+	sort.SliceStable(rows, func(i, j int) bool {
+		return collator.CompareCells(rowCell(rows[i], col), rowCell(rows[j], col)) < 0
+	})
+}
+
+// FindRow returns the index of the first row of the Sheet whose cell
+// in column col collates equal to value, along with the row itself.
+// If no row matches it returns -1 and nil.  Accents always matter, so
+// "Ole" never finds "Olé", but it finds "OLE" if the Collator ignores
+// case.  If collator is nil the language neutral Collator is used.
+func (s *Sheet) FindRow(col int, value string, collator *Collator) (int, *Row) {
+	s.ensureLoaded()
+	if collator == nil {
+		collator = &Collator{}
+	}
+	for i, row := range s.Rows {
+		cell := rowCell(row, col)
+		if cell != nil && collator.Compare(cellText(cell), value) == 0 {
+			return i, row
+		}
+	}
+	return -1, nil
+}
@@ -0,0 +1,97 @@
+package xlsx
+
+import (
+	"sort"
+
+	. "gopkg.in/check.v1"
+)
+
+type CollateSuite struct{}
+
+var _ = Suite(&CollateSuite{})
+
+func sortStrings(collator *Collator, values []string) []string {
+	sorted := append([]string{}, values...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return collator.Compare(sorted[i], sorted[j]) < 0
+	})
+	return sorted
+}
+
+func (s *CollateSuite) TestNeutralOrder(c *C) {
+	collator := &Collator{}
+	c.Assert(sortStrings(collator, []string{"Emma", "émile", "Emil", "Émile", "apple", "Zebra"}),
+		DeepEquals, []string{"apple", "Emil", "émile", "Émile", "Emma", "Zebra"})
+	c.Assert(collator.Compare("straße", "strasse"), Not(Equals), 0)
+	c.Assert(collator.Compare("straße", "strasst"), Equals, -1)
+	c.Assert(collator.Compare("abc", "abc"), Equals, 0)
+	c.Assert(collator.Compare("abc", "ABC"), Equals, -1)
+	c.Assert(collator.Compare("a b", "ab"), Equals, -1)
+}
+
+func (s *CollateSuite) TestIgnoreCase(c *C) {
+	collator := &Collator{IgnoreCase: true}
+	c.Assert(collator.Compare("abc", "ABC"), Equals, 0)
+	c.Assert(collator.Compare("abc", "ÁBC"), Equals, -1)
+}
+
+func (s *CollateSuite) TestNumeric(c *C) {
+	values := []string{"Item 10", "Item 2", "Item 1", "Item 02"}
+	c.Assert(sortStrings(&Collator{}, values), DeepEquals,
+		[]string{"Item 02", "Item 1", "Item 10", "Item 2"})
+	c.Assert(sortStrings(&Collator{Numeric: true}, values), DeepEquals,
+		[]string{"Item 1", "Item 2", "Item 02", "Item 10"})
+}
+
+func (s *CollateSuite) TestLanguageTailoring(c *C) {
+	names := []string{"Øster", "Zola", "Åberg", "Ærø", "Olsen", "Aaby"}
+	c.Assert(sortStrings(&Collator{Language: "en"}, names), DeepEquals,
+		[]string{"Aaby", "Åberg", "Ærø", "Olsen", "Øster", "Zola"})
+	c.Assert(sortStrings(&Collator{Language: "da"}, names), DeepEquals,
+		[]string{"Aaby", "Olsen", "Zola", "Ærø", "Øster", "Åberg"})
+	c.Assert(sortStrings(&Collator{Language: "sv-SE"}, names), DeepEquals,
+		[]string{"Aaby", "Olsen", "Zola", "Åberg", "Ærø", "Øster"})
+	c.Assert(sortStrings(&Collator{Language: "es"}, []string{"ñu", "nz", "na"}), DeepEquals,
+		[]string{"na", "nz", "ñu"})
+}
+
+func makeCollationSheet() *Sheet {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Customers")
+	for _, name := range []string{"Name", "Øster", "Zola", "Åberg", "olsen", "10", "9"} {
+		sheet.AddRow().AddCell().SetString(name)
+	}
+	sheet.Cell(5, 0).SetInt(10)
+	sheet.Cell(6, 0).SetInt(9)
+	return sheet
+}
+
+func (s *CollateSuite) TestSortRows(c *C) {
+	sheet := makeCollationSheet()
+	sheet.SortRows(1, 0, &Collator{Language: "nb"})
+	var names []string
+	for _, row := range sheet.Rows {
+		names = append(names, row.Cells[0].Value)
+	}
+	c.Assert(names, DeepEquals, []string{"Name", "9", "10", "olsen", "Zola", "Øster", "Åberg"})
+	c.Assert(sheet.Rows[6].Cells[0].Row, Equals, sheet.Rows[6])
+
+	// Out of range arguments are harmless.
+	sheet.SortRows(10, 0, nil)
+	sheet.SortRows(-1, 3, nil)
+	c.Assert(sheet.Rows, HasLen, 7)
+}
+
+func (s *CollateSuite) TestFindRow(c *C) {
+	sheet := makeCollationSheet()
+	index, row := sheet.FindRow(0, "OLSEN", &Collator{IgnoreCase: true})
+	c.Assert(index, Equals, 4)
+	c.Assert(row, Equals, sheet.Rows[4])
+	index, row = sheet.FindRow(0, "OLSEN", nil)
+	c.Assert(index, Equals, -1)
+	c.Assert(row, IsNil)
+	index, _ = sheet.FindRow(0, "Oster", &Collator{IgnoreCase: true})
+	c.Assert(index, Equals, -1)
+	index, _ = sheet.FindRow(0, "10", nil)
+	c.Assert(index, Equals, 5)
+}
@@ -0,0 +1,48 @@
+package xlsx
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// defaultIndexedColors is the legacy palette that indexed colours
+// refer to unless a style sheet gives its own.  The last two entries
+// are the system foreground and background colours.
+var defaultIndexedColors = []string{
+	"FF000000", "FFFFFFFF", "FFFF0000", "FF00FF00", "FF0000FF", "FFFFFF00", "FFFF00FF", "FF00FFFF",
+	"FF000000", "FFFFFFFF", "FFFF0000", "FF00FF00", "FF0000FF", "FFFFFF00", "FFFF00FF", "FF00FFFF",
+	"FF800000", "FF008000", "FF000080", "FF808000", "FF800080", "FF008080", "FFC0C0C0", "FF808080",
+	"FF9999FF", "FF993366", "FFFFFFCC", "FFCCFFFF", "FF660066", "FFFF8080", "FF0066CC", "FFCCCCFF",
+	"FF000080", "FFFF00FF", "FFFFFF00", "FF00FFFF", "FF800080", "FF800000", "FF008080", "FF0000FF",
+	"FF00CCFF", "FFCCFFFF", "FFCCFFCC", "FFFFFF99", "FF99CCFF", "FFFF99CC", "FFCC99FF", "FFFFCC99",
+	"FF3366FF", "FF33CCCC", "FF99CC00", "FFFFCC00", "FFFF9900", "FFFF6600", "FF666699", "FF969696",
+	"FF003366", "FF339966", "FF003300", "FF333300", "FF993300", "FF993366", "FF333399", "FF333333",
+	"FF000000", "FFFFFFFF",
+}
+
+// defaultTheme holds the colours of Excel's Office theme, for
+// resolving theme colours in files that have no theme of their own.
+var defaultTheme = &theme{colors: []string{
+	"FFFFFF", "000000", "E7E6E6", "44546A", "4472C4", "ED7D31",
+	"A5A5A5", "FFC000", "5B9BD5", "70AD47", "0563C1", "954F72",
+}}
+
+// applyTint lightens an ARGB hex colour by tint, or darkens it if
+// tint is negative, as Excel does: by moving its luminance that
+// fraction of the way towards white or black.
+func applyTint(argb string, tint float64) string {
+	if tint == 0 || len(argb) != 8 {
+		return argb
+	}
+	r, _ := strconv.ParseInt(argb[2:4], 16, 64)
+	g, _ := strconv.ParseInt(argb[4:6], 16, 64)
+	b, _ := strconv.ParseInt(argb[6:8], 16, 64)
+	h, s, l := RGBToHSL(uint8(r), uint8(g), uint8(b))
+	if tint < 0 {
+		l *= (1 + tint)
+	} else {
+		l = l*(1-tint) + (1 - (1 - tint))
+	}
+	br, bg, bb := HSLToRGB(h, s, l)
+	return fmt.Sprintf("%s%02X%02X%02X", argb[:2], br, bg, bb)
+}
@@ -0,0 +1,49 @@
+package xlsx
+
+import (
+	"encoding/xml"
+
+	. "gopkg.in/check.v1"
+)
+
+type ColorSuite struct{}
+
+var _ = Suite(&ColorSuite{})
+
+func (s *ColorSuite) TestArgbValue(c *C) {
+	index := func(i int) *int { return &i }
+	styles := newXlsxStyleSheet(nil)
+	c.Assert(styles.argbValue(xlsxColor{RGB: "FF123456"}), Equals, "FF123456")
+	c.Assert(styles.argbValue(xlsxColor{Indexed: index(10)}), Equals, "FFFF0000")
+	c.Assert(styles.argbValue(xlsxColor{Indexed: index(64)}), Equals, "FF000000")
+	c.Assert(styles.argbValue(xlsxColor{Indexed: index(99)}), Equals, "")
+	// Without a theme of its own, the Office theme is used.
+	c.Assert(styles.argbValue(xlsxColor{Theme: index(4)}), Equals, "FF4472C4")
+	c.Assert(styles.argbValue(xlsxColor{Theme: index(0), Tint: -0.5}), Equals, "FF808080")
+	c.Assert(styles.argbValue(xlsxColor{RGB: "FF000000", Tint: 0.5}), Equals, "FF808080")
+	c.Assert(styles.argbValue(xlsxColor{Theme: index(12)}), Equals, "")
+
+	styles.Colors = &xlsxStyleColors{IndexedColors: []xlsxColor{{RGB: "FFABCDEF"}}}
+	c.Assert(styles.argbValue(xlsxColor{Indexed: index(0)}), Equals, "FFABCDEF")
+	c.Assert(styles.argbValue(xlsxColor{Indexed: index(65)}), Equals, "FFFFFFFF")
+
+	var noStyles *xlsxStyleSheet
+	c.Assert(noStyles.argbValue(xlsxColor{Indexed: index(2)}), Equals, "FFFF0000")
+}
+
+func (s *ColorSuite) TestReadIndexedAndThemeColors(c *C) {
+	styles := newXlsxStyleSheet(nil)
+	err := xml.Unmarshal([]byte(`<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<fonts count="1"><font><sz val="11"/><color indexed="1"/><name val="Calibri"/></font></fonts>
+<fills count="1"><fill><patternFill patternType="solid"><fgColor theme="5" tint="0.5"/><bgColor indexed="64"/></patternFill></fill></fills>
+<borders count="1"><border><left style="thin"><color indexed="2"/></left><right/><top/><bottom/></border></borders>
+<cellXfs count="1"><xf fontId="0" fillId="0" borderId="0" numFmtId="0"/></cellXfs>
+<colors><indexedColors><rgbColor rgb="FF111111"/><rgbColor rgb="FF222222"/><rgbColor rgb="FF333333"/></indexedColors></colors>
+</styleSheet>`), styles)
+	c.Assert(err, IsNil)
+	style := styles.getStyle(0)
+	c.Assert(style.Font.Color, Equals, "FF222222")
+	c.Assert(style.Fill.FgColor, Equals, "FFF6BE98")
+	c.Assert(style.Fill.BgColor, Equals, "FF000000")
+	c.Assert(style.Border.LeftColor, Equals, "FF333333")
+}
@@ -0,0 +1,190 @@
+package xlsx
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Column is one column of a Sheet's cells, from a row down to the last
+// row of the Sheet, whose values can be pulled out as a slice of one
+// type, as Excel's Text to Columns does, without going over the rows by
+// hand.  A Column is made by Sheet.Column.
+type Column struct {
+	sheet *Sheet
+	col   int
+	from  int
+}
+
+// Column returns the column of the Sheet with the zero based index
+// idx, from its first row down.
+func (s *Sheet) Column(idx int) *Column {
+	return &Column{sheet: s, col: idx}
+}
+
+// From returns the column from the row with the zero based index row
+// down, such as the Schema's DataRow, to leave out the header row.
+func (c *Column) From(row int) *Column {
+	if row < 0 {
+		row = 0
+	}
+	return &Column{sheet: c.sheet, col: c.col, from: row}
+}
+
+// ColumnError is returned when cells of a Column hold values that
+// aren't of the type asked for, and gives the cells.  The values of
+// the other cells are still returned.
+type ColumnError struct {
+	Sheet string
+	// Type is the type asked for, such as "number".
+	Type string
+	// Cells are the cells in error, from the top down.
+	Cells []CellRef
+	// Values are the values of the Cells, as they are held.
+	Values []string
+}
+
+// Error returns a string value from a ColumnError in order that it
+// might comply with the builtin.error interface.
+func (e *ColumnError) Error() string {
+	if len(e.Cells) == 1 {
+		return fmt.Sprintf("sheet '%s': %s holds %q, which isn't a %s", e.Sheet, e.Cells[0], e.Values[0], e.Type)
+	}
+	return fmt.Sprintf("sheet '%s': %d cells, the first %s holding %q, aren't a %s", e.Sheet, len(e.Cells), e.Cells[0], e.Values[0], e.Type)
+}
+
+// cells calls fn with the row index and cell of each row of the
+// column, the cell being nil for rows that don't have one.
+func (c *Column) cells(fn func(row int, cell *Cell)) {
+	c.sheet.ensureLoaded()
+	for r := c.from; r < len(c.sheet.Rows); r++ {
+		fn(r, rowCell(c.sheet.Rows[r], c.col))
+	}
+}
+
+// Strings returns the text of each cell of the column, as it is
+// displayed, with "" for empty cells.
+func (c *Column) Strings() []string {
+	var values []string
+	c.cells(func(_ int, cell *Cell) {
+		values = append(values, cellText(cell))
+	})
+	return values
+}
+
+// Floats returns the number of each cell of the column.  Numbers and
+// the serial numbers of dates are taken as they are held, and text as
+// the number it spells out, such as "42" or " -1.5e3 ", which is what
+// numbers stored as text come to.  Empty cells give NaN, and so do
+// cells holding anything else, which are returned in a *ColumnError.
+func (c *Column) Floats() ([]float64, error) {
+	var values []float64
+	colErr := &ColumnError{Sheet: c.sheet.Name, Type: "number"}
+	c.cells(func(r int, cell *Cell) {
+		f, ok := math.NaN(), true
+		switch value := valueOf(cell).(type) {
+		case nil:
+		case float64:
+			f = value
+		case time.Time:
+			f, _ = cell.Float()
+		case string:
+			f, ok = parseColumnFloat(value)
+		default:
+			ok = false
+		}
+		if !ok {
+			colErr.add(r, c.col, cell)
+		}
+		values = append(values, f)
+	})
+	return values, colErr.orNil()
+}
+
+// columnTimeLayouts are the layouts text is parsed as by Times.
+var columnTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// Times returns the time of each cell of the column, in UTC.  Dates
+// are taken as they are held, numbers as serial numbers in the File's
+// date system, and text in the forms "2006-01-02", "2006-01-02
+// 15:04:05" or RFC 3339.  Empty cells give the zero time, and so do
+// cells holding anything else, which are returned in a *ColumnError.
+func (c *Column) Times() ([]time.Time, error) {
+	var values []time.Time
+	colErr := &ColumnError{Sheet: c.sheet.Name, Type: "time"}
+	c.cells(func(r int, cell *Cell) {
+		var t time.Time
+		ok := true
+		switch value := valueOf(cell).(type) {
+		case nil:
+		case time.Time:
+			t = value
+		case float64:
+			var err error
+			t, err = TimeFromExcelSerial(value, cell.inDate1904(), cell.datePolicy())
+			ok = err == nil
+			if ok {
+				t = t.Round(time.Millisecond)
+			}
+		case string:
+			t, ok = parseColumnTime(value)
+		default:
+			ok = false
+		}
+		if !ok {
+			colErr.add(r, c.col, cell)
+			t = time.Time{}
+		}
+		values = append(values, t)
+	})
+	return values, colErr.orNil()
+}
+
+// parseColumnFloat returns the number text spells out.  ParseFloat
+// also accepts "Inf", "NaN" and hexadecimal, none of which a column
+// of numbers stored as text would mean.
+func parseColumnFloat(text string) (float64, bool) {
+	text = strings.TrimSpace(text)
+	if strings.ContainsAny(text, "xXnNiI_") {
+		return math.NaN(), false
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return math.NaN(), false
+	}
+	return f, true
+}
+
+// parseColumnTime returns the time text spells out, in one of the
+// columnTimeLayouts.
+func parseColumnTime(text string) (time.Time, bool) {
+	text = strings.TrimSpace(text)
+	for _, layout := range columnTimeLayouts {
+		if t, err := time.Parse(layout, text); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// add records that the cell at row r and column col is in error.
+func (e *ColumnError) add(r, col int, cell *Cell) {
+	e.Cells = append(e.Cells, CellRef{Col: col, Row: r})
+	e.Values = append(e.Values, cell.Value)
+}
+
+// orNil returns the error, or nil if no cells are in error.
+func (e *ColumnError) orNil() error {
+	if len(e.Cells) == 0 {
+		return nil
+	}
+	return e
+}
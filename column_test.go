@@ -0,0 +1,76 @@
+package xlsx
+
+import (
+	"math"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type ColumnSuite struct{}
+
+var _ = Suite(&ColumnSuite{})
+
+func makeColumnSheet(c *C) *Sheet {
+	f := NewFile()
+	sheet, err := f.AddSheet("Data")
+	c.Assert(err, IsNil)
+	sheet.Cell(0, 0).SetString("ID")
+	sheet.Cell(0, 1).SetString("When")
+	sheet.Cell(1, 0).SetString(" 00042 ")
+	sheet.Cell(1, 1).SetDate(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+	sheet.Cell(2, 0).SetFloat(1.5)
+	sheet.Cell(2, 1).SetString("2024-03-02 12:30:00")
+	sheet.Cell(3, 0).SetString("n/a")
+	sheet.Cell(3, 1).SetFloat(45352.5)
+	sheet.Cell(4, 1).SetBool(true)
+	sheet.Cell(5, 0).SetString("NaN")
+	return sheet
+}
+
+func (s *ColumnSuite) TestStrings(c *C) {
+	sheet := makeColumnSheet(c)
+	c.Assert(sheet.Column(0).Strings(), DeepEquals, []string{"ID", " 00042 ", "1.5", "n/a", "", "NaN"})
+	c.Assert(sheet.Column(0).From(4).Strings(), DeepEquals, []string{"", "NaN"})
+	c.Assert(sheet.Column(3).Strings(), DeepEquals, []string{"", "", "", "", "", ""})
+	c.Assert(sheet.Column(0).From(10).Strings(), HasLen, 0)
+}
+
+func (s *ColumnSuite) TestFloats(c *C) {
+	sheet := makeColumnSheet(c)
+	floats, err := sheet.Column(0).From(1).Floats()
+	c.Assert(err, ErrorMatches, `sheet 'Data': 2 cells, the first A4 holding "n/a", aren't a number`)
+	colErr := err.(*ColumnError)
+	c.Assert(colErr.Cells, DeepEquals, []CellRef{{Col: 0, Row: 3}, {Col: 0, Row: 5}})
+	c.Assert(colErr.Values, DeepEquals, []string{"n/a", "NaN"})
+	c.Assert(floats, HasLen, 5)
+	c.Assert(floats[:2], DeepEquals, []float64{42, 1.5})
+	for _, i := range []int{2, 3, 4} {
+		c.Assert(math.IsNaN(floats[i]), Equals, true)
+	}
+
+	// Dates are taken as their serial numbers.
+	floats, err = sheet.Column(1).From(1).Floats()
+	c.Assert(err, ErrorMatches, `sheet 'Data': 2 cells, the first B3 holding "2024-03-02 12:30:00", aren't a number`)
+	c.Assert(floats[0], Equals, 45352.0)
+	c.Assert(floats[2], Equals, 45352.5)
+}
+
+func (s *ColumnSuite) TestTimes(c *C) {
+	sheet := makeColumnSheet(c)
+	times, err := sheet.Column(1).From(1).Times()
+	c.Assert(err, ErrorMatches, `sheet 'Data': B5 holds "1", which isn't a time`)
+	c.Assert(err.(*ColumnError).Cells, DeepEquals, []CellRef{{Col: 1, Row: 4}})
+	c.Assert(times, DeepEquals, []time.Time{
+		time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 2, 12, 30, 0, 0, time.UTC),
+		time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		{},
+		{},
+	})
+
+	sheet.Cell(4, 1).SetString("")
+	times, err = sheet.Column(1).From(1).Times()
+	c.Assert(err, IsNil)
+	c.Assert(times, HasLen, 5)
+}
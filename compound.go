@@ -0,0 +1,217 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// The sector numbers of OLE2 compound documents that aren't sectors.
+const (
+	compoundEndOfChain = 0xFFFFFFFE
+	compoundFreeSector = 0xFFFFFFFF
+)
+
+// compoundFile is an OLE2 compound document, such as a legacy XLS file
+// or a password protected XLSX one: a little file system of streams,
+// stored in sectors of the file that chain into each other.
+type compoundFile struct {
+	r          io.ReaderAt
+	size       int64
+	sectorSize int
+	fat        []uint32
+	// miniFAT chains the small sectors of miniStream, which holds
+	// the streams shorter than miniCutoff.
+	miniFAT        []uint32
+	miniStream     []byte
+	miniSectorSize int
+	miniCutoff     uint64
+	entries        []compoundEntry
+}
+
+// compoundEntry is the directory entry of a stream or of a storage,
+// which is a folder of streams.
+type compoundEntry struct {
+	name   string
+	kind   byte
+	start  uint32
+	length uint64
+}
+
+const (
+	compoundStream = 2
+	compoundRoot   = 5
+)
+
+// readCompoundFile reads the directory of the compound document of the
+// given size that r reads.
+func readCompoundFile(r io.ReaderAt, size int64) (*compoundFile, error) {
+	header := make([]byte, 512)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("reading compound document header: %s", err)
+	}
+	if !bytes.Equal(header[:8], oleSignature) {
+		return nil, fmt.Errorf("not a compound document")
+	}
+	cf := &compoundFile{r: r, size: size}
+	sectorShift := binary.LittleEndian.Uint16(header[30:])
+	miniSectorShift := binary.LittleEndian.Uint16(header[32:])
+	if sectorShift != 9 && sectorShift != 12 || miniSectorShift >= sectorShift {
+		return nil, fmt.Errorf("compound document has sectors of 2^%d bytes", sectorShift)
+	}
+	cf.sectorSize = 1 << sectorShift
+	cf.miniSectorSize = 1 << miniSectorShift
+	cf.miniCutoff = uint64(binary.LittleEndian.Uint32(header[56:]))
+
+	// The sectors of the FAT are listed by the DIFAT, which starts in
+	// the header and goes on in sectors of its own.
+	var fatSectors []uint32
+	for i := 0; i < 109; i++ {
+		fatSectors = append(fatSectors, binary.LittleEndian.Uint32(header[76+4*i:]))
+	}
+	difat := binary.LittleEndian.Uint32(header[68:])
+	for n := 0; difat != compoundEndOfChain && difat != compoundFreeSector; n++ {
+		if n > cf.sectorCount() {
+			return nil, fmt.Errorf("compound document's DIFAT loops")
+		}
+		sector, err := cf.sector(difat)
+		if err != nil {
+			return nil, err
+		}
+		last := len(sector) - 4
+		for i := 0; i < last; i += 4 {
+			fatSectors = append(fatSectors, binary.LittleEndian.Uint32(sector[i:]))
+		}
+		difat = binary.LittleEndian.Uint32(sector[last:])
+	}
+	fatCount := int(binary.LittleEndian.Uint32(header[44:]))
+	if fatCount > len(fatSectors) {
+		return nil, fmt.Errorf("compound document's FAT is missing sectors")
+	}
+	for _, n := range fatSectors[:fatCount] {
+		sector, err := cf.sector(n)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < len(sector); i += 4 {
+			cf.fat = append(cf.fat, binary.LittleEndian.Uint32(sector[i:]))
+		}
+	}
+
+	directory, err := cf.chain(cf.fat, binary.LittleEndian.Uint32(header[48:]), cf.sector)
+	if err != nil {
+		return nil, fmt.Errorf("reading compound document directory: %s", err)
+	}
+	for i := 0; i+128 <= len(directory); i += 128 {
+		entry := directory[i : i+128]
+		nameLength := int(binary.LittleEndian.Uint16(entry[64:]))
+		if nameLength > 64 {
+			nameLength = 64
+		}
+		units := make([]uint16, 0, nameLength/2)
+		for j := 0; j+1 < nameLength; j += 2 {
+			if unit := binary.LittleEndian.Uint16(entry[j:]); unit != 0 {
+				units = append(units, unit)
+			}
+		}
+		length := binary.LittleEndian.Uint64(entry[120:])
+		if sectorShift == 9 {
+			// Version 3 documents may leave junk in the high
+			// half.
+			length &= 0xFFFFFFFF
+		}
+		cf.entries = append(cf.entries, compoundEntry{
+			name:   string(utf16.Decode(units)),
+			kind:   entry[66],
+			start:  binary.LittleEndian.Uint32(entry[116:]),
+			length: length,
+		})
+	}
+	if len(cf.entries) == 0 || cf.entries[0].kind != compoundRoot {
+		return nil, fmt.Errorf("compound document has no root entry")
+	}
+
+	miniFAT, err := cf.chain(cf.fat, binary.LittleEndian.Uint32(header[60:]), cf.sector)
+	if err != nil {
+		return nil, fmt.Errorf("reading compound document mini FAT: %s", err)
+	}
+	for i := 0; i+4 <= len(miniFAT); i += 4 {
+		cf.miniFAT = append(cf.miniFAT, binary.LittleEndian.Uint32(miniFAT[i:]))
+	}
+	root := cf.entries[0]
+	if cf.miniStream, err = cf.chain(cf.fat, root.start, cf.sector); err != nil {
+		return nil, fmt.Errorf("reading compound document mini stream: %s", err)
+	}
+	return cf, nil
+}
+
+// stream returns the content of the stream called name, which is
+// matched regardless of case, as names are in compound documents.
+func (cf *compoundFile) stream(name string) ([]byte, bool, error) {
+	for _, entry := range cf.entries {
+		if entry.kind != compoundStream || !strings.EqualFold(entry.name, name) {
+			continue
+		}
+		var data []byte
+		var err error
+		if entry.length < cf.miniCutoff {
+			data, err = cf.chain(cf.miniFAT, entry.start, cf.miniSector)
+		} else {
+			data, err = cf.chain(cf.fat, entry.start, cf.sector)
+		}
+		if err != nil {
+			return nil, true, fmt.Errorf("reading stream %s: %s", entry.name, err)
+		}
+		if uint64(len(data)) < entry.length {
+			return nil, true, fmt.Errorf("stream %s is cut short", entry.name)
+		}
+		return data[:entry.length], true, nil
+	}
+	return nil, false, nil
+}
+
+// chain returns the content of the sectors that fat chains together
+// from start, read with sector.
+func (cf *compoundFile) chain(fat []uint32, start uint32, sector func(uint32) ([]byte, error)) ([]byte, error) {
+	var data []byte
+	for n := start; n != compoundEndOfChain && n != compoundFreeSector; n = fat[n] {
+		if int(n) >= len(fat) || len(data) > int(cf.size) {
+			return nil, fmt.Errorf("sector %d is out of place", n)
+		}
+		content, err := sector(n)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, content...)
+	}
+	return data, nil
+}
+
+// sectorCount returns how many sectors the file has room for.
+func (cf *compoundFile) sectorCount() int {
+	return int(cf.size / int64(cf.sectorSize))
+}
+
+func (cf *compoundFile) sector(n uint32) ([]byte, error) {
+	if (int64(n)+1)*int64(cf.sectorSize) >= cf.size {
+		return nil, fmt.Errorf("sector %d is past the end of the file", n)
+	}
+	sector := make([]byte, cf.sectorSize)
+	_, err := cf.r.ReadAt(sector, (int64(n)+1)*int64(cf.sectorSize))
+	if err == io.EOF {
+		// The last sector may be cut short.
+		err = nil
+	}
+	return sector, err
+}
+
+func (cf *compoundFile) miniSector(n uint32) ([]byte, error) {
+	offset := int(n) * cf.miniSectorSize
+	if offset+cf.miniSectorSize > len(cf.miniStream) {
+		return nil, fmt.Errorf("mini sector %d is past the end of the mini stream", n)
+	}
+	return cf.miniStream[offset : offset+cf.miniSectorSize], nil
+}
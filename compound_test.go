@@ -0,0 +1,193 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"unicode/utf16"
+
+	. "gopkg.in/check.v1"
+)
+
+type CompoundSuite struct{}
+
+var _ = Suite(&CompoundSuite{})
+
+// makeCompoundFile returns a version 3 compound document holding the
+// given streams, in the order given, with those of less than 4096 bytes
+// in the mini stream.
+func makeCompoundFile(names []string, streams map[string][]byte) []byte {
+	const sectorSize, miniSectorSize, noStream = 512, 64, 0xFFFFFFFF
+	sectors := func(n, size int) int { return (n + size - 1) / size }
+
+	var miniStream []byte
+	var miniFAT []uint32
+	var big [][]byte
+	starts := make([]uint32, len(names))
+	for i, name := range names {
+		data := streams[name]
+		if len(data) >= 4096 {
+			big = append(big, data)
+			continue
+		}
+		starts[i] = uint32(len(miniStream) / miniSectorSize)
+		n := sectors(len(data), miniSectorSize)
+		for j := 1; j < n; j++ {
+			miniFAT = append(miniFAT, starts[i]+uint32(j))
+		}
+		if n > 0 {
+			miniFAT = append(miniFAT, compoundEndOfChain)
+		} else {
+			starts[i] = compoundEndOfChain
+		}
+		miniStream = append(miniStream, data...)
+		miniStream = append(miniStream, make([]byte, n*miniSectorSize-len(data))...)
+	}
+
+	dirSectors := sectors((len(names)+1)*128, sectorSize)
+	miniFATSectors := sectors(len(miniFAT)*4, sectorSize)
+	miniStreamSectors := sectors(len(miniStream), sectorSize)
+	bigSectors := 0
+	for _, data := range big {
+		bigSectors += sectors(len(data), sectorSize)
+	}
+	used := dirSectors + miniFATSectors + miniStreamSectors + bigSectors
+	fatSectors := 1
+	for sectors((fatSectors+used)*4, sectorSize) > fatSectors {
+		fatSectors++
+	}
+
+	var fat []uint32
+	for i := 0; i < fatSectors; i++ {
+		fat = append(fat, 0xFFFFFFFD)
+	}
+	chain := func(n int) uint32 {
+		if n == 0 {
+			return compoundEndOfChain
+		}
+		start := uint32(len(fat))
+		for i := 1; i < n; i++ {
+			fat = append(fat, start+uint32(i))
+		}
+		fat = append(fat, compoundEndOfChain)
+		return start
+	}
+	dirStart := chain(dirSectors)
+	miniFATStart := chain(miniFATSectors)
+	miniStreamStart := chain(miniStreamSectors)
+	b := 0
+	for i, name := range names {
+		if len(streams[name]) >= 4096 {
+			starts[i] = chain(sectors(len(big[b]), sectorSize))
+			b++
+		}
+	}
+	for len(fat)%(sectorSize/4) != 0 {
+		fat = append(fat, compoundFreeSector)
+	}
+
+	var out bytes.Buffer
+	le := func(v interface{}) { binary.Write(&out, binary.LittleEndian, v) }
+	header := make([]byte, 512)
+	copy(header, oleSignature)
+	binary.LittleEndian.PutUint16(header[24:], 0x3E)
+	binary.LittleEndian.PutUint16(header[26:], 3)
+	binary.LittleEndian.PutUint16(header[28:], 0xFFFE)
+	binary.LittleEndian.PutUint16(header[30:], 9)
+	binary.LittleEndian.PutUint16(header[32:], 6)
+	binary.LittleEndian.PutUint32(header[44:], uint32(fatSectors))
+	binary.LittleEndian.PutUint32(header[48:], dirStart)
+	binary.LittleEndian.PutUint32(header[56:], 4096)
+	binary.LittleEndian.PutUint32(header[60:], miniFATStart)
+	binary.LittleEndian.PutUint32(header[64:], uint32(miniFATSectors))
+	binary.LittleEndian.PutUint32(header[68:], compoundEndOfChain)
+	for i := 0; i < 109; i++ {
+		sector := uint32(compoundFreeSector)
+		if i < fatSectors {
+			sector = uint32(i)
+		}
+		binary.LittleEndian.PutUint32(header[76+4*i:], sector)
+	}
+	out.Write(header)
+	le(fat)
+
+	entry := func(name string, kind byte, child, right, start uint32, size int) {
+		e := make([]byte, 128)
+		units := utf16.Encode([]rune(name))
+		for i, u := range units {
+			binary.LittleEndian.PutUint16(e[2*i:], u)
+		}
+		binary.LittleEndian.PutUint16(e[64:], uint16(2*len(units)+2))
+		e[66] = kind
+		e[67] = 1
+		binary.LittleEndian.PutUint32(e[68:], noStream)
+		binary.LittleEndian.PutUint32(e[72:], right)
+		binary.LittleEndian.PutUint32(e[76:], child)
+		binary.LittleEndian.PutUint32(e[116:], start)
+		binary.LittleEndian.PutUint32(e[120:], uint32(size))
+		out.Write(e)
+	}
+	entry("Root Entry", compoundRoot, 1, noStream, miniStreamStart, len(miniStream))
+	for i, name := range names {
+		right := uint32(i + 2)
+		if i == len(names)-1 {
+			right = noStream
+		}
+		entry(name, compoundStream, noStream, right, starts[i], len(streams[name]))
+	}
+	pad := func() {
+		if n := (out.Len() - 512) % sectorSize; n != 0 {
+			out.Write(make([]byte, sectorSize-n))
+		}
+	}
+	pad()
+	le(miniFAT)
+	pad()
+	out.Write(miniStream)
+	pad()
+	for _, data := range big {
+		out.Write(data)
+		pad()
+	}
+	return out.Bytes()
+}
+
+func (s *CompoundSuite) TestStreams(c *C) {
+	long := bytes.Repeat([]byte("0123456789"), 700)
+	short := []byte("Workbook stream shorter than a sector, and than two mini sectors of sixty four bytes")
+	data := makeCompoundFile([]string{"Short", "Long", "Empty"}, map[string][]byte{
+		"Short": short,
+		"Long":  long,
+		"Empty": nil,
+	})
+	cf, err := readCompoundFile(bytes.NewReader(data), int64(len(data)))
+	c.Assert(err, IsNil)
+
+	stream, ok, err := cf.stream("short")
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, true)
+	c.Assert(string(stream), Equals, string(short))
+	stream, ok, err = cf.stream("Long")
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, true)
+	c.Assert(bytes.Equal(stream, long), Equals, true)
+	stream, ok, err = cf.stream("Empty")
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, true)
+	c.Assert(stream, HasLen, 0)
+	_, ok, err = cf.stream("Missing")
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, false)
+}
+
+func (s *CompoundSuite) TestCorrupt(c *C) {
+	data := makeCompoundFile([]string{"Long"}, map[string][]byte{"Long": make([]byte, 5000)})
+	_, err := readCompoundFile(bytes.NewReader(data[:512]), 512)
+	c.Assert(err, ErrorMatches, "sector 0 is past the end of the file")
+
+	cf, err := readCompoundFile(bytes.NewReader(data), int64(len(data)))
+	c.Assert(err, IsNil)
+	// A stream whose chain loops back on itself.
+	cf.fat[cf.entries[1].start+1] = cf.entries[1].start
+	_, _, err = cf.stream("Long")
+	c.Assert(err, ErrorMatches, "reading stream Long: sector .* is out of place")
+}
@@ -0,0 +1,135 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"io"
+	"strings"
+	"time"
+)
+
+// partModified is the modification time of every part of a package:
+// the start of 1980, the earliest a zip archive can record, as the
+// time a File is written shouldn't change what is written.
+var partModified = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// CompressionStore is the compression level of parts stored in the package as
+// they are, without being compressed.  It is quickest to write, and
+// costs nothing for media that is already compressed.
+const CompressionStore = flate.HuffmanOnly - 1
+
+// WriteOption configures how File.Write and File.Save write a File.
+type WriteOption func(*writeOptions)
+
+// writeOptions holds the settings made by the WriteOptions given to
+// File.Write.
+type writeOptions struct {
+	level   int
+	parts   []partLevel
+	signers []packageSigner
+}
+
+// partLevel is a compression level for the parts that match.
+type partLevel struct {
+	level int
+	match func(name string) bool
+}
+
+func newWriteOptions(options []WriteOption) *writeOptions {
+	o := &writeOptions{level: flate.DefaultCompression}
+	for _, option := range options {
+		option(o)
+	}
+	return o
+}
+
+// levelFor returns the compression level of the part called name.
+func (o *writeOptions) levelFor(name string) int {
+	for i := len(o.parts) - 1; i >= 0; i-- {
+		if o.parts[i].match(name) {
+			return o.parts[i].level
+		}
+	}
+	return o.level
+}
+
+// CompressionLevel sets the compression level of every part without
+// one of its own given by PartCompression.  The level is either
+// CompressionStore or one of those of compress/flate, from
+// flate.HuffmanOnly to flate.BestCompression; flate.BestSpeed makes
+// large files much quicker to write.  By default flate.DefaultCompression is used.
+func CompressionLevel(level int) WriteOption {
+	return func(o *writeOptions) {
+		o.level = level
+	}
+}
+
+// PartCompression sets the compression level of the parts whose names,
+// such as "xl/worksheets/sheet1.xml", match reports true for.  If more
+// than one PartCompression matches a part, the last one given wins.
+func PartCompression(level int, match func(name string) bool) WriteOption {
+	return func(o *writeOptions) {
+		o.parts = append(o.parts, partLevel{level: level, match: match})
+	}
+}
+
+// StoreMedia stores the images and other media of the File without
+// compressing them again, as they nearly always are already.
+func StoreMedia() WriteOption {
+	return PartCompression(CompressionStore, func(name string) bool {
+		return strings.HasPrefix(name, "xl/media/")
+	})
+}
+
+// partWriter writes the parts of a package into a zip archive, each
+// compressed as the writeOptions say.
+type partWriter struct {
+	zip     *zip.Writer
+	options *writeOptions
+	// level is that of the part being written; the compressor
+	// registered with zip is called as each part is created.
+	level   int
+	flaters map[int]*flate.Writer
+}
+
+func newPartWriter(w io.Writer, options *writeOptions) *partWriter {
+	p := &partWriter{
+		zip:     zip.NewWriter(w),
+		options: options,
+		flaters: make(map[int]*flate.Writer),
+	}
+	p.zip.RegisterCompressor(zip.Deflate, p.compressor)
+	return p
+}
+
+// compressor deflates a part at the level it is being written with,
+// reusing the flate.Writer for that level, as parts are written one
+// after another.
+func (p *partWriter) compressor(w io.Writer) (io.WriteCloser, error) {
+	if flater, ok := p.flaters[p.level]; ok {
+		flater.Reset(w)
+		return flater, nil
+	}
+	flater, err := flate.NewWriter(w, p.level)
+	if err != nil {
+		return nil, err
+	}
+	p.flaters[p.level] = flater
+	return flater, nil
+}
+
+// create starts the part called name, returning the io.Writer its
+// content is to be written to.
+func (p *partWriter) create(name string) (io.Writer, error) {
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: partModified}
+	p.level = p.options.levelFor(name)
+	if p.level == CompressionStore {
+		header.Method = zip.Store
+	}
+	return p.zip.CreateHeader(header)
+}
+
+// close finishes the archive.
+func (p *partWriter) close() error {
+	return p.zip.Close()
+}
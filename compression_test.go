@@ -0,0 +1,74 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type CompressionSuite struct{}
+
+var _ = Suite(&CompressionSuite{})
+
+func makeCompressionFile(c *C) *File {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	for i := 0; i < 100; i++ {
+		sheet.AddRow().AddCell().SetString(strings.Repeat("text", 10))
+	}
+	c.Assert(sheet.SetBackground(makePNG(c, 20, 10), IMAGE_TYPE_PNG), IsNil)
+	return file
+}
+
+// writeCompressed writes the file with the options and returns how each
+// part was compressed, by name, after checking it reads back.
+func writeCompressed(c *C, file *File, options ...WriteOption) map[string]uint16 {
+	var buf bytes.Buffer
+	c.Assert(file.Write(&buf, options...), IsNil)
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	methods := make(map[string]uint16)
+	for _, f := range r.File {
+		methods[f.Name] = f.Method
+	}
+	written, err := OpenBinary(buf.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(written.Sheets[0].Rows, HasLen, 100)
+	c.Assert(written.Sheets[0].Background, NotNil)
+	return methods
+}
+
+func (s *CompressionSuite) TestStoreMedia(c *C) {
+	file := makeCompressionFile(c)
+	methods := writeCompressed(c, file, CompressionLevel(flate.BestSpeed), StoreMedia())
+	c.Assert(methods["xl/media/image1.png"], Equals, zip.Store)
+	c.Assert(methods["xl/worksheets/sheet1.xml"], Equals, zip.Deflate)
+	c.Assert(methods["xl/styles.xml"], Equals, zip.Deflate)
+}
+
+func (s *CompressionSuite) TestStoreEverything(c *C) {
+	file := makeCompressionFile(c)
+	methods := writeCompressed(c, file, CompressionLevel(CompressionStore))
+	for name, method := range methods {
+		c.Assert(method, Equals, zip.Store, Commentf("part %s", name))
+	}
+
+	// The last PartCompression to match a part wins.
+	methods = writeCompressed(c, file, CompressionLevel(CompressionStore),
+		PartCompression(flate.BestCompression, func(name string) bool {
+			return strings.HasPrefix(name, "xl/")
+		}),
+		StoreMedia())
+	c.Assert(methods["[Content_Types].xml"], Equals, zip.Store)
+	c.Assert(methods["xl/worksheets/sheet1.xml"], Equals, zip.Deflate)
+	c.Assert(methods["xl/media/image1.png"], Equals, zip.Store)
+}
+
+func (s *CompressionSuite) TestInvalidLevel(c *C) {
+	file := makeCompressionFile(c)
+	var buf bytes.Buffer
+	c.Assert(file.Write(&buf, CompressionLevel(12)), ErrorMatches, "flate: invalid compression level 12.*")
+}
@@ -0,0 +1,194 @@
+package xlsx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CopyOptions choose what CopyRange and MoveRange carry over to the
+// destination besides the values and formulas of the cells.
+type CopyOptions struct {
+	// Styles copies the style and number format of each cell, as
+	// well as its value.  Otherwise the destination cells keep
+	// their own.
+	Styles bool
+	// AdjustFormulas shifts the relative references in the formulas
+	// by as far as the cells move, as pasting them in Excel does,
+	// with those moved off the worksheet becoming #REF!.  Otherwise
+	// the formulas are copied as they are.
+	AdjustFormulas bool
+	// Merges merges the cells at the destination as those at the
+	// source are, with each merged area whose top left cell is in
+	// the range extending as far from its new position as it did
+	// from its old one.  Otherwise the destination isn't merged.
+	Merges bool
+}
+
+// CopyRange copies the cells of the range src of the Sheet, such as
+// "A1:C10", to the cells starting at dst, such as "E1".  The
+// destination may be on another sheet of the same File, as in
+// "Summary!E1", and may overlap the source.  Cells of the source that
+// don't exist empty the cells they are copied to.
+func (s *Sheet) CopyRange(src, dst string, options CopyOptions) error {
+	return s.copyRange(src, dst, options, false)
+}
+
+// MoveRange moves the cells of the range src of the Sheet to the cells
+// starting at dst, as CopyRange copies them, leaving the cells of the
+// source that aren't moved over empty.  Formulas elsewhere that refer
+// to the cells moved are left as they are.
+func (s *Sheet) MoveRange(src, dst string, options CopyOptions) error {
+	return s.copyRange(src, dst, options, true)
+}
+
+// copyRange copies the range src to dst, emptying the source first if
+// move is set.
+func (s *Sheet) copyRange(src, dst string, options CopyOptions, move bool) error {
+	if err := s.Load(); err != nil {
+		return err
+	}
+	from, err := ParseCellRange(src)
+	if err != nil {
+		return err
+	}
+	if from.Sheet != "" && from.Sheet != s.Name {
+		return fmt.Errorf("range '%s' is not on sheet '%s'", src, s.Name)
+	}
+	to, err := ParseCellRange(dst)
+	if err != nil {
+		return err
+	}
+	target := s
+	if to.Sheet != "" && to.Sheet != s.Name {
+		if s.File == nil || s.File.Sheet[to.Sheet] == nil {
+			return fmt.Errorf("no sheet called '%s' to copy to", to.Sheet)
+		}
+		target = s.File.Sheet[to.Sheet]
+		if err := target.Load(); err != nil {
+			return err
+		}
+	}
+	if to.Start.Col+from.Cols() > SheetColLimit || to.Start.Row+from.Rows() > SheetRowLimit {
+		return fmt.Errorf("range '%s' copied to '%s' runs off the worksheet", src, dst)
+	}
+	dx, dy := to.Start.Col-from.Start.Col, to.Start.Row-from.Start.Row
+
+	// Take the cells of the source before changing any, as the
+	// destination may overlap it.
+	cells := make([][]*Cell, from.Rows())
+	for i := range cells {
+		cells[i] = make([]*Cell, from.Cols())
+		r := from.Start.Row + i
+		if r >= len(s.Rows) {
+			continue
+		}
+		for j := range cells[i] {
+			if cell := rowCell(s.Rows[r], from.Start.Col+j); cell != nil {
+				copied := *cell
+				cells[i][j] = &copied
+			}
+		}
+	}
+	if move {
+		for i := range cells {
+			for j, cell := range cells[i] {
+				if cell != nil {
+					s.Rows[from.Start.Row+i].Cells[from.Start.Col+j].clear(options.Styles)
+				}
+			}
+		}
+	}
+
+	for i := range cells {
+		r := to.Start.Row + i
+		for j, source := range cells[i] {
+			c := to.Start.Col + j
+			if source == nil {
+				if r < len(target.Rows) {
+					if cell := rowCell(target.Rows[r], c); cell != nil {
+						cell.clear(options.Styles)
+					}
+				}
+				continue
+			}
+			cell := target.Cell(r, c)
+			cell.Value = source.Value
+			cell.formula = source.formula
+			cell.cellType = source.cellType
+			cell.resultType = source.resultType
+			if options.AdjustFormulas && cell.formula != "" {
+				cell.formula = pasteFormula(cell.formula, dx, dy)
+			}
+			if options.Styles {
+				cell.style = nil
+				if source.style != nil {
+					style := *source.style
+					cell.style = &style
+				}
+				cell.NumFmt = source.NumFmt
+			}
+			cell.HMerge, cell.VMerge = 0, 0
+			if options.Merges {
+				cell.HMerge, cell.VMerge = source.HMerge, source.VMerge
+			}
+		}
+	}
+	return nil
+}
+
+// pasteFormula returns the formula with its relative references moved
+// dx columns and dy rows, as Excel moves them when a formula is pasted.
+// A reference moved off the worksheet becomes #REF!, as does a range
+// with either end moved off it.
+func pasteFormula(formula string, dx, dy int) string {
+	shift := func(ref CellRef) (CellRef, bool) {
+		if !ref.AbsCol {
+			ref.Col += dx
+		}
+		if !ref.AbsRow {
+			ref.Row += dy
+		}
+		return ref, ref.Col >= 0 && ref.Col < SheetColLimit && ref.Row >= 0 && ref.Row < SheetRowLimit
+	}
+	// The references are found once to see which are lost, with the
+	// start of a range lost along with its end, and once to replace
+	// them.
+	var lost []bool
+	mapFormulaRefs(formula, func(ref CellRef, rangeEnd bool) CellRef {
+		_, ok := shift(ref)
+		if rangeEnd && len(lost) > 0 {
+			lost[len(lost)-1] = lost[len(lost)-1] || !ok
+			ok = !lost[len(lost)-1]
+		}
+		lost = append(lost, !ok)
+		return ref
+	})
+	n := 0
+	shifted := mapFormulaRefText(formula, func(ref CellRef, rangeEnd bool) string {
+		n++
+		if !lost[n-1] {
+			ref, _ = shift(ref)
+			return ref.String()
+		}
+		if rangeEnd {
+			return ""
+		}
+		// The NUL marks the start of a range lost, whose ":" is
+		// dropped along with its end.
+		return "#REF!\x00"
+	})
+	return strings.Replace(strings.Replace(shifted, "\x00:", "", -1), "\x00", "", -1)
+}
+
+// clear empties the cell, along with its style if styles is set.
+func (c *Cell) clear(styles bool) {
+	c.Value = ""
+	c.formula = ""
+	c.cellType = CellTypeString
+	c.resultType = ""
+	c.HMerge, c.VMerge = 0, 0
+	if styles {
+		c.style = nil
+		c.NumFmt = ""
+	}
+}
@@ -0,0 +1,83 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type CopyRangeSuite struct{}
+
+var _ = Suite(&CopyRangeSuite{})
+
+func makeCopyRangeSheet(c *C) *Sheet {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Report")
+	c.Assert(sheet.SetRange("A1", [][]interface{}{
+		{"name", "count"},
+		{"one", 1},
+		{"two", 2},
+	}), IsNil)
+	sheet.Cell(3, 1).SetFormula("SUM(B2:B3)*$B$1")
+	bold := NewStyle()
+	bold.Font.Bold = true
+	sheet.Cell(0, 0).SetStyle(bold)
+	sheet.Cell(0, 0).Merge(1, 0)
+	return sheet
+}
+
+func (s *CopyRangeSuite) TestCopyRangeValuesOnly(c *C) {
+	sheet := makeCopyRangeSheet(c)
+	c.Assert(sheet.CopyRange("A1:B4", "D2", CopyOptions{}), IsNil)
+	value, _ := sheet.Value("D2")
+	c.Assert(value, Equals, "name")
+	value, _ = sheet.Value("E4")
+	c.Assert(value, Equals, 2.0)
+	c.Assert(sheet.Cell(4, 4).Formula(), Equals, "SUM(B2:B3)*$B$1")
+	c.Assert(sheet.Cell(1, 3).GetStyle().Font.Bold, Equals, false)
+	c.Assert(sheet.Cell(1, 3).HMerge, Equals, 0)
+	// The source is untouched.
+	value, _ = sheet.Value("A1")
+	c.Assert(value, Equals, "name")
+}
+
+func (s *CopyRangeSuite) TestCopyRangeWithOptions(c *C) {
+	sheet := makeCopyRangeSheet(c)
+	options := CopyOptions{Styles: true, AdjustFormulas: true, Merges: true}
+	c.Assert(sheet.CopyRange("A1:B4", "D2", options), IsNil)
+	c.Assert(sheet.Cell(4, 4).Formula(), Equals, "SUM(E3:E4)*$B$1")
+	c.Assert(sheet.Cell(1, 3).GetStyle().Font.Bold, Equals, true)
+	c.Assert(sheet.Cell(1, 3).GetStyle(), Not(Equals), sheet.Cell(0, 0).GetStyle())
+	c.Assert(sheet.Cell(1, 3).HMerge, Equals, 1)
+
+	// Across sheets, and overlapping within one.
+	summary, _ := sheet.File.AddSheet("Summary")
+	c.Assert(sheet.CopyRange("A2:B3", "Summary!B1", options), IsNil)
+	value, _ := summary.Value("C2")
+	c.Assert(value, Equals, 2.0)
+	c.Assert(sheet.CopyRange("A1:B3", "A2", CopyOptions{}), IsNil)
+	for ref, expected := range map[string]interface{}{"A2": "name", "A3": "one", "B4": 2.0} {
+		value, _ := sheet.Value(ref)
+		c.Assert(value, Equals, expected, Commentf("cell %s", ref))
+	}
+
+	// References moved off the worksheet are lost.
+	sheet.Cell(5, 5).SetFormula("A1+B5+SUM(A1:B6)+SUM(E5:F6)+Summary!C4+$A$1")
+	c.Assert(sheet.CopyRange("F6", "B3", options), IsNil)
+	c.Assert(sheet.Cell(2, 1).Formula(), Equals, "#REF!+#REF!+SUM(#REF!)+SUM(A2:B3)+Summary!#REF!+$A$1")
+
+	c.Assert(sheet.CopyRange("A1:B2", "Missing!A1", options), ErrorMatches, "no sheet called 'Missing' to copy to")
+	c.Assert(sheet.CopyRange("Summary!A1:B2", "A1", options), ErrorMatches, "range 'Summary!A1:B2' is not on sheet 'Report'")
+	c.Assert(sheet.CopyRange("A1:B2", "XFD1", options), ErrorMatches, "range 'A1:B2' copied to 'XFD1' runs off the worksheet")
+}
+
+func (s *CopyRangeSuite) TestMoveRange(c *C) {
+	sheet := makeCopyRangeSheet(c)
+	c.Assert(sheet.MoveRange("A1:B2", "B1", CopyOptions{Styles: true, Merges: true}), IsNil)
+	for ref, expected := range map[string]interface{}{"A1": nil, "A2": nil, "B1": "name", "C1": "count", "B2": "one", "C2": 1.0} {
+		value, _ := sheet.Value(ref)
+		c.Assert(value, Equals, expected, Commentf("cell %s", ref))
+	}
+	c.Assert(sheet.Cell(0, 0).HMerge, Equals, 0)
+	c.Assert(sheet.Cell(0, 0).style, IsNil)
+	c.Assert(sheet.Cell(0, 1).HMerge, Equals, 1)
+	c.Assert(sheet.Cell(0, 1).GetStyle().Font.Bold, Equals, true)
+}
@@ -0,0 +1,647 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CustomView is a custom view of a workbook: a named set of settings,
+// such as which sheet is shown, how each sheet is zoomed and filtered
+// and how it is printed, that users switch between in Excel's Custom
+// Views dialog.  The settings of each sheet are the CustomSheetView of
+// the Sheet with the view's GUID; see Sheet.CustomView.
+type CustomView struct {
+	Name string
+	// GUID identifies the view, and the settings of each sheet for
+	// it, such as "{5A9E4C1B-0D2F-4E6A-9B3C-7F1D2E8A4B60}".
+	GUID string
+	// ActiveSheet is the name of the sheet the view shows.  Empty,
+	// or the name of no sheet, means the first.
+	ActiveSheet string
+	// Personal makes the view one of the user's own in a shared
+	// workbook.
+	Personal  bool
+	Maximized bool
+	Minimized bool
+	// XWindow and YWindow are the position of the workbook's window,
+	// and WindowWidth and WindowHeight its size, in pixels.  A zero
+	// size means a window of 1280 by 720 pixels.
+	XWindow      int
+	YWindow      int
+	WindowWidth  int
+	WindowHeight int
+	// TabRatio is the part of the width of the bottom of the window
+	// given to the sheet tabs, rather than the horizontal scroll bar,
+	// in thousandths.  Zero means 600.
+	TabRatio int
+	// ExcludePrintSettings leaves the print settings of the sheets
+	// out of the view, and ExcludeHiddenRowCol which of their rows
+	// and columns are hidden, and how they are filtered.
+	ExcludePrintSettings bool
+	ExcludeHiddenRowCol  bool
+	HideHorizontalScroll bool
+	HideVerticalScroll   bool
+	HideSheetTabs        bool
+	HideFormulaBar       bool
+	HideStatusBar        bool
+}
+
+// CustomSheetView is the settings of a Sheet for a custom view of the
+// workbook, the CustomView with the same GUID.  The zero value shows
+// the Sheet as Excel does by default, apart from its gridlines, which
+// are hidden unless ShowGridLines is set, as they are in
+// ViewSettings.
+type CustomSheetView struct {
+	GUID string
+	// ZoomScale is the zoom as a percentage, between 10 and 400.
+	// Zero means 100.
+	ZoomScale int
+	// Type is the kind of view.  The empty ViewType means
+	// ViewNormal.
+	Type ViewType
+	// TopLeftCell is the cell shown at the top left of the window,
+	// such as "A1".
+	TopLeftCell string
+	// ActiveCell and Selection are the cell the cursor is in and the
+	// range, or space separated ranges, that are selected, as they
+	// are in ViewSettings.
+	ActiveCell         string
+	Selection          string
+	Pane               *Pane
+	ShowGridLines      bool
+	HideRowColHeaders  bool
+	HideZeros          bool
+	HideOutlineSymbols bool
+	HideRuler          bool
+	ShowFormulas       bool
+	ShowPageBreaks     bool
+	// Hidden and VeryHidden hide the Sheet in the view, as Sheet's
+	// fields of the same names do.
+	Hidden     bool
+	VeryHidden bool
+	// HiddenRows and HiddenColumns record that rows or columns
+	// are hidden in the view.  Which are is given by the defined
+	// names Excel makes for the view, which are kept with the rest.
+	HiddenRows    bool
+	HiddenColumns bool
+	// FitToPage fits the Sheet to the page when it is printed, and
+	// PrintArea records that the view has a print area, which is
+	// also given by a defined name.
+	FitToPage bool
+	PrintArea bool
+	// AutoFilter is the range, such as "A1:D100", filtered in the
+	// view, and Filter records that it is filtered.  The criteria of
+	// a filter read are kept, but can't be changed.
+	AutoFilter     string
+	Filter         bool
+	FilterUnique   bool
+	ShowAutoFilter bool
+	// RowBreaks are the rows, counted from zero, that begin a new
+	// page when the Sheet is printed, and ColBreaks the columns.
+	RowBreaks []int
+	ColBreaks []int
+	// PageMargins, PrintOptions, PageSetUp and HeaderFooter are the
+	// print settings of the view, or nil for those the view leaves
+	// as they are.
+	PageMargins  *xlsxPageMargins
+	PrintOptions *xlsxPrintOptions
+	PageSetUp    *xlsxPageSetUp
+	HeaderFooter *xlsxHeaderFooter
+	// filterColumns is the content of the autoFilter element read.
+	filterColumns string
+	// pane is the pane of the selection read.
+	pane string
+}
+
+// AddCustomView adds a custom view called name to the File, showing
+// its active sheet, and gives each of its Sheets the settings for it
+// that its View has.  It returns an error if the File already has a
+// view with the name, compared without regard to case.
+func (f *File) AddCustomView(name string) (*CustomView, error) {
+	if f.CustomView(name) != nil {
+		return nil, fmt.Errorf("custom view '%s' already exists", name)
+	}
+	id, err := newGUID()
+	if err != nil {
+		return nil, err
+	}
+	view := &CustomView{Name: name, GUID: id}
+	if active := f.ActiveSheet(); active != nil {
+		view.ActiveSheet = active.Name
+	}
+	for _, sheet := range f.Sheets {
+		if err := sheet.Load(); err != nil {
+			return nil, err
+		}
+		sheet.CustomViews = append(sheet.CustomViews, CustomSheetView{
+			GUID:              id,
+			ZoomScale:         sheet.View.ZoomScale,
+			Type:              sheet.View.Type,
+			ActiveCell:        sheet.View.ActiveCell,
+			Selection:         sheet.View.Selection,
+			ShowGridLines:     sheet.View.ShowGridLines || sheet.ShowGridLines,
+			HideRowColHeaders: sheet.View.HideRowColHeaders,
+			Hidden:            sheet.Hidden,
+			VeryHidden:        sheet.VeryHidden,
+		})
+	}
+	f.CustomViews = append(f.CustomViews, view)
+	return view, nil
+}
+
+// CustomView returns the custom view of the File called name, compared
+// without regard to case, or nil if it has none.
+func (f *File) CustomView(name string) *CustomView {
+	for _, view := range f.CustomViews {
+		if strings.EqualFold(view.Name, name) {
+			return view
+		}
+	}
+	return nil
+}
+
+// RemoveCustomView removes the custom view called name from the File,
+// with the settings of its Sheets for it and the defined names Excel
+// made for it.  It returns false if the File has no such view.
+func (f *File) RemoveCustomView(name string) bool {
+	for i, view := range f.CustomViews {
+		if !strings.EqualFold(view.Name, name) {
+			continue
+		}
+		f.CustomViews = append(f.CustomViews[:i:i], f.CustomViews[i+1:]...)
+		for _, sheet := range f.Sheets {
+			sheet.ensureLoaded()
+			views := sheet.CustomViews[:0]
+			for _, v := range sheet.CustomViews {
+				if !strings.EqualFold(v.GUID, view.GUID) {
+					views = append(views, v)
+				}
+			}
+			sheet.CustomViews = views
+		}
+		prefix := customViewNamePrefix(view.GUID)
+		names := f.Names[:0]
+		for _, dn := range f.Names {
+			if !strings.HasPrefix(strings.ToUpper(dn.Name), prefix) {
+				names = append(names, dn)
+			}
+		}
+		f.Names = names
+		return true
+	}
+	return false
+}
+
+// customViewNamePrefix returns the start, in upper case, of the names
+// Excel defines for the print area, hidden rows and columns and filter
+// of the custom view with the GUID given, such as
+// "Z_5A9E4C1B_0D2F_4E6A_9B3C_7F1D2E8A4B60_.WVU.".
+func customViewNamePrefix(guid string) string {
+	guid = strings.Trim(strings.ToUpper(guid), "{}")
+	return "Z_" + strings.Replace(guid, "-", "_", -1) + "_.WVU."
+}
+
+// CustomView returns the Sheet's settings for the custom view of the
+// workbook with the GUID given, compared without regard to case, or
+// nil if it has none.
+func (s *Sheet) CustomView(guid string) *CustomSheetView {
+	s.ensureLoaded()
+	for i := range s.CustomViews {
+		if strings.EqualFold(s.CustomViews[i].GUID, guid) {
+			return &s.CustomViews[i]
+		}
+	}
+	return nil
+}
+
+// copy returns a copy of the CustomSheetView sharing nothing with it.
+func (v CustomSheetView) copy() CustomSheetView {
+	if v.Pane != nil {
+		pane := *v.Pane
+		v.Pane = &pane
+	}
+	v.RowBreaks = append([]int(nil), v.RowBreaks...)
+	v.ColBreaks = append([]int(nil), v.ColBreaks...)
+	if v.PageMargins != nil {
+		margins := *v.PageMargins
+		v.PageMargins = &margins
+	}
+	if v.PrintOptions != nil {
+		options := *v.PrintOptions
+		v.PrintOptions = &options
+	}
+	if v.PageSetUp != nil {
+		setUp := *v.PageSetUp
+		v.PageSetUp = &setUp
+	}
+	if v.HeaderFooter != nil {
+		headerFooter := *v.HeaderFooter
+		headerFooter.OddHeader = append([]xlsxOddHeader(nil), headerFooter.OddHeader...)
+		headerFooter.OddFooter = append([]xlsxOddFooter(nil), headerFooter.OddFooter...)
+		v.HeaderFooter = &headerFooter
+	}
+	return v
+}
+
+// xlsxCustomWorkbookViews directly maps the customWorkbookViews element
+// in the namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxCustomWorkbookViews struct {
+	CustomWorkbookView []xlsxCustomWorkbookView `xml:"customWorkbookView"`
+}
+
+// xlsxCustomWorkbookView directly maps the customWorkbookView element.
+type xlsxCustomWorkbookView struct {
+	Name                 string `xml:"name,attr"`
+	GUID                 string `xml:"guid,attr"`
+	PersonalView         bool   `xml:"personalView,attr,omitempty"`
+	Maximized            bool   `xml:"maximized,attr,omitempty"`
+	Minimized            bool   `xml:"minimized,attr,omitempty"`
+	IncludePrintSettings bool   `xml:"includePrintSettings,attr"`
+	IncludeHiddenRowCol  bool   `xml:"includeHiddenRowCol,attr"`
+	ShowHorizontalScroll bool   `xml:"showHorizontalScroll,attr"`
+	ShowVerticalScroll   bool   `xml:"showVerticalScroll,attr"`
+	ShowSheetTabs        bool   `xml:"showSheetTabs,attr"`
+	XWindow              int    `xml:"xWindow,attr,omitempty"`
+	YWindow              int    `xml:"yWindow,attr,omitempty"`
+	WindowWidth          int    `xml:"windowWidth,attr"`
+	WindowHeight         int    `xml:"windowHeight,attr"`
+	TabRatio             int    `xml:"tabRatio,attr"`
+	ActiveSheetId        int    `xml:"activeSheetId,attr"`
+	ShowFormulaBar       bool   `xml:"showFormulaBar,attr"`
+	ShowStatusbar        bool   `xml:"showStatusbar,attr"`
+}
+
+// UnmarshalXML decodes a customWorkbookView element, giving the
+// attributes it leaves out their default values.
+func (v *xlsxCustomWorkbookView) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type customWorkbookView xlsxCustomWorkbookView
+	view := customWorkbookView{
+		IncludePrintSettings: true,
+		IncludeHiddenRowCol:  true,
+		ShowHorizontalScroll: true,
+		ShowVerticalScroll:   true,
+		ShowSheetTabs:        true,
+		TabRatio:             600,
+		ShowFormulaBar:       true,
+		ShowStatusbar:        true,
+	}
+	if err := d.DecodeElement(&view, &start); err != nil {
+		return err
+	}
+	*v = xlsxCustomWorkbookView(view)
+	return nil
+}
+
+// xlsxCustomSheetViews directly maps the customSheetViews element in
+// the namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxCustomSheetViews struct {
+	CustomSheetView []xlsxCustomSheetView `xml:"customSheetView"`
+}
+
+// xlsxCustomSheetView directly maps the customSheetView element.
+type xlsxCustomSheetView struct {
+	GUID           string                    `xml:"guid,attr"`
+	Scale          int                       `xml:"scale,attr"`
+	ShowPageBreaks bool                      `xml:"showPageBreaks,attr,omitempty"`
+	ShowFormulas   bool                      `xml:"showFormulas,attr,omitempty"`
+	ShowGridLines  bool                      `xml:"showGridLines,attr"`
+	ShowRowCol     bool                      `xml:"showRowCol,attr"`
+	OutlineSymbols bool                      `xml:"outlineSymbols,attr"`
+	ZeroValues     bool                      `xml:"zeroValues,attr"`
+	FitToPage      bool                      `xml:"fitToPage,attr,omitempty"`
+	PrintArea      bool                      `xml:"printArea,attr,omitempty"`
+	Filter         bool                      `xml:"filter,attr,omitempty"`
+	ShowAutoFilter bool                      `xml:"showAutoFilter,attr,omitempty"`
+	HiddenRows     bool                      `xml:"hiddenRows,attr,omitempty"`
+	HiddenColumns  bool                      `xml:"hiddenColumns,attr,omitempty"`
+	State          string                    `xml:"state,attr,omitempty"`
+	FilterUnique   bool                      `xml:"filterUnique,attr,omitempty"`
+	View           string                    `xml:"view,attr,omitempty"`
+	ShowRuler      bool                      `xml:"showRuler,attr"`
+	TopLeftCell    string                    `xml:"topLeftCell,attr,omitempty"`
+	Pane           *xlsxPane                 `xml:"pane"`
+	Selection      *xlsxSelection            `xml:"selection"`
+	RowBreaks      *xlsxPageBreaks           `xml:"rowBreaks"`
+	ColBreaks      *xlsxPageBreaks           `xml:"colBreaks"`
+	PageMargins    *xlsxPageMargins          `xml:"pageMargins"`
+	PrintOptions   *xlsxPrintOptions         `xml:"printOptions"`
+	PageSetUp      *xlsxPageSetUp            `xml:"pageSetup"`
+	HeaderFooter   *xlsxHeaderFooter         `xml:"headerFooter"`
+	AutoFilter     *xlsxCustomViewAutoFilter `xml:"autoFilter"`
+}
+
+// UnmarshalXML decodes a customSheetView element, giving the
+// attributes it leaves out their default values.
+func (v *xlsxCustomSheetView) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type customSheetView xlsxCustomSheetView
+	view := customSheetView{
+		Scale:          100,
+		ShowGridLines:  true,
+		ShowRowCol:     true,
+		OutlineSymbols: true,
+		ZeroValues:     true,
+		ShowRuler:      true,
+	}
+	if err := d.DecodeElement(&view, &start); err != nil {
+		return err
+	}
+	*v = xlsxCustomSheetView(view)
+	return nil
+}
+
+// xlsxPageBreaks directly maps the rowBreaks and colBreaks elements.
+type xlsxPageBreaks struct {
+	Count            int         `xml:"count,attr"`
+	ManualBreakCount int         `xml:"manualBreakCount,attr"`
+	Brk              []xlsxBreak `xml:"brk"`
+}
+
+// xlsxBreak directly maps the brk element.
+type xlsxBreak struct {
+	Id  int  `xml:"id,attr"`
+	Max int  `xml:"max,attr,omitempty"`
+	Man bool `xml:"man,attr,omitempty"`
+}
+
+// xlsxCustomViewAutoFilter is the autoFilter element of a custom sheet
+// view, whose criteria are kept as they were read.
+type xlsxCustomViewAutoFilter struct {
+	Ref     string `xml:"ref,attr"`
+	Content string `xml:",innerxml"`
+}
+
+// readCustomViews returns the custom views of a workbook.
+func readCustomViews(workbook *xlsxWorkbook) []*CustomView {
+	if workbook.CustomWorkbookViews == nil {
+		return nil
+	}
+	names := make(map[int]string, len(workbook.Sheets.Sheet))
+	for _, sheet := range workbook.Sheets.Sheet {
+		if id, err := strconv.Atoi(sheet.SheetId); err == nil {
+			names[id] = sheet.Name
+		}
+	}
+	var views []*CustomView
+	for _, x := range workbook.CustomWorkbookViews.CustomWorkbookView {
+		view := &CustomView{
+			Name:                 x.Name,
+			GUID:                 x.GUID,
+			ActiveSheet:          names[x.ActiveSheetId],
+			Personal:             x.PersonalView,
+			Maximized:            x.Maximized,
+			Minimized:            x.Minimized,
+			XWindow:              x.XWindow,
+			YWindow:              x.YWindow,
+			WindowWidth:          x.WindowWidth,
+			WindowHeight:         x.WindowHeight,
+			ExcludePrintSettings: !x.IncludePrintSettings,
+			ExcludeHiddenRowCol:  !x.IncludeHiddenRowCol,
+			HideHorizontalScroll: !x.ShowHorizontalScroll,
+			HideVerticalScroll:   !x.ShowVerticalScroll,
+			HideSheetTabs:        !x.ShowSheetTabs,
+			HideFormulaBar:       !x.ShowFormulaBar,
+			HideStatusBar:        !x.ShowStatusbar,
+		}
+		if x.TabRatio != 600 {
+			view.TabRatio = x.TabRatio
+		}
+		views = append(views, view)
+	}
+	return views
+}
+
+// makeCustomWorkbookViews returns the customWorkbookViews element of
+// the File's custom views, or nil if it has none, finding the ids of
+// their active sheets among the sheets of the workbook.
+func (f *File) makeCustomWorkbookViews(sheets []xlsxSheet) *xlsxCustomWorkbookViews {
+	if len(f.CustomViews) == 0 || len(sheets) == 0 {
+		return nil
+	}
+	var xViews xlsxCustomWorkbookViews
+	for _, view := range f.CustomViews {
+		x := xlsxCustomWorkbookView{
+			Name:                 view.Name,
+			GUID:                 view.GUID,
+			PersonalView:         view.Personal,
+			Maximized:            view.Maximized,
+			Minimized:            view.Minimized,
+			IncludePrintSettings: !view.ExcludePrintSettings,
+			IncludeHiddenRowCol:  !view.ExcludeHiddenRowCol,
+			ShowHorizontalScroll: !view.HideHorizontalScroll,
+			ShowVerticalScroll:   !view.HideVerticalScroll,
+			ShowSheetTabs:        !view.HideSheetTabs,
+			XWindow:              view.XWindow,
+			YWindow:              view.YWindow,
+			WindowWidth:          view.WindowWidth,
+			WindowHeight:         view.WindowHeight,
+			TabRatio:             view.TabRatio,
+			ShowFormulaBar:       !view.HideFormulaBar,
+			ShowStatusbar:        !view.HideStatusBar,
+		}
+		if x.WindowWidth == 0 || x.WindowHeight == 0 {
+			x.WindowWidth, x.WindowHeight = 1280, 720
+		}
+		if x.TabRatio == 0 {
+			x.TabRatio = 600
+		}
+		x.ActiveSheetId, _ = strconv.Atoi(sheets[0].SheetId)
+		for _, sheet := range sheets {
+			if sheet.Name == view.ActiveSheet {
+				x.ActiveSheetId, _ = strconv.Atoi(sheet.SheetId)
+				break
+			}
+		}
+		xViews.CustomWorkbookView = append(xViews.CustomWorkbookView, x)
+	}
+	return &xViews
+}
+
+// readCustomSheetViews reads the settings of the Sheet for the custom
+// views of the workbook from its worksheet.  The customSheetViews
+// element is decoded again from the part as it is written, as the
+// criteria of the views' filters are kept as they are.
+func (f *File) readCustomSheetViews(sheet *Sheet, worksheet *xlsxWorksheet, worksheetPart string) error {
+	if worksheet.CustomSheetViews == nil {
+		return nil
+	}
+	data, err := f.readBinaryPart(f.parts[worksheetPart])
+	if err != nil {
+		return err
+	}
+	content, _, err := rawElementContent(data, "customSheetViews")
+	if err != nil {
+		return fmt.Errorf("sheet '%s': custom views: %s", sheet.Name, err)
+	}
+	var xViews xlsxCustomSheetViews
+	if err := xml.NewDecoder(bytes.NewReader([]byte("<customSheetViews>" + content + "</customSheetViews>"))).Decode(&xViews); err != nil {
+		return fmt.Errorf("sheet '%s': custom views: %s", sheet.Name, err)
+	}
+	sheet.CustomViews = nil
+	for _, x := range xViews.CustomSheetView {
+		sheet.CustomViews = append(sheet.CustomViews, readCustomSheetView(x))
+	}
+	return nil
+}
+
+// readCustomSheetView returns the CustomSheetView of a customSheetView
+// element.
+func readCustomSheetView(x xlsxCustomSheetView) CustomSheetView {
+	v := CustomSheetView{
+		GUID:               x.GUID,
+		TopLeftCell:        x.TopLeftCell,
+		ShowGridLines:      x.ShowGridLines,
+		HideRowColHeaders:  !x.ShowRowCol,
+		HideZeros:          !x.ZeroValues,
+		HideOutlineSymbols: !x.OutlineSymbols,
+		HideRuler:          !x.ShowRuler,
+		ShowFormulas:       x.ShowFormulas,
+		ShowPageBreaks:     x.ShowPageBreaks,
+		Hidden:             x.State == sheetStateHidden,
+		VeryHidden:         x.State == sheetStateVeryHidden,
+		HiddenRows:         x.HiddenRows,
+		HiddenColumns:      x.HiddenColumns,
+		FitToPage:          x.FitToPage,
+		PrintArea:          x.PrintArea,
+		Filter:             x.Filter,
+		FilterUnique:       x.FilterUnique,
+		ShowAutoFilter:     x.ShowAutoFilter,
+		PageMargins:        x.PageMargins,
+		PrintOptions:       x.PrintOptions,
+		PageSetUp:          x.PageSetUp,
+		HeaderFooter:       x.HeaderFooter,
+	}
+	if x.Scale != 100 {
+		v.ZoomScale = x.Scale
+	}
+	if x.View != "" && x.View != string(ViewNormal) {
+		v.Type = ViewType(x.View)
+	}
+	if x.Pane != nil {
+		v.Pane = &Pane{XSplit: x.Pane.XSplit, YSplit: x.Pane.YSplit, TopLeftCell: x.Pane.TopLeftCell,
+			ActivePane: x.Pane.ActivePane, State: x.Pane.State}
+	}
+	if x.Selection != nil {
+		v.ActiveCell, v.Selection = x.Selection.ActiveCell, x.Selection.SQRef
+		// Only a split view has a pane other than the top left one.
+		if x.Pane != nil {
+			v.pane = x.Selection.Pane
+			if v.pane == "" {
+				v.pane = "topLeft"
+			}
+		}
+	}
+	for _, brk := range breaksOf(x.RowBreaks) {
+		v.RowBreaks = append(v.RowBreaks, brk.Id)
+	}
+	for _, brk := range breaksOf(x.ColBreaks) {
+		v.ColBreaks = append(v.ColBreaks, brk.Id)
+	}
+	if x.AutoFilter != nil {
+		v.AutoFilter, v.filterColumns = x.AutoFilter.Ref, x.AutoFilter.Content
+	}
+	return v
+}
+
+// breaksOf returns the breaks of a rowBreaks or colBreaks element,
+// which may be missing.
+func breaksOf(x *xlsxPageBreaks) []xlsxBreak {
+	if x == nil {
+		return nil
+	}
+	return x.Brk
+}
+
+// makeCustomSheetViews returns the customSheetViews element of the
+// Sheet's settings for the custom views of its File, or nil if it has
+// none.  Settings for views the File doesn't have are left out.
+func (s *Sheet) makeCustomSheetViews() *xlsxCustomSheetViews {
+	if s.File == nil {
+		return nil
+	}
+	var xViews xlsxCustomSheetViews
+	for _, v := range s.CustomViews {
+		known := false
+		for _, view := range s.File.CustomViews {
+			known = known || strings.EqualFold(view.GUID, v.GUID)
+		}
+		if known {
+			xViews.CustomSheetView = append(xViews.CustomSheetView, v.make())
+		}
+	}
+	if len(xViews.CustomSheetView) == 0 {
+		return nil
+	}
+	return &xViews
+}
+
+// make returns the customSheetView element of the CustomSheetView.
+func (v CustomSheetView) make() xlsxCustomSheetView {
+	x := xlsxCustomSheetView{
+		GUID:           v.GUID,
+		Scale:          v.ZoomScale,
+		ShowPageBreaks: v.ShowPageBreaks,
+		ShowFormulas:   v.ShowFormulas,
+		ShowGridLines:  v.ShowGridLines,
+		ShowRowCol:     !v.HideRowColHeaders,
+		OutlineSymbols: !v.HideOutlineSymbols,
+		ZeroValues:     !v.HideZeros,
+		FitToPage:      v.FitToPage,
+		PrintArea:      v.PrintArea,
+		Filter:         v.Filter,
+		ShowAutoFilter: v.ShowAutoFilter,
+		HiddenRows:     v.HiddenRows,
+		HiddenColumns:  v.HiddenColumns,
+		FilterUnique:   v.FilterUnique,
+		View:           string(v.Type),
+		ShowRuler:      !v.HideRuler,
+		TopLeftCell:    v.TopLeftCell,
+		RowBreaks:      makePageBreaks(v.RowBreaks, 16383),
+		ColBreaks:      makePageBreaks(v.ColBreaks, 1048575),
+		PageMargins:    v.PageMargins,
+		PrintOptions:   v.PrintOptions,
+		PageSetUp:      v.PageSetUp,
+		HeaderFooter:   v.HeaderFooter,
+	}
+	if x.Scale == 0 {
+		x.Scale = 100
+	}
+	if v.VeryHidden {
+		x.State = sheetStateVeryHidden
+	} else if v.Hidden {
+		x.State = sheetStateHidden
+	}
+	if p := v.Pane; p != nil {
+		x.Pane = &xlsxPane{XSplit: p.XSplit, YSplit: p.YSplit, TopLeftCell: p.TopLeftCell, ActivePane: p.ActivePane, State: p.State}
+	}
+	if v.ActiveCell != "" || v.Selection != "" {
+		activeCell, selection := v.ActiveCell, v.Selection
+		if activeCell == "" {
+			activeCell = strings.SplitN(strings.SplitN(selection, " ", 2)[0], ":", 2)[0]
+		}
+		if selection == "" {
+			selection = activeCell
+		}
+		x.Selection = &xlsxSelection{Pane: selectionPane(x.Pane, v.pane), ActiveCell: activeCell, SQRef: selection}
+	}
+	if v.AutoFilter != "" {
+		x.AutoFilter = &xlsxCustomViewAutoFilter{Ref: v.AutoFilter, Content: v.filterColumns}
+	}
+	return x
+}
+
+// makePageBreaks returns the rowBreaks or colBreaks element of manual
+// page breaks before the rows or columns given, each running to max,
+// or nil if there are none.
+func makePageBreaks(ids []int, max int) *xlsxPageBreaks {
+	if len(ids) == 0 {
+		return nil
+	}
+	x := &xlsxPageBreaks{Count: len(ids), ManualBreakCount: len(ids)}
+	for _, id := range ids {
+		x.Brk = append(x.Brk, xlsxBreak{Id: id, Max: max, Man: true})
+	}
+	return x
+}
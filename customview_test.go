@@ -0,0 +1,149 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type CustomViewSuite struct{}
+
+var _ = Suite(&CustomViewSuite{})
+
+const (
+	printViewGUID = "{5A9E4C1B-0D2F-4E6A-9B3C-7F1D2E8A4B60}"
+	dataViewGUID  = "{0B1C2D3E-4F50-4617-8293-A4B5C6D7E8F9}"
+	viewFilter    = `<filterColumn colId="1"><filters><filter val="East"/></filters></filterColumn>`
+)
+
+func customViewParts() map[string]string {
+	return map[string]string{
+		"[Content_Types].xml": `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="xml" ContentType="application/xml"/></Types>`,
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>` +
+			`<sheet name="Data" sheetId="3" r:id="rId1"/><sheet name="Notes" sheetId="7" r:id="rId2"/></sheets>` +
+			`<definedNames><definedName name="Z_5A9E4C1B_0D2F_4E6A_9B3C_7F1D2E8A4B60_.wvu.PrintArea" localSheetId="0" hidden="1">Data!$A$1:$D$20</definedName>` +
+			`<definedName name="Total">Data!$D$20</definedName></definedNames>` +
+			`<customWorkbookViews><customWorkbookView name="Print" guid="` + printViewGUID + `" includeHiddenRowCol="0" maximized="1" windowWidth="1436" windowHeight="805" activeSheetId="7"/>` +
+			`<customWorkbookView name="Data entry" guid="` + dataViewGUID + `" xWindow="20" yWindow="40" windowWidth="800" windowHeight="600" tabRatio="350" showFormulaBar="0" activeSheetId="3"/>` +
+			`</customWorkbookViews></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet2.xml"/></Relationships>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData><row r="1"><c r="A1" t="inlineStr"><is><t>Region</t></is></c></row></sheetData>` +
+			`<customSheetViews><customSheetView guid="` + printViewGUID + `" scale="80" showGridLines="0" fitToPage="1" printArea="1" filter="1" view="pageLayout" topLeftCell="A3">` +
+			`<selection activeCell="B4" sqref="B4:C6"/><rowBreaks count="1" manualBreakCount="1"><brk id="10" max="16383" man="1"/></rowBreaks>` +
+			`<pageMargins left="0.5" right="0.5" top="1" bottom="1" header="0.3" footer="0.3"/><pageSetup orientation="landscape" fitToHeight="0"/>` +
+			`<autoFilter ref="A1:D20">` + viewFilter + `</autoFilter></customSheetView>` +
+			`<customSheetView guid="` + dataViewGUID + `" zeroValues="0" state="hidden"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>` +
+			`<selection pane="bottomLeft" activeCell="A2" sqref="A2"/></customSheetView></customSheetViews></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData/>` +
+			`<customSheetViews><customSheetView guid="` + printViewGUID + `"/><customSheetView guid="` + dataViewGUID + `" showRuler="0"/></customSheetViews></worksheet>`,
+	}
+}
+
+func (s *CustomViewSuite) TestRead(c *C) {
+	f, err := ReadZipReader(makeZipReader(c, customViewParts()))
+	c.Assert(err, IsNil)
+	c.Assert(f.CustomViews, HasLen, 2)
+	c.Assert(*f.CustomViews[0], DeepEquals, CustomView{Name: "Print", GUID: printViewGUID, ActiveSheet: "Notes",
+		Maximized: true, WindowWidth: 1436, WindowHeight: 805, ExcludeHiddenRowCol: true})
+	c.Assert(*f.CustomView("data ENTRY"), DeepEquals, CustomView{Name: "Data entry", GUID: dataViewGUID, ActiveSheet: "Data",
+		XWindow: 20, YWindow: 40, WindowWidth: 800, WindowHeight: 600, TabRatio: 350, HideFormulaBar: true})
+
+	data := f.Sheets[0]
+	c.Assert(data.CustomViews, HasLen, 2)
+	print := data.CustomView(printViewGUID)
+	c.Assert(print.ZoomScale, Equals, 80)
+	c.Assert(print.Type, Equals, ViewPageLayout)
+	c.Assert(print.ShowGridLines, Equals, false)
+	c.Assert([]string{print.TopLeftCell, print.ActiveCell, print.Selection, print.AutoFilter}, DeepEquals, []string{"A3", "B4", "B4:C6", "A1:D20"})
+	c.Assert([]bool{print.FitToPage, print.PrintArea, print.Filter}, DeepEquals, []bool{true, true, true})
+	c.Assert(print.RowBreaks, DeepEquals, []int{10})
+	c.Assert(print.PageMargins.Left, Equals, 0.5)
+	c.Assert(print.PageSetUp.Orientation, Equals, "landscape")
+	c.Assert(print.PrintOptions, IsNil)
+	entry := data.CustomView(dataViewGUID)
+	c.Assert(entry.ShowGridLines, Equals, true)
+	c.Assert(entry.HideZeros, Equals, true)
+	c.Assert(entry.Hidden, Equals, true)
+	c.Assert(*entry.Pane, Equals, Pane{YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft", State: "frozen"})
+	c.Assert(f.Sheets[1].CustomView(dataViewGUID).HideRuler, Equals, true)
+	c.Assert(f.Sheets[1].CustomView("{00000000-0000-0000-0000-000000000000}"), IsNil)
+}
+
+func (s *CustomViewSuite) TestRoundTrip(c *C) {
+	f, err := ReadZipReader(makeZipReader(c, customViewParts()))
+	c.Assert(err, IsNil)
+	f.CustomView("Print").TabRatio = 500
+	f.Sheets[0].CustomView(printViewGUID).ColBreaks = []int{4}
+	// Settings for a view the workbook doesn't have are left out.
+	f.Sheets[1].CustomViews = append(f.Sheets[1].CustomViews, CustomSheetView{GUID: "{00000000-0000-0000-0000-000000000000}"})
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/workbook.xml"], Matches, `(?s).*</calcPr><customWorkbookViews>`+
+		`<customWorkbookView name="Print" guid="\`+printViewGUID+`" maximized="true" includePrintSettings="true" includeHiddenRowCol="false" showHorizontalScroll="true" showVerticalScroll="true" showSheetTabs="true" `+
+		`windowWidth="1436" windowHeight="805" tabRatio="500" activeSheetId="2" showFormulaBar="true" showStatusbar="true"></customWorkbookView>`+
+		`<customWorkbookView name="Data entry" [^>]*xWindow="20" yWindow="40" windowWidth="800" windowHeight="600" tabRatio="350" activeSheetId="1" showFormulaBar="false" .*`)
+	sheet1 := parts["xl/worksheets/sheet1.xml"]
+	c.Assert(sheet1, Matches, `(?s).*<customSheetViews><customSheetView guid="\`+printViewGUID+`" scale="80" showGridLines="false" showRowCol="true" outlineSymbols="true" zeroValues="true" fitToPage="true" printArea="true" filter="true" view="pageLayout" showRuler="true" topLeftCell="A3">`+
+		`<selection pane="topLeft" activeCell="B4" activeCellId="0" sqref="B4:C6"></selection><rowBreaks count="1" manualBreakCount="1"><brk id="10" max="16383" man="true"></brk></rowBreaks>`+
+		`<colBreaks count="1" manualBreakCount="1"><brk id="4" max="1048575" man="true"></brk></colBreaks><pageMargins left="0.5" [^>]*></pageMargins><pageSetup [^>]*orientation="landscape"[^>]*></pageSetup>`+
+		`<autoFilter ref="A1:D20">`+viewFilter+`</autoFilter></customSheetView>.*</customSheetViews>.*`)
+	c.Assert(sheet1, Matches, `(?s).*</sheetData><customSheetViews>.*</customSheetViews><printOptions .*`)
+	c.Assert(sheet1, Matches, `(?s).*<pane [^>]*activePane="bottomLeft"[^>]*></pane><selection pane="bottomLeft" activeCell="A2" .*`)
+	c.Assert(parts["xl/worksheets/sheet2.xml"], Not(Matches), `.*00000000-.*`)
+
+	written, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	c.Assert(written.CustomViews, DeepEquals, f.CustomViews)
+	c.Assert(written.Sheets[0].CustomViews, DeepEquals, f.Sheets[0].CustomViews)
+	c.Assert(written.Sheets[1].CustomViews, DeepEquals, f.Sheets[1].CustomViews[:2])
+}
+
+func (s *CustomViewSuite) TestSelectionPane(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	view, err := f.AddCustomView("Split")
+	c.Assert(err, IsNil)
+	entry := sheet.CustomView(view.GUID)
+	entry.ActiveCell = "C3"
+	entry.Pane = &Pane{XSplit: 2, YSplit: 1, TopLeftCell: "C2", State: "split"}
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/worksheets/sheet1.xml"], Matches, `(?s).*<customSheetView [^>]*><pane [^>]*></pane><selection pane="bottomRight" activeCell="C3" .*`)
+
+	// The sheet's own view isn't split when it's written, so its
+	// selection is in the top left pane.
+	sheet.View.ActiveCell = "A2"
+	parts, err = f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/worksheets/sheet1.xml"], Matches, `(?s).*<sheetView [^>]*><selection pane="topLeft" activeCell="A2" .*`)
+}
+
+func (s *CustomViewSuite) TestAddAndRemove(c *C) {
+	f, err := ReadZipReader(makeZipReader(c, customViewParts()))
+	c.Assert(err, IsNil)
+	f.Sheets[0].View.ZoomScale = 150
+	f.Sheets[0].View.ShowGridLines = true
+	view, err := f.AddCustomView("Review")
+	c.Assert(err, IsNil)
+	c.Assert(view.GUID, Matches, `\{[0-9A-F]{8}-[0-9A-F]{4}-4[0-9A-F]{3}-[89AB][0-9A-F]{3}-[0-9A-F]{12}\}`)
+	c.Assert(view.ActiveSheet, Equals, "Data")
+	c.Assert(*f.Sheets[0].CustomView(view.GUID), DeepEquals, CustomSheetView{GUID: view.GUID, ZoomScale: 150, ShowGridLines: true})
+	c.Assert(f.Sheets[1].CustomView(view.GUID), NotNil)
+	_, err = f.AddCustomView("print")
+	c.Assert(err, ErrorMatches, "custom view 'print' already exists")
+
+	copied := f.Sheets[0].clone("Copy", f)
+	copied.CustomView(printViewGUID).RowBreaks[0] = 12
+	c.Assert(f.Sheets[0].CustomView(printViewGUID).RowBreaks, DeepEquals, []int{10})
+
+	c.Assert(f.RemoveCustomView("Print"), Equals, true)
+	c.Assert(f.RemoveCustomView("Print"), Equals, false)
+	c.Assert(f.CustomViews, HasLen, 2)
+	c.Assert(f.Sheets[0].CustomView(printViewGUID), IsNil)
+	c.Assert(f.Sheets[1].CustomViews, HasLen, 2)
+	c.Assert(f.Names, HasLen, 1)
+	c.Assert(f.Names[0].Name, Equals, "Total")
+}
@@ -0,0 +1,171 @@
+package xlsx
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	relTypeCustomXML          = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/customXml"
+	relTypeCustomXMLProps     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/customXmlProps"
+	contentTypeCustomXMLProps = "application/vnd.openxmlformats-officedocument.customXmlProperties+xml"
+	customXMLNamespace        = "http://schemas.openxmlformats.org/officeDocument/2006/customXml"
+)
+
+// CustomXMLPart is a custom XML part of a workbook: an XML document of
+// an application's own, such as an Office add-in's, that the workbook
+// carries without spreadsheet applications looking at it.
+type CustomXMLPart struct {
+	// ID is the part's item id, a GUID such as
+	// "{5A9E4C1B-0D2F-4E6A-9B3C-7F1D2E8A4B60}", by which add-ins
+	// find it.
+	ID string
+	// SchemaRefs are the namespaces of the schemas Data follows.
+	SchemaRefs []string
+	// Data is the XML document.
+	Data []byte
+}
+
+// xlsxDatastoreItem directly maps the datastoreItem element of the
+// properties part of a custom XML part.
+type xlsxDatastoreItem struct {
+	XMLName    xml.Name `xml:"http://schemas.openxmlformats.org/officeDocument/2006/customXml datastoreItem"`
+	ItemID     string   `xml:"http://schemas.openxmlformats.org/officeDocument/2006/customXml itemID,attr"`
+	SchemaRefs []struct {
+		URI string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/customXml uri,attr"`
+	} `xml:"http://schemas.openxmlformats.org/officeDocument/2006/customXml schemaRefs>schemaRef"`
+}
+
+// AddCustomXML adds data, an XML document following the schemas whose
+// namespaces are given, to the File as a custom XML part with an item
+// id of its own.  It returns an error if data isn't well formed XML.
+func (f *File) AddCustomXML(data []byte, schemaRefs ...string) (*CustomXMLPart, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	root := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("custom XML part: %s", err)
+		}
+		if _, ok := token.(xml.StartElement); ok {
+			root = true
+		}
+	}
+	if !root {
+		return nil, fmt.Errorf("custom XML part: there is no root element")
+	}
+	id, err := newGUID()
+	if err != nil {
+		return nil, err
+	}
+	part := &CustomXMLPart{ID: id, SchemaRefs: append([]string(nil), schemaRefs...), Data: data}
+	f.CustomXMLParts = append(f.CustomXMLParts, part)
+	return part, nil
+}
+
+// CustomXML returns the custom XML part of the File with the item id
+// given, compared without regard to case, or nil if it has none.
+func (f *File) CustomXML(id string) *CustomXMLPart {
+	for _, part := range f.CustomXMLParts {
+		if strings.EqualFold(part.ID, id) {
+			return part
+		}
+	}
+	return nil
+}
+
+// newGUID returns a random GUID in braces, as item ids are written.
+func newGUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("{%X-%X-%X-%X-%X}", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// readCustomXML reads the custom XML parts the workbook refers to, in
+// the order of their names, with their item ids and schemas.
+func (f *File) readCustomXML() error {
+	rels, err := f.readRelationships("xl/workbook.xml")
+	if err != nil {
+		return err
+	}
+	var targets []string
+	for _, rel := range rels {
+		if rel.Type == relTypeCustomXML && f.parts[rel.Target] != nil {
+			targets = append(targets, rel.Target)
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool { return naturalLess(targets[i], targets[j]) })
+	for _, target := range targets {
+		data, err := f.readBinaryPart(f.parts[target])
+		if err != nil {
+			return err
+		}
+		part := &CustomXMLPart{Data: data}
+		itemRels, err := f.readRelationships(target)
+		if err != nil {
+			return err
+		}
+		for _, rel := range itemRels {
+			if rel.Type != relTypeCustomXMLProps || f.parts[rel.Target] == nil {
+				continue
+			}
+			rc, err := f.openPart(f.parts[rel.Target])
+			if err != nil {
+				return err
+			}
+			var item xlsxDatastoreItem
+			err = newPartDecoder(rc).Decode(&item)
+			rc.Close()
+			if err != nil {
+				return fmt.Errorf("custom XML part %s: %s", rel.Target, err)
+			}
+			part.ID = item.ItemID
+			for _, ref := range item.SchemaRefs {
+				part.SchemaRefs = append(part.SchemaRefs, ref.URI)
+			}
+		}
+		f.CustomXMLParts = append(f.CustomXMLParts, part)
+	}
+	return nil
+}
+
+// makeCustomXMLParts adds the File's custom XML parts, and the parts
+// holding their properties, to parts, relating them to the workbook in
+// rels.
+func (f *File) makeCustomXMLParts(parts map[string]string, rels *packageRelationships) {
+	for i, part := range f.CustomXMLParts {
+		itemName := fmt.Sprintf("customXml/item%d.xml", i+1)
+		propsName := fmt.Sprintf("customXml/itemProps%d.xml", i+1)
+		parts[itemName] = string(part.Data)
+		rels.of("xl/workbook.xml").add(relTypeCustomXML, itemName)
+		rels.of(itemName).add(relTypeCustomXMLProps, propsName)
+
+		var props strings.Builder
+		props.WriteString(xmlHeader)
+		props.WriteString(`<ds:datastoreItem ds:itemID="` + escapeXMLAttr(part.ID) + `" xmlns:ds="` + customXMLNamespace + `"><ds:schemaRefs>`)
+		for _, ref := range part.SchemaRefs {
+			props.WriteString(`<ds:schemaRef ds:uri="` + escapeXMLAttr(ref) + `"/>`)
+		}
+		props.WriteString(`</ds:schemaRefs></ds:datastoreItem>`)
+		parts[propsName] = props.String()
+	}
+}
+
+// escapeXMLAttr returns s escaped as the value of an attribute.
+func escapeXMLAttr(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,52 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type CustomXMLSuite struct{}
+
+var _ = Suite(&CustomXMLSuite{})
+
+func (s *CustomXMLSuite) TestRoundTrip(c *C) {
+	f := NewFile()
+	_, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	order := []byte(`<order xmlns="urn:example:orders"><id>42</id></order>`)
+	part, err := f.AddCustomXML(order, "urn:example:orders", "urn:example:a&b")
+	c.Assert(err, IsNil)
+	c.Assert(part.ID, Matches, `\{[0-9A-F]{8}-[0-9A-F]{4}-4[0-9A-F]{3}-[89AB][0-9A-F]{3}-[0-9A-F]{12}\}`)
+	c.Assert(part.Data, DeepEquals, order)
+	_, err = f.AddCustomXML([]byte(`<settings/>`))
+	c.Assert(err, IsNil)
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["customXml/item1.xml"], Equals, string(order))
+	c.Assert(parts["customXml/itemProps1.xml"], Equals, xmlHeader+`<ds:datastoreItem ds:itemID="`+part.ID+
+		`" xmlns:ds="http://schemas.openxmlformats.org/officeDocument/2006/customXml"><ds:schemaRefs>`+
+		`<ds:schemaRef ds:uri="urn:example:orders"/><ds:schemaRef ds:uri="urn:example:a&amp;b"/></ds:schemaRefs></ds:datastoreItem>`)
+	c.Assert(parts["xl/_rels/workbook.xml.rels"], Matches, `(?s).*Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/customXml" Target="../customXml/item2.xml".*`)
+	c.Assert(parts["customXml/_rels/item1.xml.rels"], Matches, `(?s).*Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/customXmlProps" Target="itemProps1.xml".*`)
+	c.Assert(parts["[Content_Types].xml"], Matches, `(?s).*<Override PartName="/customXml/itemProps1.xml" ContentType="application/vnd.openxmlformats-officedocument.customXmlProperties\+xml"></Override>.*`)
+
+	written, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	c.Assert(written.CustomXMLParts, HasLen, 2)
+	read := written.CustomXML(part.ID)
+	c.Assert(read, NotNil)
+	c.Assert(read.SchemaRefs, DeepEquals, []string{"urn:example:orders", "urn:example:a&b"})
+	c.Assert(read.Data, DeepEquals, order)
+	c.Assert(written.CustomXMLParts[1].Data, DeepEquals, []byte(`<settings/>`))
+	c.Assert(written.CustomXMLParts[1].SchemaRefs, HasLen, 0)
+	c.Assert(written.CustomXML("{00000000-0000-0000-0000-000000000000}"), IsNil)
+}
+
+func (s *CustomXMLSuite) TestAddMalformed(c *C) {
+	f := NewFile()
+	_, err := f.AddCustomXML([]byte(`<order><id>42</order>`))
+	c.Assert(err, ErrorMatches, "custom XML part: .*")
+	_, err = f.AddCustomXML([]byte(`  `))
+	c.Assert(err, ErrorMatches, "custom XML part: there is no root element")
+	c.Assert(f.CustomXMLParts, HasLen, 0)
+}
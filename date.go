@@ -1,7 +1,10 @@
 package xlsx
 
 import (
+	"errors"
+	"fmt"
 	"math"
+	"strconv"
 	"time"
 )
 
@@ -75,31 +78,152 @@ func doTheFliegelAndVanFlandernAlgorithm(jd int) (day, month, year int) {
 	return d, m, y
 }
 
+// DatePolicy determines how serial numbers in the 1900 date system
+// that fall before 1 March 1900 are converted to and from time.Time.
+// Excel, for the sake of compatibility with Lotus 1-2-3, treats 1900
+// as a leap year: serial 60 is the non-existent 29 February 1900 and
+// every earlier serial is one day later than a continuous count of
+// days would make it.  Serial 0 is displayed by Excel as "1900-01-00".
+// Dates from 1 March 1900 onwards, and all dates in the 1904 date
+// system, are the same whichever policy is used.
+type DatePolicy int
+
+const (
+	// DatePolicyCorrect treats serial numbers as a continuous
+	// count of days from 30 December 1899, so serial 1 is 31
+	// December 1899 and serial 60 is 28 February 1900.  This is the
+	// default.
+	DatePolicyCorrect DatePolicy = iota
+	// DatePolicyExcelCompatible gives the dates Excel displays, so
+	// serial 0 is 31 December 1899, serial 1 is 1 January 1900 and
+	// serial 59 is 28 February 1900.  Serial 60 can't be
+	// represented by a time.Time and is converted to 28 February
+	// 1900, along with the error ErrLeapDay1900.
+	DatePolicyExcelCompatible
+)
+
+// ErrLeapDay1900 is returned when converting serial 60, which Excel
+// considers to be 29 February 1900, to a time.Time.
+var ErrLeapDay1900 = errors.New("serial 60 is 29 February 1900, which doesn't exist")
+
+const dayNanoseconds = 24 * 60 * 60 * 1e9
+
+var (
+	excelEpoch1900 = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	excelEpoch1904 = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// TimeFromExcelSerial converts a serial number, as stored in a cell,
+// to a time.Time in UTC, following the given DatePolicy.  The time of
+// day is rounded to the nearest nanosecond, so that converting a
+// time.Time near the epoch to a serial number and back again gives
+// exactly the same time.Time.
+func TimeFromExcelSerial(serial float64, date1904 bool, policy DatePolicy) (time.Time, error) {
+	var err error
+	epoch := excelEpoch1900
+	if date1904 {
+		epoch = excelEpoch1904
+	}
+	days := math.Floor(serial)
+	nanoseconds := math.Floor((serial-days)*dayNanoseconds + 0.5)
+	if !date1904 && policy == DatePolicyExcelCompatible {
+		switch {
+		case days == 60:
+			err = ErrLeapDay1900
+		case days < 60:
+			days++
+		}
+	}
+	date := epoch.AddDate(0, 0, int(days)).Add(time.Duration(nanoseconds))
+	return date, err
+}
+
+// ExcelSerialFromTime converts a time.Time to a serial number, as
+// stored in a cell, following the given DatePolicy.  The wall clock
+// time is used, whatever the location of the time.Time.
+func ExcelSerialFromTime(t time.Time, date1904 bool, policy DatePolicy) float64 {
+	epoch := excelEpoch1900
+	if date1904 {
+		epoch = excelEpoch1904
+	}
+	year, month, day := t.Date()
+	midnight := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	days := (midnight.Unix() - epoch.Unix()) / (24 * 60 * 60)
+	hour, min, sec := t.Clock()
+	nanoseconds := ((int64(hour)*60+int64(min))*60+int64(sec))*1e9 + int64(t.Nanosecond())
+	if !date1904 && policy == DatePolicyExcelCompatible && days <= 60 {
+		days--
+	}
+	return float64(days) + float64(nanoseconds)/dayNanoseconds
+}
+
 // Convert an excelTime representation (stored as a floating point number) to a time.Time.
+// Serial numbers before 1 March 1900 are converted using DatePolicyCorrect.
 func TimeFromExcelTime(excelTime float64, date1904 bool) time.Time {
-	var date time.Time
-	var intPart int64 = int64(excelTime)
-	// Excel uses Julian dates prior to March 1st 1900, and
-	// Gregorian thereafter.
-	if intPart <= 61 {
-		const OFFSET1900 = 15018.0
-		const OFFSET1904 = 16480.0
-		var date time.Time
-		if date1904 {
-			date = julianDateToGregorianTime(MJD_0, excelTime+OFFSET1904)
-		} else {
-			date = julianDateToGregorianTime(MJD_0, excelTime+OFFSET1900)
+	date, _ := TimeFromExcelSerial(excelTime, date1904, DatePolicyCorrect)
+	return date
+}
+
+// SetDate1904 changes the date system of the File, converting the
+// serial numbers of the dates in its cells, those whose number formats
+// display a date, so that they stay the same dates.  Times of day
+// don't depend on the date system and are left as they are.  Dates
+// before 1904 can't be written in the 1904 date system, and are left
+// as negative serial numbers, which spreadsheet applications show as
+// errors, with a warning recorded in the File.  The Sheets of a File
+// opened with LazySheets that haven't been loaded are converted as
+// they are.
+func (f *File) SetDate1904(date1904 bool) {
+	if f.Date1904 == date1904 {
+		return
+	}
+	from := f.Date1904
+	f.Date1904 = date1904
+	for _, sheet := range f.Sheets {
+		if l := sheet.lazy; l != nil {
+			l.mu.Lock()
+			if l.loaded {
+				sheet.convertDates(from, date1904)
+			}
+			l.mu.Unlock()
+			continue
 		}
-		return date
+		sheet.convertDates(from, date1904)
 	}
-	var floatPart float64 = excelTime - float64(intPart)
-	var dayNanoSeconds float64 = 24 * 60 * 60 * 1000 * 1000 * 1000
-	if date1904 {
-		date = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
-	} else {
-		date = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+}
+
+// convertDates converts the serial numbers of the dates in the Sheet
+// from one date system to the other, as SetDate1904 does.
+func (s *Sheet) convertDates(from1904, to1904 bool) {
+	policy := DatePolicyCorrect
+	if s.File != nil {
+		policy = s.File.DatePolicy
+	}
+	for r, row := range s.Rows {
+		if row == nil {
+			continue
+		}
+		for col, cell := range row.Cells {
+			if cell == nil {
+				continue
+			}
+			cell.date1904 = to1904
+			switch cell.cellType {
+			case CellTypeString, CellTypeBool, CellTypeError:
+				continue
+			}
+			serial, err := strconv.ParseFloat(cell.Value, 64)
+			if err != nil || !hasDateParts(cell.NumFmt) {
+				continue
+			}
+			days := math.Floor(serial)
+			t, _ := TimeFromExcelSerial(days, from1904, policy)
+			converted := serial + (ExcelSerialFromTime(t, to1904, policy) - days)
+			cell.Value = strconv.FormatFloat(converted, 'f', -1, 64)
+			if converted < 0 && serial >= 0 && s.File != nil {
+				s.File.warn(fmt.Sprintf("cell '%s' of sheet '%s' is dated %s, before the 1904 date system begins",
+					CellRef{Col: col, Row: r}, s.Name, t.Format("2006-01-02")))
+			}
+		}
 	}
-	durationDays := time.Duration(intPart) * time.Hour * 24
-	durationPart := time.Duration(dayNanoSeconds * floatPart)
-	return date.Add(durationDays).Add(durationPart)
 }
@@ -1,8 +1,11 @@
 package xlsx
 
 import (
-	. "gopkg.in/check.v1"
+	"archive/zip"
+	"bytes"
 	"time"
+
+	. "gopkg.in/check.v1"
 )
 
 type DateSuite struct{}
@@ -74,3 +77,126 @@ func (d *DateSuite) TestTimeFromExcelTimeWith1904Offest(c *C) {
 	c.Assert(date1904Offset, Equals, time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC))
 
 }
+
+func (d *DateSuite) TestTimeFromExcelSerialCorrect(c *C) {
+	date, err := TimeFromExcelSerial(0, false, DatePolicyCorrect)
+	c.Assert(err, IsNil)
+	c.Assert(date, Equals, time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC))
+	date, err = TimeFromExcelSerial(60, false, DatePolicyCorrect)
+	c.Assert(err, IsNil)
+	c.Assert(date, Equals, time.Date(1900, 2, 28, 0, 0, 0, 0, time.UTC))
+	date, err = TimeFromExcelSerial(61, false, DatePolicyCorrect)
+	c.Assert(err, IsNil)
+	c.Assert(date, Equals, time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC))
+}
+
+func (d *DateSuite) TestTimeFromExcelSerialExcelCompatible(c *C) {
+	date, err := TimeFromExcelSerial(0, false, DatePolicyExcelCompatible)
+	c.Assert(err, IsNil)
+	c.Assert(date, Equals, time.Date(1899, 12, 31, 0, 0, 0, 0, time.UTC))
+	date, err = TimeFromExcelSerial(1.5, false, DatePolicyExcelCompatible)
+	c.Assert(err, IsNil)
+	c.Assert(date, Equals, time.Date(1900, 1, 1, 12, 0, 0, 0, time.UTC))
+	date, err = TimeFromExcelSerial(59, false, DatePolicyExcelCompatible)
+	c.Assert(err, IsNil)
+	c.Assert(date, Equals, time.Date(1900, 2, 28, 0, 0, 0, 0, time.UTC))
+	date, err = TimeFromExcelSerial(60.25, false, DatePolicyExcelCompatible)
+	c.Assert(err, Equals, ErrLeapDay1900)
+	c.Assert(date, Equals, time.Date(1900, 2, 28, 6, 0, 0, 0, time.UTC))
+	date, err = TimeFromExcelSerial(61, false, DatePolicyExcelCompatible)
+	c.Assert(err, IsNil)
+	c.Assert(date, Equals, time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC))
+
+	// The 1904 date system doesn't have the problem.
+	date, err = TimeFromExcelSerial(60, true, DatePolicyExcelCompatible)
+	c.Assert(err, IsNil)
+	c.Assert(date, Equals, time.Date(1904, 3, 1, 0, 0, 0, 0, time.UTC))
+}
+
+func (d *DateSuite) TestExcelSerialFromTime(c *C) {
+	c.Assert(ExcelSerialFromTime(time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC), false, DatePolicyCorrect), Equals, 2.0)
+	c.Assert(ExcelSerialFromTime(time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC), false, DatePolicyExcelCompatible), Equals, 1.0)
+	c.Assert(ExcelSerialFromTime(time.Date(1900, 2, 28, 18, 0, 0, 0, time.UTC), false, DatePolicyExcelCompatible), Equals, 59.75)
+	c.Assert(ExcelSerialFromTime(time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC), false, DatePolicyExcelCompatible), Equals, 61.0)
+	c.Assert(ExcelSerialFromTime(time.Date(1899, 12, 31, 0, 0, 0, 0, time.UTC), false, DatePolicyExcelCompatible), Equals, 0.0)
+	c.Assert(ExcelSerialFromTime(time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC), false, DatePolicyExcelCompatible), Equals, 41275.0)
+	c.Assert(ExcelSerialFromTime(time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC), true, DatePolicyExcelCompatible), Equals, 39813.0)
+	// Only the wall clock time counts.
+	est := time.FixedZone("EST", -5*60*60)
+	c.Assert(ExcelSerialFromTime(time.Date(2013, 1, 1, 12, 0, 0, 0, est), false, DatePolicyCorrect), Equals, 41275.5)
+}
+
+func (d *DateSuite) TestExcelSerialRoundTripNearEpoch(c *C) {
+	for _, policy := range []DatePolicy{DatePolicyCorrect, DatePolicyExcelCompatible} {
+		for _, date := range []time.Time{
+			time.Date(1899, 12, 31, 0, 0, 0, 1, time.UTC),
+			time.Date(1900, 1, 1, 13, 14, 15, 123456789, time.UTC),
+			time.Date(1900, 2, 28, 23, 59, 59, 999999999, time.UTC),
+			time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(1900, 3, 1, 8, 30, 0, 7, time.UTC),
+		} {
+			serial := ExcelSerialFromTime(date, false, policy)
+			back, err := TimeFromExcelSerial(serial, false, policy)
+			c.Assert(err, IsNil)
+			c.Assert(back, Equals, date)
+		}
+	}
+}
+
+func (d *DateSuite) TestCellDatePolicy(c *C) {
+	file := NewFile()
+	file.DatePolicy = DatePolicyExcelCompatible
+	sheet, _ := file.AddSheet("Sheet1")
+	cell := sheet.AddRow().AddCell()
+	cell.SetDate(time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC))
+	c.Assert(cell.Value, Equals, "1")
+	cell.NumFmt = "yyyy-mm-dd"
+	value, err := cell.FormattedValue()
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "1900-01-01")
+}
+
+func (d *DateSuite) TestSetDate1904(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	date := time.Date(2017, 3, 14, 13, 5, 0, 0, time.UTC)
+	sheet.Cell(0, 0).SetDateTime(date)
+	sheet.Cell(0, 1).SetDateTimeWithFormat(0.5, "hh:mm")
+	sheet.Cell(0, 2).SetFloatWithFormat(42808, "0.00")
+	sheet.Cell(0, 3).SetDate(time.Date(1903, 6, 1, 0, 0, 0, 0, time.UTC))
+	serial := sheet.Cell(0, 0).Value
+
+	file.SetDate1904(true)
+	c.Assert(file.Date1904, Equals, true)
+	value, err := sheet.Value("A1")
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, date)
+	c.Assert(sheet.Cell(0, 1).Value, Equals, "0.5")
+	c.Assert(sheet.Cell(0, 2).Value, Equals, "42808")
+	c.Assert(sheet.Cell(0, 3).Value, Equals, "-214")
+	c.Assert(file.Warnings, DeepEquals, []string{"cell 'D1' of sheet 'Sheet1' is dated 1903-06-01, before the 1904 date system begins"})
+
+	// Written and opened again in the 1900 date system.
+	var buf bytes.Buffer
+	c.Assert(file.Write(&buf), IsNil)
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	for _, options := range [][]FileOption{{Date1900System()}, {Date1900System(), LazySheets()}} {
+		reopened, err := readZipReader(zr, options)
+		c.Assert(err, IsNil)
+		c.Assert(reopened.Date1904, Equals, false)
+		c.Assert(reopened.Sheets[0].Cell(0, 0).Value, Equals, serial)
+		value, err = reopened.Sheets[0].Value("A1")
+		c.Assert(err, IsNil)
+		c.Assert(value, Equals, date)
+	}
+	reopened, err := readZipReader(zr, nil)
+	c.Assert(err, IsNil)
+	c.Assert(reopened.Date1904, Equals, true)
+	value, err = reopened.Sheets[0].Value("A1")
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, date)
+	formatted, err := reopened.Sheets[0].Cell(0, 0).FormattedValue()
+	c.Assert(err, IsNil)
+	c.Assert(formatted, Equals, "3/14/17 13:05")
+}
@@ -0,0 +1,225 @@
+package xlsx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The names of Excel's built-in defined names, each of which is local
+// to the sheet it applies to.
+const (
+	DefinedNamePrintArea       = "_xlnm.Print_Area"
+	DefinedNamePrintTitles     = "_xlnm.Print_Titles"
+	DefinedNameFilterDatabase  = "_xlnm._FilterDatabase"
+	DefinedNameCriteria        = "_xlnm.Criteria"
+	DefinedNameExtract         = "_xlnm.Extract"
+	DefinedNameConsolidateArea = "_xlnm.Consolidate_Area"
+	DefinedNameSheetTitle      = "_xlnm.Sheet_Title"
+)
+
+var builtInDefinedNames = []string{
+	DefinedNamePrintArea,
+	DefinedNamePrintTitles,
+	DefinedNameFilterDatabase,
+	DefinedNameCriteria,
+	DefinedNameExtract,
+	DefinedNameConsolidateArea,
+	DefinedNameSheetTitle,
+}
+
+// DefinedName is a name that formulas can use in place of the formula
+// it stands for, most often a reference to a range of cells.
+type DefinedName struct {
+	Name string
+	// RefersTo is the formula the name stands for, such as
+	// "Sheet1!$A$1:$B$10", without the "=" Excel shows before it.
+	RefersTo string
+	// Sheet is the name of the sheet the name is local to, on which
+	// it can be used without the sheet's name and hides a name of
+	// the workbook spelt the same way.  If it is "", the name
+	// belongs to the workbook.
+	Sheet string
+	// Hidden hides the name from Excel's Name Manager.
+	Hidden  bool
+	Comment string
+	// read is the name as it was read, whose other attributes are
+	// written back.
+	read xlsxDefinedName
+}
+
+// AddDefinedName defines a name for the whole workbook that stands for
+// refersTo, such as "Sheet1!$A$1:$B$10".  It returns an error if the
+// name isn't one Excel allows or the workbook already defines it.
+func (f *File) AddDefinedName(name, refersTo string) (*DefinedName, error) {
+	return f.addDefinedName(name, refersTo, "")
+}
+
+// AddDefinedName defines a name local to the Sheet that stands for
+// refersTo, as File.AddDefinedName does for the whole workbook.  The
+// built-in names, such as DefinedNamePrintArea, are defined this way.
+func (s *Sheet) AddDefinedName(name, refersTo string) (*DefinedName, error) {
+	if s.File == nil {
+		return nil, fmt.Errorf("sheet '%s' doesn't belong to a file", s.Name)
+	}
+	return s.File.addDefinedName(name, refersTo, s.Name)
+}
+
+func (f *File) addDefinedName(name, refersTo, sheet string) (*DefinedName, error) {
+	dn := &DefinedName{Name: name, RefersTo: strings.TrimPrefix(refersTo, "="), Sheet: sheet}
+	if problem := dn.problem(); problem != "" {
+		return nil, fmt.Errorf("%s", problem)
+	}
+	for _, other := range f.Names {
+		if other != nil && other.sameAs(dn) {
+			return nil, fmt.Errorf("defined name '%s' is already defined", name)
+		}
+	}
+	f.Names = append(f.Names, dn)
+	return dn, nil
+}
+
+// sameAs reports whether the names are spelt the same way, ignoring
+// case as Excel does, and belong to the same sheet or the workbook.
+func (dn *DefinedName) sameAs(other *DefinedName) bool {
+	return strings.EqualFold(dn.Name, other.Name) && strings.EqualFold(dn.Sheet, other.Sheet)
+}
+
+// problem describes what is wrong with the name itself, or returns ""
+// if nothing is.
+func (dn *DefinedName) problem() string {
+	if !strings.HasPrefix(strings.ToLower(dn.Name), "_xlnm.") {
+		return definedNameProblem(dn.Name)
+	}
+	for _, builtIn := range builtInDefinedNames {
+		if strings.EqualFold(dn.Name, builtIn) {
+			if dn.Sheet == "" {
+				return fmt.Sprintf("built-in name '%s' must be local to a sheet", dn.Name)
+			}
+			return ""
+		}
+	}
+	return fmt.Sprintf("defined name '%s' isn't one of Excel's built-in names", dn.Name)
+}
+
+// referenceProblem describes what is wrong with what the name refers
+// to, among the sheets of the File that are named in sheets by their
+// names in lower case, or returns "" if nothing obvious is.
+func (dn *DefinedName) referenceProblem(sheets map[string]string) string {
+	if strings.TrimSpace(dn.RefersTo) == "" {
+		return fmt.Sprintf("defined name '%s' refers to nothing", dn.Name)
+	}
+	if problem := formulaProblem(dn.RefersTo); problem != "" {
+		return fmt.Sprintf("defined name '%s': %s", dn.Name, problem)
+	}
+	for _, sheet := range formulaSheets(dn.RefersTo) {
+		if _, ok := sheets[strings.ToLower(sheet)]; !ok {
+			return fmt.Sprintf("defined name '%s' refers to sheet '%s', which doesn't exist", dn.Name, sheet)
+		}
+	}
+	return ""
+}
+
+// formulaSheets returns the names of the sheets of the workbook that
+// the formula refers to, as in "Sheet1!A1", "'Sheet 1'!A1" and each
+// end of "Sheet1:Sheet3!A1".  Sheets of other workbooks aren't
+// included.
+func formulaSheets(formula string) []string {
+	var sheets []string
+	add := func(name string) {
+		for _, sheet := range strings.Split(name, ":") {
+			sheets = append(sheets, sheet)
+		}
+	}
+	for i := 0; i < len(formula); {
+		c := formula[i]
+		switch {
+		case c == '"':
+			i = closingQuote(formula, i)
+		case c == '\'':
+			j := closingQuote(formula, i)
+			if j < len(formula) && formula[j] == '!' && j-i > 2 && formula[i+1] != '[' {
+				add(strings.Replace(formula[i+1:j-1], "''", "'", -1))
+			}
+			i = j
+		case c == '[':
+			// The sheets of other workbooks, and the columns of
+			// tables.
+			j := closingBracket(formula, i)
+			for j < len(formula) && (isNameByte(formula[j]) || formula[j] == ':') {
+				j++
+			}
+			i = j
+		case c == '#':
+			// Errors, such as #REF!, and the items of tables.
+			j := i + 1
+			for j < len(formula) && (isNameByte(formula[j]) || formula[j] == '/') {
+				j++
+			}
+			if j < len(formula) && (formula[j] == '!' || formula[j] == '?') {
+				j++
+			}
+			i = j
+		case isNameByte(c):
+			j := i
+			for j < len(formula) && (isNameByte(formula[j]) || formula[j] == ':') {
+				j++
+			}
+			if j < len(formula) && formula[j] == '!' {
+				add(formula[i:j])
+			}
+			i = j
+		default:
+			i++
+		}
+	}
+	return sheets
+}
+
+// readDefinedNames returns the names the workbook defines, with the
+// sheets they are local to found among the workbook's sheets.
+func readDefinedNames(workbook *xlsxWorkbook) []*DefinedName {
+	names := make([]*DefinedName, 0, len(workbook.DefinedNames.DefinedName))
+	for _, xName := range workbook.DefinedNames.DefinedName {
+		dn := &DefinedName{
+			Name:     xName.Name,
+			RefersTo: strings.TrimSpace(xName.Data),
+			Hidden:   xName.Hidden,
+			Comment:  xName.Comment,
+			read:     xName,
+		}
+		if id := xName.LocalSheetID; xName.hasLocalSheetID && id >= 0 && id < len(workbook.Sheets.Sheet) {
+			dn.Sheet = workbook.Sheets.Sheet[id].Name
+		}
+		names = append(names, dn)
+	}
+	return names
+}
+
+// makeDefinedNames returns the definedNames element for the File, as
+// it is written with sheets of the names given, in order.
+func (f *File) makeDefinedNames(sheets []string) (xlsxDefinedNames, error) {
+	var xNames xlsxDefinedNames
+	for _, dn := range f.Names {
+		if dn == nil {
+			continue
+		}
+		xName := dn.read
+		xName.Name, xName.Data, xName.Hidden, xName.Comment = dn.Name, dn.RefersTo, dn.Hidden, dn.Comment
+		xName.LocalSheetID, xName.hasLocalSheetID = 0, false
+		if dn.Sheet != "" {
+			id := -1
+			for i, sheet := range sheets {
+				if strings.EqualFold(sheet, dn.Sheet) {
+					id = i
+					break
+				}
+			}
+			if id < 0 {
+				return xNames, fmt.Errorf("defined name '%s' is local to sheet '%s', which doesn't exist", dn.Name, dn.Sheet)
+			}
+			xName.LocalSheetID, xName.hasLocalSheetID = id, true
+		}
+		xNames.DefinedName = append(xNames.DefinedName, xName)
+	}
+	return xNames, nil
+}
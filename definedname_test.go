@@ -0,0 +1,101 @@
+package xlsx
+
+import (
+	"bytes"
+
+	. "gopkg.in/check.v1"
+)
+
+type DefinedNameSuite struct{}
+
+var _ = Suite(&DefinedNameSuite{})
+
+func (s *DefinedNameSuite) TestAddDefinedName(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Data")
+	_, err := file.AddDefinedName("Rate", "=Data!$B$1")
+	c.Assert(err, IsNil)
+	local, err := sheet.AddDefinedName("rate", "Data!$B$2")
+	c.Assert(err, IsNil)
+	c.Assert(local.Sheet, Equals, "Data")
+	_, err = sheet.AddDefinedName(DefinedNamePrintArea, "Data!$A$1:$C$10")
+	c.Assert(err, IsNil)
+
+	_, err = file.AddDefinedName("RATE", "Data!$B$3")
+	c.Assert(err, ErrorMatches, "defined name 'RATE' is already defined")
+	_, err = file.AddDefinedName(DefinedNamePrintArea, "Data!$A$1")
+	c.Assert(err, ErrorMatches, "built-in name '_xlnm.Print_Area' must be local to a sheet")
+	_, err = sheet.AddDefinedName("_xlnm.Whatever", "Data!$A$1")
+	c.Assert(err, ErrorMatches, "defined name '_xlnm.Whatever' isn't one of Excel's built-in names")
+	_, err = file.AddDefinedName("A1", "Data!$A$1")
+	c.Assert(err, ErrorMatches, "defined name 'A1' looks like a cell reference")
+	c.Assert(file.Names, HasLen, 3)
+}
+
+func (s *DefinedNameSuite) TestFormulaSheets(c *C) {
+	cases := map[string][]string{
+		"Sheet1!$A$1":                  {"Sheet1"},
+		"'My ''Data'''!A1:B2,Other!C3": {"My 'Data'", "Other"},
+		"SUM(Jan:Dec!B2)":              {"Jan", "Dec"},
+		`#REF!+"Not!A1"+[1]Other!A1`:   nil,
+		"Table1[[#Headers],[Col]]":     nil,
+	}
+	for formula, expected := range cases {
+		c.Assert(formulaSheets(formula), DeepEquals, expected, Commentf(formula))
+	}
+}
+
+func (s *DefinedNameSuite) TestValidateReferences(c *C) {
+	file := NewFile()
+	file.AddSheet("Data")
+	file.Names = append(file.Names,
+		&DefinedName{Name: "Missing", RefersTo: "Gone!$A$1"},
+		&DefinedName{Name: "Empty"},
+		&DefinedName{Name: "Broken", RefersTo: "SUM(Data!A1"},
+		&DefinedName{Name: "Local", Sheet: "Gone", RefersTo: "Data!$A$1"},
+		&DefinedName{Name: DefinedNamePrintTitles, RefersTo: "Data!$1:$1"})
+	c.Assert(diagnosticStrings(file.Validate()), DeepEquals, []string{
+		"defined name 'Missing' refers to sheet 'Gone', which doesn't exist",
+		"defined name 'Empty' refers to nothing",
+		"defined name 'Broken': unbalanced parentheses in formula 'SUM(Data!A1'",
+		"defined name 'Local' is local to sheet 'Gone', which doesn't exist",
+		"built-in name '_xlnm.Print_Titles' must be local to a sheet",
+	})
+}
+
+func (s *DefinedNameSuite) TestWriteAndReadDefinedNames(c *C) {
+	file := NewFile()
+	file.AddSheet("First")
+	second, _ := file.AddSheet("Second")
+	total, _ := file.AddDefinedName("Total", "First!$A$1 & \"<x>\"")
+	total.Comment = "the total"
+	hidden, _ := second.AddDefinedName(DefinedNameFilterDatabase, "Second!$A$1:$C$9")
+	hidden.Hidden = true
+	first := file.Sheets[0]
+	_, err := first.AddDefinedName(DefinedNamePrintArea, "First!$A$1:$B$2")
+	c.Assert(err, IsNil)
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/workbook.xml"], Matches, `(?s).*<definedNames><definedName name="Total" comment="the total">First!\$A\$1 &amp; &#34;&lt;x&gt;&#34;</definedName><definedName name="_xlnm._FilterDatabase" localSheetId="1" hidden="true">Second!\$A\$1:\$C\$9</definedName><definedName localSheetId="0" name="_xlnm.Print_Area">First!\$A\$1:\$B\$2</definedName></definedNames>.*`)
+
+	var buf bytes.Buffer
+	c.Assert(file.Write(&buf), IsNil)
+	read, err := OpenBinary(buf.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(read.Names, HasLen, 3)
+	c.Assert(read.Names[0].Name, Equals, "Total")
+	c.Assert(read.Names[0].Sheet, Equals, "")
+	c.Assert(read.Names[0].RefersTo, Equals, `First!$A$1 & "<x>"`)
+	c.Assert(read.Names[1].Sheet, Equals, "Second")
+	c.Assert(read.Names[1].Hidden, Equals, true)
+	c.Assert(read.Names[2].Sheet, Equals, "First")
+	c.Assert(read.DefinedNames, HasLen, 3)
+	c.Assert(read.DefinedNames[2].LocalSheetID, Equals, 0)
+	c.Assert(read.DefinedNames[2].Data, Equals, "First!$A$1:$B$2")
+
+	// A name local to a sheet that isn't there can't be written.
+	file.Names = append(file.Names, &DefinedName{Name: "Lost", Sheet: "Third", RefersTo: "First!A1"})
+	_, err = file.MarshallParts()
+	c.Assert(err, ErrorMatches, "defined name 'Lost' is local to sheet 'Third', which doesn't exist")
+}
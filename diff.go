@@ -0,0 +1,266 @@
+package xlsx
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// DifferenceKind says what a Difference between two workbooks is.
+type DifferenceKind int
+
+const (
+	// SheetAdded is a sheet only the second workbook has.
+	SheetAdded DifferenceKind = iota
+	// SheetRemoved is a sheet only the first workbook has.
+	SheetRemoved
+	// ValueChanged is a cell whose value, or type of value, differs.
+	ValueChanged
+	// FormulaChanged is a cell whose formula differs.
+	FormulaChanged
+	// NumFmtChanged is a cell whose number format differs.
+	NumFmtChanged
+	// StyleChanged is a cell whose font, fill, border, alignment or
+	// protection differs.
+	StyleChanged
+)
+
+func (k DifferenceKind) String() string {
+	switch k {
+	case SheetAdded:
+		return "sheet added"
+	case SheetRemoved:
+		return "sheet removed"
+	case ValueChanged:
+		return "value changed"
+	case FormulaChanged:
+		return "formula changed"
+	case NumFmtChanged:
+		return "number format changed"
+	case StyleChanged:
+		return "style changed"
+	}
+	return fmt.Sprintf("DifferenceKind(%d)", int(k))
+}
+
+// Difference is one way in which two workbooks differ, as Compare
+// finds it.  Ref is the cell that differs, and is the zero CellRef for
+// sheets that are added or removed.  Old and New are the cell's values,
+// formulas or number formats in each workbook; a StyleChanged
+// Difference instead lists the parts of the style that differ in
+// Parts, such as "Font" or "Fill".
+type Difference struct {
+	Kind  DifferenceKind
+	Sheet string
+	Ref   CellRef
+	Old   string
+	New   string
+	Parts []string
+}
+
+// String describes the Difference on a line, such as
+//
+//	Data!B2: value changed from "1" to "2"
+func (d Difference) String() string {
+	switch d.Kind {
+	case SheetAdded, SheetRemoved:
+		return fmt.Sprintf("%s: %s", quoteSheetName(d.Sheet), d.Kind)
+	case StyleChanged:
+		return fmt.Sprintf("%s!%s: %s (%s)", quoteSheetName(d.Sheet), d.Ref, d.Kind, strings.Join(d.Parts, ", "))
+	}
+	return fmt.Sprintf("%s!%s: %s from %q to %q", quoteSheetName(d.Sheet), d.Ref, d.Kind, d.Old, d.New)
+}
+
+// Compare returns the differences between workbooks a and b, sheet by
+// sheet, matching sheets by name.  Sheets are taken in a's order, with
+// those only b has last, and cells row by row, so the result is the
+// same every time the same workbooks are compared, which makes it
+// suitable for checking a workbook against a golden file.
+//
+// A cell that one workbook doesn't have is compared as an empty cell
+// with the default style.  Values are compared as what they mean
+// rather than as they are written, so "1" and "1.0" in numeric cells
+// are the same, but a number and a string holding its digits are not.
+// Formulas are compared as they are written.  Sheets of Files opened
+// with LazySheets are loaded, and an error loading one is returned.
+func Compare(a, b *File) ([]Difference, error) {
+	var diffs []Difference
+	for _, sa := range a.Sheets {
+		sb := b.Sheet[sa.Name]
+		if sb == nil {
+			diffs = append(diffs, Difference{Kind: SheetRemoved, Sheet: sa.Name})
+			continue
+		}
+		sheetDiffs, err := compareSheets(sa, sb)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, sheetDiffs...)
+	}
+	for _, sb := range b.Sheets {
+		if a.Sheet[sb.Name] == nil {
+			diffs = append(diffs, Difference{Kind: SheetAdded, Sheet: sb.Name})
+		}
+	}
+	return diffs, nil
+}
+
+// compareSheets returns the differences between the cells of two
+// sheets of the same name.
+func compareSheets(a, b *Sheet) ([]Difference, error) {
+	if err := a.Load(); err != nil {
+		return nil, err
+	}
+	if err := b.Load(); err != nil {
+		return nil, err
+	}
+	rows := len(a.Rows)
+	if len(b.Rows) > rows {
+		rows = len(b.Rows)
+	}
+	// The styles of cells without one of their own.
+	defaultA, defaultB := a.File.newStyle(), b.File.newStyle()
+	var diffs []Difference
+	for r := 0; r < rows; r++ {
+		cols := rowLen(a, r)
+		if n := rowLen(b, r); n > cols {
+			cols = n
+		}
+		for c := 0; c < cols; c++ {
+			ca, cb := existingCell(a, r, c), existingCell(b, r, c)
+			if ca == nil && cb == nil {
+				continue
+			}
+			diff := Difference{Sheet: a.Name, Ref: CellRef{Col: c, Row: r}}
+			add := func(kind DifferenceKind, from, to string) {
+				diff.Kind, diff.Old, diff.New = kind, from, to
+				diffs = append(diffs, diff)
+			}
+			if !sameCellValue(ca, cb) {
+				add(ValueChanged, cellValue(ca).value, cellValue(cb).value)
+			}
+			if fa, fb := cellFormula(ca), cellFormula(cb); fa != fb {
+				add(FormulaChanged, fa, fb)
+			}
+			if na, nb := cellNumFmt(ca), cellNumFmt(cb); na != nb {
+				add(NumFmtChanged, na, nb)
+			}
+			if parts := styleDifferences(cellStyle(ca, defaultA), cellStyle(cb, defaultB)); len(parts) > 0 {
+				diff.Kind, diff.Old, diff.New, diff.Parts = StyleChanged, "", "", parts
+				diffs = append(diffs, diff)
+			}
+		}
+	}
+	return diffs, nil
+}
+
+// rowLen returns the number of cells in a sheet's row, which is zero
+// if it doesn't have the row.
+func rowLen(s *Sheet, row int) int {
+	if row >= len(s.Rows) || s.Rows[row] == nil {
+		return 0
+	}
+	return len(s.Rows[row].Cells)
+}
+
+// existingCell returns the cell of a sheet at the given row and column,
+// or nil if the sheet doesn't have one there, without adding it.
+func existingCell(s *Sheet, row, col int) *Cell {
+	if row >= len(s.Rows) || s.Rows[row] == nil || col >= len(s.Rows[row].Cells) {
+		return nil
+	}
+	return s.Rows[row].Cells[col]
+}
+
+// comparedValue is a cell's value as it is written and as it is
+// compared.
+type comparedValue struct {
+	value string
+	typed interface{}
+}
+
+func cellValue(c *Cell) comparedValue {
+	if c == nil {
+		return comparedValue{}
+	}
+	return comparedValue{c.Value, c.typedValue()}
+}
+
+// sameCellValue reports whether two cells have the same value, only
+// working out the typed values, which allocates, of cells that aren't
+// written and read the same way.
+func sameCellValue(a, b *Cell) bool {
+	if a != nil && b != nil && a.Value == b.Value && a.cellType == b.cellType && a.resultType == b.resultType &&
+		a.NumFmt == b.NumFmt && a.inDate1904() == b.inDate1904() && a.datePolicy() == b.datePolicy() {
+		return true
+	}
+	return sameValue(cellValue(a), cellValue(b))
+}
+
+func sameValue(a, b comparedValue) bool {
+	if ta, ok := a.typed.(time.Time); ok {
+		tb, ok := b.typed.(time.Time)
+		return ok && ta.Equal(tb)
+	}
+	return a.typed == b.typed
+}
+
+func cellFormula(c *Cell) string {
+	if c == nil {
+		return ""
+	}
+	return c.Formula()
+}
+
+func cellNumFmt(c *Cell) string {
+	if c == nil || c.NumFmt == "" {
+		return builtInNumFmt[builtInNumFmtIndex_GENERAL]
+	}
+	return c.NumFmt
+}
+
+// cellStyle returns the style of a cell, or defaultStyle, that of a
+// new cell of its sheet, if it has none.
+func cellStyle(c *Cell, defaultStyle *Style) *Style {
+	if c != nil && c.style != nil {
+		return c.style
+	}
+	return defaultStyle
+}
+
+// styleDifferences returns the names of the parts of two styles that
+// differ, or nil if none do.
+func styleDifferences(a, b *Style) []string {
+	if a == b {
+		return nil
+	}
+	var parts []string
+	if a.Font != b.Font {
+		parts = append(parts, "Font")
+	}
+	if !sameFill(a.Fill, b.Fill) {
+		parts = append(parts, "Fill")
+	}
+	if a.Border != b.Border {
+		parts = append(parts, "Border")
+	}
+	if a.Alignment != b.Alignment {
+		parts = append(parts, "Alignment")
+	}
+	if a.Protection != b.Protection {
+		parts = append(parts, "Protection")
+	}
+	return parts
+}
+
+// sameFill reports whether two fills are the same, gradients included.
+func sameFill(a, b Fill) bool {
+	if a.PatternType != b.PatternType || a.BgColor != b.BgColor || a.FgColor != b.FgColor {
+		return false
+	}
+	if a.Gradient == nil || b.Gradient == nil {
+		return a.Gradient == b.Gradient
+	}
+	return reflect.DeepEqual(*a.Gradient, *b.Gradient)
+}
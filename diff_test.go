@@ -0,0 +1,98 @@
+package xlsx
+
+import (
+	"bytes"
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+type DiffSuite struct{}
+
+var _ = Suite(&DiffSuite{})
+
+func (s *DiffSuite) TestCompare(c *C) {
+	build := func(other string) *File {
+		f := NewFile()
+		sheet, err := f.AddSheet("Data")
+		c.Assert(err, IsNil)
+		sheet.Cell(0, 0).SetString("Name")
+		sheet.Cell(0, 1).SetFloat(1)
+		sheet.Cell(1, 0).SetFormula("B1*2")
+		sheet.Cell(1, 1).SetDateTimeWithFormat(45352, "yyyy-mm-dd")
+		_, err = f.AddSheet(other)
+		c.Assert(err, IsNil)
+		return f
+	}
+	a, b := build("Old"), build("Old")
+	diffs, err := Compare(a, b)
+	c.Assert(err, IsNil)
+	c.Assert(diffs, HasLen, 0)
+
+	b = build("New")
+	sheet := b.Sheet["Data"]
+	sheet.Cell(0, 0).SetString("Title")
+	// The same number, written differently.
+	sheet.Cell(0, 1).Value = "1.0"
+	sheet.Cell(1, 0).SetFormula("B1*3")
+	sheet.Cell(1, 1).NumFmt = "dd/mm/yyyy"
+	sheet.Cell(0, 0).GetStyle().Font.Bold = true
+	sheet.Cell(2, 3).SetInt(7)
+
+	diffs, err = Compare(a, b)
+	c.Assert(err, IsNil)
+	var lines []string
+	for _, diff := range diffs {
+		lines = append(lines, diff.String())
+	}
+	c.Assert(lines, DeepEquals, []string{
+		`Data!A1: value changed from "Name" to "Title"`,
+		`Data!A1: style changed (Font)`,
+		`Data!A2: formula changed from "B1*2" to "B1*3"`,
+		`Data!B2: number format changed from "yyyy-mm-dd" to "dd/mm/yyyy"`,
+		`Data!D3: value changed from "" to "7"`,
+		`Old: sheet removed`,
+		`New: sheet added`,
+	})
+	c.Assert(diffs[0].Kind, Equals, ValueChanged)
+	c.Assert(diffs[0].Ref, Equals, CellRef{})
+	c.Assert(diffs[4].Ref, Equals, CellRef{Col: 3, Row: 2})
+}
+
+func (s *DiffSuite) TestCompareReadFile(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("My Sheet")
+	c.Assert(err, IsNil)
+	sheet.Cell(0, 0).SetFloatWithFormat(0.5, "0%")
+	sheet.Cell(0, 1).SetBool(true)
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	golden, err := OpenBinary(buf.Bytes())
+	c.Assert(err, IsNil)
+
+	sheet.Cell(0, 1).SetString("TRUE")
+	diffs, err := Compare(golden, f)
+	c.Assert(err, IsNil)
+	c.Assert(diffs, HasLen, 1)
+	c.Assert(diffs[0].String(), Equals, `'My Sheet'!B1: value changed from "1" to "TRUE"`)
+}
+
+// Comparing sheets that are the same doesn't allocate for each cell,
+// nor for the cells a row doesn't have.
+func (s *DiffSuite) TestCompareSheetsAllocations(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Data")
+	for col := 0; col < 200; col++ {
+		sheet.Cell(0, col).SetInt(col)
+	}
+	for row := 1; row < 200; row++ {
+		sheet.Cell(row, 0).SetString("x")
+	}
+	allocs := testing.AllocsPerRun(5, func() {
+		diffs, err := compareSheets(sheet, sheet)
+		if err != nil || len(diffs) != 0 {
+			panic("the sheet differs from itself")
+		}
+	})
+	c.Assert(allocs < 20, Equals, true, Commentf("%v allocations", allocs))
+}
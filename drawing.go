@@ -1,5 +1,15 @@
 package xlsx
 
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"image"
+	"io/ioutil"
+	"path"
+	"sort"
+)
+
 /*
 
 width 1 = 76200
@@ -16,12 +26,97 @@ const (
 	IMAGE_TYPE_JPG ImageType = iota
 	IMAGE_TYPE_GIF
 	IMAGE_TYPE_PNG
+	IMAGE_TYPE_BMP
+	IMAGE_TYPE_TIFF
+	IMAGE_TYPE_WEBP
+	// IMAGE_TYPE_SVG images are shown by recent versions of
+	// Excel; other applications show the Drawing's
+	// FallbackImageData instead.
+	IMAGE_TYPE_SVG
 )
 
 const (
-	IMAGE_EXT_JPG = ".jpeg"
-	IMAGE_EXT_GIF = ".gif"
-	IMAGE_EXT_PNG = ".png"
+	IMAGE_EXT_JPG  = ".jpeg"
+	IMAGE_EXT_GIF  = ".gif"
+	IMAGE_EXT_PNG  = ".png"
+	IMAGE_EXT_BMP  = ".bmp"
+	IMAGE_EXT_TIFF = ".tiff"
+	IMAGE_EXT_WEBP = ".webp"
+	IMAGE_EXT_SVG  = ".svg"
+)
+
+// extension returns the file extension of images of the type.
+func (t ImageType) extension() string {
+	switch t {
+	case IMAGE_TYPE_JPG:
+		return IMAGE_EXT_JPG
+	case IMAGE_TYPE_GIF:
+		return IMAGE_EXT_GIF
+	case IMAGE_TYPE_PNG:
+		return IMAGE_EXT_PNG
+	case IMAGE_TYPE_BMP:
+		return IMAGE_EXT_BMP
+	case IMAGE_TYPE_TIFF:
+		return IMAGE_EXT_TIFF
+	case IMAGE_TYPE_WEBP:
+		return IMAGE_EXT_WEBP
+	case IMAGE_TYPE_SVG:
+		return IMAGE_EXT_SVG
+	}
+	return ""
+}
+
+// contentType returns the MIME type of images of the type.
+func (t ImageType) contentType() string {
+	switch t {
+	case IMAGE_TYPE_JPG:
+		return "image/jpeg"
+	case IMAGE_TYPE_GIF:
+		return "image/gif"
+	case IMAGE_TYPE_PNG:
+		return "image/png"
+	case IMAGE_TYPE_BMP:
+		return "image/bmp"
+	case IMAGE_TYPE_TIFF:
+		return "image/tiff"
+	case IMAGE_TYPE_WEBP:
+		return "image/webp"
+	case IMAGE_TYPE_SVG:
+		return "image/svg+xml"
+	}
+	return ""
+}
+
+// AnchorType determines how a picture is fixed to its Sheet.
+type AnchorType int
+
+const (
+	// AnchorTwoCell fixes the corners of the picture to cells,
+	// so that it stretches when they're resized.
+	AnchorTwoCell AnchorType = iota
+	// AnchorOneCell fixes the top left corner of the picture to
+	// its TopLeftCell, keeping its size.
+	AnchorOneCell
+	// AnchorAbsolute places the picture at a position on the
+	// Sheet, regardless of the cells.
+	AnchorAbsolute
+)
+
+// The number of English Metric Units, the unit drawings are measured
+// in, in a pixel at 96 DPI, a point and a centimetre.
+const (
+	EMUPerPixel = 9525
+	EMUPerPoint = 12700
+	EMUPerCM    = 360000
+)
+
+const (
+	// maxDigitWidth is the width in pixels of the widest digit of
+	// the default font, which column widths are measured in.
+	maxDigitWidth = 7
+	// defaultRowHeight is the height in points of rows in the
+	// default font.
+	defaultRowHeight = 15
 )
 
 const (
@@ -41,9 +136,482 @@ type Drawing struct {
 	ColCount    int
 	Width       int
 	Height      int
+	// Name and Description are the picture's name and its
+	// alternative text.
+	Name        string
+	Description string
+	// Anchor determines how the picture is fixed to the Sheet.
+	Anchor AnchorType
+	// OffsetX and OffsetY are how far, in EMUs, the picture's top
+	// left corner is from that of its TopLeftCell - or, for
+	// AnchorAbsolute, from that of the Sheet.  In a Sheet laid out
+	// from right to left, the corners are the top right ones, and
+	// TopLeftCell is the cell at the picture's top right.
+	OffsetX int
+	OffsetY int
+	// ExtentX and ExtentY are the size of the picture in EMUs,
+	// when it isn't anchored to two cells or has neither a
+	// RowCount nor a ColCount.  If they are zero the size of the
+	// image, in pixels, is used.
+	ExtentX int
+	ExtentY int
+	// FallbackImageData is the PNG, JPEG or GIF image shown in
+	// place of an IMAGE_TYPE_SVG image by applications that can't
+	// show SVG.  If it is nil, a blank image is written.
+	FallbackImageData []byte
+}
+
+// SetBackground makes the image tile the Sheet behind its cells, as a
+// watermark might.  Excel can't show SVG images as backgrounds.  An
+// empty image removes the background.
+func (s *Sheet) SetBackground(data []byte, imageType ImageType) error {
+	if imageType == IMAGE_TYPE_SVG {
+		return fmt.Errorf("sheet '%s': an SVG image can't be a background", s.Name)
+	}
+	if len(data) == 0 {
+		s.Background = nil
+		return nil
+	}
+	s.Background, s.BackgroundType = data, imageType
+	return nil
+}
+
+// anchorDrawingObject adds an anchor placing the picture or shape
+// where d is placed to the drawing.
+func (s *Sheet) anchorDrawingObject(xDrawing *xlsxDrawing, d *Drawing, object drawingObject) {
+	switch d.Anchor {
+	case AnchorOneCell:
+		cx, cy := d.extent()
+		xDrawing.AddDrawingOneCellAnchor(d.TopLeftCell.ColNum, d.OffsetX, d.TopLeftCell.RowNum, d.OffsetY, cx, cy, object)
+	case AnchorAbsolute:
+		cx, cy := d.extent()
+		xDrawing.AddDrawingAbsoluteAnchor(d.OffsetX, d.OffsetY, cx, cy, object)
+	default:
+		toCol, toColOff, toRow, toRowOff := s.twoCellEnd(d)
+		xDrawing.AddDrawingTwoCellAnchor(d.TopLeftCell.ColNum, d.OffsetX, d.TopLeftCell.RowNum, d.OffsetY, toCol, toColOff, toRow, toRowOff, object)
+		s.File.logf("sheet '%s': object %d spans %s to %s, ending %d by %d EMUs into it", s.Name, len(xDrawing.Anchors),
+			getCellIDStringFromCoords(d.TopLeftCell.ColNum, d.TopLeftCell.RowNum),
+			getCellIDStringFromCoords(toCol, toRow), toColOff, toRowOff)
+	}
+}
+
+// nameDrawingPic gives the n'th picture of a drawing its id, name and
+// description.
+func (d *Drawing) nameDrawingPic(pic *drawingPic, n int) {
+	pic.NvPicPr.CNvPr.Id = n
+	pic.NvPicPr.CNvPr.Name = d.Name
+	if pic.NvPicPr.CNvPr.Name == "" {
+		pic.NvPicPr.CNvPr.Name = fmt.Sprintf("Picture %d", n)
+	}
+	pic.NvPicPr.CNvPr.Description = d.Description
+}
+
+// extent returns the size of the picture in EMUs.
+func (d *Drawing) extent() (int, int) {
+	cx, cy := d.ExtentX, d.ExtentY
+	if cx == 0 {
+		cx = d.Width * EMUPerPixel
+	}
+	if cy == 0 {
+		cy = d.Height * EMUPerPixel
+	}
+	return cx, cy
+}
+
+// twoCellEnd returns the cell that the bottom right corner of a
+// picture anchored to two cells lies in, and its offset in EMUs from
+// the top left corner of that cell.  A picture with only a RowCount or
+// a ColCount keeps the aspect ratio of its image.
+func (s *Sheet) twoCellEnd(d *Drawing) (toCol, toColOff, toRow, toRowOff int) {
+	cx, cy := d.extent()
+	switch {
+	case d.RowCount > 0 && d.ColCount > 0:
+		return d.TopLeftCell.ColNum + d.ColCount, 0, d.TopLeftCell.RowNum + d.RowCount, 0
+	case d.RowCount > 0:
+		cy = -d.OffsetY
+		for row := d.TopLeftCell.RowNum; row < d.TopLeftCell.RowNum+d.RowCount; row++ {
+			cy += s.rowHeightEMU(row)
+		}
+		cx = 0
+		if d.Height > 0 {
+			cx = int(float64(cy) * float64(d.Width) / float64(d.Height))
+		}
+		toCol, toColOff = spanCells(d.TopLeftCell.ColNum, d.OffsetX+cx, s.colWidthEMU)
+		return toCol, toColOff, d.TopLeftCell.RowNum + d.RowCount, 0
+	case d.ColCount > 0:
+		cx = -d.OffsetX
+		for col := d.TopLeftCell.ColNum; col < d.TopLeftCell.ColNum+d.ColCount; col++ {
+			cx += s.colWidthEMU(col)
+		}
+		cy = 0
+		if d.Width > 0 {
+			cy = int(float64(cx) * float64(d.Height) / float64(d.Width))
+		}
+		toRow, toRowOff = spanCells(d.TopLeftCell.RowNum, d.OffsetY+cy, s.rowHeightEMU)
+		return d.TopLeftCell.ColNum + d.ColCount, 0, toRow, toRowOff
+	}
+	toCol, toColOff = spanCells(d.TopLeftCell.ColNum, d.OffsetX+cx, s.colWidthEMU)
+	toRow, toRowOff = spanCells(d.TopLeftCell.RowNum, d.OffsetY+cy, s.rowHeightEMU)
+	return toCol, toColOff, toRow, toRowOff
+}
+
+// spanCells returns the cell, and the offset within it, that lies
+// length EMUs from the start of cell start, given the size of each
+// cell.
+func spanCells(start, length int, size func(int) int) (int, int) {
+	cell := start
+	for n := size(cell); length >= n; n = size(cell) {
+		length -= n
+		cell++
+	}
+	return cell, length
+}
+
+// colWidthEMU returns the width in EMUs of the column with the zero
+// based index col.
+func (s *Sheet) colWidthEMU(col int) int {
+	width := s.SheetFormat.DefaultColWidth
+	if width == 0 {
+		width = ColWidth
+	}
+	if c := s.Cols.FindCol(col); c != nil {
+		if c.Hidden {
+			return 0
+		}
+		if c.Width != 0 {
+			width = c.Width
+		}
+	}
+	return int(width*maxDigitWidth+0.5) * EMUPerPixel
+}
+
+// rowHeightEMU returns the height in EMUs of the row with the zero
+// based index row.
+func (s *Sheet) rowHeightEMU(row int) int {
+	height := s.SheetFormat.DefaultRowHeight
+	if height == 0 {
+		height = defaultRowHeight
+	}
+	if row < len(s.Rows) && s.Rows[row] != nil {
+		if s.Rows[row].Hidden {
+			return 0
+		}
+		if s.Rows[row].HasCustomHeight() {
+			height = s.Rows[row].Height
+		}
+	}
+	return int(height * EMUPerPoint)
 }
 
 type DrawingCell struct {
 	RowNum int
 	ColNum int
 }
+
+const (
+	relTypeDrawing = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/drawing"
+	relTypeImage   = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/image"
+)
+
+// Pictures returns the pictures on the Sheet: those read from its
+// file as well as those added with InsertImage.  Changing a picture's
+// TopLeftCell, RowCount or ColCount moves or resizes it; its image is
+// in ImageData.  RemovePicture and ReanchorPicture take the pictures
+// it returns.
+func (s *Sheet) Pictures() []*Drawing {
+	s.ensureLoaded()
+	pictures := make([]*Drawing, len(s.Drawings))
+	for i := range s.Drawings {
+		pictures[i] = &s.Drawings[i]
+	}
+	return pictures
+}
+
+// pictureIndex returns the index in the Sheet's Drawings of a picture
+// Pictures returned, or an error if it isn't one of them.
+func (s *Sheet) pictureIndex(d *Drawing) (int, error) {
+	for i := range s.Drawings {
+		if &s.Drawings[i] == d {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("sheet '%s': the picture isn't on the sheet", s.Name)
+}
+
+// RemovePicture removes a picture Pictures returned from the Sheet.
+// The pictures after it move down, so Pictures must be called again
+// for their new places once one is removed.
+func (s *Sheet) RemovePicture(d *Drawing) error {
+	i, err := s.pictureIndex(d)
+	if err != nil {
+		return err
+	}
+	s.Drawings = append(s.Drawings[:i], s.Drawings[i+1:]...)
+	return nil
+}
+
+// ReanchorPicture fixes a picture Pictures returned to the Sheet with
+// the anchor given, moving its top left corner to that of the cell at
+// the zero based row and col, and keeping its size as it is now.  A
+// picture anchored to two cells with a RowCount or ColCount is given
+// the size those cells have, and is then sized by its ExtentX and
+// ExtentY instead.  An AnchorAbsolute picture is placed where the cell
+// is, given the widths of the columns and heights of the rows before
+// it.
+func (s *Sheet) ReanchorPicture(d *Drawing, anchor AnchorType, row, col int) error {
+	if _, err := s.pictureIndex(d); err != nil {
+		return err
+	}
+	if d.Anchor == AnchorTwoCell && (d.RowCount > 0 || d.ColCount > 0) {
+		toCol, toColOff, toRow, toRowOff := s.twoCellEnd(d)
+		d.ExtentX, d.ExtentY = toColOff-d.OffsetX, toRowOff-d.OffsetY
+		for c := d.TopLeftCell.ColNum; c < toCol; c++ {
+			d.ExtentX += s.colWidthEMU(c)
+		}
+		for r := d.TopLeftCell.RowNum; r < toRow; r++ {
+			d.ExtentY += s.rowHeightEMU(r)
+		}
+	} else {
+		d.ExtentX, d.ExtentY = d.extent()
+	}
+	d.RowCount, d.ColCount = 0, 0
+	d.Anchor = anchor
+	d.TopLeftCell = DrawingCell{RowNum: row, ColNum: col}
+	d.OffsetX, d.OffsetY = 0, 0
+	if anchor == AnchorAbsolute {
+		d.TopLeftCell = DrawingCell{}
+		for c := 0; c < col; c++ {
+			d.OffsetX += s.colWidthEMU(c)
+		}
+		for r := 0; r < row; r++ {
+			d.OffsetY += s.rowHeightEMU(r)
+		}
+	}
+	return nil
+}
+
+// decodeImage works out the type and size of an image.
+func decodeImage(data []byte) (ImageType, image.Config, error) {
+	config, formatName, err := image.DecodeConfig(bytes.NewReader(data))
+	if err == image.ErrFormat {
+		return decodeImageHeader(data)
+	}
+	if err != nil {
+		return 0, config, err
+	}
+	switch formatName {
+	case "jpg", "jpeg":
+		return IMAGE_TYPE_JPG, config, nil
+	case "png":
+		return IMAGE_TYPE_PNG, config, nil
+	case "gif":
+		return IMAGE_TYPE_GIF, config, nil
+	case "bmp":
+		return IMAGE_TYPE_BMP, config, nil
+	case "tiff":
+		return IMAGE_TYPE_TIFF, config, nil
+	case "webp":
+		return IMAGE_TYPE_WEBP, config, nil
+	}
+	return 0, config, fmt.Errorf("images in %s format aren't supported", formatName)
+}
+
+// relationshipsPartName returns the name of the part holding the
+// relationships of the given part.
+func relationshipsPartName(part string) string {
+	dir, name := path.Split(part)
+	return dir + "_rels/" + name + ".rels"
+}
+
+// readRelationships returns the relationships of the named part by
+// their ids, with the targets of internal ones resolved to normalized
+// part names.  A part without a relationships part has none.
+func (f *File) readRelationships(part string) (map[string]xlsxWorksheetRelationship, error) {
+	zf, ok := f.parts[relationshipsPartName(part)]
+	if !ok {
+		return nil, nil
+	}
+	rc, err := f.openPart(zf)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	var xRels struct {
+		Relationships []xlsxWorksheetRelationship `xml:"Relationship"`
+	}
+	if err := newPartDecoder(rc).Decode(&xRels); err != nil {
+		return nil, err
+	}
+	rels := make(map[string]xlsxWorksheetRelationship, len(xRels.Relationships))
+	for _, r := range xRels.Relationships {
+		if r.TargetMode != "External" {
+			r.Target = resolveRelTarget(part, r.Target)
+		}
+		rels[r.Id] = r
+	}
+	return rels, nil
+}
+
+// readDrawings reads the pictures in the drawing that the worksheet
+// part refers to into the Sheet's Drawings.  Pictures whose images are
+// missing or of a type that isn't supported are left out, with a
+// warning.
+func (f *File) readDrawings(sheet *Sheet, worksheetPart string) error {
+	rels, err := f.readRelationships(worksheetPart)
+	if err != nil {
+		return err
+	}
+	var drawingParts []string
+	for _, rel := range rels {
+		if rel.Type == relTypeDrawing {
+			drawingParts = append(drawingParts, rel.Target)
+		}
+	}
+	sort.Strings(drawingParts)
+	for _, drawingPart := range drawingParts {
+		zf, ok := f.parts[drawingPart]
+		if !ok {
+			f.warn(fmt.Sprintf("sheet '%s': drawing %s doesn't exist", sheet.Name, drawingPart))
+			continue
+		}
+		rc, err := f.openPart(zf)
+		if err != nil {
+			return err
+		}
+		xDrawing := new(xlsxReadDrawing)
+		err = newPartDecoder(rc).Decode(xDrawing)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		imageRels, err := f.readRelationships(drawingPart)
+		if err != nil {
+			return err
+		}
+		for _, anchor := range xDrawing.Anchors {
+			if anchor.Sp != nil {
+				var placement Drawing
+				if readAnchorPlacement(anchor, &placement) {
+					shape := readShape(anchor.Sp)
+					shape.setPlacement(placement)
+					shape.read, shape.picturesBefore = true, len(sheet.Drawings)
+					sheet.Shapes = append(sheet.Shapes, shape)
+				}
+				continue
+			}
+			if anchor.Pic == nil {
+				continue
+			}
+			name := anchor.Pic.CNvPr.Name
+			rel, ok := imageRels[anchor.Pic.Blip.Embed]
+			if !ok || rel.Type != relTypeImage || f.parts[rel.Target] == nil {
+				f.warn(fmt.Sprintf("sheet '%s': the image of picture '%s' doesn't exist", sheet.Name, name))
+				continue
+			}
+			data, err := f.readBinaryPart(f.parts[rel.Target])
+			if err != nil {
+				return err
+			}
+			var fallback []byte
+			if svgRel, ok := imageRels[anchor.Pic.Blip.SVGBlip.Embed]; ok && f.parts[svgRel.Target] != nil {
+				// The image embedded in the blip itself is
+				// the fallback for the SVG image.
+				fallback = data
+				data, err = f.readBinaryPart(f.parts[svgRel.Target])
+				if err != nil {
+					return err
+				}
+			}
+			imageType, config, err := decodeImage(data)
+			if err != nil {
+				f.warn(fmt.Sprintf("sheet '%s': left out picture '%s': %s", sheet.Name, name, err))
+				continue
+			}
+			drawing := Drawing{
+				Sheet:             sheet,
+				ImageData:         data,
+				ImageType:         imageType,
+				Width:             config.Width,
+				Height:            config.Height,
+				Name:              name,
+				Description:       anchor.Pic.CNvPr.Description,
+				FallbackImageData: fallback,
+			}
+			if readAnchorPlacement(anchor, &drawing) {
+				sheet.Drawings = append(sheet.Drawings, drawing)
+			}
+		}
+	}
+	return nil
+}
+
+// readAnchorPlacement places d as the anchor says, reporting whether
+// the anchor could be understood.
+func readAnchorPlacement(anchor xlsxReadAnchor, d *Drawing) bool {
+	switch {
+	case anchor.XMLName.Local == "absoluteAnchor" && anchor.Pos != nil:
+		d.Anchor = AnchorAbsolute
+		d.OffsetX, d.OffsetY = anchor.Pos.X, anchor.Pos.Y
+	case anchor.From == nil:
+		return false
+	case anchor.XMLName.Local == "oneCellAnchor":
+		d.Anchor = AnchorOneCell
+	case anchor.To != nil:
+		d.RowCount = anchor.To.Row - anchor.From.Row
+		d.ColCount = anchor.To.Col - anchor.From.Col
+	}
+	if anchor.From != nil {
+		d.TopLeftCell = DrawingCell{RowNum: anchor.From.Row, ColNum: anchor.From.Col}
+		d.OffsetX, d.OffsetY = anchor.From.ColOffset, anchor.From.RowOffset
+	}
+	if anchor.Ext != nil && d.Anchor != AnchorTwoCell {
+		d.ExtentX, d.ExtentY = anchor.Ext.CX, anchor.Ext.CY
+	}
+	return true
+}
+
+// readBackground reads the background image of the worksheet part,
+// which its picture element relates it to by id, into the Sheet.  The
+// worksheet's other images, such as the icons of embedded objects,
+// aren't its background.
+func (f *File) readBackground(sheet *Sheet, worksheetPart, id string) error {
+	if id == "" {
+		return nil
+	}
+	rels, err := f.readRelationships(worksheetPart)
+	if err != nil {
+		return err
+	}
+	for _, rel := range rels {
+		if rel.Id != id || rel.Type != relTypeImage {
+			continue
+		}
+		if f.parts[rel.Target] == nil {
+			f.warn(fmt.Sprintf("sheet '%s': the background image %s doesn't exist", sheet.Name, rel.Target))
+			return nil
+		}
+		data, err := f.readBinaryPart(f.parts[rel.Target])
+		if err != nil {
+			return err
+		}
+		imageType, _, err := decodeImage(data)
+		if err != nil {
+			f.warn(fmt.Sprintf("sheet '%s': left out the background image: %s", sheet.Name, err))
+			return nil
+		}
+		sheet.Background, sheet.BackgroundType = data, imageType
+		return nil
+	}
+	return nil
+}
+
+// readBinaryPart returns the content of a part that isn't XML, such
+// as an image.
+func (f *File) readBinaryPart(part *zip.File) ([]byte, error) {
+	rc, err := f.openRawPart(part)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
@@ -0,0 +1,358 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type DrawingSuite struct{}
+
+var _ = Suite(&DrawingSuite{})
+
+func makePNG(c *C, width, height int) []byte {
+	var buf bytes.Buffer
+	c.Assert(png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))), IsNil)
+	return buf.Bytes()
+}
+
+func (d *DrawingSuite) TestReadPictures(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	sheet.AddRow().AddCell().SetString("logo")
+	data := makePNG(c, 40, 20)
+	sheet.Drawings = append(sheet.Drawings, Drawing{
+		Sheet:       sheet,
+		ImageData:   data,
+		ImageType:   IMAGE_TYPE_PNG,
+		TopLeftCell: DrawingCell{RowNum: 1, ColNum: 2},
+		RowCount:    3,
+		ColCount:    4,
+		Width:       40,
+		Height:      20,
+		Description: "Our logo",
+	})
+
+	var buf bytes.Buffer
+	c.Assert(file.Write(&buf), IsNil)
+	written, err := OpenBinary(buf.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(written.Warnings, HasLen, 0)
+	pictures := written.Sheets[0].Pictures()
+	c.Assert(pictures, HasLen, 1)
+	picture := pictures[0]
+	c.Assert(picture.Sheet, Equals, written.Sheets[0])
+	c.Assert(picture.ImageData, DeepEquals, data)
+	c.Assert(picture.ImageType, Equals, IMAGE_TYPE_PNG)
+	c.Assert(picture.TopLeftCell, Equals, DrawingCell{RowNum: 1, ColNum: 2})
+	c.Assert(picture.RowCount, Equals, 3)
+	c.Assert(picture.ColCount, Equals, 4)
+	c.Assert(picture.Width, Equals, 40)
+	c.Assert(picture.Height, Equals, 20)
+	c.Assert(picture.Name, Equals, "Picture 1")
+	c.Assert(picture.Description, Equals, "Our logo")
+
+	// Moving the picture and writing the file again carries it
+	// through.
+	picture.TopLeftCell = DrawingCell{RowNum: 5, ColNum: 0}
+	buf.Reset()
+	c.Assert(written.Write(&buf), IsNil)
+	again, err := OpenBinary(buf.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(again.Sheets[0].Pictures(), HasLen, 1)
+	c.Assert(again.Sheets[0].Pictures()[0].TopLeftCell, Equals, DrawingCell{RowNum: 5, ColNum: 0})
+	c.Assert(again.Sheets[0].Pictures()[0].ImageData, DeepEquals, data)
+}
+
+func (d *DrawingSuite) TestReadPictureWithMissingImage(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	sheet.AddRow().AddCell().SetString("logo")
+	sheet.Drawings = append(sheet.Drawings, Drawing{
+		Sheet:     sheet,
+		ImageData: makePNG(c, 1, 1),
+		ImageType: IMAGE_TYPE_PNG,
+		RowCount:  1,
+		ColCount:  1,
+	})
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	delete(parts, "xl/media/image1.png")
+	zr := makeZipReader(c, parts)
+	written, err := ReadZipReader(zr)
+	c.Assert(err, IsNil)
+	c.Assert(written.Sheets[0].Pictures(), HasLen, 0)
+	c.Assert(written.Warnings, DeepEquals, []string{"sheet 'Sheet1': the image of picture 'Picture 1' doesn't exist"})
+}
+
+func (d *DrawingSuite) TestPictureAnchors(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	sheet.AddRow().AddCell().SetString("logos")
+	data := makePNG(c, 40, 20)
+	sheet.Drawings = append(sheet.Drawings,
+		Drawing{Sheet: sheet, ImageData: data, ImageType: IMAGE_TYPE_PNG,
+			Anchor: AnchorOneCell, TopLeftCell: DrawingCell{RowNum: 2, ColNum: 1},
+			OffsetX: 5 * EMUPerPixel, OffsetY: 3 * EMUPerPixel, Width: 40, Height: 20},
+		Drawing{Sheet: sheet, ImageData: data, ImageType: IMAGE_TYPE_PNG,
+			Anchor: AnchorAbsolute, OffsetX: 100 * EMUPerPixel, OffsetY: 50 * EMUPerPixel,
+			ExtentX: 80 * EMUPerPixel, ExtentY: 40 * EMUPerPixel, Width: 40, Height: 20},
+	)
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	drawing := parts["xl/drawings/drawing1.xml"]
+	c.Assert(drawing, Matches, `(?s).*<xdr:oneCellAnchor>.*<xdr:absoluteAnchor>.*`)
+	c.Assert(drawing, Matches, `(?s).*<xdr:ext cx="381000" cy="190500"></xdr:ext>.*`)
+	c.Assert(drawing, Matches, `(?s).*<xdr:pos x="952500" y="476250"></xdr:pos>.*`)
+
+	written, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	pictures := written.Sheets[0].Pictures()
+	c.Assert(pictures, HasLen, 2)
+	c.Assert(pictures[0].Anchor, Equals, AnchorOneCell)
+	c.Assert(pictures[0].TopLeftCell, Equals, DrawingCell{RowNum: 2, ColNum: 1})
+	c.Assert(pictures[0].OffsetX, Equals, 5*EMUPerPixel)
+	c.Assert(pictures[0].OffsetY, Equals, 3*EMUPerPixel)
+	c.Assert(pictures[0].ExtentX, Equals, 40*EMUPerPixel)
+	c.Assert(pictures[0].ExtentY, Equals, 20*EMUPerPixel)
+	c.Assert(pictures[1].Anchor, Equals, AnchorAbsolute)
+	c.Assert(pictures[1].OffsetX, Equals, 100*EMUPerPixel)
+	c.Assert(pictures[1].OffsetY, Equals, 50*EMUPerPixel)
+	c.Assert(pictures[1].ExtentX, Equals, 80*EMUPerPixel)
+	c.Assert(pictures[1].ExtentY, Equals, 40*EMUPerPixel)
+	c.Assert(pictures[1].Name, Equals, "Picture 2")
+}
+
+func (d *DrawingSuite) TestInsertImageScaled(c *C) {
+	path := filepath.Join(c.MkDir(), "logo.png")
+	c.Assert(ioutil.WriteFile(path, makePNG(c, 40, 20), 0644), IsNil)
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	sheet.AddRow().AddCell().SetString("logo")
+
+	c.Assert(sheet.InsertImageScaled(path, 0, 0, 1.5), IsNil)
+	c.Assert(sheet.InsertImageSized(path, 0, 0, 80, 0), IsNil)
+	c.Assert(sheet.InsertImageSizedCM(path, 0, 0, 0, 1), IsNil)
+	c.Assert(sheet.Drawings, HasLen, 3)
+	c.Assert(sheet.Drawings[0].ExtentX, Equals, 60*EMUPerPixel)
+	c.Assert(sheet.Drawings[0].ExtentY, Equals, 30*EMUPerPixel)
+	c.Assert(sheet.Drawings[1].ExtentX, Equals, 80*EMUPerPixel)
+	c.Assert(sheet.Drawings[1].ExtentY, Equals, 40*EMUPerPixel)
+	c.Assert(sheet.Drawings[2].ExtentX, Equals, 2*EMUPerCM)
+	c.Assert(sheet.Drawings[2].ExtentY, Equals, EMUPerCM)
+	c.Assert(sheet.InsertImageScaled(filepath.Join(c.MkDir(), "missing.png"), 0, 0, 1), NotNil)
+}
+
+func (d *DrawingSuite) TestTwoCellEnd(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	c.Assert(sheet.SetColWidth(0, 0, 10), IsNil) // 70 pixels
+	sheet.AddRow().SetHeight(30)
+	sheet.AddRow()
+	sheet.Rows[1].Hidden = true
+
+	// 100 by 50 pixels: one column and 30 pixels into the next,
+	// which has the default width; all of the tall first row and
+	// 10 pixels into the third, the second being hidden.
+	drawing := &Drawing{Width: 100, Height: 50}
+	toCol, toColOff, toRow, toRowOff := sheet.twoCellEnd(drawing)
+	c.Assert([]int{toCol, toColOff, toRow, toRowOff}, DeepEquals,
+		[]int{1, 30 * EMUPerPixel, 2, 50*EMUPerPixel - 30*EMUPerPoint})
+
+	// Offsets within the top left cell move the end too: the
+	// default column width of 9.5 is 67 pixels.
+	drawing.OffsetX = 40 * EMUPerPixel
+	toCol, toColOff, _, _ = sheet.twoCellEnd(drawing)
+	c.Assert([]int{toCol, toColOff}, DeepEquals, []int{2, 3 * EMUPerPixel})
+
+	// A RowCount keeps the aspect ratio, using the rows' heights.
+	drawing = &Drawing{Width: 100, Height: 50, RowCount: 1}
+	toCol, toColOff, toRow, toRowOff = sheet.twoCellEnd(drawing)
+	c.Assert([]int{toCol, toColOff, toRow, toRowOff}, DeepEquals,
+		[]int{1, 60*EMUPerPoint - 70*EMUPerPixel, 1, 0})
+}
+
+func (d *DrawingSuite) TestRemoveAndReanchorPictures(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	c.Assert(sheet.SetColWidth(0, 0, 10), IsNil) // 70 pixels
+	sheet.AddRow().SetHeight(30)
+	data := makePNG(c, 40, 20)
+	for _, name := range []string{"Logo", "Chart", "Photo"} {
+		sheet.Drawings = append(sheet.Drawings, Drawing{Sheet: sheet, Name: name, ImageData: data,
+			ImageType: IMAGE_TYPE_PNG, RowCount: 1, ColCount: 2, Width: 40, Height: 20})
+	}
+
+	pictures := sheet.Pictures()
+	c.Assert(sheet.RemovePicture(pictures[1]), IsNil)
+	pictures = sheet.Pictures()
+	c.Assert(pictures, HasLen, 2)
+	c.Assert(pictures[0].Name, Equals, "Logo")
+	c.Assert(pictures[1].Name, Equals, "Photo")
+	c.Assert(sheet.RemovePicture(&Drawing{}), ErrorMatches, "sheet 'Sheet1': the picture isn't on the sheet")
+
+	// The picture keeps the size of the two cells it spanned: the
+	// first column and one of the default width, and the tall row.
+	c.Assert(sheet.ReanchorPicture(pictures[0], AnchorOneCell, 3, 2), IsNil)
+	c.Assert(pictures[0].Anchor, Equals, AnchorOneCell)
+	c.Assert(pictures[0].TopLeftCell, Equals, DrawingCell{RowNum: 3, ColNum: 2})
+	c.Assert(pictures[0].RowCount, Equals, 0)
+	c.Assert(pictures[0].ExtentX, Equals, 137*EMUPerPixel)
+	c.Assert(pictures[0].ExtentY, Equals, 30*EMUPerPoint)
+
+	c.Assert(sheet.ReanchorPicture(pictures[1], AnchorAbsolute, 1, 1), IsNil)
+	c.Assert(pictures[1].Anchor, Equals, AnchorAbsolute)
+	c.Assert(pictures[1].OffsetX, Equals, 70*EMUPerPixel)
+	c.Assert(pictures[1].OffsetY, Equals, 30*EMUPerPoint)
+	c.Assert(sheet.ReanchorPicture(&Drawing{}, AnchorOneCell, 0, 0), NotNil)
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	drawing := parts["xl/drawings/drawing1.xml"]
+	c.Assert(drawing, Matches, `(?s).*<xdr:oneCellAnchor>.*<xdr:absoluteAnchor>.*`)
+	c.Assert(drawing, Not(Matches), `(?s).*Chart.*`)
+}
+
+func (d *DrawingSuite) TestDecodeImageHeaders(c *C) {
+	bmp := make([]byte, 54)
+	copy(bmp, "BM")
+	binary.LittleEndian.PutUint32(bmp[14:], 40)
+	binary.LittleEndian.PutUint32(bmp[18:], 30)
+	binary.LittleEndian.PutUint32(bmp[22:], uint32(0xffffffec)) // -20, top down
+
+	tiff := []byte("MM\x00*\x00\x00\x00\x08\x00\x02" +
+		"\x01\x00\x00\x03\x00\x00\x00\x01\x00\x1e\x00\x00" +
+		"\x01\x01\x00\x04\x00\x00\x00\x01\x00\x00\x00\x14")
+
+	webp := make([]byte, 30)
+	copy(webp, "RIFF\x00\x00\x00\x00WEBPVP8X")
+	webp[24], webp[27] = 29, 19
+
+	for _, t := range []struct {
+		data          []byte
+		imageType     ImageType
+		width, height int
+	}{
+		{bmp, IMAGE_TYPE_BMP, 30, 20},
+		{tiff, IMAGE_TYPE_TIFF, 30, 20},
+		{webp, IMAGE_TYPE_WEBP, 30, 20},
+		{[]byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg" width="30px" height="20"/>`), IMAGE_TYPE_SVG, 30, 20},
+		{[]byte(`<svg xmlns="http://www.w3.org/2000/svg" width="1in" viewBox="0 0 60 40"/>`), IMAGE_TYPE_SVG, 96, 64},
+		{[]byte(`<svg xmlns="http://www.w3.org/2000/svg" width="100%" viewBox="0 0 60 40"/>`), IMAGE_TYPE_SVG, 60, 40},
+	} {
+		imageType, config, err := decodeImage(t.data)
+		c.Assert(err, IsNil)
+		c.Assert(imageType, Equals, t.imageType)
+		c.Assert([]int{config.Width, config.Height}, DeepEquals, []int{t.width, t.height})
+	}
+
+	_, _, err := decodeImage([]byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`))
+	c.Assert(err, ErrorMatches, "the SVG image doesn't give its size")
+	_, _, err = decodeImage([]byte("not an image"))
+	c.Assert(err, NotNil)
+}
+
+func (d *DrawingSuite) TestSVGPicture(c *C) {
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="30" height="20"><rect width="30" height="20"/></svg>`)
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	sheet.AddRow().AddCell().SetString("logo")
+	sheet.Drawings = append(sheet.Drawings, Drawing{
+		Sheet: sheet, ImageData: svg, ImageType: IMAGE_TYPE_SVG, Width: 30, Height: 20,
+	})
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/media/image1.svg"], Equals, string(svg))
+	fallback := []byte(parts["xl/media/image2.png"])
+	imageType, config, err := decodeImage(fallback)
+	c.Assert(err, IsNil)
+	c.Assert(imageType, Equals, IMAGE_TYPE_PNG)
+	c.Assert([]int{config.Width, config.Height}, DeepEquals, []int{30, 20})
+	c.Assert(parts["[Content_Types].xml"], Matches, `(?s).*<Default Extension="svg" ContentType="image/svg\+xml"></Default>.*`)
+	c.Assert(parts["xl/drawings/drawing1.xml"], Matches, `(?s).*<a:blip xmlns:r="[^"]*" r:embed="rId2"><a:extLst><a:ext uri="\{96DAC541-7B7A-43D3-8B79-37D633B846F1\}"><asvg:svgBlip xmlns:asvg="[^"]*" r:embed="rId1">.*`)
+
+	written, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	pictures := written.Sheets[0].Pictures()
+	c.Assert(pictures, HasLen, 1)
+	c.Assert(pictures[0].ImageType, Equals, IMAGE_TYPE_SVG)
+	c.Assert(pictures[0].ImageData, DeepEquals, svg)
+	c.Assert(pictures[0].FallbackImageData, DeepEquals, fallback)
+	c.Assert([]int{pictures[0].Width, pictures[0].Height}, DeepEquals, []int{30, 20})
+}
+
+func (d *DrawingSuite) TestSharedMedia(c *C) {
+	file := NewFile()
+	logo, other := makePNG(c, 40, 20), makePNG(c, 20, 40)
+	for _, name := range []string{"Jan", "Feb", "Mar"} {
+		sheet, err := file.AddSheet(name)
+		c.Assert(err, IsNil)
+		for i, data := range [][]byte{logo, append([]byte(nil), logo...)} {
+			sheet.Drawings = append(sheet.Drawings, Drawing{Sheet: sheet, ImageData: data, ImageType: IMAGE_TYPE_PNG,
+				Anchor: AnchorOneCell, TopLeftCell: DrawingCell{RowNum: i * 10}, Width: 40, Height: 20})
+		}
+	}
+	c.Assert(file.Sheet["Mar"].SetBackground(other, IMAGE_TYPE_PNG), IsNil)
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	var media []string
+	for name := range parts {
+		if strings.HasPrefix(name, "xl/media/") {
+			media = append(media, name)
+		}
+	}
+	sort.Strings(media)
+	c.Assert(media, DeepEquals, []string{"xl/media/image1.png", "xl/media/image2.png"})
+	c.Assert(parts["xl/media/image1.png"], Equals, string(logo))
+	c.Assert(parts["xl/media/image2.png"], Equals, string(other))
+	for i := 1; i <= 3; i++ {
+		rels := parts[fmt.Sprintf("xl/drawings/_rels/drawing%d.xml.rels", i)]
+		c.Assert(strings.Count(rels, "<Relationship "), Equals, 1)
+		c.Assert(rels, Matches, `(?s).*Target="../media/image1.png".*`)
+	}
+
+	written, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	for _, sheet := range written.Sheets {
+		pictures := sheet.Pictures()
+		c.Assert(pictures, HasLen, 2)
+		c.Assert(pictures[1].ImageData, DeepEquals, logo)
+	}
+}
+
+func (d *DrawingSuite) TestBackground(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Draft")
+	sheet.AddRow().AddCell().SetString("figures")
+	data := makePNG(c, 200, 100)
+	c.Assert(sheet.SetBackground([]byte("<svg/>"), IMAGE_TYPE_SVG), ErrorMatches, "sheet 'Draft': an SVG image can't be a background")
+	c.Assert(sheet.SetBackground(data, IMAGE_TYPE_PNG), IsNil)
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/worksheets/sheet1.xml"], Matches, `(?s).*<drawing r:id="rId1"></drawing><picture r:id="rId2"></picture></worksheet>`)
+	c.Assert(parts["xl/worksheets/_rels/sheet1.xml.rels"], Matches, `(?s).*<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="../media/image1.png"></Relationship>.*`)
+	c.Assert(parts["xl/media/image1.png"], Equals, string(data))
+
+	written, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	c.Assert(written.Sheets[0].Background, DeepEquals, data)
+	c.Assert(written.Sheets[0].BackgroundType, Equals, IMAGE_TYPE_PNG)
+	c.Assert(written.Sheets[0].Pictures(), HasLen, 0)
+
+	c.Assert(sheet.SetBackground(nil, IMAGE_TYPE_PNG), IsNil)
+	parts, err = file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/worksheets/sheet1.xml"], Not(Matches), `(?s).*<picture.*`)
+}
@@ -0,0 +1,169 @@
+package xlsx
+
+import (
+	"strconv"
+)
+
+// DifferentialStyle is formatting laid over that of cells, such as by
+// a table style or conditional formatting.  Only the parts of it that
+// are given are changed: a nil Font, Fill or Border, an empty NumFmt,
+// a zero font Size and the empty border sides leave the cell's own
+// formatting alone.
+type DifferentialStyle struct {
+	Font   *Font
+	Fill   *Fill
+	Border *Border
+	NumFmt string
+}
+
+// AddDifferentialStyle adds a differential format to the File's style
+// sheet, returning its index, the dxfId by which conditional formats
+// and the like refer to it.  Adding a style equal to one already in
+// the File returns the index of that one.
+func (f *File) AddDifferentialStyle(style DifferentialStyle) int {
+	for i, existing := range f.dxfs {
+		if existing.equals(style) {
+			return i
+		}
+	}
+	f.dxfs = append(f.dxfs, style)
+	return len(f.dxfs) - 1
+}
+
+// DifferentialStyles returns the differential formats of the File,
+// whether added by AddDifferentialStyle or read from a file, each at
+// its index.  Those used by table styles are added as the File is
+// written, after these.
+func (f *File) DifferentialStyles() []DifferentialStyle {
+	return f.dxfs
+}
+
+// equals reports whether two DifferentialStyles change cells in the
+// same way.
+func (style DifferentialStyle) equals(other DifferentialStyle) bool {
+	if (style.Font == nil) != (other.Font == nil) || style.Font != nil && *style.Font != *other.Font {
+		return false
+	}
+	if (style.Fill == nil) != (other.Fill == nil) || style.Fill != nil && !style.Fill.equals(*other.Fill) {
+		return false
+	}
+	if (style.Border == nil) != (other.Border == nil) || style.Border != nil && *style.Border != *other.Border {
+		return false
+	}
+	return style.NumFmt == other.NumFmt
+}
+
+// setDifferentialStyles puts the differential formats in the style
+// sheet, each at its index.
+func (styles *xlsxStyleSheet) setDifferentialStyles(dxfs []DifferentialStyle) {
+	if len(dxfs) == 0 {
+		return
+	}
+	styles.Dxfs = &xlsxDxfs{}
+	for _, style := range dxfs {
+		styles.Dxfs.Dxf = append(styles.Dxfs.Dxf, styles.makeDxf(style))
+		styles.Dxfs.Count++
+	}
+}
+
+// readDifferentialStyles returns the differential formats of a style
+// sheet read from a file.
+func (styles *xlsxStyleSheet) readDifferentialStyles() []DifferentialStyle {
+	if styles.Dxfs == nil {
+		return nil
+	}
+	var dxfs []DifferentialStyle
+	for _, dxf := range styles.Dxfs.Dxf {
+		dxfs = append(dxfs, styles.readDxf(dxf))
+	}
+	return dxfs
+}
+
+// makeDxf returns the differential format for a DifferentialStyle.
+func (styles *xlsxStyleSheet) makeDxf(style DifferentialStyle) xlsxDxf {
+	var dxf xlsxDxf
+	if font := style.Font; font != nil {
+		xFont := &xlsxFont{}
+		if font.Size != 0 {
+			xFont.Sz.Val = strconv.Itoa(font.Size)
+		}
+		xFont.Name.Val = font.Name
+		if font.Family != 0 {
+			xFont.Family.Val = strconv.Itoa(font.Family)
+		}
+		if font.Charset != 0 {
+			xFont.Charset.Val = strconv.Itoa(font.Charset)
+		}
+		xFont.Color.RGB = font.Color
+		if font.Bold {
+			xFont.B = &xlsxVal{}
+		}
+		if font.Italic {
+			xFont.I = &xlsxVal{}
+		}
+		if font.Underline {
+			xFont.U = &xlsxVal{}
+		}
+		dxf.Font = xFont
+	}
+	if style.NumFmt != "" {
+		numFmt := styles.newNumFmt(style.NumFmt)
+		dxf.NumFmt = &numFmt
+	}
+	if fill := style.Fill; fill != nil {
+		xFill := fill.makeXLSXFill()
+		if fill.Gradient == nil && fill.PatternType == FillPatternSolid && fill.BgColor == "" {
+			// A differential solid fill is drawn in its
+			// background colour.
+			xFill.PatternFill.BgColor.RGB = fill.FgColor
+		}
+		dxf.Fill = &xFill
+	}
+	if border := style.Border; border != nil {
+		dxf.Border = &xlsxBorder{
+			Left:   xlsxLine{Style: border.Left, Color: xlsxColor{RGB: border.LeftColor}},
+			Right:  xlsxLine{Style: border.Right, Color: xlsxColor{RGB: border.RightColor}},
+			Top:    xlsxLine{Style: border.Top, Color: xlsxColor{RGB: border.TopColor}},
+			Bottom: xlsxLine{Style: border.Bottom, Color: xlsxColor{RGB: border.BottomColor}},
+		}
+	}
+	return dxf
+}
+
+// readDxf returns the DifferentialStyle of a differential format.
+func (styles *xlsxStyleSheet) readDxf(dxf xlsxDxf) DifferentialStyle {
+	var style DifferentialStyle
+	if xFont := dxf.Font; xFont != nil {
+		font := &Font{Name: xFont.Name.Val, Color: styles.argbValue(xFont.Color)}
+		font.Size, _ = strconv.Atoi(xFont.Sz.Val)
+		font.Family, _ = strconv.Atoi(xFont.Family.Val)
+		font.Charset, _ = strconv.Atoi(xFont.Charset.Val)
+		font.Bold = xFont.B != nil
+		font.Italic = xFont.I != nil
+		font.Underline = xFont.U != nil
+		style.Font = font
+	}
+	if dxf.NumFmt != nil {
+		style.NumFmt = dxf.NumFmt.FormatCode
+		if style.NumFmt == "" {
+			style.NumFmt = builtInNumFmt[dxf.NumFmt.NumFmtId]
+		}
+	}
+	if xFill := dxf.Fill; xFill != nil {
+		fill := styles.readFill(*xFill)
+		style.Fill = &fill
+	}
+	if xBorder := dxf.Border; xBorder != nil {
+		style.Border = &Border{
+			Left:        xBorder.Left.Style,
+			LeftColor:   styles.argbValue(xBorder.Left.Color),
+			Right:       xBorder.Right.Style,
+			RightColor:  styles.argbValue(xBorder.Right.Color),
+			Top:         xBorder.Top.Style,
+			TopColor:    styles.argbValue(xBorder.Top.Color),
+			Bottom:      xBorder.Bottom.Style,
+			BottomColor: styles.argbValue(xBorder.Bottom.Color),
+		}
+	}
+	return style
+}
@@ -0,0 +1,50 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type DifferentialStyleSuite struct{}
+
+var _ = Suite(&DifferentialStyleSuite{})
+
+func (s *DifferentialStyleSuite) TestAddDifferentialStyle(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	sheet.AddRow().AddCell().SetInt(1)
+
+	red := DifferentialStyle{Font: &Font{Color: "FFFF0000"}}
+	c.Assert(file.AddDifferentialStyle(red), Equals, 0)
+	c.Assert(file.AddDifferentialStyle(DifferentialStyle{NumFmt: "0.00%"}), Equals, 1)
+	c.Assert(file.AddDifferentialStyle(DifferentialStyle{Font: &Font{Color: "FFFF0000"}}), Equals, 0)
+	c.Assert(file.DifferentialStyles(), HasLen, 2)
+
+	// A table style using an existing format shares it.
+	err := file.AddTableStyle(TableStyle{
+		Name: "Alert",
+		Elements: []TableStyleElement{
+			{Type: TableStyleHeaderRow, Style: red},
+			{Type: TableStyleTotalRow, Style: DifferentialStyle{Fill: &Fill{PatternType: "solid", FgColor: "FFFFFF00"}}},
+		},
+	})
+	c.Assert(err, IsNil)
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	styles := parts["xl/styles.xml"]
+	c.Assert(styles, Matches, `(?s).*<dxfs count="3"><dxf><font><color rgb="FFFF0000"/></font></dxf><dxf><numFmt numFmtId="10" formatCode="0.00%"/>.*`)
+	c.Assert(styles, Matches, `(?s).*<tableStyleElement type="headerRow" dxfId="0"></tableStyleElement><tableStyleElement type="totalRow" dxfId="2"></tableStyleElement>.*`)
+
+	written, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	dxfs := written.DifferentialStyles()
+	c.Assert(dxfs, HasLen, 3)
+	c.Assert(dxfs[0].equals(red), Equals, true)
+	c.Assert(dxfs[1].NumFmt, Equals, "0.00%")
+	c.Assert(*dxfs[2].Fill, Equals, Fill{PatternType: "solid", FgColor: "FFFFFF00", BgColor: "FFFFFF00"})
+
+	// Writing the file again doesn't duplicate the table style's formats.
+	parts, err = written.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/styles.xml"], Matches, `(?s).*<dxfs count="3">.*`)
+}
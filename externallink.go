@@ -0,0 +1,406 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	relTypeExternalLink     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/externalLink"
+	relTypeExternalLinkPath = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/externalLinkPath"
+)
+
+// ExternalLink is another workbook that the formulas of a File refer
+// to.  Formulas name the workbook in brackets, as in
+// "[Book2.xlsx]Sheet1!A1" or "'[Book 2.xlsx]Sheet 1'!A1"; the File is
+// written with each such workbook linked, after the File's
+// ExternalLinks, which are left as they are.  In the file itself a
+// formula refers to a workbook by its place among the links, so the
+// formulas of a File that has been read refer to "[1]" for the first
+// of its ExternalLinks, and so on, which is kept as it is.
+type ExternalLink struct {
+	// Target is the path or URL of the other workbook, relative to
+	// the File.  Formulas may refer to it by Target or by the last
+	// element of its path.
+	Target string
+	// SheetNames are the names of the sheets of the other workbook.
+	SheetNames []string
+	// read is the link as it was read, which is written as it was
+	// unless Target or SheetNames have changed.
+	read *readExternalLink
+}
+
+// readExternalLink holds the parts an ExternalLink was read from,
+// which keep the values of the other workbook's cells cached in them.
+type readExternalLink struct {
+	target     string
+	sheetNames []string
+	part, rels []byte
+}
+
+// AddExternalLink links the File to the workbook at target, as
+// ExternalLink describes, returning the link.  If the File is already
+// linked to it, the link it has is returned, with any of sheetNames
+// it doesn't list added.  Formulas that refer to a workbook are
+// linked to it when the File is written, so AddExternalLink is only
+// needed to give its sheets, or a target other than the name the
+// formulas use, such as "../data/Book2.xlsx" for "[Book2.xlsx]".
+func (f *File) AddExternalLink(target string, sheetNames ...string) *ExternalLink {
+	link := f.externalLink(target)
+	if link == nil {
+		link = &ExternalLink{Target: target}
+		f.ExternalLinks = append(f.ExternalLinks, link)
+	}
+	for _, name := range sheetNames {
+		link.addSheetName(name)
+	}
+	return link
+}
+
+// externalLink returns the link to the workbook that formulas call
+// book, or nil if the File has none.
+func (f *File) externalLink(book string) *ExternalLink {
+	if i := externalLinkIndex(f.ExternalLinks, book); i > 0 {
+		return f.ExternalLinks[i-1]
+	}
+	return nil
+}
+
+// externalLinkIndex returns the place, counting from 1, among links
+// of the workbook that formulas call book, or 0 if it isn't among
+// them.
+func externalLinkIndex(links []*ExternalLink, book string) int {
+	if n, err := strconv.Atoi(book); err == nil {
+		if n > 0 && n <= len(links) {
+			return n
+		}
+		return 0
+	}
+	for i, link := range links {
+		target := strings.Replace(link.Target, `\`, "/", -1)
+		if strings.EqualFold(target, book) || strings.EqualFold(path.Base(target), book) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// addSheetName adds a sheet to those the link lists, unless it does
+// already.
+func (l *ExternalLink) addSheetName(name string) {
+	for _, existing := range l.SheetNames {
+		if strings.EqualFold(existing, name) {
+			return
+		}
+	}
+	l.SheetNames = append(l.SheetNames, name)
+}
+
+// unchanged reports whether the link is as it was read.
+func (l *ExternalLink) unchanged() bool {
+	if l.read == nil || l.Target != l.read.target || len(l.SheetNames) != len(l.read.sheetNames) {
+		return false
+	}
+	for i, name := range l.SheetNames {
+		if name != l.read.sheetNames[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// linkExternalBooks returns the links the File is written with: a
+// copy of its ExternalLinks, followed by a link to each workbook the
+// formulas of the sheets refer to that the File isn't linked to, with
+// the sheets they refer to added.  It returns an error if a formula
+// refers to a link by a place the File has no link at, or a link has
+// no Target, as a link read whose part was missing doesn't.
+func (f *File) linkExternalBooks(sheets []*Sheet) ([]*ExternalLink, error) {
+	links := make([]*ExternalLink, len(f.ExternalLinks))
+	for i, link := range f.ExternalLinks {
+		if link.Target == "" {
+			return nil, fmt.Errorf("external link %d has no target", i+1)
+		}
+		copied := *link
+		copied.SheetNames = append([]string(nil), link.SheetNames...)
+		links[i] = &copied
+	}
+	var err error
+	for _, sheet := range sheets {
+		for r, row := range sheet.Rows {
+			if row == nil {
+				continue
+			}
+			for c, cell := range row.Cells {
+				if cell == nil || strings.IndexByte(cell.formula, '[') < 0 {
+					continue
+				}
+				mapExternalBooks(cell.formula, func(book, sheetName string) string {
+					if n, atoiErr := strconv.Atoi(book); atoiErr == nil {
+						// [0] is the workbook itself.
+						if (n < 0 || n > len(f.ExternalLinks)) && err == nil {
+							err = fmt.Errorf("formula '%s' in cell %s of sheet '%s' refers to external link %d, which doesn't exist", cell.formula, getCellIDStringFromCoords(c, r), sheet.Name, n)
+						}
+						return book
+					}
+					i := externalLinkIndex(links, book)
+					if i == 0 {
+						links = append(links, &ExternalLink{Target: book})
+						i = len(links)
+					}
+					if sheetName != "" {
+						links[i-1].addSheetName(sheetName)
+					}
+					return book
+				})
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return links, nil
+}
+
+// externalFormula returns the formula as it is written, with each
+// workbook it refers to by name referred to by its place among the
+// links the File is being written with, or its ExternalLinks.
+func (f *File) externalFormula(formula string) string {
+	if f == nil {
+		return formula
+	}
+	links := f.writtenLinks
+	if links == nil {
+		links = f.ExternalLinks
+	}
+	if len(links) == 0 {
+		return formula
+	}
+	return mapExternalBooks(formula, func(book, sheet string) string {
+		if i := externalLinkIndex(links, book); i > 0 {
+			return strconv.Itoa(i)
+		}
+		return book
+	})
+}
+
+// mapExternalBooks returns the formula with the name of each other
+// workbook it refers to, in the brackets before a sheet or defined
+// name, replaced by what fn returns for it and the sheet referred
+// to, which is "" for a defined name.  String literals and structured
+// references to tables, such as "Table1[[#This Row],[Col]]", are left
+// as they are.
+func mapExternalBooks(formula string, fn func(book, sheet string) string) string {
+	if strings.IndexByte(formula, '[') < 0 {
+		return formula
+	}
+	var res strings.Builder
+	for i := 0; i < len(formula); {
+		c := formula[i]
+		switch {
+		case c == '"':
+			j := closingQuote(formula, i)
+			res.WriteString(formula[i:j])
+			i = j
+		case c == '\'':
+			j := closingQuote(formula, i)
+			quoted := formula[i:j]
+			if end := strings.IndexByte(quoted, ']'); len(quoted) > 1 && quoted[1] == '[' && end > 0 {
+				sheet := strings.Replace(strings.TrimSuffix(quoted[end+1:], "'"), "''", "'", -1)
+				quoted = "'[" + fn(quoted[2:end], sheet) + quoted[end:]
+			}
+			res.WriteString(quoted)
+			i = j
+		case c == '[':
+			end := strings.IndexByte(formula[i:], ']')
+			table := i > 0 && (isNameByte(formula[i-1]) || formula[i-1] == ']')
+			if end < 0 || table || strings.ContainsAny(formula[i+1:i+end], "[#@") {
+				j := closingBracket(formula, i)
+				res.WriteString(formula[i:j])
+				i = j
+				continue
+			}
+			j := i + end + 1
+			k := j
+			for k < len(formula) && isNameByte(formula[k]) {
+				k++
+			}
+			sheet := ""
+			if k < len(formula) && formula[k] == '!' {
+				sheet = formula[j:k]
+			}
+			res.WriteString("[" + fn(formula[i+1:i+end], sheet) + "]")
+			i = j
+		default:
+			res.WriteByte(c)
+			i++
+		}
+	}
+	return res.String()
+}
+
+// closingQuote returns the index just past the quote that closes the
+// string or quoted name starting at i, a doubled quote standing for
+// the quote itself, or the length of the formula if it isn't closed.
+func closingQuote(formula string, i int) int {
+	quote := formula[i]
+	for j := i + 1; j < len(formula); j++ {
+		if formula[j] != quote {
+			continue
+		}
+		if j+1 < len(formula) && formula[j+1] == quote {
+			j++
+			continue
+		}
+		return j + 1
+	}
+	return len(formula)
+}
+
+// closingBracket returns the index just past the bracket that closes
+// the one at i, or the length of the formula if it isn't closed.
+func closingBracket(formula string, i int) int {
+	depth := 0
+	for j := i; j < len(formula); j++ {
+		switch formula[j] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return j + 1
+			}
+		}
+	}
+	return len(formula)
+}
+
+// xlsxExternalReferences directly maps the externalReferences element
+// of a workbook.
+type xlsxExternalReferences struct {
+	ExternalReference []xlsxExternalReference `xml:"externalReference"`
+}
+
+// xlsxExternalReference directly maps the externalReference element,
+// which relates the workbook to an externalLink part.
+type xlsxExternalReference struct {
+	Id string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+}
+
+// xlsxExternalLink maps the externalLink element of an externalLink
+// part, as far as the workbook it links to.
+type xlsxExternalLink struct {
+	XMLName      xml.Name          `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main externalLink"`
+	ExternalBook *xlsxExternalBook `xml:"externalBook"`
+}
+
+// xlsxExternalBook maps the externalBook element, which relates the
+// link to the other workbook and names its sheets.
+type xlsxExternalBook struct {
+	Id         string                  `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	SheetNames *xlsxExternalSheetNames `xml:"sheetNames"`
+}
+
+// xlsxExternalSheetNames maps the sheetNames element, which lists the
+// sheets of the other workbook.
+type xlsxExternalSheetNames struct {
+	SheetName []xlsxVal `xml:"sheetName"`
+}
+
+// readExternalLinks reads the links to other workbooks that the
+// workbook's externalReferences list into the File's ExternalLinks,
+// keeping their parts to be written as they were.  A reference to a
+// part that doesn't exist is kept, as the formulas refer to the links
+// by place, as a link without a Target, with a warning; the File
+// can't be written until it is given one.
+func (f *File) readExternalLinks(workbook *xlsxWorkbook) error {
+	if workbook.ExternalReferences == nil {
+		return nil
+	}
+	rels, err := f.readRelationships("xl/workbook.xml")
+	if err != nil {
+		return err
+	}
+	for _, ref := range workbook.ExternalReferences.ExternalReference {
+		rel, ok := rels[ref.Id]
+		if !ok || rel.Type != relTypeExternalLink || f.parts[rel.Target] == nil {
+			f.warn(fmt.Sprintf("external link %s doesn't exist", ref.Id))
+			f.ExternalLinks = append(f.ExternalLinks, &ExternalLink{})
+			continue
+		}
+		data, err := f.readBinaryPart(f.parts[rel.Target])
+		if err != nil {
+			return err
+		}
+		var xLink xlsxExternalLink
+		if err := newPartDecoder(bytes.NewReader(data)).Decode(&xLink); err != nil {
+			return fmt.Errorf("external link %s: %s", rel.Target, err)
+		}
+		link := &ExternalLink{}
+		if book := xLink.ExternalBook; book != nil {
+			linkRels, err := f.readRelationships(rel.Target)
+			if err != nil {
+				return err
+			}
+			link.Target = linkRels[book.Id].Target
+			if book.SheetNames != nil {
+				for _, name := range book.SheetNames.SheetName {
+					link.SheetNames = append(link.SheetNames, name.Val)
+				}
+			}
+		}
+		read := &readExternalLink{target: link.Target, part: data}
+		read.sheetNames = append(read.sheetNames, link.SheetNames...)
+		if zf := f.parts[relationshipsPartName(rel.Target)]; zf != nil {
+			if read.rels, err = f.readBinaryPart(zf); err != nil {
+				return err
+			}
+		}
+		link.read = read
+		f.ExternalLinks = append(f.ExternalLinks, link)
+	}
+	return nil
+}
+
+// makeExternalLinkParts adds the parts of the links to parts and
+// refers to them from the workbook, relating them to it in rels.
+func makeExternalLinkParts(links []*ExternalLink, parts map[string]string, workbook *xlsxWorkbook, rels *packageRelationships) error {
+	if len(links) == 0 {
+		return nil
+	}
+	workbook.ExternalReferences = &xlsxExternalReferences{}
+	for i, link := range links {
+		partName := fmt.Sprintf("xl/externalLinks/externalLink%d.xml", i+1)
+		workbook.ExternalReferences.ExternalReference = append(workbook.ExternalReferences.ExternalReference,
+			xlsxExternalReference{Id: rels.of("xl/workbook.xml").add(relTypeExternalLink, partName)})
+		if link.unchanged() {
+			parts[partName] = string(link.read.part)
+			if link.read.rels != nil {
+				rels.keep(partName, link.read.rels)
+			}
+			continue
+		}
+		xLink := xlsxExternalLink{ExternalBook: &xlsxExternalBook{
+			Id: rels.of(partName).addExternal(relTypeExternalLinkPath, link.Target),
+		}}
+		if len(link.SheetNames) > 0 {
+			xLink.ExternalBook.SheetNames = &xlsxExternalSheetNames{}
+			for _, sheetName := range link.SheetNames {
+				xLink.ExternalBook.SheetNames.SheetName = append(xLink.ExternalBook.SheetNames.SheetName, xlsxVal{Val: sheetName})
+			}
+		}
+		body, err := xml.Marshal(xLink)
+		if err != nil {
+			return err
+		}
+		parts[partName] = xmlHeader + strings.Replace(
+			strings.Replace(string(body), ` xmlns:relationships="http://schemas.openxmlformats.org/officeDocument/2006/relationships" relationships:id`, ` r:id`, 1),
+			`<externalLink xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`,
+			`<externalLink xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`, 1)
+	}
+	return nil
+}
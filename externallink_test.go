@@ -0,0 +1,118 @@
+package xlsx
+
+import (
+	"bytes"
+
+	. "gopkg.in/check.v1"
+)
+
+type ExternalLinkSuite struct{}
+
+var _ = Suite(&ExternalLinkSuite{})
+
+func (s *ExternalLinkSuite) TestMapExternalBooks(c *C) {
+	type seen struct{ book, sheet string }
+	cases := map[string][]seen{
+		"[Book2.xlsx]Sheet1!A1+1":                      {{"Book2.xlsx", "Sheet1"}},
+		"SUM('[Book 2.xlsx]My ''Data'''!A1:B2)":        {{"Book 2.xlsx", "My 'Data'"}},
+		"[1]Sheet1!$A$1*[Rates.xlsx]Rate":              {{"1", "Sheet1"}, {"Rates.xlsx", ""}},
+		`Table1[[#This Row],[Col]]&"[Book2.xlsx]"`:     nil,
+		"SUM(Table1[Amount])+COUNT(Table1[[Q1]:[Q2]])": nil,
+	}
+	for formula, expected := range cases {
+		var got []seen
+		mapped := mapExternalBooks(formula, func(book, sheet string) string {
+			got = append(got, seen{book, sheet})
+			return book
+		})
+		c.Assert(mapped, Equals, formula)
+		c.Assert(got, DeepEquals, expected, Commentf(formula))
+	}
+	c.Assert(mapExternalBooks("'[Book 2.xlsx]Sheet 1'!A1+[Book2.xlsx]S!B2", func(book, sheet string) string {
+		return "9"
+	}), Equals, "'[9]Sheet 1'!A1+[9]S!B2")
+}
+
+func (s *ExternalLinkSuite) TestWriteFormulasLinkingWorkbooks(c *C) {
+	f := NewFile()
+	link := f.AddExternalLink(`..\data\Rates.xlsx`, "Rates")
+	sheet, _ := f.AddSheet("Sheet1")
+	row := sheet.AddRow()
+	row.AddCell().SetFormula("[Book2.xlsx]Sheet1!A1*2")
+	row.AddCell().SetFormula("'[Book2.xlsx]Other Sheet'!B2+[Rates.xlsx]Rates!A1")
+	row.AddCell().SetFormula("Table1[Amount]")
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	// The File's links are left as they are.
+	c.Assert(f.ExternalLinks, HasLen, 1)
+	c.Assert(f.ExternalLinks[0], Equals, link)
+	c.Assert(link.SheetNames, DeepEquals, []string{"Rates"})
+
+	c.Assert(parts["xl/worksheets/sheet1.xml"], Matches, `(?s).*<f>\[2\]Sheet1!A1\*2</f>.*<f>&#39;\[2\]Other Sheet&#39;!B2\+\[1\]Rates!A1</f>.*<f>Table1\[Amount\]</f>.*`)
+	c.Assert(parts["xl/workbook.xml"], Matches, `(?s).*</sheets><externalReferences><externalReference r:id="rId5"></externalReference><externalReference r:id="rId6"></externalReference></externalReferences><definedNames>.*`)
+	c.Assert(parts["xl/_rels/workbook.xml.rels"], Matches, `(?s).*<Relationship Id="rId6" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/externalLink" Target="externalLinks/externalLink2.xml"></Relationship>.*`)
+	c.Assert(parts["xl/externalLinks/externalLink2.xml"], Equals, xmlHeader+`<externalLink xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><externalBook r:id="rId1"><sheetNames><sheetName val="Sheet1"></sheetName><sheetName val="Other Sheet"></sheetName></sheetNames></externalBook></externalLink>`)
+	c.Assert(parts["xl/externalLinks/_rels/externalLink1.xml.rels"], Matches, `(?s).*<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/externalLinkPath" Target="..\\data\\Rates.xlsx" TargetMode="External"></Relationship>.*`)
+	c.Assert(parts["[Content_Types].xml"], Matches, `(?s).*<Override PartName="/xl/externalLinks/externalLink2.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.externalLink\+xml"></Override>.*`)
+
+	// Writing again writes the same links.
+	again, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(f.ExternalLinks, HasLen, 1)
+	c.Assert(again["xl/externalLinks/externalLink2.xml"], Equals, parts["xl/externalLinks/externalLink2.xml"])
+	c.Assert(again["xl/externalLinks/externalLink3.xml"], Equals, "")
+}
+
+func (s *ExternalLinkSuite) TestWriteUnresolvedExternalLinks(c *C) {
+	f := NewFile()
+	sheet, _ := f.AddSheet("Sheet1")
+	cell := sheet.AddRow().AddCell()
+	cell.SetFormula("[2]Sheet1!A1")
+	_, err := f.MarshallParts()
+	c.Assert(err, ErrorMatches, "formula '\\[2\\]Sheet1!A1' in cell A1 of sheet 'Sheet1' refers to external link 2, which doesn't exist")
+
+	f.AddExternalLink("Book2.xlsx")
+	f.ExternalLinks = append(f.ExternalLinks, &ExternalLink{})
+	_, err = f.MarshallParts()
+	c.Assert(err, ErrorMatches, "external link 2 has no target")
+}
+
+func (s *ExternalLinkSuite) TestReadPreservesExternalLinks(c *C) {
+	linkPart := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<externalLink xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><externalBook r:id="rId1"><sheetNames><sheetName val="Prices"/></sheetNames><sheetDataSet><sheetData sheetId="0"><row r="1"><cell r="A1"><v>42</v></cell></row></sheetData></sheetDataSet></externalBook></externalLink>`
+	linkRels := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/externalLinkPath" Target="Prices.xlsx" TargetMode="External"/></Relationships>`
+	parts := map[string]string{
+		"xl/workbook.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets><externalReferences><externalReference r:id="rId9"/></externalReferences></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId9" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/externalLink" Target="externalLinks/externalLink1.xml"/></Relationships>`,
+		"xl/worksheets/sheet1.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData><row r="1"><c r="A1"><f>[1]Prices!A1</f><v>42</v></c></row></sheetData></worksheet>`,
+		"xl/externalLinks/externalLink1.xml":            linkPart,
+		"xl/externalLinks/_rels/externalLink1.xml.rels": linkRels,
+	}
+	f, err := readZipReader(makeZipReader(c, parts), nil)
+	c.Assert(err, IsNil)
+	c.Assert(f.ExternalLinks, HasLen, 1)
+	c.Assert(f.ExternalLinks[0].Target, Equals, "Prices.xlsx")
+	c.Assert(f.ExternalLinks[0].SheetNames, DeepEquals, []string{"Prices"})
+	c.Assert(f.Sheets[0].Cell(0, 0).Formula(), Equals, "[1]Prices!A1")
+
+	// The link is written as it was read, keeping the cached values.
+	written, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(written["xl/externalLinks/externalLink1.xml"], Equals, linkPart)
+	c.Assert(written["xl/externalLinks/_rels/externalLink1.xml.rels"], Equals, linkRels)
+	c.Assert(written["xl/worksheets/sheet1.xml"], Matches, `(?s).*<f>\[1\]Prices!A1</f>.*`)
+
+	// A formula naming the workbook refers to the link read.
+	f.Sheets[0].Cell(0, 1).SetFormula("[Prices.xlsx]Prices!B1")
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	read, err := OpenBinary(buf.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(read.ExternalLinks, HasLen, 1)
+	c.Assert(read.Sheets[0].Cell(0, 1).Formula(), Equals, "[1]Prices!B1")
+}
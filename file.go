@@ -3,28 +3,187 @@ package xlsx
 import (
 	"archive/zip"
 	"bytes"
+	"crypto/sha256"
 	"encoding/xml"
 	"fmt"
 	"io"
+	"io/fs"
+	"io/ioutil"
 	"os"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // File is a high level structure providing a slice of Sheet structs
 // to the user.
+//
+// Adding Sheets to a File, and rows, cells and images to different
+// Sheets, is safe from multiple goroutines: the shared string table
+// and style registry are only built when the File is written.  Each
+// Sheet must only be modified by one goroutine at a time, and the
+// File must not be modified while it is being written.
 type File struct {
 	worksheets     map[string]*zip.File
+	parts          map[string]*zip.File
 	referenceTable *RefTable
 	Date1904       bool
+	// CodeName is the name VBA refers to the workbook by, such as
+	// "ThisWorkbook", which macro-enabled workbooks have.
+	CodeName string
+	// storedDate1904 is the date system of the serial numbers in
+	// the file the File was opened from, which the dates of each
+	// Sheet are converted from as it is read if Date1904 differs.
+	storedDate1904 bool
 	styles         *xlsxStyleSheet
 	Sheets         []*Sheet
 	Sheet          map[string]*Sheet
 	theme          *theme
-	DefinedNames   []*xlsxDefinedName
-	Drawings       [][]Drawing
+	// DefinedNames holds the definedName elements of the workbook
+	// as they were read.  They aren't written; Names is.
+	DefinedNames []*xlsxDefinedName
+	// Names holds the names the workbook defines, which formulas
+	// can use in place of what they stand for.
+	Names    []*DefinedName
+	Drawings [][]Drawing
+	// OverflowStrategy determines how Sheets that exceed the
+	// worksheet row and column limits are written.
+	OverflowStrategy OverflowStrategy
+	// Warnings holds a description of each problem that was
+	// worked around, rather than reported as an error, while the
+	// File was read or written.
+	Warnings []string
+	// DatePolicy determines how dates before 1 March 1900 are
+	// converted to and from serial numbers.
+	DatePolicy DatePolicy
+	// WriteBudget limits the work done writing the File.
+	WriteBudget WriteBudget
+	// Calc controls how spreadsheet applications calculate the
+	// File's formulas.
+	Calc CalcProperties
+	// ExternalLinks are the other workbooks that the File's
+	// formulas refer to.
+	ExternalLinks []*ExternalLink
+	// writtenLinks are the links the File is being written with,
+	// while it is.
+	writtenLinks []*ExternalLink
+	// writeWarnings are the warnings recorded the last time the File
+	// was written.
+	writeWarnings warningSpan
+	// CustomXMLParts are the custom XML parts the workbook carries;
+	// see AddCustomXML.
+	CustomXMLParts []*CustomXMLPart
+	// PreservedSheets are the dialog sheets and macro sheets of the
+	// workbook the File was read from.
+	PreservedSheets []*PreservedSheet
+	// CustomViews are the custom views of the workbook; see
+	// AddCustomView.
+	CustomViews []*CustomView
+	// Window controls the window Excel opens the workbook in.
+	Window WindowSettings
+	// otherWindows are the workbookView elements read after the
+	// first, which is Window.
+	otherWindows []xlsxWorkBookView
+	// Signatures are the digital signatures of the workbook the File
+	// was read from, each checked against the package as read.  See
+	// Sign for signing a File as it is written.
+	Signatures []Signature
+	// mu guards the Sheets, the Sheet map and the Warnings.
+	mu        sync.Mutex
+	partHooks []func(name string, content []byte) []byte
+	// vbaProject is the VBA project of the workbook the File was
+	// read from, first, and the parts it refers to; see
+	// HasVBAProject.
+	vbaProject []preservedPart
+	// sheetWorkers is the number of worksheets parsed at once
+	// when the File is opened.
+	sheetWorkers int
+	// lazySheets defers parsing each worksheet until it is used,
+	// reading it from closer.
+	lazySheets bool
+	// sparseSheets keeps each cell read at its own coordinates,
+	// without padding; see SparseSheets.
+	sparseSheets bool
+	// rowLimit and colLimit, if more than zero, are the numbers of
+	// rows and columns of each worksheet read; see RowLimit and
+	// ColLimit.
+	rowLimit int
+	colLimit int
+	closer   io.Closer
+	// repair makes reading tolerate common defects.
+	repair bool
+	// valuesOnly reads the cached results of formulas in their
+	// place; see ValuesOnly.
+	valuesOnly bool
+	// defaults holds the workbook wide settings made by the
+	// options given to NewFileWithOptions.
+	defaults fileDefaults
+	// logger receives diagnostics; see LogTo.
+	logger Logger
+	// stats gathers the Stats of reading and writing the File; see
+	// CollectStats.
+	stats *statsCollector
+	// types are the content types of the parts of the package the
+	// File was read from, which those it writes back as they were
+	// are given.
+	types *xlsxTypes
+	// sheetMetadata is the metadata part read, which the cm and vm
+	// attributes of cells index; see readSheetMetadata.
+	sheetMetadata []byte
+	// DefaultTableStyle and DefaultPivotStyle name the styles
+	// Excel gives the tables and pivot tables created in it.  If
+	// they are empty, Excel's own defaults are used.
+	DefaultTableStyle string
+	DefaultPivotStyle string
+	// tableStyles are the custom table styles; see AddTableStyle.
+	tableStyles []TableStyle
+	// namedStyles are the named cell styles; see AddNamedStyle.
+	namedStyles []NamedStyle
+	// dxfs are the differential formats; see AddDifferentialStyle.
+	dxfs []DifferentialStyle
+	// readStyles counts the style sheet the File was read with; see
+	// StyleStats.
+	readStyles StyleCounts
+}
+
+// fileDefaults holds workbook wide settings.  Each zero value stands
+// for the package's default, so that a File made without options
+// behaves as it always has.
+type fileDefaults struct {
+	appName        string
+	font           *Font
+	dateFormat     string
+	dateTimeFormat string
+	// The shared string table settings; see InlineStrings and
+	// SharedStrings.
+	inlineStrings     bool
+	sharedStringsSize int
+	stringDuplicates  bool
+	// created is the creation date written; see CreationDate.
+	created time.Time
+	// trimEmptyCells leaves out trailing empty rows and cells; see
+	// TrimEmptyCells.
+	trimEmptyCells bool
+	// floatPrecision is the number of significant digits numbers are
+	// written with; see FloatPrecision.
+	floatPrecision int
+	// locale is that values are displayed for; see FormatLocale.
+	locale *Locale
+	// dateSystem is set if Date1904System or Date1900System chose
+	// the date system, rather than the file opened.
+	dateSystem bool
+	// rightToLeft lays out the sheets added from right to left; see
+	// RightToLeft.
+	rightToLeft bool
 }
 
+// The name written as the application that made a File, unless
+// AppName says otherwise.
+const defaultAppName = "Go XLSX"
+
 // Create a new File
 func NewFile() *File {
 	return &File{
@@ -35,33 +194,349 @@ func NewFile() *File {
 	}
 }
 
+// NewFileWithOptions creates a new File, configured with the given
+// options.  Besides the options for opening files, it accepts options
+// setting workbook wide defaults: Date1904System, FileFont,
+// DateFormats, IterativeCalc, FullCalcOnLoad, AppName, CreationDate, FloatPrecision
+// and FormatLocale.
+func NewFileWithOptions(options ...FileOption) *File {
+	f := NewFile()
+	for _, option := range options {
+		option(f)
+	}
+	return f
+}
+
+// Date1904System makes a File use the 1904 date system, in which
+// serial number 0 is 1 January 1904, as Excel for the Mac once did.
+// Given to OpenFile, the dates of a file that uses the 1900 date system
+// are converted to it, as SetDate1904 converts them.
+func Date1904System() FileOption {
+	return func(f *File) {
+		f.Date1904 = true
+		f.defaults.dateSystem = true
+	}
+}
+
+// Date1900System makes a File use the 1900 date system, in which
+// serial number 1 is 1 January 1900, as it does by default.  Given to
+// OpenFile, the dates of a file that uses the 1904 date system are
+// converted to it, as SetDate1904 converts them.
+func Date1900System() FileOption {
+	return func(f *File) {
+		f.Date1904 = false
+		f.defaults.dateSystem = true
+	}
+}
+
+// FileFont sets the font of new Styles made for the File's cells and
+// columns, in place of the package wide DefaultFont.
+func FileFont(size int, name string) FileOption {
+	return func(f *File) {
+		f.defaults.font = NewFont(size, name)
+	}
+}
+
+// DateFormats sets the number formats given to cells by Cell.SetDate
+// and Cell.SetDateTime, in place of "mm-dd-yy" and "m/d/yy h:mm".
+// An empty format leaves the default in place.
+func DateFormats(date, dateTime string) FileOption {
+	return func(f *File) {
+		f.defaults.dateFormat = date
+		f.defaults.dateTimeFormat = dateTime
+	}
+}
+
+// AppName sets the name recorded as the application that wrote the
+// File, in place of "Go XLSX".
+func AppName(name string) FileOption {
+	return func(f *File) {
+		f.defaults.appName = name
+	}
+}
+
+// CreationDate sets the date and time recorded as when the File was
+// created.  By default no date is recorded, so that writing the same
+// File twice gives the same bytes; set one that doesn't change, such
+// as the date of a build's commit, to keep that.
+func CreationDate(created time.Time) FileOption {
+	return func(f *File) {
+		f.defaults.created = created
+	}
+}
+
+// coreProperties returns the content of docProps/core.xml.
+func (f *File) coreProperties() string {
+	if f.defaults.created.IsZero() {
+		return TEMPLATE_DOCPROPS_CORE
+	}
+	created := `<dcterms:created xsi:type="dcterms:W3CDTF">` +
+		f.defaults.created.UTC().Format(time.RFC3339) + `</dcterms:created>`
+	return strings.Replace(TEMPLATE_DOCPROPS_CORE, "</cp:coreProperties>", created+"</cp:coreProperties>", 1)
+}
+
+// appName returns the name of the application writing the File.
+func (f *File) appName() string {
+	if f.defaults.appName == "" {
+		return defaultAppName
+	}
+	return f.defaults.appName
+}
+
+// newStyle returns a new Style for a cell or column of the File,
+// which may be nil.
+func (f *File) newStyle() *Style {
+	style := NewStyle()
+	if f != nil && f.defaults.font != nil {
+		style.Font = *f.defaults.font
+	}
+	return style
+}
+
+// dateFormat returns the number format for dates, or for dates with
+// times if withTime is set.  The File may be nil.
+func (f *File) dateFormat(withTime bool) string {
+	switch {
+	case f != nil && withTime && f.defaults.dateTimeFormat != "":
+		return f.defaults.dateTimeFormat
+	case f != nil && !withTime && f.defaults.dateFormat != "":
+		return f.defaults.dateFormat
+	case withTime:
+		return builtInNumFmt[22]
+	}
+	return builtInNumFmt[14]
+}
+
+// warningSpan is a run of the Warnings of a File: the index of the
+// first and how many there are.
+type warningSpan struct {
+	start, count int
+}
+
+// clearWriteWarnings removes the Warnings recorded the last time the
+// File was written, so that writing it again doesn't repeat them.  It
+// returns the index in Warnings of those the next write records.
+func (f *File) clearWriteWarnings() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	span := f.writeWarnings
+	if span.count > 0 && span.start+span.count <= len(f.Warnings) {
+		f.Warnings = append(f.Warnings[:span.start:span.start], f.Warnings[span.start+span.count:]...)
+	}
+	f.writeWarnings = warningSpan{}
+	return len(f.Warnings)
+}
+
+// setWriteWarnings records that the Warnings from index start on were
+// recorded while the File was written.
+func (f *File) setWriteWarnings(start int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.writeWarnings = warningSpan{start: start, count: len(f.Warnings) - start}
+}
+
+// warn records a problem that has been worked around.
+func (f *File) warn(warning string) {
+	f.mu.Lock()
+	f.Warnings = append(f.Warnings, warning)
+	f.mu.Unlock()
+	f.logf("warning: %s", warning)
+}
+
 // OpenFile() take the name of an XLSX file and returns a populated
-// xlsx.File struct for it.
+// xlsx.File struct for it.  XLSB files, Excel's binary workbooks, and
+// legacy XLS files of Excel 97 to 2003 are opened too, with the values
+// of their cells but not their formulas.
 func OpenFile(filename string) (file *File, err error) {
 	var f *zip.ReadCloser
 	f, err = zip.OpenReader(filename)
 	if err != nil {
-		return nil, err
+		return openCompoundFile(filename, nil, err)
 	}
 	file, err = ReadZip(f)
 	return
 }
 
+// InlineStrings makes the File write the text of each string cell in
+// the cell itself, as an inline string, rather than in the shared
+// string table.  That takes more space for text that repeats, but
+// saves keeping a table of every string while writing.  The shared
+// string table is still written, empty.
+func InlineStrings() FileOption {
+	return func(f *File) {
+		f.defaults.inlineStrings = true
+	}
+}
+
+// SharedStrings sets up the shared string table the File is written
+// with.  It starts with room for size distinct strings, and if
+// deduplicate is false each string cell gets an entry of its own, so
+// that text that rarely repeats isn't also held in a lookup table.
+func SharedStrings(size int, deduplicate bool) FileOption {
+	return func(f *File) {
+		if size < 0 {
+			size = 0
+		}
+		f.defaults.sharedStringsSize = size
+		f.defaults.stringDuplicates = !deduplicate
+	}
+}
+
+// TrimEmptyCells makes the File leave the empty rows and cells at the
+// end of each worksheet, and of each row, out of what it writes, such
+// as those left behind by clearing data, so that they don't bloat the
+// file.  A cell is empty if it has no value or formula, isn't merged,
+// and has no style other than its row's or column's; a row is empty if
+// it has no cells left and nothing else of its own, such as a height.
+// The dimension written is then the range of the cells that are left,
+// rather than everything from A1.
+func TrimEmptyCells() FileOption {
+	return func(f *File) {
+		f.defaults.trimEmptyCells = true
+	}
+}
+
+// RightToLeft makes the File a right to left workbook, for Arabic and
+// Hebrew reports: every Sheet added to it that isn't a copy of another
+// is laid out from right to left, as SheetRightToLeft lays one out.
+// Sheets a workbook opened already has keep the direction they have.
+func RightToLeft() FileOption {
+	return func(f *File) {
+		f.defaults.rightToLeft = true
+	}
+}
+
+// FloatPrecision makes the File write the numbers of its cells rounded
+// to digits significant digits, formatted as Excel's General format
+// does, such as ExcelPrecision to match what Excel itself keeps.  By
+// default numbers are written in the shortest form that reads back as
+// exactly the same float64, which keeps artifacts of floating point
+// arithmetic such as 0.30000000000000004.  Dates are never rounded.
+func FloatPrecision(digits int) FileOption {
+	return func(f *File) {
+		f.defaults.floatPrecision = digits
+	}
+}
+
+// FileOption configures how a File is opened.
+type FileOption func(*File)
+
+// ParallelSheets makes opening a File parse up to workers worksheets
+// at the same time.  A value of workers less than one uses as many
+// workers as Go will run at once, as given by runtime.GOMAXPROCS.
+// By default worksheets are parsed one after another.
+func ParallelSheets(workers int) FileOption {
+	return func(f *File) {
+		if workers < 1 {
+			workers = runtime.GOMAXPROCS(0)
+		}
+		f.sheetWorkers = workers
+	}
+}
+
+// SparseSheets makes opening a File keep each cell it reads at its
+// true row and column, without filling in the rows and cells the file
+// leaves out.  Sheet.Rows has a nil Row wherever the file has no row,
+// and Row.Cells a nil Cell wherever it has no cell, so that a sheet
+// whose data starts at Z100 costs no more than the cells it has.
+// Sheet.Cell and Sheet.ForEachCell step over the gaps.  By default the
+// gaps are filled with empty rows and cells.
+func SparseSheets() FileOption {
+	return func(f *File) {
+		f.sparseSheets = true
+	}
+}
+
+// OpenFileWithOptions is like OpenFile, but configures how the file
+// is opened with the given options.
+func OpenFileWithOptions(filename string, options ...FileOption) (*File, error) {
+	f, err := zip.OpenReader(filename)
+	if err != nil {
+		return openCompoundFile(filename, options, err)
+	}
+	file, err := readZipReader(&f.Reader, options)
+	if err != nil || !file.lazySheets {
+		f.Close()
+		return file, err
+	}
+	file.closer = f
+	return file, nil
+}
+
 // OpenBinary() take bytes of an XLSX file and returns a populated
 // xlsx.File struct for it.
 func OpenBinary(bs []byte) (*File, error) {
+	return OpenBinaryWithOptions(bs)
+}
+
+// OpenBinaryWithOptions is like OpenBinary, but configures how the
+// file is opened with the given options.
+func OpenBinaryWithOptions(bs []byte, options ...FileOption) (*File, error) {
 	r := bytes.NewReader(bs)
-	return OpenReaderAt(r, int64(r.Len()))
+	return OpenReaderAtWithOptions(r, int64(r.Len()), options...)
 }
 
 // OpenReaderAt() take io.ReaderAt of an XLSX file and returns a populated
 // xlsx.File struct for it.
 func OpenReaderAt(r io.ReaderAt, size int64) (*File, error) {
+	return OpenReaderAtWithOptions(r, size)
+}
+
+// OpenReaderAtWithOptions is like OpenReaderAt, but configures how the
+// file is opened with the given options.
+func OpenReaderAtWithOptions(r io.ReaderAt, size int64, options ...FileOption) (*File, error) {
+	if isCompoundFile(r) {
+		return readXLS(r, size, options)
+	}
 	file, err := zip.NewReader(r, size)
 	if err != nil {
 		return nil, err
 	}
-	return ReadZipReader(file)
+	return readZipReader(file, options)
+}
+
+// OpenReader reads an XLSX file from r, such as the body of an HTTP
+// response, and returns a populated xlsx.File struct for it.  As the
+// zip format has to be read from the end, the whole of r is read into
+// memory first.
+func OpenReader(r io.Reader) (*File, error) {
+	return OpenReaderWithOptions(r)
+}
+
+// OpenReaderWithOptions is like OpenReader, but configures how the
+// file is opened with the given options.
+func OpenReaderWithOptions(r io.Reader, options ...FileOption) (*File, error) {
+	bs, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return OpenBinaryWithOptions(bs, options...)
+}
+
+// OpenFS reads the XLSX file called name from fsys, such as an
+// embed.FS, and returns a populated xlsx.File struct for it.  Files
+// that support io.ReaderAt are read in place; others are read into
+// memory first.
+func OpenFS(fsys fs.FS, name string) (*File, error) {
+	return OpenFSWithOptions(fsys, name)
+}
+
+// OpenFSWithOptions is like OpenFS, but configures how the file is
+// opened with the given options.
+func OpenFSWithOptions(fsys fs.FS, name string, options ...FileOption) (*File, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if r, ok := f.(io.ReaderAt); ok {
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		return OpenReaderAtWithOptions(r, info.Size(), options...)
+	}
+	return OpenReaderWithOptions(f, options...)
 }
 
 // A convenient wrapper around File.ToSlice, FileToSlice will
@@ -86,79 +561,212 @@ func FileToSlice(path string) ([][][]string, error) {
 	return f.ToSlice()
 }
 
-// Save the File to an xlsx file at the provided path.
-func (f *File) Save(path string) (err error) {
+// Save the File to an xlsx file at the provided path, with the given
+// options, as Write.
+func (f *File) Save(path string, options ...WriteOption) (err error) {
 	target, err := os.Create(path)
 	if err != nil {
 		return err
 	}
-	err = f.Write(target)
+	err = f.Write(target, options...)
 	if err != nil {
 		return err
 	}
 	return target.Close()
 }
 
-// Write the File to io.Writer as xlsx
-func (f *File) Write(writer io.Writer) (err error) {
-	parts, err := f.MarshallParts()
+// Write the File to io.Writer as xlsx.  Each part is written straight
+// into the zip archive as it is made, rather than being built in
+// memory first, unless an OnPart hook needs its content.  The parts
+// are always written in the same order, with the same modification
+// time, so writing the same File twice gives the same bytes.  The options
+// say how the parts are compressed, see CompressionLevel, and whether
+// the package is signed, see Sign.
+func (f *File) Write(writer io.Writer, options ...WriteOption) (err error) {
+	budget := f.newWriteBudget()
+	done := f.stats.phase("parts")
+	parts, err := f.makeParts(budget)
+	done()
 	if err != nil {
 		return
 	}
-	zipWriter := zip.NewWriter(writer)
-	for partName, part := range parts {
-		w, err := zipWriter.Create(partName)
-		if err != nil {
+	defer f.stats.phase("write")()
+	written := &countingWriter{w: writer}
+	defer func() {
+		f.stats.add(func(stats *Stats) { stats.BytesWritten += written.n })
+	}()
+	writeOptions := newWriteOptions(options)
+	zipWriter := newPartWriter(written, writeOptions)
+	signing := newPackageSigning(writeOptions.signers)
+	if err := signing.prepare(parts); err != nil {
+		return err
+	}
+	var size int64
+	for _, partName := range parts.order() {
+		if err := budget.checkTime(); err != nil {
+			return err
+		}
+		if len(f.partHooks) == 0 {
+			w, err := zipWriter.create(partName)
+			if err != nil {
+				return err
+			}
+			counter := &countingWriter{w: signing.tee(partName, w)}
+			if err := parts.writeTo(partName, counter); err != nil {
+				return err
+			}
+			size += counter.n
+		} else {
+			content, err := parts.bytes(partName)
+			if err != nil {
+				return err
+			}
+			for _, hook := range f.partHooks {
+				if content == nil {
+					break
+				}
+				content = hook(partName, content)
+			}
+			if content == nil {
+				continue
+			}
+			w, err := zipWriter.create(partName)
+			if err != nil {
+				return err
+			}
+			_, err = signing.tee(partName, w).Write(content)
+			if err != nil {
+				return err
+			}
+			size += int64(len(content))
+		}
+		f.stats.add(func(stats *Stats) { stats.PartsWritten++ })
+		if err := budget.checkSize(size); err != nil {
 			return err
 		}
-		_, err = w.Write([]byte(part))
+	}
+	if signing != nil {
+		signatureParts, err := signing.signatureParts()
 		if err != nil {
 			return err
 		}
+		names := make([]string, 0, len(signatureParts))
+		for name := range signatureParts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			w, err := zipWriter.create(name)
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(signatureParts[name]); err != nil {
+				return err
+			}
+			f.stats.add(func(stats *Stats) { stats.PartsWritten++ })
+		}
 	}
-	return zipWriter.Close()
+	return zipWriter.close()
+}
+
+// OnPart registers a hook that is called with the name and content of
+// each part of the XLSX package, such as "xl/workbook.xml", just
+// before it is written.  The hook returns the content to write in its
+// place, which allows parts to be inspected or patched to use
+// features this package doesn't support.  Returning nil leaves the
+// part out altogether.  Hooks are called in the order they were
+// registered, each receiving the content returned by the one before.
+func (f *File) OnPart(hook func(name string, content []byte) []byte) {
+	f.partHooks = append(f.partHooks, hook)
 }
 
-// Add a new Sheet, with the provided name, to a File
-func (f *File) AddSheet(sheetName string) (*Sheet, error) {
+// Add a new Sheet, with the provided name, to a File.  By default the
+// Sheet is empty, visible and added after all the others; options
+// such as SheetAt, SheetHidden and SheetFrom change that.
+func (f *File) AddSheet(sheetName string, options ...SheetOption) (*Sheet, error) {
+	o := sheetOptions{index: -1}
+	for _, option := range options {
+		option(&o)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	if _, exists := f.Sheet[sheetName]; exists {
 		return nil, fmt.Errorf("duplicate sheet name '%s'.", sheetName)
 	}
-	sheet := &Sheet{
-		Name:     sheetName,
-		File:     f,
-		Selected: len(f.Sheets) == 0,
+	if o.index > len(f.Sheets) {
+		return nil, fmt.Errorf("can't insert sheet '%s' at position %d of %d.", sheetName, o.index, len(f.Sheets))
+	}
+	var sheet *Sheet
+	if o.template != nil {
+		sheet = o.template.clone(sheetName, f)
+	} else {
+		sheet = &Sheet{
+			Name: sheetName,
+			File: f,
+		}
+	}
+	sheet.Selected = len(f.Sheets) == 0
+	if o.hidden || o.veryHidden {
+		sheet.Hidden = true
+		sheet.VeryHidden = o.veryHidden
+		sheet.Selected = false
+	}
+	if o.tabColor != "" {
+		sheet.TabColor = o.tabColor
+	}
+	if o.template != nil || o.codeName != "" {
+		sheet.CodeName = o.codeName
+	}
+	if o.rightToLeft || f.defaults.rightToLeft && o.template == nil {
+		sheet.View.RightToLeft = true
 	}
 	f.Sheet[sheetName] = sheet
-	f.Sheets = append(f.Sheets, sheet)
+	if o.index < 0 {
+		f.Sheets = append(f.Sheets, sheet)
+	} else {
+		f.Sheets = append(f.Sheets, nil)
+		copy(f.Sheets[o.index+1:], f.Sheets[o.index:])
+		f.Sheets[o.index] = sheet
+	}
 	return sheet, nil
 }
 
+// SetActiveSheet makes the named Sheet the one Excel shows when the
+// workbook is opened, and the only one that is Selected.  Hidden
+// sheets can't be made active.
+func (f *File) SetActiveSheet(name string) error {
+	sheet, ok := f.Sheet[name]
+	if !ok {
+		return fmt.Errorf("sheet '%s' doesn't exist", name)
+	}
+	if sheet.Hidden || sheet.VeryHidden {
+		return fmt.Errorf("sheet '%s' is hidden, and can't be the active sheet", name)
+	}
+	for _, s := range f.Sheets {
+		s.Selected = s == sheet
+	}
+	return nil
+}
+
+// ActiveSheet returns the Sheet that Excel shows when the workbook is
+// opened: the first visible Sheet that is Selected, or failing that
+// the first visible Sheet.  It returns nil if every Sheet is hidden.
+func (f *File) ActiveSheet() *Sheet {
+	if i := activeSheetIndex(f.Sheets); i >= 0 {
+		return f.Sheets[i]
+	}
+	return nil
+}
+
 func (f *File) makeWorkbook() xlsxWorkbook {
 	return xlsxWorkbook{
-		FileVersion: xlsxFileVersion{AppName: "iTracking XLSX"},
-		WorkbookPr:  xlsxWorkbookPr{ShowObjects: "all"},
+		FileVersion: xlsxFileVersion{AppName: f.appName()},
+		WorkbookPr:  xlsxWorkbookPr{ShowObjects: "all", Date1904: f.Date1904, CodeName: f.CodeName},
 		BookViews: xlsxBookViews{
-			WorkBookView: []xlsxWorkBookView{
-				{
-					ShowHorizontalScroll: true,
-					ShowSheetTabs:        true,
-					ShowVerticalScroll:   true,
-					TabRatio:             204,
-					WindowHeight:         8192,
-					WindowWidth:          16384,
-					XWindow:              "0",
-					YWindow:              "0",
-				},
-			},
+			WorkBookView: []xlsxWorkBookView{f.Window.makeWorkbookView()},
 		},
 		Sheets: xlsxSheets{Sheet: make([]xlsxSheet, len(f.Sheets))},
-		CalcPr: xlsxCalcPr{
-			IterateCount: 100,
-			RefMode:      "A1",
-			Iterate:      false,
-			IterateDelta: 0.001,
-		},
+		CalcPr: f.makeCalcPr(),
 	}
 }
 
@@ -188,15 +796,37 @@ func replaceWorksheetNameSpace(worksheetMarshal string) string {
 }
 
 // Construct a map of file name to XML content representing the file
-// in terms of the structure of an XLSX file.
+// in terms of the structure of an XLSX file.  Write doesn't need every
+// part in memory at once, so this is only for looking at the parts.
 func (f *File) MarshallParts() (map[string]string, error) {
+	budget := f.newWriteBudget()
+	parts, err := f.makeParts(budget)
+	if err != nil {
+		return nil, err
+	}
+	content, err := parts.strings()
+	if err != nil {
+		return nil, err
+	}
+	if err := budget.checkBytes(content); err != nil {
+		return content, err
+	}
+	return content, nil
+}
+
+// makeParts makes the parts of the File, keeping within budget.  The
+// worksheets and shared strings are left to be written when they are
+// wanted.
+func (f *File) makeParts(budget *writeBudget) (*packageParts, error) {
 	var parts map[string]string
-	var refTable *RefTable = NewSharedStringRefTable()
+	var refTable *RefTable = NewSharedStringRefTableWithCapacity(f.defaults.sharedStringsSize)
 	refTable.isWrite = true
-	var workbookRels WorkBookRels = make(WorkBookRels)
+	refTable.inline = f.defaults.inlineStrings
+	refTable.duplicates = f.defaults.stringDuplicates
+	rels := newPackageRelationships()
+	workbookRels := rels.of("xl/workbook.xml")
 	var err error
 	var workbook xlsxWorkbook
-	var types xlsxTypes = MakeDefaultContentTypes()
 
 	marshal := func(thing interface{}) (string, error) {
 		body, err := xml.Marshal(thing)
@@ -209,167 +839,258 @@ func (f *File) MarshallParts() (map[string]string, error) {
 		return strings.Replace(xml.Header, `<?xml version="1.0" encoding="UTF-8"?>`, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`, -1) + outputStr, nil
 	}
 
-	parts = make(map[string]string)
+	packaged := newPackageParts()
+	parts = packaged.content
 	workbook = f.makeWorkbook()
 	sheetIndex := 1
-	drawingCount := 0
+	mediaCount := 0
+	// media holds the part written for each image, by its type and a
+	// digest of its data, so that an image used many times, such as a
+	// logo on every sheet, is written once.
+	media := make(map[string]string)
+	// embedded writes the parts of the objects embedded in each
+	// worksheet, once those made for the worksheets are named.
+	var embedded []func() error
 
 	if f.styles == nil {
 		f.styles = newXlsxStyleSheet(f.theme)
 	}
 	f.styles.reset()
+	f.styles.setNamedStyles(f.namedStyles)
+	f.styles.setDifferentialStyles(f.dxfs)
+	f.styles.setTableStyles(f.tableStyles, f.DefaultTableStyle, f.DefaultPivotStyle)
 
 	for _, sheet := range f.Sheets {
+		if err := sheet.Load(); err != nil {
+			return nil, err
+		}
+	}
+	start := f.clearWriteWarnings()
+	defer f.setWriteWarnings(start)
+	sheets, err := f.sheetsToWrite(SheetRowLimit, SheetColLimit)
+	if err != nil {
+		return nil, err
+	}
+	if err := budget.checkCells(sheets); err != nil {
+		return nil, err
+	}
+	if err := f.checkCodeNames(sheets); err != nil {
+		return nil, err
+	}
+	// The dialog and macro sheets read are placed among the
+	// worksheets, and the indexes of the workbook's sheets count
+	// them too.
+	places, preservedPlaces := f.sheetPlaces(len(sheets))
+	active := -1
+	if len(sheets) > 0 {
+		// Excel won't open a workbook without a visible sheet, and
+		// shows a blank window if the active one is hidden.
+		visible := firstVisibleSheet(sheets)
+		if visible < 0 {
+			return nil, fmt.Errorf("every sheet is hidden, but a workbook needs at least one visible sheet")
+		}
+		active = activeSheetIndex(sheets)
+		workbook.BookViews.WorkBookView[0].ActiveTab = places[active]
+		workbook.BookViews.WorkBookView[0].FirstSheet = places[visible]
+	}
+	workbook.Sheets.Sheet = make([]xlsxSheet, len(sheets)+len(f.PreservedSheets))
+	workbook.BookViews.WorkBookView = append(workbook.BookViews.WorkBookView, f.otherWorkbookViews(len(workbook.Sheets.Sheet))...)
+	names := make([]string, len(workbook.Sheets.Sheet))
+	for i, sheet := range sheets {
+		names[places[i]] = sheet.Name
+	}
+	for i, sheet := range f.PreservedSheets {
+		names[preservedPlaces[i]] = sheet.Name
+	}
+	if f.writtenLinks, err = f.linkExternalBooks(sheets); err != nil {
+		return nil, err
+	}
+	defer func() { f.writtenLinks = nil }()
+	if workbook.DefinedNames, err = f.makeDefinedNames(names); err != nil {
+		return nil, err
+	}
+
+	for _, sheet := range sheets {
+		if err := budget.checkTime(); err != nil {
+			return nil, err
+		}
 
 		xSheet := sheet.makeXLSXSheet(refTable, f.styles)
-		rId := fmt.Sprintf("rId%d", sheetIndex)
+		if sheetIndex-1 == active {
+			xSheet.SheetViews.SheetView[0].TabSelected = true
+		} else if sheet.Hidden || sheet.VeryHidden {
+			xSheet.SheetViews.SheetView[0].TabSelected = false
+		}
 		sheetId := strconv.Itoa(sheetIndex)
-		sheetPath := fmt.Sprintf("worksheets/sheet%d.xml", sheetIndex)
-		partName := "xl/" + sheetPath
-		types.Overrides = append(
-			types.Overrides,
-			xlsxOverride{
-				PartName:    "/" + partName,
-				ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"})
-		workbookRels[rId] = sheetPath
-		workbook.Sheets.Sheet[sheetIndex-1] = xlsxSheet{
+		partName := fmt.Sprintf("xl/worksheets/sheet%d.xml", sheetIndex)
+		rId := workbookRels.add(relTypeWorksheet, partName)
+		workbook.Sheets.Sheet[places[sheetIndex-1]] = xlsxSheet{
 			Name:    sheet.Name,
 			SheetId: sheetId,
 			Id:      rId,
-			State:   "visible"}
-		parts[partName], err = marshal(xSheet)
-
-		if err != nil {
-			return parts, err
+			State:   sheet.state()}
+		packaged.writers[partName] = func(w io.Writer) error {
+			return writeWorksheet(w, xSheet)
 		}
 
 		xDrawing := newXlsxDrawing()
-		xDrawingRel := newXlsxDrawingRelationships()
+		drawingPartName := fmt.Sprintf("xl/drawings/drawing%d.xml", sheetIndex)
+		drawingRels := rels.of(drawingPartName)
+		sheetRels := rels.of(partName)
+		if xSheet.Hyperlinks != nil {
+			linkHyperlinks(sheet.Hyperlinks, xSheet.Hyperlinks, sheetRels)
+		}
+		if objects := sheet.oleObjects; objects != nil {
+			embedded = append(embedded, func() error {
+				return objects.write(xSheet, partName, packaged, rels)
+			})
+		}
 
-		colWidths := make([]float64, sheet.MaxCol)
-		for _, col := range sheet.Cols {
-			for i := col.Min - 1; i < col.Max; i++ {
-				colWidths[i] = col.Width
+		addImage := func(data []byte, imageType ImageType) string {
+			digest := sha256.Sum256(data)
+			key := imageType.extension() + string(digest[:])
+			if imagePartName, ok := media[key]; ok {
+				return imagePartName
 			}
+			mediaCount++
+			imagePartName := fmt.Sprintf("xl/media/image%d%s", mediaCount, imageType.extension())
+			parts[imagePartName] = string(data)
+			packaged.setContentType(imagePartName, imageType.contentType())
+			media[key] = imagePartName
+			return imagePartName
 		}
-
-		for _, drawing := range sheet.Drawings {
-			drawingCount++
-			var imageExt string
-			switch drawing.ImageType {
-			case IMAGE_TYPE_JPG:
-				imageExt = IMAGE_EXT_JPG
-			case IMAGE_TYPE_GIF:
-				imageExt = IMAGE_EXT_GIF
-			case IMAGE_TYPE_PNG:
-				imageExt = IMAGE_EXT_PNG
+		// Shapes read are put back among the pictures where they
+		// were; others follow the pictures.
+		shapesAt := make(map[int][]*Shape)
+		for _, shape := range sheet.Shapes {
+			at := len(sheet.Drawings)
+			if shape.read && shape.picturesBefore < at {
+				at = shape.picturesBefore
 			}
-			imageName := fmt.Sprintf("image%d%s", drawingCount, imageExt)
-			parts[fmt.Sprintf("xl/media/%s", imageName)] = string(drawing.ImageData)
-			// TODO - calculate the bottom right cell location and offset
-			var toCol, toColOff, toRow, toRowOff int
-			if drawing.RowCount > 0 && drawing.ColCount > 0 {
-				toCol = drawing.TopLeftCell.ColNum + drawing.ColCount
-				toRow = drawing.TopLeftCell.RowNum + drawing.RowCount
-			} else if drawing.RowCount > 0 {
-				toRow = drawing.TopLeftCell.RowNum + drawing.RowCount
-				targetHeightInPixel := PixelPerUnitHeight * UnitHeightPerCell * float64(drawing.RowCount)
-				targetWidthInPixel := float64(drawing.Width) / float64(drawing.Height) * targetHeightInPixel
-				targeWidth := targetWidthInPixel / PixelPerUnitWidth * NumberPerUnitWidth
-				colIndex := drawing.TopLeftCell.ColNum
-				for targeWidth >= colWidths[colIndex]*NumberPerUnitWidth {
-					targeWidth -= colWidths[colIndex] * NumberPerUnitWidth
-					colIndex++
-				}
-				toCol = colIndex
-				toColOff = int(targeWidth)
-			} else {
-				toCol = drawing.TopLeftCell.ColNum + drawing.ColCount
-				targetWidthInPixel := float64(0)
-				for colIndex := drawing.TopLeftCell.ColNum; colIndex < toCol; colIndex++ {
-					targetWidthInPixel += colWidths[colIndex] * PixelPerUnitWidth
-				}
-				targetHeightInPixel := float64(drawing.Height) / float64(drawing.Width) * targetWidthInPixel
-				targetHeight := targetHeightInPixel / PixelPerUnitHeight * NumberPerUnitHeight
-				rowIndex := drawing.TopLeftCell.RowNum
-				fmt.Println(targetHeight)
-				for targetHeight >= UnitHeightPerCell*NumberPerUnitHeight {
-					targetHeight -= UnitHeightPerCell * NumberPerUnitHeight
-					rowIndex++
+			shapesAt[at] = append(shapesAt[at], shape)
+		}
+		addShapes := func(at int) {
+			for _, shape := range shapesAt[at] {
+				placement := shape.placement()
+				sheet.anchorDrawingObject(xDrawing, &placement, shape.makeDrawingSp(len(xDrawing.Anchors)+1))
+			}
+		}
+		for i, drawing := range sheet.Drawings {
+			addShapes(i)
+			addMedia := func(data []byte, imageType ImageType) string {
+				return drawingRels.add(relTypeImage, addImage(data, imageType))
+			}
+			var svgEmbedId string
+			imageData, imageType := drawing.ImageData, drawing.ImageType
+			if imageType == IMAGE_TYPE_SVG {
+				svgEmbedId = addMedia(imageData, imageType)
+				imageData, imageType = drawing.FallbackImageData, IMAGE_TYPE_PNG
+				if imageData == nil {
+					imageData = blankPNG(drawing.Width, drawing.Height)
+				} else if imageType, _, err = decodeImage(imageData); err != nil {
+					return nil, fmt.Errorf("sheet '%s': the fallback image of picture '%s': %s", sheet.Name, drawing.Name, err)
 				}
-				toRow = rowIndex
-				toRowOff = int(targetHeight)
-				fmt.Println(targetHeight, rowIndex)
 			}
-			embedId := xDrawingRel.AddDrawingRelationship(imageName)
-			xDrawing.AddDrawingTwoCellAnchor(drawing.TopLeftCell.ColNum, 0, drawing.TopLeftCell.RowNum, 0, toCol, toColOff, toRow, toRowOff, embedId)
+			pic := newDrawingPic(addMedia(imageData, imageType))
+			sheet.anchorDrawingObject(xDrawing, &drawing, pic)
+			drawing.nameDrawingPic(pic, len(xDrawing.Anchors))
+			if svgEmbedId != "" {
+				pic.setSVG(svgEmbedId)
+			}
 		}
+		addShapes(len(sheet.Drawings))
 
-		drawingXML := fmt.Sprintf("drawing%d.xml", sheetIndex)
-		drawingPartName := fmt.Sprintf("xl/drawings/%s", drawingXML)
-		types.Overrides = append(
-			types.Overrides,
-			xlsxOverride{
-				PartName:    "/" + drawingPartName,
-				ContentType: "application/vnd.openxmlformats-officedocument.drawing+xml"})
-		parts[fmt.Sprintf("xl/drawings/_rels/%s.rels", drawingXML)], err = marshal(xDrawingRel)
-		if err != nil {
-			return parts, err
-		}
 		parts[drawingPartName], err = marshal(xDrawing)
 		if err != nil {
-			return parts, err
+			return nil, err
 		}
-		xSheetRelationships := newXlsxWorksheetRelationships()
-		xSheetRelationships.AddWorksheetDrawingRelationship(drawingXML)
-		parts[fmt.Sprintf("xl/worksheets/_rels/sheet%d.xml.rels", sheetIndex)], err = marshal(xSheetRelationships)
-		if err != nil {
-			return parts, err
+		xSheet.Drawing = &worksheetDrawing{DrawingIdStr: sheetRels.add(relTypeDrawing, drawingPartName)}
+		if sheet.Background != nil {
+			xSheet.Picture = &worksheetPicture{Id: sheetRels.add(relTypeImage, addImage(sheet.Background, sheet.BackgroundType))}
 		}
 
 		sheetIndex++
 	}
+	for _, write := range embedded {
+		if err := write(); err != nil {
+			return nil, err
+		}
+	}
+	if err := f.makePreservedSheetParts(packaged, &workbook, rels, preservedPlaces, sheetIndex); err != nil {
+		return nil, err
+	}
+	if err := f.makeVBAProjectParts(packaged, rels); err != nil {
+		return nil, err
+	}
+	workbook.CustomWorkbookViews = f.makeCustomWorkbookViews(workbook.Sheets.Sheet)
 
+	workbookRels.add(relTypeSharedStrings, "xl/sharedStrings.xml")
+	workbookRels.add(relTypeTheme, "xl/theme/theme1.xml")
+	workbookRels.add(relTypeStyles, "xl/styles.xml")
+	if f.sheetMetadata != nil {
+		parts["xl/metadata.xml"] = string(f.sheetMetadata)
+		workbookRels.add(relTypeSheetMetadata, "xl/metadata.xml")
+	}
+	if err := makeExternalLinkParts(f.writtenLinks, parts, &workbook, rels); err != nil {
+		return nil, err
+	}
+	f.makeCustomXMLParts(parts, rels)
 	workbookMarshal, err := marshal(workbook)
 	if err != nil {
-		return parts, err
+		return nil, err
 	}
 	workbookMarshal = replaceRelationshipsNameSpace(workbookMarshal)
 	parts["xl/workbook.xml"] = workbookMarshal
 	if err != nil {
-		return parts, err
+		return nil, err
 	}
 
-	parts["_rels/.rels"] = TEMPLATE__RELS_DOT_RELS
+	packageRels := rels.of("")
+	packageRels.add(relTypeOfficeDocument, "xl/workbook.xml")
+	packageRels.add(relTypeCoreProperties, "docProps/core.xml")
+	packageRels.add(relTypeExtendedProps, "docProps/app.xml")
 	parts["docProps/app.xml"] = TEMPLATE_DOCPROPS_APP
+	if name := f.appName(); name != defaultAppName {
+		var escaped bytes.Buffer
+		xml.EscapeText(&escaped, []byte(name))
+		parts["docProps/app.xml"] = strings.Replace(TEMPLATE_DOCPROPS_APP,
+			"<Application>"+defaultAppName+"</Application>", "<Application>"+escaped.String()+"</Application>", 1)
+	}
 	// TODO - do this properly, modification and revision information
-	parts["docProps/core.xml"] = TEMPLATE_DOCPROPS_CORE
+	parts["docProps/core.xml"] = f.coreProperties()
 	parts["xl/theme/theme1.xml"] = TEMPLATE_XL_THEME_THEME
 
-	xSST := refTable.makeXLSXSST()
-	parts["xl/sharedStrings.xml"], err = marshal(xSST)
-	if err != nil {
-		return parts, err
+	packaged.writers["xl/sharedStrings.xml"] = func(w io.Writer) error {
+		return writeSharedStrings(w, refTable)
 	}
 
-	xWRel := workbookRels.MakeXLSXWorkbookRels()
+	if err := rels.write(parts); err != nil {
+		return nil, err
+	}
 
-	parts["xl/_rels/workbook.xml.rels"], err = marshal(xWRel)
+	parts["xl/styles.xml"], err = f.styles.Marshal()
 	if err != nil {
-		return parts, err
+		return nil, err
 	}
-
-	parts["[Content_Types].xml"], err = marshal(types)
+	types, err := makeContentTypes(packaged.order(), packaged.contentTypes)
 	if err != nil {
-		return parts, err
+		return nil, err
 	}
-
-	parts["xl/styles.xml"], err = f.styles.Marshal()
+	parts["[Content_Types].xml"], err = marshal(types)
 	if err != nil {
-		return parts, err
+		return nil, err
+	}
+	if err := budget.checkTime(); err != nil {
+		return nil, err
+	}
+	if err := budget.checkBytes(parts); err != nil {
+		return nil, err
+	}
+	if err := rels.check(packaged); err != nil {
+		return nil, err
 	}
 
-	return parts, nil
+	return packaged, nil
 }
 
 // Return the raw data contained in the File as three
@@ -388,6 +1109,9 @@ func (f *File) MarshallParts() (map[string]string, error) {
 func (file *File) ToSlice() (output [][][]string, err error) {
 	output = [][][]string{}
 	for _, sheet := range file.Sheets {
+		if err := sheet.Load(); err != nil {
+			return output, err
+		}
 		s := [][]string{}
 		for _, row := range sheet.Rows {
 			if row == nil {
@@ -395,6 +1119,10 @@ func (file *File) ToSlice() (output [][][]string, err error) {
 			}
 			r := []string{}
 			for _, cell := range row.Cells {
+				if cell == nil {
+					r = append(r, "")
+					continue
+				}
 				str, err := cell.String()
 				if err != nil {
 					return output, err
@@ -0,0 +1,387 @@
+package xlsx
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+var (
+	placeholderRegexp = regexp.MustCompile(`\{\{\s*([^{}]*?)\s*\}\}`)
+	rangeMarkerRegexp = regexp.MustCompile(`^\{\{\s*range\s+([^{}\s]+)\s*\}\}$`)
+	endMarkerRegexp   = regexp.MustCompile(`^\{\{\s*end\s*\}\}$`)
+)
+
+// FillTemplate fills in the placeholders in every Sheet of the File
+// from data.  See Sheet.FillTemplate for details.
+func (f *File) FillTemplate(data interface{}) error {
+	for _, sheet := range f.Sheets {
+		if err := sheet.FillTemplate(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FillTemplate treats the Sheet as a template, replacing the placeholders in
+// its cells with values from data, which can be a map with string
+// keys, a struct, or a pointer to either.
+//
+// A placeholder such as {{customer.name}} is replaced by the value at
+// that path in data.  A cell holding nothing but a placeholder takes
+// on the type of the value, so numbers and dates stay numbers and
+// dates; placeholders within other text are replaced by the value
+// formatted with fmt.
+//
+// A row holding the marker {{range items}} starts a loop which ends
+// with a row holding {{end}}.  The rows between the markers are
+// cloned once for each element of the slice found at items - along
+// with their styles, merges and formulas - and the marker rows are
+// removed.  Within a loop, {{.}} is the current element and other
+// placeholders are looked up in the current element before the
+// enclosing data.  Loops may be nested.
+//
+// Formulas are adjusted for the rows added and removed: references
+// to rows within the same loop iteration point to that iteration's
+// copy, and ranges ending on the last row of a loop grow to cover all
+// the copies, so that a total such as SUM(C3:C3) beneath a loop adds
+// up every element.  References to other sheets, such as Other!B4,
+// are left as they are.
+func (s *Sheet) FillTemplate(data interface{}) error {
+	if err := s.Load(); err != nil {
+		return err
+	}
+	filler := &templateFiller{sheet: s.Name, template: s.Rows}
+	out, err := filler.expand(0, len(s.Rows), []reflect.Value{reflect.ValueOf(data)}, nil)
+	if err != nil {
+		return err
+	}
+	filler.adjustFormulas(out)
+	s.Rows = make([]*Row, len(out))
+	for i, o := range out {
+		s.Rows[i] = o.row
+	}
+	s.MaxRow = len(s.Rows)
+	return nil
+}
+
+// templateFiller holds the state of a call to Sheet.FillTemplate.
+type templateFiller struct {
+	sheet    string
+	template []*Row
+	first    map[int]int // index in the output of a template row's first copy
+	last     map[int]int // index in the output of a template row's last copy
+}
+
+// filledRow is a row of the filled sheet.
+type filledRow struct {
+	row       *Row
+	origin    int // index of the template row it was made from
+	index     int // index in the filled sheet
+	iteration *loopIteration
+}
+
+// loopIteration records the rows made by one iteration of a loop.
+type loopIteration struct {
+	parent *loopIteration
+	rows   map[int]*filledRow
+}
+
+// expand fills template rows [start, end) and returns the resulting
+// rows.
+func (t *templateFiller) expand(start, end int, scopes []reflect.Value, iteration *loopIteration) ([]*filledRow, error) {
+	var out []*filledRow
+	for i := start; i < end; i++ {
+		row := t.template[i]
+		path, isRange := rowMarker(row, rangeMarkerRegexp)
+		if _, isEnd := rowMarker(row, endMarkerRegexp); isEnd {
+			return nil, fmt.Errorf("{{end}} without {{range}} in row %d", i+1)
+		}
+		if !isRange {
+			filled, err := fillRow(row, scopes)
+			if err != nil {
+				return nil, err
+			}
+			o := &filledRow{row: filled, origin: i, iteration: iteration}
+			if iteration != nil {
+				iteration.rows[i] = o
+			}
+			out = append(out, o)
+			continue
+		}
+
+		loopEnd, err := t.findLoopEnd(i)
+		if err != nil {
+			return nil, err
+		}
+		items, err := lookupPlaceholder(path, scopes)
+		if err != nil {
+			return nil, err
+		}
+		items = indirect(items)
+		if items.IsValid() && items.Kind() != reflect.Slice && items.Kind() != reflect.Array {
+			return nil, fmt.Errorf("{{range %s}} in row %d needs a slice, not %s", path, i+1, items.Type())
+		}
+		if items.IsValid() {
+			for n := 0; n < items.Len(); n++ {
+				inner := append(append([]reflect.Value{}, scopes...), items.Index(n))
+				it := &loopIteration{parent: iteration, rows: make(map[int]*filledRow)}
+				rows, err := t.expand(i+1, loopEnd, inner, it)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, rows...)
+			}
+		}
+		i = loopEnd
+	}
+	return out, nil
+}
+
+// findLoopEnd returns the index of the {{end}} row matching the
+// {{range}} row at index start.
+func (t *templateFiller) findLoopEnd(start int) (int, error) {
+	depth := 0
+	for i := start + 1; i < len(t.template); i++ {
+		if _, ok := rowMarker(t.template[i], rangeMarkerRegexp); ok {
+			depth++
+		} else if _, ok := rowMarker(t.template[i], endMarkerRegexp); ok {
+			if depth == 0 {
+				return i, nil
+			}
+			depth--
+		}
+	}
+	return -1, fmt.Errorf("{{range}} in row %d has no {{end}}", start+1)
+}
+
+// rowMarker looks for a cell in the row consisting only of a marker
+// matching re, returning the marker's argument.
+func rowMarker(row *Row, re *regexp.Regexp) (string, bool) {
+	if row == nil {
+		return "", false
+	}
+	for _, cell := range row.Cells {
+		if cell == nil {
+			continue
+		}
+		if m := re.FindStringSubmatch(strings.TrimSpace(cell.Value)); m != nil {
+			if len(m) > 1 {
+				return m[1], true
+			}
+			return "", true
+		}
+	}
+	return "", false
+}
+
+// fillRow returns a copy of the template row with its placeholders
+// filled in.
+func fillRow(row *Row, scopes []reflect.Value) (*Row, error) {
+	if row == nil {
+		return nil, nil
+	}
+	filled := *row
+	filled.Cells = make([]*Cell, len(row.Cells))
+	for i, cell := range row.Cells {
+		if cell == nil {
+			continue
+		}
+		c := *cell
+		c.Row = &filled
+		if cell.style != nil {
+			style := *cell.style
+			c.style = &style
+		}
+		if err := fillCell(&c, scopes); err != nil {
+			return nil, err
+		}
+		filled.Cells[i] = &c
+	}
+	return &filled, nil
+}
+
+// fillCell replaces the placeholders in the cell's value.
+func fillCell(cell *Cell, scopes []reflect.Value) error {
+	if cell.formula != "" || !strings.Contains(cell.Value, "{{") {
+		return nil
+	}
+	if m := placeholderRegexp.FindStringSubmatch(cell.Value); m != nil && m[0] == strings.TrimSpace(cell.Value) {
+		v, err := lookupPlaceholder(m[1], scopes)
+		if err != nil {
+			return err
+		}
+		v = indirect(v)
+		switch {
+		case !v.IsValid():
+			cell.SetString("")
+		case v.Kind() == reflect.Bool:
+			cell.SetBool(v.Bool())
+		default:
+			numFmt := cell.NumFmt
+			cell.SetValue(v.Interface())
+			keepNumFmt(cell, numFmt)
+		}
+		return nil
+	}
+	var err error
+	value := placeholderRegexp.ReplaceAllStringFunc(cell.Value, func(placeholder string) string {
+		path := placeholderRegexp.FindStringSubmatch(placeholder)[1]
+		v, lookupErr := lookupPlaceholder(path, scopes)
+		if lookupErr != nil {
+			err = lookupErr
+			return placeholder
+		}
+		v = indirect(v)
+		if !v.IsValid() {
+			return ""
+		}
+		return fmt.Sprint(v.Interface())
+	})
+	if err != nil {
+		return err
+	}
+	cell.SetString(value)
+	return nil
+}
+
+// keepNumFmt restores the number format given to a placeholder cell
+// in the template, unless it was a text format that would hide the
+// type of the new value.
+func keepNumFmt(cell *Cell, numFmt string) {
+	switch numFmt {
+	case "", builtInNumFmt[builtInNumFmtIndex_GENERAL], builtInNumFmt[builtInNumFmtIndex_STRING]:
+		return
+	}
+	if cell.cellType == CellTypeGeneral {
+		cell.cellType = CellTypeNumeric
+	}
+	if cell.cellType != CellTypeString {
+		cell.NumFmt = numFmt
+	}
+}
+
+// lookupPlaceholder returns the value at the dotted path, looking in
+// each scope in turn, starting with the innermost.
+func lookupPlaceholder(path string, scopes []reflect.Value) (reflect.Value, error) {
+	if path == "." {
+		return scopes[len(scopes)-1], nil
+	}
+	names := strings.Split(strings.TrimPrefix(path, "."), ".")
+	if strings.HasPrefix(path, ".") {
+		// An explicit reference to the current element.
+		scopes = scopes[len(scopes)-1:]
+	}
+	for i := len(scopes) - 1; i >= 0; i-- {
+		v, ok := lookupField(scopes[i], names[0])
+		if !ok {
+			continue
+		}
+		for _, name := range names[1:] {
+			v, ok = lookupField(v, name)
+			if !ok {
+				return reflect.Value{}, fmt.Errorf("no value for placeholder {{%s}}", path)
+			}
+		}
+		return v, nil
+	}
+	return reflect.Value{}, fmt.Errorf("no value for placeholder {{%s}}", path)
+}
+
+// lookupField returns the named map entry or struct field of v.
+// Struct fields match the name case insensitively.
+func lookupField(v reflect.Value, name string) (reflect.Value, bool) {
+	v = indirect(v)
+	if !v.IsValid() {
+		return reflect.Value{}, false
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return reflect.Value{}, false
+		}
+		entry := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		return entry, entry.IsValid()
+	case reflect.Struct:
+		field := v.FieldByNameFunc(func(field string) bool {
+			return strings.EqualFold(field, name)
+		})
+		if !field.IsValid() || !field.CanInterface() {
+			return reflect.Value{}, false
+		}
+		return field, true
+	}
+	return reflect.Value{}, false
+}
+
+// indirect follows pointers and interfaces to the value they hold.
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// adjustFormulas rewrites the references in the formulas of the
+// filled rows to account for the rows added and removed.
+func (t *templateFiller) adjustFormulas(out []*filledRow) {
+	t.first = make(map[int]int, len(out))
+	t.last = make(map[int]int, len(out))
+	for i, o := range out {
+		o.index = i
+		if _, ok := t.first[o.origin]; !ok {
+			t.first[o.origin] = i
+		}
+		t.last[o.origin] = i
+	}
+	for _, o := range out {
+		if o.row == nil {
+			continue
+		}
+		for _, cell := range o.row.Cells {
+			if cell == nil || cell.formula == "" {
+				continue
+			}
+			cell.formula = mapFormulaSheetRefs(cell.formula, func(sheet string, ref CellRef, rangeEnd bool) CellRef {
+				if sheet != "" && !strings.EqualFold(sheet, t.sheet) {
+					return ref
+				}
+				ref.Row = t.mapRow(ref.Row, rangeEnd, o.iteration, len(out))
+				return ref
+			})
+		}
+	}
+}
+
+// mapRow returns the index in the filled sheet of a row referred to
+// by its index in the template.
+func (t *templateFiller) mapRow(row int, rangeEnd bool, iteration *loopIteration, filledRows int) int {
+	if row >= len(t.template) {
+		return row + filledRows - len(t.template)
+	}
+	for it := iteration; it != nil; it = it.parent {
+		if o, ok := it.rows[row]; ok {
+			return o.index
+		}
+	}
+	if rangeEnd {
+		// The last copy of this row, or of the nearest one above.
+		for r := row; r >= 0; r-- {
+			if i, ok := t.last[r]; ok {
+				return i
+			}
+		}
+		return 0
+	}
+	// The first copy of this row, or of the nearest one below.
+	for r := row; r < len(t.template); r++ {
+		if i, ok := t.first[r]; ok {
+			return i
+		}
+	}
+	return filledRows
+}
@@ -0,0 +1,187 @@
+package xlsx
+
+import (
+	"bytes"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type FillSuite struct{}
+
+var _ = Suite(&FillSuite{})
+
+type fillLine struct {
+	Product  string
+	Quantity int
+	Price    float64
+}
+
+type fillInvoice struct {
+	Customer struct{ Name string }
+	Date     time.Time
+	Lines    []fillLine
+	Paid     bool
+}
+
+func makeInvoiceTemplate() *Sheet {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Invoice")
+	cells := func(values ...string) *Row {
+		row := sheet.AddRow()
+		for _, v := range values {
+			row.AddCell().SetString(v)
+		}
+		return row
+	}
+	cells("Invoice for {{customer.name}}", "{{date}}")
+	cells("Product", "Quantity", "Price", "Total")
+	cells("{{range lines}}")
+	row := cells("{{product}}", "{{quantity}}", "{{price}}")
+	row.AddCell().SetFormula("B4*C4")
+	row.Cells[0].GetStyle().Font.Bold = true
+	cells("{{end}}")
+	row = cells("Total", "", "")
+	row.AddCell().SetFormula("SUM(D4:D4)")
+	cells("Paid: {{paid}}", "{{paid}}")
+	row = sheet.AddRow()
+	row.AddCell().SetFormula("D6")
+	return sheet
+}
+
+func makeInvoice() *fillInvoice {
+	invoice := &fillInvoice{
+		Date: time.Date(2016, 3, 1, 0, 0, 0, 0, time.UTC),
+		Lines: []fillLine{
+			{"Fish", 2, 1.5},
+			{"Chips", 3, 0.75},
+			{"Peas", 1, 0.5},
+		},
+	}
+	invoice.Customer.Name = "Bob"
+	return invoice
+}
+
+func (s *FillSuite) TestFill(c *C) {
+	sheet := makeInvoiceTemplate()
+	c.Assert(sheet.FillTemplate(makeInvoice()), IsNil)
+	c.Assert(sheet.Rows, HasLen, 8)
+
+	c.Assert(sheet.Cell(0, 0).Value, Equals, "Invoice for Bob")
+	c.Assert(sheet.Cell(0, 1).Type(), Equals, CellTypeDate)
+	serial, err := sheet.Cell(0, 1).Float()
+	c.Assert(err, IsNil)
+	date, err := TimeFromExcelSerial(serial, false, DatePolicyCorrect)
+	c.Assert(err, IsNil)
+	c.Assert(date.Equal(time.Date(2016, 3, 1, 0, 0, 0, 0, time.UTC)), Equals, true)
+
+	for i, product := range []string{"Fish", "Chips", "Peas"} {
+		row := sheet.Rows[2+i]
+		c.Assert(row.Cells[0].Value, Equals, product)
+		c.Assert(row.Cells[0].GetStyle().Font.Bold, Equals, true)
+		c.Assert(row.Cells[0].Row, Equals, row)
+		c.Assert(row.Cells[1].Type(), Not(Equals), CellTypeString)
+		c.Assert(row.Cells[3].Formula(), Equals, ref("B", 3+i)+"*"+ref("C", 3+i))
+	}
+	quantity, _ := sheet.Cell(3, 1).Int()
+	c.Assert(quantity, Equals, 3)
+	price, _ := sheet.Cell(4, 2).Float()
+	c.Assert(price, Equals, 0.5)
+
+	// Styles are copied, not shared.
+	c.Assert(sheet.Cell(2, 0).GetStyle() == sheet.Cell(3, 0).GetStyle(), Equals, false)
+
+	c.Assert(sheet.Cell(5, 0).Value, Equals, "Total")
+	c.Assert(sheet.Cell(5, 3).Formula(), Equals, "SUM(D3:D5)")
+	c.Assert(sheet.Cell(6, 0).Value, Equals, "Paid: false")
+	c.Assert(sheet.Cell(6, 1).Type(), Equals, CellTypeBool)
+	c.Assert(sheet.Cell(7, 0).Formula(), Equals, "D6")
+
+	// The result can be written.
+	var buf bytes.Buffer
+	c.Assert(sheet.File.Write(&buf), IsNil)
+}
+
+// References to other sheets aren't moved with the rows.
+func (s *FillSuite) TestFillOtherSheetRefs(c *C) {
+	sheet := makeInvoiceTemplate()
+	sheet.Cell(3, 4).SetFormula("Other!B4+'It''s'!B4:C4+invoice!B4+SUM(Other!D4:D4)+C4")
+	c.Assert(sheet.FillTemplate(makeInvoice()), IsNil)
+	for i := 0; i < 3; i++ {
+		c.Assert(sheet.Cell(2+i, 4).Formula(), Equals, "Other!B4+'It''s'!B4:C4+invoice!"+ref("B", 3+i)+"+SUM(Other!D4:D4)+"+ref("C", 3+i))
+	}
+}
+
+func ref(col string, row int) string {
+	return col + string(rune('0'+row))
+}
+
+func (s *FillSuite) TestFillEmptyLoop(c *C) {
+	sheet := makeInvoiceTemplate()
+	invoice := makeInvoice()
+	invoice.Lines = nil
+	c.Assert(sheet.FillTemplate(invoice), IsNil)
+	c.Assert(sheet.Rows, HasLen, 5)
+	c.Assert(sheet.Cell(2, 0).Value, Equals, "Total")
+	c.Assert(sheet.Cell(4, 0).Formula(), Equals, "D3")
+}
+
+func (s *FillSuite) TestFillMapsAndNesting(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	sheet.AddRow().AddCell().SetString("{{range groups}}")
+	sheet.AddRow().AddCell().SetString("{{name}} ({{title}})")
+	sheet.AddRow().AddCell().SetString("{{ range members }}")
+	sheet.AddRow().AddCell().SetString("- {{.}}")
+	sheet.AddRow().AddCell().SetString("{{end}}")
+	sheet.AddRow().AddCell().SetString("{{end}}")
+
+	data := map[string]interface{}{
+		"title": "Teams",
+		"groups": []map[string]interface{}{
+			{"name": "Red", "members": []string{"Ann", "Bo"}},
+			{"name": "Blue", "members": []string{"Cy"}},
+		},
+	}
+	c.Assert(sheet.FillTemplate(data), IsNil)
+	var values []string
+	for _, row := range sheet.Rows {
+		values = append(values, row.Cells[0].Value)
+	}
+	c.Assert(values, DeepEquals, []string{"Red (Teams)", "- Ann", "- Bo", "Blue (Teams)", "- Cy"})
+}
+
+func (s *FillSuite) TestFillErrors(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	sheet.AddRow().AddCell().SetString("{{missing}}")
+	c.Assert(sheet.FillTemplate(map[string]string{}), ErrorMatches, `no value for placeholder \{\{missing\}\}`)
+
+	sheet, _ = file.AddSheet("Sheet2")
+	sheet.AddRow().AddCell().SetString("{{range items}}")
+	c.Assert(sheet.FillTemplate(map[string]interface{}{"items": []int{1}}), ErrorMatches, `\{\{range\}\} in row 1 has no \{\{end\}\}`)
+
+	sheet, _ = file.AddSheet("Sheet3")
+	sheet.AddRow().AddCell().SetString("{{range items}}")
+	sheet.AddRow().AddCell().SetString("{{end}}")
+	c.Assert(sheet.FillTemplate(map[string]interface{}{"items": 1}), ErrorMatches, `\{\{range items\}\} in row 1 needs a slice, not int`)
+}
+
+func (s *FillSuite) TestMapFormulaRefs(c *C) {
+	shift := func(ref CellRef, rangeEnd bool) CellRef {
+		if rangeEnd {
+			return ref.Offset(0, 10)
+		}
+		return ref.Offset(1, 1)
+	}
+	c.Assert(mapFormulaRefs("SUM(A1:$B$2)+LOG10(C3)", shift), Equals, "SUM(B2:$B$12)+LOG10(D4)")
+	c.Assert(mapFormulaRefs(`IF(A1="A1",'Sheet A1'!A1,Sheet1!A1)`, shift), Equals, `IF(B2="A1",'Sheet A1'!B2,Sheet1!B2)`)
+	c.Assert(mapFormulaRefs("my_A1+ATAN2(1,2)", shift), Equals, "my_A1+ATAN2(1,2)")
+
+	var sheets []string
+	mapFormulaSheetRefs(`A1+Other!A1:B2+'It''s'!C3+D4:E5+"x"!F6`, func(sheet string, ref CellRef, rangeEnd bool) CellRef {
+		sheets = append(sheets, sheet)
+		return ref
+	})
+	c.Assert(sheets, DeepEquals, []string{"", "Other", "Other", "It's", "", "", ""})
+}
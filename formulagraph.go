@@ -0,0 +1,461 @@
+package xlsx
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FormulaNode is a cell holding a formula, in a FormulaGraph.
+type FormulaNode struct {
+	Sheet   string
+	Ref     CellRef
+	Formula string
+	// Precedents are the ranges of cells the formula refers to,
+	// each on a named sheet, in the order the formula refers to
+	// them.  Defined names are replaced by the ranges they stand
+	// for.
+	Precedents []CellRange
+}
+
+// String returns the cell of the node, such as "Data!B2".
+func (n *FormulaNode) String() string {
+	return quoteSheetName(n.Sheet) + "!" + n.Ref.String()
+}
+
+// FormulaGraph is how the formulas of a File depend on each other and
+// on the cells they refer to, as File.FormulaGraph finds it.
+type FormulaGraph struct {
+	// Nodes are the cells of the File holding formulas, sheet by
+	// sheet and row by row.
+	Nodes []*FormulaNode
+	// cells holds the index of each node in Nodes, by sheet and cell.
+	cells map[string]map[CellRef]int
+	// precedents and dependents are the indexes of the nodes each
+	// node refers to, and of those that refer to it.
+	precedents [][]int
+	dependents [][]int
+}
+
+// CircularReferenceError is returned by FormulaGraph.Order when
+// formulas refer to themselves, directly or through each other.
+type CircularReferenceError struct {
+	// Cells are the cells whose formulas are part of, or lie between,
+	// circular references, such as "Data!B2".
+	Cells []string
+}
+
+// Error returns a string value from a CircularReferenceError in order
+// that it might comply with the builtin.error interface.
+func (e *CircularReferenceError) Error() string {
+	return fmt.Sprintf("circular reference between %s", strings.Join(e.Cells, ", "))
+}
+
+// FormulaGraph parses the formulas of every Sheet of the File and
+// returns the graph of their dependencies, for finding what a change
+// to a cell affects or the order in which to calculate them.  A
+// reference to another sheet, or to a range of sheets such as
+// "Jan:Mar!B2", depends on the cells of those sheets; references to
+// other workbooks, to tables and to sheets the File doesn't have are
+// left out.  Whole columns and rows, such as "A:A", are ranges
+// reaching to the edge of the worksheet.  Sheets of a File opened with
+// LazySheets are loaded, and an error loading one is returned.
+func (f *File) FormulaGraph() (*FormulaGraph, error) {
+	g := &FormulaGraph{cells: make(map[string]map[CellRef]int)}
+	for _, sheet := range f.Sheets {
+		err := sheet.ForEachCell(func(row, col int, cell *Cell) error {
+			if cell.formula == "" {
+				return nil
+			}
+			ref := CellRef{Col: col, Row: row}
+			if g.cells[sheet.Name] == nil {
+				g.cells[sheet.Name] = make(map[CellRef]int)
+			}
+			g.cells[sheet.Name][ref] = len(g.Nodes)
+			g.Nodes = append(g.Nodes, &FormulaNode{
+				Sheet:      sheet.Name,
+				Ref:        ref,
+				Formula:    cell.formula,
+				Precedents: f.formulaRanges(cell.formula, sheet.Name, nil),
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	g.precedents = make([][]int, len(g.Nodes))
+	g.dependents = make([][]int, len(g.Nodes))
+	for i, node := range g.Nodes {
+		seen := make(map[int]bool)
+		for _, r := range node.Precedents {
+			for _, j := range g.nodesIn(r) {
+				if !seen[j] {
+					seen[j] = true
+					g.precedents[i] = append(g.precedents[i], j)
+					g.dependents[j] = append(g.dependents[j], i)
+				}
+			}
+		}
+	}
+	return g, nil
+}
+
+// nodesIn returns the indexes of the nodes in the range, looking each
+// cell of a small range up and going over the sheet's nodes for a
+// large one, such as a whole column.
+func (g *FormulaGraph) nodesIn(r CellRange) []int {
+	cells := g.cells[r.Sheet]
+	var found []int
+	if r.Cols()*r.Rows() <= len(cells) {
+		for row := r.Start.Row; row <= r.End.Row; row++ {
+			for col := r.Start.Col; col <= r.End.Col; col++ {
+				if i, ok := cells[CellRef{Col: col, Row: row}]; ok {
+					found = append(found, i)
+				}
+			}
+		}
+		return found
+	}
+	for ref, i := range cells {
+		if r.Contains(ref) {
+			found = append(found, i)
+		}
+	}
+	sort.Ints(found)
+	return found
+}
+
+// Node returns the node of the cell at ref on the named sheet, or nil
+// if it doesn't hold a formula.
+func (g *FormulaGraph) Node(sheet string, ref CellRef) *FormulaNode {
+	if i, ok := g.cells[sheet][CellRef{Col: ref.Col, Row: ref.Row}]; ok {
+		return g.Nodes[i]
+	}
+	return nil
+}
+
+// Dependents returns the nodes whose formulas refer to the cell at ref
+// on the named sheet directly, in the order of Nodes.
+func (g *FormulaGraph) Dependents(sheet string, ref CellRef) []*FormulaNode {
+	var dependents []*FormulaNode
+	for _, node := range g.Nodes {
+		for _, r := range node.Precedents {
+			if r.Sheet == sheet && r.Contains(ref) {
+				dependents = append(dependents, node)
+				break
+			}
+		}
+	}
+	return dependents
+}
+
+// Impact returns the nodes whose values depend on the cell at ref on
+// the named sheet, directly or through other formulas: those that
+// would need calculating again if it changed.  They are in the order
+// of Nodes.
+func (g *FormulaGraph) Impact(sheet string, ref CellRef) []*FormulaNode {
+	affected := make([]bool, len(g.Nodes))
+	var queue []int
+	for _, node := range g.Dependents(sheet, ref) {
+		i := g.cells[node.Sheet][node.Ref]
+		affected[i] = true
+		queue = append(queue, i)
+	}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		for _, j := range g.dependents[i] {
+			if !affected[j] {
+				affected[j] = true
+				queue = append(queue, j)
+			}
+		}
+	}
+	var impact []*FormulaNode
+	for i, node := range g.Nodes {
+		if affected[i] {
+			impact = append(impact, node)
+		}
+	}
+	return impact
+}
+
+// Order returns the nodes in an order in which they can be calculated,
+// each after the formulas it refers to.  Formulas that refer to each
+// other in a circle can't be ordered, and a *CircularReferenceError
+// giving them is returned.
+func (g *FormulaGraph) Order() ([]*FormulaNode, error) {
+	waiting := make([]int, len(g.Nodes))
+	var queue []int
+	for i := range g.Nodes {
+		waiting[i] = len(g.precedents[i])
+		if waiting[i] == 0 {
+			queue = append(queue, i)
+		}
+	}
+	var order []*FormulaNode
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		order = append(order, g.Nodes[i])
+		for _, j := range g.dependents[i] {
+			if waiting[j]--; waiting[j] == 0 {
+				queue = append(queue, j)
+			}
+		}
+	}
+	if len(order) == len(g.Nodes) {
+		return order, nil
+	}
+
+	// Of the nodes left, those that only lead away from circles
+	// aren't part of any, so they are passed over as well.
+	left := make([]bool, len(g.Nodes))
+	leading := make([]int, len(g.Nodes))
+	for i := range g.Nodes {
+		left[i] = waiting[i] > 0
+	}
+	queue = queue[:0]
+	for i := range g.Nodes {
+		if !left[i] {
+			continue
+		}
+		for _, j := range g.dependents[i] {
+			if left[j] {
+				leading[i]++
+			}
+		}
+		if leading[i] == 0 {
+			queue = append(queue, i)
+		}
+	}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		left[i] = false
+		for _, j := range g.precedents[i] {
+			if left[j] {
+				if leading[j]--; leading[j] == 0 {
+					queue = append(queue, j)
+				}
+			}
+		}
+	}
+	circular := &CircularReferenceError{}
+	for i, node := range g.Nodes {
+		if left[i] {
+			circular.Cells = append(circular.Cells, node.String())
+		}
+	}
+	return nil, circular
+}
+
+// formulaRanges returns the ranges a formula on the named sheet refers
+// to, each with its sheet, replacing the defined names it uses by the
+// ranges they stand for.  names holds the names being replaced, so
+// that names defined in terms of themselves end.
+func (f *File) formulaRanges(formula, sheet string, names map[string]bool) []CellRange {
+	var ranges []CellRange
+	add := func(sheets []string, r CellRange) {
+		for _, name := range sheets {
+			r.Sheet = name
+			ranges = append(ranges, r)
+		}
+	}
+	for i := 0; i < len(formula); {
+		c := formula[i]
+		switch {
+		case c == '"':
+			i = closingQuote(formula, i)
+		case c == '\'':
+			j := closingQuote(formula, i)
+			if j >= len(formula) || formula[j] != '!' {
+				i = j
+				continue
+			}
+			quoted := strings.Replace(formula[i+1:j-1], "''", "'", -1)
+			r, end, ok := parseFormulaRange(formula, j+1)
+			if ok && !strings.HasPrefix(quoted, "[") {
+				add(f.sheetSpan(quoted), r)
+			}
+			i = end
+		case c == '[':
+			// The sheets of other workbooks, and the columns of
+			// tables.
+			j := closingBracket(formula, i)
+			for j < len(formula) && (isNameByte(formula[j]) || formula[j] == ':' || formula[j] == '!' || formula[j] == '$') {
+				j++
+			}
+			i = j
+		case c == '#':
+			// Errors, such as #REF!
+			j := i + 1
+			for j < len(formula) && (isNameByte(formula[j]) || formula[j] == '/') {
+				j++
+			}
+			if j < len(formula) && (formula[j] == '!' || formula[j] == '?') {
+				j++
+			}
+			i = j
+		case isNameByte(c) || c == '$':
+			j := i
+			for j < len(formula) && (isNameByte(formula[j]) || formula[j] == '$' || formula[j] == ':') {
+				j++
+			}
+			token := formula[i:j]
+			switch {
+			case j < len(formula) && formula[j] == '!':
+				r, end, ok := parseFormulaRange(formula, j+1)
+				if ok {
+					add(f.sheetSpan(token), r)
+				}
+				j = end
+			case j < len(formula) && (formula[j] == '(' || formula[j] == '['):
+				// A function, or a table.
+			default:
+				if r, ok := parseRangeToken(token); ok {
+					add([]string{sheet}, r)
+				} else if refersTo, local, ok := f.lookupName(token, sheet); ok && !names[strings.ToLower(token)] {
+					inner := make(map[string]bool, len(names)+1)
+					for name := range names {
+						inner[name] = true
+					}
+					inner[strings.ToLower(token)] = true
+					if local == "" {
+						local = sheet
+					}
+					ranges = append(ranges, f.formulaRanges(refersTo, local, inner)...)
+				}
+			}
+			i = j
+		default:
+			i++
+		}
+	}
+	return ranges
+}
+
+// parseFormulaRange parses the reference starting at i in the formula,
+// just after the "!" of its sheet, returning it and the index just
+// past it.
+func parseFormulaRange(formula string, i int) (CellRange, int, bool) {
+	j := i
+	for j < len(formula) && (isNameByte(formula[j]) || formula[j] == '$' || formula[j] == ':') {
+		j++
+	}
+	r, ok := parseRangeToken(formula[i:j])
+	return r, j, ok
+}
+
+// parseRangeToken parses a reference without a sheet: a cell such as
+// "$B$2", a range of cells such as "A1:B2", or whole columns or rows
+// such as "A:C" or "1:3".
+func parseRangeToken(token string) (CellRange, bool) {
+	parts := strings.Split(token, ":")
+	if len(parts) > 2 {
+		return CellRange{}, false
+	}
+	if r, err := ParseCellRange(token); err == nil {
+		return r, true
+	}
+	if len(parts) != 2 {
+		return CellRange{}, false
+	}
+	start, okStart := parseColOrRow(parts[0])
+	end, okEnd := parseColOrRow(parts[1])
+	if !okStart || !okEnd || start.isRow != end.isRow {
+		return CellRange{}, false
+	}
+	if start.isRow {
+		return NewCellRange(CellRef{Row: start.index}, CellRef{Col: SheetColLimit - 1, Row: end.index}), true
+	}
+	return NewCellRange(CellRef{Col: start.index}, CellRef{Col: end.index, Row: SheetRowLimit - 1}), true
+}
+
+// colOrRow is one end of a reference to whole columns or rows.
+type colOrRow struct {
+	index int
+	isRow bool
+}
+
+// parseColOrRow parses a column, such as "$C", or a row, such as "3".
+func parseColOrRow(s string) (colOrRow, bool) {
+	s = strings.TrimPrefix(s, "$")
+	if s == "" {
+		return colOrRow{}, false
+	}
+	digits := true
+	for _, c := range s {
+		digits = digits && '0' <= c && c <= '9'
+	}
+	if digits {
+		var row int
+		if _, err := fmt.Sscan(s, &row); err != nil || row < 1 || row > SheetRowLimit {
+			return colOrRow{}, false
+		}
+		return colOrRow{index: row - 1, isRow: true}, true
+	}
+	col, err := ColLettersToIndex(s)
+	if err != nil || col >= SheetColLimit {
+		return colOrRow{}, false
+	}
+	return colOrRow{index: col}, true
+}
+
+// sheetSpan returns the names of the sheets a reference's sheet part
+// names: one sheet, or those between two, as in "Jan:Mar", in the
+// File's order.  Sheets the File doesn't have aren't included.
+func (f *File) sheetSpan(name string) []string {
+	parts := strings.SplitN(name, ":", 2)
+	if len(parts) == 1 {
+		if f.Sheet[name] == nil {
+			return nil
+		}
+		return []string{name}
+	}
+	first, last := -1, -1
+	for i, sheet := range f.Sheets {
+		if sheet.Name == parts[0] {
+			first = i
+		}
+		if sheet.Name == parts[1] {
+			last = i
+		}
+	}
+	if first < 0 || last < 0 {
+		return nil
+	}
+	if first > last {
+		first, last = last, first
+	}
+	var names []string
+	for _, sheet := range f.Sheets[first : last+1] {
+		names = append(names, sheet.Name)
+	}
+	return names
+}
+
+// lookupName returns what the defined name stands for in a formula on
+// the named sheet, and the sheet it is local to: one local to that
+// sheet or, failing that, one of the workbook.  Names are matched
+// regardless of case, as Excel matches them.
+func (f *File) lookupName(name, sheet string) (string, string, bool) {
+	var found *DefinedName
+	for _, dn := range f.Names {
+		if !strings.EqualFold(dn.Name, name) {
+			continue
+		}
+		if dn.Sheet == sheet {
+			return dn.RefersTo, dn.Sheet, true
+		}
+		if dn.Sheet == "" {
+			found = dn
+		}
+	}
+	if found == nil {
+		return "", "", false
+	}
+	return found.RefersTo, "", true
+}
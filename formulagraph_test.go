@@ -0,0 +1,113 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type FormulaGraphSuite struct{}
+
+var _ = Suite(&FormulaGraphSuite{})
+
+// nodeNames returns the cells of the nodes, such as "Data!B2".
+func nodeNames(nodes []*FormulaNode) []string {
+	var names []string
+	for _, node := range nodes {
+		names = append(names, node.String())
+	}
+	return names
+}
+
+func makeGraphFile(c *C) *File {
+	f := NewFile()
+	for _, name := range []string{"Jan", "Feb", "Mar", "Summary"} {
+		_, err := f.AddSheet(name)
+		c.Assert(err, IsNil)
+	}
+	for _, name := range []string{"Jan", "Feb", "Mar"} {
+		sheet := f.Sheet[name]
+		sheet.Cell(0, 0).SetInt(1)
+		sheet.Cell(1, 0).SetInt(2)
+		sheet.Cell(2, 0).SetFormula("SUM(A1:A2)")
+	}
+	_, err := f.AddDefinedName("Rate", "Summary!$B$1")
+	c.Assert(err, IsNil)
+	summary := f.Sheet["Summary"]
+	summary.Cell(0, 1).SetFloat(0.2)
+	summary.Cell(1, 0).SetFormula("SUM(Jan:Mar!A3)")
+	summary.Cell(2, 0).SetFormula("A2*Rate")
+	summary.Cell(3, 0).SetFormula(`IF(A3>0,"Sheet1!A1",'Feb'!$A$1) + [1]Other!A1 + COUNT(C:C) + #REF!`)
+	return f
+}
+
+func (s *FormulaGraphSuite) TestPrecedents(c *C) {
+	g, err := makeGraphFile(c).FormulaGraph()
+	c.Assert(err, IsNil)
+	c.Assert(nodeNames(g.Nodes), DeepEquals, []string{"Jan!A3", "Feb!A3", "Mar!A3", "Summary!A2", "Summary!A3", "Summary!A4"})
+	c.Assert(g.Node("Jan", CellRef{Col: 0, Row: 2}).Precedents, DeepEquals, []CellRange{
+		{Sheet: "Jan", Start: CellRef{Col: 0, Row: 0}, End: CellRef{Col: 0, Row: 1}},
+	})
+	a3 := CellRef{Col: 0, Row: 2}
+	c.Assert(g.Node("Summary", CellRef{Col: 0, Row: 1}).Precedents, DeepEquals, []CellRange{
+		{Sheet: "Jan", Start: a3, End: a3},
+		{Sheet: "Feb", Start: a3, End: a3},
+		{Sheet: "Mar", Start: a3, End: a3},
+	})
+	b1 := CellRef{Col: 1, Row: 0, AbsCol: true, AbsRow: true}
+	c.Assert(g.Node("Summary", a3).Precedents, DeepEquals, []CellRange{
+		{Sheet: "Summary", Start: CellRef{Col: 0, Row: 1}, End: CellRef{Col: 0, Row: 1}},
+		{Sheet: "Summary", Start: b1, End: b1},
+	})
+	a1 := CellRef{Col: 0, Row: 0, AbsCol: true, AbsRow: true}
+	c.Assert(g.Node("Summary", CellRef{Col: 0, Row: 3}).Precedents, DeepEquals, []CellRange{
+		{Sheet: "Summary", Start: a3, End: a3},
+		{Sheet: "Feb", Start: a1, End: a1},
+		{Sheet: "Summary", Start: CellRef{Col: 2}, End: CellRef{Col: 2, Row: SheetRowLimit - 1}},
+	})
+	c.Assert(g.Node("Summary", CellRef{Col: 1, Row: 0}), IsNil)
+}
+
+func (s *FormulaGraphSuite) TestImpactAndOrder(c *C) {
+	f := makeGraphFile(c)
+	g, err := f.FormulaGraph()
+	c.Assert(err, IsNil)
+	c.Assert(nodeNames(g.Dependents("Feb", CellRef{Col: 0, Row: 0})), DeepEquals, []string{"Feb!A3", "Summary!A4"})
+	c.Assert(nodeNames(g.Impact("Feb", CellRef{Col: 0, Row: 0})), DeepEquals, []string{"Feb!A3", "Summary!A2", "Summary!A3", "Summary!A4"})
+	c.Assert(nodeNames(g.Impact("Summary", CellRef{Col: 1, Row: 0})), DeepEquals, []string{"Summary!A3", "Summary!A4"})
+	c.Assert(g.Impact("Summary", CellRef{Col: 5, Row: 5}), HasLen, 0)
+
+	order, err := g.Order()
+	c.Assert(err, IsNil)
+	c.Assert(nodeNames(order), DeepEquals, []string{"Jan!A3", "Feb!A3", "Mar!A3", "Summary!A2", "Summary!A3", "Summary!A4"})
+
+	// A circle through a defined name, and a formula downstream of
+	// it that isn't part of it.
+	f.Sheet["Jan"].Cell(0, 0).SetFormula("Summary!A3")
+	f.Sheet["Jan"].Cell(5, 5).SetFormula("F6")
+	g, err = f.FormulaGraph()
+	c.Assert(err, IsNil)
+	_, err = g.Order()
+	c.Assert(err, ErrorMatches, `circular reference between Jan!A1, Jan!A3, Jan!F6, Summary!A2, Summary!A3`)
+	c.Assert(err.(*CircularReferenceError).Cells, HasLen, 5)
+}
+
+func (s *FormulaGraphSuite) TestRecursiveName(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Data")
+	c.Assert(err, IsNil)
+	_, err = f.AddDefinedName("Loop", "Loop+B1")
+	c.Assert(err, IsNil)
+	local, err := sheet.AddDefinedName("Loop", "C1")
+	c.Assert(err, IsNil)
+	c.Assert(local.Sheet, Equals, "Data")
+	sheet.Cell(0, 0).SetFormula("loop")
+	other, err := f.AddSheet("Other")
+	c.Assert(err, IsNil)
+	other.Cell(0, 0).SetFormula("Loop*2")
+
+	g, err := f.FormulaGraph()
+	c.Assert(err, IsNil)
+	c1 := CellRef{Col: 2}
+	c.Assert(g.Node("Data", CellRef{}).Precedents, DeepEquals, []CellRange{{Sheet: "Data", Start: c1, End: c1}})
+	b1 := CellRef{Col: 1}
+	c.Assert(g.Node("Other", CellRef{}).Precedents, DeepEquals, []CellRange{{Sheet: "Other", Start: b1, End: b1}})
+}
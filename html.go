@@ -0,0 +1,386 @@
+package xlsx
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ToHTML writes the contents of the Sheet to w as an HTML table.
+// Merged cells become cells spanning several rows or columns, and the
+// fonts, fills and horizontal alignment of each cell are carried over
+// as inline CSS, so the table can be embedded directly in an email.
+func (s *Sheet) ToHTML(w io.Writer) error {
+	if err := s.Load(); err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	covered := make(map[[2]int]bool)
+	for r, row := range s.Rows {
+		if row == nil {
+			continue
+		}
+		for c, cell := range row.Cells {
+			if cell == nil || cell.HMerge == 0 && cell.VMerge == 0 {
+				continue
+			}
+			for dr := 0; dr <= cell.VMerge; dr++ {
+				for dc := 0; dc <= cell.HMerge; dc++ {
+					if dr != 0 || dc != 0 {
+						covered[[2]int{r + dr, c + dc}] = true
+					}
+				}
+			}
+		}
+	}
+
+	bw.WriteString(`<table`)
+	if s.View.RightToLeft {
+		// The first column is then at the right.
+		bw.WriteString(` dir="rtl"`)
+	}
+	bw.WriteString(` style="border-collapse:collapse">` + "\n")
+	for r, row := range s.Rows {
+		bw.WriteString("<tr")
+		if row != nil && row.isCustom && row.Height > 0 {
+			fmt.Fprintf(bw, ` style="height:%gpt"`, row.Height)
+		}
+		bw.WriteString(">")
+		if row != nil {
+			for c, cell := range row.Cells {
+				if covered[[2]int{r, c}] {
+					continue
+				}
+				if cell == nil {
+					bw.WriteString("<td></td>")
+					continue
+				}
+				bw.WriteString("<td")
+				if cell.HMerge > 0 {
+					fmt.Fprintf(bw, ` colspan="%d"`, cell.HMerge+1)
+				}
+				if cell.VMerge > 0 {
+					fmt.Fprintf(bw, ` rowspan="%d"`, cell.VMerge+1)
+				}
+				if css := cell.style.css(); css != "" {
+					fmt.Fprintf(bw, ` style="%s"`, html.EscapeString(css))
+				}
+				bw.WriteString(">")
+				value, err := cell.FormattedValue()
+				if err != nil {
+					value = cell.Value
+				}
+				text := html.EscapeString(value)
+				bw.WriteString(strings.Replace(text, "\n", "<br>", -1))
+				bw.WriteString("</td>")
+			}
+		}
+		bw.WriteString("</tr>\n")
+	}
+	bw.WriteString("</table>\n")
+	return bw.Flush()
+}
+
+// css returns the inline CSS describing the parts of the Style that
+// HTML can represent.
+func (style *Style) css() string {
+	if style == nil {
+		return ""
+	}
+	var rules []string
+	if style.Font.Bold {
+		rules = append(rules, "font-weight:bold")
+	}
+	if style.Font.Italic {
+		rules = append(rules, "font-style:italic")
+	}
+	if style.Font.Underline {
+		rules = append(rules, "text-decoration:underline")
+	}
+	if style.Font.Name != "" {
+		rules = append(rules, "font-family:"+style.Font.Name)
+	}
+	if style.Font.Size > 0 {
+		rules = append(rules, fmt.Sprintf("font-size:%dpt", style.Font.Size))
+	}
+	if color := cssColor(style.Font.Color); color != "" {
+		rules = append(rules, "color:"+color)
+	}
+	if style.Fill.PatternType == "solid" {
+		if color := cssColor(style.Fill.FgColor); color != "" {
+			rules = append(rules, "background-color:"+color)
+		}
+	}
+	switch style.Alignment.Horizontal {
+	case "left", "center", "right", "justify":
+		rules = append(rules, "text-align:"+string(style.Alignment.Horizontal))
+	case "centerContinuous":
+		rules = append(rules, "text-align:center")
+	case "distributed":
+		rules = append(rules, "text-align:justify", "text-align-last:justify")
+	}
+	switch style.Alignment.Vertical {
+	case "top", "bottom":
+		rules = append(rules, "vertical-align:"+string(style.Alignment.Vertical))
+	case "center", "justify", "distributed":
+		rules = append(rules, "vertical-align:middle")
+	}
+	return strings.Join(rules, ";")
+}
+
+// cssColor converts an ARGB color, as used in styles, to a CSS color.
+func cssColor(argb string) string {
+	switch len(argb) {
+	case 8:
+		return "#" + argb[2:]
+	case 6:
+		return "#" + argb
+	}
+	return ""
+}
+
+// xlsxColorFromCSS converts a CSS hex color to an ARGB color.
+func xlsxColorFromCSS(color string) string {
+	color = strings.TrimPrefix(strings.TrimSpace(color), "#")
+	if len(color) == 3 {
+		color = string([]byte{color[0], color[0], color[1], color[1], color[2], color[2]})
+	}
+	if len(color) != 6 {
+		return ""
+	}
+	if _, err := strconv.ParseUint(color, 16, 32); err != nil {
+		return ""
+	}
+	return "FF" + strings.ToUpper(color)
+}
+
+// FromHTML reads the first table found in r and appends its rows to
+// the Sheet.  This is a best effort importer: colspan and rowspan
+// become merged cells, th cells and b or strong elements become bold
+// text, and the inline CSS written by ToHTML is understood.  Values
+// that look like numbers are stored as numbers, everything else as
+// strings.
+func (s *Sheet) FromHTML(r io.Reader) error {
+	if err := s.Load(); err != nil {
+		return err
+	}
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	var (
+		inTable, done bool
+		rowIndex      = len(s.Rows) - 1
+		colIndex      int
+		cell          *Cell
+		text          strings.Builder
+		bold          int
+		occupied      = make(map[[2]int]bool)
+		startRow      = len(s.Rows)
+	)
+
+	finishCell := func() {
+		if cell == nil {
+			return
+		}
+		lines := strings.Split(text.String(), "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimSpace(line)
+		}
+		value := strings.Join(lines, "\n")
+		// ParseFloat also accepts "Inf", "NaN" and hexadecimal,
+		// none of which a table cell would mean as a number.
+		if f, err := strconv.ParseFloat(value, 64); err == nil && !strings.ContainsAny(value, "xXnNiI") {
+			cell.SetFloat(f)
+		} else {
+			cell.SetString(value)
+		}
+		cell = nil
+		text.Reset()
+	}
+
+	for !done {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			if !inTable {
+				inTable = name == "table"
+				for _, attr := range t.Attr {
+					if inTable && strings.ToLower(attr.Name.Local) == "dir" && strings.ToLower(attr.Value) == "rtl" {
+						s.View.RightToLeft = true
+					}
+				}
+				continue
+			}
+			switch name {
+			case "tr":
+				finishCell()
+				rowIndex++
+				colIndex = 0
+				s.Cell(rowIndex, 0)
+			case "td", "th":
+				finishCell()
+				if rowIndex < startRow {
+					// A cell outside of any row.
+					rowIndex = startRow
+					s.Cell(rowIndex, 0)
+				}
+				for occupied[[2]int{rowIndex, colIndex}] {
+					colIndex++
+				}
+				cell = s.Cell(rowIndex, colIndex)
+				colspan, rowspan := 1, 1
+				for _, attr := range t.Attr {
+					switch strings.ToLower(attr.Name.Local) {
+					case "colspan":
+						colspan, _ = strconv.Atoi(attr.Value)
+					case "rowspan":
+						rowspan, _ = strconv.Atoi(attr.Value)
+					case "style":
+						applyCSS(cell.GetStyle(), attr.Value)
+					}
+				}
+				if colspan < 1 {
+					colspan = 1
+				}
+				if rowspan < 1 {
+					rowspan = 1
+				}
+				if colspan > 1 || rowspan > 1 {
+					cell.Merge(colspan-1, rowspan-1)
+					for dr := 0; dr < rowspan; dr++ {
+						for dc := 0; dc < colspan; dc++ {
+							occupied[[2]int{rowIndex + dr, colIndex + dc}] = true
+						}
+					}
+				}
+				if name == "th" || bold > 0 {
+					cell.GetStyle().Font.Bold = true
+					cell.GetStyle().ApplyFont = true
+				}
+				colIndex += colspan
+			case "b", "strong":
+				bold++
+				if cell != nil {
+					cell.GetStyle().Font.Bold = true
+					cell.GetStyle().ApplyFont = true
+				}
+			case "br":
+				text.WriteString("\n")
+			}
+		case xml.EndElement:
+			if !inTable {
+				continue
+			}
+			switch strings.ToLower(t.Name.Local) {
+			case "td", "th":
+				finishCell()
+			case "b", "strong":
+				if bold > 0 {
+					bold--
+				}
+			case "table":
+				finishCell()
+				done = true
+			}
+		case xml.CharData:
+			if cell != nil {
+				text.WriteString(collapseSpace(string(t)))
+			}
+		}
+	}
+	finishCell()
+	return nil
+}
+
+// collapseSpace replaces every run of white space in s with a single
+// space, as a browser would when rendering it.
+func collapseSpace(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	if space {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+// applyCSS applies those inline CSS rules that have an equivalent in
+// a Style.
+func applyCSS(style *Style, css string) {
+	for _, rule := range strings.Split(css, ";") {
+		parts := strings.SplitN(rule, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		property := strings.ToLower(strings.TrimSpace(parts[0]))
+		value := strings.TrimSpace(parts[1])
+		switch property {
+		case "font-weight":
+			if value == "bold" || value == "700" || value == "800" || value == "900" {
+				style.Font.Bold = true
+				style.ApplyFont = true
+			}
+		case "font-style":
+			if value == "italic" {
+				style.Font.Italic = true
+				style.ApplyFont = true
+			}
+		case "text-decoration":
+			if strings.Contains(value, "underline") {
+				style.Font.Underline = true
+				style.ApplyFont = true
+			}
+		case "font-family":
+			style.Font.Name = strings.Trim(strings.SplitN(value, ",", 2)[0], `"' `)
+			style.ApplyFont = true
+		case "font-size":
+			if size, err := strconv.ParseFloat(strings.TrimSuffix(value, "pt"), 64); err == nil {
+				style.Font.Size = int(size)
+				style.ApplyFont = true
+			}
+		case "color":
+			if color := xlsxColorFromCSS(value); color != "" {
+				style.Font.Color = color
+				style.ApplyFont = true
+			}
+		case "background-color", "background":
+			if color := xlsxColorFromCSS(value); color != "" {
+				style.Fill.PatternType = "solid"
+				style.Fill.FgColor = color
+				style.ApplyFill = true
+			}
+		case "text-align":
+			style.Alignment.Horizontal = HorizontalAlignment(value)
+			style.ApplyAlignment = true
+		case "vertical-align":
+			if value == "middle" {
+				value = "center"
+			}
+			style.Alignment.Vertical = VerticalAlignment(value)
+			style.ApplyAlignment = true
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package xlsx
+
+import (
+	"bytes"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type HTMLSuite struct{}
+
+var _ = Suite(&HTMLSuite{})
+
+func (h *HTMLSuite) TestToHTML(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	row := sheet.AddRow()
+	title := row.AddCell()
+	title.SetString("Fish & Chips")
+	title.Merge(1, 0)
+	style := title.GetStyle()
+	style.Font.Bold = true
+	style.Font.Color = "FFFF0000"
+	style.Fill = *NewFill("solid", "FF00FF00", "FF000000")
+	style.Alignment.Horizontal = "center"
+	row.AddCell()
+	row = sheet.AddRow()
+	row.AddCell().SetInt(1)
+	row.AddCell().SetString("a<b")
+
+	var buf bytes.Buffer
+	c.Assert(sheet.ToHTML(&buf), IsNil)
+	output := buf.String()
+	c.Assert(strings.HasPrefix(output, "<table"), Equals, true)
+	c.Assert(strings.Count(output, "<tr>"), Equals, 2)
+	c.Assert(strings.Count(output, "<td"), Equals, 3)
+	c.Assert(strings.Contains(output, `colspan="2"`), Equals, true)
+	c.Assert(strings.Contains(output, "Fish &amp; Chips"), Equals, true)
+	c.Assert(strings.Contains(output, "a&lt;b"), Equals, true)
+	c.Assert(strings.Contains(output, "font-weight:bold"), Equals, true)
+	c.Assert(strings.Contains(output, "color:#FF0000"), Equals, true)
+	c.Assert(strings.Contains(output, "background-color:#00FF00"), Equals, true)
+	c.Assert(strings.Contains(output, "text-align:center"), Equals, true)
+}
+
+func (h *HTMLSuite) TestRightToLeft(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1", SheetRightToLeft())
+	sheet.Cell(0, 0).SetString("שלום")
+	var buf bytes.Buffer
+	c.Assert(sheet.ToHTML(&buf), IsNil)
+	c.Assert(strings.HasPrefix(buf.String(), `<table dir="rtl" style="border-collapse:collapse">`), Equals, true)
+
+	other, _ := file.AddSheet("Sheet2")
+	c.Assert(other.FromHTML(&buf), IsNil)
+	c.Assert(other.View.RightToLeft, Equals, true)
+	c.Assert(other.Cell(0, 0).Value, Equals, "שלום")
+}
+
+func (h *HTMLSuite) TestFromHTML(c *C) {
+	input := `<html><body><p>Ignored</p>
+<table>
+  <tr><th colspan="2">Name &amp; Age</th><th rowspan="2">Notes</th></tr>
+  <tr><td style="color: #f00; text-align: right">Bob</td><td>42</td></tr>
+  <tr><td><b>Alice</b> <i>Smith</i></td><td>NaN</td><td>line <br> break</td></tr>
+</table>
+<table><tr><td>Second table</td></tr></table>
+</body></html>`
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	c.Assert(sheet.FromHTML(strings.NewReader(input)), IsNil)
+	c.Assert(sheet.Rows, HasLen, 3)
+
+	header := sheet.Cell(0, 0)
+	c.Assert(header.Value, Equals, "Name & Age")
+	c.Assert(header.HMerge, Equals, 1)
+	c.Assert(header.GetStyle().Font.Bold, Equals, true)
+	notes := sheet.Cell(0, 2)
+	c.Assert(notes.Value, Equals, "Notes")
+	c.Assert(notes.VMerge, Equals, 1)
+
+	bob := sheet.Cell(1, 0)
+	c.Assert(bob.Value, Equals, "Bob")
+	c.Assert(bob.GetStyle().Font.Color, Equals, "FFFF0000")
+	c.Assert(bob.GetStyle().Alignment.Horizontal, Equals, AlignRight)
+	age := sheet.Cell(1, 1)
+	c.Assert(age.Type(), Equals, CellTypeGeneral)
+	v, err := age.Int()
+	c.Assert(err, IsNil)
+	c.Assert(v, Equals, 42)
+
+	alice := sheet.Cell(2, 0)
+	c.Assert(alice.Value, Equals, "Alice Smith")
+	c.Assert(alice.GetStyle().Font.Bold, Equals, true)
+	c.Assert(sheet.Cell(2, 1).Type(), Equals, CellTypeString)
+	c.Assert(sheet.Cell(2, 2).Value, Equals, "line\nbreak")
+}
+
+func (h *HTMLSuite) TestHTMLRoundTrip(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	row := sheet.AddRow()
+	cell := row.AddCell()
+	cell.SetString("Merged")
+	cell.Merge(0, 1)
+	cell.GetStyle().Font.Italic = true
+	row.AddCell().SetString("B1")
+	row = sheet.AddRow()
+	row.AddCell()
+	row.AddCell().SetString("B2")
+
+	var buf bytes.Buffer
+	c.Assert(sheet.ToHTML(&buf), IsNil)
+
+	copied, _ := file.AddSheet("Sheet2")
+	c.Assert(copied.FromHTML(&buf), IsNil)
+	c.Assert(copied.Rows, HasLen, 2)
+	c.Assert(copied.Cell(0, 0).Value, Equals, "Merged")
+	c.Assert(copied.Cell(0, 0).VMerge, Equals, 1)
+	c.Assert(copied.Cell(0, 0).GetStyle().Font.Italic, Equals, true)
+	c.Assert(copied.Cell(0, 1).Value, Equals, "B1")
+	c.Assert(copied.Cell(1, 1).Value, Equals, "B2")
+}
@@ -0,0 +1,206 @@
+package xlsx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// relTypeHyperlink is the type of the relationship of a worksheet to
+// the web page or file one of its Hyperlinks is to.
+const relTypeHyperlink = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink"
+
+// Hyperlink is a link from a cell to a place in the workbook, or to a
+// web page or other file, which Excel goes to when the cell is
+// clicked.
+type Hyperlink struct {
+	// Ref is the cell, or range, the link is on, such as "B2".
+	Ref string
+	// URL is the address of the web page or file linked to, such
+	// as "https://example.com/" or "Report.docx".  If it is empty,
+	// the link is to a place in the workbook.
+	URL string
+	// Location is the place linked to, such as "'Q1 Sales'!A1" or a
+	// defined name, or the place within the page or file at URL.
+	Location string
+	// Tooltip is the text shown when the mouse is over the cell.
+	Tooltip string
+	// Display is the text Excel shows for the link, which it keeps
+	// in step with the cell's value.
+	Display string
+}
+
+// AddHyperlink puts a link to the cell ref of target, such as "A1", on
+// the cell at ref, such as "B2", without changing what the cell holds.
+func (s *Sheet) AddHyperlink(ref string, target *Sheet, targetRef string) {
+	s.Hyperlinks = append(s.Hyperlinks, Hyperlink{Ref: ref, Location: quoteSheetName(target.Name) + "!" + targetRef})
+}
+
+// hyperlinkStyle gives a cell Excel's built in Hyperlink cell style,
+// defining it for the File, looking as it does in Excel, unless the
+// File has it already.
+func hyperlinkStyle(cell *Cell) error {
+	f := cell.file()
+	if _, ok := f.NamedStyleIndex("Hyperlink"); !ok {
+		style := *f.newStyle()
+		style.Font.Color = "FF0563C1"
+		style.Font.Underline = true
+		style.ApplyFont = true
+		if _, err := f.AddNamedStyle("Hyperlink", style, ""); err != nil {
+			return err
+		}
+	}
+	return cell.SetNamedStyle("Hyperlink")
+}
+
+// xlsxHyperlinks directly maps the hyperlinks element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxHyperlinks struct {
+	Hyperlink []xlsxHyperlink `xml:"hyperlink"`
+}
+
+// xlsxHyperlink directly maps the hyperlink element.  Links out of the
+// workbook have an r:id, naming the relationship giving their target.
+// RID is the id a link is read with, and Id that it is written with.
+type xlsxHyperlink struct {
+	Ref      string `xml:"ref,attr"`
+	RID      string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr,omitempty"`
+	Id       string `xml:"r:id,attr,omitempty"`
+	Location string `xml:"location,attr,omitempty"`
+	Tooltip  string `xml:"tooltip,attr,omitempty"`
+	Display  string `xml:"display,attr,omitempty"`
+}
+
+// readHyperlinks returns the Hyperlinks of a hyperlinks element, which
+// may be missing, finding the targets of links out of the workbook
+// among the relationships of the worksheet, rels.  Links to nothing
+// are left out.
+func readHyperlinks(xLinks *xlsxHyperlinks, rels map[string]xlsxWorksheetRelationship) []Hyperlink {
+	if xLinks == nil {
+		return nil
+	}
+	var links []Hyperlink
+	for _, x := range xLinks.Hyperlink {
+		link := Hyperlink{Ref: x.Ref, Location: x.Location, Tooltip: x.Tooltip, Display: x.Display}
+		if x.RID != "" {
+			if rel, ok := rels[x.RID]; ok && rel.Type == relTypeHyperlink {
+				link.URL = rel.Target
+			}
+		}
+		if link.URL == "" && link.Location == "" {
+			continue
+		}
+		links = append(links, link)
+	}
+	return links
+}
+
+// makeHyperlinks returns the hyperlinks element of a sheet's
+// Hyperlinks, or nil if there are none.  The links out of the workbook
+// are given ids by linkHyperlinks.
+func makeHyperlinks(links []Hyperlink) *xlsxHyperlinks {
+	if len(links) == 0 {
+		return nil
+	}
+	xLinks := &xlsxHyperlinks{}
+	for _, link := range links {
+		xLinks.Hyperlink = append(xLinks.Hyperlink, xlsxHyperlink{Ref: link.Ref, Location: link.Location, Tooltip: link.Tooltip, Display: link.Display})
+	}
+	return xLinks
+}
+
+// linkHyperlinks relates the worksheet written for the links, whose
+// hyperlinks element is xLinks, to the pages and files its links out of
+// the workbook are to, in rels.
+func linkHyperlinks(links []Hyperlink, xLinks *xlsxHyperlinks, rels *partRelationships) {
+	for i, link := range links {
+		if link.URL != "" {
+			xLinks.Hyperlink[i].Id = rels.addExternal(relTypeHyperlink, link.URL)
+		}
+	}
+}
+
+// ContentsOptions controls the sheet File.AddContents makes.
+type ContentsOptions struct {
+	// Title is the heading of the contents sheet, written above the
+	// links.  It defaults to "Contents".
+	Title string
+	// BackLink is the cell of every other sheet, such as "H1", that
+	// is given a link back to the contents sheet, reading BackText.
+	// If it is empty, no links back are made.
+	BackLink string
+	// BackText is the text of the links back.  It defaults to the
+	// Title.
+	BackText string
+}
+
+// AddContents adds a sheet with the given name at the front of the
+// File, listing every visible sheet with a link to it, and makes it
+// the active sheet, for report packs with dozens of sheets.  Sheets
+// added afterwards aren't listed.  If the options give a BackLink
+// cell, that cell of each sheet listed is given a link back; an error
+// is returned, and nothing is changed, if any of those cells already
+// holds something.
+func (f *File) AddContents(name string, options ContentsOptions) (*Sheet, error) {
+	if options.Title == "" {
+		options.Title = "Contents"
+	}
+	if options.BackText == "" {
+		options.BackText = options.Title
+	}
+	var listed []*Sheet
+	for _, sheet := range f.Sheets {
+		if !sheet.Hidden && !sheet.VeryHidden {
+			listed = append(listed, sheet)
+		}
+	}
+	var back CellRef
+	if options.BackLink != "" {
+		var err error
+		if back, err = ParseCellRef(options.BackLink); err != nil {
+			return nil, err
+		}
+		for _, sheet := range listed {
+			if err := sheet.Load(); err != nil {
+				return nil, err
+			}
+			if cell := existingCell(sheet, back.Row, back.Col); cell != nil && (cell.Value != "" || cell.formula != "") {
+				return nil, fmt.Errorf("sheet '%s' already has something in %s, where the link back to the contents was to go", sheet.Name, options.BackLink)
+			}
+		}
+	}
+
+	contents, err := f.AddSheet(name, SheetAt(0))
+	if err != nil {
+		return nil, err
+	}
+	title := contents.Cell(0, 0)
+	title.SetString(options.Title)
+	title.GetStyle().Font.Bold = true
+	width := len([]rune(options.Title))
+	for i, sheet := range listed {
+		cell := contents.Cell(i+2, 0)
+		cell.SetString(sheet.Name)
+		if err := hyperlinkStyle(cell); err != nil {
+			return nil, err
+		}
+		contents.AddHyperlink(getCellIDStringFromCoords(0, i+2), sheet, "A1")
+		if n := len([]rune(sheet.Name)); n > width {
+			width = n
+		}
+		if options.BackLink != "" {
+			cell := sheet.Cell(back.Row, back.Col)
+			cell.SetString(options.BackText)
+			if err := hyperlinkStyle(cell); err != nil {
+				return nil, err
+			}
+			sheet.AddHyperlink(strings.Replace(options.BackLink, "$", "", -1), contents, "A1")
+		}
+	}
+	if err := contents.SetColWidth(0, 0, float64(width+2)); err != nil {
+		return nil, err
+	}
+	if err := f.SetActiveSheet(name); err != nil {
+		return nil, err
+	}
+	return contents, nil
+}
@@ -0,0 +1,88 @@
+package xlsx
+
+import (
+	"bytes"
+
+	. "gopkg.in/check.v1"
+)
+
+type HyperlinkSuite struct{}
+
+var _ = Suite(&HyperlinkSuite{})
+
+func (s *HyperlinkSuite) TestRoundTrip(c *C) {
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Data" sheetId="1" r:id="rId1"/><sheet name="Q1 Sales" sheetId="2" r:id="rId2"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet2.xml"/>` +
+			`</Relationships>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheetData><row r="1"><c r="A1" t="inlineStr"><is><t>Go</t></is></c></row></sheetData>` +
+			`<hyperlinks><hyperlink ref="A1" location="'Q1 Sales'!B2" tooltip="Sales" display="Go"/><hyperlink ref="A2" r:id="rId9" location="top"/><hyperlink ref="A3" r:id="rId8"/></hyperlinks></worksheet>`,
+		"xl/worksheets/_rels/sheet1.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId9" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="https://example.com/" TargetMode="External"/>` +
+			`</Relationships>`,
+		"xl/worksheets/sheet2.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData/></worksheet>`,
+	}
+	f, err := readZipReader(makeZipReader(c, parts), nil)
+	c.Assert(err, IsNil)
+	// The link whose target is missing is left out.
+	c.Assert(f.Sheets[0].Hyperlinks, DeepEquals, []Hyperlink{
+		{Ref: "A1", Location: "'Q1 Sales'!B2", Tooltip: "Sales", Display: "Go"},
+		{Ref: "A2", URL: "https://example.com/", Location: "top"},
+	})
+
+	f.Sheets[1].AddHyperlink("C3", f.Sheets[0], "A1")
+	written, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(written["xl/worksheets/sheet1.xml"], Matches, `(?s).*</sheetData><hyperlinks><hyperlink ref="A1" location="&#39;Q1 Sales&#39;!B2" tooltip="Sales" display="Go"></hyperlink><hyperlink ref="A2" r:id="rId1" location="top"></hyperlink></hyperlinks><printOptions.*`)
+	c.Assert(written["xl/worksheets/_rels/sheet1.xml.rels"], Matches, `(?s).*<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="https://example.com/" TargetMode="External"></Relationship>.*`)
+	c.Assert(written["xl/worksheets/sheet2.xml"], Matches, `(?s).*<hyperlinks><hyperlink ref="C3" location="Data!A1"></hyperlink></hyperlinks>.*`)
+}
+
+func (s *HyperlinkSuite) TestAddContents(c *C) {
+	f := NewFile()
+	for _, name := range []string{"Summary", "Q1 Sales", "Workings"} {
+		_, err := f.AddSheet(name)
+		c.Assert(err, IsNil)
+	}
+	f.Sheet["Workings"].Hidden = true
+	f.Sheet["Summary"].Cell(0, 0).SetString("Summary of the year")
+
+	contents, err := f.AddContents("Contents", ContentsOptions{BackLink: "$H$1"})
+	c.Assert(err, IsNil)
+	c.Assert(f.Sheets[0], Equals, contents)
+	c.Assert(f.ActiveSheet(), Equals, contents)
+	c.Assert(contents.Cell(0, 0).Value, Equals, "Contents")
+	c.Assert(contents.Cell(0, 0).GetStyle().Font.Bold, Equals, true)
+	c.Assert(contents.Cell(2, 0).Value, Equals, "Summary")
+	c.Assert(contents.Cell(3, 0).Value, Equals, "Q1 Sales")
+	c.Assert(contents.Cell(3, 0).GetStyle().Font.Underline, Equals, true)
+	hyperlink, _ := f.NamedStyleIndex("Hyperlink")
+	c.Assert(*contents.Cell(3, 0).GetStyle().NamedStyleIndex, Equals, hyperlink)
+	c.Assert(contents.Cell(4, 0).Value, Equals, "")
+	c.Assert(contents.Hyperlinks, DeepEquals, []Hyperlink{
+		{Ref: "A3", Location: "Summary!A1"},
+		{Ref: "A4", Location: "'Q1 Sales'!A1"},
+	})
+	c.Assert(contents.Cols.FindCol(0).Width, Equals, 10.0)
+	c.Assert(f.Sheet["Q1 Sales"].Cell(0, 7).Value, Equals, "Contents")
+	c.Assert(f.Sheet["Q1 Sales"].Hyperlinks, DeepEquals, []Hyperlink{{Ref: "H1", Location: "Contents!A1"}})
+	c.Assert(f.Sheet["Workings"].Hyperlinks, HasLen, 0)
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/styles.xml"], Matches, `(?s).*<cellStyle builtinId="8" name="Hyperlink" xfId="1"></cellStyle>.*`)
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	read, err := OpenBinary(buf.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(read.Sheets[0].Hyperlinks, DeepEquals, contents.Hyperlinks)
+
+	// The cells for links back must be free.
+	_, err = f.AddContents("Index", ContentsOptions{Title: "Index", BackLink: "A1"})
+	c.Assert(err, ErrorMatches, "sheet 'Contents' already has something in A1, where the link back to the contents was to go")
+	c.Assert(f.Sheet["Index"], IsNil)
+	_, err = f.AddContents("Contents", ContentsOptions{})
+	c.Assert(err, ErrorMatches, "duplicate sheet name 'Contents'.")
+}
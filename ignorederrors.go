@@ -0,0 +1,112 @@
+package xlsx
+
+// IgnoredError is a range of cells whose errors of some kinds Excel
+// doesn't flag with a green triangle, as it does after a user chooses
+// to ignore them, such as the numbers stored as text in a column of
+// IDs kept as text on purpose.
+type IgnoredError struct {
+	// Ref is the range, or space separated ranges, such as "A2:A100
+	// C2:C100".
+	Ref string
+	// NumberStoredAsText ignores numbers that are stored as text.
+	NumberStoredAsText bool
+	// EvalError ignores formulas that evaluate to an error.
+	EvalError bool
+	// TwoDigitTextYear ignores dates in text with two digit years.
+	TwoDigitTextYear bool
+	// Formula ignores formulas that differ from those of the cells
+	// around them.
+	Formula bool
+	// FormulaRange ignores formulas that leave out cells next to the
+	// range they refer to.
+	FormulaRange bool
+	// UnlockedFormula ignores formulas in cells that aren't locked.
+	UnlockedFormula bool
+	// EmptyCellReference ignores formulas that refer to empty cells.
+	EmptyCellReference bool
+	// ListDataValidation ignores values that the cell's list data
+	// validation doesn't allow.
+	ListDataValidation bool
+	// CalculatedColumn ignores formulas that differ from the rest of
+	// their table's calculated column.
+	CalculatedColumn bool
+}
+
+// IgnoreNumbersStoredAsText stops Excel flagging the numbers stored as
+// text in the range ref, such as "A2:A100", of the Sheet.
+func (s *Sheet) IgnoreNumbersStoredAsText(ref string) {
+	s.IgnoredErrors = append(s.IgnoredErrors, IgnoredError{Ref: ref, NumberStoredAsText: true})
+}
+
+// xlsxIgnoredErrors directly maps the ignoredErrors element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxIgnoredErrors struct {
+	IgnoredError []xlsxIgnoredError `xml:"ignoredError"`
+}
+
+// xlsxIgnoredError directly maps the ignoredError element.
+type xlsxIgnoredError struct {
+	SQRef              string `xml:"sqref,attr"`
+	EvalError          bool   `xml:"evalError,attr,omitempty"`
+	TwoDigitTextYear   bool   `xml:"twoDigitTextYear,attr,omitempty"`
+	NumberStoredAsText bool   `xml:"numberStoredAsText,attr,omitempty"`
+	Formula            bool   `xml:"formula,attr,omitempty"`
+	FormulaRange       bool   `xml:"formulaRange,attr,omitempty"`
+	UnlockedFormula    bool   `xml:"unlockedFormula,attr,omitempty"`
+	EmptyCellReference bool   `xml:"emptyCellReference,attr,omitempty"`
+	ListDataValidation bool   `xml:"listDataValidation,attr,omitempty"`
+	CalculatedColumn   bool   `xml:"calculatedColumn,attr,omitempty"`
+}
+
+// readIgnoredErrors returns the IgnoredErrors of an ignoredErrors
+// element, which may be missing.
+func readIgnoredErrors(xIgnored *xlsxIgnoredErrors) []IgnoredError {
+	if xIgnored == nil {
+		return nil
+	}
+	var ignored []IgnoredError
+	for _, x := range xIgnored.IgnoredError {
+		ignored = append(ignored, IgnoredError{
+			Ref:                x.SQRef,
+			NumberStoredAsText: x.NumberStoredAsText,
+			EvalError:          x.EvalError,
+			TwoDigitTextYear:   x.TwoDigitTextYear,
+			Formula:            x.Formula,
+			FormulaRange:       x.FormulaRange,
+			UnlockedFormula:    x.UnlockedFormula,
+			EmptyCellReference: x.EmptyCellReference,
+			ListDataValidation: x.ListDataValidation,
+			CalculatedColumn:   x.CalculatedColumn,
+		})
+	}
+	return ignored
+}
+
+// makeIgnoredErrors returns the ignoredErrors element of a sheet's
+// IgnoredErrors, or nil if there are none.  Ranges with no range, or
+// no kind of error to ignore, are left out, as Excel rejects them.
+func makeIgnoredErrors(ignored []IgnoredError) *xlsxIgnoredErrors {
+	var xIgnored xlsxIgnoredErrors
+	for _, e := range ignored {
+		x := xlsxIgnoredError{
+			SQRef:              e.Ref,
+			EvalError:          e.EvalError,
+			TwoDigitTextYear:   e.TwoDigitTextYear,
+			NumberStoredAsText: e.NumberStoredAsText,
+			Formula:            e.Formula,
+			FormulaRange:       e.FormulaRange,
+			UnlockedFormula:    e.UnlockedFormula,
+			EmptyCellReference: e.EmptyCellReference,
+			ListDataValidation: e.ListDataValidation,
+			CalculatedColumn:   e.CalculatedColumn,
+		}
+		if x.SQRef == "" || x == (xlsxIgnoredError{SQRef: x.SQRef}) {
+			continue
+		}
+		xIgnored.IgnoredError = append(xIgnored.IgnoredError, x)
+	}
+	if len(xIgnored.IgnoredError) == 0 {
+		return nil
+	}
+	return &xIgnored
+}
@@ -0,0 +1,47 @@
+package xlsx
+
+import (
+	"bytes"
+
+	. "gopkg.in/check.v1"
+)
+
+type IgnoredErrorsSuite struct{}
+
+var _ = Suite(&IgnoredErrorsSuite{})
+
+func (s *IgnoredErrorsSuite) TestRoundTrip(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("IDs")
+	c.Assert(err, IsNil)
+	sheet.Cell(0, 0).SetString("00123")
+	sheet.IgnoreNumbersStoredAsText("A1:A100")
+	sheet.IgnoredErrors = append(sheet.IgnoredErrors,
+		IgnoredError{Ref: "C1 E1:E5", Formula: true, EmptyCellReference: true},
+		// Nothing to ignore, so it isn't written.
+		IgnoredError{Ref: "F1"})
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/worksheets/sheet1.xml"], Matches, `(?s).*</headerFooter><ignoredErrors><ignoredError sqref="A1:A100" numberStoredAsText="true"></ignoredError><ignoredError sqref="C1 E1:E5" formula="true" emptyCellReference="true"></ignoredError></ignoredErrors><drawing .*`)
+
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	written, err := OpenBinary(buf.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(written.Sheets[0].IgnoredErrors, DeepEquals, []IgnoredError{
+		{Ref: "A1:A100", NumberStoredAsText: true},
+		{Ref: "C1 E1:E5", Formula: true, EmptyCellReference: true},
+	})
+
+	copied, err := written.AddSheet("Copy", SheetFrom(written.Sheets[0]))
+	c.Assert(err, IsNil)
+	copied.IgnoredErrors[0].Ref = "B1"
+	c.Assert(written.Sheets[0].IgnoredErrors[0].Ref, Equals, "A1:A100")
+}
+
+func (s *IgnoredErrorsSuite) TestNone(c *C) {
+	c.Assert(makeIgnoredErrors(nil), IsNil)
+	c.Assert(makeIgnoredErrors([]IgnoredError{{NumberStoredAsText: true}}), IsNil)
+	c.Assert(readIgnoredErrors(nil), IsNil)
+}
@@ -0,0 +1,230 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"image"
+	"image/png"
+	"strconv"
+	"strings"
+)
+
+// The image package can only decode the formats registered with it,
+// which by default doesn't include BMP, TIFF, WebP or SVG.  As all a
+// drawing needs of an image is its size, the headers of those formats
+// are read here instead.
+
+var errImageHeader = errors.New("the image's header is damaged")
+
+// decodeImageHeader works out the type and size of an image in one of
+// the formats the image package doesn't know about.
+func decodeImageHeader(data []byte) (ImageType, image.Config, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("BM")):
+		config, err := decodeBMPHeader(data)
+		return IMAGE_TYPE_BMP, config, err
+	case bytes.HasPrefix(data, []byte("II*\x00")), bytes.HasPrefix(data, []byte("MM\x00*")):
+		config, err := decodeTIFFHeader(data)
+		return IMAGE_TYPE_TIFF, config, err
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		config, err := decodeWebPHeader(data)
+		return IMAGE_TYPE_WEBP, config, err
+	case isSVG(data):
+		config, err := decodeSVGHeader(data)
+		return IMAGE_TYPE_SVG, config, err
+	}
+	return 0, image.Config{}, image.ErrFormat
+}
+
+func decodeBMPHeader(data []byte) (image.Config, error) {
+	if len(data) < 26 {
+		return image.Config{}, errImageHeader
+	}
+	if binary.LittleEndian.Uint32(data[14:]) == 12 {
+		// An OS/2 BITMAPCOREHEADER, with 16 bit sizes.
+		return image.Config{
+			Width:  int(binary.LittleEndian.Uint16(data[18:])),
+			Height: int(binary.LittleEndian.Uint16(data[20:])),
+		}, nil
+	}
+	width := int(int32(binary.LittleEndian.Uint32(data[18:])))
+	height := int(int32(binary.LittleEndian.Uint32(data[22:])))
+	if height < 0 {
+		// Rows stored from the top down.
+		height = -height
+	}
+	return image.Config{Width: width, Height: height}, nil
+}
+
+func decodeTIFFHeader(data []byte) (image.Config, error) {
+	var order binary.ByteOrder = binary.LittleEndian
+	if data[0] == 'M' {
+		order = binary.BigEndian
+	}
+	if len(data) < 8 {
+		return image.Config{}, errImageHeader
+	}
+	ifd := int(order.Uint32(data[4:]))
+	if ifd+2 > len(data) || ifd < 0 {
+		return image.Config{}, errImageHeader
+	}
+	var config image.Config
+	entries := int(order.Uint16(data[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(data) {
+			return image.Config{}, errImageHeader
+		}
+		var value int
+		switch order.Uint16(data[entry+2:]) {
+		case 3: // SHORT
+			value = int(order.Uint16(data[entry+8:]))
+		case 4: // LONG
+			value = int(order.Uint32(data[entry+8:]))
+		default:
+			continue
+		}
+		switch order.Uint16(data[entry:]) {
+		case 256:
+			config.Width = value
+		case 257:
+			config.Height = value
+		}
+	}
+	if config.Width == 0 || config.Height == 0 {
+		return image.Config{}, errImageHeader
+	}
+	return config, nil
+}
+
+func decodeWebPHeader(data []byte) (image.Config, error) {
+	if len(data) < 30 {
+		return image.Config{}, errImageHeader
+	}
+	switch string(data[12:16]) {
+	case "VP8 ":
+		// A lossy image, whose key frame starts with 9d 01 2a.
+		if !bytes.Equal(data[23:26], []byte{0x9d, 0x01, 0x2a}) {
+			return image.Config{}, errImageHeader
+		}
+		return image.Config{
+			Width:  int(binary.LittleEndian.Uint16(data[26:]) & 0x3fff),
+			Height: int(binary.LittleEndian.Uint16(data[28:]) & 0x3fff),
+		}, nil
+	case "VP8L":
+		// A lossless image, with 14 bit sizes less one.
+		if data[20] != 0x2f {
+			return image.Config{}, errImageHeader
+		}
+		bits := binary.LittleEndian.Uint32(data[21:])
+		return image.Config{
+			Width:  int(bits&0x3fff) + 1,
+			Height: int(bits>>14&0x3fff) + 1,
+		}, nil
+	case "VP8X":
+		// An extended image, with 24 bit canvas sizes less one.
+		return image.Config{
+			Width:  int(uint32(data[24])|uint32(data[25])<<8|uint32(data[26])<<16) + 1,
+			Height: int(uint32(data[27])|uint32(data[28])<<8|uint32(data[29])<<16) + 1,
+		}, nil
+	}
+	return image.Config{}, errImageHeader
+}
+
+// isSVG reports whether the data is XML whose root element is svg.
+func isSVG(data []byte) bool {
+	start, err := svgRoot(data)
+	return err == nil && start.Name.Local == "svg"
+}
+
+func svgRoot(data []byte) (xml.StartElement, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start, nil
+		}
+	}
+}
+
+// decodeSVGHeader works out the size in pixels of an SVG image from
+// the width and height of its root element, or from its viewBox if
+// they aren't given in absolute units.
+func decodeSVGHeader(data []byte) (image.Config, error) {
+	start, err := svgRoot(data)
+	if err != nil {
+		return image.Config{}, err
+	}
+	var width, height, viewBox string
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "width":
+			width = attr.Value
+		case "height":
+			height = attr.Value
+		case "viewBox":
+			viewBox = attr.Value
+		}
+	}
+	w, wOK := svgLength(width)
+	h, hOK := svgLength(height)
+	if wOK && hOK {
+		return image.Config{Width: w, Height: h}, nil
+	}
+	box := strings.FieldsFunc(viewBox, func(r rune) bool { return r == ',' || r == ' ' })
+	if len(box) == 4 {
+		bw, wErr := strconv.ParseFloat(box[2], 64)
+		bh, hErr := strconv.ParseFloat(box[3], 64)
+		if wErr == nil && hErr == nil && bw > 0 && bh > 0 {
+			// Keep the viewBox's aspect ratio if only one
+			// size is known.
+			switch {
+			case wOK:
+				return image.Config{Width: w, Height: int(float64(w)*bh/bw + 0.5)}, nil
+			case hOK:
+				return image.Config{Width: int(float64(h)*bw/bh + 0.5), Height: h}, nil
+			}
+			return image.Config{Width: int(bw + 0.5), Height: int(bh + 0.5)}, nil
+		}
+	}
+	return image.Config{}, errors.New("the SVG image doesn't give its size")
+}
+
+// svgLength converts an SVG length to pixels at 96 DPI.  Lengths that
+// are relative, such as percentages, can't be converted.
+func svgLength(length string) (int, bool) {
+	length = strings.TrimSpace(length)
+	pixelsPerUnit := map[string]float64{
+		"px": 1, "pt": 96.0 / 72, "pc": 16, "in": 96, "cm": 96 / 2.54, "mm": 96 / 25.4,
+	}
+	scale := 1.0
+	for unit, pixels := range pixelsPerUnit {
+		if strings.HasSuffix(length, unit) {
+			length = strings.TrimSuffix(length, unit)
+			scale = pixels
+			break
+		}
+	}
+	value, err := strconv.ParseFloat(length, 64)
+	if err != nil || value <= 0 {
+		return 0, false
+	}
+	return int(value*scale + 0.5), true
+}
+
+// blankPNG returns a transparent PNG of the given size, to stand in
+// for an SVG image in applications that can't show it.
+func blankPNG(width, height int) []byte {
+	if width <= 0 || height <= 0 {
+		width, height = 1, 1
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, width, height)))
+	return buf.Bytes()
+}
@@ -0,0 +1,105 @@
+package xlsx
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// LazySheets makes opening a File read only the list of its sheets,
+// leaving each worksheet to be parsed when its Sheet is first used.
+// This saves time and memory when only some of the sheets of a large
+// workbook are needed.
+//
+// The Sheet methods load the Sheet as needed, as does writing the
+// File, but code reading the fields of a Sheet, such as Rows, must
+// call Sheet.Load first.  The file stays open until File.Close is
+// called.
+func LazySheets() FileOption {
+	return func(f *File) {
+		f.lazySheets = true
+	}
+}
+
+// lazySheet records where to read a lazily loaded Sheet from.
+type lazySheet struct {
+	mu          sync.Mutex
+	loaded      bool
+	rsheet      xlsxSheet
+	sheetXMLMap map[string]string
+}
+
+// Load parses the worksheet of a Sheet whose File was opened with
+// LazySheets, if it hasn't been already.  It does nothing for other
+// Sheets.
+func (s *Sheet) Load() error {
+	l := s.lazy
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.loaded {
+		return nil
+	}
+	defer s.File.stats.phase("load")()
+	if err := loadSheet(s, l.rsheet, l.sheetXMLMap); err != nil {
+		return fmt.Errorf("can't load sheet '%s': %s", s.Name, err)
+	}
+	l.loaded = true
+	return nil
+}
+
+// Unload releases the rows, columns and views of a Sheet whose File
+// was opened with LazySheets, so that the memory they use can be
+// reclaimed.  They are parsed again when the Sheet is next used, and
+// so any changes made to them are lost.
+func (s *Sheet) Unload() error {
+	l := s.lazy
+	if l == nil {
+		return fmt.Errorf("sheet '%s' was not opened with LazySheets, and can't be unloaded", s.Name)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s.Rows = nil
+	s.Cols = nil
+	s.MaxRow = 0
+	s.MaxCol = 0
+	s.SheetViews = nil
+	s.Drawings = nil
+	s.Shapes = nil
+	s.Background = nil
+	s.CustomViews = nil
+	s.oleObjects = nil
+	l.loaded = false
+	return nil
+}
+
+// ensureLoaded loads the Sheet for methods that can't return an
+// error.  If it can't be loaded a warning is recorded in the File and
+// the Sheet is left empty, and treated as loaded so that what the
+// caller adds to it isn't later replaced.
+func (s *Sheet) ensureLoaded() {
+	if err := s.Load(); err != nil {
+		s.lazy.mu.Lock()
+		s.lazy.loaded = true
+		s.lazy.mu.Unlock()
+		s.File.warn(err.Error())
+	}
+}
+
+// Close closes the file a File opened with LazySheets is read from.
+// Sheets that haven't been loaded can't be loaded once it is closed.
+// Close does nothing for other Files.
+func (f *File) Close() error {
+	f.mu.Lock()
+	closer := f.closer
+	f.closer = nil
+	f.mu.Unlock()
+	if closer == nil {
+		return nil
+	}
+	return closer.Close()
+}
+
+var _ io.Closer = (*File)(nil)
@@ -0,0 +1,71 @@
+package xlsx
+
+import (
+	"bytes"
+
+	. "gopkg.in/check.v1"
+)
+
+type LazySuite struct{}
+
+var _ = Suite(&LazySuite{})
+
+func (l *LazySuite) TestLazySheets(c *C) {
+	expected, err := OpenFile("./testdocs/empty_rows.xlsx")
+	c.Assert(err, IsNil)
+
+	file, err := OpenFileWithOptions("./testdocs/empty_rows.xlsx", LazySheets())
+	c.Assert(err, IsNil)
+	defer file.Close()
+	c.Assert(file.Sheets, HasLen, 3)
+	for i, sheet := range file.Sheets {
+		c.Assert(sheet.Name, Equals, expected.Sheets[i].Name)
+		c.Assert(sheet.Rows, IsNil)
+		c.Assert(file.Sheet[sheet.Name], Equals, sheet)
+	}
+
+	// Using a Sheet loads it, and only it.
+	sheet := file.Sheets[1]
+	want := expected.Sheets[1]
+	c.Assert(sheet.Cell(0, 0).Value, Equals, want.Cell(0, 0).Value)
+	c.Assert(sheet.Rows, HasLen, len(want.Rows))
+	c.Assert(sheet.MaxRow, Equals, want.MaxRow)
+	c.Assert(sheet.MaxCol, Equals, want.MaxCol)
+	c.Assert(file.Sheets[0].Rows, IsNil)
+	c.Assert(file.Sheets[0].Load(), IsNil)
+	c.Assert(file.Sheets[0].Rows, HasLen, len(expected.Sheets[0].Rows))
+
+	// Unloading releases the rows, which are read again when needed.
+	sheet.Cell(0, 0).SetString("changed")
+	c.Assert(sheet.Unload(), IsNil)
+	c.Assert(sheet.Rows, IsNil)
+	c.Assert(sheet.Cell(0, 0).Value, Equals, want.Cell(0, 0).Value)
+
+	// Writing loads every sheet.
+	c.Assert(file.Sheets[2].Unload(), IsNil)
+	var buf bytes.Buffer
+	c.Assert(file.Write(&buf), IsNil)
+	output, err := file.ToSlice()
+	c.Assert(err, IsNil)
+	wantOutput, err := expected.ToSlice()
+	c.Assert(err, IsNil)
+	c.Assert(output, DeepEquals, wantOutput)
+}
+
+func (l *LazySuite) TestUnloadNeedsLazySheets(c *C) {
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	c.Assert(sheet.Load(), IsNil)
+	c.Assert(sheet.Unload(), ErrorMatches, "sheet 'Sheet1' was not opened with LazySheets, and can't be unloaded")
+	c.Assert(file.Close(), IsNil)
+}
+
+func (l *LazySuite) TestLoadAfterClose(c *C) {
+	file, err := OpenFileWithOptions("./testdocs/empty_rows.xlsx", LazySheets())
+	c.Assert(err, IsNil)
+	c.Assert(file.Close(), IsNil)
+	c.Assert(file.Sheets[0].Load(), ErrorMatches, "can't load sheet '.*': .*")
+	c.Assert(file.Sheets[0].Cell(0, 0), NotNil)
+	c.Assert(file.Warnings, HasLen, 1)
+}
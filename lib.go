@@ -6,9 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"path"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // XLSXReaderError is the standard error type for otherwise undefined
@@ -106,12 +108,12 @@ func formatColumnName(colId []int) string {
 			// range 0-25, all other numbers are 1-26,
 			// hence we use a differente offset for the
 			// last part.
-			result += string(part + 65)
+			result += string(rune(part + 65))
 		} else {
 			// Don't output leading 0s, as there is no
 			// representation of 0 in this format.
 			if part > 0 {
-				result += string(part + 64)
+				result += string(rune(part + 64))
 			}
 		}
 	}
@@ -297,7 +299,7 @@ func makeRowFromSpan(spans string, sheet *Sheet) *Row {
 	error = nil
 	row.Cells = make([]*Cell, upper)
 	for i := 0; i < upper; i++ {
-		cell = new(Cell)
+		cell = NewCell(row)
 		cell.Value = ""
 		row.Cells[i] = cell
 	}
@@ -333,7 +335,7 @@ func makeRowFromRaw(rawrow xlsxRow, sheet *Sheet) *Row {
 
 	row.Cells = make([]*Cell, upper)
 	for i := 0; i < upper; i++ {
-		cell = new(Cell)
+		cell = NewCell(row)
 		cell.Value = ""
 		row.Cells[i] = cell
 	}
@@ -371,53 +373,63 @@ func formulaForCell(rawcell xlsxC, sharedFormulas map[int]sharedFormula) string
 				sharedFormula := sharedFormulas[f.Si]
 				dx := x - sharedFormula.x
 				dy := y - sharedFormula.y
-				orig := []byte(sharedFormula.formula)
-				var start, end int
-				var stringLiteral bool
-				for end = 0; end < len(orig); end++ {
-					c := orig[end]
-
-					if c == '"' {
-						stringLiteral = !stringLiteral
-					}
+				res = shiftFormula(sharedFormula.formula, dx, dy)
+			}
+		}
+	} else {
+		res = f.Content
+	}
+	return strings.Trim(res, " \t\n\r")
+}
 
-					if stringLiteral {
-						continue // Skip characters in quotes
-					}
+// shiftFormula returns the formula with every relative cell reference
+// in it shifted by dx columns and dy rows.  String literals are left
+// untouched.  This is how the formula of a shared formula's anchor
+// cell is transformed into the formula of each cell sharing it.
+func shiftFormula(formula string, dx, dy int) string {
+	var res string
+	orig := []byte(formula)
+	var start, end int
+	var stringLiteral bool
+	for end = 0; end < len(orig); end++ {
+		c := orig[end]
+
+		if c == '"' {
+			stringLiteral = !stringLiteral
+		}
 
-					if c >= 'A' && c <= 'Z' || c == '$' {
-						res += string(orig[start:end])
-						start = end
-						end++
-						foundNum := false
-						for ; end < len(orig); end++ {
-							idc := orig[end]
-							if idc >= '0' && idc <= '9' || idc == '$' {
-								foundNum = true
-							} else if idc >= 'A' && idc <= 'Z' {
-								if foundNum {
-									break
-								}
-							} else {
-								break
-							}
-						}
-						if foundNum {
-							cellID := string(orig[start:end])
-							res += shiftCell(cellID, dx, dy)
-							start = end
-						}
+		if stringLiteral {
+			continue // Skip characters in quotes
+		}
+
+		if c >= 'A' && c <= 'Z' || c == '$' {
+			res += string(orig[start:end])
+			start = end
+			end++
+			foundNum := false
+			for ; end < len(orig); end++ {
+				idc := orig[end]
+				if idc >= '0' && idc <= '9' || idc == '$' {
+					foundNum = true
+				} else if idc >= 'A' && idc <= 'Z' {
+					if foundNum {
+						break
 					}
-				}
-				if start < len(orig) {
-					res += string(orig[start:])
+				} else {
+					break
 				}
 			}
+			if foundNum {
+				cellID := string(orig[start:end])
+				res += shiftCell(cellID, dx, dy)
+				start = end
+			}
 		}
-	} else {
-		res = f.Content
 	}
-	return strings.Trim(res, " \t\n\r")
+	if start < len(orig) {
+		res += string(orig[start:])
+	}
+	return res
 }
 
 // shiftCell returns the cell shifted according to dx and dy taking into consideration of absolute
@@ -474,6 +486,24 @@ func shiftCell(cellID string, dx, dy int) string {
 // general enough - we should support retaining tabs and newlines.
 func fillCellData(rawcell xlsxC, reftable *RefTable, sharedFormulas map[int]sharedFormula, cell *Cell) {
 	var data string = rawcell.V
+	if rawcell.T == "inlineStr" {
+		// An inline string, which some writers use instead of
+		// the shared string table.
+		if rawcell.Is != nil {
+			cell.Value = rawcell.Is.text()
+			cell.setReadPhonetic(readPhonetic(rawcell.Is.RPh, rawcell.Is.PhoneticPr), rawcell.Ph)
+		}
+		cell.cellType = CellTypeString
+		return
+	}
+	if len(data) == 0 && rawcell.F != nil {
+		// A formula without a cached value.  It still has to be
+		// read, not least because it may be the anchor of a
+		// shared formula.
+		cell.formula = formulaForCell(rawcell, sharedFormulas)
+		cell.cellType = CellTypeFormula
+		return
+	}
 	if len(data) > 0 {
 		vval := strings.Trim(data, " \t\n\r")
 		switch rawcell.T {
@@ -483,14 +513,39 @@ func fillCellData(rawcell xlsxC, reftable *RefTable, sharedFormulas map[int]shar
 				panic(error)
 			}
 			cell.Value = reftable.ResolveSharedString(ref)
+			cell.setReadPhonetic(reftable.resolvePhonetic(ref), rawcell.Ph)
 			cell.cellType = CellTypeString
 		case "b": // Boolean
 			cell.Value = vval
 			cell.cellType = CellTypeBool
+			if rawcell.F != nil {
+				cell.formula = formulaForCell(rawcell, sharedFormulas)
+				cell.resultType = "b"
+				cell.cellType = CellTypeFormula
+			}
 		case "e": // Error
 			cell.Value = vval
 			cell.formula = formulaForCell(rawcell, sharedFormulas)
 			cell.cellType = CellTypeError
+		case "str": // The string result of a formula
+			cell.Value = data
+			if rawcell.F == nil {
+				cell.cellType = CellTypeString
+			} else {
+				cell.formula = formulaForCell(rawcell, sharedFormulas)
+				cell.resultType = "str"
+				cell.cellType = CellTypeFormula
+			}
+		case "d": // An ISO 8601 date, found in strict files
+			serial, ok := serialFromISO8601(vval, cell)
+			if !ok {
+				cell.Value = vval
+				cell.cellType = CellTypeString
+				break
+			}
+			cell.Value = strconv.FormatFloat(serial, 'f', -1, 64)
+			cell.formula = formulaForCell(rawcell, sharedFormulas)
+			cell.cellType = CellTypeDate
 		default:
 			if rawcell.F == nil {
 				// Numeric
@@ -506,6 +561,35 @@ func fillCellData(rawcell xlsxC, reftable *RefTable, sharedFormulas map[int]shar
 	}
 }
 
+// iso8601Layouts are the forms of ISO 8601 date and time accepted in
+// the value of a d type cell.
+var iso8601Layouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// serialFromISO8601 converts the value of a d type cell to a serial
+// number, using the calendar of the cell's File.  Times without a
+// date become fractions of a day.  Any time zone is ignored, as
+// spreadsheets don't have them.
+func serialFromISO8601(value string, cell *Cell) (float64, bool) {
+	for _, layout := range iso8601Layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return ExcelSerialFromTime(t, cell.inDate1904(), cell.datePolicy()), true
+		}
+	}
+	for _, layout := range []string{"15:04:05.999999999", "15:04"} {
+		if t, err := time.Parse(layout, strings.TrimPrefix(value, "T")); err == nil {
+			hour, min, sec := t.Clock()
+			seconds := float64((hour*60+min)*60+sec) + float64(t.Nanosecond())/1e9
+			return seconds / (24 * 60 * 60), true
+		}
+	}
+	return 0, false
+}
+
 // readRowsFromSheet is an internal helper function that extracts the
 // rows from a XSLXWorksheet, populates them with Cells and resolves
 // the value references from the reference table and stores them in
@@ -546,7 +630,8 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet) ([]*R
 
 	if Worksheet.Cols != nil {
 		// Columns can apply to a range, for convenience we expand the
-		// ranges out into individual column definitions.
+		// ranges out into individual column definitions, each
+		// covering its own column so that they don't overlap.
 		for _, rawcol := range Worksheet.Cols.Col {
 			// Note, below, that sometimes column definitions can
 			// exist outside the defined dimensions of the
@@ -554,28 +639,40 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet) ([]*R
 			// columns.
 			for i := rawcol.Min; i <= rawcol.Max && i <= colCount; i++ {
 				col := &Col{
-					Min:          rawcol.Min,
-					Max:          rawcol.Max,
+					Min:          i,
+					Max:          i,
 					Hidden:       rawcol.Hidden,
+					Collapsed:    rawcol.Collapsed,
 					Width:        rawcol.Width,
 					OutlineLevel: rawcol.OutlineLevel}
 				cols[i-1] = col
 				if file.styles != nil {
 					col.style = file.styles.getStyle(rawcol.Style)
 					col.numFmt = file.styles.getNumberFormat(rawcol.Style)
+					col.customStyle = rawcol.Style != 0
 				}
 			}
 		}
 	}
 
 	// insert leading empty rows that is in front of minRow
-	for rowIndex := 0; rowIndex < minRow; rowIndex++ {
+	for rowIndex := 0; rowIndex < minRow && !file.sparseSheets; rowIndex++ {
 		rows[rowIndex] = makeEmptyRow(sheet)
 	}
 
 	numRows := len(rows)
 	for rowIndex := 0; rowIndex < len(Worksheet.SheetData.Row); rowIndex++ {
 		rawrow := Worksheet.SheetData.Row[rowIndex]
+		if file.sparseSheets {
+			// Leave out the rows the file does, and put each
+			// row where it says it goes.
+			if rawrow.R > 0 {
+				insertRowIndex = rawrow.R - 1
+			}
+			for insertRowIndex >= len(rows) {
+				rows = append(rows, nil)
+			}
+		}
 		// Some spreadsheets will omit blank rows from the
 		// stored data
 		for rawrow.R > (insertRowIndex + 1) {
@@ -586,7 +683,9 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet) ([]*R
 			insertRowIndex++
 		}
 		// range is not empty and only one range exist
-		if len(rawrow.Spans) != 0 && strings.Count(rawrow.Spans, ":") == 1 {
+		if file.sparseSheets {
+			row = &Row{Sheet: sheet}
+		} else if len(rawrow.Spans) != 0 && strings.Count(rawrow.Spans, ":") == 1 {
 			row = makeRowFromSpan(rawrow.Spans, sheet)
 		} else {
 			row = makeRowFromRaw(rawrow, sheet)
@@ -599,6 +698,10 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet) ([]*R
 		}
 		row.isCustom = rawrow.CustomHeight
 		row.OutlineLevel = rawrow.OutlineLevel
+		row.meta = readRowMetadata(rawrow)
+		if rawrow.CustomFormat && file.styles != nil {
+			row.style = file.styles.getStyle(rawrow.S)
+		}
 
 		insertColIndex = minCol
 		for _, rawcell := range rawrow.C {
@@ -607,12 +710,22 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet) ([]*R
 				panic(err.Error())
 			}
 			x, _, _ := getCoordsFromCellIDString(rawcell.R)
+			if file.sparseSheets {
+				// Leave out the cells the file does, too.
+				if rawcell.R != "" {
+					insertColIndex = x
+				}
+				for insertColIndex >= len(row.Cells) {
+					row.Cells = append(row.Cells, nil)
+				}
+				row.Cells[insertColIndex] = NewCell(row)
+			}
 
 			// Some spreadsheets will omit blank cells
 			// from the data.
 			for x > insertColIndex {
 				// Put an empty Cell into the array
-				row.Cells[insertColIndex] = new(Cell)
+				row.Cells[insertColIndex] = NewCell(row)
 				insertColIndex++
 			}
 			cellX := insertColIndex
@@ -620,11 +733,28 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet) ([]*R
 			cell.HMerge = h
 			cell.VMerge = v
 			fillCellData(rawcell, reftable, sharedFormulas, cell)
+			if file.valuesOnly {
+				cell.dropFormula()
+			}
+			cell.meta = readCellMetadata(rawcell, cell.formula)
 			if file.styles != nil {
 				cell.style = file.styles.getStyle(rawcell.S)
 				cell.NumFmt = file.styles.getNumberFormat(rawcell.S)
 			}
-			cell.date1904 = file.Date1904
+			if cell.cellType == CellTypeDate && !isTimeFormat(cell.NumFmt) {
+				// A d type cell without a date format would
+				// otherwise show its serial number.
+				serial, _ := strconv.ParseFloat(cell.Value, 64)
+				switch {
+				case serial < 1:
+					cell.NumFmt = builtInNumFmt[21]
+				case serial == math.Trunc(serial):
+					cell.NumFmt = builtInNumFmt[14]
+				default:
+					cell.NumFmt = builtInNumFmt[22]
+				}
+			}
+			cell.date1904 = file.storedDate1904
 			// Cell is considered hidden if the row or the column of this cell is hidden
 			cell.Hidden = rawrow.Hidden || (len(cols) > cellX && cols[cellX].Hidden)
 			insertColIndex++
@@ -671,41 +801,102 @@ func readSheetViews(xSheetViews xlsxSheetViews) []SheetView {
 // sheet and get the results back on the provided channel.
 func readSheetFromFile(sc chan *indexedSheet, index int, rsheet xlsxSheet, fi *File, sheetXMLMap map[string]string) {
 	result := &indexedSheet{Index: index, Sheet: nil, Error: nil}
+	sheet := newSheetFromWorkbook(rsheet, fi)
+	if err := loadSheet(sheet, rsheet, sheetXMLMap); err != nil {
+		result.Error = err
+	} else {
+		result.Sheet = sheet
+	}
+	sc <- result
+}
+
+// newSheetFromWorkbook returns a Sheet holding what the workbook
+// records about rsheet, without its contents.
+func newSheetFromWorkbook(rsheet xlsxSheet, fi *File) *Sheet {
+	sheet := new(Sheet)
+	sheet.File = fi
+	sheet.Name = rsheet.Name
+	sheet.Hidden = rsheet.State == sheetStateHidden || rsheet.State == sheetStateVeryHidden
+	sheet.VeryHidden = rsheet.State == sheetStateVeryHidden
+	return sheet
+}
+
+// loadSheet reads the worksheet of rsheet into sheet.
+func loadSheet(sheet *Sheet, rsheet xlsxSheet, sheetXMLMap map[string]string) (err error) {
+	fi := sheet.File
 	defer func() {
 		if e := recover(); e != nil {
 			switch e.(type) {
 			case error:
-				result.Error = e.(error)
+				err = e.(error)
 			default:
-				result.Error = errors.New("unexpected error")
+				err = errors.New("unexpected error")
 			}
-			// The only thing here, is if one close the channel. but its not the case
-			sc <- result
 		}
 	}()
 
-	worksheet, error := getWorksheetFromSheet(rsheet, fi.worksheets, sheetXMLMap)
-	if error != nil {
-		result.Error = error
-		sc <- result
-		return
+	worksheet, err := getWorksheetFromSheet(rsheet, fi, sheetXMLMap)
+	if err != nil {
+		return err
+	}
+	if fi.repair {
+		fi.repairWorksheet(sheet.Name, worksheet)
 	}
-	sheet := new(Sheet)
-	sheet.File = fi
 	sheet.Rows, sheet.Cols, sheet.MaxCol, sheet.MaxRow = readRowsFromSheet(worksheet, fi, sheet)
-	sheet.Hidden = rsheet.State == sheetStateHidden || rsheet.State == sheetStateVeryHidden
+	sheet.Cols.normalise()
+	fi.stats.add(func(stats *Stats) {
+		for _, row := range worksheet.SheetData.Row {
+			stats.CellsRead += len(row.C)
+		}
+	})
+	if fi.Date1904 != fi.storedDate1904 {
+		sheet.convertDates(fi.storedDate1904, fi.Date1904)
+	}
+	if worksheet.SheetPr.TabColor != nil {
+		sheet.TabColor = fi.styles.argbValue(*worksheet.SheetPr.TabColor)
+	}
+	sheet.CodeName = worksheet.SheetPr.CodeName
+	sheet.Outline = readOutlineSettings(worksheet.SheetPr.OutlinePr)
 	sheet.SheetViews = readSheetViews(worksheet.SheetViews)
+	var sheetRels map[string]xlsxWorksheetRelationship
+	if part := worksheetFileForSheet(rsheet, fi.worksheets, sheetXMLMap); part != nil {
+		var err error
+		if sheetRels, err = fi.readRelationships(normalizePartName(part.Name)); err != nil {
+			return err
+		}
+		if err := fi.readDrawings(sheet, normalizePartName(part.Name)); err != nil {
+			return err
+		}
+		background := ""
+		if worksheet.Picture != nil {
+			background = worksheet.Picture.RID
+		}
+		if err := fi.readBackground(sheet, normalizePartName(part.Name), background); err != nil {
+			return err
+		}
+		if err := fi.readOLEObjects(sheet, worksheet, normalizePartName(part.Name)); err != nil {
+			return err
+		}
+		if err := fi.readCustomSheetViews(sheet, worksheet, normalizePartName(part.Name)); err != nil {
+			return err
+		}
+	}
+	sheet.Protected = worksheet.SheetProtection != nil && worksheet.SheetProtection.Sheet
+	sheet.IgnoredErrors = readIgnoredErrors(worksheet.IgnoredErrors)
+	sheet.Hyperlinks = readHyperlinks(worksheet.Hyperlinks, sheetRels)
 
 	sheet.SheetFormat.DefaultColWidth = worksheet.SheetFormatPr.DefaultColWidth
 	sheet.SheetFormat.DefaultRowHeight = worksheet.SheetFormatPr.DefaultRowHeight
+	sheet.SheetFormat.CustomHeight = worksheet.SheetFormatPr.CustomHeight
 	sheet.SheetFormat.OutlineLevelCol = worksheet.SheetFormatPr.OutlineLevelCol
 	sheet.SheetFormat.OutlineLevelRow = worksheet.SheetFormatPr.OutlineLevelRow
 
 	// Read gridlines option
 	if len(worksheet.SheetViews.SheetView) > 0 {
-		sheet.ShowGridLines = worksheet.SheetViews.SheetView[0].ShowGridLines
+		sheet.View = readViewSettings(worksheet.SheetViews.SheetView[0])
+		sheet.ShowGridLines = sheet.View.ShowGridLines
 	}
-	
+
 	// Read header content
 	if len(worksheet.HeaderFooter.OddHeader) > 0 {
 		sheet.OddHeader = worksheet.HeaderFooter.OddHeader[0].Content
@@ -715,12 +906,14 @@ func readSheetFromFile(sc chan *indexedSheet, index int, rsheet xlsxSheet, fi *F
 
 	// Print "landscape"
 	if len(worksheet.SheetPr.PageSetUpPr) > 0 {
-		sheet.FitToPage = worksheet.SheetPr.PageSetUpPr[0].FitToPage
+		pr := worksheet.SheetPr.PageSetUpPr[0]
+		if pr.invalid != "" {
+			return fmt.Errorf("sheet '%s': pageSetUpPr: fitToPage %q isn't a boolean", sheet.Name, pr.invalid)
+		}
+		sheet.FitToPage = pr.FitToPage
 	}
 	sheet.PageSetUp = worksheet.PageSetUp
-
-	result.Sheet = sheet
-	sc <- result
+	return nil
 }
 
 // readSheetsFromZipFile is an internal helper function that loops
@@ -733,25 +926,63 @@ func readSheetsFromZipFile(f *zip.File, file *File, sheetXMLMap map[string]strin
 	var decoder *xml.Decoder
 	var sheetCount int
 	workbook = new(xlsxWorkbook)
-	rc, err = f.Open()
+	rc, err = file.openPart(f)
 	if err != nil {
 		return nil, nil, err
 	}
-	decoder = xml.NewDecoder(rc)
+	decoder = newPartDecoder(rc)
 	err = decoder.Decode(workbook)
 	if err != nil {
 		return nil, nil, err
 	}
-	file.Date1904 = workbook.WorkbookPr.Date1904
+	file.storedDate1904 = workbook.WorkbookPr.Date1904
+	file.CodeName = workbook.WorkbookPr.CodeName
+	file.Calc = mergeCalcProperties(readCalcProperties(workbook.CalcPr), file.Calc)
+	if !file.defaults.dateSystem {
+		file.Date1904 = file.storedDate1904
+	}
 
 	for entryNum := range workbook.DefinedNames.DefinedName {
 		file.DefinedNames = append(file.DefinedNames, &workbook.DefinedNames.DefinedName[entryNum])
 	}
+	file.Names = readDefinedNames(workbook)
+	file.CustomViews = readCustomViews(workbook)
+	if views := workbook.BookViews.WorkBookView; len(views) > 0 {
+		file.Window = readWindowSettings(views[0])
+		file.otherWindows = views[1:]
+	}
+	if err := file.readExternalLinks(workbook); err != nil {
+		return nil, nil, err
+	}
 
 	// Only try and read sheets that have corresponding files.
-	// Notably this excludes chartsheets don't right now
+	// Notably this excludes chartsheets don't right now.  Dialog
+	// and macro sheets are kept as they are, and mustn't be taken
+	// for the worksheets their sheetIds might name.
+	workbookRels, err := file.readRelationships("xl/workbook.xml")
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := file.readContentTypes(); err != nil {
+		return nil, nil, err
+	}
+	if err := file.readVBAProject(workbookRels); err != nil {
+		return nil, nil, err
+	}
 	var workbookSheets []xlsxSheet
-	for _, sheet := range workbook.Sheets.Sheet {
+	for i, sheet := range workbook.Sheets.Sheet {
+		if rel, ok := workbookRels[sheet.Id]; ok {
+			if kind, ok := sheetKindOf(rel.Type); ok {
+				preserved, err := file.readPreservedSheet(sheet, i, kind, rel.Target)
+				if err != nil {
+					return nil, nil, err
+				}
+				if preserved != nil {
+					file.PreservedSheets = append(file.PreservedSheets, preserved)
+				}
+				continue
+			}
+		}
 		if f := worksheetFileForSheet(sheet, file.worksheets, sheetXMLMap); f != nil {
 			workbookSheets = append(workbookSheets, sheet)
 		}
@@ -759,15 +990,36 @@ func readSheetsFromZipFile(f *zip.File, file *File, sheetXMLMap map[string]strin
 	sheetCount = len(workbookSheets)
 	sheetsByName := make(map[string]*Sheet, sheetCount)
 	sheets := make([]*Sheet, sheetCount)
+	if file.lazySheets {
+		for i, rsheet := range workbookSheets {
+			sheet := newSheetFromWorkbook(rsheet, file)
+			sheet.lazy = &lazySheet{rsheet: rsheet, sheetXMLMap: sheetXMLMap}
+			sheetsByName[sheet.Name] = sheet
+			sheets[i] = sheet
+		}
+		selectActiveSheet(workbook, sheetsByName)
+		return sheetsByName, sheets, nil
+	}
+	// Both channels are large enough that the workers never block,
+	// and so finish even if we return early with an error.
 	sheetChan := make(chan *indexedSheet, sheetCount)
-	defer close(sheetChan)
+	jobs := make(chan int, sheetCount)
+	for i := range workbookSheets {
+		jobs <- i
+	}
+	close(jobs)
 
-	go func() {
-		err = nil
-		for i, rawsheet := range workbookSheets {
-			readSheetFromFile(sheetChan, i, rawsheet, file, sheetXMLMap)
-		}
-	}()
+	workers := file.sheetWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	for w := 0; w < workers && w < sheetCount; w++ {
+		go func() {
+			for i := range jobs {
+				readSheetFromFile(sheetChan, i, workbookSheets[i], file, sheetXMLMap)
+			}
+		}()
+	}
 
 	for j := 0; j < sheetCount; j++ {
 		sheet := <-sheetChan
@@ -779,18 +1031,28 @@ func readSheetsFromZipFile(f *zip.File, file *File, sheetXMLMap map[string]strin
 		sheet.Sheet.Name = sheetName
 		sheets[sheet.Index] = sheet.Sheet
 	}
+	selectActiveSheet(workbook, sheetsByName)
 	return sheetsByName, sheets, nil
 }
 
+// selectActiveSheet marks the sheet that the workbook's view has as
+// its active tab as Selected.
+func selectActiveSheet(workbook *xlsxWorkbook, sheetsByName map[string]*Sheet) {
+	views := workbook.BookViews.WorkBookView
+	if len(views) == 0 || views[0].ActiveTab >= len(workbook.Sheets.Sheet) {
+		return
+	}
+	if sheet, ok := sheetsByName[workbook.Sheets.Sheet[views[0].ActiveTab].Name]; ok {
+		sheet.Selected = true
+	}
+}
+
 // readSharedStringsFromZipFile() is an internal helper function to
 // extract a reference table from the sharedStrings.xml file within
 // the XLSX zip file.
-func readSharedStringsFromZipFile(f *zip.File) (*RefTable, error) {
-	var sst *xlsxSST
+func readSharedStringsFromZipFile(f *zip.File, file *File) (*RefTable, error) {
 	var error error
 	var rc io.ReadCloser
-	var decoder *xml.Decoder
-	var reftable *RefTable
 
 	// In a file with no strings it's possible that
 	// sharedStrings.xml doesn't exist.  In this case the value
@@ -798,17 +1060,19 @@ func readSharedStringsFromZipFile(f *zip.File) (*RefTable, error) {
 	if f == nil {
 		return nil, nil
 	}
-	rc, error = f.Open()
+	rc, error = file.openPart(f)
 	if error != nil {
 		return nil, error
 	}
-	sst = new(xlsxSST)
-	decoder = xml.NewDecoder(rc)
-	error = decoder.Decode(sst)
-	if error != nil {
-		return nil, error
+	defer rc.Close()
+	reftable, distinct, err := readSharedStrings(newPartDecoder(rc))
+	if err != nil {
+		return nil, err
 	}
-	reftable = MakeSharedStringRefTable(sst)
+	file.stats.add(func(stats *Stats) {
+		stats.SharedStrings += reftable.Length()
+		stats.StringsInterned += reftable.Length() - distinct
+	})
 	return reftable, nil
 }
 
@@ -825,7 +1089,7 @@ func readStylesFromZipFile(f *zip.File, theme *theme) (*xlsxStyleSheet, error) {
 		return nil, error
 	}
 	style = newXlsxStyleSheet(theme)
-	decoder = xml.NewDecoder(rc)
+	decoder = newPartDecoder(rc)
 	error = decoder.Decode(style)
 	if error != nil {
 		return nil, error
@@ -848,7 +1112,7 @@ func readThemeFromZipFile(f *zip.File) (*theme, error) {
 	}
 
 	var themeXml xlsxTheme
-	err = xml.NewDecoder(rc).Decode(&themeXml)
+	err = newPartDecoder(rc).Decode(&themeXml)
 	if err != nil {
 		return nil, err
 	}
@@ -858,6 +1122,10 @@ func readThemeFromZipFile(f *zip.File) (*theme, error) {
 
 type WorkBookRels map[string]string
 
+// MakeXLSXWorkbookRels returns the workbook's relationships to the
+// worksheets, followed by those to the shared strings, the theme and
+// the styles.  Files aren't written with it, as their relationships
+// are assigned as their parts are made.
 func (w *WorkBookRels) MakeXLSXWorkbookRels() xlsxWorkbookRels {
 	relCount := len(*w)
 	xWorkbookRels := xlsxWorkbookRels{}
@@ -898,9 +1166,9 @@ func (w *WorkBookRels) MakeXLSXWorkbookRels() xlsxWorkbookRels {
 }
 
 // readWorkbookRelationsFromZipFile is an internal helper function to
-// extract a map of relationship ID strings to the name of the
-// worksheet.xml file they refer to.  The resulting map can be used to
-// reliably derefence the worksheets in the XLSX file.
+// extract a map of relationship ID strings to the normalized part name
+// of the worksheet.xml file they refer to.  The resulting map can be
+// used to reliably derefence the worksheets in the XLSX file.
 func readWorkbookRelationsFromZipFile(workbookRels *zip.File) (WorkBookRels, error) {
 	var sheetXMLMap WorkBookRels
 	var wbRelationships *xlsxWorkbookRels
@@ -912,7 +1180,7 @@ func readWorkbookRelationsFromZipFile(workbookRels *zip.File) (WorkBookRels, err
 	if err != nil {
 		return nil, err
 	}
-	decoder = xml.NewDecoder(rc)
+	decoder = newPartDecoder(rc)
 	wbRelationships = new(xlsxWorkbookRels)
 	err = decoder.Decode(wbRelationships)
 	if err != nil {
@@ -920,14 +1188,53 @@ func readWorkbookRelationsFromZipFile(workbookRels *zip.File) (WorkBookRels, err
 	}
 	sheetXMLMap = make(WorkBookRels)
 	for _, rel := range wbRelationships.Relationships {
-		if strings.HasSuffix(rel.Target, ".xml") && rel.Type == "http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" {
-			_, filename := path.Split(rel.Target)
-			sheetXMLMap[rel.Id] = strings.Replace(filename, ".xml", "", 1)
+		target := resolveRelTarget("xl/workbook.xml", rel.Target)
+		if strings.HasSuffix(target, ".xml") && rel.Type == relTypeWorksheet {
+			sheetXMLMap[rel.Id] = target
 		}
 	}
 	return sheetXMLMap, nil
 }
 
+// normalizePartName returns the name of a part in the form the OPC
+// spec compares part names in: with forward slashes, no leading slash
+// or dot segments, and in lower case, since part names are case
+// insensitive.  Some generators write backslashes or capitalise the
+// names differently to Excel.
+func normalizePartName(name string) string {
+	name = path.Clean("/" + strings.Replace(name, `\`, "/", -1))
+	return strings.ToLower(name[1:])
+}
+
+// resolveRelTarget returns the normalized part name of a
+// relationship's target, resolving relative targets against the
+// folder of the source part the relationship belongs to.
+func resolveRelTarget(source, target string) string {
+	target = strings.Replace(target, `\`, "/", -1)
+	if !strings.HasPrefix(target, "/") {
+		target = path.Join(path.Dir(source), target)
+	}
+	return normalizePartName(target)
+}
+
+// worksheetParts returns the worksheet parts of the package by their
+// normalized names - those in the xl/worksheets folder, along with any
+// elsewhere that the workbook's relationships point to.
+func worksheetParts(files []*zip.File, sheetXMLMap map[string]string) map[string]*zip.File {
+	targets := make(map[string]bool, len(sheetXMLMap))
+	for _, target := range sheetXMLMap {
+		targets[target] = true
+	}
+	worksheets := make(map[string]*zip.File, len(files))
+	for _, v := range files {
+		name := normalizePartName(v.Name)
+		if targets[name] || strings.HasPrefix(name, "xl/worksheets/") && strings.HasSuffix(name, ".xml") {
+			worksheets[name] = v
+		}
+	}
+	return worksheets
+}
+
 // ReadZip() takes a pointer to a zip.ReadCloser and returns a
 // xlsx.File struct populated with its contents.  In most cases
 // ReadZip is not used directly, but is called internally by OpenFile.
@@ -939,6 +1246,12 @@ func ReadZip(f *zip.ReadCloser) (*File, error) {
 // ReadZipReader() can be used to read an XLSX in memory without
 // touching the filesystem.
 func ReadZipReader(r *zip.Reader) (*File, error) {
+	return readZipReader(r, nil)
+}
+
+// readZipReader reads an XLSX from r into a File configured with the
+// given options.
+func readZipReader(r *zip.Reader, options []FileOption) (*File, error) {
 	var err error
 	var file *File
 	var reftable *RefTable
@@ -955,11 +1268,15 @@ func ReadZipReader(r *zip.Reader) (*File, error) {
 	var worksheets map[string]*zip.File
 
 	file = NewFile()
+	for _, option := range options {
+		option(file)
+	}
 	// file.numFmtRefTable = make(map[int]xlsxNumFmt, 1)
-	worksheets = make(map[string]*zip.File, len(r.File))
+	file.parts = make(map[string]*zip.File, len(r.File))
 	for _, v = range r.File {
-		switch v.Name {
-		case "xl/sharedStrings.xml":
+		file.parts[normalizePartName(v.Name)] = v
+		switch normalizePartName(v.Name) {
+		case "xl/sharedstrings.xml":
 			sharedStrings = v
 		case "xl/workbook.xml":
 			workbook = v
@@ -969,14 +1286,11 @@ func ReadZipReader(r *zip.Reader) (*File, error) {
 			styles = v
 		case "xl/theme/theme1.xml":
 			themeFile = v
-		default:
-			if len(v.Name) > 14 {
-				if v.Name[0:13] == "xl/worksheets" {
-					worksheets[v.Name[14:len(v.Name)-4]] = v
-				}
-			}
 		}
 	}
+	if _, ok := file.parts["xl/workbook.bin"]; ok && workbook == nil {
+		return readXLSB(file)
+	}
 	if workbookRels == nil {
 		return nil, fmt.Errorf("xl/_rels/workbook.xml.rels not found in input xlsx.")
 	}
@@ -984,20 +1298,26 @@ func ReadZipReader(r *zip.Reader) (*File, error) {
 	if err != nil {
 		return nil, err
 	}
+	file.stats.partRead()
+	worksheets = worksheetParts(r.File, sheetXMLMap)
 	if len(worksheets) == 0 {
 		return nil, fmt.Errorf("Input xlsx contains no worksheets.")
 	}
 	file.worksheets = worksheets
-	reftable, err = readSharedStringsFromZipFile(sharedStrings)
+	done := file.stats.phase("sharedStrings")
+	reftable, err = readSharedStringsFromZipFile(sharedStrings, file)
+	done()
 	if err != nil {
 		return nil, err
 	}
 	file.referenceTable = reftable
+	done = file.stats.phase("styles")
 	if themeFile != nil {
 		theme, err := readThemeFromZipFile(themeFile)
 		if err != nil {
 			return nil, err
 		}
+		file.stats.partRead()
 
 		file.theme = theme
 	}
@@ -1006,10 +1326,28 @@ func ReadZipReader(r *zip.Reader) (*File, error) {
 		if err != nil {
 			return nil, err
 		}
+		file.stats.partRead()
 
 		file.styles = style
+		file.readStyles = style.counts()
+		file.namedStyles = style.readNamedStyles()
+		file.dxfs = style.readDifferentialStyles()
+		file.tableStyles = style.readTableStyles()
+		if style.TableStyles != nil {
+			file.DefaultTableStyle = style.TableStyles.DefaultTableStyle
+			file.DefaultPivotStyle = style.TableStyles.DefaultPivotStyle
+		}
+	}
+	done()
+	if err := file.readSheetMetadata(); err != nil {
+		return nil, err
+	}
+	if err := file.readCustomXML(); err != nil {
+		return nil, err
 	}
+	done = file.stats.phase("sheets")
 	sheetsByName, sheets, err = readSheetsFromZipFile(workbook, file, sheetXMLMap)
+	done()
 	if err != nil {
 		return nil, err
 	}
@@ -1020,5 +1358,6 @@ func ReadZipReader(r *zip.Reader) (*File, error) {
 	}
 	file.Sheet = sheetsByName
 	file.Sheets = sheets
+	file.readSignatures()
 	return file, nil
 }
@@ -1,10 +1,11 @@
 package xlsx
 
 import (
+	"archive/zip"
 	"bytes"
 	"encoding/xml"
 	"os"
-
+	"regexp"
 	"strings"
 
 	. "gopkg.in/check.v1"
@@ -46,6 +47,50 @@ func (l *LibSuite) TestReadWorkbookRelationsFromZipFileWithFunnyNames(c *C) {
 	}
 }
 
+// Part names are matched without regard to case or the kind of
+// slashes used, and relationship targets may be absolute.
+func (l *LibSuite) TestReadZipReaderWithNonstandardPartNames(c *C) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, part := range [][2]string{
+		{`XL\Workbook.xml`, `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="First" sheetId="1" r:id="rId1"/><sheet name="Second" sheetId="2" r:id="rId2"/></sheets></workbook>`},
+		{`xl\_rels\workbook.xml.rels`, `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="/xl/Worksheets/Sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="..\data\second.XML"/></Relationships>`},
+		{"xl/SharedStrings.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><si><t>Hello</t></si></sst>`},
+		{"xl/worksheets/SHEET1.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData><row r="1"><c r="A1" t="s"><v>0</v></c></row></sheetData></worksheet>`},
+		{"Data/Second.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData><row r="1"><c r="A1"><v>2</v></c></row></sheetData></worksheet>`},
+	} {
+		fw, err := w.Create(part[0])
+		c.Assert(err, IsNil)
+		_, err = fw.Write([]byte(part[1]))
+		c.Assert(err, IsNil)
+	}
+	c.Assert(w.Close(), IsNil)
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+
+	file, err := ReadZipReader(zr)
+	c.Assert(err, IsNil)
+	c.Assert(file.Sheets, HasLen, 2)
+	c.Assert(file.Sheet["First"].Cell(0, 0).Value, Equals, "Hello")
+	c.Assert(file.Sheet["Second"].Cell(0, 0).Value, Equals, "2")
+
+	info, err := PeekReaderAt(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	c.Assert(info.Sheets, HasLen, 2)
+}
+
+func (l *LibSuite) TestResolveRelTarget(c *C) {
+	c.Assert(resolveRelTarget("xl/workbook.xml", "worksheets/sheet1.xml"), Equals, "xl/worksheets/sheet1.xml")
+	c.Assert(resolveRelTarget("xl/workbook.xml", "/XL/Worksheets/Sheet1.xml"), Equals, "xl/worksheets/sheet1.xml")
+	c.Assert(resolveRelTarget("xl/worksheets/sheet1.xml", `..\drawings\drawing1.xml`), Equals, "xl/drawings/drawing1.xml")
+	c.Assert(normalizePartName(`/xl\.\Styles.xml`), Equals, "xl/styles.xml")
+}
+
 // We can marshal WorkBookRels to an xml file
 func (l *LibSuite) TestWorkBookRelsMarshal(c *C) {
 	var rels WorkBookRels = make(WorkBookRels)
@@ -1178,6 +1223,58 @@ func (l *LibSuite) TestSharedFormulas(c *C) {
 	c.Assert(row.Cells[2].Formula(), Equals, "2*C1")
 }
 
+// Test reading each t type of cell.
+func (l *LibSuite) TestReadCellTypes(c *C) {
+	var sheetxml = bytes.NewBufferString(`
+<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <dimension ref="A1:I1"/>
+  <sheetData>
+    <row r="1">
+      <c r="A1" t="inlineStr"><is><t>inline</t></is></c>
+      <c r="B1" t="inlineStr"><is><r><t>rich </t></r><r><t>text</t></r></is></c>
+      <c r="C1" t="str"><f>"a"&amp;"b"</f><v>ab</v></c>
+      <c r="D1" t="str"><v> padded </v></c>
+      <c r="E1" t="b"><v>1</v></c>
+      <c r="F1" t="e"><f>1/0</f><v>#DIV/0!</v></c>
+      <c r="G1" t="d"><v>2016-03-01T12:00:00Z</v></c>
+      <c r="H1" t="d"><v>2016-03-01</v></c>
+      <c r="I1" t="d"><v>06:00:00</v></c>
+    </row>
+  </sheetData>
+</worksheet>`)
+
+	worksheet := new(xlsxWorksheet)
+	err := xml.NewDecoder(sheetxml).Decode(worksheet)
+	c.Assert(err, IsNil)
+
+	file := new(File)
+	sheet := &Sheet{File: file}
+	rows, _, _, _ := readRowsFromSheet(worksheet, file, sheet)
+	cells := rows[0].Cells
+
+	c.Assert(cells[0].Value, Equals, "inline")
+	c.Assert(cells[0].Type(), Equals, CellTypeString)
+	c.Assert(cells[1].Value, Equals, "rich text")
+	c.Assert(cells[2].Value, Equals, "ab")
+	c.Assert(cells[2].Type(), Equals, CellTypeFormula)
+	c.Assert(cells[2].Formula(), Equals, `"a"&"b"`)
+	c.Assert(cells[3].Value, Equals, " padded ")
+	c.Assert(cells[3].Type(), Equals, CellTypeString)
+	c.Assert(cells[4].Bool(), Equals, true)
+	c.Assert(cells[5].Type(), Equals, CellTypeError)
+
+	c.Assert(cells[6].Type(), Equals, CellTypeDate)
+	serial, err := cells[6].Float()
+	c.Assert(err, IsNil)
+	c.Assert(serial, Equals, 42430.5)
+	c.Assert(cells[6].NumFmt, Equals, builtInNumFmt[22])
+	c.Assert(cells[7].Value, Equals, "42430")
+	c.Assert(cells[7].NumFmt, Equals, builtInNumFmt[14])
+	c.Assert(cells[8].Value, Equals, "0.25")
+	c.Assert(cells[8].NumFmt, Equals, builtInNumFmt[21])
+}
+
 // Test shared formulas that have absolute references ($) in them
 func (l *LibSuite) TestSharedFormulasWithAbsoluteReferences(c *C) {
 	formulas := []string{
@@ -1324,3 +1421,55 @@ func (l *LibSuite) TestRoundTripFileWithNoSheetCols(c *C) {
 	c.Assert(err, IsNil)
 	os.Remove("testdocs/after_write.xlsx")
 }
+
+func (l *LibSuite) TestSparseSheets(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sparse")
+	sheet.AddRow().AddCell().SetString("x")
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	worksheet := parts["xl/worksheets/sheet1.xml"]
+	worksheet = regexp.MustCompile(`<dimension ref="[^"]*">`).ReplaceAllString(worksheet, `<dimension ref="Z100:AC102">`)
+	worksheet = regexp.MustCompile(`(?s)<sheetData>.*</sheetData>`).ReplaceAllString(worksheet,
+		`<sheetData><row r="100"><c r="Z100" t="s"><v>0</v></c></row><row r="102"><c r="AB102"><v>2</v></c><c><v>3</v></c></row></sheetData>`)
+	parts["xl/worksheets/sheet1.xml"] = worksheet
+
+	file, err = readZipReader(makeZipReader(c, parts), []FileOption{SparseSheets()})
+	c.Assert(err, IsNil)
+	sheet = file.Sheets[0]
+	c.Assert(sheet.Rows, HasLen, 102)
+	c.Assert(sheet.Rows[0], IsNil)
+	c.Assert(sheet.Rows[100], IsNil)
+	c.Assert(sheet.Rows[99].Cells, HasLen, 26)
+	c.Assert(sheet.Rows[99].Cells[0], IsNil)
+	c.Assert(sheet.Rows[99].Cells[25].Value, Equals, "x")
+	c.Assert(sheet.Rows[101].Cells, HasLen, 29)
+	c.Assert(sheet.Rows[101].Cells[26], IsNil)
+	c.Assert(sheet.Rows[101].Cells[27].Value, Equals, "2")
+	c.Assert(sheet.Rows[101].Cells[28].Value, Equals, "3")
+	c.Assert(sheet.Dimension(), Equals, "Z100:AC102")
+	count := 0
+	c.Assert(sheet.ForEachCell(func(row, col int, cell *Cell) error {
+		count++
+		return nil
+	}), IsNil)
+	c.Assert(count, Equals, 3)
+	slice, err := file.ToSlice()
+	c.Assert(err, IsNil)
+	c.Assert(slice[0][0][25], Equals, "x")
+
+	// Writing the sheet keeps the gaps.
+	parts, err = file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/worksheets/sheet1.xml"], Matches, `(?s).*<sheetData><row r="100"><c r="Z100"[^>]*>.*</row><row r="102"><c r="AB102"[^>]*>.*<c r="AC102"[^>]*>.*</row></sheetData>.*`)
+
+	// The cells are where they were without the option too.
+	file, err = ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	c.Assert(file.Sheets[0].Cell(99, 25).Value, Equals, "x")
+	c.Assert(file.Sheets[0].Cell(101, 28).Value, Equals, "3")
+	c.Assert(file.Sheets[0].Dimension(), Equals, "Z100:AC102")
+	empty, _ := NewFile().AddSheet("Empty")
+	empty.AddRow().AddCell()
+	c.Assert(empty.Dimension(), Equals, "")
+}
@@ -0,0 +1,462 @@
+package xlsx
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// The largest number of rows and columns a worksheet may hold.
+const (
+	SheetRowLimit = 1048576
+	SheetColLimit = 16384
+)
+
+// The longest name Excel will accept for a worksheet.
+const sheetNameLimit = 31
+
+// OverflowStrategy determines what happens, at write time, to a Sheet
+// holding more rows or columns than a worksheet is allowed to.
+type OverflowStrategy int
+
+const (
+	// OverflowError causes the write to fail with a
+	// *SheetLimitError.  This is the default.
+	OverflowError OverflowStrategy = iota
+	// OverflowTruncate drops the rows and cells that are out of
+	// bounds, and records a warning on the File.
+	OverflowTruncate
+	// OverflowNewSheet continues the rows that don't fit on
+	// additional worksheets, named after the original with a
+	// numeric suffix, e.g. "Data (2)".  Rows with too many
+	// cells still cause a *SheetLimitError.
+	OverflowNewSheet
+)
+
+// SheetLimitError is returned when writing a Sheet that holds more
+// rows or columns than a worksheet is allowed to.
+type SheetLimitError struct {
+	Sheet string
+	Rows  int
+	Cols  int
+}
+
+// Error returns a string value from a SheetLimitError in order that it
+// might comply with the builtin.error interface.
+func (e *SheetLimitError) Error() string {
+	return fmt.Sprintf("sheet '%s' has %d rows and %d columns, more than a worksheet can hold",
+		e.Sheet, e.Rows, e.Cols)
+}
+
+// RowLimit makes opening a File read no more than the first n rows of
+// each worksheet, leaving the rest out without building them, so that
+// a preview of a large workbook costs little more than the rows shown.
+// A limit of zero, the default, reads every row.
+func RowLimit(n int) FileOption {
+	return func(f *File) {
+		f.rowLimit = n
+	}
+}
+
+// ColLimit makes opening a File read no more than the first n columns
+// of each worksheet, as RowLimit does rows.
+func ColLimit(n int) FileOption {
+	return func(f *File) {
+		f.colLimit = n
+	}
+}
+
+// readLimits returns the numbers of rows and columns of each worksheet
+// that are read when the File is opened.
+func (f *File) readLimits() (rows, cols int) {
+	rows, cols = SheetRowLimit, SheetColLimit
+	if f.rowLimit > 0 && f.rowLimit < rows {
+		rows = f.rowLimit
+	}
+	if f.colLimit > 0 && f.colLimit < cols {
+		cols = f.colLimit
+	}
+	return rows, cols
+}
+
+// sheetLimiter reads the tokens of a worksheet, leaving out the rows
+// and cells past the File's read limits.  The spans of the rows read are
+// left out too, if columns are limited, lest the empty cells they call
+// for go past the limit.  As rows are in order, those after the first
+// past the limit are passed over unparsed, by data, if it isn't nil.
+type sheetLimiter struct {
+	d          *xml.Decoder
+	data       *sheetDataReader
+	rows, cols int
+	// row and col are the indexes of the next row, and the next cell
+	// of the row, for those that don't say where they go.
+	row, col int
+}
+
+func (l *sheetLimiter) Token() (xml.Token, error) {
+	for {
+		token, err := l.d.Token()
+		start, ok := token.(xml.StartElement)
+		if err != nil || !ok {
+			return token, err
+		}
+		switch start.Name.Local {
+		case "row":
+			index := l.row
+			attrs := start.Attr[:0:0]
+			for _, attr := range start.Attr {
+				switch attr.Name.Local {
+				case "r":
+					if r, err := strconv.Atoi(attr.Value); err == nil && r > 0 {
+						index = r - 1
+					}
+				case "spans":
+					if l.cols < SheetColLimit {
+						continue
+					}
+				}
+				attrs = append(attrs, attr)
+			}
+			l.row, l.col = index+1, 0
+			if index >= l.rows {
+				if l.data != nil {
+					if err := l.data.skipRows(); err != nil {
+						return nil, err
+					}
+				}
+				if err := l.d.Skip(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			start.Attr = attrs
+			return start, nil
+		case "c":
+			index := l.col
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "r" {
+					if x, _, err := getCoordsFromCellIDString(attr.Value); err == nil {
+						index = x
+					}
+				}
+			}
+			l.col = index + 1
+			if index >= l.cols {
+				if err := l.d.Skip(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+		return token, nil
+	}
+}
+
+// sheetDataReader reads the XML of a worksheet for its decoder, a byte
+// at a time, so that once the decoder has read the start tag of a
+// row, what is left of the sheetData can be passed over; see
+// skipRows.
+type sheetDataReader struct {
+	r *bufio.Reader
+	// parsed is the number of bytes read by the decoder, and last the
+	// last two of them.
+	parsed int64
+	last   [2]byte
+	// pending are the bytes read before the rest of r.
+	pending []byte
+}
+
+func (r *sheetDataReader) ReadByte() (byte, error) {
+	var b byte
+	if len(r.pending) > 0 {
+		b, r.pending = r.pending[0], r.pending[1:]
+	} else {
+		var err error
+		if b, err = r.r.ReadByte(); err != nil {
+			return 0, err
+		}
+	}
+	r.parsed++
+	r.last[0], r.last[1] = r.last[1], b
+	return b, nil
+}
+
+func (r *sheetDataReader) Read(p []byte) (int, error) {
+	for n := range p {
+		b, err := r.ReadByte()
+		if err != nil {
+			return n, err
+		}
+		p[n] = b
+	}
+	return len(p), nil
+}
+
+// skipRows passes over the bytes up to the end tag of the sheetData,
+// having given the decoder the end tag of the row whose start tag it
+// has just read, if it wasn't an empty element.
+func (r *sheetDataReader) skipRows() error {
+	emptyRow := r.last == [2]byte{'/', '>'}
+	for {
+		if _, err := r.r.ReadSlice('<'); err != nil {
+			if err == bufio.ErrBufferFull {
+				continue
+			}
+			return unexpected(err)
+		}
+		if b, err := r.r.Peek(1); err != nil || b[0] != '/' {
+			continue
+		}
+		var name []byte
+		for {
+			b, err := r.r.ReadByte()
+			if err != nil {
+				return unexpected(err)
+			}
+			if b == '>' || b == ' ' || b == '\t' || b == '\r' || b == '\n' || b == '<' {
+				r.r.UnreadByte()
+				break
+			}
+			name = append(name, b)
+		}
+		// name is the slash and the name of the end tag.
+		prefix := name[1:]
+		if i := bytes.IndexByte(prefix, ':'); i >= 0 {
+			prefix, name = prefix[:i+1], append([]byte{'/'}, prefix[i+1:]...)
+		} else {
+			prefix = nil
+		}
+		if string(name) != "/sheetData" {
+			continue
+		}
+		r.pending = nil
+		if !emptyRow {
+			r.pending = append(append(append(r.pending, "</"...), prefix...), "row>"...)
+		}
+		r.pending = append(append(append(r.pending, "</"...), prefix...), "sheetData"...)
+		return nil
+	}
+}
+
+// unexpected returns err, or io.ErrUnexpectedEOF if a worksheet ended
+// in the middle of its sheetData.
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// limitDimension returns the dimension of a worksheet, ref, cut down
+// to the rows and columns read from it.
+func limitDimension(ref string, rows, cols int) string {
+	minx, miny, maxx, maxy, err := getMaxMinFromDimensionRef(ref)
+	if err != nil {
+		return ref
+	}
+	if maxx >= cols {
+		maxx = cols - 1
+	}
+	if maxy >= rows {
+		maxy = rows - 1
+	}
+	if minx > maxx || miny > maxy {
+		return ""
+	}
+	return getCellIDStringFromCoords(minx, miny) + ":" + getCellIDStringFromCoords(maxx, maxy)
+}
+
+// extent returns the number of rows in the Sheet and the number of
+// cells in its widest row.
+func (s *Sheet) extent() (rows, cols int) {
+	for _, row := range s.Rows {
+		if row != nil && len(row.Cells) > cols {
+			cols = len(row.Cells)
+		}
+	}
+	return len(s.Rows), cols
+}
+
+// sheetsToWrite returns the Sheets to be written for the File, having
+// applied the File's OverflowStrategy to any Sheet that has more than
+// maxRows rows or maxCols columns.  The Sheets in the File are never
+// modified; Sheets that need to change are replaced by copies.
+func (f *File) sheetsToWrite(maxRows, maxCols int) ([]*Sheet, error) {
+	sheets := make([]*Sheet, 0, len(f.Sheets))
+	names := make(map[string]bool, len(f.Sheets))
+	for _, sheet := range f.Sheets {
+		names[sheet.Name] = true
+	}
+	for _, sheet := range f.Sheets {
+		rows, cols := sheet.extent()
+		if rows <= maxRows && cols <= maxCols {
+			sheets = append(sheets, sheet)
+			continue
+		}
+		limitErr := &SheetLimitError{Sheet: sheet.Name, Rows: rows, Cols: cols}
+		switch f.OverflowStrategy {
+		case OverflowTruncate:
+			sheets = append(sheets, sheet.truncated(maxRows, maxCols))
+			f.warn(limitErr.Error() + ", truncating")
+		case OverflowNewSheet:
+			if cols > maxCols {
+				return nil, limitErr
+			}
+			for n, start := 1, 0; start < rows; n, start = n+1, start+maxRows {
+				end := start + maxRows
+				if end > rows {
+					end = rows
+				}
+				part := sheet.rowsPart(start, end)
+				if n > 1 {
+					part.Name = overflowSheetName(sheet.Name, n, names)
+					part.Selected = false
+					part.Drawings = nil
+					part.CodeName = ""
+					names[part.Name] = true
+				}
+				sheets = append(sheets, part)
+			}
+		default:
+			return nil, limitErr
+		}
+	}
+	return sheets, nil
+}
+
+// withRows returns a shallow copy of the Sheet holding the given rows.
+func (s *Sheet) withRows(rows []*Row) *Sheet {
+	part := *s
+	part.Rows = rows
+	part.MaxRow = len(rows)
+	return &part
+}
+
+// rowsPart returns a copy of the Sheet holding its rows from start up
+// to end, moved up to the top of the sheet.  Its merged cells and
+// hyperlinks are cut down to those rows.
+func (s *Sheet) rowsPart(start, end int) *Sheet {
+	part := s.withRows(clipMerges(s.Rows[start:end:end], SheetColLimit))
+	part.Hyperlinks = clipHyperlinks(s.Hyperlinks, start, end, SheetColLimit)
+	return part
+}
+
+// clipMerges returns the rows with the merged cells that reach beyond
+// the last of them, or beyond the first cols columns, made smaller.
+// The rows and cells that change are replaced by copies.
+func clipMerges(rows []*Row, cols int) []*Row {
+	var clipped []*Row
+	for i, row := range rows {
+		if row == nil {
+			continue
+		}
+		var cells []*Cell
+		for j, cell := range row.Cells {
+			if cell == nil || (i+cell.VMerge < len(rows) && j+cell.HMerge < cols) {
+				continue
+			}
+			if cells == nil {
+				cells = append([]*Cell(nil), row.Cells...)
+			}
+			c := *cell
+			if i+c.VMerge >= len(rows) {
+				c.VMerge = len(rows) - 1 - i
+			}
+			if j+c.HMerge >= cols {
+				c.HMerge = cols - 1 - j
+			}
+			cells[j] = &c
+		}
+		if cells == nil {
+			continue
+		}
+		if clipped == nil {
+			clipped = append([]*Row(nil), rows...)
+		}
+		r := *row
+		r.Cells = cells
+		clipped[i] = &r
+	}
+	if clipped == nil {
+		return rows
+	}
+	return clipped
+}
+
+// clipHyperlinks returns the part of each of the links that lies in
+// the rows from start up to end and the first cols columns, moved up
+// by start rows.  Links that lie wholly outside are left out.
+func clipHyperlinks(links []Hyperlink, start, end, cols int) []Hyperlink {
+	if links == nil {
+		return nil
+	}
+	bounds := CellRange{Start: CellRef{Row: start}, End: CellRef{Col: cols - 1, Row: end - 1}}
+	clipped := make([]Hyperlink, 0, len(links))
+	for _, link := range links {
+		ref, err := ParseCellRange(link.Ref)
+		if err != nil {
+			clipped = append(clipped, link)
+			continue
+		}
+		ref, ok := ref.Intersect(bounds)
+		if !ok {
+			continue
+		}
+		link.Ref = ref.Offset(0, -start).String()
+		clipped = append(clipped, link)
+	}
+	return clipped
+}
+
+// truncated returns a copy of the Sheet with its rows and cells cut
+// down to the given number of rows and columns.
+func (s *Sheet) truncated(maxRows, maxCols int) *Sheet {
+	rows := s.Rows
+	if len(rows) > maxRows {
+		rows = rows[:maxRows]
+	}
+	trimmed := make([]*Row, len(rows))
+	for i, row := range rows {
+		if row != nil && len(row.Cells) > maxCols {
+			r := *row
+			r.Cells = row.Cells[:maxCols]
+			row = &r
+		}
+		trimmed[i] = row
+	}
+	part := s.withRows(clipMerges(trimmed, maxCols))
+	part.Hyperlinks = clipHyperlinks(s.Hyperlinks, 0, len(trimmed), maxCols)
+	part.Cols = make(ColStore, 0, len(s.Cols))
+	for _, col := range s.Cols {
+		if col.Min > maxCols {
+			continue
+		}
+		if col.Max > maxCols {
+			c := *col
+			c.Max = maxCols
+			col = &c
+		}
+		part.Cols = append(part.Cols, col)
+	}
+	return part
+}
+
+// overflowSheetName returns the name of the nth worksheet holding the
+// rows of the named sheet, avoiding any name already in use and
+// respecting the limit on the length of sheet names.
+func overflowSheetName(name string, n int, used map[string]bool) string {
+	for ; ; n++ {
+		suffix := fmt.Sprintf(" (%d)", n)
+		base := []rune(name)
+		if len(base)+len(suffix) > sheetNameLimit {
+			base = base[:sheetNameLimit-len(suffix)]
+		}
+		candidate := string(base) + suffix
+		if !used[candidate] {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package xlsx
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type LimitsSuite struct{}
+
+var _ = Suite(&LimitsSuite{})
+
+func makeOversizedFile(strategy OverflowStrategy) *File {
+	file := NewFile()
+	file.OverflowStrategy = strategy
+	sheet, _ := file.AddSheet("Data")
+	for i := 0; i < 5; i++ {
+		row := sheet.AddRow()
+		row.AddCell().SetInt(i)
+		row.AddCell().SetInt(i * 2)
+	}
+	return file
+}
+
+func (l *LimitsSuite) TestOverflowError(c *C) {
+	file := makeOversizedFile(OverflowError)
+	sheets, err := file.sheetsToWrite(4, 2)
+	c.Assert(sheets, IsNil)
+	c.Assert(err, FitsTypeOf, &SheetLimitError{})
+	c.Assert(err.(*SheetLimitError).Rows, Equals, 5)
+	c.Assert(err.(*SheetLimitError).Cols, Equals, 2)
+}
+
+func (l *LimitsSuite) TestOverflowTruncate(c *C) {
+	file := makeOversizedFile(OverflowTruncate)
+	sheets, err := file.sheetsToWrite(3, 1)
+	c.Assert(err, IsNil)
+	c.Assert(sheets, HasLen, 1)
+	c.Assert(sheets[0].Rows, HasLen, 3)
+	c.Assert(sheets[0].Rows[2].Cells, HasLen, 1)
+	c.Assert(sheets[0].Cols, HasLen, 1)
+	c.Assert(file.Warnings, HasLen, 1)
+
+	// The original sheet is left alone.
+	c.Assert(file.Sheets[0].Rows, HasLen, 5)
+	c.Assert(file.Sheets[0].Rows[2].Cells, HasLen, 2)
+}
+
+func (l *LimitsSuite) TestOverflowNewSheet(c *C) {
+	file := makeOversizedFile(OverflowNewSheet)
+	_, err := file.AddSheet("Data (2)")
+	c.Assert(err, IsNil)
+	sheets, err := file.sheetsToWrite(2, 2)
+	c.Assert(err, IsNil)
+	c.Assert(sheets, HasLen, 4)
+	c.Assert(sheets[0].Name, Equals, "Data")
+	c.Assert(sheets[0].Rows, HasLen, 2)
+	c.Assert(sheets[1].Name, Equals, "Data (3)")
+	c.Assert(sheets[1].Rows, HasLen, 2)
+	c.Assert(sheets[1].Selected, Equals, false)
+	c.Assert(sheets[2].Name, Equals, "Data (4)")
+	c.Assert(sheets[2].Rows, HasLen, 1)
+	c.Assert(sheets[3].Name, Equals, "Data (2)")
+	v, _ := sheets[2].Rows[0].Cells[0].Int()
+	c.Assert(v, Equals, 4)
+
+	// Too many columns can't be fixed by adding sheets.
+	_, err = file.sheetsToWrite(2, 1)
+	c.Assert(err, FitsTypeOf, &SheetLimitError{})
+}
+
+func (l *LimitsSuite) TestOverflowNewSheetClipsMergesAndLinks(c *C) {
+	file := makeOversizedFile(OverflowNewSheet)
+	data := file.Sheets[0]
+	data.Cell(1, 0).VMerge = 2
+	data.Hyperlinks = []Hyperlink{{Ref: "B1"}, {Ref: "A2:B4", URL: "https://example.com/"}, {Ref: "B5"}}
+	sheets, err := file.sheetsToWrite(2, 2)
+	c.Assert(err, IsNil)
+	c.Assert(sheets, HasLen, 3)
+	c.Assert(sheets[0].Rows[1].Cells[0].VMerge, Equals, 0)
+	c.Assert(sheets[0].Hyperlinks, DeepEquals, []Hyperlink{{Ref: "B1"}, {Ref: "A2:B2", URL: "https://example.com/"}})
+	c.Assert(sheets[1].Hyperlinks, DeepEquals, []Hyperlink{{Ref: "A1:B2", URL: "https://example.com/"}})
+	c.Assert(sheets[2].Hyperlinks, DeepEquals, []Hyperlink{{Ref: "B1"}})
+
+	// The original sheet is left alone.
+	c.Assert(data.Cell(1, 0).VMerge, Equals, 2)
+	c.Assert(data.Hyperlinks[1].Ref, Equals, "A2:B4")
+}
+
+func (l *LimitsSuite) TestWarningsArentRepeated(c *C) {
+	file := makeOversizedFile(OverflowTruncate)
+	sheet := file.Sheets[0]
+	sheet.Rows = append(sheet.Rows, make([]*Row, SheetRowLimit)...)
+	file.warn("read")
+	for i := 0; i < 2; i++ {
+		_, err := file.MarshallParts()
+		c.Assert(err, IsNil)
+		c.Assert(file.Warnings, HasLen, 2)
+		c.Assert(file.Warnings[0], Equals, "read")
+	}
+}
+
+func (l *LimitsSuite) TestOverflowSheetName(c *C) {
+	used := map[string]bool{}
+	c.Assert(overflowSheetName("Data", 2, used), Equals, "Data (2)")
+	long := "A very long sheet name indeed!!"
+	name := overflowSheetName(long, 2, used)
+	c.Assert(name, Equals, "A very long sheet name inde (2)")
+	c.Assert(len(name), Equals, sheetNameLimit)
+}
+
+func (l *LimitsSuite) TestWriteWithinLimits(c *C) {
+	file := makeOversizedFile(OverflowError)
+	var buf bytes.Buffer
+	c.Assert(file.Write(&buf), IsNil)
+}
+
+func (s *LimitsSuite) TestReadLimits(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Data")
+	c.Assert(err, IsNil)
+	for r := 0; r < 20; r++ {
+		for col := 0; col < 10; col++ {
+			sheet.Cell(r, col).SetInt(r*10 + col)
+		}
+	}
+	sheet.Cell(0, 0).HMerge = 3
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+
+	read, err := readZipReader(makeZipReader(c, parts), []FileOption{RowLimit(5), ColLimit(3)})
+	c.Assert(err, IsNil)
+	sheet = read.Sheets[0]
+	c.Assert(sheet.MaxRow, Equals, 5)
+	c.Assert(sheet.MaxCol, Equals, 3)
+	c.Assert(sheet.Rows, HasLen, 5)
+	for _, row := range sheet.Rows {
+		c.Assert(row.Cells, HasLen, 3)
+	}
+	c.Assert(sheet.Cell(4, 2).Value, Equals, "42")
+	c.Assert(sheet.Cell(0, 0).HMerge, Equals, 3)
+
+	read, err = readZipReader(makeZipReader(c, parts), []FileOption{RowLimit(2), LazySheets()})
+	c.Assert(err, IsNil)
+	c.Assert(read.Sheets[0].Load(), IsNil)
+	c.Assert(read.Sheets[0].Rows, HasLen, 2)
+	c.Assert(read.Sheets[0].Rows[1].Cells, HasLen, 10)
+}
+
+func (s *LimitsSuite) TestReadLimitsWithoutReferences(c *C) {
+	// Rows and cells that don't say where they go, and spans wider
+	// than the columns read.
+	parts := makeSheetParts(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><dimension ref="A1:D3"/><sheetData>` +
+		`<row spans="1:4"><c><v>1</v></c><c><v>2</v></c><c><v>3</v></c><c><v>4</v></c></row>` +
+		`<row spans="1:4"><c><v>5</v></c><c><v>6</v></c><c><v>7</v></c><c><v>8</v></c></row>` +
+		`<row spans="1:4"><c><v>9</v></c></row>` +
+		`</sheetData></worksheet>`)
+	read, err := readZipReader(makeZipReader(c, parts), []FileOption{RowLimit(2), ColLimit(2)})
+	c.Assert(err, IsNil)
+	sheet := read.Sheets[0]
+	c.Assert(sheet.Rows, HasLen, 2)
+	c.Assert(sheet.Rows[1].Cells, HasLen, 2)
+	c.Assert(sheet.Cell(1, 1).Value, Equals, "6")
+	c.Assert(limitDimension("B2:Z100", 10, 5), Equals, "B2:E10")
+	c.Assert(limitDimension("B20:C30", 10, 5), Equals, "")
+}
+
+func (s *LimitsSuite) TestReadLimitsXLS(c *C) {
+	xls := makeXLS(
+		biffRecord(biffNumber, uint16(0), uint16(0), uint16(0), 1.0),
+		biffRecord(biffNumber, uint16(0), uint16(5), uint16(0), 2.0),
+		biffRecord(biffNumber, uint16(9), uint16(0), uint16(0), 3.0),
+	)
+	r := bytes.NewReader(xls)
+	f, err := readXLS(r, int64(len(xls)), []FileOption{RowLimit(5), ColLimit(2)})
+	c.Assert(err, IsNil)
+	sheet := f.Sheet["Data"]
+	c.Assert(sheet.Rows, HasLen, 1)
+	c.Assert(sheet.Rows[0].Cells, HasLen, 1)
+	c.Assert(sheet.Cell(0, 0).Value, Equals, "1")
+}
+
+// Rows past the limit aren't parsed, but what follows the sheetData is.
+func (s *LimitsSuite) TestReadRowLimitSkipsRows(c *C) {
+	var rows strings.Builder
+	for r := 1; r <= 200; r++ {
+		if r == 6 {
+			rows.WriteString(`<x:row r="6"/>`)
+			continue
+		}
+		fmt.Fprintf(&rows, `<x:row r="%d"><x:c r="A%d"><x:v>%d</x:v></x:c></x:row>`, r, r, r)
+	}
+	parts := makeSheetParts(`<x:worksheet xmlns:x="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><x:sheetData>` + rows.String() +
+		`</x:sheetData><x:mergeCells count="1"><x:mergeCell ref="A1:B1"/></x:mergeCells></x:worksheet>`)
+	full, err := readZipReader(makeZipReader(c, parts), []FileOption{CollectStats()})
+	c.Assert(err, IsNil)
+	c.Assert(full.Sheets[0].Rows, HasLen, 200)
+
+	for _, limit := range []int{5, 100, 200} {
+		read, err := readZipReader(makeZipReader(c, parts), []FileOption{RowLimit(limit), CollectStats()})
+		c.Assert(err, IsNil)
+		sheet := read.Sheets[0]
+		c.Assert(sheet.Rows, HasLen, limit)
+		c.Assert(sheet.Cell(limit-2, 0).Value, Equals, strconv.Itoa(limit-1))
+		c.Assert(sheet.Cell(0, 0).HMerge, Equals, 1)
+		if limit == 5 {
+			c.Assert(read.Stats().BytesParsed < full.Stats().BytesParsed/10, Equals, true)
+		}
+	}
+
+	parts["xl/worksheets/sheet1.xml"] = strings.Replace(parts["xl/worksheets/sheet1.xml"], `</x:sheetData>`, ``, 1)
+	_, err = readZipReader(makeZipReader(c, parts), []FileOption{RowLimit(5)})
+	c.Assert(err, ErrorMatches, "unexpected EOF")
+}
@@ -0,0 +1,99 @@
+package xlsx
+
+// Locale holds the conventions of a language and region that Excel
+// displays values with: the separators of numbers, the currency
+// symbol, and the names of months and days.
+type Locale struct {
+	// Name identifies the locale, such as "de-DE".
+	Name string
+	// DecimalSeparator comes between the integer and fractional
+	// parts of a number, and ThousandsSeparator between each group
+	// of three digits of its integer part in formats such as
+	// "#,##0".
+	DecimalSeparator   string
+	ThousandsSeparator string
+	// CurrencySymbol replaces the "$" of currency formats, coming
+	// after the number rather than before it if CurrencyAfter is
+	// set.  A symbol given in the format itself, as in
+	// "[$€-407]#,##0.00", is kept.
+	CurrencySymbol string
+	CurrencyAfter  bool
+	// The full and abbreviated names of the months, from January,
+	// and the days of the week, from Sunday.
+	MonthNames      [12]string
+	ShortMonthNames [12]string
+	DayNames        [7]string
+	ShortDayNames   [7]string
+}
+
+// The locales Excel is most often used with.
+var (
+	LocaleEnUS = &Locale{
+		Name:               "en-US",
+		DecimalSeparator:   ".",
+		ThousandsSeparator: ",",
+		CurrencySymbol:     "$",
+		MonthNames:         [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		ShortMonthNames:    [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+		DayNames:           [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+		ShortDayNames:      [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+	}
+	LocaleEnGB = &Locale{
+		Name:               "en-GB",
+		DecimalSeparator:   ".",
+		ThousandsSeparator: ",",
+		CurrencySymbol:     "£",
+		MonthNames:         LocaleEnUS.MonthNames,
+		ShortMonthNames:    LocaleEnUS.ShortMonthNames,
+		DayNames:           LocaleEnUS.DayNames,
+		ShortDayNames:      LocaleEnUS.ShortDayNames,
+	}
+	LocaleDeDE = &Locale{
+		Name:               "de-DE",
+		DecimalSeparator:   ",",
+		ThousandsSeparator: ".",
+		CurrencySymbol:     "€",
+		CurrencyAfter:      true,
+		MonthNames:         [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		ShortMonthNames:    [12]string{"Jan", "Feb", "Mrz", "Apr", "Mai", "Jun", "Jul", "Aug", "Sep", "Okt", "Nov", "Dez"},
+		DayNames:           [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		ShortDayNames:      [7]string{"So", "Mo", "Di", "Mi", "Do", "Fr", "Sa"},
+	}
+	LocaleFrFR = &Locale{
+		Name:               "fr-FR",
+		DecimalSeparator:   ",",
+		ThousandsSeparator: "\u00a0",
+		CurrencySymbol:     "€",
+		CurrencyAfter:      true,
+		MonthNames:         [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		ShortMonthNames:    [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		DayNames:           [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		ShortDayNames:      [7]string{"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."},
+	}
+)
+
+// FormatOptions control how FormattedValueWithOptions displays the
+// value of a cell.
+type FormatOptions struct {
+	// Locale is that the value is displayed for, which is LocaleEnUS
+	// if it is nil.
+	Locale *Locale
+}
+
+// FormatLocale makes FormattedValue, and so Cell.String, display the
+// values of the File's cells for the locale, as Excel would show them
+// to its users.
+func FormatLocale(locale *Locale) FileOption {
+	return func(f *File) {
+		f.defaults.locale = locale
+	}
+}
+
+// formatOptions returns the FormatOptions the cells of the File are
+// displayed with, which are the defaults if there is no File.
+func (f *File) formatOptions() FormatOptions {
+	if f == nil {
+		return FormatOptions{}
+	}
+	return FormatOptions{Locale: f.defaults.locale}
+}
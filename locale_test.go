@@ -0,0 +1,62 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type LocaleSuite struct{}
+
+var _ = Suite(&LocaleSuite{})
+
+func formatFor(c *C, locale *Locale, value, numFmt string) string {
+	cell := Cell{Value: value, NumFmt: numFmt, cellType: CellTypeNumeric}
+	formatted, err := cell.FormattedValueWithOptions(FormatOptions{Locale: locale})
+	c.Assert(err, IsNil)
+	return formatted
+}
+
+func (s *LocaleSuite) TestNumbers(c *C) {
+	for _, test := range []struct {
+		locale        *Locale
+		value, numFmt string
+		expected      string
+	}{
+		{nil, "1234567.5", "#,##0.00", "1,234,567.50"},
+		{LocaleEnUS, "1234567.5", "#,##0.00", "1,234,567.50"},
+		{LocaleDeDE, "1234567.5", "#,##0.00", "1.234.567,50"},
+		{LocaleFrFR, "1234567.5", "#,##0", "1\u00a0234\u00a0568"},
+		{LocaleDeDE, "1234.5", "0.00", "1234,50"},
+		{LocaleDeDE, "-1.25", "general", "-1,25"},
+		{LocaleDeDE, "0.125", "0.00%", "12,50%"},
+		{LocaleDeDE, "1.5", "@", "1,5"},
+		{LocaleEnGB, "1234.5", `"$"#,##0.00`, "£1,234.50"},
+		{LocaleDeDE, "1234.5", `$#,##0.00`, "1.234,50\u00a0€"},
+		{LocaleEnUS, "1234.5", "[$€-407]#,##0.00", "€1,234.50"},
+		{LocaleEnUS, "1234.5", "#,##0.00 [$$-409]", "1,234.50 $"},
+	} {
+		c.Assert(formatFor(c, test.locale, test.value, test.numFmt), Equals, test.expected,
+			Commentf("%s in %s", test.value, test.numFmt))
+	}
+	cell := Cell{Value: "text", NumFmt: "general"}
+	formatted, err := cell.FormattedValueWithOptions(FormatOptions{Locale: LocaleDeDE})
+	c.Assert(err, IsNil)
+	c.Assert(formatted, Equals, "text")
+}
+
+func (s *LocaleSuite) TestDates(c *C) {
+	// 1 March 2017, a Wednesday.
+	const serial = "42795"
+	c.Assert(formatFor(c, nil, serial, "dddd, d mmmm yyyy"), Equals, "Wednesday, 1 March 2017")
+	c.Assert(formatFor(c, LocaleDeDE, serial, "dddd, d. mmmm yyyy"), Equals, "Mittwoch, 1. März 2017")
+	c.Assert(formatFor(c, LocaleFrFR, serial, "ddd d mmm yy"), Equals, "mer. 1 mars 17")
+}
+
+func (s *LocaleSuite) TestFormatLocale(c *C) {
+	file := NewFileWithOptions(FormatLocale(LocaleDeDE))
+	sheet, _ := file.AddSheet("Sheet1")
+	cell := sheet.Cell(0, 0)
+	cell.SetFloatWithFormat(1234.5, "#,##0.00")
+	formatted, err := cell.String()
+	c.Assert(err, IsNil)
+	c.Assert(formatted, Equals, "1.234,50")
+}
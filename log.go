@@ -0,0 +1,29 @@
+package xlsx
+
+// Logger receives diagnostics about reading and writing a File, such
+// as the warnings recorded in File.Warnings and where each picture and
+// shape is placed.  A *log.Logger is a Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// LogTo makes a File send its diagnostics to logger.  By default they
+// are discarded.
+func LogTo(logger Logger) FileOption {
+	return func(f *File) {
+		f.logger = logger
+	}
+}
+
+// SetLogger makes the File send its diagnostics to logger, or discard
+// them if logger is nil.
+func (f *File) SetLogger(logger Logger) {
+	f.logger = logger
+}
+
+// logf sends a diagnostic to the File's Logger, if it has one.
+func (f *File) logf(format string, v ...interface{}) {
+	if f != nil && f.logger != nil {
+		f.logger.Printf(format, v...)
+	}
+}
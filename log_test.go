@@ -0,0 +1,60 @@
+package xlsx
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	. "gopkg.in/check.v1"
+)
+
+type LogSuite struct{}
+
+var _ = Suite(&LogSuite{})
+
+type recordingLogger struct {
+	lines []string
+}
+
+func (l *recordingLogger) Printf(format string, v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func (l *LogSuite) TestLogTo(c *C) {
+	logger := &recordingLogger{}
+	file := NewFileWithOptions(LogTo(logger))
+	sheet, _ := file.AddSheet("Sheet1")
+	sheet.AddRow().AddCell().SetString("logo")
+	sheet.Drawings = append(sheet.Drawings, Drawing{
+		Sheet: sheet, ImageData: makePNG(c, 40, 20), ImageType: IMAGE_TYPE_PNG,
+		RowCount: 1, Width: 40, Height: 20,
+	})
+	file.warn("something was worked around")
+
+	// Nothing is written to stdout, even with a picture whose size
+	// has to be worked out.
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	c.Assert(err, IsNil)
+	os.Stdout = w
+	var buf bytes.Buffer
+	err = file.Write(&buf)
+	os.Stdout = stdout
+	w.Close()
+	c.Assert(err, IsNil)
+	printed, err := ioutil.ReadAll(r)
+	c.Assert(err, IsNil)
+	c.Assert(string(printed), Equals, "")
+
+	c.Assert(logger.lines, DeepEquals, []string{
+		"warning: something was worked around",
+		"sheet 'Sheet1': object 1 spans A1 to A2, ending 381000 by 0 EMUs into it",
+	})
+
+	// Without a Logger, diagnostics are discarded.
+	file.SetLogger(nil)
+	file.warn("another")
+	c.Assert(logger.lines, HasLen, 2)
+	c.Assert(file.Warnings, HasLen, 2)
+}
@@ -0,0 +1,148 @@
+package xlsx
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+const relTypeSheetMetadata = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/sheetMetadata"
+
+// rowMetadata holds the attributes of a row read from a file that Row
+// doesn't otherwise have, so that they can be written back as Excel
+// wrote them.
+type rowMetadata struct {
+	// spans is the range of columns the row's cells are in, which
+	// is only written back while it still holds them.
+	spans     string
+	collapsed bool
+	thickTop  bool
+	thickBot  bool
+	phonetic  bool
+}
+
+// cellMetadata holds the attributes of a cell read from a file that
+// Cell doesn't otherwise have.  cm and vm index the cell metadata, such
+// as that of dynamic array formulas, and value metadata of the
+// workbook's metadata part, and are only written back along with it.
+// arrayRef is the range of the array formula, arrayFormula, the cell
+// held, which is written back as one for as long as the cell's formula
+// is left as it was.
+type cellMetadata struct {
+	cm, vm       int
+	phonetic     bool
+	arrayRef     string
+	arrayFormula string
+}
+
+func readCellMetadata(raw xlsxC, formula string) cellMetadata {
+	meta := cellMetadata{cm: raw.Cm, vm: raw.Vm, phonetic: raw.Ph}
+	if raw.F != nil && raw.F.T == "array" && raw.F.Ref != "" && formula != "" {
+		meta.arrayRef, meta.arrayFormula = raw.F.Ref, formula
+	}
+	return meta
+}
+
+// array returns the f element to write for cell, whose formula
+// is written as formula, if it still holds the array formula it was
+// read with, or else nil.
+func (m cellMetadata) array(cell *Cell, formula string) *xlsxF {
+	if m.arrayRef == "" || cell.formula != m.arrayFormula {
+		return nil
+	}
+	return &xlsxF{Content: formula, T: "array", Ref: m.arrayRef}
+}
+
+// write sets the attributes of xC, the cell written, from the metadata.
+// The cm attribute of an array formula that has been changed is left
+// out, as it describes the array.
+func (m cellMetadata) write(xC *xlsxC, withMetadata bool) {
+	xC.Ph = m.phonetic
+	if withMetadata {
+		if m.arrayRef == "" || xC.F != nil && xC.F.T == "array" {
+			xC.Cm = m.cm
+		}
+		xC.Vm = m.vm
+	}
+}
+
+func readRowMetadata(raw xlsxRow) rowMetadata {
+	return rowMetadata{
+		spans:     raw.Spans,
+		collapsed: raw.Collapsed,
+		thickTop:  raw.ThickTop,
+		thickBot:  raw.ThickBot,
+		phonetic:  raw.Ph,
+	}
+}
+
+// write sets the attributes of xRow from the metadata, for a row whose
+// cells are from column first to column last.
+func (m rowMetadata) write(xRow *xlsxRow, first, last int) {
+	if spansCover(m.spans, first, last) {
+		xRow.Spans = m.spans
+	}
+	xRow.Collapsed = m.collapsed
+	xRow.ThickTop = m.thickTop
+	xRow.ThickBot = m.thickBot
+	xRow.Ph = m.phonetic
+}
+
+// spansCover reports whether spans, a list of one based column ranges
+// such as "1:3 5:8", covers the zero based columns from first to last.
+// A row without cells is covered by any spans.
+func spansCover(spans string, first, last int) bool {
+	if spans == "" {
+		return false
+	}
+	lower, upper := SheetColLimit, 0
+	for _, span := range strings.Fields(spans) {
+		bounds := strings.SplitN(span, ":", 2)
+		if len(bounds) != 2 {
+			return false
+		}
+		from, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return false
+		}
+		to, err := strconv.Atoi(bounds[1])
+		if err != nil {
+			return false
+		}
+		if from < lower {
+			lower = from
+		}
+		if to > upper {
+			upper = to
+		}
+	}
+	return first < 0 || lower <= first+1 && last+1 <= upper
+}
+
+// readSheetMetadata keeps the metadata part the workbook relates to,
+// which the cm and vm attributes of its cells index, to be written as
+// it was.  Metadata describing rich values, such as pictures in cells,
+// refers to parts that aren't kept, and so isn't kept either, with a
+// warning.
+func (f *File) readSheetMetadata() error {
+	rels, err := f.readRelationships("xl/workbook.xml")
+	if err != nil {
+		return err
+	}
+	for _, rel := range rels {
+		part := f.parts[rel.Target]
+		if rel.Type != relTypeSheetMetadata || part == nil {
+			continue
+		}
+		data, err := f.readBinaryPart(part)
+		if err != nil {
+			return err
+		}
+		if bytes.Contains(data, []byte(`"XLRICHVALUE"`)) {
+			f.warn("the rich values of cells, such as pictures in cells, aren't kept")
+			return nil
+		}
+		f.sheetMetadata = data
+	}
+	return nil
+}
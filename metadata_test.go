@@ -0,0 +1,84 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type MetadataSuite struct{}
+
+var _ = Suite(&MetadataSuite{})
+
+// makeMetadataParts returns the parts of a workbook whose sheet has a
+// dynamic array formula in A1, with the given metadata part.
+func makeMetadataParts(metadata string) map[string]string {
+	return map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Data" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/sheetMetadata" Target="metadata.xml"/>` +
+			`</Relationships>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+			`<row r="1" spans="1:3" thickBot="1" ph="1"><c r="A1" cm="1"><f t="array" ref="A1:A2">SEQUENCE(2)</f><v>1</v></c><c r="C1" vm="1" ph="1"><v>2</v></c></row>` +
+			`<row r="2" spans="1:1" collapsed="1" thickTop="1"><c r="A2"><v>2</v></c></row>` +
+			`</sheetData></worksheet>`,
+		"xl/metadata.xml": metadata,
+	}
+}
+
+func (s *MetadataSuite) TestRoundTrip(c *C) {
+	metadata := `<metadata xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><metadataTypes count="1"><metadataType name="XLDAPR"/></metadataTypes></metadata>`
+	f, err := readZipReader(makeZipReader(c, makeMetadataParts(metadata)), nil)
+	c.Assert(err, IsNil)
+	sheet := f.Sheets[0]
+	// The second row's spans no longer cover its cells.
+	sheet.Cell(1, 2).SetInt(3)
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/metadata.xml"], Equals, metadata)
+	c.Assert(parts["xl/_rels/workbook.xml.rels"], Matches, `(?s).*Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/sheetMetadata" Target="metadata.xml".*`)
+	c.Assert(parts["[Content_Types].xml"], Matches, `(?s).*PartName="/xl/metadata.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheetMetadata\+xml".*`)
+	worksheet := parts["xl/worksheets/sheet1.xml"]
+	c.Assert(worksheet, Matches, `(?s).*<row r="1" spans="1:3" thickBot="true" ph="true"><c r="A1" cm="1"><f t="array" ref="A1:A2">SEQUENCE\(2\)</f><v>1</v></c>.*<c r="C1" vm="1" ph="true"><v>2</v></c></row>.*`)
+	c.Assert(worksheet, Matches, `(?s).*<row r="2" collapsed="true" thickTop="true">.*`)
+
+	// A changed array formula is written as an ordinary one.
+	sheet.Cell(0, 0).SetFormula("SEQUENCE(3)")
+	parts, err = f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/worksheets/sheet1.xml"], Matches, `(?s).*<c r="A1"><f>SEQUENCE\(3\)</f>.*`)
+
+	// A File made from scratch has no metadata, and so its cells
+	// don't refer to any.
+	other := NewFile()
+	copied, err := other.AddSheet("Copy", SheetFrom(sheet))
+	c.Assert(err, IsNil)
+	c.Assert(copied.Cell(0, 0).meta.cm, Equals, 1)
+	parts, err = other.MarshallParts()
+	c.Assert(err, IsNil)
+	_, ok := parts["xl/metadata.xml"]
+	c.Assert(ok, Equals, false)
+	c.Assert(parts["xl/worksheets/sheet1.xml"], Not(Matches), `(?s).* cm=.*`)
+}
+
+func (s *MetadataSuite) TestRichValues(c *C) {
+	metadata := `<metadata xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><metadataTypes count="1"><metadataType name="XLRICHVALUE"/></metadataTypes></metadata>`
+	f, err := readZipReader(makeZipReader(c, makeMetadataParts(metadata)), nil)
+	c.Assert(err, IsNil)
+	c.Assert(f.Warnings, DeepEquals, []string{"the rich values of cells, such as pictures in cells, aren't kept"})
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	_, ok := parts["xl/metadata.xml"]
+	c.Assert(ok, Equals, false)
+	c.Assert(parts["xl/worksheets/sheet1.xml"], Matches, `(?s).*<c r="C1" ph="true">.*`)
+}
+
+func (s *MetadataSuite) TestSpansCover(c *C) {
+	c.Assert(spansCover("1:3", 0, 2), Equals, true)
+	c.Assert(spansCover("2:3 5:8", 1, 7), Equals, true)
+	c.Assert(spansCover("2:3", 0, 2), Equals, false)
+	c.Assert(spansCover("1:3", 0, 3), Equals, false)
+	c.Assert(spansCover("1:3", -1, -1), Equals, true)
+	c.Assert(spansCover("", 0, 0), Equals, false)
+	c.Assert(spansCover("1-3", 0, 0), Equals, false)
+}
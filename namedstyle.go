@@ -0,0 +1,200 @@
+package xlsx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NamedStyle is a cell style with a name, listed in Excel's Cell
+// Styles gallery unless it is Hidden, which cells can be given so that
+// restyling it restyles all of them.  NumFmt is its number format,
+// with the empty string meaning General.
+type NamedStyle struct {
+	Name   string
+	Style  Style
+	NumFmt string
+	Hidden bool
+	// builtinId is the number of the built in style this is, as
+	// read from a file, where it may have a translated name.
+	builtinId *int
+}
+
+// builtInCellStyles maps the names of the cell styles built into Excel
+// to their builtinId.
+var builtInCellStyles = map[string]int{
+	"Normal":             0,
+	"Comma":              3,
+	"Currency":           4,
+	"Percent":            5,
+	"Comma [0]":          6,
+	"Currency [0]":       7,
+	"Hyperlink":          8,
+	"Followed Hyperlink": 9,
+	"Note":               10,
+	"Warning Text":       11,
+	"Title":              15,
+	"Heading 1":          16,
+	"Heading 2":          17,
+	"Heading 3":          18,
+	"Heading 4":          19,
+	"Input":              20,
+	"Output":             21,
+	"Calculation":        22,
+	"Check Cell":         23,
+	"Linked Cell":        24,
+	"Total":              25,
+	"Good":               26,
+	"Bad":                27,
+	"Neutral":            28,
+	"Explanatory Text":   53,
+}
+
+func init() {
+	for i := 1; i <= 6; i++ {
+		accent := fmt.Sprintf("Accent%d", i)
+		id := 29 + (i-1)*4
+		builtInCellStyles[accent] = id
+		builtInCellStyles["20% - "+accent] = id + 1
+		builtInCellStyles["40% - "+accent] = id + 2
+		builtInCellStyles["60% - "+accent] = id + 3
+	}
+}
+
+// IsBuiltInCellStyle reports whether name is that of a cell style
+// built into Excel, such as "Normal" or "Heading 1".  Excel restores
+// the look of a built in style that a file doesn't define.
+func IsBuiltInCellStyle(name string) bool {
+	_, ok := builtInCellStyles[name]
+	return ok
+}
+
+// AddNamedStyle defines a named cell style in the File, returning its
+// index for a Style's NamedStyleIndex.  The name may be that of a built
+// in style, to change how it looks, but mustn't already be defined.
+// The first named style added to a File is preceded by "Normal", in
+// the File's default font, unless it is "Normal" itself.
+func (f *File) AddNamedStyle(name string, style Style, numFmt string) (int, error) {
+	if name == "" {
+		return -1, fmt.Errorf("a named style needs a name")
+	}
+	if _, ok := f.NamedStyleIndex(name); ok {
+		return -1, fmt.Errorf("named style '%s' is already defined", name)
+	}
+	if len(f.namedStyles) == 0 && name != "Normal" {
+		f.namedStyles = append(f.namedStyles, NamedStyle{Name: "Normal", Style: *f.newStyle()})
+	}
+	style.NamedStyleIndex = nil
+	f.namedStyles = append(f.namedStyles, NamedStyle{Name: name, Style: style, NumFmt: numFmt})
+	return len(f.namedStyles) - 1, nil
+}
+
+// NamedStyles returns the named cell styles defined in the File,
+// whether by AddNamedStyle or in the file it was read from, each at
+// its NamedStyleIndex.
+func (f *File) NamedStyles() []NamedStyle {
+	return f.namedStyles
+}
+
+// NamedStyleIndex returns the index of the named cell style called
+// name, which is matched case insensitively, as Excel does.
+func (f *File) NamedStyleIndex(name string) (int, bool) {
+	for i, namedStyle := range f.namedStyles {
+		if strings.EqualFold(namedStyle.Name, name) {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// SetNamedStyle gives the cell the named cell style called name,
+// replacing its style with a copy of the named one.
+func (c *Cell) SetNamedStyle(name string) error {
+	f := c.file()
+	if f == nil {
+		return fmt.Errorf("cell is not in a sheet of a file")
+	}
+	index, ok := f.NamedStyleIndex(name)
+	if !ok {
+		return fmt.Errorf("named style '%s' is not defined", name)
+	}
+	namedStyle := f.namedStyles[index]
+	style := namedStyle.Style
+	style.NamedStyleIndex = &index
+	c.style = &style
+	if namedStyle.NumFmt != "" {
+		c.NumFmt = namedStyle.NumFmt
+	}
+	return nil
+}
+
+// setNamedStyles puts the named cell styles in the style sheet, each
+// at its index, so that cells keep referring to the right one.
+func (styles *xlsxStyleSheet) setNamedStyles(namedStyles []NamedStyle) {
+	if len(namedStyles) == 0 {
+		styles.CellStyles = nil
+		return
+	}
+	styles.CellStyles = &xlsxCellStyles{}
+	for i, namedStyle := range namedStyles {
+		numFmt := styles.newNumFmt(namedStyle.NumFmt)
+		xf := styles.makeXf(&namedStyle.Style, numFmt.NumFmtId)
+		xf.XfId = nil
+		styles.CellStyleXfs.Xf = append(styles.CellStyleXfs.Xf, xf)
+		styles.CellStyleXfs.Count++
+		if namedStyle.Name == "" {
+			continue
+		}
+		cellStyle := xlsxCellStyle{Name: namedStyle.Name, XfId: i, BuiltInId: namedStyle.builtinId}
+		if id, ok := builtInCellStyles[namedStyle.Name]; ok && cellStyle.BuiltInId == nil {
+			cellStyle.BuiltInId = &id
+		}
+		if namedStyle.Hidden {
+			hidden := true
+			cellStyle.Hidden = &hidden
+		}
+		styles.CellStyles.CellStyle = append(styles.CellStyles.CellStyle, cellStyle)
+		styles.CellStyles.Count++
+	}
+}
+
+// readNamedStyles returns the named cell styles of a style sheet read
+// from a file, one for each of its cellStyleXfs so that their indexes
+// match those the cells refer to.  A cellStyleXf without a name gives
+// a NamedStyle with an empty Name.
+func (styles *xlsxStyleSheet) readNamedStyles() []NamedStyle {
+	if styles.CellStyleXfs == nil || len(styles.CellStyleXfs.Xf) == 0 {
+		return nil
+	}
+	namedStyles := make([]NamedStyle, len(styles.CellStyleXfs.Xf))
+	for i, xf := range styles.CellStyleXfs.Xf {
+		style := Style{Protection: *DefaultProtection()}
+		styles.readXf(&style, xf)
+		style.ApplyBorder = xf.ApplyBorder
+		style.ApplyFill = xf.ApplyFill
+		style.ApplyFont = xf.ApplyFont
+		style.ApplyAlignment = xf.ApplyAlignment
+		style.ApplyProtection = xf.ApplyProtection
+		namedStyles[i].Style = style
+		if xf.NumFmtId != 0 {
+			namedStyles[i].NumFmt = styles.numFmtCode(xf.NumFmtId)
+		}
+	}
+	if styles.CellStyles != nil {
+		for _, cellStyle := range styles.CellStyles.CellStyle {
+			if cellStyle.XfId >= 0 && cellStyle.XfId < len(namedStyles) && namedStyles[cellStyle.XfId].Name == "" {
+				namedStyles[cellStyle.XfId].Name = cellStyle.Name
+				namedStyles[cellStyle.XfId].Hidden = cellStyle.Hidden != nil && *cellStyle.Hidden
+				namedStyles[cellStyle.XfId].builtinId = cellStyle.BuiltInId
+			}
+		}
+	}
+	return namedStyles
+}
+
+// numFmtCode returns the format code of the number format numFmtId.
+func (styles *xlsxStyleSheet) numFmtCode(numFmtId int) string {
+	if builtin := getBuiltinNumberFormat(numFmtId); builtin != "" {
+		return builtin
+	}
+	return styles.numFmtRefTable[numFmtId].FormatCode
+}
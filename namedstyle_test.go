@@ -0,0 +1,70 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type NamedStyleSuite struct{}
+
+var _ = Suite(&NamedStyleSuite{})
+
+func (s *NamedStyleSuite) TestReadNamedStyles(c *C) {
+	// The file's Normal style has its Japanese name.
+	file, err := OpenFile("./testdocs/testcelltypes.xlsx")
+	c.Assert(err, IsNil)
+	namedStyles := file.NamedStyles()
+	c.Assert(namedStyles, HasLen, 1)
+	c.Assert(namedStyles[0].Name, Equals, "標準")
+	index, ok := file.NamedStyleIndex("標準")
+	c.Assert(ok, Equals, true)
+	c.Assert(index, Equals, 0)
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/styles.xml"], Matches, `(?s).*<cellStyleXfs count="1">.*<cellStyles count="1"><cellStyle builtinId="0" name="標準" xfId="0"></cellStyle></cellStyles>.*`)
+
+	c.Assert(IsBuiltInCellStyle("Heading 1"), Equals, true)
+	c.Assert(IsBuiltInCellStyle("60% - Accent6"), Equals, true)
+	c.Assert(IsBuiltInCellStyle("Bob"), Equals, false)
+}
+
+func (s *NamedStyleSuite) TestAddNamedStyle(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	cell := sheet.AddRow().AddCell()
+	cell.SetString("Summary")
+
+	heading := *NewStyle()
+	heading.Font.Bold = true
+	heading.Font.Size = 15
+	heading.ApplyFont = true
+	index, err := file.AddNamedStyle("Heading 1", heading, "")
+	c.Assert(err, IsNil)
+	c.Assert(index, Equals, 1)
+	_, err = file.AddNamedStyle("heading 1", heading, "")
+	c.Assert(err, ErrorMatches, "named style 'heading 1' is already defined")
+	_, err = file.AddNamedStyle("Money", *NewStyle(), "#,##0.00 €")
+	c.Assert(err, IsNil)
+	c.Assert(cell.SetNamedStyle("Heading 1"), IsNil)
+	c.Assert(cell.SetNamedStyle("Nope"), ErrorMatches, "named style 'Nope' is not defined")
+	c.Assert(*cell.GetStyle().NamedStyleIndex, Equals, 1)
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	styles := parts["xl/styles.xml"]
+	c.Assert(styles, Matches, `(?s).*<cellStyleXfs count="3">.*`)
+	c.Assert(styles, Matches, `(?s).*<cellStyles count="3"><cellStyle builtinId="0" name="Normal" xfId="0"></cellStyle><cellStyle builtinId="16" name="Heading 1" xfId="1"></cellStyle><cellStyle name="Money" xfId="2"></cellStyle></cellStyles>.*`)
+	c.Assert(styles, Matches, `(?s).*<cellXfs count="3">.*fontId="1" numFmtId="0" xfId="1">.*`)
+
+	written, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	namedStyles := written.NamedStyles()
+	c.Assert(namedStyles, HasLen, 3)
+	c.Assert(namedStyles[1].Name, Equals, "Heading 1")
+	c.Assert(namedStyles[1].Style.Font.Bold, Equals, true)
+	c.Assert(namedStyles[1].Style.Font.Size, Equals, 15)
+	c.Assert(namedStyles[2].NumFmt, Equals, "#,##0.00 €")
+	readCell := written.Sheets[0].Cell(0, 0)
+	c.Assert(*readCell.GetStyle().NamedStyleIndex, Equals, 1)
+	c.Assert(readCell.GetStyle().Font.Bold, Equals, true)
+}
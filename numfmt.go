@@ -0,0 +1,1185 @@
+package xlsx
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The kinds of token a section of a number format is made of.
+type formatTokenKind int
+
+const (
+	// tokenLiteral is text displayed as it is.
+	tokenLiteral formatTokenKind = iota
+	// tokenGeneral is the General format, displaying the number as
+	// it is.
+	tokenGeneral
+	// tokenDigit is a digit placeholder: 0, # or ?.
+	tokenDigit
+	// tokenPoint is the decimal point.
+	tokenPoint
+	// tokenComma is a comma, which either groups the digits of the
+	// integer part in thousands or scales the number by a thousand.
+	tokenComma
+	tokenPercent
+	// tokenExponent is the E+ or E- of scientific notation.
+	tokenExponent
+	// tokenSlash divides the numerator of a fraction from its
+	// denominator.
+	tokenSlash
+	// tokenText is the @ displaying the text of a cell.
+	tokenText
+	// tokenCurrency is a currency symbol, which is that of the locale
+	// if it is "$".
+	tokenCurrency
+	// tokenDate is a part of a date or time, such as "yyyy", "m",
+	// "hh" or "am/pm", in lower case.
+	tokenDate
+	// tokenElapsed is an elapsed time in hours, minutes or seconds,
+	// such as "[h]", given as "h".
+	tokenElapsed
+	// tokenSubsecond is the fraction of a second, given as the
+	// number of digits.
+	tokenSubsecond
+)
+
+// formatToken is a token of a section of a number format.
+type formatToken struct {
+	kind formatTokenKind
+	text string
+}
+
+// formatCondition is the condition, such as [>=100], for a section of a
+// number format to be used.
+type formatCondition struct {
+	op    string
+	value float64
+}
+
+// holds reports whether v meets the condition.
+func (fc *formatCondition) holds(v float64) bool {
+	switch fc.op {
+	case "<":
+		return v < fc.value
+	case "<=":
+		return v <= fc.value
+	case ">":
+		return v > fc.value
+	case ">=":
+		return v >= fc.value
+	case "=":
+		return v == fc.value
+	case "<>":
+		return v != fc.value
+	}
+	return false
+}
+
+// formatSection is one of the sections, separated by semicolons, of a
+// number format.
+type formatSection struct {
+	tokens    []formatToken
+	color     string
+	condition *formatCondition
+	// isDate is set if the section displays a date or time, and
+	// hasText if it displays the text of a cell.
+	isDate  bool
+	hasText bool
+	// hasDigits is set if the section has any digit placeholders or
+	// General, so that it displays the number.
+	hasDigits bool
+	// The number is multiplied by 100 for each percent sign, and
+	// divided by 1000 for each comma scaling it.
+	percents int
+	scale    int
+	// grouping is set if the digits of the integer part are grouped
+	// in thousands.
+	grouping bool
+	// ampm is set if hours are displayed on a 12 hour clock.
+	ampm bool
+	// fraction is set if the number is displayed as a fraction; the
+	// denominator is fixed if denominator isn't 0.
+	fraction    bool
+	denominator int
+}
+
+// numberFormat is a parsed number format.
+type numberFormat struct {
+	sections []*formatSection
+}
+
+// Parsed number formats, by their codes, as the same few are used by
+// the cells of whole columns.  The cache is emptied once it holds
+// maxParsedFormats, so that files with many formats can't make it grow
+// without bound.
+var parsedFormats struct {
+	sync.Mutex
+	formats map[string]*numberFormat
+}
+
+// maxParsedFormats is the most parsed number formats cached.
+const maxParsedFormats = 1024
+
+// parseNumberFormat parses an Excel number format code, such as
+// `#,##0.00;[Red](#,##0.00)`.
+func parseNumberFormat(code string) *numberFormat {
+	parsedFormats.Lock()
+	format, ok := parsedFormats.formats[code]
+	parsedFormats.Unlock()
+	if ok {
+		return format
+	}
+	format = &numberFormat{}
+	for _, section := range splitFormatSections(code) {
+		format.sections = append(format.sections, parseFormatSection(section))
+	}
+	parsedFormats.Lock()
+	if parsedFormats.formats == nil || len(parsedFormats.formats) >= maxParsedFormats {
+		parsedFormats.formats = make(map[string]*numberFormat)
+	}
+	parsedFormats.formats[code] = format
+	parsedFormats.Unlock()
+	return format
+}
+
+// splitFormatSections splits a number format at the semicolons that
+// aren't quoted or escaped.
+func splitFormatSections(code string) []string {
+	var sections []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(code); i++ {
+		switch code[i] {
+		case '"':
+			quoted = !quoted
+		case '\\':
+			if !quoted {
+				i++
+			}
+		case ';':
+			if !quoted {
+				sections = append(sections, code[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(sections, code[start:])
+}
+
+// The colors a section of a number format can be displayed in.
+var formatColors = map[string]bool{
+	"black": true, "blue": true, "cyan": true, "green": true,
+	"magenta": true, "red": true, "white": true, "yellow": true,
+}
+
+// parseFormatSection parses a section of a number format into tokens.
+func parseFormatSection(code string) *formatSection {
+	section := &formatSection{}
+	add := func(kind formatTokenKind, text string) {
+		if n := len(section.tokens); kind == tokenLiteral && n > 0 && section.tokens[n-1].kind == tokenLiteral {
+			section.tokens[n-1].text += text
+			return
+		}
+		section.tokens = append(section.tokens, formatToken{kind: kind, text: text})
+	}
+	lower := asciiLower(code)
+	for i := 0; i < len(code); {
+		r, size := utf8.DecodeRuneInString(code[i:])
+		switch {
+		case r == '"':
+			end := strings.IndexByte(code[i+1:], '"')
+			if end < 0 {
+				end = len(code) - i - 1
+			}
+			if quoted := code[i+1 : i+1+end]; quoted == "$" {
+				add(tokenCurrency, "$")
+			} else {
+				add(tokenLiteral, quoted)
+			}
+			i += end + 2
+			continue
+		case r == '\\' || r == '_' || r == '*':
+			if i+1 < len(code) {
+				next, nextSize := utf8.DecodeRuneInString(code[i+1:])
+				// _ leaves space as wide as the next character,
+				// and * repeats it to fill the cell, which leaves
+				// nothing in text.
+				switch r {
+				case '\\':
+					if next == '$' {
+						add(tokenCurrency, "$")
+					} else {
+						add(tokenLiteral, string(next))
+					}
+				case '_':
+					add(tokenLiteral, " ")
+				}
+				size += nextSize
+			}
+		case r == '[':
+			end := strings.IndexByte(code[i:], ']')
+			if end < 0 {
+				add(tokenLiteral, code[i:])
+				i = len(code)
+				continue
+			}
+			section.bracket(code[i+1:i+end], add)
+			size = end + 1
+		case strings.HasPrefix(lower[i:], "general"):
+			add(tokenGeneral, "")
+			size = len("general")
+		case r == '0' || r == '#' || r == '?':
+			add(tokenDigit, string(r))
+		case r == '.':
+			add(tokenPoint, ".")
+		case r == ',':
+			add(tokenComma, ",")
+		case r == '%':
+			add(tokenPercent, "%")
+		case (r == 'E' || r == 'e') && i+1 < len(code) && (code[i+1] == '+' || code[i+1] == '-'):
+			add(tokenExponent, code[i:i+2])
+			size = 2
+		case r == '/':
+			add(tokenSlash, "/")
+		case r == '@':
+			add(tokenText, "")
+		case r == '$':
+			add(tokenCurrency, "$")
+		case strings.HasPrefix(lower[i:], "am/pm"):
+			add(tokenDate, "am/pm")
+			size = len("am/pm")
+		case strings.HasPrefix(lower[i:], "a/p"):
+			add(tokenDate, code[i:i+3])
+			size = len("a/p")
+		case strings.ContainsRune("ymdhse", unicode.ToLower(r)):
+			letter := lower[i]
+			n := 1
+			for i+n < len(lower) && lower[i+n] == letter {
+				n++
+			}
+			if letter == 'e' {
+				// The era year, which is the year in the Gregorian
+				// calendar.
+				add(tokenDate, "yyyy")
+			} else {
+				add(tokenDate, lower[i:i+n])
+			}
+			size = n
+		default:
+			add(tokenLiteral, string(r))
+		}
+		i += size
+	}
+	section.resolve()
+	return section
+}
+
+// bracket handles the content of a pair of square brackets in the
+// section: a color, a condition, an elapsed time, or a currency symbol
+// and locale.
+func (s *formatSection) bracket(content string, add func(formatTokenKind, string)) {
+	lower := strings.ToLower(content)
+	switch {
+	case strings.HasPrefix(content, "$"):
+		symbol := content[1:]
+		if dash := strings.IndexByte(symbol, '-'); dash >= 0 {
+			symbol = symbol[:dash]
+		}
+		if symbol != "" {
+			add(tokenLiteral, symbol)
+		}
+	case strings.IndexAny(content, "<>=") == 0:
+		op := content[:1]
+		if len(content) > 1 && strings.IndexByte("<>=", content[1]) >= 0 {
+			op = content[:2]
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(content[len(op):]), 64)
+		if err == nil {
+			s.condition = &formatCondition{op: op, value: value}
+		}
+	case lower != "" && strings.Trim(lower, string(lower[0])) == "" && strings.IndexByte("hms", lower[0]) >= 0:
+		add(tokenElapsed, lower)
+	case formatColors[lower] || strings.HasPrefix(lower, "color"):
+		s.color = content
+	}
+}
+
+// resolve works out what the tokens of the section mean from the
+// tokens around them, once they are all parsed.
+func (s *formatSection) resolve() {
+	for _, token := range s.tokens {
+		switch token.kind {
+		case tokenDate, tokenElapsed:
+			s.isDate = true
+		case tokenText:
+			s.hasText = true
+		case tokenDigit, tokenGeneral:
+			s.hasDigits = true
+		}
+	}
+	if s.isDate {
+		s.resolveDate()
+	} else {
+		s.resolveNumber()
+	}
+}
+
+// resolveDate resolves the tokens of a section displaying dates: which
+// m's are minutes rather than months, and which digits after a point
+// are fractions of a second.
+func (s *formatSection) resolveDate() {
+	tokens := s.tokens[:0]
+	for i := 0; i < len(s.tokens); i++ {
+		token := s.tokens[i]
+		switch token.kind {
+		case tokenPoint:
+			n := 0
+			for i+1+n < len(s.tokens) && s.tokens[i+1+n].kind == tokenDigit && s.tokens[i+1+n].text == "0" {
+				n++
+			}
+			if n > 0 {
+				tokens = append(tokens, formatToken{kind: tokenSubsecond, text: strconv.Itoa(n)})
+				i += n
+				continue
+			}
+			token.kind = tokenLiteral
+		case tokenDate:
+			if token.text == "am/pm" || strings.ToLower(token.text) == "a/p" {
+				s.ampm = true
+			}
+		case tokenDigit, tokenComma, tokenPercent, tokenSlash, tokenExponent, tokenCurrency:
+			token.kind = tokenLiteral
+		}
+		tokens = append(tokens, token)
+	}
+	s.tokens = tokens
+	s.hasDigits = false
+	// An m or mm is minutes if it follows hours or comes before
+	// seconds.
+	for i, token := range s.tokens {
+		if token.kind != tokenDate || (token.text != "m" && token.text != "mm") {
+			continue
+		}
+		if prev := s.dateNeighbour(i, -1); prev != nil && prev.text[0] == 'h' {
+			s.tokens[i].kind = tokenElapsed
+		} else if next := s.dateNeighbour(i, 1); next != nil && next.text[0] == 's' {
+			s.tokens[i].kind = tokenElapsed
+		}
+		if s.tokens[i].kind == tokenElapsed {
+			// Minutes of the hour, rather than elapsed.
+			s.tokens[i] = formatToken{kind: tokenDate, text: "n" + token.text[1:]}
+		}
+	}
+}
+
+// dateNeighbour returns the nearest date or time token before or after
+// the token i, as step is -1 or 1, or nil if there is none.
+func (s *formatSection) dateNeighbour(i, step int) *formatToken {
+	for j := i + step; j >= 0 && j < len(s.tokens); j += step {
+		if kind := s.tokens[j].kind; kind == tokenDate || kind == tokenElapsed {
+			return &s.tokens[j]
+		}
+	}
+	return nil
+}
+
+// resolveNumber resolves the tokens of a section displaying numbers:
+// what each comma does, and whether a slash makes a fraction.
+func (s *formatSection) resolveNumber() {
+	for i, token := range s.tokens {
+		switch token.kind {
+		case tokenPercent:
+			s.percents++
+		case tokenComma:
+			afterDigit := i > 0 && (s.tokens[i-1].kind == tokenDigit || s.tokens[i-1].kind == tokenComma)
+			beforeDigit := false
+			for j := i + 1; j < len(s.tokens) && s.tokens[j].kind != tokenPoint; j++ {
+				if s.tokens[j].kind == tokenDigit {
+					beforeDigit = true
+					break
+				}
+				if s.tokens[j].kind != tokenComma {
+					break
+				}
+			}
+			switch {
+			case afterDigit && beforeDigit:
+				s.grouping = true
+				s.tokens[i].text = ""
+			case afterDigit:
+				s.scale++
+				s.tokens[i].text = ""
+			default:
+				s.tokens[i].kind = tokenLiteral
+			}
+		}
+	}
+	s.resolveFraction()
+}
+
+// resolveFraction makes the first slash after a digit placeholder that
+// of a fraction, and works out whether its denominator is fixed, as
+// the 8 of ?/8 is.
+func (s *formatSection) resolveFraction() {
+	slash := -1
+	for i, token := range s.tokens {
+		if token.kind != tokenSlash {
+			continue
+		}
+		if slash >= 0 || i == 0 || s.tokens[i-1].kind != tokenDigit {
+			s.tokens[i].kind = tokenLiteral
+			continue
+		}
+		slash = i
+	}
+	if slash < 0 {
+		return
+	}
+	s.fraction = true
+	digits := ""
+	rest := slash + 1
+	for ; rest < len(s.tokens); rest++ {
+		token := s.tokens[rest]
+		if token.kind == tokenDigit && token.text == "0" && digits != "" {
+			digits += "0"
+			continue
+		}
+		if token.kind != tokenLiteral {
+			break
+		}
+		n := 0
+		for n < len(token.text) && token.text[n] >= '0' && token.text[n] <= '9' && (digits != "" || n > 0 || token.text[n] != '0') {
+			n++
+		}
+		if n == 0 {
+			break
+		}
+		digits += token.text[:n]
+		if n < len(token.text) {
+			s.tokens[rest].text = token.text[n:]
+			break
+		}
+	}
+	if digits != "" {
+		s.denominator, _ = strconv.Atoi(digits)
+		s.tokens = append(s.tokens[:slash+1], s.tokens[rest:]...)
+	}
+	// Digit placeholders after the denominator have no digits to
+	// show, so they are displayed as they are.
+	end := slash + 1
+	for s.denominator == 0 && end < len(s.tokens) && s.tokens[end].kind == tokenDigit {
+		end++
+	}
+	for i := end; i < len(s.tokens); i++ {
+		if s.tokens[i].kind == tokenDigit {
+			s.tokens[i].kind = tokenLiteral
+		}
+	}
+}
+
+// hasDateParts reports whether the number format displays a date,
+// rather than only a time of day or a number, so that its serial
+// number depends on the date system.
+func hasDateParts(code string) bool {
+	for _, section := range parseNumberFormat(code).sections {
+		for _, token := range section.tokens {
+			if token.kind == tokenDate && strings.IndexByte("ymd", token.text[0]) >= 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isGeneral reports whether the number format is just General.
+func (nf *numberFormat) isGeneral() bool {
+	if len(nf.sections) != 1 {
+		return false
+	}
+	tokens := nf.sections[0].tokens
+	return len(tokens) == 0 || len(tokens) == 1 && tokens[0].kind == tokenGeneral
+}
+
+// numberSection returns the section of the number format that displays
+// v, and whether that section displays its absolute value, as the
+// section for negative numbers does.
+func (nf *numberFormat) numberSection(v float64) (*formatSection, bool) {
+	sections := nf.sections
+	if len(sections) > 3 {
+		sections = sections[:3]
+	}
+	conditional := sections
+	if len(conditional) > 2 {
+		conditional = conditional[:2]
+	}
+	if conditional[0].condition != nil || len(conditional) > 1 && conditional[1].condition != nil {
+		for _, section := range conditional {
+			if section.condition != nil && section.condition.holds(v) {
+				return section, false
+			}
+		}
+		for _, section := range sections {
+			if section.condition == nil {
+				return section, false
+			}
+		}
+		return nil, false
+	}
+	switch {
+	case v < 0 && len(sections) > 1:
+		return sections[1], true
+	case v == 0 && len(sections) > 2:
+		return sections[2], false
+	}
+	return sections[0], false
+}
+
+// textSection returns the section of the number format that displays
+// text, or nil if there is none.
+func (nf *numberFormat) textSection() *formatSection {
+	if len(nf.sections) > 3 {
+		return nf.sections[3]
+	}
+	if len(nf.sections) == 1 && nf.sections[0].hasText {
+		return nf.sections[0]
+	}
+	return nil
+}
+
+// formatValue displays the value of the cell in its number format, for
+// the locale.
+func (c *Cell) formatValue(locale *Locale) (string, error) {
+	if locale == nil {
+		locale = LocaleEnUS
+	}
+	format := parseNumberFormat(c.GetNumberFormat())
+	v, err := strconv.ParseFloat(c.Value, 64)
+	// ParseFloat reads NaN and Inf too, which are text to Excel.
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		if section := format.textSection(); section != nil {
+			return section.formatText(c.Value, locale), nil
+		}
+		if format.isGeneral() || err == nil {
+			return c.Value, nil
+		}
+		return c.Value, err
+	}
+	if format.isGeneral() {
+		return strings.Replace(c.Value, ".", locale.DecimalSeparator, 1), nil
+	}
+	section, abs := format.numberSection(v)
+	switch {
+	case section == nil:
+		// No section's condition holds, which Excel shows as a
+		// cell too narrow for the number.
+		return "####", nil
+	case section.isDate:
+		return section.formatDate(v, c.inDate1904(), c.datePolicy(), locale), nil
+	case section.hasText && !section.hasDigits:
+		return section.formatText(formatGeneral(v, locale), locale), nil
+	}
+	if abs {
+		v = math.Abs(v)
+	}
+	return section.formatNumber(v, locale), nil
+}
+
+// formatText displays text in the section.
+func (s *formatSection) formatText(text string, locale *Locale) string {
+	var b strings.Builder
+	for _, token := range s.tokens {
+		switch token.kind {
+		case tokenText:
+			b.WriteString(text)
+		case tokenCurrency:
+			b.WriteString(locale.CurrencySymbol)
+		case tokenLiteral, tokenDigit:
+			b.WriteString(token.text)
+		}
+	}
+	return b.String()
+}
+
+// formatGeneral displays v in the General format.
+func formatGeneral(v float64, locale *Locale) string {
+	formatted := strconv.FormatFloat(v, 'f', -1, 64)
+	if abs := math.Abs(v); abs >= 1e15 || abs != 0 && abs < 1e-9 {
+		formatted = strings.ToUpper(strconv.FormatFloat(v, 'e', -1, 64))
+	}
+	return strings.Replace(formatted, ".", locale.DecimalSeparator, 1)
+}
+
+// roundDecimal rounds v, which isn't negative, to places decimal
+// places, as Excel does: to the 15 significant digits it keeps, and
+// then half away from zero.  It returns the digits of the integer part,
+// which are "" if it is 0, and the places digits of the fractional
+// part.
+func roundDecimal(v float64, places int) (string, string) {
+	if v == 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+		return "", strings.Repeat("0", places)
+	}
+	scientific := strconv.FormatFloat(v, 'e', 14, 64)
+	mantissa := scientific[:strings.IndexByte(scientific, 'e')]
+	exponent, _ := strconv.Atoi(scientific[len(mantissa)+1:])
+	digits := []byte(strings.Replace(mantissa, ".", "", 1))
+	// point is the number of digits before the decimal point.
+	point := exponent + 1
+	if point < 0 {
+		digits = append([]byte(strings.Repeat("0", -point)), digits...)
+		point = 0
+	}
+	keep := point + places
+	for len(digits) < keep {
+		digits = append(digits, '0')
+	}
+	if keep < len(digits) {
+		up := digits[keep] >= '5'
+		digits = digits[:keep]
+		for i := keep - 1; up && i >= 0; i-- {
+			if digits[i] == '9' {
+				digits[i] = '0'
+				continue
+			}
+			digits[i]++
+			up = false
+		}
+		if up {
+			digits = append([]byte{'1'}, digits...)
+			point++
+		}
+	}
+	integer := strings.TrimLeft(string(digits[:point]), "0")
+	return integer, string(digits[point:])
+}
+
+// formatNumber displays v, with its sign, in the section.
+func (s *formatSection) formatNumber(v float64, locale *Locale) string {
+	negative := v < 0
+	v = math.Abs(v) * math.Pow(100, float64(s.percents)) / math.Pow(1000, float64(s.scale))
+	var body string
+	if s.fraction {
+		body = s.formatFraction(v, locale)
+	} else {
+		body = s.formatDecimal(v, locale)
+	}
+	if negative && s.hasDigits {
+		return "-" + body
+	}
+	return body
+}
+
+// placeholders splits the digit placeholders of the section into those
+// of the integer part, the fractional part and the exponent.
+func (s *formatSection) placeholders() (integer, fraction, exponent []string) {
+	part := &integer
+	for _, token := range s.tokens {
+		switch token.kind {
+		case tokenDigit:
+			*part = append(*part, token.text)
+		case tokenPoint:
+			if part == &integer {
+				part = &fraction
+			}
+		case tokenExponent:
+			part = &exponent
+		}
+	}
+	return integer, fraction, exponent
+}
+
+// fillIntegerDigits spreads the digits of an integer over its
+// placeholders, from the right, with any digits left over going to the
+// first placeholder, and those placeholders left over padded as their
+// kind says.  Each digit is followed by the thousands separator if
+// grouping is set and it ends a group of thousands.
+func fillIntegerDigits(digits string, placeholders []string, grouping bool, separator string) []string {
+	filled := make([]string, len(placeholders))
+	position := 0
+	for i := len(placeholders) - 1; i >= 0; i-- {
+		take := 1
+		if i == 0 {
+			take = len(digits)
+		}
+		var part strings.Builder
+		var chars string
+		switch {
+		case len(digits) > 0:
+			if take > len(digits) {
+				take = len(digits)
+			}
+			chars = digits[len(digits)-take:]
+			digits = digits[:len(digits)-take]
+		case placeholders[i] == "0":
+			chars = "0"
+		case placeholders[i] == "?":
+			filled[i] = " "
+			continue
+		}
+		for j := 0; j < len(chars); j++ {
+			part.WriteByte(chars[j])
+			if grouping && position+len(chars)-1-j > 0 && (position+len(chars)-1-j)%3 == 0 {
+				part.WriteString(separator)
+			}
+		}
+		position += len(chars)
+		filled[i] = part.String()
+	}
+	return filled
+}
+
+// formatDecimal displays v, which isn't negative, as a decimal number,
+// in scientific notation if the section has an exponent.
+func (s *formatSection) formatDecimal(v float64, locale *Locale) string {
+	intPlaces, fracPlaces, expPlaces := s.placeholders()
+	hasExponent := false
+	for _, token := range s.tokens {
+		if token.kind == tokenExponent {
+			hasExponent = true
+		}
+	}
+	exponent := 0
+	if hasExponent && v != 0 {
+		exponent = int(math.Floor(math.Log10(v)))
+		n := len(intPlaces)
+		if n < 1 {
+			n = 1
+		}
+		if strings.Contains(strings.Join(intPlaces, ""), "#") {
+			// Engineering notation, with the exponent a multiple
+			// of the number of integer places.
+			exponent = int(math.Floor(float64(exponent)/float64(n))) * n
+		} else {
+			exponent -= n - 1
+		}
+		v /= math.Pow(10, float64(exponent))
+	}
+	intDigits, fracDigits := roundDecimal(v, len(fracPlaces))
+	if hasExponent && len(intDigits) > len(intPlaces) && len(intPlaces) > 0 && !strings.Contains(strings.Join(intPlaces, ""), "#") {
+		// Rounding carried into another digit.
+		exponent++
+		intDigits, fracDigits = roundDecimal(v/10, len(fracPlaces))
+	}
+	filled := fillIntegerDigits(intDigits, intPlaces, s.grouping, locale.ThousandsSeparator)
+
+	// The placeholders of the fractional part show trailing zeros
+	// only if they are 0's, or as spaces if they are ?'s.
+	fracShown := []byte(fracDigits)
+	trailing := len(fracShown)
+	for trailing > 0 && fracShown[trailing-1] == '0' && fracPlaces[trailing-1] != "0" {
+		trailing--
+	}
+
+	var b strings.Builder
+	pendingCurrency := false
+	started := false
+	intIndex, fracIndex, expIndex := 0, 0, 0
+	inFraction, inExponent := false, false
+	for _, token := range s.tokens {
+		switch token.kind {
+		case tokenDigit, tokenPoint, tokenComma, tokenPercent, tokenExponent, tokenGeneral:
+			started = true
+		default:
+			if pendingCurrency && started {
+				b.WriteString("\u00a0" + locale.CurrencySymbol)
+				pendingCurrency = false
+			}
+		}
+		switch token.kind {
+		case tokenLiteral:
+			b.WriteString(token.text)
+		case tokenCurrency:
+			if locale.CurrencyAfter && !started {
+				pendingCurrency = true
+			} else {
+				b.WriteString(locale.CurrencySymbol)
+			}
+		case tokenGeneral:
+			b.WriteString(formatGeneral(v, locale))
+		case tokenPercent:
+			b.WriteString("%")
+		case tokenPoint:
+			if inFraction || inExponent {
+				continue
+			}
+			if len(intPlaces) == 0 {
+				b.WriteString(intDigits)
+			}
+			inFraction = true
+			b.WriteString(locale.DecimalSeparator)
+		case tokenExponent:
+			if !inFraction && len(intPlaces) == 0 {
+				b.WriteString(intDigits)
+			}
+			inExponent = true
+			b.WriteByte(token.text[0])
+			if exponent < 0 {
+				b.WriteString("-")
+			} else if token.text[1] == '+' {
+				b.WriteString("+")
+			}
+			expDigits := strconv.Itoa(absInt(exponent))
+			for len(expDigits) < len(expPlaces) {
+				expDigits = "0" + expDigits
+			}
+			b.WriteString(expDigits)
+		case tokenDigit:
+			switch {
+			case inExponent:
+				expIndex++
+			case inFraction:
+				if fracIndex < trailing {
+					b.WriteByte(fracShown[fracIndex])
+				} else if token.text == "?" {
+					b.WriteString(" ")
+				}
+				fracIndex++
+			default:
+				b.WriteString(filled[intIndex])
+				intIndex++
+			}
+		}
+	}
+	if pendingCurrency {
+		b.WriteString("\u00a0" + locale.CurrencySymbol)
+	}
+	if len(intPlaces) == 0 && !inFraction && !inExponent && !s.hasGeneral() && s.hasDigits {
+		return intDigits + b.String()
+	}
+	return b.String()
+}
+
+// hasGeneral reports whether the section has General in it.
+func (s *formatSection) hasGeneral() bool {
+	for _, token := range s.tokens {
+		if token.kind == tokenGeneral {
+			return true
+		}
+	}
+	return false
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// formatFraction displays v, which isn't negative, as a fraction, with
+// a whole number before it if the section has one.
+func (s *formatSection) formatFraction(v float64, locale *Locale) string {
+	slash := 0
+	for i, token := range s.tokens {
+		if token.kind == tokenSlash {
+			slash = i
+		}
+	}
+	// The placeholders of the whole number, the numerator and the
+	// denominator.
+	var whole, numer, denom []string
+	numerStart := slash
+	for numerStart > 0 && s.tokens[numerStart-1].kind == tokenDigit {
+		numerStart--
+		numer = append([]string{s.tokens[numerStart].text}, numer...)
+	}
+	for _, token := range s.tokens[:numerStart] {
+		if token.kind == tokenDigit {
+			whole = append(whole, token.text)
+		}
+	}
+	denomEnd := slash + 1
+	if s.denominator == 0 {
+		for denomEnd < len(s.tokens) && s.tokens[denomEnd].kind == tokenDigit {
+			denom = append(denom, s.tokens[denomEnd].text)
+			denomEnd++
+		}
+	}
+
+	integer := 0.0
+	if len(whole) > 0 {
+		integer = math.Floor(v)
+	}
+	var n, d int
+	if s.denominator > 0 {
+		d = s.denominator
+		n = int(math.Round((v - integer) * float64(d)))
+	} else {
+		places := len(denom)
+		if places > maxDenominatorDigits {
+			places = maxDenominatorDigits
+		}
+		maxDenominator := int(math.Pow(10, float64(places))) - 1
+		n, d = approximateFraction(v-integer, maxDenominator)
+	}
+	if len(whole) > 0 && n == d {
+		integer++
+		n = 0
+	}
+	wholeDigits, _ := roundDecimal(integer, 0)
+	if wholeDigits == "" && n == 0 {
+		wholeDigits = "0"
+	}
+	filled := fillIntegerDigits(wholeDigits, whole, s.grouping, locale.ThousandsSeparator)
+	// A mixed number with no fraction is displayed with spaces in
+	// place of the fraction.
+	blank := n == 0 && len(whole) > 0
+	denomDigits := strconv.Itoa(d)
+	if s.denominator > 0 {
+		denom = make([]string, len(denomDigits))
+	}
+
+	var b strings.Builder
+	wholeIndex := 0
+	for i, token := range s.tokens {
+		switch {
+		case i == numerStart:
+			b.WriteString(padDigits(strconv.Itoa(n), numer, true, blank))
+		case i > numerStart && i < slash:
+		case i == slash:
+			if blank {
+				b.WriteString(" ")
+			} else {
+				b.WriteString("/")
+			}
+			b.WriteString(padDigits(denomDigits, denom, false, blank))
+		case i > slash && i < denomEnd:
+		default:
+			switch token.kind {
+			case tokenDigit:
+				b.WriteString(filled[wholeIndex])
+				wholeIndex++
+			case tokenLiteral:
+				b.WriteString(token.text)
+			case tokenPoint:
+				b.WriteString(locale.DecimalSeparator)
+			case tokenPercent:
+				b.WriteString("%")
+			case tokenCurrency:
+				b.WriteString(locale.CurrencySymbol)
+			}
+		}
+	}
+	return b.String()
+}
+
+// padDigits pads the digits of a numerator, to the left, or of a
+// denominator, to the right, to fill their placeholders, displaying
+// only the padding if blank is set.
+func padDigits(digits string, placeholders []string, left, blank bool) string {
+	if blank {
+		return strings.Repeat(" ", len(placeholders))
+	}
+	if len(placeholders) == 0 {
+		return digits
+	}
+	var padding strings.Builder
+	for i := len(digits); i < len(placeholders); i++ {
+		switch placeholders[i] {
+		case "0":
+			padding.WriteString("0")
+		case "?":
+			padding.WriteString(" ")
+		}
+	}
+	if left {
+		return padding.String() + digits
+	}
+	return digits + padding.String()
+}
+
+// maxDenominatorDigits is the most digits the denominator of a
+// fraction is worked out to, however many placeholders it has.
+const maxDenominatorDigits = 9
+
+// approximateFraction returns the fraction closest to v, which isn't
+// negative, with a denominator no greater than maxDenominator.  It is
+// either the last convergent of the continued fraction of v whose
+// denominator is small enough, or the semiconvergent between it and
+// the one before.
+func approximateFraction(v float64, maxDenominator int) (int, int) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, 1
+	}
+	if maxDenominator <= 1 || v >= float64(math.MaxInt32) {
+		return int(math.Round(v)), 1
+	}
+	// p0/q0 and p1/q1 are the last two convergents.  The first term
+	// is the integer part, which is no part of the denominator.
+	p0, q0, p1, q1 := 0, 1, 1, 0
+	for x := v; ; {
+		a := math.Floor(x)
+		if q1 > 0 && a > float64(maxDenominator) {
+			break
+		}
+		q2 := q0 + int(a)*q1
+		if q2 > maxDenominator {
+			break
+		}
+		p0, q0, p1, q1 = p1, q1, p0+int(a)*p1, q2
+		if float64(p1)/float64(q1) == v {
+			// Closer fractions can't be told apart from v.
+			return p1, q1
+		}
+		if x == a {
+			break
+		}
+		x = 1 / (x - a)
+	}
+	k := (maxDenominator - q0) / q1
+	n, d := p0+k*p1, q0+k*q1
+	if math.Abs(v-float64(n)/float64(d)) < math.Abs(v-float64(p1)/float64(q1)) {
+		return n, d
+	}
+	return p1, q1
+}
+
+// formatDate displays the serial number v as a date and time in the
+// section.
+func (s *formatSection) formatDate(v float64, date1904 bool, policy DatePolicy, locale *Locale) string {
+	// Round to the fraction of a second displayed.
+	places := 0
+	for _, token := range s.tokens {
+		if token.kind == tokenSubsecond {
+			if n, _ := strconv.Atoi(token.text); n > places {
+				places = n
+			}
+		}
+	}
+	if places > 3 {
+		places = 3
+	}
+	// The time of day is worked out from the rounded serial number
+	// in whole units, as converting it as a whole to a time.Time
+	// loses a few microseconds.
+	perSecond := int64(math.Pow(10, float64(places)))
+	perDay := 86400 * perSecond
+	units := int64(math.Round(v * float64(perDay)))
+	days, unitsOfDay := units/perDay, units%perDay
+	if unitsOfDay < 0 {
+		days--
+		unitsOfDay += perDay
+	}
+	date, _ := TimeFromExcelSerial(float64(days), date1904, policy)
+	secondsOfDay := int(unitsOfDay / perSecond)
+	t := time.Date(date.Year(), date.Month(), date.Day(),
+		secondsOfDay/3600, secondsOfDay/60%60, secondsOfDay%60,
+		int(unitsOfDay%perSecond*(1e9/perSecond)), time.UTC)
+	seconds := float64(units) / float64(perSecond)
+
+	var b strings.Builder
+	for _, token := range s.tokens {
+		switch token.kind {
+		case tokenLiteral:
+			b.WriteString(token.text)
+		case tokenElapsed:
+			var elapsed float64
+			switch token.text[0] {
+			case 'h':
+				elapsed = seconds / 3600
+			case 'm':
+				elapsed = seconds / 60
+			default:
+				elapsed = seconds
+			}
+			b.WriteString(padInt(int(math.Floor(elapsed+1e-9)), len(token.text)))
+		case tokenSubsecond:
+			n, _ := strconv.Atoi(token.text)
+			fraction := padInt(int(math.Round(float64(t.Nanosecond())/1e6)), 3)
+			if n > 3 {
+				fraction += strings.Repeat("0", n-3)
+			}
+			b.WriteString(locale.DecimalSeparator)
+			b.WriteString(fraction[:n])
+		case tokenDate:
+			b.WriteString(s.datePart(token.text, t, locale))
+		}
+	}
+	return b.String()
+}
+
+// datePart displays the part of a date or time the code says, such as
+// "yyyy" for the year.
+func (s *formatSection) datePart(code string, t time.Time, locale *Locale) string {
+	n := len(code)
+	switch code[0] {
+	case 'y':
+		if n <= 2 {
+			return padInt(t.Year()%100, 2)
+		}
+		return padInt(t.Year(), 4)
+	case 'm':
+		switch {
+		case n == 1:
+			return strconv.Itoa(int(t.Month()))
+		case n == 2:
+			return padInt(int(t.Month()), 2)
+		case n == 3:
+			return locale.ShortMonthNames[t.Month()-1]
+		case n == 5:
+			first, _ := utf8.DecodeRuneInString(locale.MonthNames[t.Month()-1])
+			return string(first)
+		}
+		return locale.MonthNames[t.Month()-1]
+	case 'n':
+		// Minutes, resolved from an m.
+		return padInt(t.Minute(), n)
+	case 'd':
+		switch {
+		case n <= 2:
+			return padInt(t.Day(), n)
+		case n == 3:
+			return locale.ShortDayNames[t.Weekday()]
+		}
+		return locale.DayNames[t.Weekday()]
+	case 'h':
+		hour := t.Hour()
+		if s.ampm {
+			hour %= 12
+			if hour == 0 {
+				hour = 12
+			}
+		}
+		return padInt(hour, n)
+	case 's':
+		return padInt(t.Second(), n)
+	case 'a', 'A':
+		if code == "am/pm" {
+			if t.Hour() < 12 {
+				return "AM"
+			}
+			return "PM"
+		}
+		// A/P is displayed in the case it is written in.
+		if t.Hour() < 12 {
+			return code[:1]
+		}
+		return code[2:]
+	}
+	return code
+}
+
+// asciiLower returns s with its ASCII letters in lower case, leaving
+// every other byte where it is.
+func asciiLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
+// padInt displays n with at least width digits.
+func padInt(n, width int) string {
+	s := strconv.Itoa(n)
+	for len(s) < width {
+		s = "0" + s
+	}
+	return s
+}
@@ -0,0 +1,162 @@
+package xlsx
+
+import (
+	"math"
+	"strconv"
+
+	. "gopkg.in/check.v1"
+)
+
+type NumberFormatSuite struct{}
+
+var _ = Suite(&NumberFormatSuite{})
+
+// checkFormats checks the value is displayed as expected in each of
+// the number formats.
+func checkFormats(c *C, value string, expected map[string]string) {
+	for numFmt, display := range expected {
+		cell := Cell{Value: value, NumFmt: numFmt}
+		formatted, err := cell.FormattedValue()
+		c.Assert(err, IsNil)
+		c.Assert(formatted, Equals, display, Commentf("%s in %s", value, numFmt))
+	}
+}
+
+func (s *NumberFormatSuite) TestSections(c *C) {
+	const format = `0.00;[Red]-0.00;"zero";"text: "@`
+	checkFormats(c, "1.5", map[string]string{format: "1.50", "0;(0)": "2", "0;;": "2"})
+	checkFormats(c, "-1.5", map[string]string{format: "-1.50", "0;(0)": "(2)", "0;;": ""})
+	checkFormats(c, "0", map[string]string{format: "zero", "0;(0)": "0", "0;;": ""})
+	checkFormats(c, "pending", map[string]string{format: "text: pending", "@": "pending", `"["@"]"`: "[pending]"})
+
+	cell := Cell{Value: "pending", NumFmt: "0.00"}
+	value, err := cell.FormattedValue()
+	c.Assert(value, Equals, "pending")
+	c.Assert(err, NotNil)
+
+	// NaN and Inf are text, not numbers.
+	checkFormats(c, "NaN", map[string]string{"#,##0;(#,##0)": "NaN", format: "text: NaN", "0.00": "NaN"})
+	checkFormats(c, "-Inf", map[string]string{"#,##0;(#,##0)": "-Inf", "0;0;0": "-Inf"})
+	cell = Cell{}
+	cell.SetString("NaN")
+	cell.NumFmt = "#,##0;(#,##0)"
+	value, err = cell.String()
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "NaN")
+}
+
+func (s *NumberFormatSuite) TestConditions(c *C) {
+	const format = `[>=1000]#,##0,"K";[<0]"minus "0;0`
+	checkFormats(c, "25000", map[string]string{format: "25K"})
+	checkFormats(c, "-5", map[string]string{format: "-minus 5"})
+	checkFormats(c, "5", map[string]string{format: "5", "[<0]0": "####"})
+}
+
+func (s *NumberFormatSuite) TestNumbers(c *C) {
+	checkFormats(c, "1234567.891", map[string]string{
+		"#,##0.00":         "1,234,567.89",
+		"0.0,,\"M\"":       "1.2M",
+		"#.###":            "1234567.891",
+		"00000000.0000":    "01234567.8910",
+		"#,###.##?":        "1,234,567.891",
+		`\$#,##0_);(\$0)`:  "$1,234,568 ",
+		"0.00E+00":         "1.23E+06",
+		"##0.0E+0":         "1.2E+6",
+		"000-00-0000":      "001-23-4568",
+		"General;-General": "1234567.891",
+		"0%":               "123456789%",
+	})
+	// Halves round away from zero, at the fifteen digits Excel keeps.
+	checkFormats(c, "2.5", map[string]string{"0": "3"})
+	checkFormats(c, "1.005", map[string]string{"0.00": "1.01"})
+	checkFormats(c, "0.5", map[string]string{"#.00": ".50", "#": "1", "0.0E+00": "5.0E-01"})
+	checkFormats(c, "9.99", map[string]string{"0.0E+00": "1.0E+01"})
+}
+
+func (s *NumberFormatSuite) TestFractions(c *C) {
+	checkFormats(c, "3.25", map[string]string{
+		"# ?/?":   "3 1/4",
+		"# ??/??": "3  1/4 ",
+		"?/?":     "13/4",
+		"# ?/8":   "3 2/8",
+		"0 ?/100": "3 25/100",
+	})
+	checkFormats(c, "0.3333", map[string]string{"# ??/??": "  1/3 ", "# ???/???": "   1/3  "})
+	checkFormats(c, "5", map[string]string{"# ?/?": "5    "})
+	checkFormats(c, "-1.5", map[string]string{"# ?/?": "-1 1/2"})
+	// Placeholders after the denominator are displayed as they are.
+	checkFormats(c, "3.25", map[string]string{
+		"0/4.0":    "13/4.0",
+		"# ?/?.0":  "3 1/4.0",
+		"#/100.#":  "325/100.#",
+		"# ?/? ##": "3 1/4 ##",
+	})
+	// Denominators are found without trying each one in turn, and
+	// stop growing once the fraction equals the number.
+	checkFormats(c, "3.14159265358979", map[string]string{
+		"?/????????":     "144029661/45846065",
+		"# ?/????????":   "3 6491466/45846065",
+		"?/????????????": "144029661/45846065    ",
+	})
+	// Integer parts too big to be denominators need no fraction.
+	checkFormats(c, "10", map[string]string{"?/?": "10/1"})
+	checkFormats(c, "12.5", map[string]string{"?/?": "25/2"})
+	checkFormats(c, "100", map[string]string{"?/?": "100/1", "??/??": "100/1 "})
+	checkFormats(c, "-693594", map[string]string{"0/0": "-693594/1"})
+	checkFormats(c, "1.5", map[string]string{"#/": "2/1"})
+	checkFormats(c, "Inf", map[string]string{"# ?/?": "Inf"})
+	checkFormats(c, "Infinity", map[string]string{"# ?/?": "Infinity"})
+}
+
+func (s *NumberFormatSuite) TestParsedFormatsBounded(c *C) {
+	for i := 0; i <= maxParsedFormats; i++ {
+		parseNumberFormat(strconv.Itoa(i) + `" items"`)
+	}
+	parsedFormats.Lock()
+	defer parsedFormats.Unlock()
+	c.Assert(len(parsedFormats.formats) <= maxParsedFormats, Equals, true)
+}
+
+func (s *NumberFormatSuite) TestApproximateFraction(c *C) {
+	for _, t := range []struct {
+		v         float64
+		max, n, d int
+	}{
+		{0.25, 9, 1, 4},
+		{0.3333, 99, 1, 3},
+		{0.3333, 9999, 3332, 9997},
+		{math.Pi, 9999999, 5419351, 1725033},
+		{math.Pi - 3, 99999, 14093, 99532},
+		{math.Pi - 3, 999, 16, 113},
+		{0.999, 9, 1, 1},
+		{0.001, 9, 0, 1},
+		{0.5, 1, 1, 1},
+		{1.5, 1, 2, 1},
+		{10, 9, 10, 1},
+		{12.5, 9, 25, 2},
+		{100, 99, 100, 1},
+		{693594, 9, 693594, 1},
+		{math.NaN(), 9, 0, 1},
+		{math.Inf(1), 9, 0, 1},
+	} {
+		n, d := approximateFraction(t.v, t.max)
+		c.Assert([]int{n, d}, DeepEquals, []int{t.n, t.d}, Commentf("%v within %d", t.v, t.max))
+	}
+}
+
+func (s *NumberFormatSuite) TestDates(c *C) {
+	// 14 March 2017, 13:05:09.25.
+	checkFormats(c, "42808.5452459491", map[string]string{
+		"yyyy-mm-dd hh:mm:ss.00": "2017-03-14 13:05:09.25",
+		"d mmm yy":               "14 Mar 17",
+		"mmmmm":                  "M",
+		"ddd dddd":               "Tue Tuesday",
+		"h:mm AM/PM":             "1:05 PM",
+		"h a/p":                  "1 p",
+		"[$-409]m/d/yyyy":        "3/14/2017",
+		`"at" h"h"mm`:            "at 13h05",
+		"mm:ss":                  "05:09",
+	})
+	// Elapsed times run on past a day.
+	checkFormats(c, "1.5", map[string]string{"[h]:mm": "36:00", "[mm]:ss": "2160:00", "[ss]": "129600"})
+}
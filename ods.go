@@ -0,0 +1,595 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// odsMimeType is the media type of OpenDocument spreadsheets, which
+// starts the package, uncompressed, so that it can be recognised.
+const odsMimeType = "application/vnd.oasis.opendocument.spreadsheet"
+
+const odsNamespaces = ` xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0"` +
+	` xmlns:style="urn:oasis:names:tc:opendocument:xmlns:style:1.0"` +
+	` xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0"` +
+	` xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0"` +
+	` xmlns:fo="urn:oasis:names:tc:opendocument:xmlns:xsl-fo-compatible:1.0"` +
+	` xmlns:number="urn:oasis:names:tc:opendocument:xmlns:datastyle:1.0"`
+
+const odsManifest = xmlHeader + `<manifest:manifest xmlns:manifest="urn:oasis:names:tc:opendocument:xmlns:manifest:1.0" manifest:version="1.2">` +
+	`<manifest:file-entry manifest:full-path="/" manifest:version="1.2" manifest:media-type="` + odsMimeType + `"/>` +
+	`<manifest:file-entry manifest:full-path="content.xml" manifest:media-type="text/xml"/>` +
+	`</manifest:manifest>`
+
+// SaveODS saves the File as an OpenDocument spreadsheet at the
+// provided path, as WriteODS writes it.
+func (f *File) SaveODS(path string) error {
+	target, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := f.WriteODS(target); err != nil {
+		target.Close()
+		return err
+	}
+	return target.Close()
+}
+
+// WriteODS writes the File to writer as an OpenDocument spreadsheet,
+// as LibreOffice and other OpenDocument applications open.  The values
+// of cells are written with their number formats, fonts, fills and
+// horizontal alignment, along with merged cells, the widths of columns,
+// the heights of rows and which sheets, rows and columns are hidden.
+// Number formats with more than one section are written as their
+// first.  Cells with formulas are written with the values they were
+// last worked out to, without their formulas.
+func (f *File) WriteODS(writer io.Writer) error {
+	styles := newODSStyles()
+	var body bytes.Buffer
+	x := newXMLWriter(&body)
+	for _, sheet := range f.Sheets {
+		if err := sheet.Load(); err != nil {
+			return err
+		}
+		styles.writeTable(x, sheet)
+	}
+	if err := x.flush(); err != nil {
+		return err
+	}
+
+	zipWriter := zip.NewWriter(writer)
+	// The media type must have no extra fields, which giving its time
+	// as Modified would add, so it is given as MS-DOS does: partModified
+	// is 1 January 1980.
+	mimeType, err := zipWriter.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store, ModifiedDate: 1<<5 | 1})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(mimeType, odsMimeType); err != nil {
+		return err
+	}
+	for _, name := range []string{"META-INF/manifest.xml", "content.xml"} {
+		w, err := zipWriter.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: partModified})
+		if err != nil {
+			return err
+		}
+		if name == "META-INF/manifest.xml" {
+			_, err = io.WriteString(w, odsManifest)
+		} else {
+			_, err = io.WriteString(w, xmlHeader+`<office:document-content`+odsNamespaces+` office:version="1.2"><office:automatic-styles>`+
+				styles.xml.String()+`</office:automatic-styles><office:body><office:spreadsheet>`)
+			if err == nil {
+				_, err = w.Write(body.Bytes())
+			}
+			if err == nil {
+				_, err = io.WriteString(w, `</office:spreadsheet></office:body></office:document-content>`)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return zipWriter.Close()
+}
+
+// odsStyles are the automatic styles of an OpenDocument spreadsheet
+// being written, made as the cells, rows and columns that use them are
+// written, each once.
+type odsStyles struct {
+	xml   strings.Builder
+	names map[string]string
+	// counts are how many styles have each prefix.
+	counts map[string]int
+	// kinds are the kinds of value, such as "date", the data style
+	// of each number format displays.
+	kinds map[string]string
+}
+
+func newODSStyles() *odsStyles {
+	s := &odsStyles{names: make(map[string]string), counts: make(map[string]int), kinds: make(map[string]string)}
+	s.xml.WriteString(`<style:style style:name="ta1" style:family="table"><style:table-properties table:display="true"/></style:style>`)
+	s.xml.WriteString(`<style:style style:name="ta2" style:family="table"><style:table-properties table:display="false"/></style:style>`)
+	s.counts["ta"] = 2
+	return s
+}
+
+// style returns the name of the automatic style made by the given
+// content, which has prefix followed by a number, adding it if it is
+// new.
+func (s *odsStyles) style(prefix, content string) string {
+	key := prefix + content
+	if name, ok := s.names[key]; ok {
+		return name
+	}
+	s.counts[prefix]++
+	name := fmt.Sprintf("%s%d", prefix, s.counts[prefix])
+	s.names[key] = name
+	s.xml.WriteString(strings.Replace(content, `style:name=""`, `style:name="`+name+`"`, 1))
+	return name
+}
+
+// dataStyle returns the name of the data style displaying numbers as
+// the number format code does, and the kind of value it displays, or
+// "" for General.
+func (s *odsStyles) dataStyle(code string) (string, string) {
+	key := "N" + code
+	if name, ok := s.names[key]; ok {
+		return name, s.kinds[name]
+	}
+	format := parseNumberFormat(code)
+	if format.isGeneral() || len(format.sections) == 0 {
+		return "", ""
+	}
+	element, attrs, kind, content := odsDataStyle(format.sections[0])
+	if element == "" {
+		return "", ""
+	}
+	name := s.style("N", `<number:`+element+` style:name=""`+attrs+`>`+content+`</number:`+element+`>`)
+	s.names[key] = name
+	s.kinds[name] = kind
+	return name, kind
+}
+
+// odsDataStyle returns the element, its attributes, the kind of value
+// and the content of the data style that displays numbers as section
+// does.
+func odsDataStyle(section *formatSection) (element, attrs, kind, content string) {
+	var parts []string
+	textElement := func(text string) {
+		if text != "" {
+			parts = append(parts, "<number:text>"+escapeXMLText(text)+"</number:text>")
+		}
+	}
+	if section.isDate {
+		dates, elapsed := false, false
+		for _, token := range section.tokens {
+			switch token.kind {
+			case tokenLiteral:
+				textElement(token.text)
+			case tokenSubsecond:
+				// The fraction of the second goes on the seconds
+				// just written.
+				for i := len(parts) - 1; i >= 0; i-- {
+					if strings.HasPrefix(parts[i], "<number:seconds") {
+						parts[i] = `<number:seconds number:style="long" number:decimal-places="` + token.text + `"/>`
+						break
+					}
+				}
+			case tokenElapsed:
+				elapsed = true
+				parts = append(parts, odsDatePart(token.text))
+			case tokenDate:
+				dates = dates || strings.IndexByte("ymd", token.text[0]) >= 0
+				parts = append(parts, odsDatePart(token.text))
+			}
+		}
+		switch {
+		case elapsed:
+			return "time-style", ` number:truncate-on-overflow="false"`, "time", strings.Join(parts, "")
+		case dates:
+			return "date-style", "", "date", strings.Join(parts, "")
+		}
+		return "time-style", "", "time", strings.Join(parts, "")
+	}
+	if !section.hasDigits {
+		if !section.hasText {
+			return "", "", "", ""
+		}
+		for _, token := range section.tokens {
+			switch token.kind {
+			case tokenText:
+				parts = append(parts, "<number:text-content/>")
+			case tokenLiteral, tokenCurrency:
+				textElement(token.text)
+			}
+		}
+		return "text-style", "", "string", strings.Join(parts, "")
+	}
+
+	integer, fraction, exponent := section.placeholders()
+	minInteger := 0
+	for _, placeholder := range integer {
+		if placeholder == "0" {
+			minInteger++
+		}
+	}
+	var number string
+	switch {
+	case section.hasGeneral():
+		// A number element without decimal places shows as many as
+		// the number has.
+		number = `<number:number number:min-integer-digits="1"/>`
+	case section.fraction:
+		number = fmt.Sprintf(`<number:fraction number:min-integer-digits="%d" number:min-numerator-digits="1" number:min-denominator-digits="1"`, minInteger)
+		if section.denominator != 0 {
+			number += fmt.Sprintf(` number:denominator-value="%d"`, section.denominator)
+		}
+		number += "/>"
+	case len(exponent) > 0:
+		number = fmt.Sprintf(`<number:scientific-number number:decimal-places="%d" number:min-integer-digits="%d" number:min-exponent-digits="%d"/>`,
+			len(fraction), minInteger, len(exponent))
+	default:
+		number = fmt.Sprintf(`<number:number number:decimal-places="%d" number:min-integer-digits="%d"`, len(fraction), minInteger)
+		if section.grouping {
+			number += ` number:grouping="true"`
+		}
+		if section.scale > 0 {
+			number += fmt.Sprintf(` number:display-factor="%g"`, math.Pow(1000, float64(section.scale)))
+		}
+		number += "/>"
+	}
+	// The text before and after the digits is kept; any between them
+	// can't be.
+	first, last := -1, -1
+	for i, token := range section.tokens {
+		if token.kind == tokenDigit || token.kind == tokenGeneral {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	for i, token := range section.tokens {
+		switch {
+		case i == first:
+			parts = append(parts, number)
+		case i > first && i < last:
+		case token.kind == tokenLiteral || token.kind == tokenCurrency:
+			textElement(token.text)
+		case token.kind == tokenPercent:
+			textElement("%")
+		}
+	}
+	if section.percents > 0 {
+		return "percentage-style", "", "percentage", strings.Join(parts, "")
+	}
+	return "number-style", "", "float", strings.Join(parts, "")
+}
+
+// odsDatePart returns the element of a data style displaying the part
+// of a date or time the code of a number format says, such as "yyyy".
+func odsDatePart(code string) string {
+	long := ""
+	if len(code) >= 2 {
+		long = ` number:style="long"`
+	}
+	switch code[0] {
+	case 'y':
+		if len(code) <= 2 {
+			return "<number:year/>"
+		}
+		return `<number:year number:style="long"/>`
+	case 'm':
+		switch len(code) {
+		case 1, 2:
+			return "<number:month" + long + "/>"
+		case 3:
+			return `<number:month number:textual="true"/>`
+		}
+		return `<number:month number:textual="true" number:style="long"/>`
+	case 'n':
+		return "<number:minutes" + long + "/>"
+	case 'd':
+		switch len(code) {
+		case 1, 2:
+			return "<number:day" + long + "/>"
+		case 3:
+			return "<number:day-of-week/>"
+		}
+		return `<number:day-of-week number:style="long"/>`
+	case 'h':
+		return "<number:hours" + long + "/>"
+	case 's':
+		return "<number:seconds" + long + "/>"
+	case 'a', 'A':
+		return "<number:am-pm/>"
+	}
+	return ""
+}
+
+// escapeXMLText returns s escaped as character data.
+func escapeXMLText(s string) string {
+	var b bytes.Buffer
+	x := newXMLWriter(&b)
+	x.text(s)
+	x.flush()
+	return b.String()
+}
+
+// cellStyle returns the name of the style of cell, or "" if it has
+// nothing to show, and the kind of value its data style displays.
+func (s *odsStyles) cellStyle(cell *Cell) (string, string) {
+	dataStyle, kind := s.dataStyle(cell.NumFmt)
+	var text, fill strings.Builder
+	if style := cell.style; style != nil {
+		if style.Font.Bold {
+			text.WriteString(` fo:font-weight="bold"`)
+		}
+		if style.Font.Italic {
+			text.WriteString(` fo:font-style="italic"`)
+		}
+		if style.Font.Underline {
+			text.WriteString(` style:text-underline-style="solid" style:text-underline-width="auto" style:text-underline-color="font-color"`)
+		}
+		if color := cssColor(style.Font.Color); color != "" {
+			text.WriteString(` fo:color="` + color + `"`)
+		}
+		if style.Fill.PatternType == "solid" {
+			if color := cssColor(style.Fill.FgColor); color != "" {
+				fill.WriteString(` fo:background-color="` + color + `"`)
+			}
+		}
+		if style.Alignment.WrapText {
+			fill.WriteString(` fo:wrap-option="wrap"`)
+		}
+	}
+	var paragraph string
+	if cell.style != nil {
+		switch cell.style.Alignment.Horizontal {
+		case "left":
+			paragraph = "start"
+		case "center", "centerContinuous":
+			paragraph = "center"
+		case "right":
+			paragraph = "end"
+		case "justify", "distributed":
+			paragraph = "justify"
+		}
+	}
+	if dataStyle == "" && text.Len() == 0 && fill.Len() == 0 && paragraph == "" {
+		return "", kind
+	}
+	content := `<style:style style:name="" style:family="table-cell"`
+	if dataStyle != "" {
+		content += ` style:data-style-name="` + dataStyle + `"`
+	}
+	content += ">"
+	if fill.Len() > 0 {
+		content += "<style:table-cell-properties" + fill.String() + "/>"
+	}
+	if paragraph != "" {
+		content += `<style:paragraph-properties fo:text-align="` + paragraph + `"/>`
+	}
+	if text.Len() > 0 {
+		content += "<style:text-properties" + text.String() + "/>"
+	}
+	return s.style("ce", content+"</style:style>"), kind
+}
+
+// writeTable writes sheet as a table.
+func (s *odsStyles) writeTable(x *xmlWriter, sheet *Sheet) {
+	rows, cols := sheet.extent()
+	x.raw("<table:table")
+	x.attr("table:name", sheet.Name)
+	switch {
+	case sheet.View.RightToLeft:
+		hidden := sheet.Hidden || sheet.VeryHidden
+		x.attr("table:style-name", s.style("ta", fmt.Sprintf(`<style:style style:name="" style:family="table"><style:table-properties table:display="%t" style:writing-mode="rl-tb"/></style:style>`, !hidden)))
+	case sheet.Hidden || sheet.VeryHidden:
+		x.attr("table:style-name", "ta2")
+	default:
+		x.attr("table:style-name", "ta1")
+	}
+	x.raw(">")
+
+	if cols == 0 {
+		// A table has at least one column.
+		cols = 1
+	}
+	for c := 0; c < cols; {
+		style, hidden := s.columnStyle(sheet, c)
+		n := 1
+		for c+n < cols {
+			if next, nextHidden := s.columnStyle(sheet, c+n); next != style || nextHidden != hidden {
+				break
+			}
+			n++
+		}
+		x.raw("<table:table-column")
+		x.attr("table:style-name", style)
+		if n > 1 {
+			x.intAttr("table:number-columns-repeated", n)
+		}
+		if hidden {
+			x.raw(` table:visibility="collapse"`)
+		}
+		x.raw("/>")
+		c += n
+	}
+
+	covered := make(map[[2]int]bool)
+	for r, row := range sheet.Rows {
+		if row == nil {
+			continue
+		}
+		for c, cell := range row.Cells {
+			if cell == nil || cell.HMerge == 0 && cell.VMerge == 0 {
+				continue
+			}
+			for dr := 0; dr <= cell.VMerge; dr++ {
+				for dc := 0; dc <= cell.HMerge; dc++ {
+					if dr != 0 || dc != 0 {
+						covered[[2]int{r + dr, c + dc}] = true
+					}
+				}
+			}
+		}
+	}
+	for r := 0; r < rows; r++ {
+		row := sheet.Rows[r]
+		x.raw("<table:table-row")
+		if row != nil {
+			if row.isCustom && row.Height > 0 {
+				x.attr("table:style-name", s.style("ro", fmt.Sprintf(`<style:style style:name="" style:family="table-row"><style:table-row-properties style:row-height="%gpt" style:use-optimal-row-height="false"/></style:style>`, row.Height)))
+			}
+			if row.Hidden {
+				x.raw(` table:visibility="collapse"`)
+			}
+		}
+		x.raw(">")
+		empty := 0
+		writeEmpty := func() {
+			if empty > 0 {
+				x.raw("<table:table-cell")
+				if empty > 1 {
+					x.intAttr("table:number-columns-repeated", empty)
+				}
+				x.raw("/>")
+				empty = 0
+			}
+		}
+		var cells []*Cell
+		if row != nil {
+			cells = row.Cells
+		}
+		for c, cell := range cells {
+			if covered[[2]int{r, c}] {
+				writeEmpty()
+				x.raw("<table:covered-table-cell/>")
+				continue
+			}
+			if cell == nil {
+				empty++
+				continue
+			}
+			style, kind := s.cellStyle(cell)
+			value := cell.typedValue()
+			if value == nil && style == "" && cell.HMerge == 0 && cell.VMerge == 0 {
+				empty++
+				continue
+			}
+			writeEmpty()
+			s.writeCell(x, cell, value, style, kind)
+		}
+		writeEmpty()
+		if len(cells) == 0 {
+			x.raw("<table:table-cell/>")
+		}
+		x.raw("</table:table-row>")
+	}
+	if rows == 0 {
+		x.raw("<table:table-row><table:table-cell/></table:table-row>")
+	}
+	x.raw("</table:table>")
+}
+
+// columnStyle returns the name of the style of the column with the
+// zero based index col, and whether it is hidden.
+func (s *odsStyles) columnStyle(sheet *Sheet, col int) (string, bool) {
+	width := sheet.SheetFormat.DefaultColWidth
+	if width == 0 {
+		width = ColWidth
+	}
+	hidden := false
+	if c := sheet.Cols.FindCol(col); c != nil {
+		hidden = c.Hidden
+		if c.Width != 0 {
+			width = c.Width
+		}
+	}
+	// Columns are as wide as their characters in pixels, at 96 to
+	// the inch.
+	inches := float64(int(width*maxDigitWidth+0.5)) / 96
+	return s.style("co", fmt.Sprintf(`<style:style style:name="" style:family="table-column"><style:table-column-properties style:column-width="%.4fin"/></style:style>`, inches)), hidden
+}
+
+// writeCell writes the cell, whose value is given as typedValue gives
+// it, with the named style, whose data style displays values of the
+// given kind.
+func (s *odsStyles) writeCell(x *xmlWriter, cell *Cell, value interface{}, style, kind string) {
+	x.raw("<table:table-cell")
+	if style != "" {
+		x.attr("table:style-name", style)
+	}
+	if cell.HMerge > 0 || cell.VMerge > 0 {
+		x.intAttr("table:number-columns-spanned", cell.HMerge+1)
+		x.intAttr("table:number-rows-spanned", cell.VMerge+1)
+	}
+	text := cell.Value
+	switch v := value.(type) {
+	case nil:
+		x.raw("/>")
+		return
+	case bool:
+		x.raw(` office:value-type="boolean"`)
+		x.attr("office:boolean-value", strconv.FormatBool(v))
+		text = strings.ToUpper(strconv.FormatBool(v))
+	case float64:
+		if kind == "percentage" {
+			x.raw(` office:value-type="percentage"`)
+		} else {
+			x.raw(` office:value-type="float"`)
+		}
+		x.attr("office:value", strconv.FormatFloat(v, 'g', -1, 64))
+		text = cell.formattedText()
+	case time.Time:
+		if kind == "time" {
+			serial, _ := cell.Float()
+			x.raw(` office:value-type="time"`)
+			x.attr("office:time-value", odsDuration(serial))
+		} else {
+			x.raw(` office:value-type="date"`)
+			x.attr("office:date-value", v.Format("2006-01-02T15:04:05.999"))
+		}
+		text = cell.formattedText()
+	default:
+		x.raw(` office:value-type="string"`)
+	}
+	x.raw(">")
+	for _, line := range strings.Split(text, "\n") {
+		x.element("text:p", line)
+	}
+	x.raw("</table:table-cell>")
+}
+
+// formattedText returns the value of the cell as its number format
+// displays it, or as it is if it can't be formatted.
+func (c *Cell) formattedText() string {
+	if formatted, err := c.FormattedValue(); err == nil {
+		return formatted
+	}
+	return c.Value
+}
+
+// odsDuration returns the span of time of serial days as an ISO 8601
+// duration, such as PT36H30M00S.
+func odsDuration(serial float64) string {
+	sign := ""
+	if serial < 0 {
+		sign, serial = "-", -serial
+	}
+	milliseconds := int64(math.Round(serial * 86400000))
+	seconds := milliseconds / 1000
+	duration := fmt.Sprintf("%sPT%dH%02dM%02d", sign, seconds/3600, seconds/60%60, seconds%60)
+	if ms := milliseconds % 1000; ms != 0 {
+		duration += fmt.Sprintf(".%03d", ms)
+	}
+	return duration + "S"
+}
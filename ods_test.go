@@ -0,0 +1,132 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type ODSSuite struct{}
+
+var _ = Suite(&ODSSuite{})
+
+// odsContent writes f as an OpenDocument spreadsheet and returns its
+// content.xml, having checked the package is laid out as it should be
+// and the content is well formed.
+func odsContent(c *C, f *File) string {
+	var buf bytes.Buffer
+	c.Assert(f.WriteODS(&buf), IsNil)
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	c.Assert(zr.File[0].Name, Equals, "mimetype")
+	c.Assert(zr.File[0].Method, Equals, zip.Store)
+	parts := make(map[string]string)
+	for _, part := range zr.File {
+		rc, err := part.Open()
+		c.Assert(err, IsNil)
+		content, err := ioutil.ReadAll(rc)
+		c.Assert(err, IsNil)
+		rc.Close()
+		parts[part.Name] = string(content)
+	}
+	c.Assert(parts["mimetype"], Equals, "application/vnd.oasis.opendocument.spreadsheet")
+	c.Assert(parts["META-INF/manifest.xml"], Matches, `(?s).*manifest:full-path="content.xml".*`)
+	content := parts["content.xml"]
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	for {
+		_, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		c.Assert(err, IsNil)
+	}
+	return content
+}
+
+func (s *ODSSuite) TestWriteODS(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Data")
+	c.Assert(err, IsNil)
+	sheet.Cell(0, 0).SetString("Fish & Chips\nto go")
+	sheet.Cell(0, 0).GetStyle().Font.Bold = true
+	sheet.Cell(0, 0).HMerge = 1
+	sheet.Cell(0, 2).SetFloat(42.5)
+	sheet.Cell(1, 0).SetFloatWithFormat(0.125, "0.00%")
+	sheet.Cell(1, 1).SetDateTimeWithFormat(45352, "yyyy-mm-dd")
+	sheet.Cell(1, 2).SetBool(true)
+	sheet.Cell(1, 3).SetFloatWithFormat(1234.5, "#,##0.00")
+	sheet.Cell(2, 0).SetFormula("C1*2")
+	sheet.Cell(2, 0).Value = "85"
+	sheet.Cell(3, 4).SetString("far")
+	sheet.Rows[1].SetHeight(30)
+	sheet.Rows[2].Hidden = true
+	c.Assert(sheet.SetColWidth(3, 3, 20), IsNil)
+	hidden, err := f.AddSheet("Hidden")
+	c.Assert(err, IsNil)
+	hidden.Hidden = true
+	arabic, err := f.AddSheet("Arabic", SheetRightToLeft())
+	c.Assert(err, IsNil)
+	arabic.Cell(0, 0).SetString("مرحبا")
+
+	content := odsContent(c, f)
+	c.Assert(content, Matches, `(?s).*<style:style style:name="ta3" style:family="table"><style:table-properties table:display="true" style:writing-mode="rl-tb"/></style:style>.*<table:table table:name="Arabic" table:style-name="ta3">.*`)
+	c.Assert(content, Matches, `(?s).*<table:table table:name="Data" table:style-name="ta1">.*<table:table table:name="Hidden" table:style-name="ta2">.*`)
+	c.Assert(content, Matches, `(?s).*<table:table-cell table:style-name="ce\d+" table:number-columns-spanned="2" table:number-rows-spanned="1" office:value-type="string"><text:p>Fish &amp; Chips</text:p><text:p>to go</text:p></table:table-cell><table:covered-table-cell/>.*`)
+	c.Assert(content, Matches, `(?s).*<style:style style:name="ce\d+" style:family="table-cell"><style:text-properties fo:font-weight="bold"/></style:style>.*`)
+	c.Assert(content, Matches, `(?s).*office:value-type="float" office:value="42.5"><text:p>42.5</text:p>.*`)
+	c.Assert(content, Matches, `(?s).*<number:percentage-style style:name="N1"><number:number number:decimal-places="2" number:min-integer-digits="1"/><number:text>%</number:text></number:percentage-style>.*`)
+	c.Assert(content, Matches, `(?s).*office:value-type="percentage" office:value="0.125"><text:p>12.50%</text:p>.*`)
+	c.Assert(content, Matches, `(?s).*<number:date-style style:name="N2"><number:year number:style="long"/><number:text>-</number:text><number:month number:style="long"/><number:text>-</number:text><number:day number:style="long"/></number:date-style>.*`)
+	c.Assert(content, Matches, `(?s).*office:value-type="date" office:date-value="2024-03-01T00:00:00"><text:p>2024-03-01</text:p>.*`)
+	c.Assert(content, Matches, `(?s).*office:value-type="boolean" office:boolean-value="true"><text:p>TRUE</text:p>.*`)
+	c.Assert(content, Matches, `(?s).*<number:number-style style:name="N3"><number:number number:decimal-places="2" number:min-integer-digits="1" number:grouping="true"/></number:number-style>.*`)
+	// Formulas are written as the values they were worked out to.
+	c.Assert(content, Matches, `(?s).*<table:table-row table:visibility="collapse"><table:table-cell office:value-type="float" office:value="85"><text:p>85</text:p>.*`)
+	c.Assert(content, Not(Matches), `(?s).*table:formula.*`)
+	c.Assert(content, Matches, `(?s).*<table:table-row><table:table-cell table:number-columns-repeated="4"/><table:table-cell office:value-type="string"><text:p>far</text:p></table:table-cell></table:table-row>.*`)
+	c.Assert(content, Matches, `(?s).*<style:style style:name="ro1" style:family="table-row"><style:table-row-properties style:row-height="30pt" style:use-optimal-row-height="false"/></style:style>.*`)
+	c.Assert(content, Matches, `(?s).*<table:table-column table:style-name="co1" table:number-columns-repeated="3"/><table:table-column table:style-name="co2"/><table:table-column table:style-name="co1"/>.*`)
+	c.Assert(content, Matches, `(?s).*<style:style style:name="co2" style:family="table-column"><style:table-column-properties style:column-width="1.4583in"/></style:style>.*`)
+}
+
+func (s *ODSSuite) TestDataStyles(c *C) {
+	cases := map[string]string{
+		"0":                `<number:number-style style:name=""><number:number number:decimal-places="0" number:min-integer-digits="1"/></number:number-style>`,
+		`"$"#,##0.00`:      `<number:number-style style:name=""><number:text>$</number:text><number:number number:decimal-places="2" number:min-integer-digits="1" number:grouping="true"/></number:number-style>`,
+		"0.00E+00":         `<number:number-style style:name=""><number:scientific-number number:decimal-places="2" number:min-integer-digits="1" number:min-exponent-digits="2"/></number:number-style>`,
+		"# ?/8":            `<number:number-style style:name=""><number:fraction number:min-integer-digits="0" number:min-numerator-digits="1" number:min-denominator-digits="1" number:denominator-value="8"/></number:number-style>`,
+		`0.0,," M"`:        `<number:number-style style:name=""><number:number number:decimal-places="1" number:min-integer-digits="1" number:display-factor="1e+06"/><number:text> M</number:text></number:number-style>`,
+		"h:mm:ss.00 AM/PM": `<number:time-style style:name=""><number:hours/><number:text>:</number:text><number:minutes number:style="long"/><number:text>:</number:text><number:seconds number:style="long" number:decimal-places="2"/><number:text> </number:text><number:am-pm/></number:time-style>`,
+		"[h]:mm":           `<number:time-style style:name="" number:truncate-on-overflow="false"><number:hours/><number:text>:</number:text><number:minutes number:style="long"/></number:time-style>`,
+		"dddd d mmmm yy":   `<number:date-style style:name=""><number:day-of-week number:style="long"/><number:text> </number:text><number:day/><number:text> </number:text><number:month number:textual="true" number:style="long"/><number:text> </number:text><number:year/></number:date-style>`,
+		"@":                `<number:text-style style:name=""><number:text-content/></number:text-style>`,
+	}
+	for code, expected := range cases {
+		element, attrs, _, content := odsDataStyle(parseNumberFormat(code).sections[0])
+		c.Assert(`<number:`+element+` style:name=""`+attrs+`>`+content+`</number:`+element+`>`, Equals, expected, Commentf(code))
+	}
+	c.Assert(odsDuration(1.5), Equals, "PT36H00M00S")
+	c.Assert(odsDuration(-0.25), Equals, "-PT6H00M00S")
+	c.Assert(odsDuration(1.0/86400/4), Equals, "PT0H00M00.250S")
+}
+
+func (s *ODSSuite) TestSaveODS(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Empty")
+	c.Assert(err, IsNil)
+	c.Assert(sheet.Rows, HasLen, 0)
+	path := filepath.Join(c.MkDir(), "empty.ods")
+	c.Assert(f.SaveODS(path), IsNil)
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	// The media type is right after the first local file header.
+	c.Assert(string(data[30:38]), Equals, "mimetype")
+	c.Assert(string(data[38:38+len(odsMimeType)]), Equals, odsMimeType)
+	c.Assert(odsContent(c, f), Matches, `(?s).*<table:table table:name="Empty" table:style-name="ta1"><table:table-column table:style-name="co1"/><table:table-row><table:table-cell/></table:table-row></table:table>.*`)
+}
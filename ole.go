@@ -0,0 +1,331 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+const relationshipsNamespace = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+
+// EmbeddedObject is an object, such as a PDF or a Word document,
+// embedded in a Sheet.
+type EmbeddedObject struct {
+	// ProgID names the kind of object, such as "Word.Document.12",
+	// "AcroExch.Document.DC" or "Package", for files of any kind.
+	ProgID string
+	// PartName is the name of the part holding the object, such as
+	// "xl/embeddings/Microsoft_Word_Document.docx" or
+	// "xl/embeddings/oleObject1.bin".
+	PartName string
+	// Data is the content of the part: the file itself for objects
+	// such as Word documents that are stored as packages of their
+	// own, and an OLE compound document wrapping it for the rest;
+	// see Contents.
+	Data []byte
+}
+
+// Contents returns the file the object holds, taking it out of the OLE
+// compound document wrapping it if there is one: the native content of
+// objects such as PDFs, or the file of a "Package" object.
+func (o EmbeddedObject) Contents() ([]byte, error) {
+	if !bytes.HasPrefix(o.Data, oleSignature) {
+		return o.Data, nil
+	}
+	cf, err := readCompoundFile(bytes.NewReader(o.Data), int64(len(o.Data)))
+	if err != nil {
+		return nil, fmt.Errorf("embedded object %s: %s", o.PartName, err)
+	}
+	for _, name := range []string{"CONTENTS", "Package"} {
+		if data, ok, err := cf.stream(name); ok || err != nil {
+			return data, err
+		}
+	}
+	native, ok, err := cf.stream("\x01Ole10Native")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("embedded object %s: the OLE object has no contents that can be read", o.PartName)
+	}
+	data, err := ole10NativeContents(native)
+	if err != nil {
+		return nil, fmt.Errorf("embedded object %s: %s", o.PartName, err)
+	}
+	return data, nil
+}
+
+// ole10NativeContents returns the file an Ole10Native stream, which
+// holds the file of a "Package" object, wraps: after the size of the
+// stream come a flag, the file's label and path, a reserved word, the
+// kind of object, a temporary path and then the file, with its length.
+func ole10NativeContents(native []byte) ([]byte, error) {
+	r := bytes.NewReader(native)
+	var size uint32
+	var word uint16
+	err := binary.Read(r, binary.LittleEndian, &size)
+	if err == nil {
+		err = binary.Read(r, binary.LittleEndian, &word)
+	}
+	for i := 0; i < 2 && err == nil; i++ {
+		_, err = readCString(r)
+	}
+	if err == nil {
+		var flags [2]uint16
+		err = binary.Read(r, binary.LittleEndian, &flags)
+	}
+	var length uint32
+	if err == nil {
+		err = binary.Read(r, binary.LittleEndian, &length)
+	}
+	if err == nil {
+		_, err = r.Seek(int64(length), io.SeekCurrent)
+	}
+	if err == nil {
+		err = binary.Read(r, binary.LittleEndian, &length)
+	}
+	if err != nil || int64(length) > int64(r.Len()) {
+		return nil, fmt.Errorf("the Ole10Native stream is cut short")
+	}
+	data := make([]byte, length)
+	_, err = io.ReadFull(r, data)
+	return data, err
+}
+
+// readCString reads a string ending with a NUL.
+func readCString(r io.ByteReader) (string, error) {
+	var b strings.Builder
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if c == 0 {
+			return b.String(), nil
+		}
+		b.WriteByte(c)
+	}
+}
+
+// sheetOLEObjects are the embedded objects of a worksheet as they were
+// read, which are written back as they were.
+type sheetOLEObjects struct {
+	// content is the content of the oleObjects element, and
+	// namespaces the declarations, from the worksheet element, of
+	// the namespaces it uses.
+	content    string
+	namespaces []xml.Attr
+	// rels are the worksheet's relationships that content and the
+	// legacy drawing, the VML drawing showing the objects, are
+	// related to by, by id.
+	rels          map[string]xlsxWorksheetRelationship
+	legacyDrawing string
+	parts         []preservedPart
+}
+
+// xlsxOLEObjects is the oleObjects element of a worksheet, which is
+// kept as it was read.  It is written with Content, but its content is
+// read with rawElementContent.
+type xlsxOLEObjects struct {
+	Namespaces []xml.Attr `xml:",any,attr"`
+	Content    string     `xml:",innerxml"`
+}
+
+// worksheetLegacyDrawing is the legacyDrawing element of a worksheet.
+// RID is the id it is read with, and Id that it is written with.
+type worksheetLegacyDrawing struct {
+	RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr,omitempty"`
+	Id  string `xml:"r:id,attr,omitempty"`
+}
+
+// readOLEObjects reads the embedded objects of the worksheet, with the
+// parts they use, into the Sheet.
+func (f *File) readOLEObjects(sheet *Sheet, worksheet *xlsxWorksheet, worksheetPart string) error {
+	if worksheet.OLEObjects == nil {
+		return nil
+	}
+	rels, err := f.readRelationships(worksheetPart)
+	if err != nil {
+		return err
+	}
+	data, err := f.readBinaryPart(f.parts[worksheetPart])
+	if err != nil {
+		return err
+	}
+	content, declared, err := rawElementContent(data, "oleObjects")
+	if err != nil {
+		return fmt.Errorf("sheet '%s': embedded objects: %s", sheet.Name, err)
+	}
+	objects := &sheetOLEObjects{content: content, rels: make(map[string]xlsxWorksheetRelationship)}
+	for _, attr := range append(worksheet.Namespaces, declared...) {
+		if attr.Name.Space == "xmlns" {
+			objects.namespaces = append(objects.namespaces, xml.Attr{Name: xml.Name{Local: "xmlns:" + attr.Name.Local}, Value: attr.Value})
+		}
+	}
+	ids := objects.relationshipIds()
+	if worksheet.LegacyDrawing != nil {
+		objects.legacyDrawing = worksheet.LegacyDrawing.RID
+		ids = append(ids, objects.legacyDrawing)
+	}
+	var roots []string
+	for _, id := range ids {
+		rel, ok := rels[id]
+		if !ok {
+			continue
+		}
+		objects.rels[id] = rel
+		if rel.TargetMode != "External" {
+			roots = append(roots, rel.Target)
+		}
+	}
+	if objects.parts, err = f.readPartTree(fmt.Sprintf("sheet '%s': embedded objects", sheet.Name), roots...); err != nil {
+		return err
+	}
+	sheet.oleObjects = objects
+	return nil
+}
+
+// rawElementContent returns the content of the first element of the
+// document called local, as it is written in data, with the attributes
+// of the element.  The content of elements is otherwise lost, as parts
+// are read through a translation of their tokens.
+func rawElementContent(data []byte, local string) (string, []xml.Attr, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", nil, err
+		}
+		element, ok := token.(xml.StartElement)
+		if !ok || element.Name.Local != local {
+			continue
+		}
+		start := decoder.InputOffset()
+		end := start
+		for depth := 1; depth > 0; {
+			end = decoder.InputOffset()
+			token, err := decoder.Token()
+			if err != nil {
+				return "", nil, err
+			}
+			switch token.(type) {
+			case xml.StartElement:
+				depth++
+			case xml.EndElement:
+				depth--
+			}
+		}
+		return string(data[start:end]), element.Attr, nil
+	}
+}
+
+// relationshipIdPattern returns the pattern matching the attributes in
+// content that are relationship ids, with the prefixes the namespaces
+// declare for them, and the ids.
+func (o *sheetOLEObjects) relationshipIdPattern() *regexp.Regexp {
+	prefixes := []string{"r"}
+	for _, attr := range o.namespaces {
+		if attr.Value == relationshipsNamespace && attr.Name.Local != "xmlns:r" {
+			prefixes = append(prefixes, regexp.QuoteMeta(strings.TrimPrefix(attr.Name.Local, "xmlns:")))
+		}
+	}
+	return regexp.MustCompile(`(\s(?:` + strings.Join(prefixes, "|") + `):id=")([^"]*)"`)
+}
+
+// relationshipIds returns the ids of the relationships content uses.
+func (o *sheetOLEObjects) relationshipIds() []string {
+	var ids []string
+	for _, match := range o.relationshipIdPattern().FindAllStringSubmatch(o.content, -1) {
+		ids = append(ids, match[2])
+	}
+	return ids
+}
+
+// write adds the parts of the objects to packaged, relating them to the
+// worksheet, called partName, in rels, and gives xSheet the elements
+// referring to them.
+func (o *sheetOLEObjects) write(xSheet *xlsxWorksheet, partName string, packaged *packageParts, rels *packageRelationships) error {
+	names, err := addPreservedParts(packaged, rels, o.parts)
+	if err != nil {
+		return err
+	}
+	sheetRels := rels.of(partName)
+	ids := make(map[string]string, len(o.rels))
+	for _, id := range append([]string{o.legacyDrawing}, o.relationshipIds()...) {
+		rel, ok := o.rels[id]
+		if _, done := ids[id]; done || !ok {
+			continue
+		}
+		if rel.TargetMode == "External" {
+			ids[id] = sheetRels.addExternal(rel.Type, rel.Target)
+		} else if name, ok := names[rel.Target]; ok {
+			ids[id] = sheetRels.add(rel.Type, name)
+		}
+	}
+	content := o.relationshipIdPattern().ReplaceAllStringFunc(o.content, func(attr string) string {
+		match := o.relationshipIdPattern().FindStringSubmatch(attr)
+		return match[1] + ids[match[2]] + `"`
+	})
+	xSheet.OLEObjects = &xlsxOLEObjects{Namespaces: o.namespaces, Content: content}
+	if id, ok := ids[o.legacyDrawing]; ok {
+		xSheet.LegacyDrawing = &worksheetLegacyDrawing{Id: id}
+	}
+	return nil
+}
+
+// EmbeddedObjects returns the objects embedded in the Sheet, as it was
+// read.  Embedded objects are kept, and written back with the Sheet,
+// but can't be added or changed.
+func (s *Sheet) EmbeddedObjects() []EmbeddedObject {
+	s.ensureLoaded()
+	o := s.oleObjects
+	if o == nil {
+		return nil
+	}
+	data := make(map[string][]byte, len(o.parts))
+	for _, part := range o.parts {
+		data[part.name] = part.data
+	}
+	var start strings.Builder
+	start.WriteString("<oleObjects")
+	for _, attr := range o.namespaces {
+		start.WriteString(" " + attr.Name.Local + `="` + escapeXMLAttr(attr.Value) + `"`)
+	}
+	start.WriteString(">")
+	decoder := xml.NewDecoder(strings.NewReader(start.String() + o.content + "</oleObjects>"))
+	var objects []EmbeddedObject
+	seen := make(map[string]bool)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		element, ok := token.(xml.StartElement)
+		if !ok || element.Name.Local != "oleObject" {
+			continue
+		}
+		var object EmbeddedObject
+		for _, attr := range element.Attr {
+			switch {
+			case attr.Name.Local == "progId":
+				object.ProgID = attr.Value
+			case attr.Name.Local == "id" && attr.Name.Space == relationshipsNamespace:
+				object.PartName = o.rels[attr.Value].Target
+			}
+		}
+		// The objects are given once for applications that
+		// understand the drawings that place them, and once for
+		// those that don't.
+		if object.PartName == "" || seen[object.PartName] {
+			continue
+		}
+		seen[object.PartName] = true
+		object.Data = data[object.PartName]
+		objects = append(objects, object)
+	}
+	return objects
+}
@@ -0,0 +1,127 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	. "gopkg.in/check.v1"
+)
+
+type OLESuite struct{}
+
+var _ = Suite(&OLESuite{})
+
+// ole10Native returns an Ole10Native stream wrapping a file.
+func ole10Native(label string, file []byte) []byte {
+	var body bytes.Buffer
+	binary.Write(&body, binary.LittleEndian, uint16(2))
+	body.WriteString(label + "\x00C:\\" + label + "\x00")
+	binary.Write(&body, binary.LittleEndian, [2]uint16{0, 3})
+	temp := "C:\\Temp\\" + label + "\x00"
+	binary.Write(&body, binary.LittleEndian, uint32(len(temp)))
+	body.WriteString(temp)
+	binary.Write(&body, binary.LittleEndian, uint32(len(file)))
+	body.Write(file)
+	var native bytes.Buffer
+	binary.Write(&native, binary.LittleEndian, uint32(body.Len()))
+	native.Write(body.Bytes())
+	return native.Bytes()
+}
+
+const oleObjectsXML = `<oleObjects><mc:AlternateContent xmlns:mc="http://schemas.openxmlformats.org/markup-compatibility/2006">` +
+	`<mc:Choice Requires="x14"><oleObject progId="Word.Document.12" shapeId="1025" r:id="rId2"><objectPr defaultSize="0" r:id="rId3">` +
+	`<anchor moveWithCells="1"><from><xdr:col>1</xdr:col><xdr:colOff>0</xdr:colOff><xdr:row>1</xdr:row><xdr:rowOff>0</xdr:rowOff></from>` +
+	`<to><xdr:col>3</xdr:col><xdr:colOff>0</xdr:colOff><xdr:row>5</xdr:row><xdr:rowOff>0</xdr:rowOff></to></anchor></objectPr></oleObject></mc:Choice>` +
+	`<mc:Fallback><oleObject progId="Word.Document.12" shapeId="1025" r:id="rId2"/></mc:Fallback></mc:AlternateContent>` +
+	`<oleObject progId="Package" shapeId="1026" r:id="rId4"/></oleObjects>`
+
+func oleParts(pdf []byte) map[string]string {
+	parts := makeSheetParts(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" ` +
+		`xmlns:xdr="http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing" xmlns:x14="http://schemas.microsoft.com/office/spreadsheetml/2009/9/main" ` +
+		`xmlns:mc="http://schemas.openxmlformats.org/markup-compatibility/2006" mc:Ignorable="x14ac"><sheetData><row r="1"><c r="A1" t="inlineStr"><is><t>see below</t></is></c></row></sheetData>` +
+		`<legacyDrawing r:id="rId1"/>` + oleObjectsXML + `</worksheet>`)
+	for name, part := range map[string]string{
+		"[Content_Types].xml": `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="xml" ContentType="application/xml"/>` +
+			`<Default Extension="vml" ContentType="application/vnd.openxmlformats-officedocument.vmlDrawing"/><Default Extension="emf" ContentType="image/x-emf"/>` +
+			`<Default Extension="bin" ContentType="application/vnd.openxmlformats-officedocument.oleObject"/>` +
+			`<Override PartName="/xl/embeddings/Microsoft_Word_Document.docx" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document"/></Types>`,
+		"xl/worksheets/_rels/sheet1.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/vmlDrawing" Target="../drawings/vmlDrawing1.vml"/>` +
+			`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/package" Target="../embeddings/Microsoft_Word_Document.docx"/>` +
+			`<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="../media/image1.emf"/>` +
+			`<Relationship Id="rId4" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/oleObject" Target="../embeddings/oleObject1.bin"/>` +
+			`</Relationships>`,
+		"xl/drawings/vmlDrawing1.vml": `<xml xmlns:v="urn:schemas-microsoft-com:vml"><v:shape id="_x0000_s1025"><v:imagedata o:relid="rId1"/></v:shape></xml>`,
+		"xl/drawings/_rels/vmlDrawing1.vml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="../media/image1.emf"/></Relationships>`,
+		"xl/media/image1.emf":                        "EMF icon",
+		"xl/embeddings/Microsoft_Word_Document.docx": "PK docx",
+		"xl/embeddings/oleObject1.bin": string(makeCompoundFile([]string{"\x01Ole10Native"},
+			map[string][]byte{"\x01Ole10Native": ole10Native("report.pdf", pdf)})),
+	} {
+		parts[name] = part
+	}
+	return parts
+}
+
+func (s *OLESuite) TestRoundTrip(c *C) {
+	pdf := []byte("%PDF-1.7 the report")
+	f, err := ReadZipReader(makeZipReader(c, oleParts(pdf)))
+	c.Assert(err, IsNil)
+	sheet := f.Sheets[0]
+	// The icon isn't taken for the sheet's background.
+	c.Assert(sheet.Background, IsNil)
+	objects := sheet.EmbeddedObjects()
+	c.Assert(objects, HasLen, 2)
+	c.Assert(objects[0].ProgID, Equals, "Word.Document.12")
+	c.Assert(objects[0].PartName, Equals, "xl/embeddings/microsoft_word_document.docx")
+	contents, err := objects[0].Contents()
+	c.Assert(err, IsNil)
+	c.Assert(string(contents), Equals, "PK docx")
+	c.Assert(objects[1].ProgID, Equals, "Package")
+	contents, err = objects[1].Contents()
+	c.Assert(err, IsNil)
+	c.Assert(contents, DeepEquals, pdf)
+
+	c.Assert(sheet.SetBackground(makePNG(c, 4, 4), IMAGE_TYPE_PNG), IsNil)
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	// The drawing and background take the first ids.
+	c.Assert(parts["xl/worksheets/sheet1.xml"], Matches, `(?s).*<drawing r:id="rId1"></drawing><legacyDrawing r:id="rId3"></legacyDrawing><picture r:id="rId2"></picture>`+
+		`<oleObjects xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" xmlns:xdr="http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing" `+
+		`xmlns:x14="http://schemas.microsoft.com/office/spreadsheetml/2009/9/main" xmlns:mc="http://schemas.openxmlformats.org/markup-compatibility/2006">`+
+		`<mc:AlternateContent .*<oleObject progId="Word.Document.12" shapeId="1025" r:id="rId4"><objectPr defaultSize="0" r:id="rId5">.*`+
+		`<mc:Fallback><oleObject progId="Word.Document.12" shapeId="1025" r:id="rId4"/></mc:Fallback></mc:AlternateContent>`+
+		`<oleObject progId="Package" shapeId="1026" r:id="rId6"/></oleObjects></worksheet>`)
+	rels := parts["xl/worksheets/_rels/sheet1.xml.rels"]
+	c.Assert(rels, Matches, `(?s).*<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/vmlDrawing" Target="../drawings/vmldrawing1.vml"></Relationship>.*`)
+	c.Assert(rels, Matches, `(?s).*<Relationship Id="rId4" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/package" Target="../embeddings/microsoft_word_document.docx"></Relationship>.*`)
+	c.Assert(rels, Matches, `(?s).*<Relationship Id="rId5" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="../media/image1.emf"></Relationship>.*`)
+	c.Assert(rels, Matches, `(?s).*<Relationship Id="rId6" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/oleObject" Target="../embeddings/oleobject1.bin"></Relationship>.*`)
+	c.Assert(parts["xl/drawings/_rels/vmldrawing1.vml.rels"], Matches, `(?s).*Target="/xl/media/image1.emf".*`)
+	c.Assert(parts["xl/media/image1.emf"], Equals, "EMF icon")
+	c.Assert(parts["[Content_Types].xml"], Matches, `(?s).*<Override PartName="/xl/embeddings/microsoft_word_document.docx" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document"></Override>.*`)
+	c.Assert(parts["[Content_Types].xml"], Matches, `(?s).*<Override PartName="/xl/embeddings/oleobject1.bin" ContentType="application/vnd.openxmlformats-officedocument.oleObject"></Override>.*`)
+
+	written, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	c.Assert(written.Sheets[0].Background, NotNil)
+	c.Assert(written.Sheets[0].EmbeddedObjects(), DeepEquals, objects)
+}
+
+func (s *OLESuite) TestContents(c *C) {
+	pdf := []byte("%PDF-1.4")
+	object := EmbeddedObject{PartName: "xl/embeddings/oleObject1.bin",
+		Data: makeCompoundFile([]string{"CONTENTS"}, map[string][]byte{"CONTENTS": pdf})}
+	contents, err := object.Contents()
+	c.Assert(err, IsNil)
+	c.Assert(contents, DeepEquals, pdf)
+
+	object.Data = makeCompoundFile([]string{"Other"}, map[string][]byte{"Other": pdf})
+	_, err = object.Contents()
+	c.Assert(err, ErrorMatches, "embedded object xl/embeddings/oleObject1.bin: the OLE object has no contents that can be read")
+	native := ole10Native("a.txt", []byte("text"))
+	object.Data = makeCompoundFile([]string{"\x01Ole10Native"}, map[string][]byte{"\x01Ole10Native": native[:len(native)-2]})
+	_, err = object.Contents()
+	c.Assert(err, ErrorMatches, ".*the Ole10Native stream is cut short")
+}
@@ -0,0 +1,191 @@
+package xlsx
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+)
+
+// packageParts holds the parts of an XLSX package being written.  The
+// small ones are made in full, by name in content; the worksheets and
+// shared strings, which can be huge, are kept as the functions that
+// write them, so that they can go straight to wherever the package is
+// going.
+type packageParts struct {
+	content map[string]string
+	writers map[string]func(io.Writer) error
+	// contentTypes are the content types of the parts whose kind
+	// doesn't give them theirs, by name.
+	contentTypes map[string]string
+}
+
+func newPackageParts() *packageParts {
+	return &packageParts{
+		content:      make(map[string]string),
+		writers:      make(map[string]func(io.Writer) error),
+		contentTypes: make(map[string]string),
+	}
+}
+
+// setContentType registers the content type of the part called name,
+// for a part whose content type partContentTypes doesn't give.
+func (p *packageParts) setContentType(name, contentType string) {
+	p.contentTypes[name] = contentType
+}
+
+// order returns the names of the parts in the order they are written.
+func (p *packageParts) order() []string {
+	names := make([]string, 0, len(p.content)+len(p.writers))
+	for name := range p.content {
+		names = append(names, name)
+	}
+	for name := range p.writers {
+		if _, ok := p.content[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sortPartNames(names)
+	return names
+}
+
+// writeTo writes the part called name to w.
+func (p *packageParts) writeTo(name string, w io.Writer) error {
+	if write, ok := p.writers[name]; ok {
+		return write(w)
+	}
+	_, err := io.WriteString(w, p.content[name])
+	return err
+}
+
+// bytes returns the content of the part called name.
+func (p *packageParts) bytes(name string) ([]byte, error) {
+	if _, ok := p.writers[name]; !ok {
+		return []byte(p.content[name]), nil
+	}
+	var buf bytes.Buffer
+	err := p.writeTo(name, &buf)
+	return buf.Bytes(), err
+}
+
+// strings returns the content of every part, by name.
+func (p *packageParts) strings() (map[string]string, error) {
+	parts := make(map[string]string, len(p.content)+len(p.writers))
+	for name, content := range p.content {
+		parts[name] = content
+	}
+	for name := range p.writers {
+		var buf strings.Builder
+		if err := p.writeTo(name, &buf); err != nil {
+			return nil, err
+		}
+		parts[name] = buf.String()
+	}
+	return parts, nil
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// partRanks lists the parts, or the directories holding them, of an
+// XLSX package in the order they are written: package level parts
+// first, then the workbook and the parts it depends on, then the
+// worksheets and their drawings.
+var partRanks = []string{
+	"[Content_Types].xml",
+	"_rels/",
+	"docProps/",
+	"xl/workbook.xml",
+	"xl/_rels/",
+	"xl/styles.xml",
+	"xl/theme/",
+	"xl/sharedStrings.xml",
+	"xl/worksheets/_rels/",
+	"xl/worksheets/",
+	"xl/drawings/_rels/",
+	"xl/drawings/",
+	"xl/media/",
+}
+
+// partRank returns the position of the part in partRanks, or
+// len(partRanks) for parts not listed there.
+func partRank(name string) int {
+	for i, prefix := range partRanks {
+		if name == prefix || (strings.HasSuffix(prefix, "/") && strings.HasPrefix(name, prefix)) {
+			return i
+		}
+	}
+	return len(partRanks)
+}
+
+// PartOrder returns the names of the parts, as returned by
+// File.MarshallParts, in the canonical order File.Write writes them
+// in.  Parts of the same kind are in natural order, so that
+// "sheet2.xml" comes before "sheet10.xml".
+func PartOrder(parts map[string]string) []string {
+	names := make([]string, 0, len(parts))
+	for name := range parts {
+		names = append(names, name)
+	}
+	sortPartNames(names)
+	return names
+}
+
+// sortPartNames sorts the names of parts into the order PartOrder
+// gives.
+func sortPartNames(names []string) {
+	sort.Slice(names, func(i, j int) bool {
+		ri, rj := partRank(names[i]), partRank(names[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return naturalLess(names[i], names[j])
+	})
+}
+
+// naturalLess reports whether a sorts before b, comparing runs of
+// digits by their numeric value.  Names that differ only in leading
+// zeros, such as "sheet02" and "sheet2", are compared as they are.
+func naturalLess(a, b string) bool {
+	x, y := a, b
+	for a != "" && b != "" {
+		da, db := leadingDigits(a), leadingDigits(b)
+		if da != "" && db != "" {
+			na, nb := strings.TrimLeft(da, "0"), strings.TrimLeft(db, "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			a, b = a[len(da):], b[len(db):]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return x < y
+}
+
+// leadingDigits returns the run of ASCII digits at the start of s.
+func leadingDigits(s string) string {
+	i := 0
+	for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+		i++
+	}
+	return s[:i]
+}
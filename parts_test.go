@@ -0,0 +1,174 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type PartsSuite struct{}
+
+var _ = Suite(&PartsSuite{})
+
+func (p *PartsSuite) TestPartOrder(c *C) {
+	parts := map[string]string{}
+	for _, name := range []string{
+		"xl/worksheets/sheet10.xml",
+		"xl/media/image1.png",
+		"xl/worksheets/sheet2.xml",
+		"xl/styles.xml",
+		"docProps/core.xml",
+		"xl/workbook.xml",
+		"customXml/item1.xml",
+		"[Content_Types].xml",
+		"xl/worksheets/_rels/sheet1.xml.rels",
+		"_rels/.rels",
+		"docProps/app.xml",
+		"xl/worksheets/sheet1.xml",
+	} {
+		parts[name] = ""
+	}
+	c.Assert(PartOrder(parts), DeepEquals, []string{
+		"[Content_Types].xml",
+		"_rels/.rels",
+		"docProps/app.xml",
+		"docProps/core.xml",
+		"xl/workbook.xml",
+		"xl/styles.xml",
+		"xl/worksheets/_rels/sheet1.xml.rels",
+		"xl/worksheets/sheet1.xml",
+		"xl/worksheets/sheet2.xml",
+		"xl/worksheets/sheet10.xml",
+		"xl/media/image1.png",
+		"customXml/item1.xml",
+	})
+}
+
+func (p *PartsSuite) TestNaturalLess(c *C) {
+	c.Assert(naturalLess("sheet2.xml", "sheet10.xml"), Equals, true)
+	c.Assert(naturalLess("sheet10.xml", "sheet2.xml"), Equals, false)
+	c.Assert(naturalLess("sheet02", "sheet2"), Equals, true)
+	c.Assert(naturalLess("sheet2", "sheet02"), Equals, false)
+	c.Assert(naturalLess("sheet2", "sheet2"), Equals, false)
+	c.Assert(naturalLess("a", "ab"), Equals, true)
+	c.Assert(naturalLess("b", "a1"), Equals, false)
+}
+
+// Test that writing the same File twice produces the zip entries in
+// the same, canonical order.
+func (p *PartsSuite) TestWriteOrderIsStable(c *C) {
+	file := NewFile()
+	for _, name := range []string{"One", "Two", "Three"} {
+		sheet, _ := file.AddSheet(name)
+		sheet.AddRow().AddCell().SetString(name)
+	}
+	entries := func() []string {
+		var buf bytes.Buffer
+		c.Assert(file.Write(&buf), IsNil)
+		r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		c.Assert(err, IsNil)
+		var names []string
+		for _, f := range r.File {
+			names = append(names, f.Name)
+		}
+		return names
+	}
+	first := entries()
+	c.Assert(first[0], Equals, "[Content_Types].xml")
+	for i := 0; i < 5; i++ {
+		c.Assert(entries(), DeepEquals, first)
+	}
+}
+
+// Test that the parts Write streams into the zip are those MarshallParts
+// makes.
+func (p *PartsSuite) TestWriteStreamsParts(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	for i := 0; i < 100; i++ {
+		row := sheet.AddRow()
+		row.AddCell().SetInt(i)
+		row.AddCell().SetString("text")
+	}
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+
+	var buf bytes.Buffer
+	c.Assert(file.Write(&buf), IsNil)
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	c.Assert(r.File, HasLen, len(parts))
+	for _, f := range r.File {
+		rc, err := f.Open()
+		c.Assert(err, IsNil)
+		content, err := ioutil.ReadAll(rc)
+		rc.Close()
+		c.Assert(err, IsNil)
+		c.Assert(string(content), Equals, parts[f.Name], Commentf("part %s", f.Name))
+	}
+}
+
+// Test that writing the same File, or the same file read twice, gives
+// the same bytes.
+func (p *PartsSuite) TestWriteIsReproducible(c *C) {
+	created := time.Date(2020, time.March, 4, 5, 6, 7, 0, time.UTC)
+	write := func(file *File) []byte {
+		var buf bytes.Buffer
+		c.Assert(file.Write(&buf), IsNil)
+		return buf.Bytes()
+	}
+	build := func() []byte {
+		file := NewFileWithOptions(CreationDate(created))
+		for _, name := range []string{"One", "Two", "Three"} {
+			sheet, _ := file.AddSheet(name)
+			row := sheet.AddRow()
+			row.AddCell().SetString(name)
+			row.AddCell().SetFloatWithFormat(1.5, "0.000")
+			bold := NewStyle()
+			bold.Font.Bold = true
+			row.AddCell().SetStyle(bold)
+			c.Assert(sheet.SetBackground(makePNG(c, 20, 10), IMAGE_TYPE_PNG), IsNil)
+		}
+		return write(file)
+	}
+	first := build()
+	for i := 0; i < 5; i++ {
+		c.Assert(bytes.Equal(build(), first), Equals, true)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(first), int64(len(first)))
+	c.Assert(err, IsNil)
+	for _, f := range r.File {
+		c.Assert(f.Modified.Equal(partModified), Equals, true, Commentf("part %s", f.Name))
+	}
+
+	read, err := OpenFile("./testdocs/testcelltypes.xlsx")
+	c.Assert(err, IsNil)
+	again, err := OpenFile("./testdocs/testcelltypes.xlsx")
+	c.Assert(err, IsNil)
+	c.Assert(bytes.Equal(write(read), write(again)), Equals, true)
+}
+
+func (p *PartsSuite) TestCreationDate(c *C) {
+	created := time.Date(2020, time.March, 4, 5, 6, 7, 0, time.FixedZone("", 3600))
+	file := NewFileWithOptions(CreationDate(created))
+	sheet, _ := file.AddSheet("Sheet1")
+	sheet.AddRow().AddCell().SetString("figures")
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["docProps/core.xml"], Matches, `(?s).*<dcterms:created xsi:type="dcterms:W3CDTF">2020-03-04T04:06:07Z</dcterms:created></cp:coreProperties>`)
+
+	path := filepath.Join(c.MkDir(), "created.xlsx")
+	c.Assert(file.Save(path), IsNil)
+	info, err := Peek(path)
+	c.Assert(err, IsNil)
+	c.Assert(info.Properties.Created.Equal(created), Equals, true)
+
+	parts, err = NewFile().MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["docProps/core.xml"], Equals, TEMPLATE_DOCPROPS_CORE)
+}
@@ -0,0 +1,222 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"os"
+	"time"
+)
+
+// oleSignature starts every OLE2 compound document.  Password protected
+// XLSX files are wrapped in one, as are legacy XLS files.
+var oleSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// WorkbookInfo holds the metadata of a workbook that can be read
+// without loading its worksheets.  It is returned by Peek.
+type WorkbookInfo struct {
+	Sheets       []SheetInfo
+	DefinedNames []*DefinedName
+	Properties   DocProperties
+	Date1904     bool
+	// Encrypted is set for password protected workbooks, whether
+	// XLSX files or legacy XLS ones.  Nothing else can be read from
+	// those without the password.
+	Encrypted bool
+	// MacroEnabled is set when the workbook carries a VBA project.
+	MacroEnabled bool
+}
+
+// SheetInfo describes a single sheet of a workbook.
+type SheetInfo struct {
+	Name   string
+	Hidden bool
+	// Dimension is the range of cells in use as recorded by the
+	// worksheet, e.g. "A1:C10".  It is empty for chartsheets and
+	// for worksheets that don't record one.
+	Dimension string
+}
+
+// DocProperties holds the core and extended document properties
+// stored in docProps/core.xml and docProps/app.xml.
+type DocProperties struct {
+	Title          string
+	Subject        string
+	Creator        string
+	Keywords       string
+	Description    string
+	LastModifiedBy string
+	Created        time.Time
+	Modified       time.Time
+	Application    string
+	AppVersion     string
+}
+
+// xlsxCoreProperties directly maps the coreProperties element of
+// docProps/core.xml.
+type xlsxCoreProperties struct {
+	Title          string `xml:"http://purl.org/dc/elements/1.1/ title"`
+	Subject        string `xml:"http://purl.org/dc/elements/1.1/ subject"`
+	Creator        string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Keywords       string `xml:"http://schemas.openxmlformats.org/package/2006/metadata/core-properties keywords"`
+	Description    string `xml:"http://purl.org/dc/elements/1.1/ description"`
+	LastModifiedBy string `xml:"http://schemas.openxmlformats.org/package/2006/metadata/core-properties lastModifiedBy"`
+	Created        string `xml:"http://purl.org/dc/terms/ created"`
+	Modified       string `xml:"http://purl.org/dc/terms/ modified"`
+}
+
+// xlsxAppProperties directly maps the Properties element of
+// docProps/app.xml - only the parts Peek reports.
+type xlsxAppProperties struct {
+	Application string `xml:"Application"`
+	AppVersion  string `xml:"AppVersion"`
+}
+
+// Peek returns the metadata of the XLSX file at path - its sheet
+// names and dimensions, defined names and document properties -
+// reading only the workbook, its relationships, the document
+// properties and the first few elements of each worksheet.  It is
+// much faster than OpenFile for large workbooks.
+func Peek(path string) (*WorkbookInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return PeekReaderAt(f, fi.Size())
+}
+
+// PeekReaderAt is like Peek, but reads the XLSX file from r.
+func PeekReaderAt(r io.ReaderAt, size int64) (*WorkbookInfo, error) {
+	if isCompoundFile(r) {
+		return peekXLS(r, size)
+	}
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var workbookFile, workbookRels, coreFile, appFile *zip.File
+	info := new(WorkbookInfo)
+	for _, v := range zr.File {
+		switch normalizePartName(v.Name) {
+		case "xl/workbook.xml":
+			workbookFile = v
+		case "xl/_rels/workbook.xml.rels":
+			workbookRels = v
+		case "docprops/core.xml":
+			coreFile = v
+		case "docprops/app.xml":
+			appFile = v
+		case "xl/vbaproject.bin":
+			info.MacroEnabled = true
+		}
+	}
+	if workbookFile == nil {
+		return nil, &XLSXReaderError{Err: "xl/workbook.xml not found in input xlsx."}
+	}
+
+	sheetXMLMap := make(WorkBookRels)
+	if workbookRels != nil {
+		sheetXMLMap, err = readWorkbookRelationsFromZipFile(workbookRels)
+		if err != nil {
+			return nil, err
+		}
+	}
+	worksheets := worksheetParts(zr.File, sheetXMLMap)
+
+	workbook := new(xlsxWorkbook)
+	if err = decodeZipFile(workbookFile, workbook); err != nil {
+		return nil, err
+	}
+	info.Date1904 = workbook.WorkbookPr.Date1904
+	info.DefinedNames = readDefinedNames(workbook)
+	for _, sheet := range workbook.Sheets.Sheet {
+		sheetInfo := SheetInfo{
+			Name:   sheet.Name,
+			Hidden: sheet.State == sheetStateHidden || sheet.State == sheetStateVeryHidden,
+		}
+		if f := worksheetFileForSheet(sheet, worksheets, sheetXMLMap); f != nil {
+			sheetInfo.Dimension, err = peekDimension(f)
+			if err != nil {
+				return nil, err
+			}
+		}
+		info.Sheets = append(info.Sheets, sheetInfo)
+	}
+
+	if coreFile != nil {
+		core := new(xlsxCoreProperties)
+		if err = decodeZipFile(coreFile, core); err != nil {
+			return nil, err
+		}
+		info.Properties.Title = core.Title
+		info.Properties.Subject = core.Subject
+		info.Properties.Creator = core.Creator
+		info.Properties.Keywords = core.Keywords
+		info.Properties.Description = core.Description
+		info.Properties.LastModifiedBy = core.LastModifiedBy
+		info.Properties.Created, _ = time.Parse(time.RFC3339, core.Created)
+		info.Properties.Modified, _ = time.Parse(time.RFC3339, core.Modified)
+	}
+	if appFile != nil {
+		app := new(xlsxAppProperties)
+		if err = decodeZipFile(appFile, app); err != nil {
+			return nil, err
+		}
+		info.Properties.Application = app.Application
+		info.Properties.AppVersion = app.AppVersion
+	}
+	return info, nil
+}
+
+// decodeZipFile unmarshals the XML content of a file within a zip
+// archive into v.
+func decodeZipFile(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return newPartDecoder(rc).Decode(v)
+}
+
+// peekDimension returns the ref of the dimension element of a
+// worksheet.  The dimension precedes the cell data, so reading stops
+// as soon as either is found.
+func peekDimension(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	decoder := newPartDecoder(rc)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "dimension":
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "ref" {
+					return attr.Value, nil
+				}
+			}
+			return "", nil
+		case "sheetData":
+			return "", nil
+		}
+	}
+}
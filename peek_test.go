@@ -0,0 +1,92 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type PeekSuite struct{}
+
+var _ = Suite(&PeekSuite{})
+
+func (p *PeekSuite) TestPeek(c *C) {
+	info, err := Peek("./testdocs/testfile.xlsx")
+	c.Assert(err, IsNil)
+	c.Assert(info.Encrypted, Equals, false)
+	c.Assert(info.MacroEnabled, Equals, false)
+	c.Assert(info.Date1904, Equals, false)
+	c.Assert(info.Sheets, HasLen, 3)
+	c.Assert(info.Sheets[0], Equals, SheetInfo{Name: "Tabelle1", Dimension: "A1:B2"})
+	c.Assert(info.Sheets[2], Equals, SheetInfo{Name: "Tabelle3", Dimension: "A1"})
+	c.Assert(info.Properties.Creator, Equals, "TealeG")
+	c.Assert(info.Properties.Application, Equals, "Microsoft Excel")
+	c.Assert(info.Properties.Created.Equal(time.Date(2011, 6, 28, 14, 13, 3, 0, time.UTC)), Equals, true)
+}
+
+func (p *PeekSuite) TestPeekChartsheet(c *C) {
+	info, err := Peek("./testdocs/testchartsheet.xlsx")
+	c.Assert(err, IsNil)
+	c.Assert(info.Sheets, HasLen, 2)
+	c.Assert(info.Sheets[0], Equals, SheetInfo{Name: "Chart1"})
+	c.Assert(info.Sheets[1], Equals, SheetInfo{Name: "Sheet1", Dimension: "A1:A5"})
+}
+
+func (p *PeekSuite) TestPeekEncrypted(c *C) {
+	data := makeCompoundFile([]string{"EncryptionInfo", "EncryptedPackage"}, map[string][]byte{
+		"EncryptionInfo":   make([]byte, 200),
+		"EncryptedPackage": make([]byte, 200),
+	})
+	info, err := PeekReaderAt(bytes.NewReader(data), int64(len(data)))
+	c.Assert(err, IsNil)
+	c.Assert(info.Encrypted, Equals, true)
+	c.Assert(info.Sheets, HasLen, 0)
+
+	// An XLS file is only encrypted if it has a FilePass record.
+	data = makeXLS()
+	info, err = PeekReaderAt(bytes.NewReader(data), int64(len(data)))
+	c.Assert(err, IsNil)
+	c.Assert(info.Encrypted, Equals, false)
+	c.Assert(info.Sheets, DeepEquals, []SheetInfo{{Name: "Data"}, {Name: "Hidden", Hidden: true}, {Name: "Chart"}})
+
+	stream := biffRecord(biffBOF, uint16(0x0600), uint16(0x0005), uint16(0), uint16(0), uint32(0), uint32(0))
+	stream = append(stream, biffRecord(biffFilePass, uint16(1), make([]byte, 52))...)
+	stream = append(stream, biffRecord(biffEOF)...)
+	data = makeCompoundFile([]string{"Workbook"}, map[string][]byte{"Workbook": stream})
+	info, err = PeekReaderAt(bytes.NewReader(data), int64(len(data)))
+	c.Assert(err, IsNil)
+	c.Assert(info.Encrypted, Equals, true)
+}
+
+func (p *PeekSuite) TestPeekMacroEnabled(c *C) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("xl/workbook.xml")
+	w.Write([]byte(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><workbookPr date1904="true"/><sheets><sheet name="Macros" sheetId="1" state="hidden"/></sheets><definedNames><definedName name="Total">Macros!$A$1</definedName></definedNames></workbook>`))
+	w, _ = zw.Create("xl/vbaProject.bin")
+	w.Write([]byte{0})
+	c.Assert(zw.Close(), IsNil)
+
+	info, err := PeekReaderAt(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	c.Assert(info.MacroEnabled, Equals, true)
+	c.Assert(info.Date1904, Equals, true)
+	c.Assert(info.Sheets, DeepEquals, []SheetInfo{{Name: "Macros", Hidden: true}})
+	c.Assert(info.DefinedNames, HasLen, 1)
+	c.Assert(info.DefinedNames[0].Name, Equals, "Total")
+	c.Assert(info.DefinedNames[0].RefersTo, Equals, "Macros!$A$1")
+}
+
+func (p *PeekSuite) TestPeekWrittenFile(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Data")
+	sheet.Cell(2, 1).SetString("x")
+	var buf bytes.Buffer
+	c.Assert(file.Write(&buf), IsNil)
+
+	info, err := PeekReaderAt(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	c.Assert(info.Sheets, DeepEquals, []SheetInfo{{Name: "Data", Dimension: "A1:B3"}})
+}
@@ -0,0 +1,151 @@
+package xlsx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Phonetic is the phonetic text, such as the furigana of Japanese,
+// shown over the text of a cell.
+type Phonetic struct {
+	Runs []PhoneticRun
+	// Type is the kind of characters the phonetic text is written
+	// in: "halfwidthKatakana", "fullwidthKatakana", "Hiragana" or
+	// "noConversion".  Excel takes "" to be "fullwidthKatakana".
+	Type string
+	// Alignment is how the phonetic text is aligned over the text it
+	// belongs to: "noControl", "left", "center" or "distributed".
+	// Excel takes "" to be "left".
+	Alignment string
+	// Show is set if the phonetic text is shown in the cell, rather
+	// than only being kept with it.
+	Show bool
+	// Font is the font the phonetic text is shown in, or nil for
+	// the workbook's default font.
+	Font *Font
+	// fontID is the index of the font among those of the style
+	// sheet, as the phonetic text is read or written.
+	fontID int
+}
+
+// PhoneticRun is the phonetic text of the characters of a cell's
+// value from Start up to End, as counted by Excel.
+type PhoneticRun struct {
+	Start, End int
+	Text       string
+}
+
+// Phonetic returns the phonetic text of the cell, or nil if it has
+// none.  A cell whose value is changed loses the phonetic text it had,
+// as that was the reading of the old value.
+func (c *Cell) Phonetic() *Phonetic {
+	if c.phonetic == nil || c.phoneticOf != c.Value {
+		return nil
+	}
+	return c.phonetic
+}
+
+// SetPhonetic gives the cell's value the phonetic text p, or takes
+// away that it has if p is nil.
+func (c *Cell) SetPhonetic(p *Phonetic) {
+	c.phonetic = p
+	c.phoneticOf = c.Value
+}
+
+// setReadPhonetic sets the phonetic text of a cell read, which is shown
+// if its ph attribute, show, is set, finding its font among those of
+// the File's style sheet.  The cell has a copy of its own, as the
+// phonetic text of a shared string is read once for all the cells
+// using it.
+func (c *Cell) setReadPhonetic(p *Phonetic, show bool) {
+	if p == nil {
+		return
+	}
+	cell := *p
+	cell.Runs = append([]PhoneticRun(nil), p.Runs...)
+	cell.Show = show
+	cell.Font, cell.fontID = nil, 0
+	if f := c.file(); f != nil && f.styles != nil && p.fontID > 0 && p.fontID < len(f.styles.Fonts.Font) {
+		font := f.styles.readFont(f.styles.Fonts.Font[p.fontID])
+		cell.Font = &font
+	}
+	c.SetPhonetic(&cell)
+}
+
+// readPhonetic returns the phonetic text of a string, or nil if it has
+// none.
+func readPhonetic(runs []xlsxPhoneticRun, properties *xlsxPhoneticPr) *Phonetic {
+	if len(runs) == 0 && properties == nil {
+		return nil
+	}
+	p := &Phonetic{}
+	for _, run := range runs {
+		p.Runs = append(p.Runs, PhoneticRun{Start: run.Sb, End: run.Eb, Text: run.T})
+	}
+	if properties != nil {
+		p.Type, p.Alignment, p.fontID = properties.Type, properties.Alignment, properties.FontID
+	}
+	return p
+}
+
+// written returns the phonetic text as it is written, with its font
+// added to the style sheet being written.
+func (p *Phonetic) written(styles *xlsxStyleSheet) *Phonetic {
+	written := *p
+	written.fontID = 0
+	if p.Font != nil {
+		// The first font is the default one of the workbook,
+		// which the phonetic text's mustn't become.
+		if len(styles.Fonts.Font) == 0 {
+			styles.addFont(DefaultFont().makeXLSXFont())
+		}
+		written.fontID = styles.addFont(p.Font.makeXLSXFont())
+	}
+	return &written
+}
+
+// xlsx returns the rPh and phoneticPr elements of the phonetic text.
+func (p *Phonetic) xlsx() ([]xlsxPhoneticRun, *xlsxPhoneticPr) {
+	var runs []xlsxPhoneticRun
+	for _, run := range p.Runs {
+		runs = append(runs, xlsxPhoneticRun{Sb: run.Start, Eb: run.End, T: run.Text})
+	}
+	return runs, &xlsxPhoneticPr{FontID: p.fontID, Type: p.Type, Alignment: p.Alignment}
+}
+
+// key returns a string identifying the phonetic text, apart from
+// whether it is shown, which belongs to the cell rather than the
+// string.
+func (p *Phonetic) key() string {
+	var key strings.Builder
+	fmt.Fprintf(&key, "%s\x00%s\x00%d", p.Type, p.Alignment, p.fontID)
+	for _, run := range p.Runs {
+		fmt.Fprintf(&key, "\x00%d:%d:%s", run.Start, run.End, run.Text)
+	}
+	return key.String()
+}
+
+// phonetic writes the rPh and phoneticPr elements of a string, as
+// marshalling them would.
+func (x *xmlWriter) phonetic(runs []xlsxPhoneticRun, properties *xlsxPhoneticPr) {
+	for _, run := range runs {
+		x.raw("<rPh")
+		x.intAttr("sb", run.Sb)
+		x.intAttr("eb", run.Eb)
+		x.raw(">")
+		x.element("t", run.T)
+		x.raw("</rPh>")
+	}
+	if properties == nil {
+		return
+	}
+	x.raw("<phoneticPr")
+	x.intAttr("fontId", properties.FontID)
+	if properties.Type != "" {
+		x.attr("type", properties.Type)
+	}
+	if properties.Alignment != "" {
+		x.attr("alignment", properties.Alignment)
+	}
+	x.raw("></phoneticPr>")
+}
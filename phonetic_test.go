@@ -0,0 +1,98 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type PhoneticSuite struct{}
+
+var _ = Suite(&PhoneticSuite{})
+
+// makePhoneticParts returns the parts of a workbook whose sheet has
+// strings with furigana: a shared one in A1 and A2, shown only in A1,
+// and an inline one in B1, both in a font of their own.
+func makePhoneticParts() map[string]string {
+	return map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Data" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/sharedStrings" Target="sharedStrings.xml"/>` +
+			`<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+			`</Relationships>`,
+		"xl/styles.xml": `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><sz val="6"/><name val="MS PGothic"/><family val="3"/><charset val="128"/></font></fonts>` +
+			`<cellXfs count="1"><xf fontId="0"/></cellXfs></styleSheet>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="3" uniqueCount="2">` +
+			`<si><t>東京都</t><rPh sb="0" eb="2"><t>トウキョウ</t></rPh><rPh sb="2" eb="3"><t>ト</t></rPh><phoneticPr fontId="1" type="Hiragana" alignment="center"/></si>` +
+			`<si><t>plain</t></si>` +
+			`</sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+			`<row r="1"><c r="A1" t="s" ph="1"><v>0</v></c><c r="B1" t="inlineStr"><is><t>大阪</t><rPh sb="0" eb="2"><t>オオサカ</t></rPh><phoneticPr fontId="1"/></is></c><c r="C1" t="s"><v>1</v></c></row>` +
+			`<row r="2"><c r="A2" t="s"><v>0</v></c></row>` +
+			`</sheetData></worksheet>`,
+	}
+}
+
+func (s *PhoneticSuite) TestRead(c *C) {
+	f, err := readZipReader(makeZipReader(c, makePhoneticParts()), nil)
+	c.Assert(err, IsNil)
+	sheet := f.Sheets[0]
+	gothic := &Font{Size: 6, Name: "MS PGothic", Family: 3, Charset: 128}
+	tokyo := &Phonetic{
+		Runs:      []PhoneticRun{{0, 2, "トウキョウ"}, {2, 3, "ト"}},
+		Type:      "Hiragana",
+		Alignment: "center",
+		Show:      true,
+		Font:      gothic,
+	}
+	c.Assert(sheet.Cell(0, 0).Value, Equals, "東京都")
+	c.Assert(sheet.Cell(0, 0).Phonetic(), DeepEquals, tokyo)
+	tokyo.Show = false
+	c.Assert(sheet.Cell(1, 0).Phonetic(), DeepEquals, tokyo)
+	c.Assert(sheet.Cell(0, 1).Value, Equals, "大阪")
+	c.Assert(sheet.Cell(0, 1).Phonetic(), DeepEquals, &Phonetic{Runs: []PhoneticRun{{0, 2, "オオサカ"}}, Font: gothic})
+	c.Assert(sheet.Cell(0, 2).Phonetic(), IsNil)
+
+	// The cells sharing a string have phonetic text of their own.
+	sheet.Cell(0, 0).Phonetic().Runs[0].Text = "ヒガシキョウ"
+	c.Assert(sheet.Cell(1, 0).Phonetic().Runs[0].Text, Equals, "トウキョウ")
+}
+
+func (s *PhoneticSuite) TestRoundTrip(c *C) {
+	f, err := readZipReader(makeZipReader(c, makePhoneticParts()), nil)
+	c.Assert(err, IsNil)
+	sheet := f.Sheets[0]
+	// A cell given a new value loses the reading of the old one.
+	sheet.Cell(1, 0).SetString("東京")
+	sheet.Cell(0, 2).SetPhonetic(&Phonetic{Runs: []PhoneticRun{{0, 5, "プレーン"}}, Show: true})
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/sharedStrings.xml"], Matches, `(?s).*<si><t>東京都</t><rPh sb="0" eb="2"><t>トウキョウ</t></rPh><rPh sb="2" eb="3"><t>ト</t></rPh><phoneticPr fontId="1" type="Hiragana" alignment="center"></phoneticPr></si>`+
+		`<si><t>大阪</t><rPh sb="0" eb="2"><t>オオサカ</t></rPh><phoneticPr fontId="1"></phoneticPr></si>`+
+		`<si><t>plain</t><rPh sb="0" eb="5"><t>プレーン</t></rPh><phoneticPr fontId="0"></phoneticPr></si>`+
+		`<si><t>東京</t></si></sst>`)
+	c.Assert(parts["xl/styles.xml"], Matches, `(?s).*<fonts count="2"><font><sz val="11"/><name val="Calibri"/>.*</font><font><sz val="6"/><name val="MS PGothic"/><family val="3"/><charset val="128"/></font></fonts>.*`)
+	worksheet := parts["xl/worksheets/sheet1.xml"]
+	c.Assert(worksheet, Matches, `(?s).*<c r="A1" s="1" t="s" ph="true"><v>0</v></c><c r="B1" s="1" t="s"><v>1</v></c><c r="C1" s="1" t="s" ph="true"><v>2</v></c>.*`)
+	c.Assert(worksheet, Matches, `(?s).*<c r="A2" s="1" t="s"><v>3</v></c>.*`)
+
+	f, err = readZipReader(makeZipReader(c, makePhoneticParts()), []FileOption{InlineStrings()})
+	c.Assert(err, IsNil)
+	parts, err = f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/worksheets/sheet1.xml"], Matches, `(?s).*<c r="A1" s="1" t="inlineStr" ph="true"><is><t>東京都</t><rPh sb="0" eb="2"><t>トウキョウ</t></rPh><rPh sb="2" eb="3"><t>ト</t></rPh><phoneticPr fontId="1" type="Hiragana" alignment="center"></phoneticPr></is></c>.*`)
+}
+
+func (s *PhoneticSuite) TestSharedStrings(c *C) {
+	rt := NewSharedStringRefTable()
+	rt.isWrite = true
+	p := &Phonetic{Runs: []PhoneticRun{{0, 1, "にち"}}}
+	c.Assert(rt.AddString("日"), Equals, 0)
+	c.Assert(rt.addPhoneticString("日", p), Equals, 1)
+	c.Assert(rt.addPhoneticString("日", &Phonetic{Runs: []PhoneticRun{{0, 1, "にち"}}, Show: true}), Equals, 1)
+	c.Assert(rt.addPhoneticString("日", &Phonetic{Runs: []PhoneticRun{{0, 1, "ひ"}}}), Equals, 2)
+	c.Assert(rt.AddString("日"), Equals, 0)
+	c.Assert(rt.resolvePhonetic(1), Equals, p)
+	c.Assert(rt.resolvePhonetic(0), IsNil)
+}
@@ -0,0 +1,454 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	relTypeDialogsheet    = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/dialogsheet"
+	relTypeMacrosheet     = "http://schemas.microsoft.com/office/2006/relationships/xlMacrosheet"
+	relTypeIntlMacrosheet = "http://schemas.microsoft.com/office/2006/relationships/xlIntlMacrosheet"
+)
+
+// SheetKind is the kind of a sheet that isn't a worksheet.
+type SheetKind int
+
+const (
+	// DialogSheet is an Excel 5 dialog sheet, holding a custom
+	// dialog box.
+	DialogSheet SheetKind = iota
+	// MacroSheet is an Excel 4 macro sheet.
+	MacroSheet
+	// InternationalMacroSheet is an Excel 4 macro sheet whose
+	// macros are written in English, whatever the language of the
+	// application running them.
+	InternationalMacroSheet
+)
+
+func (k SheetKind) String() string {
+	switch k {
+	case DialogSheet:
+		return "dialog sheet"
+	case MacroSheet:
+		return "macro sheet"
+	case InternationalMacroSheet:
+		return "international macro sheet"
+	}
+	return fmt.Sprintf("SheetKind(%d)", int(k))
+}
+
+// relType returns the type of the workbook's relationship to sheets of
+// the kind.
+func (k SheetKind) relType() string {
+	switch k {
+	case MacroSheet:
+		return relTypeMacrosheet
+	case InternationalMacroSheet:
+		return relTypeIntlMacrosheet
+	}
+	return relTypeDialogsheet
+}
+
+// sheetKindOf returns the kind of sheet a relationship of the type
+// given from the workbook refers to, and false if it isn't one that is
+// preserved.
+func sheetKindOf(relType string) (SheetKind, bool) {
+	switch relType {
+	case relTypeDialogsheet:
+		return DialogSheet, true
+	case relTypeMacrosheet:
+		return MacroSheet, true
+	case relTypeIntlMacrosheet:
+		return InternationalMacroSheet, true
+	}
+	return 0, false
+}
+
+// PreservedSheet is a dialog sheet or macro sheet of the workbook a
+// File was read from.  They can't be edited, but are written back as
+// they were read, along with the parts they use, such as the drawings
+// of a dialog box's controls.  As the workbook's shared strings and
+// styles are written anew, the sheet's cells hold their strings inline
+// and the formats of its cells, rows and columns are renumbered.
+type PreservedSheet struct {
+	Name string
+	Kind SheetKind
+	// CodeName is the name VBA refers to the sheet by, if it has one.
+	CodeName   string
+	Hidden     bool
+	VeryHidden bool
+	// Position is the zero based place of the sheet among all the
+	// workbook's sheets, worksheets included.  It is written there,
+	// or after the last worksheet if there are fewer sheets.
+	Position int
+	// parts are the sheet's part, first, and those it refers to.
+	parts []preservedPart
+	// styleRefs are the attributes of the sheet's part that give the
+	// index of a cell format, in order.
+	styleRefs []styleRef
+}
+
+// styleRef is an attribute of a PreservedSheet's part giving the index
+// of a cell format, along with the format it had when read.
+type styleRef struct {
+	// start and end are the offsets of the index in the part.
+	start, end int
+	style      *Style
+	numFmt     string
+}
+
+// preservedPart is a part of a PreservedSheet, as it was read.
+type preservedPart struct {
+	name        string
+	contentType string
+	data        []byte
+	rels        []xlsxWorksheetRelationship
+}
+
+func (s *PreservedSheet) state() string {
+	switch {
+	case s.VeryHidden:
+		return sheetStateVeryHidden
+	case s.Hidden:
+		return sheetStateHidden
+	}
+	return sheetStateVisible
+}
+
+// readPreservedSheet reads the sheet at position among the workbook's
+// sheets, whose part is called partName, with the parts the sheet
+// refers to.  It returns nil if the sheet's part is missing.
+func (f *File) readPreservedSheet(rsheet xlsxSheet, position int, kind SheetKind, partName string) (*PreservedSheet, error) {
+	parts, err := f.readPartTree(fmt.Sprintf("%s '%s'", kind, rsheet.Name), partName)
+	if err != nil || len(parts) == 0 || parts[0].name != partName {
+		return nil, err
+	}
+	data, err := f.inlineSharedStrings(parts[0].data)
+	if err != nil {
+		return nil, fmt.Errorf("%s '%s': %v", kind, rsheet.Name, err)
+	}
+	parts[0].data = data
+	styleRefs, err := f.readStyleRefs(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s '%s': %v", kind, rsheet.Name, err)
+	}
+	return &PreservedSheet{
+		Name:       rsheet.Name,
+		Kind:       kind,
+		CodeName:   preservedCodeName(data),
+		Hidden:     rsheet.State == sheetStateHidden || rsheet.State == sheetStateVeryHidden,
+		VeryHidden: rsheet.State == sheetStateVeryHidden,
+		Position:   position,
+		parts:      parts,
+		styleRefs:  styleRefs,
+	}, nil
+}
+
+var (
+	sharedStringTypeAttr = regexp.MustCompile(`\st\s*=\s*("s"|'s')`)
+	cellStyleAttr        = regexp.MustCompile(`\ss\s*=\s*["'](\d+)["']`)
+	colStyleAttr         = regexp.MustCompile(`\sstyle\s*=\s*["'](\d+)["']`)
+)
+
+// inlineSharedStrings returns the part of a sheet, data, with the
+// cells that refer to the workbook's shared strings holding their
+// strings inline instead, as the shared strings are numbered anew when
+// the workbook is written.
+func (f *File) inlineSharedStrings(data []byte) ([]byte, error) {
+	var out bytes.Buffer
+	last := 0
+	// tagStart and tagEnd are the offsets of the start tag of a cell
+	// of a shared string, valueStart those of its value's start tag,
+	// and value is the value.
+	tagStart, tagEnd, valueStart := -1, -1, -1
+	var value []byte
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		offset := int(d.InputOffset())
+		token, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "c":
+				tagStart, tagEnd, valueStart = -1, -1, -1
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "t" && attr.Name.Space == "" && attr.Value == "s" {
+						tagStart, tagEnd = offset, int(d.InputOffset())
+					}
+				}
+			case "v":
+				if tagStart >= 0 {
+					valueStart, value = offset, nil
+				}
+			}
+		case xml.CharData:
+			if valueStart >= 0 {
+				value = append(value, t...)
+			}
+		case xml.EndElement:
+			if t.Name.Local != "v" || valueStart < 0 {
+				continue
+			}
+			index, err := strconv.Atoi(strings.TrimSpace(string(value)))
+			if err != nil || index < 0 || f.referenceTable == nil || index >= len(f.referenceTable.indexedStrings) {
+				return nil, fmt.Errorf("a cell refers to shared string %q, which doesn't exist", value)
+			}
+			prefix := ""
+			if t.Name.Space != "" {
+				prefix = t.Name.Space + ":"
+			}
+			out.Write(data[last:tagStart])
+			out.Write(sharedStringTypeAttr.ReplaceAll(data[tagStart:tagEnd], []byte(` t="inlineStr"`)))
+			out.Write(data[tagEnd:valueStart])
+			fmt.Fprintf(&out, `<%sis><%st xml:space="preserve">`, prefix, prefix)
+			xml.EscapeText(&out, []byte(f.referenceTable.ResolveSharedString(index)))
+			fmt.Fprintf(&out, `</%st></%sis>`, prefix, prefix)
+			last = int(d.InputOffset())
+			tagStart, tagEnd, valueStart = -1, -1, -1
+		}
+	}
+	if last == 0 {
+		return data, nil
+	}
+	out.Write(data[last:])
+	return out.Bytes(), nil
+}
+
+// readStyleRefs returns the attributes of the part of a sheet, data,
+// that give the index of a cell format: the s attributes of its cells
+// and rows, and the style attributes of its columns.
+func (f *File) readStyleRefs(data []byte) ([]styleRef, error) {
+	var refs []styleRef
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		offset := int(d.InputOffset())
+		token, err := d.RawToken()
+		if err == io.EOF {
+			return refs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		var attr *regexp.Regexp
+		switch start.Name.Local {
+		case "c", "row":
+			attr = cellStyleAttr
+		case "col":
+			attr = colStyleAttr
+		default:
+			continue
+		}
+		m := attr.FindSubmatchIndex(data[offset:d.InputOffset()])
+		if m == nil {
+			continue
+		}
+		index, err := strconv.Atoi(string(data[offset+m[2] : offset+m[3]]))
+		if err != nil || index == 0 {
+			continue
+		}
+		ref := styleRef{start: offset + m[2], end: offset + m[3]}
+		if f.styles != nil && index < len(f.styles.CellXfs.Xf) {
+			ref.style = f.styles.getStyle(index)
+			ref.numFmt = f.styles.getNumberFormat(index)
+		}
+		refs = append(refs, ref)
+	}
+}
+
+// renumberStyles returns the part of the sheet with the indexes of its
+// styleRefs changed to those of their formats among styles, adding the
+// formats to them.
+func (s *PreservedSheet) renumberStyles(styles *xlsxStyleSheet) []byte {
+	data := s.parts[0].data
+	if len(s.styleRefs) == 0 {
+		return data
+	}
+	var out bytes.Buffer
+	last := 0
+	for _, ref := range s.styleRefs {
+		xfId := 0
+		if ref.style != nil {
+			xfId = handleStyleForXLSX(ref.style, styles.newNumFmt(ref.numFmt).NumFmtId, styles)
+		}
+		out.Write(data[last:ref.start])
+		out.WriteString(strconv.Itoa(xfId))
+		last = ref.end
+	}
+	out.Write(data[last:])
+	return out.Bytes()
+}
+
+// readPartTree reads the parts called roots, in order, and those they
+// refer to, directly or through others, for what, which names them in
+// warnings.  Parts that are missing are left out, with a warning.
+func (f *File) readPartTree(what string, roots ...string) ([]preservedPart, error) {
+	var parts []preservedPart
+	seen := make(map[string]bool)
+	queue := make([]string, 0, len(roots))
+	for _, root := range roots {
+		if !seen[root] {
+			seen[root] = true
+			queue = append(queue, root)
+		}
+	}
+	for ; len(queue) > 0; queue = queue[1:] {
+		name := queue[0]
+		zf := f.parts[name]
+		if zf == nil {
+			f.warn(fmt.Sprintf("%s: part %s doesn't exist", what, name))
+			continue
+		}
+		data, err := f.readBinaryPart(zf)
+		if err != nil {
+			return nil, err
+		}
+		rels, err := f.readRelationships(name)
+		if err != nil {
+			return nil, err
+		}
+		part := preservedPart{name: name, contentType: f.types.contentType(name), data: data}
+		for _, rel := range rels {
+			part.rels = append(part.rels, rel)
+			if rel.TargetMode != "External" && !seen[rel.Target] {
+				seen[rel.Target] = true
+				queue = append(queue, rel.Target)
+			}
+		}
+		sort.Slice(part.rels, func(i, j int) bool { return part.rels[i].Id < part.rels[j].Id })
+		parts = append(parts, part)
+	}
+	return parts, nil
+}
+
+// addPreservedParts adds parts read with readPartTree to packaged,
+// relating them to each other in rels as they were, and returns the
+// names they are written with.  Parts whose names have been taken are
+// renamed.
+func addPreservedParts(packaged *packageParts, rels *packageRelationships, parts []preservedPart) (map[string]string, error) {
+	taken := func(name string) bool {
+		_, ok := packaged.content[name]
+		return ok || packaged.writers[name] != nil
+	}
+	names := make(map[string]string, len(parts))
+	for _, part := range parts {
+		name := part.name
+		ext := path.Ext(name)
+		for n := 2; taken(name); n++ {
+			name = strings.TrimSuffix(part.name, ext) + "_" + strconv.Itoa(n) + ext
+		}
+		names[part.name] = name
+		packaged.content[name] = string(part.data)
+		if part.contentType != "" {
+			packaged.setContentType(name, part.contentType)
+		}
+	}
+	for _, part := range parts {
+		if len(part.rels) == 0 {
+			continue
+		}
+		xRels := newXlsxWorksheetRelationships()
+		for _, rel := range part.rels {
+			rel := rel
+			rel.XMLName = xml.Name{}
+			if rel.TargetMode != "External" {
+				name, ok := names[rel.Target]
+				if !ok {
+					continue
+				}
+				rel.Target = "/" + name
+			}
+			xRels.Relationships = append(xRels.Relationships, &rel)
+		}
+		body, err := xml.Marshal(xRels)
+		if err != nil {
+			return nil, err
+		}
+		rels.keep(names[part.name], []byte(xmlHeader+string(body)))
+	}
+	return names, nil
+}
+
+// readContentTypes reads the content types of the parts of the package
+// the File is read from into its types.
+func (f *File) readContentTypes() error {
+	f.types = new(xlsxTypes)
+	zf := f.parts["[content_types].xml"]
+	if zf == nil {
+		return nil
+	}
+	rc, err := f.openPart(zf)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return newPartDecoder(rc).Decode(f.types)
+}
+
+// sheetPlaces returns the places among all the sheets of the workbook
+// of n worksheets, in order, and of the File's PreservedSheets, which
+// are put at their Positions as far as they can be.
+func (f *File) sheetPlaces(n int) (worksheets, preserved []int) {
+	order := make([]int, len(f.PreservedSheets))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return f.PreservedSheets[order[i]].Position < f.PreservedSheets[order[j]].Position
+	})
+	worksheets = make([]int, 0, n)
+	preserved = make([]int, len(f.PreservedSheets))
+	next := 0
+	for place := 0; place < n+len(order); place++ {
+		if next < len(order) && (f.PreservedSheets[order[next]].Position <= place || len(worksheets) == n) {
+			preserved[order[next]] = place
+			next++
+			continue
+		}
+		worksheets = append(worksheets, place)
+	}
+	return worksheets, preserved
+}
+
+// makePreservedSheetParts adds the parts of the File's PreservedSheets
+// to packaged, and the sheets to the workbook at their places, relating
+// them to it in rels.
+func (f *File) makePreservedSheetParts(packaged *packageParts, workbook *xlsxWorkbook, rels *packageRelationships, places []int, sheetId int) error {
+	for i, sheet := range f.PreservedSheets {
+		for _, other := range workbook.Sheets.Sheet {
+			if strings.EqualFold(other.Name, sheet.Name) {
+				return fmt.Errorf("sheet '%s' has the name of the %s '%s'", other.Name, sheet.Kind, sheet.Name)
+			}
+		}
+		parts := append([]preservedPart(nil), sheet.parts...)
+		parts[0].data = sheet.renumberStyles(f.styles)
+		names, err := addPreservedParts(packaged, rels, parts)
+		if err != nil {
+			return err
+		}
+		workbook.Sheets.Sheet[places[i]] = xlsxSheet{
+			Name:    sheet.Name,
+			SheetId: strconv.Itoa(sheetId + i),
+			Id:      rels.of("xl/workbook.xml").add(sheet.Kind.relType(), names[sheet.parts[0].name]),
+			State:   sheet.state(),
+		}
+	}
+	return nil
+}
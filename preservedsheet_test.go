@@ -0,0 +1,149 @@
+package xlsx
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type PreservedSheetSuite struct{}
+
+var _ = Suite(&PreservedSheetSuite{})
+
+const (
+	dialogSheetXML   = `<dialogsheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheetViews><sheetView zoomScale="100" workbookViewId="0"/></sheetViews><drawing r:id="rId1"/></dialogsheet>`
+	dialogDrawingXML = `<xdr:wsDr xmlns:xdr="http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing"/>`
+	macroSheetXML    = `<xm:macrosheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:xm="http://schemas.microsoft.com/office/excel/2006/main"><sheetData><row r="1"><c r="A1"><f>HALT()</f></c></row></sheetData></xm:macrosheet>`
+)
+
+// preservedSheetParts are the parts of a workbook with a dialog sheet
+// and a macro sheet between its worksheets.  The dialog sheet's sheetId
+// is that of the name of the second worksheet's part.
+func preservedSheetParts() map[string]string {
+	return map[string]string{
+		"[Content_Types].xml": `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/dialogsheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.dialogsheet+xml"/>` +
+			`<Override PartName="/xl/macrosheets/sheet1.xml" ContentType="application/vnd.ms-excel.macrosheet+xml"/>` +
+			`<Override PartName="/xl/drawings/drawing1.xml" ContentType="application/vnd.openxmlformats-officedocument.drawing+xml"/></Types>`,
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><bookViews><workbookView activeTab="3"/></bookViews><sheets>` +
+			`<sheet name="Data" sheetId="1" r:id="rId1"/><sheet name="Dialog1" sheetId="2" r:id="rId2"/><sheet name="Macro1" sheetId="3" state="hidden" r:id="rId3"/><sheet name="Summary" sheetId="4" r:id="rId4"/></sheets>` +
+			`<definedNames><definedName name="Auto_Open" localSheetId="2">Macro1!$A$1</definedName><definedName name="Total" localSheetId="3">Summary!$A$1</definedName></definedNames></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/dialogsheet" Target="dialogsheets/sheet1.xml"/>` +
+			`<Relationship Id="rId3" Type="http://schemas.microsoft.com/office/2006/relationships/xlMacrosheet" Target="macrosheets/sheet1.xml"/>` +
+			`<Relationship Id="rId4" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet2.xml"/>` +
+			`</Relationships>`,
+		"xl/worksheets/sheet1.xml":   `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData><row r="1"><c r="A1" t="inlineStr"><is><t>data</t></is></c></row></sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml":   `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData><row r="1"><c r="A1"><v>42</v></c></row></sheetData></worksheet>`,
+		"xl/dialogsheets/sheet1.xml": dialogSheetXML,
+		"xl/dialogsheets/_rels/sheet1.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/drawing" Target="../drawings/drawing1.xml"/>` +
+			`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="http://example.com/" TargetMode="External"/>` +
+			`</Relationships>`,
+		"xl/drawings/drawing1.xml":  dialogDrawingXML,
+		"xl/macrosheets/sheet1.xml": macroSheetXML,
+	}
+}
+
+func (s *PreservedSheetSuite) TestRead(c *C) {
+	f, err := ReadZipReader(makeZipReader(c, preservedSheetParts()))
+	c.Assert(err, IsNil)
+	c.Assert(f.Sheets, HasLen, 2)
+	c.Assert(f.Sheets[0].Name, Equals, "Data")
+	c.Assert(f.Sheets[1].Name, Equals, "Summary")
+	c.Assert(f.Sheets[1].Cell(0, 0).Value, Equals, "42")
+	c.Assert(f.Sheets[1].Selected, Equals, true)
+	c.Assert(f.PreservedSheets, HasLen, 2)
+	dialog, macro := f.PreservedSheets[0], f.PreservedSheets[1]
+	c.Assert([]interface{}{dialog.Name, dialog.Kind, dialog.Position, dialog.Hidden}, DeepEquals, []interface{}{"Dialog1", DialogSheet, 1, false})
+	c.Assert([]interface{}{macro.Name, macro.Kind, macro.Position, macro.Hidden}, DeepEquals, []interface{}{"Macro1", MacroSheet, 2, true})
+	c.Assert(dialog.parts, HasLen, 2)
+	c.Assert(f.Names[0].Sheet, Equals, "Macro1")
+	c.Assert(f.Names[1].Sheet, Equals, "Summary")
+}
+
+func (s *PreservedSheetSuite) TestWrite(c *C) {
+	f, err := ReadZipReader(makeZipReader(c, preservedSheetParts()))
+	c.Assert(err, IsNil)
+	// The worksheet's picture takes the name of the dialog sheet's
+	// drawing, which is given another.
+	f.Sheets[0].Drawings = append(f.Sheets[0].Drawings, Drawing{Sheet: f.Sheets[0], ImageData: makePNG(c, 4, 4),
+		ImageType: IMAGE_TYPE_PNG, Width: 4, Height: 4})
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/workbook.xml"], Matches, `(?s).*<workbookView [^>]*activeTab="3".*`)
+	c.Assert(parts["xl/workbook.xml"], Matches, `(?s).*<sheets><sheet name="Data" sheetId="1" r:id="rId1" state="visible"></sheet>`+
+		`<sheet name="Dialog1" sheetId="3" r:id="rId3" state="visible"></sheet><sheet name="Macro1" sheetId="4" r:id="rId4" state="hidden"></sheet>`+
+		`<sheet name="Summary" sheetId="2" r:id="rId2" state="visible"></sheet></sheets>.*`)
+	c.Assert(parts["xl/workbook.xml"], Matches, `(?s).*<definedName name="Auto_Open" localSheetId="2">Macro1!\$A\$1</definedName><definedName name="Total" localSheetId="3">.*`)
+	c.Assert(parts["xl/_rels/workbook.xml.rels"], Matches, `(?s).*<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/dialogsheet" Target="dialogsheets/sheet1.xml"></Relationship>`+
+		`<Relationship Id="rId4" Type="http://schemas.microsoft.com/office/2006/relationships/xlMacrosheet" Target="macrosheets/sheet1.xml"></Relationship>.*`)
+	c.Assert(parts["xl/dialogsheets/sheet1.xml"], Equals, dialogSheetXML)
+	c.Assert(parts["xl/macrosheets/sheet1.xml"], Equals, macroSheetXML)
+	c.Assert(parts["xl/drawings/drawing1.xml"], Not(Equals), dialogDrawingXML)
+	c.Assert(parts["xl/drawings/drawing1_2.xml"], Equals, dialogDrawingXML)
+	c.Assert(parts["xl/dialogsheets/_rels/sheet1.xml.rels"], Equals, xmlHeader+`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`+
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/drawing" Target="/xl/drawings/drawing1_2.xml"></Relationship>`+
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="http://example.com/" TargetMode="External"></Relationship>`+
+		`</Relationships>`)
+	c.Assert(parts["[Content_Types].xml"], Matches, `(?s).*<Override PartName="/xl/macrosheets/sheet1.xml" ContentType="application/vnd.ms-excel.macrosheet\+xml"></Override>.*`)
+	c.Assert(parts["[Content_Types].xml"], Matches, `(?s).*<Override PartName="/xl/drawings/drawing1_2.xml" ContentType="application/vnd.openxmlformats-officedocument.drawing\+xml"></Override>.*`)
+
+	written, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	c.Assert(written.Sheets, HasLen, 2)
+	c.Assert(written.PreservedSheets, HasLen, 2)
+	c.Assert(written.PreservedSheets[0].parts[1].data, DeepEquals, []byte(dialogDrawingXML))
+	c.Assert(written.Sheets[0].Pictures(), HasLen, 1)
+
+	_, err = f.AddSheet("Macro1")
+	c.Assert(err, IsNil)
+	_, err = f.MarshallParts()
+	c.Assert(err, ErrorMatches, "sheet 'Macro1' has the name of the macro sheet 'Macro1'")
+}
+
+func (s *PreservedSheetSuite) TestSheetPlaces(c *C) {
+	f := NewFile()
+	f.PreservedSheets = []*PreservedSheet{{Position: 7}, {Position: 0}, {Position: 2}}
+	worksheets, preserved := f.sheetPlaces(3)
+	c.Assert(worksheets, DeepEquals, []int{1, 3, 4})
+	c.Assert(preserved, DeepEquals, []int{5, 0, 2})
+}
+
+// The strings and styles of a macro sheet's cells are numbered anew with
+// those of the worksheets when the workbook is written.
+func (s *PreservedSheetSuite) TestWriteStringsAndStyles(c *C) {
+	parts := preservedSheetParts()
+	parts["xl/_rels/workbook.xml.rels"] = strings.Replace(parts["xl/_rels/workbook.xml.rels"], `</Relationships>`,
+		`<Relationship Id="rId5" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/sharedStrings" Target="sharedStrings.xml"/>`+
+			`<Relationship Id="rId6" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`, 1)
+	parts["xl/sharedStrings.xml"] = `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="2" uniqueCount="2"><si><t>unused</t></si><si><t>Tom &amp; Jerry</t></si></sst>`
+	parts["xl/styles.xml"] = `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="1"><fill><patternFill patternType="none"/></fill></fills><borders count="1"><border/></borders>` +
+		`<cellXfs count="3"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/>` +
+		`<xf numFmtId="10" fontId="1" fillId="0" borderId="0" applyFont="1" applyNumberFormat="1"/></cellXfs></styleSheet>`
+	parts["xl/macrosheets/sheet1.xml"] = `<xm:macrosheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:xm="http://schemas.microsoft.com/office/excel/2006/main">` +
+		`<cols><col min="1" max="1" style="2"/></cols><sheetData><row r="1" s="2" customFormat="1"><c r="A1" t="s" s="2"><v>1</v></c><c r="B1" s="0"><v>3</v></c></row></sheetData></xm:macrosheet>`
+	f, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	f.Sheets[0].Cell(0, 0).SetString("written first")
+
+	written, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	macro := written["xl/macrosheets/sheet1.xml"]
+	c.Assert(macro, Matches, `.*<c r="A1" t="inlineStr" s="([1-9]\d*)"><is><t xml:space="preserve">Tom &amp; Jerry</t></is></c><c r="B1" s="0">.*`)
+	xfId := regexp.MustCompile(`<c r="A1" t="inlineStr" s="(\d+)"`).FindStringSubmatch(macro)[1]
+	c.Assert(macro, Matches, `.*<col min="1" max="1" style="`+xfId+`"/>.*<row r="1" s="`+xfId+`" customFormat="1">.*`)
+
+	reread, err := ReadZipReader(makeZipReader(c, written))
+	c.Assert(err, IsNil)
+	index, _ := strconv.Atoi(xfId)
+	c.Assert(reread.styles.getStyle(index).Font.Bold, Equals, true)
+	c.Assert(reread.styles.getNumberFormat(index), Equals, "0.00%")
+	c.Assert(reread.PreservedSheets[1].parts[0].data, DeepEquals, []byte(macro))
+}
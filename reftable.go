@@ -1,9 +1,26 @@
 package xlsx
 
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+)
+
 type RefTable struct {
 	indexedStrings []string
 	knownStrings   map[string]int
 	isWrite        bool
+	// duplicates is set when a table being written keeps each
+	// string added as an entry of its own, rather than giving equal
+	// strings the same index.
+	duplicates bool
+	// inline is set when the strings of the cells being written go
+	// in the cells themselves, rather than in the table.
+	inline bool
+	// phonetics holds the phonetic text of the strings that have
+	// any, by index.
+	phonetics map[int]*Phonetic
 }
 
 // NewSharedStringRefTable() creates a new, empty RefTable.
@@ -13,6 +30,16 @@ func NewSharedStringRefTable() *RefTable {
 	return &rt
 }
 
+// NewSharedStringRefTableWithCapacity creates a new, empty RefTable
+// with room for capacity distinct strings, so that one filled with
+// millions of them needn't keep growing.
+func NewSharedStringRefTableWithCapacity(capacity int) *RefTable {
+	rt := RefTable{}
+	rt.indexedStrings = make([]string, 0, capacity)
+	rt.knownStrings = make(map[string]int, capacity)
+	return &rt
+}
+
 // MakeSharedStringRefTable() takes an xlsxSST struct and converts
 // it's contents to an slice of strings used to refer to string values
 // by numeric index - this is the model used within XLSX worksheet (a
@@ -21,17 +48,113 @@ func MakeSharedStringRefTable(source *xlsxSST) *RefTable {
 	reftable := NewSharedStringRefTable()
 	reftable.isWrite = false
 	for _, si := range source.SI {
-		if len(si.R) > 0 {
-			newString := ""
-			for j := 0; j < len(si.R); j++ {
-				newString = newString + si.R[j].T
+		reftable.addPhoneticString(si.text(), readPhonetic(si.RPh, si.PhoneticPr))
+	}
+	return reftable
+}
+
+// maxSharedStringsCapacity bounds the room made for the strings of a
+// table as it is read, which is taken from its uniqueCount attribute
+// and so can't be trusted.
+const maxSharedStringsCapacity = 1 << 16
+
+// readSharedStrings reads a reference table from a sharedStrings part
+// as it is decoded, rather than decoding the whole part first, so that
+// nothing but the strings themselves is kept.  Each string is built in
+// a buffer that is reused, and strings held by the table more than once
+// share a single copy.  It returns the number of distinct strings, too.
+func readSharedStrings(decoder *xml.Decoder) (*RefTable, int, error) {
+	reftable := NewSharedStringRefTable()
+	interned := make(map[string]string)
+	var text []byte
+	// phonetic is the phonetic text of the string being read, if it
+	// has any.
+	var phonetic *Phonetic
+	// stack holds the names of the elements being read, from the sst
+	// element down.
+	var stack []string
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		switch tok := token.(type) {
+		case xml.StartElement:
+			if len(stack) == 0 {
+				if tok.Name.Local != "sst" {
+					return nil, 0, fmt.Errorf("expected element type <sst> but have <%s>", tok.Name.Local)
+				}
+				for _, attr := range tok.Attr {
+					if n, err := strconv.Atoi(attr.Value); attr.Name.Local == "uniqueCount" && err == nil && n > 0 {
+						if n > maxSharedStringsCapacity {
+							n = maxSharedStringsCapacity
+						}
+						reftable.indexedStrings = make([]string, 0, n)
+					}
+				}
+			}
+			stack = append(stack, tok.Name.Local)
+			switch {
+			case len(stack) == 2:
+				text = text[:0]
+				phonetic = nil
+			case len(stack) == 3 && stack[1] == "si" && (tok.Name.Local == "rPh" || tok.Name.Local == "phoneticPr"):
+				if phonetic == nil {
+					phonetic = &Phonetic{}
+				}
+				readPhoneticAttrs(phonetic, tok)
+			}
+		case xml.CharData:
+			// The text of an si element is in its t element,
+			// or those of its runs of rich text, but not in
+			// those of its phonetic runs.
+			if n := len(stack); n >= 3 && stack[n-1] == "t" && stack[1] == "si" &&
+				(n == 3 || n == 4 && stack[2] == "r") {
+				text = append(text, tok...)
+			} else if n == 4 && stack[3] == "t" && stack[2] == "rPh" && stack[1] == "si" {
+				run := &phonetic.Runs[len(phonetic.Runs)-1]
+				run.Text += string(tok)
 			}
-			reftable.AddString(newString)
-		} else {
-			reftable.AddString(si.T)
+		case xml.EndElement:
+			if len(stack) == 2 && stack[1] == "si" {
+				str, ok := interned[string(text)]
+				if !ok {
+					str = string(text)
+					interned[str] = str
+				}
+				reftable.addPhoneticString(str, phonetic)
+			}
+			stack = stack[:len(stack)-1]
 		}
 	}
-	return reftable
+	return reftable, len(interned), nil
+}
+
+// readPhoneticAttrs adds what the attributes of an rPh or phoneticPr
+// element say to the phonetic text of a string: a run for the first,
+// or how the text is shown for the second.
+func readPhoneticAttrs(p *Phonetic, tok xml.StartElement) {
+	var run PhoneticRun
+	for _, attr := range tok.Attr {
+		switch attr.Name.Local {
+		case "sb":
+			run.Start, _ = strconv.Atoi(attr.Value)
+		case "eb":
+			run.End, _ = strconv.Atoi(attr.Value)
+		case "type":
+			p.Type = attr.Value
+		case "alignment":
+			p.Alignment = attr.Value
+		case "fontId":
+			p.fontID, _ = strconv.Atoi(attr.Value)
+		}
+	}
+	if tok.Name.Local == "rPh" {
+		p.Runs = append(p.Runs, run)
+	}
 }
 
 // makeXlsxSST() takes a RefTable and returns and
@@ -40,9 +163,12 @@ func (rt *RefTable) makeXLSXSST() xlsxSST {
 	sst := xlsxSST{}
 	sst.Count = len(rt.indexedStrings)
 	sst.UniqueCount = sst.Count
-	for _, ref := range rt.indexedStrings {
+	for i, ref := range rt.indexedStrings {
 		si := xlsxSI{}
 		si.T = ref
+		if p := rt.phonetics[i]; p != nil {
+			si.RPh, si.PhoneticPr = p.xlsx()
+		}
 		sst.SI = append(sst.SI, si)
 	}
 	return sst
@@ -60,7 +186,7 @@ func (rt *RefTable) ResolveSharedString(index int) string {
 // numeric index.  If the string already exists then it simply returns
 // the existing index.
 func (rt *RefTable) AddString(str string) int {
-	if rt.isWrite {
+	if rt.isWrite && !rt.duplicates {
 		index, ok := rt.knownStrings[str]
 		if ok {
 			return index
@@ -68,10 +194,46 @@ func (rt *RefTable) AddString(str string) int {
 	}
 	rt.indexedStrings = append(rt.indexedStrings, str)
 	index := len(rt.indexedStrings) - 1
-	rt.knownStrings[str] = index
+	// Only a table being written looks strings up, so one read from
+	// a file needn't hold them twice.
+	if rt.isWrite && !rt.duplicates {
+		rt.knownStrings[str] = index
+	}
+	return index
+}
+
+// addPhoneticString adds a string with phonetic text p to the table,
+// as AddString does, or just the string if p is nil.  A string is only
+// the same as one already in the table if their phonetic text is the
+// same too.
+func (rt *RefTable) addPhoneticString(str string, p *Phonetic) int {
+	if p == nil {
+		return rt.AddString(str)
+	}
+	key := str + "\x00" + p.key()
+	if rt.isWrite && !rt.duplicates {
+		if index, ok := rt.knownStrings[key]; ok {
+			return index
+		}
+	}
+	rt.indexedStrings = append(rt.indexedStrings, str)
+	index := len(rt.indexedStrings) - 1
+	if rt.phonetics == nil {
+		rt.phonetics = make(map[int]*Phonetic)
+	}
+	rt.phonetics[index] = p
+	if rt.isWrite && !rt.duplicates {
+		rt.knownStrings[key] = index
+	}
 	return index
 }
 
+// resolvePhonetic returns the phonetic text of the string at index, or
+// nil if it has none.
+func (rt *RefTable) resolvePhonetic(index int) *Phonetic {
+	return rt.phonetics[index]
+}
+
 func (rt *RefTable) Length() int {
 	return len(rt.indexedStrings)
 }
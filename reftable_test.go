@@ -3,6 +3,9 @@ package xlsx
 import (
 	"bytes"
 	"encoding/xml"
+	"fmt"
+	"strings"
+	"testing"
 
 	. "gopkg.in/check.v1"
 )
@@ -123,3 +126,87 @@ func (s *RefTableSuite) TestRefTableWriteAddString(c *C) {
 	c.Assert(index2, Equals, 0)
 	c.Assert(refTable.ResolveSharedString(0), Equals, "Foo")
 }
+
+func (s *RefTableSuite) TestRefTableWriteAddStringWithoutDeduplication(c *C) {
+	refTable := NewSharedStringRefTableWithCapacity(2)
+	refTable.isWrite = true
+	refTable.duplicates = true
+	c.Assert(refTable.AddString("Foo"), Equals, 0)
+	c.Assert(refTable.AddString("Foo"), Equals, 1)
+	c.Assert(refTable.knownStrings, HasLen, 0)
+	c.Assert(refTable.Length(), Equals, 2)
+}
+
+func (s *RefTableSuite) TestStringOptions(c *C) {
+	fill := func(file *File) {
+		sheet, _ := file.AddSheet("Sheet1")
+		for _, text := range []string{"Foo", "Bar", "Foo"} {
+			sheet.AddRow().AddCell().SetString(text)
+		}
+	}
+
+	file := NewFileWithOptions(InlineStrings())
+	fill(file)
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/worksheets/sheet1.xml"], Matches, `(?s).*<c r="A1" s="1" t="inlineStr"><is><t>Foo</t></is></c>.*`)
+	c.Assert(parts["xl/sharedStrings.xml"], Matches, `(?s).*<sst [^>]*count="0" uniqueCount="0"></sst>`)
+	written, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	c.Assert(written.Sheets[0].Cell(2, 0).Value, Equals, "Foo")
+
+	file = NewFileWithOptions(SharedStrings(1000, false))
+	fill(file)
+	parts, err = file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/sharedStrings.xml"], Matches, `(?s).*count="3" uniqueCount="3".*`)
+	c.Assert(parts["xl/worksheets/sheet1.xml"], Matches, `(?s).*<c r="A3" s="1" t="s"><v>2</v></c>.*`)
+
+	file = NewFileWithOptions(SharedStrings(1000, true))
+	fill(file)
+	parts, err = file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/worksheets/sheet1.xml"], Matches, `(?s).*<c r="A3" s="1" t="s"><v>0</v></c>.*`)
+}
+
+func (s *RefTableSuite) TestReadSharedStrings(c *C) {
+	reftable, distinct, err := readSharedStrings(newPartDecoder(s.SharedStringsXML))
+	c.Assert(err, IsNil)
+	c.Assert(distinct, Equals, 4)
+	c.Assert(reftable.indexedStrings, DeepEquals, []string{"Foo", "Bar", "Baz ", "Quuk"})
+	c.Assert(reftable.isWrite, Equals, false)
+
+	// Runs of rich text are joined, phonetic runs are left out, and a
+	// string the table holds twice is read both times.
+	reftable, distinct, err = readSharedStrings(newPartDecoder(strings.NewReader(`<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="4" uniqueCount="4">
+  <si><r><rPr><b/></rPr><t>Bold</t></r><r><t xml:space="preserve"> text</t></r></si>
+  <si><t>東京</t><rPh sb="0" eb="2"><t>トウキョウ</t></rPh><phoneticPr fontId="1"/></si>
+  <si><t/></si>
+  <si><t>Foo</t></si>
+  <si><t>Foo</t></si>
+</sst>`)))
+	c.Assert(err, IsNil)
+	c.Assert(reftable.indexedStrings, DeepEquals, []string{"Bold text", "東京", "", "Foo", "Foo"})
+	c.Assert(distinct, Equals, 4)
+
+	_, _, err = readSharedStrings(newPartDecoder(strings.NewReader(`<worksheet/>`)))
+	c.Assert(err, ErrorMatches, "expected element type <sst> but have <worksheet>")
+	_, _, err = readSharedStrings(newPartDecoder(strings.NewReader(`<sst><si><t>Foo</si></sst>`)))
+	c.Assert(err, NotNil)
+}
+
+func BenchmarkReadSharedStrings(b *testing.B) {
+	var sst bytes.Buffer
+	sst.WriteString(`<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" uniqueCount="10000">`)
+	for i := 0; i < 10000; i++ {
+		fmt.Fprintf(&sst, `<si><t>String number %d, which is a little long</t></si>`, i%2500)
+	}
+	sst.WriteString(`</sst>`)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := readSharedStrings(newPartDecoder(bytes.NewReader(sst.Bytes()))); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	relTypeOfficeDocument = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument"
+	relTypeCoreProperties = "http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties"
+	relTypeExtendedProps  = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/extended-properties"
+	relTypeWorksheet      = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet"
+	relTypeSharedStrings  = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/sharedStrings"
+	relTypeTheme          = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/theme"
+	relTypeStyles         = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles"
+)
+
+// packageRelationships are the relationships between the parts of a
+// package being written, from which the package's relationships parts
+// are made.  Each part's relationships are given their ids as they are
+// added, so the id to refer to a part by is known as soon as the
+// relationship to it is.
+type packageRelationships struct {
+	parts map[string]*partRelationships
+}
+
+// partRelationships are the relationships of one part of a package to
+// others, in the order they were added.
+type partRelationships struct {
+	// source is the name of the part, or "" for the package itself.
+	source string
+	rels   []partRelationship
+	// kept is a relationships part that was read and is written
+	// back as it was, in place of rels.
+	kept []byte
+}
+
+// partRelationship is a relationship of a part to another part of the
+// package, by the other part's name, or to something outside it.
+type partRelationship struct {
+	id, relType, target string
+	external            bool
+}
+
+func newPackageRelationships() *packageRelationships {
+	return &packageRelationships{parts: make(map[string]*partRelationships)}
+}
+
+// of returns the relationships of the part called source, "" being the
+// package itself.
+func (p *packageRelationships) of(source string) *partRelationships {
+	r, ok := p.parts[source]
+	if !ok {
+		r = &partRelationships{source: source}
+		p.parts[source] = r
+	}
+	return r
+}
+
+// keep has the relationships of the part called source written just as
+// content, a relationships part that was read, gives them.
+func (p *packageRelationships) keep(source string, content []byte) {
+	p.of(source).kept = content
+}
+
+// add relates the part to the part called target, such as
+// "xl/media/image1.png", in the way relType says, and returns the id of
+// the relationship.  Relating the part to the same target in the same
+// way again returns the same id.
+func (r *partRelationships) add(relType, target string) string {
+	return r.relate(relType, target, false)
+}
+
+// addExternal relates the part to target, which is outside the package,
+// such as another workbook, and returns the id of the relationship.
+func (r *partRelationships) addExternal(relType, target string) string {
+	return r.relate(relType, target, true)
+}
+
+func (r *partRelationships) relate(relType, target string, external bool) string {
+	for _, rel := range r.rels {
+		if rel.relType == relType && rel.target == target && rel.external == external {
+			return rel.id
+		}
+	}
+	id := fmt.Sprintf("rId%d", len(r.rels)+1)
+	r.rels = append(r.rels, partRelationship{id: id, relType: relType, target: target, external: external})
+	return id
+}
+
+// check returns an error if a part is related to another that parts
+// doesn't hold, or that has no relationships part to hold its own.
+func (p *packageRelationships) check(parts *packageParts) error {
+	for _, source := range p.sources() {
+		if _, ok := parts.content[source]; source != "" && !ok && parts.writers[source] == nil {
+			return fmt.Errorf("relationships of %s, which isn't in the package", source)
+		}
+		for _, rel := range p.parts[source].rels {
+			if rel.external {
+				continue
+			}
+			if _, ok := parts.content[rel.target]; !ok && parts.writers[rel.target] == nil {
+				from := source
+				if from == "" {
+					from = "the package"
+				}
+				return fmt.Errorf("relationship %s of %s refers to %s, which isn't in the package", rel.id, from, rel.target)
+			}
+		}
+	}
+	return nil
+}
+
+// sources returns the names of the parts with relationships, in order.
+func (p *packageRelationships) sources() []string {
+	sources := make([]string, 0, len(p.parts))
+	for source, r := range p.parts {
+		if len(r.rels) > 0 || r.kept != nil {
+			sources = append(sources, source)
+		}
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// write adds the relationships part of every part with relationships
+// to parts.
+func (p *packageRelationships) write(parts map[string]string) error {
+	for _, source := range p.sources() {
+		r := p.parts[source]
+		if r.kept != nil {
+			parts[relationshipsPartName(source)] = string(r.kept)
+			continue
+		}
+		xRels := newXlsxWorksheetRelationships()
+		for _, rel := range r.rels {
+			xRel := &xlsxWorksheetRelationship{Id: rel.id, Type: rel.relType, Target: rel.target, TargetMode: "External"}
+			if !rel.external {
+				xRel.Target, xRel.TargetMode = relativeTarget(source, rel.target), ""
+			}
+			xRels.Relationships = append(xRels.Relationships, xRel)
+		}
+		body, err := xml.Marshal(xRels)
+		if err != nil {
+			return err
+		}
+		parts[relationshipsPartName(source)] = xmlHeader + string(body)
+	}
+	return nil
+}
+
+// relativeTarget returns the target by which the part called source,
+// or the package if it is "", refers to the part called target: its
+// name relative to the folder source is in, as resolveRelTarget
+// resolves it.
+func relativeTarget(source, target string) string {
+	dir := strings.Split(path.Dir(source), "/")
+	if source == "" || dir[0] == "." {
+		dir = nil
+	}
+	names := strings.Split(target, "/")
+	common := 0
+	for common < len(dir) && common < len(names)-1 && dir[common] == names[common] {
+		common++
+	}
+	relative := make([]string, 0, len(dir)-common+len(names)-common)
+	for range dir[common:] {
+		relative = append(relative, "..")
+	}
+	return strings.Join(append(relative, names[common:]...), "/")
+}
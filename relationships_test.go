@@ -0,0 +1,82 @@
+package xlsx
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type RelationshipsSuite struct{}
+
+var _ = Suite(&RelationshipsSuite{})
+
+func (s *RelationshipsSuite) TestRelativeTarget(c *C) {
+	cases := []struct{ source, target, expected string }{
+		{"", "xl/workbook.xml", "xl/workbook.xml"},
+		{"xl/workbook.xml", "xl/worksheets/sheet1.xml", "worksheets/sheet1.xml"},
+		{"xl/worksheets/sheet1.xml", "xl/drawings/drawing1.xml", "../drawings/drawing1.xml"},
+		{"xl/drawings/drawing1.xml", "xl/media/image1.png", "../media/image1.png"},
+		{"xl/workbook.xml", "docProps/custom.xml", "../docProps/custom.xml"},
+		{"xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml", "sheet2.xml"},
+	}
+	for _, test := range cases {
+		target := relativeTarget(test.source, test.target)
+		c.Assert(target, Equals, test.expected, Commentf("%s -> %s", test.source, test.target))
+		c.Assert(resolveRelTarget(test.source, target), Equals, strings.ToLower(test.target))
+	}
+}
+
+func (s *RelationshipsSuite) TestAssignIds(c *C) {
+	rels := newPackageRelationships()
+	sheet := rels.of("xl/worksheets/sheet1.xml")
+	c.Assert(sheet.add(relTypeDrawing, "xl/drawings/drawing1.xml"), Equals, "rId1")
+	c.Assert(sheet.add(relTypeImage, "xl/media/image1.png"), Equals, "rId2")
+	c.Assert(sheet.addExternal(relTypeImage, "http://example.com/a.png"), Equals, "rId3")
+	// The same relationship again has the same id.
+	c.Assert(sheet.add(relTypeImage, "xl/media/image1.png"), Equals, "rId2")
+	c.Assert(rels.of("xl/worksheets/sheet1.xml"), Equals, sheet)
+	rels.of("xl/worksheets/sheet2.xml")
+
+	parts := map[string]string{}
+	c.Assert(rels.write(parts), IsNil)
+	c.Assert(parts, HasLen, 1)
+	c.Assert(parts["xl/worksheets/_rels/sheet1.xml.rels"], Equals, xmlHeader+`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`+
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/drawing" Target="../drawings/drawing1.xml"></Relationship>`+
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="../media/image1.png"></Relationship>`+
+		`<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="http://example.com/a.png" TargetMode="External"></Relationship>`+
+		`</Relationships>`)
+}
+
+func (s *RelationshipsSuite) TestCheck(c *C) {
+	rels := newPackageRelationships()
+	rels.of("").add(relTypeOfficeDocument, "xl/workbook.xml")
+	rels.of("xl/workbook.xml").addExternal(relTypeExternalLinkPath, "Other.xlsx")
+	parts := newPackageParts()
+	c.Assert(rels.check(parts), ErrorMatches, "relationship rId1 of the package refers to xl/workbook.xml, which isn't in the package")
+	parts.content["xl/workbook.xml"] = ""
+	c.Assert(rels.check(parts), IsNil)
+	rels.of("xl/comments1.xml").add(relTypeImage, "xl/workbook.xml")
+	c.Assert(rels.check(parts), ErrorMatches, "relationships of xl/comments1.xml, which isn't in the package")
+}
+
+func (s *RelationshipsSuite) TestWrittenRelationshipsAreConsistent(c *C) {
+	f := NewFile()
+	for _, name := range []string{"One", "Two"} {
+		sheet, err := f.AddSheet(name)
+		c.Assert(err, IsNil)
+		sheet.Cell(0, 0).SetString(name)
+	}
+	f.Sheets[1].Background = blankPNG(1, 1)
+	f.Sheets[1].BackgroundType = IMAGE_TYPE_PNG
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/_rels/workbook.xml.rels"], Matches, `(?s).*<Relationship Id="rId2" Type="[^"]*/worksheet" Target="worksheets/sheet2.xml"></Relationship><Relationship Id="rId3" Type="[^"]*/sharedStrings".*`)
+	c.Assert(parts["xl/workbook.xml"], Matches, `(?s).*<sheet name="Two" sheetId="2" r:id="rId2" state="visible"></sheet>.*`)
+	c.Assert(parts["xl/worksheets/sheet1.xml"], Matches, `(?s).*<drawing r:id="rId1"></drawing></worksheet>`)
+	c.Assert(parts["xl/worksheets/sheet2.xml"], Matches, `(?s).*<drawing r:id="rId1"></drawing><picture r:id="rId2"></picture></worksheet>`)
+	c.Assert(parts["xl/worksheets/_rels/sheet2.xml.rels"], Matches, `(?s).*Id="rId2" Type="[^"]*/image" Target="../media/image1.png".*`)
+	// A drawing without pictures relates to nothing.
+	_, ok := parts["xl/drawings/_rels/drawing1.xml.rels"]
+	c.Assert(ok, Equals, false)
+}
@@ -0,0 +1,217 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"unicode/utf8"
+)
+
+// Repair makes opening a File tolerate the defects commonly found in
+// files written by other programs, recording a warning in the File's
+// Warnings for each one worked around instead of failing:
+//
+//   - characters that aren't allowed in XML, and ampersands that
+//     don't start an entity, are dropped or escaped;
+//   - a dimension element that is missing or doesn't cover all the
+//     cells is recalculated;
+//   - rows that appear more than once are merged, and rows and cells
+//     out of order are sorted;
+//   - a missing sharedStrings part, or references to shared strings
+//     that don't exist, leave the cells concerned empty.
+func Repair() FileOption {
+	return func(f *File) {
+		f.repair = true
+	}
+}
+
+// openPart opens an XML part of the package being read.  When
+// repairing, the part is read into memory and any characters that
+// would stop it being parsed are fixed.
+func (f *File) openPart(part *zip.File) (io.ReadCloser, error) {
+	rc, err := f.openRawPart(part)
+	if err != nil || !f.repair {
+		return rc, err
+	}
+	defer rc.Close()
+	data, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+	data, fixes := sanitizeXML(data)
+	if fixes > 0 {
+		f.warn(fmt.Sprintf("%s: fixed %d characters that aren't allowed in XML", part.Name, fixes))
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// xmlEntityRegexp matches the start of a character reference or one
+// of the entities predefined by XML.
+var xmlEntityRegexp = regexp.MustCompile(`^&(#[0-9]+|#x[0-9a-fA-F]+|amp|lt|gt|quot|apos);`)
+
+// openRawPart opens a part of the package being read as it is, as
+// parts that aren't XML have to be.  Every part read is opened by it,
+// so that it is counted in the File's Stats.
+func (f *File) openRawPart(part *zip.File) (io.ReadCloser, error) {
+	if part == nil {
+		return nil, fmt.Errorf("the part to read doesn't exist")
+	}
+	f.stats.partRead()
+	return part.Open()
+}
+
+// sanitizeXML drops the characters XML doesn't allow, replaces bytes
+// that aren't UTF-8 and escapes ampersands that don't start an
+// entity.  It returns the fixed data and the number of fixes made.
+func sanitizeXML(data []byte) ([]byte, int) {
+	var out *bytes.Buffer
+	fixes := 0
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		var fix string
+		switch {
+		case r == utf8.RuneError && size == 1:
+			fix = "\uFFFD"
+		case r < 0x20 && r != '\t' && r != '\n' && r != '\r', r == 0xFFFE, r == 0xFFFF:
+			fix = ""
+		case r == '&' && !xmlEntityRegexp.Match(data[i:]):
+			fix = "&amp;"
+		default:
+			if out != nil {
+				out.Write(data[i : i+size])
+			}
+			i += size
+			continue
+		}
+		if out == nil {
+			out = bytes.NewBuffer(make([]byte, 0, len(data)+16))
+			out.Write(data[:i])
+		}
+		out.WriteString(fix)
+		fixes++
+		i += size
+	}
+	if out == nil {
+		return data, 0
+	}
+	return out.Bytes(), fixes
+}
+
+// repairWorksheet fixes the structural defects of a worksheet read in
+// repair mode, recording a warning for each kind of defect found.
+func (f *File) repairWorksheet(name string, worksheet *xlsxWorksheet) {
+	rows := worksheet.SheetData.Row
+
+	// Merge rows that appear more than once into the first, later
+	// cells replacing earlier ones with the same reference.
+	byIndex := make(map[int]int, len(rows))
+	merged := rows[:0]
+	duplicates := 0
+	for _, row := range rows {
+		if row.R <= 0 {
+			merged = append(merged, row)
+			continue
+		}
+		i, seen := byIndex[row.R]
+		if !seen {
+			byIndex[row.R] = len(merged)
+			merged = append(merged, row)
+			continue
+		}
+		duplicates++
+		first := &merged[i]
+		for _, cell := range row.C {
+			replaced := false
+			for j := range first.C {
+				if first.C[j].R == cell.R && cell.R != "" {
+					first.C[j] = cell
+					replaced = true
+				}
+			}
+			if !replaced {
+				first.C = append(first.C, cell)
+			}
+		}
+	}
+	rows = merged
+	if duplicates > 0 {
+		f.warn(fmt.Sprintf("sheet '%s': merged %d rows that appeared more than once", name, duplicates))
+	}
+
+	// Put rows and cells in order.
+	sorted := true
+	if !sort.SliceIsSorted(rows, func(i, j int) bool { return rows[i].R < rows[j].R }) {
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].R < rows[j].R })
+		sorted = false
+	}
+	for i := range rows {
+		cells := rows[i].C
+		x := func(j int) int {
+			x, _, _ := getCoordsFromCellIDString(cells[j].R)
+			return x
+		}
+		if !sort.SliceIsSorted(cells, func(a, b int) bool { return x(a) < x(b) }) {
+			sort.SliceStable(cells, func(a, b int) bool { return x(a) < x(b) })
+			sorted = false
+		}
+		// The spans would no longer cover merged cells.
+		if duplicates > 0 {
+			rows[i].Spans = ""
+		}
+	}
+	if !sorted {
+		f.warn(fmt.Sprintf("sheet '%s': sorted rows or cells that were out of order", name))
+	}
+	worksheet.SheetData.Row = rows
+
+	// A sheet whose fitToPage makes no sense isn't fitted to pages.
+	for i := range worksheet.SheetPr.PageSetUpPr {
+		if pr := &worksheet.SheetPr.PageSetUpPr[i]; pr.invalid != "" {
+			f.warn(fmt.Sprintf("sheet '%s': fitToPage %q isn't a boolean, so the sheet isn't fitted to pages", name, pr.invalid))
+			pr.invalid = ""
+		}
+	}
+
+	// Make sure the dimension covers every cell.
+	if len(rows) > 0 {
+		minCol, minRow, maxCol, maxRow, err := calculateMaxMinFromWorksheet(worksheet)
+		if err == nil {
+			dimension := worksheet.Dimension.Ref
+			dMinCol, dMinRow, dMaxCol, dMaxRow, dErr := getMaxMinFromDimensionRef(dimension)
+			if dimension == "" || dErr != nil || dMinCol > minCol || dMinRow > minRow || dMaxCol < maxCol || dMaxRow < maxRow {
+				worksheet.Dimension.Ref = getCellIDStringFromCoords(minCol, minRow) + ":" + getCellIDStringFromCoords(maxCol, maxRow)
+				if dimension != "" {
+					f.warn(fmt.Sprintf("sheet '%s': dimension %s doesn't cover all the cells, using %s",
+						name, dimension, worksheet.Dimension.Ref))
+				}
+			}
+		}
+	}
+
+	// Empty the cells referring to shared strings that don't exist.
+	missing := 0
+	for i := range rows {
+		for j := range rows[i].C {
+			cell := &rows[i].C[j]
+			if cell.T != "s" || cell.V == "" {
+				continue
+			}
+			var index int
+			if _, err := fmt.Sscan(cell.V, &index); err == nil && f.referenceTable != nil &&
+				index >= 0 && index < f.referenceTable.Length() {
+				continue
+			}
+			cell.T = "inlineStr"
+			cell.V = ""
+			cell.Is = nil
+			missing++
+		}
+	}
+	if missing > 0 {
+		f.warn(fmt.Sprintf("sheet '%s': %d cells refer to shared strings that don't exist, and are left empty", name, missing))
+	}
+}
@@ -0,0 +1,113 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+
+	. "gopkg.in/check.v1"
+)
+
+type RepairSuite struct{}
+
+var _ = Suite(&RepairSuite{})
+
+// makeDefectiveXLSX returns an XLSX package holding a single
+// worksheet with the given sheetData, and the given shared strings
+// part if it isn't empty.
+func makeDefectiveXLSX(c *C, dimension, sheetData, sharedStrings string) *zip.Reader {
+	parts := makeSheetParts(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` + dimension + `<sheetData>` + sheetData + `</sheetData></worksheet>`)
+	if sharedStrings != "" {
+		parts["xl/sharedStrings.xml"] = `<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` + sharedStrings + `</sst>`
+	}
+	return makeZipReader(c, parts)
+}
+
+// makeSheetParts returns the parts of a workbook holding a single
+// worksheet, named Data, whose part is sheet.
+func makeSheetParts(sheet string) map[string]string {
+	return map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Data" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`,
+		"xl/worksheets/sheet1.xml": sheet,
+	}
+}
+
+// makeZipReader returns a zip archive holding the given parts.
+func makeZipReader(c *C, parts map[string]string) *zip.Reader {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, name := range PartOrder(parts) {
+		part, err := w.Create(name)
+		c.Assert(err, IsNil)
+		_, err = part.Write([]byte(parts[name]))
+		c.Assert(err, IsNil)
+	}
+	c.Assert(w.Close(), IsNil)
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	return r
+}
+
+func (r *RepairSuite) TestUnescapedCharacters(c *C) {
+	zr := makeDefectiveXLSX(c, "",
+		`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="inlineStr"><is><t>Fish &amp; Chips`+"\x01"+`</t></is></c></row>`,
+		`<si><t>Salt & Vinegar &lt;3</t></si>`)
+	_, err := readZipReader(zr, nil)
+	c.Assert(err, NotNil)
+
+	file, err := readZipReader(zr, []FileOption{Repair()})
+	c.Assert(err, IsNil)
+	c.Assert(file.Sheets[0].Cell(0, 0).Value, Equals, "Salt & Vinegar <3")
+	c.Assert(file.Sheets[0].Cell(0, 1).Value, Equals, "Fish & Chips")
+	c.Assert(file.Warnings, DeepEquals, []string{
+		"xl/sharedStrings.xml: fixed 1 characters that aren't allowed in XML",
+		"xl/worksheets/sheet1.xml: fixed 1 characters that aren't allowed in XML",
+	})
+}
+
+func (r *RepairSuite) TestRowsAndDimension(c *C) {
+	zr := makeDefectiveXLSX(c, `<dimension ref="A1:B2"/>`,
+		`<row r="1"><c r="A1"><v>1</v></c></row>`+
+			`<row r="3"><c r="B3"><v>3</v></c><c r="A3"><v>2</v></c></row>`+
+			`<row r="1"><c r="B1"><v>4</v></c></row>`, "")
+
+	file, err := readZipReader(zr, []FileOption{Repair()})
+	c.Assert(err, IsNil)
+	output, err := file.ToSlice()
+	c.Assert(err, IsNil)
+	c.Assert(output, DeepEquals, [][][]string{{{"1", "4"}, {}, {"2", "3"}}})
+	c.Assert(file.Warnings, DeepEquals, []string{
+		"sheet 'Data': merged 1 rows that appeared more than once",
+		"sheet 'Data': sorted rows or cells that were out of order",
+		"sheet 'Data': dimension A1:B2 doesn't cover all the cells, using A1:B3",
+	})
+}
+
+func (r *RepairSuite) TestMissingSharedStrings(c *C) {
+	zr := makeDefectiveXLSX(c, "",
+		`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1"><v>2</v></c></row>`, "")
+	_, err := readZipReader(zr, nil)
+	c.Assert(err, NotNil)
+
+	file, err := readZipReader(zr, []FileOption{Repair()})
+	c.Assert(err, IsNil)
+	c.Assert(file.Sheets[0].Cell(0, 0).Value, Equals, "")
+	c.Assert(file.Sheets[0].Cell(0, 1).Value, Equals, "2")
+	c.Assert(file.Warnings, DeepEquals, []string{
+		"sheet 'Data': 1 cells refer to shared strings that don't exist, and are left empty",
+	})
+}
+
+func (r *RepairSuite) TestSanitizeXML(c *C) {
+	data := []byte("ok &amp; &#38; &#x26; &lt;")
+	fixed, fixes := sanitizeXML(data)
+	c.Assert(fixes, Equals, 0)
+	c.Assert(string(fixed), Equals, string(data))
+
+	fixed, fixes = sanitizeXML([]byte("a & b &nbsp; \x00\xff"))
+	c.Assert(fixes, Equals, 4)
+	c.Assert(string(fixed), Equals, "a &amp; b &amp;nbsp; �")
+}
@@ -7,18 +7,96 @@ type Row struct {
 	Height       float64
 	OutlineLevel uint8
 	isCustom     bool
+	style        *Style
+	// meta holds the attributes of the row read that are written
+	// back as they were.
+	meta rowMetadata
 }
 
 func (r *Row) SetHeightCM(ht float64) {
-	r.Height = ht * 28.3464567 // Convert CM to postscript points
+	r.SetHeight(ht * 28.3464567) // Convert CM to postscript points
+}
+
+// SetHeight gives the Row a custom height, in points, which Excel
+// keeps whatever the Row holds.
+func (r *Row) SetHeight(points float64) {
+	r.Height = points
 	r.isCustom = true
 }
 
+// SetAutoHeight removes any custom height from the Row, so that Excel
+// sizes it to fit its contents, such as wrapped text, when the
+// workbook is opened.
+func (r *Row) SetAutoHeight() {
+	r.Height = 0
+	r.isCustom = false
+}
+
+// HasCustomHeight reports whether the Row has a custom height, rather
+// than being sized by Excel.
+func (r *Row) HasCustomHeight() bool {
+	return r.isCustom
+}
+
+// GetStyle returns the Style of the Row, or nil if it has none.
+func (r *Row) GetStyle() *Style {
+	return r.style
+}
+
+// SetStyle sets the style of the Row, which Excel uses for its empty
+// cells and which cells added to it later are given a copy of, in
+// preference to that of their column.  Cells already in the Row keep
+// their own styles unless RestyleCells is called.
+func (r *Row) SetStyle(style *Style) {
+	r.style = style
+}
+
+// RestyleCells gives each cell already in the Row a copy of the Row's
+// style, as Excel does when a whole row is formatted.
+func (r *Row) RestyleCells() {
+	if r.style == nil {
+		return
+	}
+	for _, cell := range r.Cells {
+		if cell == nil {
+			continue
+		}
+		style := *r.style
+		cell.style = &style
+	}
+}
+
 func (r *Row) AddCell() *Cell {
 	cell := NewCell(r)
+	r.addCell(cell)
+	return cell
+}
+
+// addCells adds n new cells to the end of the Row, allocated together.
+func (r *Row) addCells(n int) {
+	if len(r.Cells)+n > cap(r.Cells) {
+		cells := make([]*Cell, len(r.Cells), len(r.Cells)+n)
+		copy(cells, r.Cells)
+		r.Cells = cells
+	}
+	block := make([]Cell, n)
+	for i := range block {
+		block[i].Row = r
+		r.addCell(&block[i])
+	}
+}
+
+// addCell adds a new cell to the end of the Row, giving it the
+// style of the Row or of its column.
+func (r *Row) addCell(cell *Cell) {
 	r.Cells = append(r.Cells, cell)
 	r.Sheet.maybeAddCol(len(r.Cells))
-	return cell
+	if r.style != nil {
+		style := *r.style
+		cell.style = &style
+	} else if col := r.Sheet.Cols.FindCol(len(r.Cells) - 1); col != nil {
+		col.styleCell(cell)
+	}
 }
 
 // AddCellToRow add from exist
@@ -18,3 +18,38 @@ func (r *RowSuite) TestAddCell(c *C) {
 	c.Assert(cell, NotNil)
 	c.Assert(len(row.Cells), Equals, 1)
 }
+
+// Test a Row's style is written on it and given to its new cells.
+func (r *RowSuite) TestRowStyle(c *C) {
+	f := NewFile()
+	sheet, _ := f.AddSheet("MySheet")
+	italic := NewStyle()
+	italic.Font.Italic = true
+	italic.ApplyFont = true
+	c.Assert(sheet.SetColStyle(0, 1, NewStyle()), IsNil)
+	row := sheet.AddRow()
+	before := row.AddCell()
+	before.SetString("before")
+	c.Assert(row.GetStyle(), IsNil)
+	row.SetStyle(italic)
+	after := row.AddCell()
+	after.SetString("after")
+	c.Assert(before.GetStyle().Font.Italic, Equals, false)
+	c.Assert(after.GetStyle().Font.Italic, Equals, true)
+	c.Assert(after.GetStyle(), Not(Equals), italic)
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/worksheets/sheet1.xml"], Matches, `(?s).*<row r="1" s="2" customFormat="true"><c r="A1" s="1" t="s"><v>0</v></c><c r="B1" s="2" t="s"><v>1</v></c></row>.*`)
+
+	row.RestyleCells()
+	c.Assert(before.GetStyle().Font.Italic, Equals, true)
+
+	written, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	readRow := written.Sheets[0].Rows[0]
+	c.Assert(readRow.GetStyle(), NotNil)
+	c.Assert(readRow.GetStyle().Font.Italic, Equals, true)
+	c.Assert(readRow.Cells[0].GetStyle().Font.Italic, Equals, false)
+	c.Assert(readRow.AddCell().GetStyle().Font.Italic, Equals, true)
+}
@@ -0,0 +1,206 @@
+package xlsx
+
+import (
+	"fmt"
+	"time"
+)
+
+// ColumnType is the type of the values of a column, as InferSchema
+// infers it.
+type ColumnType int
+
+const (
+	// ColumnEmpty is a column with no values in the rows sampled.
+	ColumnEmpty ColumnType = iota
+	// ColumnString is a column of text, or of values of more than
+	// one type.
+	ColumnString
+	// ColumnNumber is a column of numbers.
+	ColumnNumber
+	// ColumnDate is a column of dates and times.
+	ColumnDate
+	// ColumnBool is a column of booleans.
+	ColumnBool
+)
+
+func (t ColumnType) String() string {
+	switch t {
+	case ColumnEmpty:
+		return "empty"
+	case ColumnString:
+		return "string"
+	case ColumnNumber:
+		return "number"
+	case ColumnDate:
+		return "date"
+	case ColumnBool:
+		return "bool"
+	}
+	return fmt.Sprintf("ColumnType(%d)", int(t))
+}
+
+// SchemaOptions controls how Sheet.InferSchema looks at a sheet.
+type SchemaOptions struct {
+	// HeaderRows is the number of rows at the top of the sheet
+	// searched for the header row.  It defaults to 10.
+	HeaderRows int
+	// SampleRows is the number of rows after the header row whose
+	// values the types of the columns are inferred from.  It
+	// defaults to 100.
+	SampleRows int
+}
+
+// ColumnSchema describes a column of a sheet.
+type ColumnSchema struct {
+	// Col is the zero based index of the column.
+	Col int
+	// Name is the text of the column's cell in the header row, or
+	// its letters, such as "C", if it has none.
+	Name string
+	Type ColumnType
+	// Nullable is set if any of the rows sampled have no value in
+	// the column.
+	Nullable bool
+}
+
+// Schema describes the columns of a sheet holding a table of data, as
+// InferSchema infers it.
+type Schema struct {
+	// HeaderRow is the zero based index of the header row, or -1 if
+	// the sheet doesn't seem to have one.
+	HeaderRow int
+	// DataRow is the index of the first row of data: the one after
+	// the header row, or the first row of the sheet.
+	DataRow int
+	Columns []ColumnSchema
+}
+
+// InferSchema inspects the Sheet as a table of data, finding its
+// header row and the type of each column's values.  The header row is
+// the first, among the first rows of the sheet, holding nothing but
+// text, with no text twice, in at least half of the columns the rows
+// after it use, which passes over titles and notes above the table.
+// Each column's type is inferred from the rows after it: a column
+// whose values are all of one type has that type, and one with values
+// of more than one type is a ColumnString.  Values are taken as the
+// cells hold them, so text that looks like a number is still text.
+func (s *Sheet) InferSchema(options SchemaOptions) Schema {
+	s.ensureLoaded()
+	if options.HeaderRows <= 0 {
+		options.HeaderRows = 10
+	}
+	if options.SampleRows <= 0 {
+		options.SampleRows = 100
+	}
+	schema := Schema{HeaderRow: -1}
+	for r := 0; r < options.HeaderRows && r < len(s.Rows); r++ {
+		if s.isHeaderRow(r, options.SampleRows) {
+			schema.HeaderRow, schema.DataRow = r, r+1
+			break
+		}
+	}
+
+	var header *Row
+	if schema.HeaderRow >= 0 {
+		header = s.Rows[schema.HeaderRow]
+	}
+	cols := 0
+	if header != nil {
+		cols = len(header.Cells)
+	}
+	end := schema.DataRow + options.SampleRows
+	if end > len(s.Rows) {
+		end = len(s.Rows)
+	}
+	for r := schema.DataRow; r < end; r++ {
+		if row := s.Rows[r]; row != nil && len(row.Cells) > cols {
+			cols = len(row.Cells)
+		}
+	}
+
+	for col := 0; col < cols; col++ {
+		column := ColumnSchema{Col: col, Name: ColIndexToLetters(col)}
+		if name := cellText(rowCell(header, col)); name != "" {
+			column.Name = name
+		}
+		for r := schema.DataRow; r < end; r++ {
+			t := valueColumnType(rowCell(s.Rows[r], col))
+			switch {
+			case t == ColumnEmpty:
+				column.Nullable = true
+			case column.Type == ColumnEmpty:
+				column.Type = t
+			case column.Type != t:
+				column.Type = ColumnString
+			}
+		}
+		schema.Columns = append(schema.Columns, column)
+	}
+	return schema
+}
+
+// isHeaderRow reports whether the row at index r looks like the header
+// row of the table below it, sampling as many rows of it as given.
+func (s *Sheet) isHeaderRow(r, sample int) bool {
+	row := s.Rows[r]
+	if row == nil {
+		return false
+	}
+	names := make(map[string]bool)
+	for _, cell := range row.Cells {
+		switch value := valueOf(cell).(type) {
+		case nil:
+		case string:
+			if names[value] {
+				return false
+			}
+			names[value] = true
+		default:
+			return false
+		}
+	}
+	if len(names) == 0 {
+		return false
+	}
+	width := 0
+	for i := r + 1; i <= r+sample && i < len(s.Rows); i++ {
+		if s.Rows[i] == nil {
+			continue
+		}
+		used := 0
+		for col, cell := range s.Rows[i].Cells {
+			if valueOf(cell) != nil {
+				used = col + 1
+			}
+		}
+		if used > width {
+			width = used
+		}
+	}
+	return 2*len(names) >= width
+}
+
+// valueOf returns the typed value of a cell, or nil if it is missing
+// or empty.
+func valueOf(cell *Cell) interface{} {
+	if cell == nil {
+		return nil
+	}
+	return cell.typedValue()
+}
+
+// valueColumnType returns the type of column a cell's value belongs
+// in.
+func valueColumnType(cell *Cell) ColumnType {
+	switch valueOf(cell).(type) {
+	case nil:
+		return ColumnEmpty
+	case bool:
+		return ColumnBool
+	case float64:
+		return ColumnNumber
+	case time.Time:
+		return ColumnDate
+	}
+	return ColumnString
+}
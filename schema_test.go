@@ -0,0 +1,81 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type SchemaSuite struct{}
+
+var _ = Suite(&SchemaSuite{})
+
+func (s *SchemaSuite) TestInferSchema(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Orders")
+	c.Assert(err, IsNil)
+	// A title and a blank row above the table.
+	sheet.Cell(0, 0).SetString("Orders for March")
+	for col, name := range []string{"Customer", "Placed", "Total", "Paid", ""} {
+		if name != "" {
+			sheet.Cell(2, col).SetString(name)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		r := 3 + i
+		sheet.Cell(r, 0).SetString("Customer")
+		sheet.Cell(r, 1).SetDateTimeWithFormat(45352+float64(i), "yyyy-mm-dd")
+		sheet.Cell(r, 2).SetFloat(10.5 * float64(i))
+		sheet.Cell(r, 3).SetBool(i%2 == 0)
+		if i != 1 {
+			sheet.Cell(r, 4).SetInt(i)
+		}
+	}
+	sheet.Cell(5, 4).SetString("n/a")
+
+	schema := sheet.InferSchema(SchemaOptions{})
+	c.Assert(schema.HeaderRow, Equals, 2)
+	c.Assert(schema.DataRow, Equals, 3)
+	c.Assert(schema.Columns, DeepEquals, []ColumnSchema{
+		{Col: 0, Name: "Customer", Type: ColumnString},
+		{Col: 1, Name: "Placed", Type: ColumnDate},
+		{Col: 2, Name: "Total", Type: ColumnNumber},
+		{Col: 3, Name: "Paid", Type: ColumnBool},
+		{Col: 4, Name: "E", Type: ColumnString, Nullable: true},
+	})
+	c.Assert(ColumnDate.String(), Equals, "date")
+
+	// Only the rows sampled count.
+	schema = sheet.InferSchema(SchemaOptions{SampleRows: 2})
+	c.Assert(schema.Columns[4].Type, Equals, ColumnNumber)
+	c.Assert(schema.Columns[4].Nullable, Equals, true)
+	// The header has to be among the rows searched.
+	schema = sheet.InferSchema(SchemaOptions{HeaderRows: 2})
+	c.Assert(schema.HeaderRow, Equals, -1)
+	c.Assert(schema.DataRow, Equals, 0)
+	c.Assert(schema.Columns[0].Name, Equals, "A")
+}
+
+func (s *SchemaSuite) TestInferSchemaWithoutHeader(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Data")
+	c.Assert(err, IsNil)
+	for r := 0; r < 3; r++ {
+		sheet.Cell(r, 0).SetInt(r)
+		sheet.Cell(r, 1).SetString("same")
+	}
+	schema := sheet.InferSchema(SchemaOptions{})
+	c.Assert(schema.HeaderRow, Equals, -1)
+	c.Assert(schema.Columns, DeepEquals, []ColumnSchema{
+		{Col: 0, Name: "A", Type: ColumnNumber},
+		{Col: 1, Name: "B", Type: ColumnString},
+	})
+
+	// A row repeating a name isn't a header.
+	sheet.Cell(0, 0).SetString("same")
+	c.Assert(sheet.InferSchema(SchemaOptions{}).HeaderRow, Equals, -1)
+
+	empty, err := f.AddSheet("Empty")
+	c.Assert(err, IsNil)
+	schema = empty.InferSchema(SchemaOptions{})
+	c.Assert(schema.HeaderRow, Equals, -1)
+	c.Assert(schema.Columns, HasLen, 0)
+}
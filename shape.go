@@ -0,0 +1,295 @@
+package xlsx
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ShapeType is the kind of a Shape.
+type ShapeType string
+
+const (
+	ShapeRectangle        ShapeType = "rect"
+	ShapeRoundedRectangle ShapeType = "roundRect"
+	ShapeEllipse          ShapeType = "ellipse"
+	// ShapeLine and ShapeArrow are drawn from the top left to the
+	// bottom right corner of where the shape is placed, the arrow
+	// having its head at the bottom right.
+	ShapeLine  ShapeType = "line"
+	ShapeArrow ShapeType = "arrow"
+	// ShapeTextBox is a rectangle meant for text.
+	ShapeTextBox ShapeType = "textBox"
+)
+
+// Shape is a shape or text box drawn over the cells of a Sheet.  It is
+// placed in the same way as a Drawing, except that a Shape anchored to
+// two cells without a RowCount and ColCount uses ExtentX and ExtentY
+// for its size.
+type Shape struct {
+	Type        ShapeType
+	Name        string
+	TopLeftCell DrawingCell
+	RowCount    int
+	ColCount    int
+	Anchor      AnchorType
+	OffsetX     int
+	OffsetY     int
+	ExtentX     int
+	ExtentY     int
+	// Text is shown in the shape, each line as a paragraph, in the
+	// Font - whose Size is in points and Color is ARGB hex - and
+	// aligned as given by the Alignment's Horizontal and Vertical.
+	Text      string
+	Font      Font
+	Alignment Alignment
+	// FillColor and LineColor are the ARGB hex colours of the
+	// shape's inside and outline.  If either is empty, that part
+	// isn't drawn.  LineWidth is in points; if it is zero, the
+	// thinnest line is drawn.
+	FillColor string
+	LineColor string
+	LineWidth float64
+	// read is set for a Shape read from a drawing, and
+	// picturesBefore is then the number of the Sheet's Drawings
+	// that came before it there, so that it's written back in the
+	// same place among them.
+	read           bool
+	picturesBefore int
+}
+
+// AddShape adds a shape covering rowCount rows and colCount columns
+// from the cell with the zero based coordinates row and col, filled
+// and outlined in the colours Excel gives new shapes.
+func (s *Sheet) AddShape(shapeType ShapeType, row, col, rowCount, colCount int) *Shape {
+	s.ensureLoaded()
+	shape := &Shape{
+		Type:        shapeType,
+		TopLeftCell: DrawingCell{RowNum: row, ColNum: col},
+		RowCount:    rowCount,
+		ColCount:    colCount,
+		Alignment:   Alignment{Horizontal: "center", Vertical: "center"},
+		FillColor:   "FF4472C4",
+		LineColor:   "FF2F528F",
+		LineWidth:   1,
+	}
+	switch shapeType {
+	case ShapeLine, ShapeArrow:
+		shape.FillColor = ""
+		shape.LineColor = "FF4472C4"
+	case ShapeTextBox:
+		shape.Alignment = Alignment{Horizontal: "left", Vertical: "top"}
+		shape.FillColor = "FFFFFFFF"
+		shape.LineColor = "FFBFBFBF"
+		shape.LineWidth = 0.75
+	}
+	s.Shapes = append(s.Shapes, shape)
+	return shape
+}
+
+// AddTextBox adds a text box showing text, covering rowCount rows and
+// colCount columns from the cell with the zero based coordinates row
+// and col.
+func (s *Sheet) AddTextBox(text string, row, col, rowCount, colCount int) *Shape {
+	shape := s.AddShape(ShapeTextBox, row, col, rowCount, colCount)
+	shape.Text = text
+	return shape
+}
+
+// placement returns a Drawing placed where the shape is, for working
+// out its anchor.
+func (shape *Shape) placement() Drawing {
+	return Drawing{
+		TopLeftCell: shape.TopLeftCell,
+		RowCount:    shape.RowCount,
+		ColCount:    shape.ColCount,
+		Anchor:      shape.Anchor,
+		OffsetX:     shape.OffsetX,
+		OffsetY:     shape.OffsetY,
+		ExtentX:     shape.ExtentX,
+		ExtentY:     shape.ExtentY,
+	}
+}
+
+// setPlacement places the shape where a Drawing read from a file is.
+func (shape *Shape) setPlacement(d Drawing) {
+	shape.TopLeftCell = d.TopLeftCell
+	shape.RowCount = d.RowCount
+	shape.ColCount = d.ColCount
+	shape.Anchor = d.Anchor
+	shape.OffsetX, shape.OffsetY = d.OffsetX, d.OffsetY
+	shape.ExtentX, shape.ExtentY = d.ExtentX, d.ExtentY
+}
+
+// drawingColor converts an ARGB hex colour to the RGB hex that
+// drawings use.
+func drawingColor(argb string) string {
+	if len(argb) == 8 {
+		return strings.ToUpper(argb[2:])
+	}
+	return strings.ToUpper(argb)
+}
+
+func drawingSolidFill(argb string) *mainSolidFill {
+	return &mainSolidFill{SrgbClr: mainSrgbClr{Val: drawingColor(argb)}}
+}
+
+var drawingAlignments = map[string]string{
+	"left": "l", "center": "ctr", "right": "r", "justify": "just", "distributed": "dist",
+	"top": "t", "bottom": "b",
+}
+
+// makeDrawingSp returns the n'th object of a drawing for the shape.
+func (shape *Shape) makeDrawingSp(n int) *drawingSp {
+	sp := new(drawingSp)
+	sp.NvSpPr.CNvPr.Id = n
+	sp.NvSpPr.CNvPr.Name = shape.Name
+	if sp.NvSpPr.CNvPr.Name == "" {
+		sp.NvSpPr.CNvPr.Name = fmtShapeName(shape.Type, n)
+	}
+	spPr := &sp.SpPr
+	switch shape.Type {
+	case ShapeArrow:
+		spPr.PrstGeom.Prst = "line"
+	case ShapeTextBox, "":
+		spPr.PrstGeom.Prst = "rect"
+		sp.NvSpPr.CNvSpPr.TxBox = 1
+	default:
+		spPr.PrstGeom.Prst = string(shape.Type)
+	}
+	if shape.FillColor == "" {
+		spPr.NoFill = &mainNoFill{}
+	} else {
+		spPr.SolidFill = drawingSolidFill(shape.FillColor)
+	}
+	spPr.Ln = &mainLn{W: int(shape.LineWidth * EMUPerPoint)}
+	if shape.LineColor == "" {
+		spPr.Ln.NoFill = &mainNoFill{}
+	} else {
+		spPr.Ln.SolidFill = drawingSolidFill(shape.LineColor)
+	}
+	if shape.Type == ShapeArrow {
+		spPr.Ln.TailEnd = &mainLineEnd{Type: "triangle"}
+	}
+	if shape.Text == "" {
+		return sp
+	}
+
+	body := &drawingTxBody{}
+	body.BodyPr = mainBodyPr{VertOverflow: "clip", Wrap: "square", Anchor: "t"}
+	if anchor, ok := drawingAlignments[string(shape.Alignment.Vertical)]; ok {
+		body.BodyPr.Anchor = anchor
+	}
+	align := "l"
+	if a, ok := drawingAlignments[string(shape.Alignment.Horizontal)]; ok {
+		align = a
+	}
+	for _, line := range strings.Split(shape.Text, "\n") {
+		p := mainParagraph{PPr: mainPPr{Algn: align}}
+		if line != "" {
+			run := &mainRun{T: line}
+			run.RPr.Lang = "en-US"
+			run.RPr.Sz = shape.Font.Size * 100
+			if shape.Font.Bold {
+				run.RPr.B = 1
+			}
+			if shape.Font.Italic {
+				run.RPr.I = 1
+			}
+			if shape.Font.Underline {
+				run.RPr.U = "sng"
+			}
+			if shape.Font.Color != "" {
+				run.RPr.SolidFill = drawingSolidFill(shape.Font.Color)
+			}
+			if shape.Font.Name != "" {
+				run.RPr.Latin = &mainFont{Typeface: shape.Font.Name}
+			}
+			p.R = run
+		}
+		body.P = append(body.P, p)
+	}
+	sp.TxBody = body
+	return sp
+}
+
+// fmtShapeName returns the name Excel gives the n'th object of a
+// drawing if it is a shape of the given type.
+func fmtShapeName(shapeType ShapeType, n int) string {
+	names := map[ShapeType]string{
+		ShapeRectangle:        "Rectangle",
+		ShapeRoundedRectangle: "Rounded Rectangle",
+		ShapeEllipse:          "Oval",
+		ShapeLine:             "Straight Connector",
+		ShapeArrow:            "Straight Arrow Connector",
+		ShapeTextBox:          "TextBox",
+	}
+	name, ok := names[shapeType]
+	if !ok {
+		name = "Shape"
+	}
+	return name + " " + strconv.Itoa(n)
+}
+
+// readShape makes a Shape of a shape read from a drawing.
+func readShape(sp *xlsxReadSp) *Shape {
+	shape := &Shape{
+		Name: sp.CNvPr.Name,
+		Type: ShapeType(sp.SpPr.PrstGeom.Prst),
+	}
+	if sp.CNvSpPr.TxBox {
+		shape.Type = ShapeTextBox
+	}
+	if sp.SpPr.SolidFill != nil {
+		shape.FillColor = "FF" + sp.SpPr.SolidFill.SrgbClr.Val
+	}
+	if ln := sp.SpPr.Ln; ln != nil {
+		shape.LineWidth = float64(ln.W) / EMUPerPoint
+		if ln.SolidFill != nil {
+			shape.LineColor = "FF" + ln.SolidFill.SrgbClr.Val
+		}
+		if shape.Type == ShapeLine && ln.TailEnd != nil && ln.TailEnd.Type != "" && ln.TailEnd.Type != "none" {
+			shape.Type = ShapeArrow
+		}
+	}
+	if sp.TxBody == nil {
+		return shape
+	}
+	for value, a := range drawingAlignments {
+		if a == sp.TxBody.BodyPr.Anchor && (value == "top" || value == "center" || value == "bottom") {
+			shape.Alignment.Vertical = VerticalAlignment(value)
+		}
+	}
+	// The alignment of the first paragraph and the font of the first
+	// run stand for the whole text.
+	var lines []string
+	fontRead := false
+	for i, p := range sp.TxBody.P {
+		if i == 0 {
+			for value, a := range drawingAlignments {
+				if a == p.PPr.Algn && value != "top" && value != "bottom" {
+					shape.Alignment.Horizontal = HorizontalAlignment(value)
+				}
+			}
+		}
+		var line strings.Builder
+		for _, r := range p.R {
+			line.WriteString(r.T)
+			if !fontRead {
+				fontRead = true
+				shape.Font.Size = r.RPr.Sz / 100
+				shape.Font.Bold = r.RPr.B
+				shape.Font.Italic = r.RPr.I
+				shape.Font.Underline = r.RPr.U != "" && r.RPr.U != "none"
+				if r.RPr.SolidFill != nil {
+					shape.Font.Color = "FF" + r.RPr.SolidFill.SrgbClr.Val
+				}
+				if r.RPr.Latin != nil {
+					shape.Font.Name = r.RPr.Latin.Typeface
+				}
+			}
+		}
+		lines = append(lines, line.String())
+	}
+	shape.Text = strings.Join(lines, "\n")
+	return shape
+}
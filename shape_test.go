@@ -0,0 +1,92 @@
+package xlsx
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type ShapeSuite struct{}
+
+var _ = Suite(&ShapeSuite{})
+
+func (s *ShapeSuite) TestShapes(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Dashboard")
+	sheet.AddRow().AddCell().SetString("Sales")
+	sheet.Drawings = append(sheet.Drawings, Drawing{
+		Sheet: sheet, ImageData: makePNG(c, 10, 10), ImageType: IMAGE_TYPE_PNG, RowCount: 1, ColCount: 1,
+	})
+	box := sheet.AddTextBox("Sales are up\nby 10%", 1, 1, 3, 2)
+	box.Font = Font{Size: 14, Name: "Arial", Color: "FFFF0000", Bold: true}
+	box.Alignment.Horizontal = "center"
+	box.Anchor = AnchorOneCell
+	box.ExtentX, box.ExtentY = 100*EMUPerPixel, 50*EMUPerPixel
+	sheet.AddShape(ShapeEllipse, 5, 0, 2, 2).Text = "Note"
+	arrow := sheet.AddShape(ShapeArrow, 5, 2, 1, 3)
+	arrow.LineWidth = 2
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	drawing := parts["xl/drawings/drawing1.xml"]
+	c.Assert(drawing, Matches, `(?s).*<xdr:pic>.*<xdr:oneCellAnchor>.*<xdr:sp macro="" textlink=""><xdr:nvSpPr><xdr:cNvPr id="2" name="TextBox 2" descr=""></xdr:cNvPr><xdr:cNvSpPr txBox="1"></xdr:cNvSpPr>.*`)
+	c.Assert(drawing, Matches, `(?s).*<a:ln w="25400"><a:solidFill><a:srgbClr val="4472C4"></a:srgbClr></a:solidFill><a:tailEnd type="triangle"></a:tailEnd></a:ln>.*`)
+	c.Assert(drawing, Matches, `(?s).*<a:p><a:pPr algn="ctr"></a:pPr><a:r><a:rPr lang="en-US" sz="1400" b="1"><a:solidFill><a:srgbClr val="FF0000"></a:srgbClr></a:solidFill><a:latin typeface="Arial"></a:latin></a:rPr><a:t>Sales are up</a:t></a:r></a:p>.*`)
+
+	written, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	c.Assert(written.Sheets[0].Pictures(), HasLen, 1)
+	shapes := written.Sheets[0].Shapes
+	c.Assert(shapes, HasLen, 3)
+	c.Assert(*shapes[0], DeepEquals, Shape{
+		Type:        ShapeTextBox,
+		Name:        "TextBox 2",
+		TopLeftCell: DrawingCell{RowNum: 1, ColNum: 1},
+		Anchor:      AnchorOneCell,
+		ExtentX:     100 * EMUPerPixel,
+		ExtentY:     50 * EMUPerPixel,
+		Text:        "Sales are up\nby 10%",
+		Font:        Font{Size: 14, Name: "Arial", Color: "FFFF0000", Bold: true},
+		Alignment:   Alignment{Horizontal: "center", Vertical: "top"},
+		FillColor:   "FFFFFFFF",
+		LineColor:   "FFBFBFBF",
+		LineWidth:   0.75,
+
+		read:           true,
+		picturesBefore: 1,
+	})
+	c.Assert(shapes[1].Type, Equals, ShapeEllipse)
+	c.Assert(shapes[1].Text, Equals, "Note")
+	c.Assert(shapes[1].RowCount, Equals, 2)
+	c.Assert(shapes[1].Alignment, Equals, Alignment{Horizontal: "center", Vertical: "center"})
+	c.Assert(shapes[2].Type, Equals, ShapeArrow)
+	c.Assert(shapes[2].FillColor, Equals, "")
+	c.Assert(shapes[2].LineWidth, Equals, 2.0)
+	c.Assert(shapes[2].ColCount, Equals, 3)
+}
+
+func (s *ShapeSuite) TestShapeOrder(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	sheet.Drawings = append(sheet.Drawings, Drawing{
+		Sheet: sheet, ImageData: makePNG(c, 10, 10), ImageType: IMAGE_TYPE_PNG, RowCount: 1, ColCount: 1,
+	})
+	sheet.AddShape(ShapeRectangle, 2, 2, 1, 1)
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+
+	// Put the shape behind the picture, as Excel would if it were
+	// sent to the back.
+	drawing := parts["xl/drawings/drawing1.xml"]
+	anchors := strings.Split(strings.TrimSuffix(drawing, "</xdr:wsDr>"), "<xdr:twoCellAnchor")
+	c.Assert(anchors, HasLen, 3)
+	c.Assert(anchors[2], Matches, `(?s).*<xdr:sp .*`)
+	parts["xl/drawings/drawing1.xml"] = anchors[0] + "<xdr:twoCellAnchor" + anchors[2] + "<xdr:twoCellAnchor" + anchors[1] + "</xdr:wsDr>"
+
+	read, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	read.Sheets[0].AddShape(ShapeEllipse, 4, 4, 1, 1)
+	parts, err = read.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/drawings/drawing1.xml"], Matches, `(?s).*<a:prstGeom prst="rect">.*<xdr:pic>.*<a:prstGeom prst="ellipse">.*`)
+}
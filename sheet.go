@@ -2,7 +2,6 @@ package xlsx
 
 import (
 	"fmt"
-	"image"
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
@@ -18,23 +17,91 @@ import (
 // Sheet is a high level structure intended to provide user access to
 // the contents of a particular sheet within an XLSX file.
 type Sheet struct {
-	Name          string
-	File          *File
-	Rows          []*Row
-	Cols          []*Col
-	MaxRow        int
-	MaxCol        int
-	Hidden        bool
-	Selected      bool
-	SheetViews    []SheetView
-	SheetFormat   SheetFormat
+	Name        string
+	File        *File
+	Rows        []*Row
+	Cols        ColStore
+	MaxRow      int
+	MaxCol      int
+	Hidden      bool
+	VeryHidden  bool
+	Selected    bool
+	SheetViews  []SheetView
+	SheetFormat SheetFormat
+	// ShowGridLines is the same as View.ShowGridLines; the gridlines
+	// are shown if either is set.
 	ShowGridLines bool
 	OddHeader     string
 	FitToPage     int
 	PageSetUp     xlsxPageSetUp
 	PageMargins   xlsxPageMargins
 	Drawings      []Drawing
-	Index         int
+	Shapes        []*Shape
+	// Background is the image tiled behind the cells of the
+	// Sheet, of type BackgroundType; see SetBackground.
+	Background     []byte
+	BackgroundType ImageType
+	Index          int
+	TabColor       string
+	// CodeName is the name VBA refers to the Sheet by, such as
+	// "Sheet1", which stays the same when the Sheet is renamed.
+	// Macro-enabled workbooks give every sheet one.
+	CodeName string
+	View     ViewSettings
+	// Outline controls how the groups of the Sheet's rows and
+	// columns are outlined.
+	Outline OutlineSettings
+	// Protected protects the Sheet, so that only the cells whose
+	// style's Protection doesn't lock them can be edited.
+	Protected bool
+	// IgnoredErrors are the ranges of cells whose errors Excel
+	// doesn't flag with a green triangle.
+	IgnoredErrors []IgnoredError
+	// Hyperlinks are the links from cells of the Sheet to places in
+	// the workbook, web pages and other files.
+	Hyperlinks []Hyperlink
+	// CustomViews are the Sheet's settings for the custom views of
+	// its File; see File.AddCustomView.
+	CustomViews []CustomSheetView
+	// oleObjects are the embedded objects of the Sheet as read; see
+	// EmbeddedObjects.
+	oleObjects *sheetOLEObjects
+	lazy       *lazySheet
+}
+
+// ViewType is the kind of view Excel shows a Sheet in.
+type ViewType string
+
+const (
+	ViewNormal           ViewType = "normal"
+	ViewPageBreakPreview ViewType = "pageBreakPreview"
+	ViewPageLayout       ViewType = "pageLayout"
+)
+
+// ViewSettings control how Excel shows a Sheet when the workbook is
+// opened.  The zero value is Excel's default view, apart from the
+// gridlines, which are hidden unless ShowGridLines is set.  The color
+// of the sheet's tab is its TabColor.
+type ViewSettings struct {
+	// ZoomScale is the zoom as a percentage, between 10 and 400.
+	// Zero means 100.
+	ZoomScale int
+	// ActiveCell is the cell the cursor is in, such as "B2".  Empty
+	// means the first cell of the Selection, or A1.
+	ActiveCell string
+	// Selection is the range, or space separated ranges, that are
+	// selected, such as "B2:C4".  Empty means just the ActiveCell.
+	Selection         string
+	ShowGridLines     bool
+	HideRowColHeaders bool
+	// RightToLeft lays the Sheet out from right to left, with
+	// column A at the right.  Only how it is shown changes: cells,
+	// columns and the anchors of pictures are given as they are in
+	// a Sheet laid out from left to right, with column A first.
+	RightToLeft bool
+	// Type is the kind of view.  The empty ViewType means
+	// ViewNormal.
+	Type ViewType
 }
 
 type SheetView struct {
@@ -52,12 +119,16 @@ type Pane struct {
 type SheetFormat struct {
 	DefaultColWidth  float64
 	DefaultRowHeight float64
-	OutlineLevelCol  uint8
-	OutlineLevelRow  uint8
+	// CustomHeight is set when DefaultRowHeight was chosen, rather
+	// than being the height of the default font.
+	CustomHeight    bool
+	OutlineLevelCol uint8
+	OutlineLevelRow uint8
 }
 
 // Add a new Row to a Sheet
 func (s *Sheet) AddRow() *Row {
+	s.ensureLoaded()
 	row := &Row{Sheet: s}
 	s.Rows = append(s.Rows, row)
 	if len(s.Rows) > s.MaxRow {
@@ -68,22 +139,169 @@ func (s *Sheet) AddRow() *Row {
 
 // Make sure we always have as many Cols as we do cells.
 func (s *Sheet) maybeAddCol(cellCount int) {
-	if cellCount > s.MaxCol {
+	if s.Cols.FindCol(cellCount-1) == nil {
 		col := &Col{
-			style:     NewStyle(),
+			style:     s.File.newStyle(),
 			Min:       cellCount,
 			Max:       cellCount,
 			Hidden:    false,
 			Collapsed: false}
-		s.Cols = append(s.Cols, col)
+		s.Cols.insert(col)
+	}
+	if cellCount > s.MaxCol {
 		s.MaxCol = cellCount
 	}
 }
 
-// Make sure we always have as many Cols as we do cells.
+// SetDefaultRowHeight sets the height, in points, of the rows of the
+// Sheet that have no height of their own.
+func (s *Sheet) SetDefaultRowHeight(points float64) {
+	s.SheetFormat.DefaultRowHeight = points
+	s.SheetFormat.CustomHeight = true
+}
+
+// SetDefaultColWidth sets the width, in characters, of the columns
+// of the Sheet that have no width of their own.
+func (s *Sheet) SetDefaultColWidth(width float64) {
+	s.SheetFormat.DefaultColWidth = width
+}
+
+// Col returns the Col for the zero based column idx, splitting it
+// out of any range it is defined in so that changing it changes idx
+// alone.
 func (s *Sheet) Col(idx int) *Col {
+	s.ensureLoaded()
 	s.maybeAddCol(idx + 1)
-	return s.Cols[idx]
+	i, ok := s.Cols.index(idx + 1)
+	if !ok {
+		col := &Col{style: s.File.newStyle(), Min: idx + 1, Max: idx + 1}
+		s.Cols.insert(col)
+		return col
+	}
+	col := s.Cols[i]
+	if col.Min == col.Max {
+		return col
+	}
+	col = col.piece(idx+1, idx+1)
+	s.Cols.insert(col)
+	return col
+}
+
+// state returns the value of the state attribute of the Sheet's entry
+// in the workbook.
+func (s *Sheet) state() string {
+	switch {
+	case s.VeryHidden:
+		return sheetStateVeryHidden
+	case s.Hidden:
+		return sheetStateHidden
+	}
+	return sheetStateVisible
+}
+
+// apply sets the attributes of a sheetView element to match the
+// ViewSettings.
+func (v ViewSettings) apply(xView *xlsxSheetView) {
+	xView.ShowGridLines = v.ShowGridLines
+	xView.ShowRowColHeaders = !v.HideRowColHeaders
+	xView.RightToLeft = v.RightToLeft
+	if v.Type != "" {
+		xView.View = string(v.Type)
+	}
+	if v.ActiveCell != "" || v.Selection != "" {
+		activeCell, selection := v.ActiveCell, v.Selection
+		if activeCell == "" {
+			activeCell = strings.SplitN(strings.SplitN(selection, " ", 2)[0], ":", 2)[0]
+		}
+		if selection == "" {
+			selection = activeCell
+		}
+		xView.Selection = []xlsxSelection{{Pane: selectionPane(xView.Pane, ""), ActiveCell: activeCell, SQRef: selection}}
+	}
+	if v.ZoomScale != 0 {
+		zoom := float64(v.ZoomScale)
+		xView.ZoomScale = zoom
+		switch v.Type {
+		case ViewPageBreakPreview:
+			xView.ZoomScaleSheetLayoutView = zoom
+		case ViewPageLayout:
+			xView.ZoomScalePageLayoutView = zoom
+		default:
+			xView.ZoomScaleNormal = zoom
+		}
+	}
+}
+
+// readViewSettings returns the ViewSettings of a sheetView element.
+func readViewSettings(xView xlsxSheetView) ViewSettings {
+	v := ViewSettings{
+		ShowGridLines:     xView.ShowGridLines,
+		HideRowColHeaders: !xView.ShowRowColHeaders,
+		RightToLeft:       xView.RightToLeft,
+	}
+	if xView.View != "" && xView.View != string(ViewNormal) {
+		v.Type = ViewType(xView.View)
+	}
+	if xView.ZoomScale != 0 && xView.ZoomScale != 100 {
+		v.ZoomScale = int(xView.ZoomScale)
+	}
+	if n := len(xView.Selection); n > 0 {
+		// With split panes there's a selection in each, the last
+		// being in the active pane.
+		selection := xView.Selection[n-1]
+		if selection.ActiveCell != "A1" {
+			v.ActiveCell = selection.ActiveCell
+		}
+		if selection.SQRef != selection.ActiveCell {
+			v.Selection = selection.SQRef
+		}
+	}
+	return v
+}
+
+// selectionPane returns the pane that the selection of a view with the
+// given pane is in.  That's the pane read, if there was one, or else
+// the active pane, or failing that the pane after the splits.  A view
+// that isn't split has only the top left pane.
+func selectionPane(pane *xlsxPane, read string) string {
+	switch {
+	case pane == nil:
+		return "topLeft"
+	case read != "":
+		return read
+	case pane.ActivePane != "":
+		return pane.ActivePane
+	case pane.XSplit > 0 && pane.YSplit > 0:
+		return "bottomRight"
+	case pane.XSplit > 0:
+		return "topRight"
+	case pane.YSplit > 0:
+		return "bottomLeft"
+	}
+	return "topLeft"
+}
+
+// activeSheetIndex returns the index of the first of the sheets that
+// is both Selected and visible, or failing that the first visible one,
+// or -1 if they are all hidden.
+func activeSheetIndex(sheets []*Sheet) int {
+	for i, sheet := range sheets {
+		if sheet.Selected && !sheet.Hidden && !sheet.VeryHidden {
+			return i
+		}
+	}
+	return firstVisibleSheet(sheets)
+}
+
+// firstVisibleSheet returns the index of the first of the sheets that
+// isn't hidden, or -1 if they all are.
+func firstVisibleSheet(sheets []*Sheet) int {
+	for i, sheet := range sheets {
+		if !sheet.Hidden && !sheet.VeryHidden {
+			return i
+		}
+	}
+	return -1
 }
 
 // Get a Cell by passing it's cartesian coordinates (zero based) as
@@ -94,8 +312,11 @@ func (s *Sheet) Col(idx int) *Col {
 //    cell := sheet.Cell(0,0)
 //
 // ... would set the variable "cell" to contain a Cell struct
-// containing the data from the field "A1" on the spreadsheet.
+// containing the data from the field "A1" on the spreadsheet.  Any
+// rows and cells up to the one asked for that don't exist yet, or are
+// nil in a sparse sheet, are made on the way.
 func (sh *Sheet) Cell(row, col int) *Cell {
+	sh.ensureLoaded()
 
 	// If the user requests a row beyond what we have, then extend.
 	for len(sh.Rows) <= row {
@@ -103,11 +324,202 @@ func (sh *Sheet) Cell(row, col int) *Cell {
 	}
 
 	r := sh.Rows[row]
+	if r == nil {
+		r = &Row{Sheet: sh}
+		sh.Rows[row] = r
+	}
 	for len(r.Cells) <= col {
 		r.AddCell()
 	}
 
-	return r.Cells[col]
+	cell := r.Cells[col]
+	if cell == nil {
+		cell = NewCell(r)
+		if r.style != nil {
+			style := *r.style
+			cell.style = &style
+		} else if c := sh.Cols.FindCol(col); c != nil {
+			c.styleCell(cell)
+		}
+		r.Cells[col] = cell
+	}
+	return cell
+}
+
+// SetFormulaR1C1 sets the formula of the cell at the given zero based
+// row and column to formula, written in R1C1 notation, as in
+// "SUM(R[-3]C:R[-1]C)".  It is stored in A1 notation, as every formula
+// is; see FormulaFromR1C1.
+func (sh *Sheet) SetFormulaR1C1(row, col int, formula string) error {
+	a1, err := FormulaFromR1C1(formula, CellRef{Col: col, Row: row})
+	if err != nil {
+		return err
+	}
+	sh.Cell(row, col).SetFormula(a1)
+	return nil
+}
+
+// FormulaR1C1 returns the formula of the cell at the given zero based
+// row and column in R1C1 notation, or "" if it has none.
+func (sh *Sheet) FormulaR1C1(row, col int) string {
+	sh.ensureLoaded()
+	if row >= len(sh.Rows) || sh.Rows[row] == nil {
+		return ""
+	}
+	cell := rowCell(sh.Rows[row], col)
+	if cell == nil {
+		return ""
+	}
+	return FormulaToR1C1(cell.formula, CellRef{Col: col, Row: row})
+}
+
+// SetValue sets the value of the cell at ref, such as "B7", making it
+// if need be, as Cell.SetValue does: strings, numbers, bools and
+// time.Time values each get the cell type that suits them, and nil
+// empties the cell.
+func (sh *Sheet) SetValue(ref string, value interface{}) error {
+	cellRef, err := ParseCellRef(ref)
+	if err != nil {
+		return err
+	}
+	sh.Cell(cellRef.Row, cellRef.Col).SetValue(value)
+	return nil
+}
+
+// SetRange sets the values of the block of cells whose top left cell
+// is ref, such as "A1", one row of the block for each slice of values,
+// as SetValue does.  The rows and cells are made in one go, rather
+// than one at a time, so it is much quicker than adding them one by
+// one for a large block.
+func (sh *Sheet) SetRange(ref string, values [][]interface{}) error {
+	start, err := ParseCellRef(ref)
+	if err != nil {
+		return err
+	}
+	width := 0
+	for _, rowValues := range values {
+		if len(rowValues) > width {
+			width = len(rowValues)
+		}
+	}
+	if start.Row+len(values) > SheetRowLimit || start.Col+width > SheetColLimit {
+		return fmt.Errorf("range of %d rows and %d columns from '%s' is out of range", len(values), width, ref)
+	}
+	sh.ensureLoaded()
+	if end := start.Row + len(values); end > cap(sh.Rows) {
+		rows := make([]*Row, len(sh.Rows), end)
+		copy(rows, sh.Rows)
+		sh.Rows = rows
+	}
+	for len(sh.Rows) < start.Row+len(values) {
+		sh.AddRow()
+	}
+	for i, rowValues := range values {
+		if len(rowValues) == 0 {
+			continue
+		}
+		r := start.Row + i
+		row := sh.Rows[r]
+		if row == nil {
+			row = sh.Cell(r, 0).Row
+		}
+		if end := start.Col + len(rowValues); end > len(row.Cells) {
+			row.addCells(end - len(row.Cells))
+		}
+		for j, value := range rowValues {
+			sh.Cell(r, start.Col+j).SetValue(value)
+		}
+	}
+	return nil
+}
+
+// Value returns the value of the cell at ref, such as "B7", as a
+// string, float64, bool or time.Time according to its type and number
+// format, or nil if the cell is empty or doesn't exist.  A formula's
+// value is the result cached when the file was last calculated.
+func (sh *Sheet) Value(ref string) (interface{}, error) {
+	cellRef, err := ParseCellRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	sh.ensureLoaded()
+	if cellRef.Row >= len(sh.Rows) || sh.Rows[cellRef.Row] == nil {
+		return nil, nil
+	}
+	cells := sh.Rows[cellRef.Row].Cells
+	if cellRef.Col >= len(cells) || cells[cellRef.Col] == nil {
+		return nil, nil
+	}
+	return cells[cellRef.Col].typedValue(), nil
+}
+
+// ForEachCell calls visit with the zero based row and column of each
+// cell of the Sheet that holds a value or formula, row by row.  Rows
+// and cells that are nil, or are empty, are skipped, so visiting a
+// sparse sheet costs no more than the cells it has.  If visit returns
+// an error, ForEachCell stops and returns it.
+func (sh *Sheet) ForEachCell(visit func(row, col int, cell *Cell) error) error {
+	if err := sh.Load(); err != nil {
+		return err
+	}
+	for r, row := range sh.Rows {
+		if row == nil {
+			continue
+		}
+		for c, cell := range row.Cells {
+			if cell == nil || cell.Value == "" && cell.formula == "" {
+				continue
+			}
+			if err := visit(r, c, cell); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ForEachValue calls visit with the zero based row and column, and the
+// raw, displayed and typed values, of each cell of the Sheet that
+// ForEachCell visits, stopping at the first error visit returns.
+func (sh *Sheet) ForEachValue(visit func(row, col int, v CellValues) error) error {
+	return sh.ForEachCell(func(row, col int, cell *Cell) error {
+		return visit(row, col, cell.Values())
+	})
+}
+
+// Dimension returns the used range of the Sheet, such as "Z100:AB120":
+// the smallest range holding every cell with a value or formula.  It
+// returns "" if there are no such cells.
+func (sh *Sheet) Dimension() string {
+	sh.ensureLoaded()
+	minRow, minCol, maxRow, maxCol := -1, -1, -1, -1
+	for r, row := range sh.Rows {
+		if row == nil {
+			continue
+		}
+		for c, cell := range row.Cells {
+			if cell == nil || cell.Value == "" && cell.formula == "" {
+				continue
+			}
+			if minRow < 0 {
+				minRow = r
+			}
+			if minCol < 0 || c < minCol {
+				minCol = c
+			}
+			if c > maxCol {
+				maxCol = c
+			}
+			maxRow = r
+		}
+	}
+	if minRow < 0 {
+		return ""
+	}
+	if minRow == maxRow && minCol == maxCol {
+		return getCellIDStringFromCoords(minCol, minRow)
+	}
+	return getCellIDStringFromCoords(minCol, minRow) + ":" + getCellIDStringFromCoords(maxCol, maxRow)
 }
 
 //Set the width of a single column or multiple columns.
@@ -115,14 +527,27 @@ func (s *Sheet) SetColWidth(startcol, endcol int, width float64) error {
 	if startcol > endcol {
 		return fmt.Errorf("Could not set width for range %d-%d: startcol must be less than endcol.", startcol, endcol)
 	}
-	col := &Col{
-		style:     NewStyle(),
-		Min:       startcol + 1,
-		Max:       endcol + 1,
-		Hidden:    false,
-		Collapsed: false,
-		Width:     width}
-	s.Cols = append(s.Cols, col)
+	if err := s.Load(); err != nil {
+		return err
+	}
+	s.Cols.SetColWidth(startcol, endcol, width)
+	if endcol+1 > s.MaxCol {
+		s.MaxCol = endcol + 1
+	}
+	return nil
+}
+
+// SetColStyle sets the style of a single column or multiple columns,
+// which cells added to them later are given.  Cells already in them
+// keep their own styles.
+func (s *Sheet) SetColStyle(startcol, endcol int, style *Style) error {
+	if startcol > endcol {
+		return fmt.Errorf("Could not set style for range %d-%d: startcol must be less than endcol.", startcol, endcol)
+	}
+	if err := s.Load(); err != nil {
+		return err
+	}
+	s.Cols.SetColStyle(startcol, endcol, style)
 	if endcol+1 > s.MaxCol {
 		s.MaxCol = endcol + 1
 	}
@@ -138,8 +563,11 @@ func (s *Sheet) handleMerged() {
 	merged := make(map[string]*Cell)
 
 	for r, row := range s.Rows {
+		if row == nil {
+			continue
+		}
 		for c, cell := range row.Cells {
-			if cell.HMerge > 0 || cell.VMerge > 0 {
+			if cell != nil && (cell.HMerge > 0 || cell.VMerge > 0) {
 				coord := fmt.Sprintf("%s%d", numericToLetters(c), r+1)
 				merged[coord] = cell
 			}
@@ -198,6 +626,17 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxW
 	maxRow := 0
 	maxCell := 0
 	var maxLevelCol, maxLevelRow uint8
+	// When trimming, used is the range of the cells written, as
+	// its first row and column and last row and column, and
+	// keptRows the number of rows up to the last one that isn't
+	// blank.
+	trim := s.File != nil && s.File.defaults.trimEmptyCells
+	precision := 0
+	if s.File != nil {
+		precision = s.File.defaults.floatPrecision
+	}
+	used := [4]int{-1, -1, -1, -1}
+	keptRows := 0
 
 	// Scan through the sheet and see if there are any merged cells. If there
 	// are, we may need to extend the size of the sheet. There needs to be
@@ -208,6 +647,7 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxW
 		worksheet.SheetViews.SheetView[0].TabSelected = true
 	}
 
+	s.View.apply(&worksheet.SheetViews.SheetView[0])
 	if s.ShowGridLines == true {
 		worksheet.SheetViews.SheetView[0].ShowGridLines = true
 	}
@@ -217,31 +657,40 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxW
 	}
 
 	worksheet.SheetPr.PageSetUpPr[0].FitToPage = s.FitToPage
+	if s.TabColor != "" {
+		worksheet.SheetPr.TabColor = &xlsxColor{RGB: s.TabColor}
+	}
+	worksheet.SheetPr.CodeName = s.CodeName
+	worksheet.SheetPr.OutlinePr = s.Outline.makeOutlinePr()
 
 	worksheet.PageMargins = s.PageMargins
 	worksheet.PageSetUp = s.PageSetUp
+	if s.Protected {
+		worksheet.SheetProtection = &xlsxSheetProtection{Sheet: true, Objects: true, Scenarios: true}
+	}
+	worksheet.IgnoredErrors = makeIgnoredErrors(s.IgnoredErrors)
+	worksheet.CustomSheetViews = s.makeCustomSheetViews()
+	worksheet.Hyperlinks = makeHyperlinks(s.Hyperlinks)
 
 	if strings.EqualFold(worksheet.PageSetUp.Orientation, "landscape") == true {
 		worksheet.SheetPr.PageSetUpPr[0].FitToPage = 1
 		worksheet.PageSetUp.UsePrinterDefaults = true
-		worksheet.PageSetUp.FitToWidth = 1
+		if worksheet.PageSetUp.FitToWidth == 0 && s.FitToPage == 0 {
+			worksheet.PageSetUp.FitToWidth = 1
+		}
 	}
 
 	if s.SheetFormat.DefaultRowHeight != 0 {
 		worksheet.SheetFormatPr.DefaultRowHeight = s.SheetFormat.DefaultRowHeight
+		worksheet.SheetFormatPr.CustomHeight = s.SheetFormat.CustomHeight
 	}
 	worksheet.SheetFormatPr.DefaultColWidth = s.SheetFormat.DefaultColWidth
 
-	colsXfIdList := make([]int, len(s.Cols))
+	cols := s.Cols.normalised()
+	colsXfIdList := make([]int, len(cols))
 	worksheet.Cols = &xlsxCols{Col: []xlsxCol{}}
-	for c, col := range s.Cols {
+	for c, col := range cols {
 		XfId := 0
-		if col.Min == 0 {
-			col.Min = 1
-		}
-		if col.Max == 0 {
-			col.Max = 1
-		}
 		style := col.GetStyle()
 		//col's style always not nil
 		if style != nil {
@@ -251,8 +700,12 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxW
 		colsXfIdList[c] = XfId
 
 		var customWidth int
-		if col.Width == 0 {
-			col.Width = ColWidth
+		width := col.Width
+		if width == 0 {
+			width = s.SheetFormat.DefaultColWidth
+			if width == 0 {
+				width = ColWidth
+			}
 		} else {
 			customWidth = 1
 		}
@@ -260,7 +713,7 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxW
 			xlsxCol{Min: col.Min,
 				Max:          col.Max,
 				Hidden:       col.Hidden,
-				Width:        col.Width,
+				Width:        width,
 				CustomWidth:  customWidth,
 				Collapsed:    col.Collapsed,
 				OutlineLevel: col.OutlineLevel,
@@ -272,12 +725,27 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxW
 		}
 	}
 
+	formulas := newSharedFormulaWriter()
+	// formulaFor returns the f element of a cell, which an array
+	// formula read is kept as rather than joining a shared formula.
+	formulaFor := func(c, r int, cell *Cell) *xlsxF {
+		formula := s.File.externalFormula(cell.formula)
+		if f := cell.meta.array(cell, formula); f != nil {
+			return f
+		}
+		return formulas.formula(c, r, formula)
+	}
+
 	for r, row := range s.Rows {
+		if row == nil {
+			continue
+		}
 		if r > maxRow {
 			maxRow = r
 		}
 		xRow := xlsxRow{}
 		xRow.R = r + 1
+		xRow.Hidden = row.Hidden
 		if row.isCustom {
 			xRow.CustomHeight = true
 			xRow.Ht = fmt.Sprintf("%g", row.Height)
@@ -286,8 +754,25 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxW
 		if row.OutlineLevel > maxLevelRow {
 			maxLevelRow = row.OutlineLevel
 		}
+		if row.style != nil {
+			xRow.S = handleStyleForXLSX(row.style, 0, styles)
+			xRow.CustomFormat = true
+		}
+		keptCells := 0
 		for c, cell := range row.Cells {
-			XfId := colsXfIdList[c]
+			if cell == nil {
+				continue
+			}
+			XfId := 0
+			var colNumFmt string
+			if i, ok := cols.index(c + 1); ok {
+				XfId = colsXfIdList[i]
+				colNumFmt = cols[i].numFmt
+			}
+			if xRow.CustomFormat {
+				XfId = xRow.S
+			}
+			inheritedXfId := XfId
 
 			// generate NumFmtId and add new NumFmt
 			xNumFmt := styles.newNumFmt(cell.NumFmt)
@@ -295,7 +780,7 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxW
 			style := cell.style
 			if style != nil {
 				XfId = handleStyleForXLSX(style, xNumFmt.NumFmtId, styles)
-			} else if len(cell.NumFmt) > 0 && s.Cols[c].numFmt != cell.NumFmt {
+			} else if len(cell.NumFmt) > 0 && colNumFmt != cell.NumFmt {
 				XfId = handleNumFmtIdForXLSX(xNumFmt.NumFmtId, styles)
 			}
 
@@ -306,36 +791,72 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxW
 			xC.R = fmt.Sprintf("%s%d", numericToLetters(c), r+1)
 			switch cell.cellType {
 			case CellTypeString:
-				if len(cell.Value) > 0 {
-					xC.V = strconv.Itoa(refTable.AddString(cell.Value))
+				if refTable.inline {
+					xC.T = "inlineStr"
+					xC.Is = &xlsxSI{T: cell.Value}
+					if p := cell.Phonetic(); p != nil {
+						xC.Is.RPh, xC.Is.PhoneticPr = p.written(styles).xlsx()
+					}
+				} else {
+					if len(cell.Value) > 0 {
+						p := cell.Phonetic()
+						if p != nil {
+							p = p.written(styles)
+						}
+						xC.V = strconv.Itoa(refTable.addPhoneticString(cell.Value, p))
+					}
+					xC.T = "s"
 				}
-				xC.T = "s"
 				xC.S = XfId
 			case CellTypeBool:
 				xC.V = cell.Value
 				xC.T = "b"
 				xC.S = XfId
 			case CellTypeNumeric:
-				xC.V = cell.Value
+				xC.V = cell.roundedValue(precision)
 				xC.S = XfId
 			case CellTypeDate:
 				xC.V = cell.Value
 				xC.S = XfId
 			case CellTypeFormula:
 				xC.V = cell.Value
-				xC.F = &xlsxF{Content: cell.formula}
+				xC.F = formulaFor(c, r, cell)
 				xC.S = XfId
+				xC.T = cell.formulaResultT()
+				if xC.T == "" && !isTimeFormat(cell.NumFmt) {
+					xC.V = cell.roundedValue(precision)
+				}
 			case CellTypeError:
 				xC.V = cell.Value
-				xC.F = &xlsxF{Content: cell.formula}
+				if cell.formula != "" {
+					xC.F = formulaFor(c, r, cell)
+				}
 				xC.T = "e"
 				xC.S = XfId
 			case CellTypeGeneral:
-				xC.V = cell.Value
+				xC.V = cell.roundedValue(precision)
 				xC.S = XfId
 			}
 
+			cell.meta.write(&xC, s.File != nil && s.File.sheetMetadata != nil)
+			if cell.phonetic != nil {
+				p := cell.Phonetic()
+				xC.Ph = p != nil && p.Show
+			}
 			xRow.C = append(xRow.C, xC)
+			if cell.Value != "" || cell.formula != "" || cell.HMerge > 0 || cell.VMerge > 0 || XfId != inheritedXfId {
+				keptCells = len(xRow.C)
+				if used[0] < 0 {
+					used[0] = r
+				}
+				if used[1] < 0 || c < used[1] {
+					used[1] = c
+				}
+				used[2] = r
+				if c > used[3] {
+					used[3] = c
+				}
+			}
 
 			if cell.HMerge > 0 || cell.VMerge > 0 {
 				// r == rownum, c == colnum
@@ -351,7 +872,22 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxW
 				worksheet.MergeCells.Cells = append(worksheet.MergeCells.Cells, mc)
 			}
 		}
+		if trim {
+			xRow.C = xRow.C[:keptCells]
+		}
+		first, last := -1, -1
+		if len(xRow.C) > 0 {
+			first, _, _ = getCoordsFromCellIDString(xRow.C[0].R)
+			last, _, _ = getCoordsFromCellIDString(xRow.C[len(xRow.C)-1].R)
+		}
+		row.meta.write(&xRow, first, last)
 		xSheet.Row = append(xSheet.Row, xRow)
+		if len(xRow.C) > 0 || xRow.Hidden || xRow.CustomHeight || xRow.CustomFormat || xRow.OutlineLevel != 0 {
+			keptRows = len(xSheet.Row)
+		}
+	}
+	if trim {
+		xSheet.Row = xSheet.Row[:keptRows]
 	}
 
 	// Update sheet format with the freshly determined max levels
@@ -369,90 +905,177 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxW
 	dimension := xlsxDimension{}
 	dimension.Ref = fmt.Sprintf("A1:%s%d",
 		numericToLetters(maxCell), maxRow+1)
+	if trim && used[0] >= 0 {
+		dimension.Ref = getCellIDStringFromCoords(used[1], used[0]) + ":" + getCellIDStringFromCoords(used[3], used[2])
+	} else if trim {
+		dimension.Ref = "A1"
+	}
 	if dimension.Ref == "A1:A1" {
 		dimension.Ref = "A1"
 	}
 	worksheet.Dimension = dimension
-	worksheet.Drawing.SetId(1)
 
 	return worksheet
 }
 
+// sharedFormulaGroup is a run of cells in a single column whose
+// formulas are all the anchor's formula with its relative references
+// shifted down one row per cell.
+type sharedFormulaGroup struct {
+	x, y    int // coordinates of the anchor cell
+	lastY   int // row of the last cell in the run
+	formula string
+	anchor  *xlsxF
+}
+
+// sharedFormulaWriter collapses formulas that repeat down a column
+// into shared formulas (t="shared") as a worksheet is written, so that
+// a formula copied into thousands of rows is only stored once.  Shared
+// formula indexes are scoped to the worksheet being written.
+type sharedFormulaWriter struct {
+	groups map[int]*sharedFormulaGroup // keyed by column
+	count  int
+}
+
+func newSharedFormulaWriter() *sharedFormulaWriter {
+	return &sharedFormulaWriter{groups: make(map[int]*sharedFormulaGroup)}
+}
+
+// formula returns the xlsxF to write for the formula of the cell at
+// the zero based coordinates x, y.  Cells must be passed in row order.
+func (w *sharedFormulaWriter) formula(x, y int, formula string) *xlsxF {
+	group, ok := w.groups[x]
+	if ok && formula != "" && group.lastY == y-1 && shiftFormula(group.formula, 0, y-group.y) == formula {
+		if group.anchor.T != "shared" {
+			group.anchor.T = "shared"
+			group.anchor.Si = w.count
+			w.count++
+		}
+		group.lastY = y
+		group.anchor.Ref = getCellIDStringFromCoords(x, group.y) + ":" + getCellIDStringFromCoords(x, y)
+		return &xlsxF{T: "shared", Si: group.anchor.Si}
+	}
+	f := &xlsxF{Content: formula}
+	w.groups[x] = &sharedFormulaGroup{x: x, y: y, lastY: y, formula: formula, anchor: f}
+	return f
+}
+
 // InsertImage from path
 // Support from URL or filesystem
 // rowCount = 0 for dynamic height
 func (s *Sheet) InsertImage(imagePath string, row, col, rowCount, colCount int) error {
+	drawing, err := s.newImageDrawing(imagePath, row, col)
+	if err != nil {
+		return err
+	}
+	drawing.RowCount = rowCount
+	drawing.ColCount = colCount
+	s.Drawings = append(s.Drawings, drawing)
+	return nil
+}
 
+// InsertImageScaled inserts the image at imagePath, from a URL or the
+// filesystem, with its top left corner at the cell, at scale times its
+// size in pixels.  The cell its bottom right corner falls in is worked
+// out from the widths of the columns and heights of the rows beneath
+// it when the File is written.
+func (s *Sheet) InsertImageScaled(imagePath string, row, col int, scale float64) error {
+	drawing, err := s.newImageDrawing(imagePath, row, col)
+	if err != nil {
+		return err
+	}
+	drawing.ExtentX = int(float64(drawing.Width*EMUPerPixel) * scale)
+	drawing.ExtentY = int(float64(drawing.Height*EMUPerPixel) * scale)
+	s.Drawings = append(s.Drawings, drawing)
+	return nil
+}
+
+// InsertImageSized is like InsertImageScaled, but scales the image to
+// width by height pixels.  If either of them is zero, it is worked out
+// from the other so as to keep the image's aspect ratio.
+func (s *Sheet) InsertImageSized(imagePath string, row, col, width, height int) error {
+	return s.insertImageSize(imagePath, row, col, float64(width*EMUPerPixel), float64(height*EMUPerPixel))
+}
+
+// InsertImageSizedCM is like InsertImageSized, with the width and
+// height in centimetres.
+func (s *Sheet) InsertImageSizedCM(imagePath string, row, col int, width, height float64) error {
+	return s.insertImageSize(imagePath, row, col, width*EMUPerCM, height*EMUPerCM)
+}
+
+func (s *Sheet) insertImageSize(imagePath string, row, col int, cx, cy float64) error {
+	drawing, err := s.newImageDrawing(imagePath, row, col)
+	if err != nil {
+		return err
+	}
+	switch {
+	case cx == 0 && cy == 0:
+	case cx == 0:
+		cx = cy * float64(drawing.Width) / float64(drawing.Height)
+	case cy == 0:
+		cy = cx * float64(drawing.Height) / float64(drawing.Width)
+	}
+	drawing.ExtentX, drawing.ExtentY = int(cx), int(cy)
+	s.Drawings = append(s.Drawings, drawing)
+	return nil
+}
+
+// newImageDrawing returns a Drawing of the image at imagePath, from a
+// URL or the filesystem, with its top left corner at the cell.
+func (s *Sheet) newImageDrawing(imagePath string, row, col int) (Drawing, error) {
 	fileName := imagePath // Full path file
 
-	if _, err := url.ParseRequestURI(imagePath); err == nil {
+	if u, err := url.ParseRequestURI(imagePath); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
 		// http URL
 		// don't worry about errors
 		resp, err := http.Get(imagePath)
-		defer resp.Body.Close()
-
 		if err != nil {
-			return err
+			return Drawing{}, err
 		}
+		defer resp.Body.Close()
 
 		tmpfile, err := ioutil.TempFile("", "gmaps")
 		if err != nil {
-			return err
+			return Drawing{}, err
 		}
 
 		defer os.Remove(tmpfile.Name()) // clean up
 		// Use io.Copy to just dump the response body to the file. This supports huge files
 		if _, err = io.Copy(tmpfile, resp.Body); err != nil {
-			return err
+			return Drawing{}, err
 		}
 
 		if err := tmpfile.Close(); err != nil {
-			return err
+			return Drawing{}, err
 		}
 		fileName = tmpfile.Name()
 	}
 
-	if imageFileData, err := ioutil.ReadFile(fileName); err == nil {
-		if reader, err := os.Open(fileName); err == nil {
-			if im, formatName, err := image.DecodeConfig(reader); err == nil {
-				var imageType ImageType
-				switch formatName {
-				case "jpg", "jpeg":
-					imageType = IMAGE_TYPE_JPG
-				case "png":
-					imageType = IMAGE_TYPE_PNG
-				case "gif":
-					imageType = IMAGE_TYPE_GIF
-				}
-
-				drawing := Drawing{
-					s,
-					imageFileData,
-					imageType,
-					DrawingCell{
-						row,
-						col,
-					},
-					rowCount,
-					colCount,
-					im.Width,
-					im.Height,
-				}
-				s.Drawings = append(s.Drawings, drawing)
-			} else {
-				return err
-			}
-		} else {
-			return err
-		}
-	} else {
-		return err
+	imageFileData, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return Drawing{}, err
 	}
-
-	return nil
+	imageType, config, err := decodeImage(imageFileData)
+	if err != nil {
+		return Drawing{}, err
+	}
+	return Drawing{
+		Sheet:       s,
+		ImageData:   imageFileData,
+		ImageType:   imageType,
+		TopLeftCell: DrawingCell{RowNum: row, ColNum: col},
+		Width:       config.Width,
+		Height:      config.Height,
+	}, nil
 }
 
 func handleStyleForXLSX(style *Style, NumFmtId int, styles *xlsxStyleSheet) (XfId int) {
+	return styles.addCellXf(styles.makeXf(style, NumFmtId))
+}
+
+// makeXf returns the xf for a style, adding its font, fill and border
+// to the style sheet.
+func (styles *xlsxStyleSheet) makeXf(style *Style, NumFmtId int) xlsxXf {
 	xFont, xFill, xBorder, xCellXf := style.makeXLSXStyleElements()
 	fontId := styles.addFont(xFont)
 	fillId := styles.addFill(xFill)
@@ -472,15 +1095,13 @@ func handleStyleForXLSX(style *Style, NumFmtId int, styles *xlsxStyleSheet) (XfI
 		xCellXf.ApplyNumberFormat = true
 	}
 
-	xCellXf.Alignment.Horizontal = style.Alignment.Horizontal
+	xCellXf.Alignment.Horizontal = alignmentValue(string(style.Alignment.Horizontal), horizontalAlignments)
 	xCellXf.Alignment.Indent = style.Alignment.Indent
 	xCellXf.Alignment.ShrinkToFit = style.Alignment.ShrinkToFit
 	xCellXf.Alignment.TextRotation = style.Alignment.TextRotation
-	xCellXf.Alignment.Vertical = style.Alignment.Vertical
+	xCellXf.Alignment.Vertical = alignmentValue(string(style.Alignment.Vertical), verticalAlignments)
 	xCellXf.Alignment.WrapText = style.Alignment.WrapText
-
-	XfId = styles.addCellXf(xCellXf)
-	return
+	return xCellXf
 }
 
 func handleNumFmtIdForXLSX(NumFmtId int, styles *xlsxStyleSheet) (XfId int) {
@@ -492,3 +1113,136 @@ func handleNumFmtIdForXLSX(NumFmtId int, styles *xlsxStyleSheet) (XfId int) {
 	XfId = styles.addCellXf(xCellXf)
 	return
 }
+
+// SheetOption configures a Sheet as it is added to a File by
+// File.AddSheet.
+type SheetOption func(*sheetOptions)
+
+type sheetOptions struct {
+	index       int
+	hidden      bool
+	veryHidden  bool
+	tabColor    string
+	codeName    string
+	rightToLeft bool
+	template    *Sheet
+}
+
+// SheetAt inserts the new Sheet at the given zero based position among
+// the File's Sheets, rather than after the last one.
+func SheetAt(index int) SheetOption {
+	return func(o *sheetOptions) {
+		o.index = index
+	}
+}
+
+// SheetHidden hides the new Sheet.  Users can unhide it from Excel.
+func SheetHidden() SheetOption {
+	return func(o *sheetOptions) {
+		o.hidden = true
+	}
+}
+
+// SheetVeryHidden hides the new Sheet so that it can only be unhidden
+// programmatically, e.g. from VBA.
+func SheetVeryHidden() SheetOption {
+	return func(o *sheetOptions) {
+		o.veryHidden = true
+	}
+}
+
+// SheetTabColor sets the color of the new Sheet's tab, given in ARGB
+// form, e.g. "FFFF0000" for red.
+func SheetTabColor(argb string) SheetOption {
+	return func(o *sheetOptions) {
+		o.tabColor = argb
+	}
+}
+
+// SheetRightToLeft lays the new Sheet out from right to left, for
+// Arabic and Hebrew text, with column A at the right; see
+// ViewSettings.RightToLeft.
+func SheetRightToLeft() SheetOption {
+	return func(o *sheetOptions) {
+		o.rightToLeft = true
+	}
+}
+
+// SheetFrom makes the new Sheet a copy of template - its rows, cells,
+// styles, columns, views and page setup - which may belong to the
+// same File or another one.
+func SheetFrom(template *Sheet) SheetOption {
+	return func(o *sheetOptions) {
+		o.template = template
+	}
+}
+
+// clone returns a deep copy of the Sheet, with the given name and
+// belonging to the given File.
+func (s *Sheet) clone(name string, file *File) *Sheet {
+	s.ensureLoaded()
+	sheet := *s
+	sheet.lazy = nil
+	sheet.Name = name
+	sheet.File = file
+	sheet.Rows = make([]*Row, len(s.Rows))
+	for i, row := range s.Rows {
+		if row == nil {
+			continue
+		}
+		r := *row
+		r.Sheet = &sheet
+		if row.style != nil {
+			style := *row.style
+			r.style = &style
+		}
+		r.Cells = make([]*Cell, len(row.Cells))
+		for j, cell := range row.Cells {
+			if cell == nil {
+				continue
+			}
+			c := *cell
+			c.Row = &r
+			if cell.style != nil {
+				style := *cell.style
+				c.style = &style
+			}
+			r.Cells[j] = &c
+		}
+		sheet.Rows[i] = &r
+	}
+	sheet.Cols = make(ColStore, len(s.Cols))
+	for i, col := range s.Cols {
+		c := *col
+		if col.style != nil {
+			style := *col.style
+			c.style = &style
+		}
+		sheet.Cols[i] = &c
+	}
+	sheet.SheetViews = make([]SheetView, len(s.SheetViews))
+	for i, view := range s.SheetViews {
+		if view.Pane != nil {
+			pane := *view.Pane
+			view.Pane = &pane
+		}
+		sheet.SheetViews[i] = view
+	}
+	sheet.Drawings = make([]Drawing, len(s.Drawings))
+	for i, drawing := range s.Drawings {
+		drawing.Sheet = &sheet
+		sheet.Drawings[i] = drawing
+	}
+	sheet.Shapes = make([]*Shape, len(s.Shapes))
+	for i, shape := range s.Shapes {
+		c := *shape
+		sheet.Shapes[i] = &c
+	}
+	sheet.IgnoredErrors = append([]IgnoredError(nil), s.IgnoredErrors...)
+	sheet.Hyperlinks = append([]Hyperlink(nil), s.Hyperlinks...)
+	sheet.CustomViews = make([]CustomSheetView, len(s.CustomViews))
+	for i, view := range s.CustomViews {
+		sheet.CustomViews[i] = view.copy()
+	}
+	return &sheet
+}
@@ -3,6 +3,9 @@ package xlsx
 import (
 	"bytes"
 	"encoding/xml"
+	"fmt"
+	"testing"
+	"time"
 
 	. "gopkg.in/check.v1"
 )
@@ -250,6 +253,73 @@ func (s *SheetSuite) TestSetRowHeightCM(c *C) {
 	c.Assert(row.Height, Equals, 42.51968505)
 }
 
+func (s *SheetSuite) TestRowHeights(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	sheet.SetDefaultRowHeight(18)
+	tall := sheet.AddRow()
+	tall.AddCell().SetString("tall")
+	tall.SetHeight(30)
+	c.Assert(tall.HasCustomHeight(), Equals, true)
+	auto := sheet.AddRow()
+	auto.AddCell().SetString("wrapped\ntext")
+	auto.SetHeight(10)
+	auto.SetAutoHeight()
+	c.Assert(auto.HasCustomHeight(), Equals, false)
+
+	xSheet := sheet.makeXLSXSheet(NewSharedStringRefTable(), newXlsxStyleSheet(nil))
+	c.Assert(xSheet.SheetFormatPr.DefaultRowHeight, Equals, 18.0)
+	c.Assert(xSheet.SheetFormatPr.CustomHeight, Equals, true)
+	c.Assert(xSheet.SheetData.Row[0].Ht, Equals, "30")
+	c.Assert(xSheet.SheetData.Row[0].CustomHeight, Equals, true)
+	c.Assert(xSheet.SheetData.Row[1].Ht, Equals, "")
+	c.Assert(xSheet.SheetData.Row[1].CustomHeight, Equals, false)
+
+	var buf bytes.Buffer
+	c.Assert(file.Write(&buf), IsNil)
+	written, err := OpenBinary(buf.Bytes())
+	c.Assert(err, IsNil)
+	sheet = written.Sheets[0]
+	c.Assert(sheet.SheetFormat.DefaultRowHeight, Equals, 18.0)
+	c.Assert(sheet.SheetFormat.CustomHeight, Equals, true)
+	c.Assert(sheet.Rows[0].Height, Equals, 30.0)
+	c.Assert(sheet.Rows[0].HasCustomHeight(), Equals, true)
+	c.Assert(sheet.Rows[1].HasCustomHeight(), Equals, false)
+
+	// A default height that wasn't chosen, such as the one Excel
+	// writes for its default font, isn't made custom.
+	sheet.SheetFormat = SheetFormat{DefaultRowHeight: 15}
+	xSheet = sheet.makeXLSXSheet(NewSharedStringRefTable(), newXlsxStyleSheet(nil))
+	c.Assert(xSheet.SheetFormatPr.DefaultRowHeight, Equals, 15.0)
+	c.Assert(xSheet.SheetFormatPr.CustomHeight, Equals, false)
+}
+
+func (s *SheetSuite) TestHiddenRowsAndCols(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	for r := 0; r < 3; r++ {
+		row := sheet.AddRow()
+		for col := 0; col < 3; col++ {
+			row.AddCell().SetInt(r*3 + col)
+		}
+	}
+	sheet.Rows[1].Hidden = true
+	sheet.Col(2).Hidden = true
+
+	var buf bytes.Buffer
+	c.Assert(file.Write(&buf), IsNil)
+	written, err := OpenBinary(buf.Bytes())
+	c.Assert(err, IsNil)
+	sheet = written.Sheets[0]
+	c.Assert(sheet.Rows[0].Hidden, Equals, false)
+	c.Assert(sheet.Rows[1].Hidden, Equals, true)
+	c.Assert(sheet.Cols[1].Hidden, Equals, false)
+	c.Assert(sheet.Cols[2].Hidden, Equals, true)
+	c.Assert(sheet.Cell(0, 0).Hidden, Equals, false)
+	c.Assert(sheet.Cell(1, 0).Hidden, Equals, true)
+	c.Assert(sheet.Cell(0, 2).Hidden, Equals, true)
+}
+
 func (s *SheetSuite) TestAlignment(c *C) {
 	leftalign := *DefaultAlignment()
 	leftalign.Horizontal = "left"
@@ -392,3 +462,319 @@ func (s *SheetSuite) TestOutlineLevels(c *C) {
 	c.Assert(worksheet.SheetData.Row[1].OutlineLevel, Equals, uint8(2))
 	c.Assert(worksheet.SheetData.Row[2].OutlineLevel, Equals, uint8(0))
 }
+
+// Test that a formula repeated down a column is written as a single
+// shared formula, and that a break in the pattern starts a new one.
+func (s *SheetSuite) TestMakeXLSXSheetWithSharedFormulas(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	for i, formula := range []string{"2*A1", "2*A2", "2*A3", "SUM(A1:A4)", "A1"} {
+		row := sheet.AddRow()
+		row.AddCell().SetInt(i)
+		row.AddCell().SetFormula(formula)
+	}
+
+	refTable := NewSharedStringRefTable()
+	styles := newXlsxStyleSheet(nil)
+	worksheet := sheet.makeXLSXSheet(refTable, styles)
+
+	rows := worksheet.SheetData.Row
+	c.Assert(*rows[0].C[1].F, Equals, xlsxF{Content: "2*A1", T: "shared", Ref: "B1:B3", Si: 0})
+	c.Assert(*rows[1].C[1].F, Equals, xlsxF{T: "shared", Si: 0})
+	c.Assert(*rows[2].C[1].F, Equals, xlsxF{T: "shared", Si: 0})
+	c.Assert(*rows[3].C[1].F, Equals, xlsxF{Content: "SUM(A1:A4)"})
+	c.Assert(*rows[4].C[1].F, Equals, xlsxF{Content: "A1"})
+
+	output, err := xml.Marshal(rows[1].C[1])
+	c.Assert(err, IsNil)
+	c.Assert(string(output), Equals, `<xlsxC r="B2"><f t="shared" si="0"></f></xlsxC>`)
+}
+
+// Test that string results of formulas are written with t="str".
+func (s *SheetSuite) TestMakeXLSXSheetWithStringFormulaResult(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	cell := sheet.AddRow().AddCell()
+	cell.SetFormula(`"a"&"b"`)
+	cell.Value = "ab"
+	xSheet := sheet.makeXLSXSheet(NewSharedStringRefTable(), newXlsxStyleSheet(nil))
+	c.Assert(xSheet.SheetData.Row[0].C[0].T, Equals, "str")
+	c.Assert(xSheet.SheetData.Row[0].C[0].V, Equals, "ab")
+}
+
+// Test that shared formulas survive a round trip through a file.
+func (s *SheetSuite) TestSharedFormulasRoundTrip(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	for i := 0; i < 4; i++ {
+		row := sheet.AddRow()
+		row.AddCell().SetInt(i)
+		row.AddCell().SetFormula(fmt.Sprintf("A%d*$A$1", i+1))
+	}
+
+	var buf bytes.Buffer
+	err := file.Write(&buf)
+	c.Assert(err, IsNil)
+	file, err = OpenBinary(buf.Bytes())
+	c.Assert(err, IsNil)
+
+	sheet = file.Sheets[0]
+	for i := 0; i < 4; i++ {
+		c.Assert(sheet.Rows[i].Cells[1].Formula(), Equals, fmt.Sprintf("A%d*$A$1", i+1))
+	}
+}
+
+func (s *SheetSuite) TestViewSettings(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	sheet.AddRow().AddCell().SetString("A1")
+	sheet.View = ViewSettings{
+		ZoomScale:         150,
+		ShowGridLines:     true,
+		HideRowColHeaders: true,
+		RightToLeft:       true,
+		Type:              ViewPageLayout,
+	}
+	sheet.TabColor = "FF00FF00"
+	plain, _ := file.AddSheet("Sheet2")
+	plain.AddRow().AddCell().SetString("A1")
+
+	xSheet := sheet.makeXLSXSheet(NewSharedStringRefTable(), newXlsxStyleSheet(nil))
+	xView := xSheet.SheetViews.SheetView[0]
+	c.Assert(xView.ZoomScale, Equals, 150.0)
+	c.Assert(xView.ZoomScalePageLayoutView, Equals, 150.0)
+	c.Assert(xView.ZoomScaleNormal, Equals, 100.0)
+	c.Assert(xView.View, Equals, "pageLayout")
+	c.Assert(xView.ShowRowColHeaders, Equals, false)
+
+	var buf bytes.Buffer
+	c.Assert(file.Write(&buf), IsNil)
+	written, err := OpenBinary(buf.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(written.Sheet["Sheet1"].View, DeepEquals, sheet.View)
+	c.Assert(written.Sheet["Sheet1"].TabColor, Equals, "FF00FF00")
+	c.Assert(written.Sheet["Sheet2"].View, DeepEquals, ViewSettings{})
+}
+
+// A sheetView element that leaves out its attributes has Excel's
+// defaults, so the gridlines and headers are shown.
+func (s *SheetSuite) TestReadDefaultViewSettings(c *C) {
+	worksheet := new(xlsxWorksheet)
+	err := xml.Unmarshal([]byte(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetViews><sheetView workbookViewId="0"/></sheetViews></worksheet>`), worksheet)
+	c.Assert(err, IsNil)
+	view := readViewSettings(worksheet.SheetViews.SheetView[0])
+	c.Assert(view, DeepEquals, ViewSettings{ShowGridLines: true})
+}
+
+func (s *SheetSuite) TestCellInSparseSheet(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sparse")
+	sheet.Rows = []*Row{nil, {Sheet: sheet, Cells: []*Cell{nil, nil}}}
+	cell := sheet.Cell(0, 2)
+	cell.SetString("made")
+	c.Assert(sheet.Rows[0].Cells, HasLen, 3)
+	c.Assert(sheet.Cell(1, 1).Row, Equals, sheet.Rows[1])
+	c.Assert(sheet.Rows[1].Cells[0], IsNil)
+	c.Assert(sheet.Cell(3, 0), NotNil)
+	c.Assert(sheet.Rows, HasLen, 4)
+}
+
+func (s *SheetSuite) TestForEachCell(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sparse")
+	sheet.Cell(0, 0).SetString("A1")
+	sheet.Cell(2, 3).SetInt(4)
+	sheet.Cell(2, 5).SetFormula("D3*2")
+	sheet.Cell(3, 1)
+	sheet.Rows[1] = nil
+	sheet.Rows[2].Cells[1] = nil
+
+	var visited []string
+	err := sheet.ForEachCell(func(row, col int, cell *Cell) error {
+		visited = append(visited, getCellIDStringFromCoords(col, row))
+		return nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(visited, DeepEquals, []string{"A1", "D3", "F3"})
+
+	visited = nil
+	err = sheet.ForEachCell(func(row, col int, cell *Cell) error {
+		visited = append(visited, getCellIDStringFromCoords(col, row))
+		if row > 0 {
+			return fmt.Errorf("stopped at %s", cell.Value)
+		}
+		return nil
+	})
+	c.Assert(err, ErrorMatches, "stopped at 4")
+	c.Assert(visited, DeepEquals, []string{"A1", "D3"})
+}
+
+func (s *SheetSuite) TestForEachValue(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Values")
+	sheet.Cell(0, 0).SetString("00123")
+	sheet.Cell(0, 1).SetFloatWithFormat(0.125, "0.00%")
+	sheet.Cell(1, 0).SetDate(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+	sheet.Cell(1, 1).SetBool(true)
+	sheet.Cell(1, 2).SetString("")
+
+	values := make(map[string]CellValues)
+	err := sheet.ForEachValue(func(row, col int, v CellValues) error {
+		values[getCellIDStringFromCoords(col, row)] = v
+		return nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(values, DeepEquals, map[string]CellValues{
+		"A1": {Raw: "00123", Display: "00123", Typed: "00123"},
+		"B1": {Raw: "0.125", Display: "12.50%", Typed: 0.125},
+		"A2": {Raw: "45352", Display: "03-01-24", Typed: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		"B2": {Raw: "1", Display: "TRUE", Typed: true},
+	})
+	c.Assert(sheet.Cell(1, 2).Values(), DeepEquals, CellValues{})
+
+	err = sheet.ForEachValue(func(row, col int, v CellValues) error {
+		return fmt.Errorf("stopped at %s", v.Display)
+	})
+	c.Assert(err, ErrorMatches, "stopped at 00123")
+}
+
+func (s *SheetSuite) TestTrimEmptyCells(c *C) {
+	for _, trim := range []bool{false, true} {
+		var options []FileOption
+		if trim {
+			options = append(options, TrimEmptyCells())
+		}
+		file := NewFileWithOptions(options...)
+		sheet, _ := file.AddSheet("Trimmed")
+		sheet.Cell(1, 1).SetString("B2")
+		sheet.Cell(2, 3).SetInt(4)
+		// Data that has been cleared leaves empty cells behind.
+		sheet.Cell(2, 6).SetString("cleared")
+		sheet.Cell(2, 6).Value = ""
+		sheet.Cell(5, 8)
+		sheet.Cell(4, 0).Row.SetHeight(30)
+
+		xSheet := sheet.makeXLSXSheet(NewSharedStringRefTable(), newXlsxStyleSheet(nil))
+		if !trim {
+			c.Assert(xSheet.Dimension.Ref, Equals, "A1:I6")
+			c.Assert(xSheet.SheetData.Row, HasLen, 6)
+			c.Assert(xSheet.SheetData.Row[2].C, HasLen, 7)
+			continue
+		}
+		c.Assert(xSheet.Dimension.Ref, Equals, "B2:D3")
+		// The row with a height stays; the empty row after it goes.
+		c.Assert(xSheet.SheetData.Row, HasLen, 5)
+		c.Assert(xSheet.SheetData.Row[0].C, HasLen, 0)
+		c.Assert(xSheet.SheetData.Row[1].C, HasLen, 2)
+		c.Assert(xSheet.SheetData.Row[2].C, HasLen, 4)
+		c.Assert(xSheet.SheetData.Row[2].C[3].R, Equals, "D3")
+		c.Assert(xSheet.SheetData.Row[4].CustomHeight, Equals, true)
+		c.Assert(xSheet.SheetData.Row[4].C, HasLen, 0)
+	}
+}
+
+func (s *SheetSuite) TestTrimEmptyCellsKeepsStyledCells(c *C) {
+	file := NewFileWithOptions(TrimEmptyCells())
+	sheet, _ := file.AddSheet("Styled")
+	sheet.Cell(0, 0).SetString("A1")
+	fill := NewStyle()
+	fill.Fill = *NewFill("solid", "FFFFFF00", "FFFFFF00")
+	fill.ApplyFill = true
+	sheet.Cell(0, 2).SetStyle(fill)
+	sheet.Cell(0, 3)
+
+	xSheet := sheet.makeXLSXSheet(NewSharedStringRefTable(), newXlsxStyleSheet(nil))
+	c.Assert(xSheet.SheetData.Row[0].C, HasLen, 3)
+	c.Assert(xSheet.Dimension.Ref, Equals, "A1:C1")
+}
+
+func (s *SheetSuite) TestSetValueAndValue(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Values")
+	when := time.Date(2021, time.June, 7, 12, 30, 0, 0, time.UTC)
+	values := map[string]interface{}{
+		"A1": "text",
+		"B2": 42,
+		"C3": uint8(7),
+		"D4": 1.5,
+		"E5": true,
+		"F6": when,
+	}
+	for ref, value := range values {
+		c.Assert(sheet.SetValue(ref, value), IsNil)
+	}
+	c.Assert(sheet.SetValue("7B", 1), ErrorMatches, "invalid cell reference '7B'")
+	_, err := sheet.Value("A0")
+	c.Assert(err, ErrorMatches, "invalid cell reference 'A0'")
+
+	check := func(sheet *Sheet) {
+		expected := map[string]interface{}{
+			"A1":  "text",
+			"B2":  42.0,
+			"C3":  7.0,
+			"D4":  1.5,
+			"E5":  true,
+			"F6":  when,
+			"B1":  nil,
+			"Z99": nil,
+		}
+		for ref, value := range expected {
+			got, err := sheet.Value(ref)
+			c.Assert(err, IsNil)
+			c.Assert(got, DeepEquals, value, Commentf("cell %s", ref))
+		}
+	}
+	check(sheet)
+	c.Assert(sheet.Rows, HasLen, 6)
+
+	var buf bytes.Buffer
+	c.Assert(file.Write(&buf), IsNil)
+	read, err := OpenBinary(buf.Bytes())
+	c.Assert(err, IsNil)
+	check(read.Sheets[0])
+
+	c.Assert(sheet.SetValue("A1", nil), IsNil)
+	value, err := sheet.Value("A1")
+	c.Assert(err, IsNil)
+	c.Assert(value, IsNil)
+}
+
+func (s *SheetSuite) TestSetRange(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Block")
+	sheet.Cell(0, 0).SetString("title")
+	err := sheet.SetRange("B2", [][]interface{}{
+		{"name", "count", "done"},
+		{"one", 1, true},
+		{},
+		{"four", 4.5},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(sheet.Rows, HasLen, 5)
+	c.Assert(sheet.MaxCol, Equals, 4)
+	for ref, expected := range map[string]interface{}{
+		"A1": "title", "B2": "name", "D2": "done", "C3": 1.0, "D3": true,
+		"B4": nil, "B5": "four", "C5": 4.5, "D5": nil,
+	} {
+		value, err := sheet.Value(ref)
+		c.Assert(err, IsNil)
+		c.Assert(value, DeepEquals, expected, Commentf("cell %s", ref))
+	}
+	c.Assert(sheet.Rows[1].Cells[0], NotNil)
+
+	c.Assert(sheet.SetRange("B", nil), ErrorMatches, "invalid cell reference 'B'")
+	c.Assert(sheet.SetRange("XFD1", [][]interface{}{{1, 2}}), ErrorMatches, "range of 1 rows and 2 columns from 'XFD1' is out of range")
+}
+
+func BenchmarkSetRange(b *testing.B) {
+	values := make([][]interface{}, 1000)
+	for i := range values {
+		values[i] = []interface{}{i, "text", 1.5, true, "more text"}
+	}
+	for i := 0; i < b.N; i++ {
+		sheet, _ := NewFile().AddSheet("Block")
+		if err := sheet.SetRange("A1", values); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package xlsx
+
+import (
+	"encoding/xml"
+)
+
+// OutlineSettings control how Excel outlines the groups of rows and
+// columns of a Sheet, which the OutlineLevel of its Rows and Cols
+// give.  The zero value is Excel's default, with the row summarising
+// each group of rows below it and the column summarising each group of
+// columns to its right.
+type OutlineSettings struct {
+	// SummaryAbove puts the row summarising each group of rows, with
+	// the button that collapses it, above the group, as in reports
+	// with subtotals before their details.
+	SummaryAbove bool
+	// SummaryLeft puts the column summarising each group of columns
+	// to its left.
+	SummaryLeft bool
+	// ApplyStyles has Excel style the summary rows and columns with
+	// its RowLevel and ColLevel cell styles.
+	ApplyStyles bool
+	// HideOutlineSymbols hides the buttons that collapse and expand
+	// the groups.
+	HideOutlineSymbols bool
+}
+
+// FitToPages scales the Sheet, when it is printed, to fit on wide pages
+// across and tall pages down.  Zero for either leaves the number of
+// pages that way to be as many as are needed, so that FitToPages(1, 0)
+// fits the Sheet's columns onto one page.
+func (s *Sheet) FitToPages(wide, tall int) {
+	s.FitToPage = 1
+	s.PageSetUp.FitToWidth = wide
+	s.PageSetUp.FitToHeight = tall
+}
+
+// xlsxOutlinePr directly maps the outlinePr element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxOutlinePr struct {
+	ApplyStyles        bool `xml:"applyStyles,attr,omitempty"`
+	SummaryBelow       bool `xml:"summaryBelow,attr"`
+	SummaryRight       bool `xml:"summaryRight,attr"`
+	ShowOutlineSymbols bool `xml:"showOutlineSymbols,attr"`
+}
+
+// UnmarshalXML decodes an outlinePr element, giving the attributes it
+// leaves out their default values.
+func (o *xlsxOutlinePr) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type outlinePr xlsxOutlinePr
+	pr := outlinePr{SummaryBelow: true, SummaryRight: true, ShowOutlineSymbols: true}
+	if err := d.DecodeElement(&pr, &start); err != nil {
+		return err
+	}
+	*o = xlsxOutlinePr(pr)
+	return nil
+}
+
+// readOutlineSettings returns the OutlineSettings of an outlinePr
+// element, which may be missing.
+func readOutlineSettings(x *xlsxOutlinePr) OutlineSettings {
+	if x == nil {
+		return OutlineSettings{}
+	}
+	return OutlineSettings{
+		SummaryAbove:       !x.SummaryBelow,
+		SummaryLeft:        !x.SummaryRight,
+		ApplyStyles:        x.ApplyStyles,
+		HideOutlineSymbols: !x.ShowOutlineSymbols,
+	}
+}
+
+// makeOutlinePr returns the outlinePr element of the OutlineSettings,
+// or nil if they are Excel's default.
+func (o OutlineSettings) makeOutlinePr() *xlsxOutlinePr {
+	if o == (OutlineSettings{}) {
+		return nil
+	}
+	return &xlsxOutlinePr{
+		ApplyStyles:        o.ApplyStyles,
+		SummaryBelow:       !o.SummaryAbove,
+		SummaryRight:       !o.SummaryLeft,
+		ShowOutlineSymbols: !o.HideOutlineSymbols,
+	}
+}
@@ -0,0 +1,84 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type SheetPrSuite struct{}
+
+var _ = Suite(&SheetPrSuite{})
+
+func (s *SheetPrSuite) TestOutlineRoundTrip(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Report")
+	c.Assert(err, IsNil)
+	for i := 0; i < 3; i++ {
+		row := sheet.AddRow()
+		row.AddCell().SetInt(i)
+		row.OutlineLevel = 1
+	}
+	sheet.Outline = OutlineSettings{SummaryAbove: true, ApplyStyles: true}
+	plain, err := f.AddSheet("Plain")
+	c.Assert(err, IsNil)
+	plain.AddRow().AddCell().SetString("x")
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/worksheets/sheet1.xml"], Matches, `(?s).*<sheetPr filterMode="false"><outlinePr applyStyles="true" summaryBelow="false" summaryRight="true" showOutlineSymbols="true"></outlinePr><pageSetUpPr .*`)
+	c.Assert(parts["xl/worksheets/sheet2.xml"], Not(Matches), `(?s).*outlinePr.*`)
+
+	written, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	c.Assert(written.Sheets[0].Outline, Equals, sheet.Outline)
+	c.Assert(written.Sheets[1].Outline, Equals, OutlineSettings{})
+}
+
+func (s *SheetPrSuite) TestRead(c *C) {
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>` +
+			`<sheet name="A" sheetId="1" r:id="rId1"/><sheet name="B" sheetId="2" r:id="rId2"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet2.xml"/></Relationships>`,
+		// Some writers spell booleans out.
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetPr><outlinePr summaryRight="0" showOutlineSymbols="0"/><pageSetUpPr fitToPage="true"/></sheetPr><sheetData/></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetPr><pageSetUpPr fitToPage="false"/></sheetPr><sheetData/></worksheet>`,
+	}
+	f, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	c.Assert(f.Sheets[0].Outline, Equals, OutlineSettings{SummaryLeft: true, HideOutlineSymbols: true})
+	c.Assert(f.Sheets[0].FitToPage, Equals, 1)
+	c.Assert(f.Sheets[1].FitToPage, Equals, 0)
+	written, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(written["xl/worksheets/sheet1.xml"], Matches, `(?s).*<outlinePr summaryBelow="true" summaryRight="false" showOutlineSymbols="false"></outlinePr>.*`)
+
+	parts["xl/worksheets/sheet2.xml"] = `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetPr><pageSetUpPr fitToPage="yes"/></sheetPr><sheetData/></worksheet>`
+	_, err = ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, ErrorMatches, `sheet 'B': pageSetUpPr: fitToPage "yes" isn't a boolean`)
+	// A File being repaired is read without fitting the sheet.
+	f, err = readZipReader(makeZipReader(c, parts), []FileOption{Repair()})
+	c.Assert(err, IsNil)
+	c.Assert(f.Sheets[1].FitToPage, Equals, 0)
+	c.Assert(f.Warnings, DeepEquals, []string{`sheet 'B': fitToPage "yes" isn't a boolean, so the sheet isn't fitted to pages`})
+}
+
+func (s *SheetPrSuite) TestFitToPages(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Wide")
+	c.Assert(err, IsNil)
+	sheet.AddRow().AddCell().SetString("x")
+	sheet.PageSetUp.Orientation = "landscape"
+	sheet.FitToPages(2, 0)
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/worksheets/sheet1.xml"], Matches, `(?s).*<pageSetUpPr fitToPage="1"></pageSetUpPr>.*`)
+	c.Assert(parts["xl/worksheets/sheet1.xml"], Matches, `(?s).*<pageSetup [^>]*fitToWidth="2" fitToHeight="0" [^>]*orientation="landscape".*`)
+
+	written, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	c.Assert(written.Sheets[0].FitToPage, Equals, 1)
+	c.Assert(written.Sheets[0].PageSetUp.FitToWidth, Equals, 2)
+	c.Assert(written.Sheets[0].PageSetUp.FitToHeight, Equals, 0)
+}
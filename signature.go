@@ -0,0 +1,816 @@
+package xlsx
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	relTypeSignatureOrigin     = "http://schemas.openxmlformats.org/package/2006/relationships/digital-signature/origin"
+	relTypeSignature           = "http://schemas.openxmlformats.org/package/2006/relationships/digital-signature/signature"
+	contentTypeSignatureOrigin = "application/vnd.openxmlformats-package.digital-signature-origin"
+	contentTypeSignature       = "application/vnd.openxmlformats-package.digital-signature-xmlsignature+xml"
+
+	nsXMLDSig           = "http://www.w3.org/2000/09/xmldsig#"
+	nsDigitalSignature  = "http://schemas.openxmlformats.org/package/2006/digital-signature"
+	nsRelationships     = "http://schemas.openxmlformats.org/package/2006/relationships"
+	algC14N             = "http://www.w3.org/TR/2001/REC-xml-c14n-20010315"
+	algC14NComments     = "http://www.w3.org/TR/2001/REC-xml-c14n-20010315#WithComments"
+	algRelTransform     = "http://schemas.openxmlformats.org/package/2006/RelationshipTransform"
+	algSHA256           = "http://www.w3.org/2001/04/xmlenc#sha256"
+	refTypeObject       = "http://www.w3.org/2000/09/xmldsig#Object"
+	signatureOriginPart = "_xmlsignatures/origin.sigs"
+	signatureOriginRel  = "rIdSignatureOrigin"
+)
+
+// digestMethods are the hashes of the digest methods signatures are
+// checked with, by their algorithm URIs.
+var digestMethods = map[string]crypto.Hash{
+	"http://www.w3.org/2000/09/xmldsig#sha1":  crypto.SHA1,
+	algSHA256:                                 crypto.SHA256,
+	"http://www.w3.org/2001/04/xmlenc#sha512": crypto.SHA512,
+}
+
+// signatureMethod is a signature algorithm: the kind of key it signs
+// with and the hash it signs.
+type signatureMethod struct {
+	ecdsa bool
+	hash  crypto.Hash
+}
+
+// signatureMethods are the signature algorithms signatures are
+// checked with, by their URIs.
+var signatureMethods = map[string]signatureMethod{
+	"http://www.w3.org/2000/09/xmldsig#rsa-sha1":          {hash: crypto.SHA1},
+	"http://www.w3.org/2001/04/xmldsig-more#rsa-sha256":   {hash: crypto.SHA256},
+	"http://www.w3.org/2001/04/xmldsig-more#rsa-sha512":   {hash: crypto.SHA512},
+	"http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha256": {ecdsa: true, hash: crypto.SHA256},
+	"http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha512": {ecdsa: true, hash: crypto.SHA512},
+}
+
+// Signature is a digital signature of a workbook, as File.Signatures
+// lists those of a File that has been read.
+type Signature struct {
+	// Certificate is the certificate of the signer, which the
+	// signature was checked against.  Whether the certificate is to
+	// be trusted is for the caller to decide, with its Verify.
+	Certificate *x509.Certificate
+	// SigningTime is when the signer says they signed the workbook,
+	// if they do.
+	SigningTime time.Time
+	// Parts are the names of the parts the signature covers.
+	Parts []string
+	// Err says why the signature isn't valid, such as the workbook
+	// having been changed since it was signed, or is nil if it is.
+	Err error
+}
+
+// Sign signs the package as it is written with key, the private key of
+// cert, which is either an RSA or an ECDSA key, as the digital
+// signature of the workbook.  Every part is signed but
+// [Content_Types].xml, which can't be; relationships parts are signed
+// with the relationship transform, so that the signature can be added
+// to them.  Sign may be given more than once, for more than one
+// signature.  A File is never written with the signatures it was read
+// with, which wouldn't apply to the package written.
+func Sign(cert *x509.Certificate, key crypto.Signer) WriteOption {
+	return func(o *writeOptions) {
+		o.signers = append(o.signers, packageSigner{cert: cert, key: key})
+	}
+}
+
+// packageSigner is a certificate and its key, as given to Sign.
+type packageSigner struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+// signingTime returns the time a package is signed at.
+var signingTime = time.Now
+
+// packageSigning signs a package as it is written: the content of each
+// part is digested as it goes by, and the signature parts are made
+// once every other part has been written.
+type packageSigning struct {
+	signers []packageSigner
+	parts   []*signedPart
+	// contentTypes is [Content_Types].xml, as written.
+	contentTypes []byte
+}
+
+// signedPart is a part of the package being signed, as written.  Only
+// the digest of most parts is kept; relationships parts are kept in
+// full, to be transformed.
+type signedPart struct {
+	name    string
+	digest  hash.Hash
+	content *bytes.Buffer
+}
+
+// newPackageSigning returns the signing of a package by signers, or nil
+// if there are none.
+func newPackageSigning(signers []packageSigner) *packageSigning {
+	if len(signers) == 0 {
+		return nil
+	}
+	return &packageSigning{signers: signers}
+}
+
+// prepare adds the relationship to the signatures to the package's
+// relationships, and their content types to the package's.
+func (s *packageSigning) prepare(parts *packageParts) error {
+	if s == nil {
+		return nil
+	}
+	for _, signer := range s.signers {
+		if signer.cert == nil || signer.key == nil {
+			return fmt.Errorf("a certificate and its key are needed to sign a workbook")
+		}
+		switch signer.key.Public().(type) {
+		case *rsa.PublicKey, *ecdsa.PublicKey:
+		default:
+			return fmt.Errorf("signing with %T keys isn't supported", signer.key.Public())
+		}
+	}
+	rels, ok := parts.content["_rels/.rels"]
+	if !ok || !strings.Contains(rels, "</Relationships>") {
+		return fmt.Errorf("the package's relationships can't be found to sign it")
+	}
+	parts.content["_rels/.rels"] = strings.Replace(rels, "</Relationships>",
+		`<Relationship Id="`+signatureOriginRel+`" Type="`+relTypeSignatureOrigin+`" Target="`+signatureOriginPart+`"/></Relationships>`, 1)
+
+	var types xlsxTypes
+	if err := xml.Unmarshal([]byte(parts.content["[Content_Types].xml"]), &types); err != nil {
+		return err
+	}
+	types.addDefault("sigs", contentTypeSignatureOrigin)
+	for i := range s.signers {
+		types.addOverride(fmt.Sprintf("/_xmlsignatures/sig%d.xml", i+1), contentTypeSignature)
+	}
+	body, err := xml.Marshal(types)
+	if err != nil {
+		return err
+	}
+	parts.content["[Content_Types].xml"] = xmlHeader + string(body)
+	return nil
+}
+
+// tee returns the writer the part called name is to be written to, so
+// that what is written is signed as well.
+func (s *packageSigning) tee(name string, w io.Writer) io.Writer {
+	if s == nil {
+		return w
+	}
+	if name == "[Content_Types].xml" {
+		return &contentTypesWriter{w: w, s: s}
+	}
+	part := &signedPart{name: name}
+	s.parts = append(s.parts, part)
+	if strings.HasSuffix(name, ".rels") {
+		part.content = &bytes.Buffer{}
+		return io.MultiWriter(w, part.content)
+	}
+	part.digest = crypto.SHA256.New()
+	return io.MultiWriter(w, part.digest)
+}
+
+// contentTypesWriter keeps [Content_Types].xml as it is written, to
+// find the content types of the parts signed.
+type contentTypesWriter struct {
+	w io.Writer
+	s *packageSigning
+}
+
+func (c *contentTypesWriter) Write(p []byte) (int, error) {
+	c.s.contentTypes = append(c.s.contentTypes, p...)
+	return c.w.Write(p)
+}
+
+// signatureParts returns the parts that hold the signatures of the
+// parts written, by name.
+func (s *packageSigning) signatureParts() (map[string][]byte, error) {
+	var types xlsxTypes
+	if err := xml.Unmarshal(s.contentTypes, &types); err != nil {
+		return nil, err
+	}
+	manifest, err := s.manifest(types)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string][]byte{signatureOriginPart: {}}
+	rels := newPackageRelationships()
+	for i, signer := range s.signers {
+		name := fmt.Sprintf("_xmlsignatures/sig%d.xml", i+1)
+		rels.of(signatureOriginPart).add(relTypeSignature, name)
+		signature, err := signer.sign(manifest)
+		if err != nil {
+			return nil, err
+		}
+		result[name] = signature
+	}
+	relsParts := make(map[string]string)
+	if err := rels.write(relsParts); err != nil {
+		return nil, err
+	}
+	for name, content := range relsParts {
+		result[name] = []byte(content)
+	}
+	return result, nil
+}
+
+// manifest returns the references to the parts written, in canonical
+// form, for the Manifest of each signature.
+func (s *packageSigning) manifest(types xlsxTypes) (string, error) {
+	var b strings.Builder
+	for _, part := range s.parts {
+		b.WriteString(`<Reference URI="`)
+		b.WriteString(canonicalAttr("/" + part.name + "?ContentType=" + types.contentType(part.name)))
+		b.WriteString(`">`)
+		digest := part.sum()
+		if part.content != nil {
+			var ids []string
+			for _, rel := range readRelationshipList(part.content.Bytes()) {
+				if rel.Type != relTypeSignatureOrigin {
+					ids = append(ids, rel.Id)
+				}
+			}
+			sort.Strings(ids)
+			b.WriteString(`<Transforms><Transform Algorithm="` + algRelTransform + `">`)
+			for _, id := range ids {
+				b.WriteString(`<mdssi:RelationshipReference xmlns:mdssi="` + nsDigitalSignature + `" SourceId="` + canonicalAttr(id) + `"></mdssi:RelationshipReference>`)
+			}
+			b.WriteString(`</Transform><Transform Algorithm="` + algC14N + `"></Transform></Transforms>`)
+			h := crypto.SHA256.New()
+			h.Write(relationshipTransform(part.content.Bytes(), ids, nil))
+			digest = h.Sum(nil)
+		}
+		b.WriteString(`<DigestMethod Algorithm="` + algSHA256 + `"></DigestMethod><DigestValue>`)
+		b.WriteString(base64.StdEncoding.EncodeToString(digest))
+		b.WriteString(`</DigestValue></Reference>`)
+	}
+	return b.String(), nil
+}
+
+// sum returns the SHA-256 digest of the part as it was written.
+func (p *signedPart) sum() []byte {
+	if p.digest != nil {
+		return p.digest.Sum(nil)
+	}
+	return nil
+}
+
+// sign returns the signature part signing the parts the manifest
+// refers to.
+func (p packageSigner) sign(manifest string) ([]byte, error) {
+	method := "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	if _, ok := p.key.Public().(*ecdsa.PublicKey); ok {
+		method = "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha256"
+	}
+	object := `<Object xmlns="` + nsXMLDSig + `" Id="idPackageObject"><Manifest>` + manifest +
+		`</Manifest><SignatureProperties><SignatureProperty Id="idSignatureTime" Target="#idPackageSignature">` +
+		`<mdssi:SignatureTime xmlns:mdssi="` + nsDigitalSignature + `"><mdssi:Format>YYYY-MM-DDThh:mm:ssTZD</mdssi:Format>` +
+		`<mdssi:Value>` + signingTime().UTC().Format("2006-01-02T15:04:05Z") + `</mdssi:Value></mdssi:SignatureTime>` +
+		`</SignatureProperty></SignatureProperties></Object>`
+	objectDigest := crypto.SHA256.New()
+	objectDigest.Write([]byte(object))
+	signedInfo := `<SignedInfo xmlns="` + nsXMLDSig + `"><CanonicalizationMethod Algorithm="` + algC14N + `"></CanonicalizationMethod>` +
+		`<SignatureMethod Algorithm="` + method + `"></SignatureMethod>` +
+		`<Reference Type="` + refTypeObject + `" URI="#idPackageObject"><DigestMethod Algorithm="` + algSHA256 + `"></DigestMethod>` +
+		`<DigestValue>` + base64.StdEncoding.EncodeToString(objectDigest.Sum(nil)) + `</DigestValue></Reference></SignedInfo>`
+	signedDigest := crypto.SHA256.New()
+	signedDigest.Write([]byte(signedInfo))
+	value, err := p.key.Sign(rand.Reader, signedDigest.Sum(nil), crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	if pub, ok := p.key.Public().(*ecdsa.PublicKey); ok {
+		// XML signatures give the two halves of an ECDSA
+		// signature one after the other, rather than in DER.
+		var rs struct{ R, S *big.Int }
+		if _, err := asn1.Unmarshal(value, &rs); err != nil {
+			return nil, err
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		value = make([]byte, 2*size)
+		rs.R.FillBytes(value[:size])
+		rs.S.FillBytes(value[size:])
+	}
+	return []byte(xmlHeader + `<Signature xmlns="` + nsXMLDSig + `" Id="idPackageSignature">` + signedInfo +
+		`<SignatureValue>` + base64.StdEncoding.EncodeToString(value) + `</SignatureValue>` +
+		`<KeyInfo><X509Data><X509Certificate>` + base64.StdEncoding.EncodeToString(p.cert.Raw) +
+		`</X509Certificate></X509Data></KeyInfo>` + object + `</Signature>`), nil
+}
+
+// readRelationshipList returns the relationships in a relationships
+// part, or none if it can't be read.
+func readRelationshipList(data []byte) []xlsxWorksheetRelationship {
+	var xRels struct {
+		Relationships []xlsxWorksheetRelationship `xml:"Relationship"`
+	}
+	xml.Unmarshal(data, &xRels)
+	return xRels.Relationships
+}
+
+// relationshipTransform returns the relationships part, in data, as
+// the relationship transform of OPC digital signatures turns it into:
+// the relationships with the given ids, or of the given types, in
+// order of their ids and in canonical form.
+func relationshipTransform(data []byte, ids, types []string) []byte {
+	selected := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		selected["id:"+id] = true
+	}
+	for _, relType := range types {
+		selected["type:"+relType] = true
+	}
+	var rels []xlsxWorksheetRelationship
+	for _, rel := range readRelationshipList(data) {
+		if selected["id:"+rel.Id] || selected["type:"+rel.Type] {
+			rels = append(rels, rel)
+		}
+	}
+	sort.Slice(rels, func(i, j int) bool { return rels[i].Id < rels[j].Id })
+	var b bytes.Buffer
+	b.WriteString(`<Relationships xmlns="` + nsRelationships + `">`)
+	for _, rel := range rels {
+		if rel.TargetMode == "" {
+			rel.TargetMode = "Internal"
+		}
+		fmt.Fprintf(&b, `<Relationship Id="%s" Target="%s" TargetMode="%s" Type="%s"></Relationship>`,
+			canonicalAttr(rel.Id), canonicalAttr(rel.Target), canonicalAttr(rel.TargetMode), canonicalAttr(rel.Type))
+	}
+	b.WriteString(`</Relationships>`)
+	return b.Bytes()
+}
+
+// readSignatures checks the digital signatures of the package the File
+// was read from, listing them in the File's Signatures.  Problems with
+// a signature make it invalid, rather than the File unreadable.
+func (f *File) readSignatures() {
+	rels, err := f.readRelationships("")
+	if err != nil {
+		return
+	}
+	for _, rel := range rels {
+		if rel.Type != relTypeSignatureOrigin {
+			continue
+		}
+		sigRels, err := f.readRelationships(rel.Target)
+		if err != nil {
+			f.Signatures = append(f.Signatures, Signature{Err: err})
+			continue
+		}
+		var names []string
+		for _, sigRel := range sigRels {
+			if sigRel.Type == relTypeSignature {
+				names = append(names, sigRel.Target)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			f.Signatures = append(f.Signatures, f.verifySignature(name))
+		}
+	}
+}
+
+// verifySignature checks the signature in the part called name.
+func (f *File) verifySignature(name string) Signature {
+	var sig Signature
+	part, ok := f.parts[name]
+	if !ok {
+		sig.Err = fmt.Errorf("signature part %s doesn't exist", name)
+		return sig
+	}
+	data, err := f.readBinaryPart(part)
+	if err != nil {
+		sig.Err = err
+		return sig
+	}
+	root, err := parseXMLTree(data)
+	if err != nil {
+		sig.Err = fmt.Errorf("signature %s: %s", name, err)
+		return sig
+	}
+	sig.Err = f.checkSignature(root, &sig)
+	return sig
+}
+
+// checkSignature checks the signature whose XML is root, filling in
+// what it says of sig, and returns why it isn't valid, or nil.
+func (f *File) checkSignature(root *xmlNode, sig *Signature) error {
+	if root.name.Local != "Signature" {
+		return fmt.Errorf("signature part holds %s, not a signature", root.name.Local)
+	}
+	if certificate := root.find("X509Certificate"); certificate != nil {
+		der, err := base64.StdEncoding.DecodeString(strings.TrimSpace(certificate.textContent()))
+		if err != nil {
+			return fmt.Errorf("the certificate can't be read: %s", err)
+		}
+		if sig.Certificate, err = x509.ParseCertificate(der); err != nil {
+			return fmt.Errorf("the certificate can't be read: %s", err)
+		}
+	} else {
+		return fmt.Errorf("the signature has no certificate")
+	}
+	for _, value := range root.findAll("Value") {
+		if value.parent != nil && value.parent.name.Local == "SignatureTime" {
+			sig.SigningTime, _ = time.Parse(time.RFC3339, strings.TrimSpace(value.textContent()))
+		}
+	}
+
+	signedInfo := root.find("SignedInfo")
+	if signedInfo == nil {
+		return fmt.Errorf("the signature has no SignedInfo")
+	}
+	if c14n := signedInfo.find("CanonicalizationMethod"); c14n == nil || (c14n.attr("Algorithm") != algC14N && c14n.attr("Algorithm") != algC14NComments) {
+		return fmt.Errorf("the signature's canonicalization method isn't supported")
+	}
+	// Check what is signed before the signature, so that a changed
+	// part is reported as such.
+	visited := make(map[string]bool)
+	for _, ref := range signedInfo.children {
+		if ref.name.Local == "Reference" {
+			if err := f.checkReference(root, ref, sig, visited, true); err != nil {
+				return err
+			}
+		}
+	}
+	methodNode := signedInfo.find("SignatureMethod")
+	if methodNode == nil {
+		return fmt.Errorf("the signature has no SignatureMethod")
+	}
+	method, ok := signatureMethods[methodNode.attr("Algorithm")]
+	if !ok {
+		return fmt.Errorf("signature method %s isn't supported", methodNode.attr("Algorithm"))
+	}
+	valueNode := root.find("SignatureValue")
+	if valueNode == nil {
+		return fmt.Errorf("the signature has no SignatureValue")
+	}
+	value, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(valueNode.textContent()), ""))
+	if err != nil {
+		return fmt.Errorf("the signature value can't be read: %s", err)
+	}
+	h := method.hash.New()
+	h.Write(canonicalize(signedInfo))
+	digest := h.Sum(nil)
+	switch pub := sig.Certificate.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if !method.ecdsa && rsa.VerifyPKCS1v15(pub, method.hash, digest, value) == nil {
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		half := len(value) / 2
+		r, s := new(big.Int).SetBytes(value[:half]), new(big.Int).SetBytes(value[half:])
+		if method.ecdsa && ecdsa.Verify(pub, digest, r, s) {
+			return nil
+		}
+	}
+	return fmt.Errorf("the signature doesn't match the certificate")
+}
+
+// checkReference checks that what the Reference element ref refers to,
+// either an element of the signature or a part of the package, has the
+// digest it gives.  Parts of the package are added to sig.Parts.  The
+// references of the manifest of an element are checked too if follow
+// is set, as it is for those of SignedInfo, but not theirs in turn.
+// visited holds the ids of the elements already referred to, so that a
+// signature referring to an element twice, as one referring back to
+// itself does, is refused.
+func (f *File) checkReference(root, ref *xmlNode, sig *Signature, visited map[string]bool, follow bool) error {
+	uri := ref.attr("URI")
+	what := strings.TrimPrefix(uri, "#")
+	var content []byte
+	switch {
+	case strings.HasPrefix(uri, "#"):
+		if visited[uri[1:]] {
+			return fmt.Errorf("the signature refers to %s more than once", uri)
+		}
+		visited[uri[1:]] = true
+		target := root.findID(uri[1:])
+		if target == nil {
+			return fmt.Errorf("the signature refers to %s, which it doesn't have", uri)
+		}
+		content = canonicalize(target)
+		if !follow {
+			break
+		}
+		// The manifest of an object refers to the parts signed.
+		for _, manifest := range target.findAll("Manifest") {
+			for _, partRef := range manifest.children {
+				if partRef.name.Local == "Reference" {
+					if err := f.checkReference(root, partRef, sig, visited, false); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	default:
+		name := uri
+		if i := strings.IndexByte(name, '?'); i >= 0 {
+			name = name[:i]
+		}
+		name = normalizePartName(name)
+		what = name
+		part, ok := f.parts[name]
+		if !ok {
+			return fmt.Errorf("part %s, which is signed, doesn't exist", name)
+		}
+		data, err := f.readBinaryPart(part)
+		if err != nil {
+			return err
+		}
+		content = data
+		if transforms := ref.find("Transforms"); transforms != nil {
+			for _, transform := range transforms.children {
+				switch transform.attr("Algorithm") {
+				case "":
+				case algRelTransform:
+					var ids, types []string
+					for _, sel := range transform.children {
+						switch sel.name.Local {
+						case "RelationshipReference":
+							ids = append(ids, sel.attr("SourceId"))
+						case "RelationshipsGroupReference":
+							types = append(types, sel.attr("SourceType"))
+						}
+					}
+					content = relationshipTransform(content, ids, types)
+				case algC14N, algC14NComments:
+					// The relationship transform's output
+					// is already canonical.
+				default:
+					return fmt.Errorf("transform %s of part %s isn't supported", transform.attr("Algorithm"), name)
+				}
+			}
+		}
+		sig.Parts = append(sig.Parts, name)
+	}
+	methodNode := ref.find("DigestMethod")
+	valueNode := ref.find("DigestValue")
+	if methodNode == nil || valueNode == nil {
+		return fmt.Errorf("reference to %s has no digest", uri)
+	}
+	method, ok := digestMethods[methodNode.attr("Algorithm")]
+	if !ok {
+		return fmt.Errorf("digest method %s isn't supported", methodNode.attr("Algorithm"))
+	}
+	h := method.New()
+	h.Write(content)
+	if base64.StdEncoding.EncodeToString(h.Sum(nil)) != strings.TrimSpace(valueNode.textContent()) {
+		return fmt.Errorf("%s has changed since it was signed", what)
+	}
+	return nil
+}
+
+// xmlNode is an element, or text, of an XML document, with the
+// prefixes of its names as they were written, as canonicalizing XML
+// needs.
+type xmlNode struct {
+	// name is the element's name, with its prefix as Space.
+	name     xml.Name
+	attrs    []xml.Attr
+	children []*xmlNode
+	// text is the content of a text node, which has no name.
+	text   string
+	parent *xmlNode
+}
+
+// parseXMLTree parses an XML document into its root element.
+func parseXMLTree(data []byte) (*xmlNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var root, current *xmlNode
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: t.Name, attrs: append([]xml.Attr(nil), t.Attr...), parent: current}
+			if current != nil {
+				current.children = append(current.children, node)
+			} else if root == nil {
+				root = node
+			}
+			current = node
+		case xml.EndElement:
+			if current != nil {
+				current = current.parent
+			}
+		case xml.CharData:
+			if current != nil {
+				current.children = append(current.children, &xmlNode{text: string(t), parent: current})
+			}
+		}
+	}
+	if root == nil {
+		return nil, fmt.Errorf("no root element")
+	}
+	return root, nil
+}
+
+// attr returns the value of the node's attribute called local, or "".
+func (n *xmlNode) attr(local string) string {
+	for _, a := range n.attrs {
+		if a.Name.Local == local && a.Name.Space != "xmlns" {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// find returns the first element within the node called local, or nil.
+func (n *xmlNode) find(local string) *xmlNode {
+	for _, child := range n.children {
+		if child.name.Local == local {
+			return child
+		}
+		if found := child.find(local); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// findAll returns every element within the node called local.
+func (n *xmlNode) findAll(local string) []*xmlNode {
+	var found []*xmlNode
+	for _, child := range n.children {
+		if child.name.Local == local {
+			found = append(found, child)
+		}
+		found = append(found, child.findAll(local)...)
+	}
+	return found
+}
+
+// findID returns the element within the node, or the node itself,
+// whose Id attribute is id, or nil.
+func (n *xmlNode) findID(id string) *xmlNode {
+	if n.name.Local != "" && n.attr("Id") == id {
+		return n
+	}
+	for _, child := range n.children {
+		if found := child.findID(id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// textContent returns the text within the node.
+func (n *xmlNode) textContent() string {
+	if n.name.Local == "" {
+		return n.text
+	}
+	var b strings.Builder
+	for _, child := range n.children {
+		b.WriteString(child.textContent())
+	}
+	return b.String()
+}
+
+// namespaces returns the namespaces the node declares, by prefix, the
+// default namespace having the prefix "".
+func (n *xmlNode) namespaces() map[string]string {
+	declared := make(map[string]string)
+	for _, a := range n.attrs {
+		switch {
+		case a.Name.Space == "xmlns":
+			declared[a.Name.Local] = a.Value
+		case a.Name.Space == "" && a.Name.Local == "xmlns":
+			declared[""] = a.Value
+		}
+	}
+	return declared
+}
+
+// canonicalize returns the element n in the canonical form of
+// Canonical XML 1.0, without comments, as the subset of its document
+// that signatures refer to: with every namespace in scope declared.
+func canonicalize(n *xmlNode) []byte {
+	var ancestors []*xmlNode
+	for a := n.parent; a != nil; a = a.parent {
+		ancestors = append(ancestors, a)
+	}
+	scope := make(map[string]string)
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		for prefix, uri := range ancestors[i].namespaces() {
+			scope[prefix] = uri
+		}
+	}
+	var b bytes.Buffer
+	writeCanonical(&b, n, scope, map[string]string{})
+	return b.Bytes()
+}
+
+// writeCanonical writes the node in canonical form, scope holding the
+// namespaces in scope at its parent and rendered those declared by the
+// elements written around it.
+func writeCanonical(b *bytes.Buffer, n *xmlNode, scope, rendered map[string]string) {
+	if n.name.Local == "" {
+		b.WriteString(canonicalText(n.text))
+		return
+	}
+	inScope := make(map[string]string, len(scope))
+	for prefix, uri := range scope {
+		inScope[prefix] = uri
+	}
+	for prefix, uri := range n.namespaces() {
+		inScope[prefix] = uri
+	}
+	var prefixes []string
+	for prefix, uri := range inScope {
+		if prefix == "xml" {
+			continue
+		}
+		if rendered[prefix] != uri {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	sort.Strings(prefixes)
+	childRendered := make(map[string]string, len(rendered)+len(prefixes))
+	for prefix, uri := range rendered {
+		childRendered[prefix] = uri
+	}
+
+	qname := n.name.Local
+	if n.name.Space != "" {
+		qname = n.name.Space + ":" + qname
+	}
+	b.WriteString("<" + qname)
+	for _, prefix := range prefixes {
+		childRendered[prefix] = inScope[prefix]
+		if prefix == "" {
+			b.WriteString(` xmlns="` + canonicalAttr(inScope[prefix]) + `"`)
+		} else {
+			b.WriteString(` xmlns:` + prefix + `="` + canonicalAttr(inScope[prefix]) + `"`)
+		}
+	}
+	type attr struct{ uri, local, qname, value string }
+	var attrs []attr
+	for _, a := range n.attrs {
+		if a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns") {
+			continue
+		}
+		at := attr{local: a.Name.Local, qname: a.Name.Local, value: a.Value}
+		if a.Name.Space != "" {
+			at.qname = a.Name.Space + ":" + a.Name.Local
+			at.uri = inScope[a.Name.Space]
+			if a.Name.Space == "xml" {
+				at.uri = "http://www.w3.org/XML/1998/namespace"
+			}
+		}
+		attrs = append(attrs, at)
+	}
+	sort.Slice(attrs, func(i, j int) bool {
+		if attrs[i].uri != attrs[j].uri {
+			return attrs[i].uri < attrs[j].uri
+		}
+		return attrs[i].local < attrs[j].local
+	})
+	for _, a := range attrs {
+		b.WriteString(" " + a.qname + `="` + canonicalAttr(a.value) + `"`)
+	}
+	b.WriteString(">")
+	for _, child := range n.children {
+		writeCanonical(b, child, inScope, childRendered)
+	}
+	b.WriteString("</" + qname + ">")
+}
+
+var (
+	canonicalTextReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+	canonicalAttrReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+)
+
+// canonicalText escapes text as Canonical XML does.
+func canonicalText(s string) string {
+	return canonicalTextReplacer.Replace(s)
+}
+
+// canonicalAttr escapes an attribute value as Canonical XML does.
+func canonicalAttr(s string) string {
+	return canonicalAttrReplacer.Replace(s)
+}
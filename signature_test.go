@@ -0,0 +1,211 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SignatureSuite struct{}
+
+var _ = Suite(&SignatureSuite{})
+
+// signingCertificate returns a self-signed certificate for key.
+func signingCertificate(c *C, key crypto.Signer) *x509.Certificate {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Reports"},
+		NotBefore:    time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2040, 1, 1, 0, 0, 0, 0, time.UTC),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	c.Assert(err, IsNil)
+	cert, err := x509.ParseCertificate(der)
+	c.Assert(err, IsNil)
+	return cert
+}
+
+func signedWorkbook(c *C, options ...WriteOption) []byte {
+	f := NewFile()
+	sheet, err := f.AddSheet("Report")
+	c.Assert(err, IsNil)
+	sheet.Cell(0, 0).SetString("Total")
+	sheet.Cell(0, 1).SetFloat(1234.5)
+	var b bytes.Buffer
+	c.Assert(f.Write(&b, options...), IsNil)
+	return b.Bytes()
+}
+
+func (s *SignatureSuite) TestSignAndVerify(c *C) {
+	defer func() { signingTime = time.Now }()
+	signingTime = func() time.Time { return time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC) }
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	c.Assert(err, IsNil)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, IsNil)
+	rsaCert, ecCert := signingCertificate(c, rsaKey), signingCertificate(c, ecKey)
+
+	data := signedWorkbook(c, Sign(rsaCert, rsaKey), Sign(ecCert, ecKey))
+	f, err := OpenBinary(data)
+	c.Assert(err, IsNil)
+	c.Assert(f.Signatures, HasLen, 2)
+	for i, cert := range []*x509.Certificate{rsaCert, ecCert} {
+		sig := f.Signatures[i]
+		c.Assert(sig.Err, IsNil)
+		c.Assert(sig.Certificate.Equal(cert), Equals, true)
+		c.Assert(sig.SigningTime.Equal(signingTime()), Equals, true)
+		c.Assert(sig.Parts, Not(HasLen), 0)
+		c.Assert(strings.Join(sig.Parts, " "), Matches, `.*_rels/\.rels.*xl/workbook\.xml.*xl/worksheets/sheet1\.xml.*`)
+	}
+	c.Assert(f.Sheets[0].Cell(0, 1).Value, Equals, "1234.5")
+
+	// The signature of what was read isn't written again.
+	var b bytes.Buffer
+	c.Assert(f.Write(&b), IsNil)
+	f, err = OpenBinary(b.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(f.Signatures, HasLen, 0)
+}
+
+// rezip returns the zip archive in data with the content of the part
+// called name changed by change.
+func rezip(c *C, data []byte, name string, change func([]byte) []byte) []byte {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	c.Assert(err, IsNil)
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	for _, file := range r.File {
+		rc, err := file.Open()
+		c.Assert(err, IsNil)
+		content, err := ioutil.ReadAll(rc)
+		c.Assert(err, IsNil)
+		rc.Close()
+		if file.Name == name {
+			content = change(content)
+		}
+		fw, err := w.Create(file.Name)
+		c.Assert(err, IsNil)
+		_, err = fw.Write(content)
+		c.Assert(err, IsNil)
+	}
+	c.Assert(w.Close(), IsNil)
+	return b.Bytes()
+}
+
+func (s *SignatureSuite) TestVerifyChangedWorkbook(c *C) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	c.Assert(err, IsNil)
+	cert := signingCertificate(c, key)
+
+	f := NewFile()
+	sheet, err := f.AddSheet("Report")
+	c.Assert(err, IsNil)
+	sheet.Cell(0, 0).SetFloat(100)
+	// Parts patched by hooks are signed as they are written.
+	f.OnPart(func(name string, content []byte) []byte {
+		return bytes.Replace(content, []byte("<v>100</v>"), []byte("<v>200</v>"), 1)
+	})
+	var b bytes.Buffer
+	c.Assert(f.Write(&b, Sign(cert, key)), IsNil)
+	read, err := OpenBinary(b.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(read.Signatures, HasLen, 1)
+	c.Assert(read.Signatures[0].Err, IsNil)
+	c.Assert(read.Sheets[0].Cell(0, 0).Value, Equals, "200")
+
+	changed := rezip(c, b.Bytes(), "xl/worksheets/sheet1.xml", func(content []byte) []byte {
+		return bytes.Replace(content, []byte("<v>200</v>"), []byte("<v>900</v>"), 1)
+	})
+	read, err = OpenBinary(changed)
+	c.Assert(err, IsNil)
+	c.Assert(read.Signatures, HasLen, 1)
+	c.Assert(read.Signatures[0].Err, ErrorMatches, "xl/worksheets/sheet1.xml has changed since it was signed")
+
+	// Redirecting a relationship breaks the signature too.
+	changed = rezip(c, b.Bytes(), "xl/_rels/workbook.xml.rels", func(content []byte) []byte {
+		return bytes.Replace(content, []byte(`"worksheets/sheet1.xml"`), []byte(`"../xl/worksheets/sheet1.xml"`), 1)
+	})
+	read, err = OpenBinary(changed)
+	c.Assert(err, IsNil)
+	c.Assert(read.Signatures[0].Err, ErrorMatches, "xl/_rels/workbook.xml.rels has changed since it was signed")
+
+	// So does changing the signature's certificate.
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	c.Assert(err, IsNil)
+	otherCert := signingCertificate(c, other)
+	changed = rezip(c, b.Bytes(), "_xmlsignatures/sig1.xml", func(content []byte) []byte {
+		return bytes.Replace(content, []byte(base64.StdEncoding.EncodeToString(cert.Raw)), []byte(base64.StdEncoding.EncodeToString(otherCert.Raw)), 1)
+	})
+	read, err = OpenBinary(changed)
+	c.Assert(err, IsNil)
+	c.Assert(read.Signatures[0].Err, ErrorMatches, "the signature doesn't match the certificate")
+}
+
+func (s *SignatureSuite) TestVerifyReferenceCycle(c *C) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	c.Assert(err, IsNil)
+	data := signedWorkbook(c, Sign(signingCertificate(c, key), key))
+
+	// A manifest referring to the object it belongs to is refused,
+	// rather than followed for ever.
+	cyclic := rezip(c, data, "_xmlsignatures/sig1.xml", func(content []byte) []byte {
+		return bytes.Replace(content, []byte("<Manifest>"), []byte(`<Manifest><Reference URI="#idPackageObject"></Reference>`), 1)
+	})
+	read, err := OpenBinary(cyclic)
+	c.Assert(err, IsNil)
+	c.Assert(read.Signatures, HasLen, 1)
+	c.Assert(read.Signatures[0].Err, ErrorMatches, "the signature refers to #idPackageObject more than once")
+
+	// The references of a manifest aren't followed any further.
+	nested := rezip(c, data, "_xmlsignatures/sig1.xml", func(content []byte) []byte {
+		content = bytes.Replace(content, []byte("<Manifest>"), []byte(`<Manifest><Reference URI="#idInner"></Reference>`), 1)
+		return bytes.Replace(content, []byte("</Object>"), []byte(`<Manifest Id="idInner"><Reference URI="#idInner"></Reference></Manifest></Object>`), 1)
+	})
+	read, err = OpenBinary(nested)
+	c.Assert(err, IsNil)
+	c.Assert(read.Signatures[0].Err, ErrorMatches, "reference to #idInner has no digest")
+}
+
+func (s *SignatureSuite) TestRelationshipTransform(c *C) {
+	rels := `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Id="rId2" Type="t2" Target="b.xml"/>
+  <Relationship Id="rId1" Type="t1" Target="a.xml"/>
+  <Relationship Id="rId3" Type="t3" Target="http://example.com/?a&amp;b" TargetMode="External"/>
+</Relationships>`
+	c.Assert(string(relationshipTransform([]byte(rels), []string{"rId3", "rId1"}, nil)), Equals,
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`+
+			`<Relationship Id="rId1" Target="a.xml" TargetMode="Internal" Type="t1"></Relationship>`+
+			`<Relationship Id="rId3" Target="http://example.com/?a&amp;b" TargetMode="External" Type="t3"></Relationship>`+
+			`</Relationships>`)
+	c.Assert(string(relationshipTransform([]byte(rels), nil, []string{"t2"})), Equals,
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`+
+			`<Relationship Id="rId2" Target="b.xml" TargetMode="Internal" Type="t2"></Relationship>`+
+			`</Relationships>`)
+}
+
+func (s *SignatureSuite) TestCanonicalize(c *C) {
+	doc := `<?xml version="1.0"?>
+<a:root xmlns:a="urn:a" xmlns="urn:d"><a:e2 z="1" a:y="2" b="&quot;3&#x9;"/><e3 xmlns:b="urn:b" xmlns="urn:d"><!-- comment -->x &gt; y &amp; z</e3><e4 xmlns=""/></a:root>`
+	root, err := parseXMLTree([]byte(doc))
+	c.Assert(err, IsNil)
+	c.Assert(string(canonicalize(root)), Equals,
+		`<a:root xmlns="urn:d" xmlns:a="urn:a"><a:e2 b="&quot;3&#x9;" z="1" a:y="2"></a:e2><e3 xmlns:b="urn:b">x &gt; y &amp; z</e3><e4 xmlns=""></e4></a:root>`)
+	// A subset declares the namespaces of its ancestors.
+	c.Assert(string(canonicalize(root.children[0])), Equals,
+		`<a:e2 xmlns="urn:d" xmlns:a="urn:a" b="&quot;3&#x9;" z="1" a:y="2"></a:e2>`)
+}
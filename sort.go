@@ -0,0 +1,171 @@
+package xlsx
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SortType determines how the values of a SortKey's column are
+// compared.
+type SortType int
+
+const (
+	// SortAuto compares numbers and dates numerically and text
+	// using the key's Collator, with numbers sorting first.
+	SortAuto SortType = iota
+	// SortNumeric compares the values as numbers.  Values that
+	// aren't numbers sort after those that are.
+	SortNumeric
+	// SortString compares the displayed values as text, even when
+	// they are numbers.
+	SortString
+)
+
+// SortKey is one of the columns to sort a range by.
+type SortKey struct {
+	// Col is the zero based index of the column in the sheet.
+	Col        int
+	Descending bool
+	Type       SortType
+	// Collator compares text.  If it is nil the language neutral
+	// Collator is used.
+	Collator *Collator
+}
+
+// SortRange sorts the rows of the range ref, such as "A2:D20", by the
+// given keys, the first key deciding the order and each later key the
+// order of rows the keys before it consider equal.  Rows that all the
+// keys consider equal keep their order.  Empty cells sort last in
+// either direction.
+//
+// Only the cells within the range move, taking their styles with
+// them.  When the range covers every cell of its rows the rows
+// themselves are moved, so that their heights move as well.
+func (s *Sheet) SortRange(ref string, keys []SortKey) error {
+	if err := s.Load(); err != nil {
+		return err
+	}
+	r, err := ParseCellRange(ref)
+	if err != nil {
+		return err
+	}
+	if r.Sheet != "" && r.Sheet != s.Name {
+		return fmt.Errorf("range '%s' is not on sheet '%s'", ref, s.Name)
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no sort keys given for range '%s'", ref)
+	}
+	for _, key := range keys {
+		if key.Col < r.Start.Col || key.Col > r.End.Col {
+			return fmt.Errorf("sort column %s is outside the range '%s'", ColIndexToLetters(key.Col), ref)
+		}
+	}
+	if r.Start.Row >= len(s.Rows) {
+		return nil
+	}
+	if r.End.Row >= len(s.Rows) {
+		r.End.Row = len(s.Rows) - 1
+	}
+
+	rows := s.Rows[r.Start.Row : r.End.Row+1]
+	wholeRows := r.Start.Col == 0
+	for _, row := range rows {
+		if row != nil && len(row.Cells) > r.End.Col+1 {
+			wholeRows = false
+		}
+	}
+	less := func(a, b *Row) bool {
+		for _, key := range keys {
+			if result := key.compare(rowCell(a, key.Col), rowCell(b, key.Col)); result != 0 {
+				return result < 0
+			}
+		}
+		return false
+	}
+
+	if wholeRows {
+		sort.SliceStable(rows, func(i, j int) bool {
+			return less(rows[i], rows[j])
+		})
+		return nil
+	}
+
+	// Sort the slices of the rows that lie within the range, then put
+	// the cells back into the rows in their new order.
+	parts := make([]*Row, len(rows))
+	for i := range rows {
+		for c := r.Start.Col; c <= r.End.Col; c++ {
+			s.Cell(r.Start.Row+i, c)
+		}
+		row := s.Rows[r.Start.Row+i]
+		part := &Row{Cells: make([]*Cell, r.End.Col+1)}
+		copy(part.Cells[r.Start.Col:], row.Cells[r.Start.Col:r.End.Col+1])
+		parts[i] = part
+	}
+	sort.SliceStable(parts, func(i, j int) bool {
+		return less(parts[i], parts[j])
+	})
+	for i, part := range parts {
+		row := s.Rows[r.Start.Row+i]
+		for c := r.Start.Col; c <= r.End.Col; c++ {
+			cell := part.Cells[c]
+			cell.Row = row
+			row.Cells[c] = cell
+		}
+	}
+	return nil
+}
+
+// compare compares two cells according to the key, with empty cells
+// always sorting last.
+func (key SortKey) compare(a, b *Cell) int {
+	aEmpty, bEmpty := a == nil || a.Value == "", b == nil || b.Value == ""
+	switch {
+	case aEmpty && bEmpty:
+		return 0
+	case aEmpty:
+		return 1
+	case bEmpty:
+		return -1
+	}
+	collator := key.Collator
+	if collator == nil {
+		collator = &Collator{}
+	}
+	var result int
+	switch key.Type {
+	case SortNumeric:
+		fa, aErr := strconv.ParseFloat(strings.TrimSpace(a.Value), 64)
+		fb, bErr := strconv.ParseFloat(strings.TrimSpace(b.Value), 64)
+		switch {
+		case aErr == nil && bErr == nil:
+			result = compareFloats(fa, fb)
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			result = collator.Compare(cellText(a), cellText(b))
+		}
+	case SortString:
+		result = collator.Compare(cellText(a), cellText(b))
+	default:
+		result = collator.CompareCells(a, b)
+	}
+	if key.Descending {
+		return -result
+	}
+	return result
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
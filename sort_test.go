@@ -0,0 +1,91 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type SortSuite struct{}
+
+var _ = Suite(&SortSuite{})
+
+func makeSortSheet() *Sheet {
+	file := NewFile()
+	sheet, _ := file.AddSheet("People")
+	for _, values := range [][]string{
+		{"Name", "Team", "Score"},
+		{"Cy", "Blue", "7"},
+		{"Ann", "Red", "10"},
+		{"Bo", "Blue", "9"},
+		{"Di", "Red", ""},
+		{"Ed", "Blue", "10"},
+	} {
+		row := sheet.AddRow()
+		for _, v := range values {
+			row.AddCell().SetString(v)
+		}
+	}
+	return sheet
+}
+
+func sortSheetColumn(sheet *Sheet, col int) []string {
+	var values []string
+	for _, row := range sheet.Rows {
+		values = append(values, row.Cells[col].Value)
+	}
+	return values
+}
+
+func (s *SortSuite) TestSortRangeMultipleKeys(c *C) {
+	sheet := makeSortSheet()
+	sheet.Cell(1, 0).GetStyle().Font.Bold = true
+	sheet.Rows[1].SetHeightCM(2)
+	err := sheet.SortRange("A2:C6", []SortKey{
+		{Col: 1},
+		{Col: 2, Descending: true, Type: SortNumeric},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(sortSheetColumn(sheet, 0), DeepEquals, []string{"Name", "Ed", "Bo", "Cy", "Ann", "Di"})
+
+	// The whole rows moved, with their styles and heights.
+	c.Assert(sheet.Cell(3, 0).GetStyle().Font.Bold, Equals, true)
+	c.Assert(sheet.Rows[3].isCustom, Equals, true)
+	for _, row := range sheet.Rows {
+		for _, cell := range row.Cells {
+			c.Assert(cell.Row, Equals, row)
+		}
+	}
+}
+
+func (s *SortSuite) TestSortRangeNumericVsString(c *C) {
+	sheet := makeSortSheet()
+	c.Assert(sheet.SortRange("A2:C6", []SortKey{{Col: 2, Type: SortString}}), IsNil)
+	c.Assert(sortSheetColumn(sheet, 2), DeepEquals, []string{"Score", "10", "10", "7", "9", ""})
+
+	c.Assert(sheet.SortRange("A2:C6", []SortKey{{Col: 2, Type: SortNumeric}}), IsNil)
+	c.Assert(sortSheetColumn(sheet, 2), DeepEquals, []string{"Score", "7", "9", "10", "10", ""})
+
+	// Empty cells stay last when descending.
+	c.Assert(sheet.SortRange("A2:C6", []SortKey{{Col: 2, Type: SortNumeric, Descending: true}}), IsNil)
+	c.Assert(sortSheetColumn(sheet, 0), DeepEquals, []string{"Name", "Ann", "Ed", "Bo", "Cy", "Di"})
+}
+
+func (s *SortSuite) TestSortRangePartialRows(c *C) {
+	sheet := makeSortSheet()
+	sheet.Cell(1, 2).GetStyle().Font.Italic = true
+	c.Assert(sheet.SortRange("B2:C6", []SortKey{{Col: 2, Type: SortNumeric}}), IsNil)
+	// Column A is untouched.
+	c.Assert(sortSheetColumn(sheet, 0), DeepEquals, []string{"Name", "Cy", "Ann", "Bo", "Di", "Ed"})
+	c.Assert(sortSheetColumn(sheet, 1), DeepEquals, []string{"Team", "Blue", "Blue", "Red", "Blue", "Red"})
+	c.Assert(sortSheetColumn(sheet, 2), DeepEquals, []string{"Score", "7", "9", "10", "10", ""})
+	c.Assert(sheet.Cell(1, 2).GetStyle().Font.Italic, Equals, true)
+	c.Assert(sheet.Cell(3, 2).Row, Equals, sheet.Rows[3])
+}
+
+func (s *SortSuite) TestSortRangeErrors(c *C) {
+	sheet := makeSortSheet()
+	c.Assert(sheet.SortRange("A2:", []SortKey{{Col: 0}}), NotNil)
+	c.Assert(sheet.SortRange("Other!A2:C6", []SortKey{{Col: 0}}), ErrorMatches, "range 'Other!A2:C6' is not on sheet 'People'")
+	c.Assert(sheet.SortRange("A2:C6", nil), ErrorMatches, "no sort keys given for range 'A2:C6'")
+	c.Assert(sheet.SortRange("A2:C6", []SortKey{{Col: 3}}), ErrorMatches, "sort column D is outside the range 'A2:C6'")
+	c.Assert(sheet.SortRange("People!A20:C30", []SortKey{{Col: 0}}), IsNil)
+}
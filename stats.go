@@ -0,0 +1,96 @@
+package xlsx
+
+import (
+	"sync"
+	"time"
+)
+
+// Stats counts the work done reading and writing a File, so that the
+// performance of the pipelines using it can be watched.  It is only
+// collected for Files made or opened with CollectStats, and counts
+// everything done since, so that writing a File twice counts the parts
+// of both.
+type Stats struct {
+	// PartsRead is the number of parts of the package read, whether
+	// parsed or, like pictures, taken as they are.
+	PartsRead int
+	// CellsRead is the number of cells read from worksheets.
+	CellsRead int
+	// BytesParsed is the number of bytes of worksheets' XML parsed,
+	// which is smaller than their parts when RowLimit leaves rows
+	// out.
+	BytesParsed int64
+	// SharedStrings is the number of strings read from the shared
+	// string table, and StringsInterned the number of those that
+	// were the same as one read before it, and so share its copy.
+	SharedStrings   int
+	StringsInterned int
+	// PartsWritten is the number of parts written, and BytesWritten
+	// that of the bytes of the packages they were written in.
+	PartsWritten int
+	BytesWritten int64
+	// Phases holds the time spent in each phase of reading and
+	// writing: "sharedStrings", "styles" and "sheets" when a File is
+	// opened, "load" when the sheets of one opened with LazySheets are
+	// loaded, and "parts", making the parts, and "write", writing
+	// them, when it is written.  Parts that are written as they are
+	// made are timed in "write".
+	Phases map[string]time.Duration
+}
+
+// statsCollector gathers the Stats of a File, which sheets read at the
+// same time add to together.  A nil statsCollector gathers nothing.
+type statsCollector struct {
+	mu    sync.Mutex
+	stats Stats
+}
+
+// CollectStats makes a File gather the Stats of its reading and
+// writing; see File.Stats.
+func CollectStats() FileOption {
+	return func(f *File) {
+		f.stats = &statsCollector{stats: Stats{Phases: make(map[string]time.Duration)}}
+	}
+}
+
+// Stats returns the Stats gathered for the File so far, which are all
+// zero unless it was made or opened with CollectStats.
+func (f *File) Stats() Stats {
+	s := f.stats
+	if s == nil {
+		return Stats{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := s.stats
+	stats.Phases = make(map[string]time.Duration, len(s.stats.Phases))
+	for phase, d := range s.stats.Phases {
+		stats.Phases[phase] = d
+	}
+	return stats
+}
+
+func (s *statsCollector) add(fn func(*Stats)) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	fn(&s.stats)
+	s.mu.Unlock()
+}
+
+// phase starts timing a phase, returning the function that stops it.
+func (s *statsCollector) phase(name string) func() {
+	if s == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		d := time.Since(start)
+		s.add(func(stats *Stats) { stats.Phases[name] += d })
+	}
+}
+
+func (s *statsCollector) partRead() {
+	s.add(func(stats *Stats) { stats.PartsRead++ })
+}
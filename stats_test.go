@@ -0,0 +1,89 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+
+	. "gopkg.in/check.v1"
+)
+
+type StatsSuite struct{}
+
+var _ = Suite(&StatsSuite{})
+
+func (s *StatsSuite) TestStats(c *C) {
+	f := NewFileWithOptions(CollectStats())
+	sheet, err := f.AddSheet("Data")
+	c.Assert(err, IsNil)
+	for _, text := range []string{"Foo", "Bar", "Foo"} {
+		row := sheet.AddRow()
+		row.AddCell().SetString(text)
+		row.AddCell().SetInt(1)
+	}
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	stats := f.Stats()
+	c.Assert(stats.BytesWritten, Equals, int64(buf.Len()))
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(stats.PartsWritten, Equals, len(parts))
+	c.Assert(stats.PartsRead, Equals, 0)
+	_, ok := stats.Phases["parts"]
+	c.Assert(ok, Equals, true)
+	_, ok = stats.Phases["write"]
+	c.Assert(ok, Equals, true)
+	// What is returned is a copy.
+	stats.Phases["write"] = -1
+	c.Assert(f.Stats().Phases["write"] >= 0, Equals, true)
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	read, err := readZipReader(zr, []FileOption{CollectStats()})
+	c.Assert(err, IsNil)
+	stats = read.Stats()
+	c.Assert(stats.CellsRead, Equals, 6)
+	c.Assert(stats.SharedStrings, Equals, 2)
+	c.Assert(stats.StringsInterned, Equals, 0)
+	// The relationships, shared strings, theme, styles, workbook and
+	// worksheet, at least.
+	c.Assert(stats.PartsRead >= 6, Equals, true)
+	for _, phase := range []string{"sharedStrings", "styles", "sheets"} {
+		_, ok := stats.Phases[phase]
+		c.Assert(ok, Equals, true, Commentf(phase))
+	}
+
+	c.Assert(NewFile().Stats(), DeepEquals, Stats{})
+}
+
+func (s *StatsSuite) TestLazyStats(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Data")
+	c.Assert(err, IsNil)
+	sheet.Cell(1, 1).SetString("Foo")
+	sheet.Cell(2, 1).SetString("Foo")
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	// A table written with duplicates holds the same string twice.
+	parts["xl/sharedStrings.xml"] = `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><si><t>Foo</t></si><si><t>Foo</t></si></sst>`
+
+	read, err := readZipReader(makeZipReader(c, parts), []FileOption{LazySheets(), CollectStats()})
+	c.Assert(err, IsNil)
+	c.Assert(read.Stats().CellsRead, Equals, 0)
+	c.Assert(read.Stats().StringsInterned, Equals, 1)
+	c.Assert(read.Sheets[0].Load(), IsNil)
+	c.Assert(read.Stats().CellsRead > 0, Equals, true)
+	_, ok := read.Stats().Phases["load"]
+	c.Assert(ok, Equals, true)
+}
+
+func (s *StatsSuite) TestBinaryPartsRead(c *C) {
+	zr := makeZipReader(c, map[string]string{"xl/media/image1.png": "not really a picture"})
+	f := NewFileWithOptions(CollectStats())
+	data, err := f.readBinaryPart(zr.File[0])
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "not really a picture")
+	c.Assert(f.Stats().PartsRead, Equals, 1)
+
+	_, err = f.readBinaryPart(nil)
+	c.Assert(err, ErrorMatches, "the part to read doesn't exist")
+}
@@ -0,0 +1,101 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// strictNamespaces are the namespaces of ISO/IEC 29500 Strict
+// documents, saved by Excel as "Strict Open XML Spreadsheet", with the
+// namespaces of transitional documents that stand for the same.
+var strictNamespaces = map[string]string{
+	"http://purl.oclc.org/ooxml/spreadsheetml/main":                "http://schemas.openxmlformats.org/spreadsheetml/2006/main",
+	"http://purl.oclc.org/ooxml/officeDocument/relationships":      "http://schemas.openxmlformats.org/officeDocument/2006/relationships",
+	"http://purl.oclc.org/ooxml/officeDocument/extendedProperties": "http://schemas.openxmlformats.org/officeDocument/2006/extended-properties",
+	"http://purl.oclc.org/ooxml/officeDocument/customProperties":   "http://schemas.openxmlformats.org/officeDocument/2006/custom-properties",
+	"http://purl.oclc.org/ooxml/officeDocument/docPropsVTypes":     "http://schemas.openxmlformats.org/officeDocument/2006/docPropsVTypes",
+	"http://purl.oclc.org/ooxml/officeDocument/sharedTypes":        "http://schemas.openxmlformats.org/officeDocument/2006/sharedTypes",
+	"http://purl.oclc.org/ooxml/drawingml/main":                    "http://schemas.openxmlformats.org/drawingml/2006/main",
+	"http://purl.oclc.org/ooxml/drawingml/spreadsheetDrawing":      "http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing",
+	"http://purl.oclc.org/ooxml/drawingml/chart":                   "http://schemas.openxmlformats.org/drawingml/2006/chart",
+	"http://purl.oclc.org/ooxml/drawingml/picture":                 "http://schemas.openxmlformats.org/drawingml/2006/picture",
+	"http://purl.oclc.org/ooxml/officeDocument/math":               "http://schemas.openxmlformats.org/officeDocument/2006/math",
+	"http://purl.oclc.org/ooxml/officeDocument/bibliography":       "http://schemas.openxmlformats.org/officeDocument/2006/bibliography",
+	"http://purl.oclc.org/ooxml/officeDocument/customXml":          "http://schemas.openxmlformats.org/officeDocument/2006/customXml",
+	"http://purl.oclc.org/ooxml/schemaLibrary/main":                "http://schemas.openxmlformats.org/schemaLibrary/2006/main",
+	"http://purl.oclc.org/ooxml/drawingml/diagram":                 "http://schemas.openxmlformats.org/drawingml/2006/diagram",
+	"http://purl.oclc.org/ooxml/drawingml/chartDrawing":            "http://schemas.openxmlformats.org/drawingml/2006/chartDrawing",
+	"http://purl.oclc.org/ooxml/drawingml/lockedCanvas":            "http://schemas.openxmlformats.org/drawingml/2006/lockedCanvas",
+	"http://purl.oclc.org/ooxml/drawingml/compatibility":           "http://schemas.openxmlformats.org/drawingml/2006/compatibility",
+}
+
+const (
+	strictRelationshipTypes       = "http://purl.oclc.org/ooxml/officeDocument/relationships/"
+	transitionalRelationshipTypes = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/"
+)
+
+// strictRelationshipNames are the relationship types whose names in
+// strict documents aren't those of transitional ones.
+var strictRelationshipNames = map[string]string{
+	"extendedProperties": "extended-properties",
+	"customProperties":   "custom-properties",
+}
+
+// transitionalName returns the transitional namespace or relationship
+// type that s, from a strict document, stands for, or s itself if it
+// isn't strict.
+func transitionalName(s string) string {
+	if !strings.HasPrefix(s, "http://purl.oclc.org/ooxml/") {
+		return s
+	}
+	if transitional, ok := strictNamespaces[s]; ok {
+		return transitional
+	}
+	if strings.HasPrefix(s, strictRelationshipTypes) {
+		name := s[len(strictRelationshipTypes):]
+		if transitional, ok := strictRelationshipNames[name]; ok {
+			name = transitional
+		}
+		return transitionalRelationshipTypes + name
+	}
+	return s
+}
+
+// newPartDecoder returns a decoder of the XML part read from r.  The
+// parts of strict documents are decoded as though they were the same
+// parts of a transitional one, with the namespaces of their elements
+// and attributes, and the types of their relationships, translated, so
+// that both open alike.
+func newPartDecoder(r io.Reader) *xml.Decoder {
+	return xml.NewTokenDecoder(strictTranslator{xml.NewDecoder(r)})
+}
+
+// strictTranslator reads the tokens of an XML document, translating
+// the names of strict documents into those of transitional ones.
+type strictTranslator struct {
+	d *xml.Decoder
+}
+
+func (t strictTranslator) Token() (xml.Token, error) {
+	token, err := t.d.Token()
+	switch tok := token.(type) {
+	case xml.StartElement:
+		tok.Name.Space = transitionalName(tok.Name.Space)
+		for i := range tok.Attr {
+			attr := &tok.Attr[i]
+			attr.Name.Space = transitionalName(attr.Name.Space)
+			// Only namespace declarations and the types of
+			// relationships name namespaces; other values, such
+			// as the targets of links, are left as they are.
+			if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && (attr.Name.Local == "xmlns" || attr.Name.Local == "Type")) {
+				attr.Value = transitionalName(attr.Value)
+			}
+		}
+		return tok, err
+	case xml.EndElement:
+		tok.Name.Space = transitionalName(tok.Name.Space)
+		return tok, err
+	}
+	return token, err
+}
@@ -0,0 +1,104 @@
+package xlsx
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type StrictSuite struct{}
+
+var _ = Suite(&StrictSuite{})
+
+func (s *StrictSuite) TestTransitionalName(c *C) {
+	cases := map[string]string{
+		"http://purl.oclc.org/ooxml/spreadsheetml/main":                                   "http://schemas.openxmlformats.org/spreadsheetml/2006/main",
+		"http://purl.oclc.org/ooxml/officeDocument/relationships":                         "http://schemas.openxmlformats.org/officeDocument/2006/relationships",
+		"http://purl.oclc.org/ooxml/officeDocument/relationships/worksheet":               relTypeWorksheet,
+		"http://purl.oclc.org/ooxml/officeDocument/relationships/extendedProperties":      relTypeExtendedProps,
+		"http://schemas.openxmlformats.org/spreadsheetml/2006/main":                       "http://schemas.openxmlformats.org/spreadsheetml/2006/main",
+		"http://schemas.openxmlformats.org/package/2006/relationships/metadata/thumbnail": "http://schemas.openxmlformats.org/package/2006/relationships/metadata/thumbnail",
+		"Sheet1": "Sheet1",
+	}
+	for strict, transitional := range cases {
+		c.Assert(transitionalName(strict), Equals, transitional, Commentf(strict))
+	}
+}
+
+func (s *StrictSuite) TestTranslateOnlyNames(c *C) {
+	var rels struct {
+		Relationship []struct {
+			Type   string `xml:",attr"`
+			Target string `xml:",attr"`
+		}
+	}
+	d := newPartDecoder(strings.NewReader(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://purl.oclc.org/ooxml/officeDocument/relationships/hyperlink" Target="http://purl.oclc.org/ooxml/drawingml/main" TargetMode="External"/></Relationships>`))
+	c.Assert(d.Decode(&rels), IsNil)
+	c.Assert(rels.Relationship, HasLen, 1)
+	c.Assert(rels.Relationship[0].Type, Equals, transitionalRelationshipTypes+"hyperlink")
+	// A link to a page that happens to be a strict namespace is kept.
+	c.Assert(rels.Relationship[0].Target, Equals, "http://purl.oclc.org/ooxml/drawingml/main")
+}
+
+// strictParts returns the parts of a workbook as Excel writes them when
+// saving as "Strict Open XML Spreadsheet", made from the transitional
+// parts given.
+func strictParts(parts map[string]string) map[string]string {
+	replacer := strings.NewReplacer(
+		"http://schemas.openxmlformats.org/officeDocument/2006/relationships/extended-properties", "http://purl.oclc.org/ooxml/officeDocument/relationships/extendedProperties",
+		"http://schemas.openxmlformats.org/officeDocument/2006/relationships", "http://purl.oclc.org/ooxml/officeDocument/relationships",
+		"http://schemas.openxmlformats.org/spreadsheetml/2006/main", "http://purl.oclc.org/ooxml/spreadsheetml/main",
+		"http://schemas.openxmlformats.org/drawingml/2006/main", "http://purl.oclc.org/ooxml/drawingml/main",
+		"http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing", "http://purl.oclc.org/ooxml/drawingml/spreadsheetDrawing",
+		"http://schemas.openxmlformats.org/officeDocument/2006/extended-properties", "http://purl.oclc.org/ooxml/officeDocument/extendedProperties",
+		"<workbook ", `<workbook conformance="strict" `,
+	)
+	strict := make(map[string]string, len(parts))
+	for name, content := range parts {
+		strict[name] = replacer.Replace(content)
+	}
+	return strict
+}
+
+func (s *StrictSuite) TestReadStrict(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Data")
+	c.Assert(err, IsNil)
+	sheet.Cell(0, 0).SetString("Total")
+	sheet.Cell(0, 1).SetFloat(42.5)
+	sheet.Cell(0, 1).GetStyle().Font.Bold = true
+	sheet.Cell(1, 0).SetFormula("B1*2")
+	_, err = sheet.AddDefinedName(DefinedNamePrintArea, "Data!$A$1:$B$2")
+	c.Assert(err, IsNil)
+	_, err = f.AddSheet("Other")
+	c.Assert(err, IsNil)
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	strict := strictParts(parts)
+	c.Assert(strict["xl/workbook.xml"], Matches, `(?s).*<workbook conformance="strict" xmlns="http://purl.oclc.org/ooxml/spreadsheetml/main".*`)
+	c.Assert(strict["xl/_rels/workbook.xml.rels"], Matches, `(?s).*Type="http://purl.oclc.org/ooxml/officeDocument/relationships/worksheet".*`)
+	// Strict documents give dates in ISO 8601.
+	strict["xl/worksheets/sheet2.xml"] = strings.Replace(strict["xl/worksheets/sheet2.xml"], "<sheetData></sheetData>",
+		`<sheetData><row r="1"><c r="A1" t="d"><v>2024-03-01T00:00:00</v></c></row></sheetData>`, 1)
+
+	read, err := readZipReader(makeZipReader(c, strict), nil)
+	c.Assert(err, IsNil)
+	c.Assert(read.Sheets, HasLen, 2)
+	data := read.Sheet["Data"]
+	c.Assert(data, NotNil)
+	c.Assert(data.Cell(0, 0).Value, Equals, "Total")
+	c.Assert(data.Cell(0, 1).Value, Equals, "42.5")
+	c.Assert(data.Cell(0, 1).GetStyle().Font.Bold, Equals, true)
+	c.Assert(data.Cell(1, 0).Formula(), Equals, "B1*2")
+	c.Assert(read.Names, HasLen, 1)
+	c.Assert(read.Names[0].Sheet, Equals, "Data")
+	date := read.Sheet["Other"].Cell(0, 0)
+	c.Assert(date.Type(), Equals, CellTypeDate)
+	c.Assert(date.Value, Equals, "45352")
+
+	// What is read is written back as a transitional document.
+	parts, err = read.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/workbook.xml"], Not(Matches), `(?s).*purl\.oclc\.org.*`)
+}
@@ -1,6 +1,12 @@
 package xlsx
 
-import "strconv"
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
 
 // Style is a high level structure intended to provide user access to
 // the contents of Style within an XLSX file.
@@ -13,16 +19,19 @@ type Style struct {
 	ApplyFont       bool
 	ApplyAlignment  bool
 	Alignment       Alignment
+	ApplyProtection bool
+	Protection      Protection
 	NamedStyleIndex *int
 }
 
 // Return a new Style structure initialised with the default values.
 func NewStyle() *Style {
 	return &Style{
-		Alignment: *DefaultAlignment(),
-		Border:    *DefaultBorder(),
-		Fill:      *DefaultFill(),
-		Font:      *DefaultFont(),
+		Alignment:  *DefaultAlignment(),
+		Border:     *DefaultBorder(),
+		Fill:       *DefaultFill(),
+		Font:       *DefaultFont(),
+		Protection: *DefaultProtection(),
 	}
 }
 
@@ -32,31 +41,8 @@ func (style *Style) makeXLSXStyleElements() (xFont xlsxFont, xFill xlsxFill, xBo
 	xFill = xlsxFill{}
 	xBorder = xlsxBorder{}
 	xCellXf = xlsxXf{}
-	xFont.Sz.Val = strconv.Itoa(style.Font.Size)
-	xFont.Name.Val = style.Font.Name
-	xFont.Family.Val = strconv.Itoa(style.Font.Family)
-	xFont.Charset.Val = strconv.Itoa(style.Font.Charset)
-	xFont.Color.RGB = style.Font.Color
-	if style.Font.Bold {
-		xFont.B = &xlsxVal{}
-	} else {
-		xFont.B = nil
-	}
-	if style.Font.Italic {
-		xFont.I = &xlsxVal{}
-	} else {
-		xFont.I = nil
-	}
-	if style.Font.Underline {
-		xFont.U = &xlsxVal{}
-	} else {
-		xFont.U = nil
-	}
-	xPatternFill := xlsxPatternFill{}
-	xPatternFill.PatternType = style.Fill.PatternType
-	xPatternFill.FgColor.RGB = style.Fill.FgColor
-	xPatternFill.BgColor.RGB = style.Fill.BgColor
-	xFill.PatternFill = xPatternFill
+	xFont = style.Font.makeXLSXFont()
+	xFill = style.Fill.makeXLSXFill()
 	xBorder.Left = xlsxLine{
 		Style: style.Border.Left,
 		Color: xlsxColor{RGB: style.Border.LeftColor},
@@ -78,6 +64,13 @@ func (style *Style) makeXLSXStyleElements() (xFont xlsxFont, xFill xlsxFill, xBo
 	xCellXf.ApplyFill = style.ApplyFill
 	xCellXf.ApplyFont = style.ApplyFont
 	xCellXf.ApplyAlignment = style.ApplyAlignment
+	xCellXf.ApplyProtection = style.ApplyProtection
+	if style.ApplyProtection {
+		xCellXf.Protection = &xlsxProtection{
+			Locked: style.Protection.Locked,
+			Hidden: style.Protection.Hidden,
+		}
+	}
 	if style.NamedStyleIndex != nil {
 		xCellXf.XfId = style.NamedStyleIndex
 	}
@@ -113,10 +106,112 @@ func NewBorder(left, right, top, bottom string) *Border {
 
 // Fill is a high level structure intended to provide user access to
 // the contents of background and foreground color index within an Sheet.
+// If Gradient is set the cell is filled with it rather than with the
+// pattern.
 type Fill struct {
 	PatternType string
 	BgColor     string
 	FgColor     string
+	Gradient    *GradientFill
+}
+
+// The pattern types of a Fill.  Solid fills use the FgColor; the
+// others draw their pattern in the FgColor over the BgColor.
+const (
+	FillPatternNone            = "none"
+	FillPatternSolid           = "solid"
+	FillPatternMediumGray      = "mediumGray"
+	FillPatternDarkGray        = "darkGray"
+	FillPatternLightGray       = "lightGray"
+	FillPatternDarkHorizontal  = "darkHorizontal"
+	FillPatternDarkVertical    = "darkVertical"
+	FillPatternDarkDown        = "darkDown"
+	FillPatternDarkUp          = "darkUp"
+	FillPatternDarkGrid        = "darkGrid"
+	FillPatternDarkTrellis     = "darkTrellis"
+	FillPatternLightHorizontal = "lightHorizontal"
+	FillPatternLightVertical   = "lightVertical"
+	FillPatternLightDown       = "lightDown"
+	FillPatternLightUp         = "lightUp"
+	FillPatternLightGrid       = "lightGrid"
+	FillPatternLightTrellis    = "lightTrellis"
+	FillPatternGray125         = "gray125"
+	FillPatternGray0625        = "gray0625"
+)
+
+// GradientFill fills a cell with colours blending from one Stop to
+// the next.  A gradient of Type "linear", or the empty string, runs at
+// Degree clockwise from left to right.  One of Type "path" runs out
+// from the rectangle whose edges lie the fractions Left, Right, Top and
+// Bottom of the way across and down the cell.
+type GradientFill struct {
+	Type   string
+	Degree float64
+	Left   float64
+	Right  float64
+	Top    float64
+	Bottom float64
+	Stops  []GradientStop
+}
+
+// GradientStop is the ARGB hex Color a GradientFill has at Position,
+// from 0 at its start to 1 at its end.
+type GradientStop struct {
+	Position float64
+	Color    string
+}
+
+// NewLinearGradient returns a GradientFill running at degree from
+// startColor to endColor.
+func NewLinearGradient(degree float64, startColor, endColor string) *GradientFill {
+	return &GradientFill{
+		Degree: degree,
+		Stops:  []GradientStop{{Position: 0, Color: startColor}, {Position: 1, Color: endColor}},
+	}
+}
+
+// NewPathGradient returns a GradientFill running from centerColor in
+// the middle of the cell to edgeColor at its edges.
+func NewPathGradient(centerColor, edgeColor string) *GradientFill {
+	return &GradientFill{
+		Type:   "path",
+		Left:   0.5,
+		Right:  0.5,
+		Top:    0.5,
+		Bottom: 0.5,
+		Stops:  []GradientStop{{Position: 0, Color: centerColor}, {Position: 1, Color: edgeColor}},
+	}
+}
+
+func (fill *Fill) makeXLSXFill() (xFill xlsxFill) {
+	xFill.PatternFill.PatternType = fill.PatternType
+	xFill.PatternFill.FgColor.RGB = fill.FgColor
+	xFill.PatternFill.BgColor.RGB = fill.BgColor
+	if gradient := fill.Gradient; gradient != nil {
+		xFill.GradientFill = &xlsxGradientFill{
+			Type:   gradient.Type,
+			Degree: gradient.Degree,
+			Left:   gradient.Left,
+			Right:  gradient.Right,
+			Top:    gradient.Top,
+			Bottom: gradient.Bottom,
+		}
+		for _, stop := range gradient.Stops {
+			xFill.GradientFill.Stop = append(xFill.GradientFill.Stop, xlsxGradientStop{
+				Position: stop.Position,
+				Color:    xlsxColor{RGB: stop.Color},
+			})
+		}
+	}
+	return
+}
+
+// equals reports whether two Fills look the same.
+func (fill Fill) equals(other Fill) bool {
+	if fill.PatternType != other.PatternType || fill.FgColor != other.FgColor || fill.BgColor != other.BgColor {
+		return false
+	}
+	return reflect.DeepEqual(fill.Gradient, other.Gradient)
 }
 
 func NewFill(patternType, fgColor, bgColor string) *Fill {
@@ -142,24 +237,169 @@ func NewFont(size int, name string) *Font {
 	return &Font{Size: size, Name: name}
 }
 
+// makeXLSXFont returns the font element of a style sheet for the Font.
+func (font *Font) makeXLSXFont() xlsxFont {
+	xFont := xlsxFont{}
+	xFont.Sz.Val = strconv.Itoa(font.Size)
+	xFont.Name.Val = font.Name
+	xFont.Family.Val = strconv.Itoa(font.Family)
+	xFont.Charset.Val = strconv.Itoa(font.Charset)
+	xFont.Color.RGB = font.Color
+	if font.Bold {
+		xFont.B = &xlsxVal{}
+	}
+	if font.Italic {
+		xFont.I = &xlsxVal{}
+	}
+	if font.Underline {
+		xFont.U = &xlsxVal{}
+	}
+	return xFont
+}
+
+// Alignment is how the content of a cell is placed within it.
+//
+// Indent is the number of levels, each about three characters wide,
+// that left, right or distributed content is moved in from the side it
+// is aligned to.  TextRotation is the angle of the text in Excel's
+// encoding; see SetTextRotation.  Text too wide for its cell is wrapped
+// onto more lines if WrapText is set, or else shrunk to fit if
+// ShrinkToFit is.
 type Alignment struct {
-	Horizontal   string
+	Horizontal   HorizontalAlignment
 	Indent       int
 	ShrinkToFit  bool
 	TextRotation int
-	Vertical     string
+	Vertical     VerticalAlignment
 	WrapText     bool
 }
 
+// HorizontalAlignment is how the content of a cell is placed across
+// it.  AlignFill repeats the content across the cell,
+// AlignCenterContinuous centres it across the following empty cells
+// that are also so aligned, and AlignJustify and AlignDistributed
+// spread the words of wrapped text out to both sides,
+// AlignDistributed doing so on the last line too.
+type HorizontalAlignment string
+
+const (
+	AlignGeneral          HorizontalAlignment = "general"
+	AlignLeft             HorizontalAlignment = "left"
+	AlignCenter           HorizontalAlignment = "center"
+	AlignRight            HorizontalAlignment = "right"
+	AlignFill             HorizontalAlignment = "fill"
+	AlignJustify          HorizontalAlignment = "justify"
+	AlignCenterContinuous HorizontalAlignment = "centerContinuous"
+	AlignDistributed      HorizontalAlignment = "distributed"
+)
+
+// VerticalAlignment is how the content of a cell is placed from its
+// top to its bottom.  AlignVerticalJustify and
+// AlignVerticalDistributed spread the lines of wrapped text out from
+// the top to the bottom of the cell.
+type VerticalAlignment string
+
+const (
+	AlignTop                 VerticalAlignment = "top"
+	AlignVerticalCenter      VerticalAlignment = "center"
+	AlignBottom              VerticalAlignment = "bottom"
+	AlignVerticalJustify     VerticalAlignment = "justify"
+	AlignVerticalDistributed VerticalAlignment = "distributed"
+)
+
+var (
+	horizontalAlignments = []string{
+		string(AlignGeneral), string(AlignLeft), string(AlignCenter), string(AlignRight),
+		string(AlignFill), string(AlignJustify), string(AlignCenterContinuous), string(AlignDistributed),
+	}
+	verticalAlignments = []string{
+		string(AlignTop), string(AlignVerticalCenter), string(AlignBottom),
+		string(AlignVerticalJustify), string(AlignVerticalDistributed),
+	}
+)
+
+// alignmentValue returns the one of values that value names, ignoring
+// case.  A value that isn't one of them, such as one a later version
+// of Excel knows, is returned as it is, so that it is kept rather than
+// replaced by the default.
+func alignmentValue(value string, values []string) string {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return v
+		}
+	}
+	return value
+}
+
+// TextRotationVertical is the TextRotation of text whose letters are
+// stacked one above the other, rather than turned.
+const TextRotationVertical = 255
+
+// The largest Indent Excel allows.
+const maxIndent = 250
+
+// SetTextRotation turns the text by degrees, from 90 for text running
+// up the cell to -90 for text running down it, or stacks its letters if
+// degrees is TextRotationVertical.
+func (alignment *Alignment) SetTextRotation(degrees int) error {
+	switch {
+	case degrees == TextRotationVertical:
+		alignment.TextRotation = TextRotationVertical
+	case degrees >= 0 && degrees <= 90:
+		alignment.TextRotation = degrees
+	case degrees < 0 && degrees >= -90:
+		// Excel counts angles below the horizontal from 91.
+		alignment.TextRotation = 90 - degrees
+	default:
+		return fmt.Errorf("text rotation %d is not between -90 and 90 degrees", degrees)
+	}
+	return nil
+}
+
+// Rotation returns the angle the text is turned by, in degrees from
+// -90 to 90, or TextRotationVertical if its letters are stacked.
+func (alignment *Alignment) Rotation() int {
+	if alignment.TextRotation > 90 && alignment.TextRotation <= 180 {
+		return 90 - alignment.TextRotation
+	}
+	return alignment.TextRotation
+}
+
+// SetIndent sets the Indent, which may be at most 250 levels.
+func (alignment *Alignment) SetIndent(level int) error {
+	if level < 0 || level > maxIndent {
+		return fmt.Errorf("indent %d is not between 0 and %d", level, maxIndent)
+	}
+	alignment.Indent = level
+	return nil
+}
+
+// Protection controls what happens to a cell once its sheet is
+// protected.  Locked cells can't be edited, and the formulas of Hidden
+// cells aren't shown in the formula bar.  A Style's Protection only
+// takes effect when ApplyProtection is set.
+type Protection struct {
+	Locked bool
+	Hidden bool
+}
+
 var defaultFontSize = 12
 var defaultFontName = "Verdana"
 
+// defaultFontMu guards defaultFontSize and defaultFontName, as new
+// Styles may be created from several goroutines.
+var defaultFontMu sync.RWMutex
+
 func SetDefaultFont(size int, name string) {
+	defaultFontMu.Lock()
+	defer defaultFontMu.Unlock()
 	defaultFontSize = size
 	defaultFontName = name
 }
 
 func DefaultFont() *Font {
+	defaultFontMu.RLock()
+	defer defaultFontMu.RUnlock()
 	return NewFont(defaultFontSize, defaultFontName)
 }
 
@@ -172,6 +412,12 @@ func DefaultBorder() *Border {
 	return NewBorder("none", "none", "none", "none")
 }
 
+// DefaultProtection returns the Protection Excel gives cells that
+// have none of their own: locked, but not hidden.
+func DefaultProtection() *Protection {
+	return &Protection{Locked: true}
+}
+
 func DefaultAlignment() *Alignment {
 	return &Alignment{
 		Horizontal: "general",
@@ -1,6 +1,8 @@
 package xlsx
 
 import (
+	"bytes"
+
 	. "gopkg.in/check.v1"
 )
 
@@ -54,6 +56,149 @@ func (s *StyleSuite) TestMakeXLSXStyleElements(c *C) {
 
 }
 
+func (s *StyleSuite) TestProtection(c *C) {
+	c.Assert(NewStyle().Protection, Equals, Protection{Locked: true})
+	_, _, _, xCellXf := NewStyle().makeXLSXStyleElements()
+	c.Assert(xCellXf.Protection, IsNil)
+
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	row := sheet.AddRow()
+	input := row.AddCell()
+	input.SetInt(1)
+	input.GetStyle().ApplyProtection = true
+	input.GetStyle().Protection.Locked = false
+	total := row.AddCell()
+	total.SetFormula("A1*2")
+	total.GetStyle().ApplyProtection = true
+	total.GetStyle().Protection.Hidden = true
+	row.AddCell().SetInt(3)
+	sheet.Protected = true
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/worksheets/sheet1.xml"], Matches, `(?s).*</sheetData><sheetProtection sheet="true" objects="true" scenarios="true"></sheetProtection>.*`)
+	c.Assert(parts["xl/styles.xml"], Matches, `(?s).*applyProtection="1"[^>]*><alignment [^>]*/><protection locked="0" hidden="0"/></xf>.*`)
+	c.Assert(parts["xl/styles.xml"], Matches, `(?s).*<protection locked="1" hidden="1"/>.*`)
+
+	var buf bytes.Buffer
+	c.Assert(file.Write(&buf), IsNil)
+	written, err := OpenBinary(buf.Bytes())
+	c.Assert(err, IsNil)
+	sheet = written.Sheets[0]
+	c.Assert(sheet.Protected, Equals, true)
+	c.Assert(sheet.Cell(0, 0).GetStyle().ApplyProtection, Equals, true)
+	c.Assert(sheet.Cell(0, 0).GetStyle().Protection, Equals, Protection{})
+	c.Assert(sheet.Cell(0, 1).GetStyle().Protection, Equals, Protection{Locked: true, Hidden: true})
+	c.Assert(sheet.Cell(0, 2).GetStyle().ApplyProtection, Equals, false)
+	c.Assert(sheet.Cell(0, 2).GetStyle().Protection, Equals, Protection{Locked: true})
+}
+
+func (s *StyleSuite) TestFills(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	row := sheet.AddRow()
+	linear := row.AddCell()
+	linear.SetString("linear")
+	linear.GetStyle().Fill = Fill{Gradient: NewLinearGradient(90, "FFFFFFFF", "FF4472C4")}
+	linear.GetStyle().ApplyFill = true
+	path := row.AddCell()
+	path.SetString("path")
+	path.GetStyle().Fill = Fill{Gradient: NewPathGradient("FFFFFFFF", "FF000000")}
+	path.GetStyle().ApplyFill = true
+	pattern := row.AddCell()
+	pattern.SetString("pattern")
+	pattern.GetStyle().Fill = *NewFill(FillPatternDarkTrellis, "FFFF0000", "FF00FF00")
+	pattern.GetStyle().ApplyFill = true
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	styles := parts["xl/styles.xml"]
+	c.Assert(styles, Matches, `(?s).*<fill><gradientFill degree="90"><stop position="0"><color rgb="FFFFFFFF"/></stop><stop position="1"><color rgb="FF4472C4"/></stop></gradientFill></fill>.*`)
+	c.Assert(styles, Matches, `(?s).*<fill><gradientFill type="path" left="0.5" right="0.5" top="0.5" bottom="0.5"><stop position="0">.*`)
+	c.Assert(styles, Matches, `(?s).*<fill><patternFill patternType="darkTrellis"><fgColor rgb="FFFF0000"/><bgColor rgb="FF00FF00"/></patternFill></fill>.*`)
+
+	written, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	sheet = written.Sheets[0]
+	c.Assert(*sheet.Cell(0, 0).GetStyle().Fill.Gradient, DeepEquals, *NewLinearGradient(90, "FFFFFFFF", "FF4472C4"))
+	c.Assert(*sheet.Cell(0, 1).GetStyle().Fill.Gradient, DeepEquals, *NewPathGradient("FFFFFFFF", "FF000000"))
+	c.Assert(sheet.Cell(0, 2).GetStyle().Fill, Equals, Fill{PatternType: "darkTrellis", FgColor: "FFFF0000", BgColor: "FF00FF00"})
+}
+
+func (s *StyleSuite) TestAlignment(c *C) {
+	alignment := DefaultAlignment()
+	c.Assert(alignment.SetTextRotation(45), IsNil)
+	c.Assert(alignment.TextRotation, Equals, 45)
+	c.Assert(alignment.SetTextRotation(-45), IsNil)
+	c.Assert(alignment.TextRotation, Equals, 135)
+	c.Assert(alignment.Rotation(), Equals, -45)
+	c.Assert(alignment.SetTextRotation(TextRotationVertical), IsNil)
+	c.Assert(alignment.Rotation(), Equals, TextRotationVertical)
+	c.Assert(alignment.SetTextRotation(91), ErrorMatches, "text rotation 91 is not between -90 and 90 degrees")
+	c.Assert(alignment.SetIndent(3), IsNil)
+	c.Assert(alignment.SetIndent(251), ErrorMatches, "indent 251 is not between 0 and 250")
+
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	cell := sheet.AddRow().AddCell()
+	cell.SetString("turned")
+	style := cell.GetStyle()
+	style.ApplyAlignment = true
+	style.Alignment.Horizontal = "left"
+	style.Alignment.SetIndent(2)
+	style.Alignment.SetTextRotation(-90)
+	style.Alignment.WrapText = true
+	style.Alignment.ShrinkToFit = true
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/styles.xml"], Matches, `(?s).*<alignment horizontal="left" indent="2" shrinkToFit="1" textRotation="180" vertical="bottom" wrapText="1"/>.*`)
+
+	written, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	c.Assert(written.Sheets[0].Cell(0, 0).GetStyle().Alignment, Equals, Alignment{
+		Horizontal:   "left",
+		Indent:       2,
+		ShrinkToFit:  true,
+		TextRotation: 180,
+		Vertical:     "bottom",
+		WrapText:     true,
+	})
+}
+
+func (s *StyleSuite) TestAlignmentValues(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	row := sheet.AddRow()
+	for _, alignment := range []Alignment{
+		{Horizontal: AlignCenterContinuous, Vertical: AlignVerticalDistributed},
+		{Horizontal: AlignFill, Vertical: AlignVerticalJustify},
+		{Horizontal: "Distributed", Vertical: "TOP"},
+		{Horizontal: "middle", Vertical: "baseline"},
+	} {
+		cell := row.AddCell()
+		cell.SetString("text")
+		cell.GetStyle().Alignment = alignment
+		cell.GetStyle().ApplyAlignment = true
+	}
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	written, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	sheet = written.Sheets[0]
+	alignment := func(col int) Alignment {
+		a := sheet.Cell(0, col).GetStyle().Alignment
+		return Alignment{Horizontal: a.Horizontal, Vertical: a.Vertical}
+	}
+	c.Assert(alignment(0), Equals, Alignment{Horizontal: "centerContinuous", Vertical: "distributed"})
+	c.Assert(alignment(1), Equals, Alignment{Horizontal: "fill", Vertical: "justify"})
+	c.Assert(alignment(2), Equals, Alignment{Horizontal: "distributed", Vertical: "top"})
+	// Values that aren't known are kept as they are.
+	c.Assert(alignment(3), Equals, Alignment{Horizontal: "middle", Vertical: "baseline"})
+}
+
 type FontSuite struct{}
 
 var _ = Suite(&FontSuite{})
@@ -0,0 +1,61 @@
+package xlsx
+
+// StyleStats counts what the style sheet of a File holds, to help find
+// out why a file is large or slow to open.  Written counts the
+// entries the File would be written with, once the styles of its
+// cells, rows and columns are shared out; Read counts those of the
+// file it was read from, if any, which may repeat themselves.
+type StyleStats struct {
+	Written StyleCounts
+	Read    StyleCounts
+	// StyledCells is the number of cells with a style of their own.
+	StyledCells int
+}
+
+// StyleCounts are the numbers of each kind of entry in a style sheet.
+type StyleCounts struct {
+	CellXfs int
+	Fonts   int
+	Fills   int
+	Borders int
+	NumFmts int
+}
+
+// counts returns the numbers of entries in the style sheet.
+func (styles *xlsxStyleSheet) counts() StyleCounts {
+	return StyleCounts{
+		CellXfs: len(styles.CellXfs.Xf),
+		Fonts:   len(styles.Fonts.Font),
+		Fills:   len(styles.Fills.Fill),
+		Borders: len(styles.Borders.Border),
+		NumFmts: len(styles.NumFmts.NumFmt),
+	}
+}
+
+// StyleStats returns the counts of the File's style sheet entries.
+// Working out those it would be written with takes as long as
+// preparing the sheets for writing, without the File being changed.
+func (f *File) StyleStats() (StyleStats, error) {
+	stats := StyleStats{Read: f.readStyles}
+	styles := newXlsxStyleSheet(f.theme)
+	styles.reset()
+	styles.setNamedStyles(f.namedStyles)
+	for _, sheet := range f.Sheets {
+		if err := sheet.Load(); err != nil {
+			return stats, err
+		}
+		sheet.makeXLSXSheet(NewSharedStringRefTable(), styles)
+		for _, row := range sheet.Rows {
+			if row == nil {
+				continue
+			}
+			for _, cell := range row.Cells {
+				if cell != nil && cell.style != nil {
+					stats.StyledCells++
+				}
+			}
+		}
+	}
+	stats.Written = styles.counts()
+	return stats, nil
+}
@@ -0,0 +1,54 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type StyleStatsSuite struct{}
+
+var _ = Suite(&StyleStatsSuite{})
+
+func (s *StyleStatsSuite) TestStyleStats(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	for i := 0; i < 1000; i++ {
+		cell := sheet.AddRow().AddCell()
+		cell.SetInt(i)
+		style := NewStyle()
+		style.Font.Bold = i%2 == 0
+		style.ApplyFont = true
+		cell.SetStyle(style)
+	}
+	stats, err := file.StyleStats()
+	c.Assert(err, IsNil)
+	c.Assert(stats.StyledCells, Equals, 1000)
+	c.Assert(stats.Read, Equals, StyleCounts{})
+	// The thousand styles come down to a bold and a plain one.
+	c.Assert(stats.Written.Fonts, Equals, 2)
+	c.Assert(stats.Written.Fills, Equals, 2) // none and gray125
+	c.Assert(stats.Written.Borders, Equals, 1)
+	c.Assert(stats.Written.CellXfs, Equals, 4)
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	written, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	readStats, err := written.StyleStats()
+	c.Assert(err, IsNil)
+	c.Assert(readStats.Read, Equals, stats.Written)
+	c.Assert(readStats.Written, Equals, stats.Written)
+}
+
+func (s *StyleStatsSuite) TestAddCellXfDeduplicates(c *C) {
+	styles := newXlsxStyleSheet(nil)
+	styles.reset()
+	bold := xlsxFont{Name: xlsxVal{Val: "Arial"}, B: &xlsxVal{}}
+	c.Assert(styles.addFont(bold), Equals, 0)
+	c.Assert(styles.addFont(xlsxFont{Name: xlsxVal{Val: "Arial"}, B: &xlsxVal{Val: "1"}}), Equals, 0)
+	c.Assert(styles.addFont(xlsxFont{Name: xlsxVal{Val: "Arial"}}), Equals, 1)
+	xf := xlsxXf{FontId: 1, ApplyFont: true}
+	c.Assert(styles.addCellXf(xf), Equals, 1)
+	c.Assert(styles.addCellXf(xlsxXf{FontId: 1, ApplyFont: true}), Equals, 1)
+	c.Assert(styles.addCellXf(xlsxXf{FontId: 0, ApplyFont: true}), Equals, 2)
+	c.Assert(styles.CellXfs.Count, Equals, 3)
+}
@@ -0,0 +1,156 @@
+package xlsx
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// TableStyleElementType names the part of a table that a
+// TableStyleElement formats.
+type TableStyleElementType string
+
+const (
+	TableStyleWholeTable         TableStyleElementType = "wholeTable"
+	TableStyleHeaderRow          TableStyleElementType = "headerRow"
+	TableStyleTotalRow           TableStyleElementType = "totalRow"
+	TableStyleFirstColumn        TableStyleElementType = "firstColumn"
+	TableStyleLastColumn         TableStyleElementType = "lastColumn"
+	TableStyleFirstRowStripe     TableStyleElementType = "firstRowStripe"
+	TableStyleSecondRowStripe    TableStyleElementType = "secondRowStripe"
+	TableStyleFirstColumnStripe  TableStyleElementType = "firstColumnStripe"
+	TableStyleSecondColumnStripe TableStyleElementType = "secondColumnStripe"
+	TableStyleFirstHeaderCell    TableStyleElementType = "firstHeaderCell"
+	TableStyleLastHeaderCell     TableStyleElementType = "lastHeaderCell"
+	TableStyleFirstTotalCell     TableStyleElementType = "firstTotalCell"
+	TableStyleLastTotalCell      TableStyleElementType = "lastTotalCell"
+)
+
+// TableStyleElement formats one part of the tables given its
+// TableStyle.  Size is the number of rows or columns in each band of
+// a stripe; for other types it is ignored.
+type TableStyleElement struct {
+	Type  TableStyleElementType
+	Size  int
+	Style DifferentialStyle
+}
+
+// TableStyle is a custom style for Excel tables, or for pivot tables
+// if Pivot is set, which they refer to by its Name.
+type TableStyle struct {
+	Name     string
+	Pivot    bool
+	Elements []TableStyleElement
+}
+
+// BuiltInTableStyles returns the names of the table and pivot table
+// styles built into Excel, which need not be defined to be used.
+func BuiltInTableStyles() []string {
+	var names []string
+	for _, family := range []struct {
+		prefix string
+		count  int
+	}{
+		{"TableStyleLight", 21},
+		{"TableStyleMedium", 28},
+		{"TableStyleDark", 11},
+		{"PivotStyleLight", 28},
+		{"PivotStyleMedium", 28},
+		{"PivotStyleDark", 28},
+	} {
+		for i := 1; i <= family.count; i++ {
+			names = append(names, family.prefix+strconv.Itoa(i))
+		}
+	}
+	return names
+}
+
+// IsBuiltInTableStyle reports whether name is one of the
+// BuiltInTableStyles.
+func IsBuiltInTableStyle(name string) bool {
+	for _, builtIn := range BuiltInTableStyles() {
+		if name == builtIn {
+			return true
+		}
+	}
+	return false
+}
+
+// AddTableStyle defines a custom table style in the File's style
+// sheet.  Its name mustn't be empty, built in or already defined.
+func (f *File) AddTableStyle(style TableStyle) error {
+	if style.Name == "" {
+		return fmt.Errorf("a table style needs a name")
+	}
+	if IsBuiltInTableStyle(style.Name) {
+		return fmt.Errorf("table style '%s' is built in", style.Name)
+	}
+	for _, existing := range f.tableStyles {
+		if existing.Name == style.Name {
+			return fmt.Errorf("table style '%s' is already defined", style.Name)
+		}
+	}
+	f.tableStyles = append(f.tableStyles, style)
+	return nil
+}
+
+// TableStyles returns the custom table styles defined in the File,
+// whether by AddTableStyle or in the file it was read from.
+func (f *File) TableStyles() []TableStyle {
+	return f.tableStyles
+}
+
+// setTableStyles puts the custom table styles in the style sheet,
+// adding the differential formats they use.
+func (styles *xlsxStyleSheet) setTableStyles(tableStyles []TableStyle, defaultTableStyle, defaultPivotStyle string) {
+	if len(tableStyles) == 0 && defaultTableStyle == "" && defaultPivotStyle == "" {
+		return
+	}
+	styles.TableStyles = &xlsxTableStyles{
+		DefaultTableStyle: defaultTableStyle,
+		DefaultPivotStyle: defaultPivotStyle,
+	}
+	for _, style := range tableStyles {
+		xStyle := xlsxTableStyle{Name: style.Name, Count: len(style.Elements)}
+		if style.Pivot {
+			no := false
+			xStyle.Table = &no
+		} else {
+			no := false
+			xStyle.Pivot = &no
+		}
+		for _, element := range style.Elements {
+			dxfId := styles.addDxf(styles.makeDxf(element.Style))
+			xElement := xlsxTableStyleElement{Type: string(element.Type), DxfId: &dxfId}
+			if element.Size > 1 {
+				xElement.Size = element.Size
+			}
+			xStyle.Element = append(xStyle.Element, xElement)
+		}
+		styles.TableStyles.TableStyle = append(styles.TableStyles.TableStyle, xStyle)
+	}
+	styles.TableStyles.Count = len(styles.TableStyles.TableStyle)
+}
+
+// readTableStyles returns the custom table styles defined in a style
+// sheet read from a file.
+func (styles *xlsxStyleSheet) readTableStyles() []TableStyle {
+	if styles.TableStyles == nil {
+		return nil
+	}
+	var tableStyles []TableStyle
+	for _, xStyle := range styles.TableStyles.TableStyle {
+		style := TableStyle{
+			Name:  xStyle.Name,
+			Pivot: xStyle.Table != nil && !*xStyle.Table,
+		}
+		for _, xElement := range xStyle.Element {
+			element := TableStyleElement{Type: TableStyleElementType(xElement.Type), Size: xElement.Size}
+			if xElement.DxfId != nil && styles.Dxfs != nil && *xElement.DxfId >= 0 && *xElement.DxfId < len(styles.Dxfs.Dxf) {
+				element.Style = styles.readDxf(styles.Dxfs.Dxf[*xElement.DxfId])
+			}
+			style.Elements = append(style.Elements, element)
+		}
+		tableStyles = append(tableStyles, style)
+	}
+	return tableStyles
+}
@@ -0,0 +1,80 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type TableStyleSuite struct{}
+
+var _ = Suite(&TableStyleSuite{})
+
+func (s *TableStyleSuite) TestBuiltInTableStyles(c *C) {
+	names := BuiltInTableStyles()
+	c.Assert(names, HasLen, 21+28+11+28+28+28)
+	c.Assert(names[0], Equals, "TableStyleLight1")
+	c.Assert(IsBuiltInTableStyle("TableStyleMedium2"), Equals, true)
+	c.Assert(IsBuiltInTableStyle("PivotStyleLight16"), Equals, true)
+	c.Assert(IsBuiltInTableStyle("TableStyleDark12"), Equals, false)
+}
+
+func (s *TableStyleSuite) TestAddTableStyle(c *C) {
+	file := NewFile()
+	c.Assert(file.AddTableStyle(TableStyle{}), ErrorMatches, "a table style needs a name")
+	c.Assert(file.AddTableStyle(TableStyle{Name: "TableStyleLight1"}), ErrorMatches, "table style 'TableStyleLight1' is built in")
+	c.Assert(file.AddTableStyle(TableStyle{Name: "Report"}), IsNil)
+	c.Assert(file.AddTableStyle(TableStyle{Name: "Report"}), ErrorMatches, "table style 'Report' is already defined")
+	c.Assert(file.TableStyles(), HasLen, 1)
+}
+
+func (s *TableStyleSuite) TestTableStylesRoundTrip(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	sheet.AddRow().AddCell().SetString("Name")
+	file.DefaultTableStyle = "Report"
+	file.DefaultPivotStyle = "PivotStyleLight16"
+	header := DifferentialStyle{
+		Font:   &Font{Bold: true, Color: "FFFFFFFF"},
+		Fill:   &Fill{PatternType: "solid", FgColor: "FF4472C4"},
+		Border: &Border{Bottom: "medium", BottomColor: "FF000000"},
+	}
+	err := file.AddTableStyle(TableStyle{
+		Name: "Report",
+		Elements: []TableStyleElement{
+			{Type: TableStyleHeaderRow, Style: header},
+			{Type: TableStyleFirstColumn, Style: DifferentialStyle{Font: &Font{Italic: true}}},
+			{Type: TableStyleFirstRowStripe, Size: 2, Style: DifferentialStyle{NumFmt: "0.000"}},
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(file.AddTableStyle(TableStyle{Name: "Pivot", Pivot: true}), IsNil)
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	styles := parts["xl/styles.xml"]
+	c.Assert(styles, Matches, `(?s).*<dxfs count="3"><dxf><font><color rgb="FFFFFFFF"/><b/></font><fill><patternFill patternType="solid"><fgColor rgb="FF4472C4"/><bgColor rgb="FF4472C4"/></patternFill></fill><border><bottom style="medium"><color rgb="FF000000"/></bottom></border></dxf>.*`)
+	c.Assert(styles, Matches, `(?s).*<numFmt numFmtId="164" formatCode="0.000"/></dxf></dxfs>.*`)
+	c.Assert(styles, Matches, `(?s).*<tableStyles count="2" defaultTableStyle="Report" defaultPivotStyle="PivotStyleLight16"><tableStyle name="Report" pivot="false" count="3"><tableStyleElement type="headerRow" dxfId="0"></tableStyleElement>.*<tableStyleElement type="firstRowStripe" size="2" dxfId="2"></tableStyleElement></tableStyle><tableStyle name="Pivot" table="false" count="0"></tableStyle></tableStyles></styleSheet>`)
+
+	written, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	c.Assert(written.DefaultTableStyle, Equals, "Report")
+	c.Assert(written.DefaultPivotStyle, Equals, "PivotStyleLight16")
+	read := written.TableStyles()
+	c.Assert(read, HasLen, 2)
+	c.Assert(read[0].Name, Equals, "Report")
+	c.Assert(read[0].Pivot, Equals, false)
+	c.Assert(read[0].Elements, HasLen, 3)
+	c.Assert(read[0].Elements[0].Type, Equals, TableStyleHeaderRow)
+	c.Assert(*read[0].Elements[0].Style.Font, Equals, Font{Bold: true, Color: "FFFFFFFF"})
+	c.Assert(*read[0].Elements[0].Style.Fill, Equals, Fill{PatternType: "solid", FgColor: "FF4472C4", BgColor: "FF4472C4"})
+	c.Assert(*read[0].Elements[0].Style.Border, Equals, Border{Bottom: "medium", BottomColor: "FF000000"})
+	c.Assert(read[0].Elements[1].Style.Font.Italic, Equals, true)
+	c.Assert(read[0].Elements[2].Size, Equals, 2)
+	c.Assert(read[0].Elements[2].Style.NumFmt, Equals, "0.000")
+	c.Assert(read[1].Pivot, Equals, true)
+
+	// Writing the file again keeps the styles.
+	parts, err = written.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/styles.xml"], Matches, `(?s).*<tableStyles count="2".*`)
+}
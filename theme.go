@@ -1,10 +1,5 @@
 package xlsx
 
-import (
-	"fmt"
-	"strconv"
-)
-
 type theme struct {
 	colors []string
 }
@@ -28,20 +23,8 @@ func newTheme(themeXml xlsxTheme) *theme {
 }
 
 func (t *theme) themeColor(index int64, tint float64) string {
-	baseColor := t.colors[index]
-	if tint == 0 {
-		return "FF" + baseColor
-	} else {
-		r, _ := strconv.ParseInt(baseColor[0:2], 16, 64)
-		g, _ := strconv.ParseInt(baseColor[2:4], 16, 64)
-		b, _ := strconv.ParseInt(baseColor[4:6], 16, 64)
-		h, s, l := RGBToHSL(uint8(r), uint8(g), uint8(b))
-		if tint < 0 {
-			l *= (1 + tint)
-		} else {
-			l = l*(1-tint) + (1 - (1 - tint))
-		}
-		br, bg, bb := HSLToRGB(h, s, l)
-		return fmt.Sprintf("FF%02X%02X%02X", br, bg, bb)
+	if index < 0 || index >= int64(len(t.colors)) || len(t.colors[index]) != 6 {
+		return ""
 	}
+	return applyTint("FF"+t.colors[index], tint)
 }
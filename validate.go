@@ -0,0 +1,271 @@
+package xlsx
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Diagnostic describes a problem found by File.Validate.
+type Diagnostic struct {
+	// Sheet is the name of the Sheet the problem is in, if any.
+	Sheet string
+	// Cell is the cell the problem is in, in A1 notation, if any.
+	Cell    string
+	Message string
+}
+
+// String returns the Diagnostic with the location of the problem,
+// e.g. "Sheet1!B3: unbalanced parentheses in formula 'SUM(A1'".
+func (d Diagnostic) String() string {
+	switch {
+	case d.Sheet != "" && d.Cell != "":
+		return fmt.Sprintf("%s!%s: %s", quoteSheetName(d.Sheet), d.Cell, d.Message)
+	case d.Sheet != "":
+		return fmt.Sprintf("%s: %s", quoteSheetName(d.Sheet), d.Message)
+	}
+	return d.Message
+}
+
+// The characters Excel doesn't allow in sheet names.
+const illegalSheetNameChars = `:\/?*[]`
+
+// Validate checks the File for problems that would make Excel refuse
+// to open it, or offer to repair it, once written: bad or duplicate
+// sheet names, every sheet being hidden, bad or duplicate defined
+// names and ones that refer to sheets that don't exist, sheets too
+// large for a worksheet, merged cells that overlap
+// or run off the worksheet, and formulas that don't parse or that
+// refer to cells off the worksheet.
+// It returns a Diagnostic for each problem found, or nil if there are
+// none.
+func (f *File) Validate() []Diagnostic {
+	var diagnostics []Diagnostic
+	report := func(sheet, cell, format string, args ...interface{}) {
+		diagnostics = append(diagnostics, Diagnostic{Sheet: sheet, Cell: cell, Message: fmt.Sprintf(format, args...)})
+	}
+
+	names := make(map[string]string, len(f.Sheets))
+	for _, sheet := range f.Sheets {
+		if problem := sheetNameProblem(sheet.Name); problem != "" {
+			report(sheet.Name, "", "%s", problem)
+		}
+		key := strings.ToLower(sheet.Name)
+		if other, ok := names[key]; ok {
+			report(sheet.Name, "", "sheet name is a duplicate of '%s'", other)
+		} else {
+			names[key] = sheet.Name
+		}
+	}
+	if len(f.Sheets) > 0 && firstVisibleSheet(f.Sheets) < 0 {
+		report("", "", "every sheet is hidden, but a workbook needs at least one visible sheet")
+	}
+
+	definedNames := make(map[string]bool, len(f.Names))
+	for _, dn := range f.Names {
+		if dn == nil {
+			continue
+		}
+		if problem := dn.problem(); problem != "" {
+			report(dn.Sheet, "", "%s", problem)
+		}
+		if _, ok := names[strings.ToLower(dn.Sheet)]; dn.Sheet != "" && !ok {
+			report("", "", "defined name '%s' is local to sheet '%s', which doesn't exist", dn.Name, dn.Sheet)
+		} else if problem := dn.referenceProblem(names); problem != "" {
+			report(dn.Sheet, "", "%s", problem)
+		}
+		key := strings.ToLower(dn.Sheet) + "!" + strings.ToLower(dn.Name)
+		if definedNames[key] {
+			report(dn.Sheet, "", "defined name '%s' is defined more than once", dn.Name)
+		}
+		definedNames[key] = true
+	}
+
+	for _, sheet := range f.Sheets {
+		if err := sheet.Load(); err != nil {
+			report(sheet.Name, "", "%s", err)
+			continue
+		}
+		diagnostics = append(diagnostics, sheet.validate(f.OverflowStrategy)...)
+	}
+	return diagnostics
+}
+
+// validate checks the contents of the Sheet.
+func (s *Sheet) validate(overflow OverflowStrategy) []Diagnostic {
+	var diagnostics []Diagnostic
+	report := func(cell, format string, args ...interface{}) {
+		diagnostics = append(diagnostics, Diagnostic{Sheet: s.Name, Cell: cell, Message: fmt.Sprintf(format, args...)})
+	}
+
+	rows, cols := s.extent()
+	if overflow == OverflowError && (rows > SheetRowLimit || cols > SheetColLimit) {
+		report("", "sheet has %d rows and %d columns, more than a worksheet can hold", rows, cols)
+	}
+
+	merged := make(map[[2]int]string)
+	for r, row := range s.Rows {
+		if row == nil {
+			continue
+		}
+		for c, cell := range row.Cells {
+			if cell == nil {
+				continue
+			}
+			ref := getCellIDStringFromCoords(c, r)
+			if cell.HMerge != 0 || cell.VMerge != 0 {
+				endCol, endRow := c+cell.HMerge, r+cell.VMerge
+				switch {
+				case cell.HMerge < 0 || cell.VMerge < 0:
+					report(ref, "merged cells can't extend up or to the left")
+				case endCol >= SheetColLimit || endRow >= SheetRowLimit:
+					report(ref, "merged cells run off the worksheet")
+				default:
+				overlap:
+					for y := r; y <= endRow; y++ {
+						for x := c; x <= endCol; x++ {
+							if other, ok := merged[[2]int{y, x}]; ok {
+								report(ref, "merged cells overlap those merged at %s", other)
+								break overlap
+							}
+							merged[[2]int{y, x}] = ref
+						}
+					}
+				}
+			}
+			if cell.formula != "" {
+				if problem := formulaProblem(cell.formula); problem != "" {
+					report(ref, "%s", problem)
+				}
+			}
+		}
+	}
+	return diagnostics
+}
+
+// sheetNameProblem describes what is wrong with a sheet name, or
+// returns "" if nothing is.
+func sheetNameProblem(name string) string {
+	switch {
+	case name == "":
+		return "sheet name is empty"
+	case utf8.RuneCountInString(name) > sheetNameLimit:
+		return fmt.Sprintf("sheet name is longer than %d characters", sheetNameLimit)
+	case strings.ContainsAny(name, illegalSheetNameChars):
+		return fmt.Sprintf("sheet name contains one of the characters %s", illegalSheetNameChars)
+	case strings.HasPrefix(name, "'") || strings.HasSuffix(name, "'"):
+		return "sheet name starts or ends with an apostrophe"
+	case strings.EqualFold(name, "History"):
+		return "sheet name 'History' is reserved"
+	}
+	return ""
+}
+
+// definedNameProblem describes what is wrong with the name of a
+// defined name, or returns "" if nothing is.
+func definedNameProblem(name string) string {
+	if name == "" {
+		return "defined name is empty"
+	}
+	first := name[0]
+	if !(first == '_' || first == '\\' || first >= 0x80 ||
+		('A' <= first && first <= 'Z') || ('a' <= first && first <= 'z')) {
+		return fmt.Sprintf("defined name '%s' must start with a letter, '_' or '\\'", name)
+	}
+	for i := 0; i < len(name); i++ {
+		if b := name[i]; !isNameByte(b) && b != '\\' && b < 0x80 {
+			return fmt.Sprintf("defined name '%s' contains the character '%c'", name, b)
+		}
+	}
+	if looksLikeCellRef(name) {
+		return fmt.Sprintf("defined name '%s' looks like a cell reference", name)
+	}
+	if upper := strings.ToUpper(name); upper == "R" || upper == "C" {
+		return fmt.Sprintf("defined name '%s' is reserved", name)
+	}
+	return ""
+}
+
+// looksLikeCellRef reports whether token has the shape of an A1 cell
+// reference - up to three letters followed by digits - whether or not
+// it lies on the worksheet.
+func looksLikeCellRef(token string) bool {
+	token = strings.Replace(token, "$", "", -1)
+	i := 0
+	for i < len(token) && i < 4 && (('A' <= token[i] && token[i] <= 'Z') || ('a' <= token[i] && token[i] <= 'z')) {
+		i++
+	}
+	if i == 0 || i > 3 || i == len(token) {
+		return false
+	}
+	for _, b := range []byte(token[i:]) {
+		if b < '0' || b > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// formulaProblem describes what is wrong with a formula, or returns
+// "" if nothing obvious is.  It isn't a full parser: it checks that
+// strings and quoted sheet names are closed, that parentheses
+// balance, that the formula doesn't end with an operator, and that
+// cell references lie on the worksheet.
+func formulaProblem(formula string) string {
+	trimmed := strings.TrimSpace(formula)
+	if strings.HasPrefix(trimmed, "=") {
+		return fmt.Sprintf("formula '%s' starts with '=', which isn't stored in the file", formula)
+	}
+	depth := 0
+	var quote byte
+	for i := 0; i < len(formula); {
+		c := formula[i]
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			i++
+			continue
+		}
+		switch {
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth < 0 {
+				return fmt.Sprintf("unbalanced parentheses in formula '%s'", formula)
+			}
+		}
+		isStart := c == '$' || ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z')
+		if !isStart || (i > 0 && isNameByte(formula[i-1])) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(formula) && (isNameByte(formula[j]) || formula[j] == '$') {
+			j++
+		}
+		token := formula[i:j]
+		if j < len(formula) && (formula[j] == '(' || formula[j] == '!') {
+			i = j
+			continue
+		}
+		if _, err := ParseCellRef(token); err != nil && looksLikeCellRef(token) {
+			return fmt.Sprintf("reference %s in formula '%s' is outside the worksheet", token, formula)
+		}
+		i = j
+	}
+	switch {
+	case quote == '"':
+		return fmt.Sprintf("unterminated string in formula '%s'", formula)
+	case quote == '\'':
+		return fmt.Sprintf("unterminated quoted name in formula '%s'", formula)
+	case depth != 0:
+		return fmt.Sprintf("unbalanced parentheses in formula '%s'", formula)
+	case trimmed != "" && strings.ContainsRune("+-*/^&=<>,(", rune(trimmed[len(trimmed)-1])):
+		return fmt.Sprintf("formula '%s' ends with an operator", formula)
+	}
+	return ""
+}
@@ -0,0 +1,94 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type ValidateSuite struct{}
+
+var _ = Suite(&ValidateSuite{})
+
+func diagnosticStrings(diagnostics []Diagnostic) []string {
+	var s []string
+	for _, d := range diagnostics {
+		s = append(s, d.String())
+	}
+	return s
+}
+
+func (v *ValidateSuite) TestValidFile(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet 1")
+	row := sheet.AddRow()
+	row.AddCell().SetInt(1)
+	row.AddCell().SetFormula(`SUM(A1:A3)+'Sheet 1'!A1+LOG10(A1)&"(unbalanced"`)
+	row.AddCell().Merge(2, 1)
+	file.Names = append(file.Names,
+		&DefinedName{Name: "Total", RefersTo: "'Sheet 1'!$A$1"},
+		&DefinedName{Name: "_xlnm.Print_Area", Sheet: "Sheet 1", RefersTo: "'Sheet 1'!$A$1:$B$2"})
+	c.Assert(file.Validate(), IsNil)
+}
+
+func (v *ValidateSuite) TestSheetNames(c *C) {
+	file := NewFile()
+	for _, name := range []string{"Data", "DATA", "A/B", "'quoted'", "History", "a name far too long for a worksheet"} {
+		file.AddSheet(name)
+	}
+	file.AddSheet("")
+	c.Assert(diagnosticStrings(file.Validate()), DeepEquals, []string{
+		"DATA: sheet name is a duplicate of 'Data'",
+		`'A/B': sheet name contains one of the characters :\/?*[]`,
+		"'''quoted''': sheet name starts or ends with an apostrophe",
+		"History: sheet name 'History' is reserved",
+		"'a name far too long for a worksheet': sheet name is longer than 31 characters",
+		"sheet name is empty",
+	})
+}
+
+func (v *ValidateSuite) TestDefinedNames(c *C) {
+	file := NewFile()
+	file.AddSheet("Sheet1")
+	for _, name := range []string{"Rate", "rate", "1st", "AB12", "has space", "R"} {
+		file.Names = append(file.Names, &DefinedName{Name: name, RefersTo: "Sheet1!$A$1"})
+	}
+	file.Names = append(file.Names, &DefinedName{Name: "Rate", Sheet: "Sheet1", RefersTo: "Sheet1!$A$1"})
+	c.Assert(diagnosticStrings(file.Validate()), DeepEquals, []string{
+		"defined name 'rate' is defined more than once",
+		`defined name '1st' must start with a letter, '_' or '\'`,
+		"defined name 'AB12' looks like a cell reference",
+		"defined name 'has space' contains the character ' '",
+		"defined name 'R' is reserved",
+	})
+}
+
+func (v *ValidateSuite) TestCells(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	sheet.Cell(0, 0).Merge(1, 1)
+	sheet.Cell(1, 1).Merge(1, 0)
+	sheet.Cell(2, 0).Merge(SheetColLimit, 0)
+	for _, formula := range []string{
+		"SUM(A1",
+		"A1)",
+		`"abc`,
+		"'Sheet1!A1",
+		"A1+",
+		"=A1",
+		"XFE1+A1",
+		"A1048577",
+	} {
+		sheet.AddRow().AddCell().SetFormula(formula)
+	}
+	c.Assert(diagnosticStrings(file.Validate()), DeepEquals, []string{
+		"Sheet1!B2: merged cells overlap those merged at A1",
+		"Sheet1!A3: merged cells run off the worksheet",
+		"Sheet1!A4: unbalanced parentheses in formula 'SUM(A1'",
+		"Sheet1!A5: unbalanced parentheses in formula 'A1)'",
+		`Sheet1!A6: unterminated string in formula '"abc'`,
+		"Sheet1!A7: unterminated quoted name in formula ''Sheet1!A1'",
+		"Sheet1!A8: formula 'A1+' ends with an operator",
+		"Sheet1!A9: formula '=A1' starts with '=', which isn't stored in the file",
+		"Sheet1!A10: reference XFE1 in formula 'XFE1+A1' is outside the worksheet",
+		"Sheet1!A11: reference A1048577 in formula 'A1048577' is outside the worksheet",
+	})
+}
@@ -0,0 +1,28 @@
+package xlsx
+
+// ValuesOnly makes opening a File read each cell with a formula as
+// the result cached when the file was last calculated, without the
+// formula, as tools that only want the data shown do.  A cell whose
+// formula has no cached result is empty, and writing the File back
+// writes the results as plain values.  By default formulas are kept.
+func ValuesOnly() FileOption {
+	return func(f *File) {
+		f.valuesOnly = true
+	}
+}
+
+// dropFormula leaves a cell read with just the cached result of its
+// formula, as a value of the type of the result.
+func (c *Cell) dropFormula() {
+	if c.formula == "" {
+		return
+	}
+	c.formula = ""
+	if c.cellType == CellTypeFormula {
+		c.cellType = c.FormulaResultType()
+		if c.Value == "" {
+			c.cellType = CellTypeString
+		}
+	}
+	c.resultType = ""
+}
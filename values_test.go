@@ -0,0 +1,76 @@
+package xlsx
+
+import (
+	"bytes"
+
+	. "gopkg.in/check.v1"
+)
+
+type ValuesSuite struct{}
+
+var _ = Suite(&ValuesSuite{})
+
+func makeFormulaParts() map[string]string {
+	return makeSheetParts(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+		`<row r="1"><c r="A1"><v>2</v></c><c r="B1"><f t="shared" ref="B1:B2" si="0">A1*2</f><v>4</v></c>` +
+		`<c r="C1" t="str"><f>"x"&amp;A1</f><v>x2</v></c><c r="D1" t="b"><f>A1&gt;1</f><v>1</v></c>` +
+		`<c r="E1" t="e"><f>1/0</f><v>#DIV/0!</v></c><c r="F1"><f>A1+1</f></c></row>` +
+		`<row r="2"><c r="A2"><v>3</v></c><c r="B2"><f t="shared" si="0"/><v>6</v></c></row>` +
+		`</sheetData></worksheet>`)
+}
+
+func (s *ValuesSuite) TestValuesOnly(c *C) {
+	parts := makeFormulaParts()
+	f, err := readZipReader(makeZipReader(c, parts), nil)
+	c.Assert(err, IsNil)
+	c.Assert(f.Sheets[0].Cell(1, 1).Formula(), Equals, "A2*2")
+
+	f, err = readZipReader(makeZipReader(c, parts), []FileOption{ValuesOnly()})
+	c.Assert(err, IsNil)
+	sheet := f.Sheets[0]
+	for _, t := range []struct {
+		row, col int
+		value    string
+		typ      CellType
+	}{
+		{0, 1, "4", CellTypeNumeric},
+		{0, 2, "x2", CellTypeString},
+		{0, 3, "1", CellTypeBool},
+		{0, 4, "#DIV/0!", CellTypeError},
+		{0, 5, "", CellTypeString},
+		{1, 1, "6", CellTypeNumeric},
+	} {
+		cell := sheet.Cell(t.row, t.col)
+		c.Assert(cell.Formula(), Equals, "")
+		c.Assert(cell.Value, Equals, t.value)
+		c.Assert(cell.Type(), Equals, t.typ)
+	}
+
+	written, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(written["xl/worksheets/sheet1.xml"], Not(Matches), `(?s).*<f[ >].*`)
+}
+
+func (s *ValuesSuite) TestOpenWithOptions(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	for i := 0; i < 3; i++ {
+		row := sheet.AddRow()
+		row.AddCell().SetInt(i)
+		row.AddCell().SetFormulaWithValue("A1+1", i+1)
+	}
+	var b bytes.Buffer
+	c.Assert(f.Write(&b), IsNil)
+
+	read, err := OpenBinaryWithOptions(b.Bytes(), ValuesOnly(), RowLimit(2))
+	c.Assert(err, IsNil)
+	c.Assert(read.Sheets[0].MaxRow, Equals, 2)
+	c.Assert(read.Sheets[0].Cell(1, 1).Formula(), Equals, "")
+	c.Assert(read.Sheets[0].Cell(1, 1).Value, Equals, "2")
+
+	read, err = OpenReaderWithOptions(bytes.NewReader(b.Bytes()), Date1904System())
+	c.Assert(err, IsNil)
+	c.Assert(read.Date1904, Equals, true)
+	c.Assert(read.Sheets[0].Cell(1, 1).Formula(), Equals, "A1+1")
+}
@@ -0,0 +1,127 @@
+package xlsx
+
+import (
+	"strconv"
+)
+
+// WindowVisibility is whether a workbook's window is shown.
+type WindowVisibility string
+
+const (
+	// WindowVisible shows the window; it is the same as the empty
+	// WindowVisibility.
+	WindowVisible WindowVisibility = "visible"
+	// WindowHidden hides the window, as add-ins and workbooks of
+	// macros opened in the background do, so that users only see it
+	// if they unhide it.
+	WindowHidden WindowVisibility = "hidden"
+	// WindowVeryHidden hides the window so that only VBA can show it.
+	WindowVeryHidden WindowVisibility = "veryHidden"
+)
+
+// WindowSettings control the window Excel opens a workbook in.  The
+// zero value is the window this package has always written: one of
+// 16384 by 8192 twips at the top left of the screen, with scroll bars
+// and sheet tabs.  The Sheet shown first is the active one; see
+// File.SetActiveSheet.  A workbook read with more than one window
+// keeps the others as they were.
+type WindowSettings struct {
+	// XWindow and YWindow are the position of the window's top left
+	// corner, and Width and Height its size, in twips, twentieths of
+	// a point.  A zero size means 16384 by 8192.
+	XWindow int
+	YWindow int
+	Width   int
+	Height  int
+	// TabRatio is the part of the width of the bottom of the window
+	// given to the sheet tabs, rather than the horizontal scroll bar,
+	// in thousandths.  Zero means 204.
+	TabRatio int
+	// Minimized opens the window minimized.
+	Minimized bool
+	// Visibility is whether the window is shown.  The empty
+	// WindowVisibility means WindowVisible.
+	Visibility WindowVisibility
+	// HideHorizontalScroll, HideVerticalScroll and HideSheetTabs
+	// leave the scroll bars and the sheet tabs out of the window.
+	HideHorizontalScroll bool
+	HideVerticalScroll   bool
+	HideSheetTabs        bool
+	// UngroupFilterDates lists the dates in the menus of the
+	// workbook's AutoFilters one by one, rather than grouped by
+	// year, month and day.
+	UngroupFilterDates bool
+}
+
+// makeWorkbookView returns the workbookView element of the
+// WindowSettings, without the active tab and first sheet, which depend
+// on the sheets written.
+func (w WindowSettings) makeWorkbookView() xlsxWorkBookView {
+	horizontalScroll, verticalScroll, sheetTabs := !w.HideHorizontalScroll, !w.HideVerticalScroll, !w.HideSheetTabs
+	view := xlsxWorkBookView{
+		Minimized:            w.Minimized,
+		ShowHorizontalScroll: &horizontalScroll,
+		ShowVerticalScroll:   &verticalScroll,
+		ShowSheetTabs:        &sheetTabs,
+		TabRatio:             w.TabRatio,
+		WindowHeight:         w.Height,
+		WindowWidth:          w.Width,
+		XWindow:              strconv.Itoa(w.XWindow),
+		YWindow:              strconv.Itoa(w.YWindow),
+	}
+	if w.Visibility != WindowVisible {
+		view.Visibility = string(w.Visibility)
+	}
+	if w.UngroupFilterDates {
+		grouping := false
+		view.AutoFilterDateGrouping = &grouping
+	}
+	if view.TabRatio == 0 {
+		view.TabRatio = 204
+	}
+	if view.WindowWidth == 0 || view.WindowHeight == 0 {
+		view.WindowWidth, view.WindowHeight = 16384, 8192
+	}
+	return view
+}
+
+// readWindowSettings returns the WindowSettings of a workbookView
+// element, whose attributes left out have Excel's default values.
+func readWindowSettings(view xlsxWorkBookView) WindowSettings {
+	w := WindowSettings{
+		Width:                view.WindowWidth,
+		Height:               view.WindowHeight,
+		TabRatio:             view.TabRatio,
+		Minimized:            view.Minimized,
+		HideHorizontalScroll: view.ShowHorizontalScroll != nil && !*view.ShowHorizontalScroll,
+		HideVerticalScroll:   view.ShowVerticalScroll != nil && !*view.ShowVerticalScroll,
+		HideSheetTabs:        view.ShowSheetTabs != nil && !*view.ShowSheetTabs,
+		UngroupFilterDates:   view.AutoFilterDateGrouping != nil && !*view.AutoFilterDateGrouping,
+	}
+	if view.Visibility != string(WindowVisible) {
+		w.Visibility = WindowVisibility(view.Visibility)
+	}
+	if w.TabRatio == 0 {
+		w.TabRatio = 600
+	}
+	w.XWindow, _ = strconv.Atoi(view.XWindow)
+	w.YWindow, _ = strconv.Atoi(view.YWindow)
+	return w
+}
+
+// otherWorkbookViews returns the windows of the File, other than the
+// first, as they were read, with the active tab and first sheet of
+// each kept within the count sheets written.
+func (f *File) otherWorkbookViews(count int) []xlsxWorkBookView {
+	views := make([]xlsxWorkBookView, len(f.otherWindows))
+	for i, view := range f.otherWindows {
+		if view.ActiveTab >= count {
+			view.ActiveTab = 0
+		}
+		if view.FirstSheet >= count {
+			view.FirstSheet = 0
+		}
+		views[i] = view
+	}
+	return views
+}
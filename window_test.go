@@ -0,0 +1,61 @@
+package xlsx
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type WindowSuite struct{}
+
+var _ = Suite(&WindowSuite{})
+
+func (s *WindowSuite) TestDefault(c *C) {
+	f := NewFile()
+	_, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/workbook.xml"], Matches, `(?s).*<bookViews><workbookView showHorizontalScroll="true" showVerticalScroll="true" showSheetTabs="true" `+
+		`tabRatio="204" windowHeight="8192" windowWidth="16384" xWindow="0" yWindow="0"></workbookView></bookViews>.*`)
+}
+
+func (s *WindowSuite) TestRoundTrip(c *C) {
+	f := NewFile()
+	for _, name := range []string{"Sheet1", "Sheet2"} {
+		_, err := f.AddSheet(name)
+		c.Assert(err, IsNil)
+	}
+	c.Assert(f.SetActiveSheet("Sheet2"), IsNil)
+	f.Window = WindowSettings{XWindow: -120, YWindow: 480, Width: 28800, Height: 15600, TabRatio: 750,
+		Minimized: true, Visibility: WindowVeryHidden, HideVerticalScroll: true, HideSheetTabs: true, UngroupFilterDates: true}
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/workbook.xml"], Matches, `(?s).*<workbookView visibility="veryHidden" minimized="true" activeTab="1" showHorizontalScroll="true" showVerticalScroll="false" showSheetTabs="false" `+
+		`tabRatio="750" windowHeight="15600" windowWidth="28800" xWindow="-120" yWindow="480" autoFilterDateGrouping="false"></workbookView></bookViews>.*`)
+
+	written, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	c.Assert(written.Window, Equals, f.Window)
+	c.Assert(written.ActiveSheet().Name, Equals, "Sheet2")
+}
+
+func (s *WindowSuite) TestReadDefaults(c *C) {
+	parts := makeSheetParts(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData/></worksheet>`)
+	parts["xl/workbook.xml"] = strings.Replace(parts["xl/workbook.xml"], "<sheets>",
+		`<bookViews><workbookView visibility="hidden" windowWidth="20000" windowHeight="10000"/>`+
+			`<workbookView xWindow="600" yWindow="300" windowWidth="9000" windowHeight="7000" activeTab="3" autoFilterDateGrouping="0"/></bookViews><sheets>`, 1)
+	f, err := ReadZipReader(makeZipReader(c, parts))
+	c.Assert(err, IsNil)
+	// Attributes left out have Excel's defaults, rather than this
+	// package's.
+	c.Assert(f.Window, Equals, WindowSettings{Width: 20000, Height: 10000, TabRatio: 600, Visibility: WindowHidden})
+
+	// The second window is kept, with its active tab on a sheet the
+	// workbook has.
+	written, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(written["xl/workbook.xml"], Matches, `(?s).*<bookViews><workbookView visibility="hidden" [^>]*></workbookView>`+
+		`<workbookView windowHeight="7000" windowWidth="9000" xWindow="600" yWindow="300" autoFilterDateGrouping="false"></workbookView></bookViews>.*`)
+}
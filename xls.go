@@ -0,0 +1,547 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"unicode/utf16"
+)
+
+// The types of the records of BIFF8 workbook streams that are read, as
+// MS-XLS numbers them.
+const (
+	biffFormula    = 0x0006
+	biffEOF        = 0x000A
+	biffDateMode   = 0x0022
+	biffFilePass   = 0x002F
+	biffContinue   = 0x003C
+	biffColInfo    = 0x007D
+	biffBoundSheet = 0x0085
+	biffMulRK      = 0x00BD
+	biffMulBlank   = 0x00BE
+	biffXF         = 0x00E0
+	biffMergeCells = 0x00E5
+	biffSST        = 0x00FC
+	biffLabelSST   = 0x00FD
+	biffBlank      = 0x0201
+	biffNumber     = 0x0203
+	biffLabel      = 0x0204
+	biffBoolErr    = 0x0205
+	biffString     = 0x0207
+	biffRow        = 0x0208
+	biffArray      = 0x0221
+	biffTable      = 0x0236
+	biffRK         = 0x027E
+	biffFormat     = 0x041E
+	biffShrFmla    = 0x04BC
+	biffBOF        = 0x0809
+)
+
+// isCompoundFile reports whether r reads an OLE2 compound document,
+// such as a legacy XLS file, rather than a zip package.
+func isCompoundFile(r io.ReaderAt) bool {
+	signature := make([]byte, len(oleSignature))
+	_, err := r.ReadAt(signature, 0)
+	return err == nil && bytes.Equal(signature, oleSignature)
+}
+
+// openCompoundFile opens the file called filename, which couldn't be
+// opened as a zip package for zipErr, as a legacy XLS file if it is
+// an OLE2 compound document, or returns zipErr.
+func openCompoundFile(filename string, options []FileOption, zipErr error) (*File, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, zipErr
+	}
+	defer f.Close()
+	if !isCompoundFile(f) {
+		return nil, zipErr
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return readXLS(f, info.Size(), options)
+}
+
+// readXLS reads the legacy XLS workbook of the given size that r reads
+// into a File configured with the given options.  Only workbooks in
+// BIFF8, the format of Excel 97 to 2003, can be read, and of those the
+// values of cells, with their number formats, the rows' heights, the
+// columns' widths, merged cells and which sheets are hidden.  As with
+// XLSB files, cells with formulas hold just their values.
+func readXLS(r io.ReaderAt, size int64, options []FileOption) (*File, error) {
+	cf, err := readCompoundFile(r, size)
+	if err != nil {
+		return nil, err
+	}
+	stream, ok, err := cf.stream("Workbook")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if _, ok, _ := cf.stream("Book"); ok {
+			return nil, fmt.Errorf("only XLS files of Excel 97 and later can be read")
+		}
+		if _, ok, _ := cf.stream("EncryptionInfo"); ok {
+			return nil, fmt.Errorf("the workbook is password protected")
+		}
+		return nil, fmt.Errorf("no workbook found in compound document")
+	}
+
+	file := NewFile()
+	for _, option := range options {
+		option(file)
+	}
+	type boundSheet struct {
+		name   string
+		offset int
+		state  byte
+		kind   byte
+	}
+	var sheets []boundSheet
+	var sst []string
+	formats := make(map[int]string)
+	var xfs []int
+	records := &biffRecords{data: stream}
+	if typ, data := records.next(); typ != biffBOF || data.u16() != 0x0600 {
+		return nil, fmt.Errorf("only XLS files of Excel 97 and later can be read")
+	}
+	for typ, data := records.next(); typ != biffEOF && typ >= 0; typ, data = records.next() {
+		switch typ {
+		case biffFilePass:
+			return nil, fmt.Errorf("the workbook is password protected")
+		case biffDateMode:
+			file.storedDate1904 = data.u16() == 1
+		case biffBoundSheet:
+			var b boundSheet
+			b.offset = int(data.u32())
+			b.state = data.u8() & 3
+			b.kind = data.u8()
+			b.name = data.unicodeString(int(data.u8()))
+			sheets = append(sheets, b)
+		case biffFormat:
+			id := int(data.u16())
+			formats[id] = data.unicodeString(int(data.u16()))
+		case biffXF:
+			data.u16() // ifnt
+			xfs = append(xfs, int(data.u16()))
+		case biffSST:
+			data.u32() // cstTotal
+			unique := int(data.u32())
+			for i := 0; i < unique && !data.empty(); i++ {
+				sst = append(sst, data.unicodeString(int(data.u16())))
+			}
+		}
+	}
+	numFmts := make([]string, len(xfs))
+	for i, id := range xfs {
+		if code, ok := formats[id]; ok {
+			numFmts[i] = code
+		} else {
+			numFmts[i] = builtInNumFmt[id]
+		}
+	}
+	if !file.defaults.dateSystem {
+		file.Date1904 = file.storedDate1904
+	}
+
+	for _, b := range sheets {
+		if b.kind == 6 {
+			// Visual Basic modules aren't sheets.
+			continue
+		}
+		sheet, err := file.AddSheet(b.name)
+		if err != nil {
+			return nil, err
+		}
+		sheet.Hidden = b.state == 1
+		sheet.VeryHidden = b.state == 2
+		if b.kind != 0 {
+			// Chart sheets and macro sheets are read as empty
+			// worksheets.
+			continue
+		}
+		if b.offset < 0 || b.offset >= len(stream) {
+			return nil, fmt.Errorf("sheet '%s' is past the end of the workbook", b.name)
+		}
+		file.readXLSSheet(sheet, &biffRecords{data: stream, pos: b.offset}, sst, numFmts)
+		if file.Date1904 != file.storedDate1904 {
+			sheet.convertDates(file.storedDate1904, file.Date1904)
+		}
+	}
+	if len(file.Sheets) == 0 {
+		return nil, &XLSXReaderError{Err: "No sheets found in XLSX File"}
+	}
+	return file, nil
+}
+
+// peekXLS returns the metadata of the compound document of the given
+// size that r reads, which is either a password protected workbook or
+// a legacy XLS file, whose sheets are read from the globals of its
+// Workbook stream.
+func peekXLS(r io.ReaderAt, size int64) (*WorkbookInfo, error) {
+	cf, err := readCompoundFile(r, size)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok, _ := cf.stream("EncryptionInfo"); ok {
+		return &WorkbookInfo{Encrypted: true}, nil
+	}
+	stream, ok, err := cf.stream("Workbook")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("no workbook found in compound document")
+	}
+	records := &biffRecords{data: stream}
+	if typ, data := records.next(); typ != biffBOF || data.u16() != 0x0600 {
+		return nil, fmt.Errorf("only XLS files of Excel 97 and later can be read")
+	}
+	info := new(WorkbookInfo)
+	for typ, data := records.next(); typ != biffEOF && typ >= 0; typ, data = records.next() {
+		switch typ {
+		case biffFilePass:
+			return &WorkbookInfo{Encrypted: true}, nil
+		case biffDateMode:
+			info.Date1904 = data.u16() == 1
+		case biffBoundSheet:
+			data.u32() // lbPlyPos
+			state := data.u8() & 3
+			data.u8() // dt
+			name := data.unicodeString(int(data.u8()))
+			info.Sheets = append(info.Sheets, SheetInfo{Name: name, Hidden: state != 0})
+		}
+	}
+	return info, nil
+}
+
+// readXLSSheet reads the worksheet whose substream records reads into
+// sheet.
+func (f *File) readXLSSheet(sheet *Sheet, records *biffRecords, sst, numFmts []string) {
+	rowLimit, colLimit := f.readLimits()
+	cell := func(row, col, xf int) *Cell {
+		if row >= rowLimit || col >= colLimit {
+			return nil
+		}
+		c := sheet.Cell(row, col)
+		if xf >= 0 && xf < len(numFmts) {
+			c.NumFmt = numFmts[xf]
+		}
+		c.date1904 = f.storedDate1904
+		return c
+	}
+	setNumber := func(c *Cell, n float64) {
+		if c != nil {
+			c.Value = strconv.FormatFloat(n, 'f', -1, 64)
+			c.cellType = CellTypeNumeric
+		}
+	}
+	setString := func(c *Cell, s string) {
+		if c != nil {
+			c.Value = s
+			c.cellType = CellTypeString
+		}
+	}
+	// formula is the cell of the last formula read, whose string
+	// value is in the next record.
+	var formula *Cell
+	depth := 0
+	for typ, data := records.next(); typ >= 0; typ, data = records.next() {
+		switch typ {
+		case biffBOF:
+			// Embedded charts have substreams of their own.
+			depth++
+			continue
+		case biffEOF:
+			if depth--; depth == 0 {
+				return
+			}
+			continue
+		}
+		if depth != 1 {
+			continue
+		}
+		switch typ {
+		case biffRow:
+			row := int(data.u16())
+			data.skip(4) // colMic, colMac
+			height := data.u16() & 0x7FFF
+			data.skip(4)
+			flags := data.u32()
+			if row >= rowLimit {
+				continue
+			}
+			for len(sheet.Rows) <= row {
+				sheet.AddRow()
+			}
+			r := sheet.Rows[row]
+			r.OutlineLevel = uint8(flags & 7)
+			r.Hidden = flags&0x20 != 0
+			if flags&0x40 != 0 {
+				r.SetHeight(float64(height) / 20)
+			}
+		case biffColInfo:
+			first, last := int(data.u16()), int(data.u16())
+			width := data.u16()
+			data.u16() // ixfe
+			flags := data.u16()
+			if last >= colLimit {
+				last = colLimit - 1
+			}
+			if first > last {
+				continue
+			}
+			sheet.Cols.SetColWidth(first, last, float64(width)/256)
+			if flags&1 != 0 {
+				sheet.Cols.SetColHidden(first, last, true)
+			}
+		case biffMergeCells:
+			count := int(data.u16())
+			for i := 0; i < count && !data.empty(); i++ {
+				rowFirst, rowLast := int(data.u16()), int(data.u16())
+				colFirst, colLast := int(data.u16()), int(data.u16())
+				if c := cell(rowFirst, colFirst, -1); c != nil && rowLast >= rowFirst && colLast >= colFirst {
+					c.HMerge, c.VMerge = colLast-colFirst, rowLast-rowFirst
+				}
+			}
+		case biffBlank:
+			cell(int(data.u16()), int(data.u16()), int(data.u16()))
+		case biffMulBlank, biffMulRK:
+			row, col := int(data.u16()), int(data.u16())
+			for ; data.remaining() > 2; col++ {
+				c := cell(row, col, int(data.u16()))
+				if typ == biffMulRK {
+					setNumber(c, rkNumber(data.u32()))
+				}
+			}
+		case biffNumber:
+			setNumber(cell(int(data.u16()), int(data.u16()), int(data.u16())), data.f64())
+		case biffRK:
+			setNumber(cell(int(data.u16()), int(data.u16()), int(data.u16())), rkNumber(data.u32()))
+		case biffLabelSST:
+			c := cell(int(data.u16()), int(data.u16()), int(data.u16()))
+			if i := int(data.u32()); i < len(sst) {
+				setString(c, sst[i])
+			} else {
+				setString(c, "")
+			}
+		case biffLabel:
+			c := cell(int(data.u16()), int(data.u16()), int(data.u16()))
+			setString(c, data.unicodeString(int(data.u16())))
+		case biffBoolErr:
+			c := cell(int(data.u16()), int(data.u16()), int(data.u16()))
+			value, isError := data.u8(), data.u8()
+			setBoolOrError(c, value, isError != 0)
+		case biffFormula:
+			c := cell(int(data.u16()), int(data.u16()), int(data.u16()))
+			result := data.take(8)
+			if c == nil {
+				continue
+			}
+			if result[6] != 0xFF || result[7] != 0xFF {
+				setNumber(c, math.Float64frombits(binary.LittleEndian.Uint64(result)))
+				continue
+			}
+			switch result[0] {
+			case 0:
+				formula = c
+				setString(c, "")
+			case 1:
+				setBoolOrError(c, result[2], false)
+			case 2:
+				setBoolOrError(c, result[2], true)
+			default:
+				setString(c, "")
+			}
+			continue
+		case biffShrFmla, biffArray, biffTable:
+			// The formula these belong to may still be waiting
+			// for its string.
+			continue
+		case biffString:
+			if formula != nil {
+				formula.Value = data.unicodeString(int(data.u16()))
+			}
+		}
+		formula = nil
+	}
+}
+
+// setBoolOrError sets cell to the boolean or error value of a BIFF8
+// BoolErr.
+func setBoolOrError(cell *Cell, value byte, isError bool) {
+	if cell == nil {
+		return
+	}
+	if isError {
+		cell.Value = xlsbErrors[value]
+		cell.cellType = CellTypeError
+		return
+	}
+	cell.Value = "0"
+	if value != 0 {
+		cell.Value = "1"
+	}
+	cell.cellType = CellTypeBool
+}
+
+// biffRecords reads the records of a BIFF8 stream in turn.
+type biffRecords struct {
+	data []byte
+	pos  int
+}
+
+// next returns the type and the data of the next record, with the data
+// of any Continue records that follow it, or a type of -1 at the end.
+func (r *biffRecords) next() (int, *biffData) {
+	data := &biffData{}
+	typ := -1
+	for r.pos+4 <= len(r.data) {
+		recordType := int(binary.LittleEndian.Uint16(r.data[r.pos:]))
+		if typ >= 0 && recordType != biffContinue {
+			break
+		}
+		size := int(binary.LittleEndian.Uint16(r.data[r.pos+2:]))
+		start := r.pos + 4
+		end := start + size
+		if end > len(r.data) {
+			end = len(r.data)
+		}
+		r.pos = end
+		if typ < 0 {
+			typ = recordType
+			data.b = r.data[start:end]
+		} else {
+			data.continues = append(data.continues, r.data[start:end])
+		}
+	}
+	return typ, data
+}
+
+// biffData reads the fields of a record in turn, going on into the
+// Continue records that follow it.  Reading past the end gives zeroes.
+type biffData struct {
+	b         []byte
+	continues [][]byte
+}
+
+func (d *biffData) empty() bool {
+	return d.remaining() == 0
+}
+
+// remaining returns how many bytes are left to read.
+func (d *biffData) remaining() int {
+	n := len(d.b)
+	for _, b := range d.continues {
+		n += len(b)
+	}
+	return n
+}
+
+// advance moves on to the next Continue record once the current one is
+// read, and reports whether there was one.
+func (d *biffData) advance() bool {
+	if len(d.b) > 0 {
+		return true
+	}
+	if len(d.continues) == 0 {
+		return false
+	}
+	d.b, d.continues = d.continues[0], d.continues[1:]
+	return true
+}
+
+func (d *biffData) take(n int) []byte {
+	d.advance()
+	if len(d.b) >= n {
+		field := d.b[:n]
+		d.b = d.b[n:]
+		return field
+	}
+	field := make([]byte, 0, n)
+	for len(field) < n && d.advance() {
+		m := n - len(field)
+		if m > len(d.b) {
+			m = len(d.b)
+		}
+		field = append(field, d.b[:m]...)
+		d.b = d.b[m:]
+	}
+	return field[:n]
+}
+
+// skip passes over n bytes, or over all those left if there are fewer.
+func (d *biffData) skip(n int) {
+	for n > 0 && d.advance() {
+		m := n
+		if m > len(d.b) {
+			m = len(d.b)
+		}
+		d.b = d.b[m:]
+		n -= m
+	}
+}
+
+func (d *biffData) u8() uint8 {
+	return d.take(1)[0]
+}
+
+func (d *biffData) u16() uint16 {
+	return binary.LittleEndian.Uint16(d.take(2))
+}
+
+func (d *biffData) u32() uint32 {
+	return binary.LittleEndian.Uint32(d.take(4))
+}
+
+func (d *biffData) f64() float64 {
+	return math.Float64frombits(binary.LittleEndian.Uint64(d.take(8)))
+}
+
+// unicodeString reads the rest of a string of count characters, after
+// its count: its flags, then its characters, each of one byte or two.
+// Rich text runs and phonetic text are skipped.  A string split across
+// Continue records starts each with flags of its own.
+func (d *biffData) unicodeString(count int) string {
+	flags := d.u8()
+	runs, extra := 0, 0
+	if flags&0x08 != 0 {
+		runs = int(d.u16())
+	}
+	if flags&0x04 != 0 {
+		extra = int(d.u32())
+	}
+	units := make([]uint16, 0, count)
+	for len(units) < count {
+		if len(d.b) == 0 {
+			if len(d.continues) == 0 {
+				break
+			}
+			d.advance()
+			flags = d.u8()
+			// The Continue record may hold nothing but the
+			// flags.
+			continue
+		}
+		if flags&1 == 0 {
+			units = append(units, uint16(d.b[0]))
+			d.b = d.b[1:]
+		} else {
+			units = append(units, d.u16())
+		}
+	}
+	// The runs and phonetic text can't go on past the record, however
+	// long they say they are.
+	if n := 4*runs + extra; n < d.remaining() {
+		d.skip(n)
+	} else {
+		d.skip(d.remaining())
+	}
+	return string(utf16.Decode(units))
+}
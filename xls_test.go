@@ -0,0 +1,222 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+type XLSSuite struct{}
+
+var _ = Suite(&XLSSuite{})
+
+// biffRecord returns a record of a BIFF8 stream, of type typ, made from
+// fields.
+func biffRecord(typ int, fields ...interface{}) []byte {
+	var data bytes.Buffer
+	for _, field := range fields {
+		binary.Write(&data, binary.LittleEndian, field)
+	}
+	record := make([]byte, 4, 4+data.Len())
+	binary.LittleEndian.PutUint16(record, uint16(typ))
+	binary.LittleEndian.PutUint16(record[2:], uint16(data.Len()))
+	return append(record, data.Bytes()...)
+}
+
+// biffChars returns the flags and characters of a string, as one byte
+// each if they fit.
+func biffChars(s string) []byte {
+	wide := false
+	for _, r := range s {
+		wide = wide || r > 0xFF
+	}
+	if !wide {
+		chars := []byte{0}
+		for _, r := range s {
+			chars = append(chars, byte(r))
+		}
+		return chars
+	}
+	chars := []byte{1}
+	for _, r := range s {
+		chars = append(chars, byte(r), byte(r>>8))
+	}
+	return chars
+}
+
+// makeXLS returns an XLS file of a workbook with a sheet, "Data",
+// holding the records of sheet, a hidden sheet and a chart sheet.
+func makeXLS(sheet ...[]byte) []byte {
+	bof := func(dt uint16) []byte {
+		return biffRecord(biffBOF, uint16(0x0600), dt, uint16(0x0DBB), uint16(0x07CC), uint32(0), uint32(0x0206))
+	}
+	boundSheets := func(offsets []uint32) []byte {
+		var records []byte
+		for i, name := range []string{"Data", "Hidden", "Chart"} {
+			states := []uint8{0, 1, 0}
+			kinds := []uint8{0, 0, 2}
+			records = append(records, biffRecord(biffBoundSheet, offsets[i], states[i], kinds[i], uint8(len(name)), biffChars(name))...)
+		}
+		return records
+	}
+	// The second string of the shared string table is split across a
+	// Continue record, which goes on with two byte characters.
+	sst := biffRecord(biffSST, uint32(3), uint32(2),
+		uint16(4), biffChars("Name"),
+		uint16(6), uint8(0x08), uint16(1), []byte("En"))
+	sst = append(sst, biffRecord(biffContinue, uint8(1), []byte("t\x00r\x00\xe9\x00e\x00"), uint32(0))...)
+	globals := func(offsets []uint32) []byte {
+		records := bof(0x0005)
+		records = append(records, biffRecord(biffDateMode, uint16(0))...)
+		records = append(records, biffRecord(biffFormat, uint16(164), uint16(10), biffChars("yyyy-mm-dd"))...)
+		for _, numFmt := range []uint16{0, 164, 10} {
+			records = append(records, biffRecord(biffXF, uint16(0), numFmt, uint16(0), uint16(0), make([]byte, 14))...)
+		}
+		records = append(records, boundSheets(offsets)...)
+		records = append(records, sst...)
+		return append(records, biffRecord(biffEOF)...)
+	}
+
+	data := bof(0x0010)
+	for _, record := range sheet {
+		data = append(data, record...)
+	}
+	data = append(data, biffRecord(biffEOF)...)
+	hidden := append(bof(0x0010), biffRecord(biffEOF)...)
+	chart := append(bof(0x0020), biffRecord(biffEOF)...)
+
+	start := uint32(len(globals(make([]uint32, 3))))
+	offsets := []uint32{start, start + uint32(len(data)), start + uint32(len(data)+len(hidden))}
+	var stream []byte
+	for _, part := range [][]byte{globals(offsets), data, hidden, chart} {
+		stream = append(stream, part...)
+	}
+	return makeCompoundFile([]string{"Workbook"}, map[string][]byte{"Workbook": stream})
+}
+
+func (s *XLSSuite) TestReadXLS(c *C) {
+	cached := biffChars("cached")
+	xls := makeXLS(
+		biffRecord(biffColInfo, uint16(0), uint16(1), uint16(20*256), uint16(15), uint16(0), uint16(0)),
+		biffRecord(biffColInfo, uint16(3), uint16(3), uint16(10*256), uint16(15), uint16(1), uint16(0)),
+		biffRecord(biffRow, uint16(0), uint16(0), uint16(3), uint16(600), uint16(0), uint16(0), uint32(0x40|15<<16)),
+		biffRecord(biffRow, uint16(1), uint16(0), uint16(4), uint16(300), uint16(0), uint16(0), uint32(0x20|15<<16)),
+		biffRecord(biffLabelSST, uint16(0), uint16(0), uint16(0), uint32(0)),
+		biffRecord(biffLabelSST, uint16(0), uint16(1), uint16(0), uint32(1)),
+		biffRecord(biffBoolErr, uint16(0), uint16(2), uint16(0), uint8(1), uint8(0)),
+		biffRecord(biffRK, uint16(1), uint16(0), uint16(0), uint32(42<<2|2)),
+		biffRecord(biffMulRK, uint16(1), uint16(1), uint16(2), uint32(1234<<2|3), uint16(1), uint32(math.Float64bits(45352)>>32), uint16(2)),
+		biffRecord(biffLabel, uint16(1), uint16(3), uint16(0), uint16(6), biffChars("inline")),
+		biffRecord(biffFormula, uint16(3), uint16(0), uint16(0), 84.0, uint16(0), uint32(0), uint16(0)),
+		biffRecord(biffFormula, uint16(3), uint16(1), uint16(0), []byte{0, 0, 0, 0, 0, 0, 0xFF, 0xFF}, uint16(0), uint32(0), uint16(0)),
+		biffRecord(biffShrFmla, make([]byte, 10)),
+		biffRecord(biffString, uint16(6), cached),
+		biffRecord(biffFormula, uint16(3), uint16(2), uint16(0), []byte{2, 0, 0x07, 0, 0, 0, 0xFF, 0xFF}, uint16(0), uint32(0), uint16(0)),
+		biffRecord(biffNumber, uint16(3), uint16(3), uint16(1), 45352.5),
+		biffRecord(biffMergeCells, uint16(1), uint16(4), uint16(5), uint16(0), uint16(2)),
+		// An embedded chart, whose records aren't the sheet's.
+		biffRecord(biffBOF, uint16(0x0600), uint16(0x0020), uint16(0), uint16(0), uint32(0), uint32(0)),
+		biffRecord(biffNumber, uint16(0), uint16(0), uint16(0), 1.0),
+		biffRecord(biffEOF),
+		biffRecord(biffNumber, uint16(3), uint16(4), uint16(0), 2.0),
+	)
+	f, err := OpenBinary(xls)
+	c.Assert(err, IsNil)
+	c.Assert(f.Sheets, HasLen, 3)
+	c.Assert(f.Sheets[1].Name, Equals, "Hidden")
+	c.Assert(f.Sheets[1].Hidden, Equals, true)
+	c.Assert(f.Sheets[2].Name, Equals, "Chart")
+	sheet := f.Sheet["Data"]
+	c.Assert(sheet, NotNil)
+
+	c.Assert(sheet.Cell(0, 0).Value, Equals, "Name")
+	c.Assert(sheet.Cell(0, 1).Value, Equals, "Entrée")
+	c.Assert(sheet.Cell(0, 1).Type(), Equals, CellTypeString)
+	c.Assert(sheet.Cell(0, 2).Type(), Equals, CellTypeBool)
+	c.Assert(sheet.Cell(0, 2).Bool(), Equals, true)
+	c.Assert(sheet.Rows[0].Height, Equals, 30.0)
+	c.Assert(sheet.Rows[0].HasCustomHeight(), Equals, true)
+
+	c.Assert(sheet.Rows[1].Hidden, Equals, true)
+	c.Assert(sheet.Cell(1, 0).Value, Equals, "42")
+	c.Assert(sheet.Cell(1, 0).Type(), Equals, CellTypeNumeric)
+	c.Assert(sheet.Cell(1, 1).Value, Equals, "12.34")
+	c.Assert(sheet.Cell(1, 1).NumFmt, Equals, "0.00%")
+	c.Assert(sheet.Cell(1, 2).Value, Equals, "45352")
+	c.Assert(sheet.Cell(1, 2).NumFmt, Equals, "yyyy-mm-dd")
+	formatted, err := sheet.Cell(1, 2).FormattedValue()
+	c.Assert(err, IsNil)
+	c.Assert(formatted, Equals, "2024-03-01")
+	c.Assert(sheet.Cell(1, 3).Value, Equals, "inline")
+
+	// Cells with formulas hold the values they were last worked out
+	// to.
+	c.Assert(sheet.Cell(3, 0).Value, Equals, "84")
+	c.Assert(sheet.Cell(3, 0).Formula(), Equals, "")
+	c.Assert(sheet.Cell(3, 1).Value, Equals, "cached")
+	c.Assert(sheet.Cell(3, 1).Type(), Equals, CellTypeString)
+	c.Assert(sheet.Cell(3, 2).Value, Equals, "#DIV/0!")
+	c.Assert(sheet.Cell(3, 2).Type(), Equals, CellTypeError)
+	c.Assert(sheet.Cell(3, 3).Value, Equals, "45352.5")
+	// The embedded chart's number isn't put in A1.
+	c.Assert(sheet.Cell(0, 0).Value, Equals, "Name")
+	c.Assert(sheet.Cell(3, 4).Value, Equals, "2")
+
+	c.Assert(sheet.Cell(4, 0).HMerge, Equals, 2)
+	c.Assert(sheet.Cell(4, 0).VMerge, Equals, 1)
+	c.Assert(sheet.Cols.FindCol(1).Width, Equals, 20.0)
+	c.Assert(sheet.Cols.FindCol(3).Hidden, Equals, true)
+}
+
+func (s *XLSSuite) TestOpenFile(c *C) {
+	dir, err := ioutil.TempDir("", "xls")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "legacy.xls")
+	c.Assert(ioutil.WriteFile(path, makeXLS(biffRecord(biffNumber, uint16(0), uint16(0), uint16(0), 1.5)), 0644), IsNil)
+
+	f, err := OpenFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(f.Sheet["Data"].Cell(0, 0).Value, Equals, "1.5")
+	f, err = OpenFileWithOptions(path, Date1904System())
+	c.Assert(err, IsNil)
+	c.Assert(f.Date1904, Equals, true)
+
+	// Files that are neither zip packages nor XLS files fail as they
+	// always have.
+	c.Assert(ioutil.WriteFile(path, []byte("not a workbook"), 0644), IsNil)
+	_, err = OpenFile(path)
+	c.Assert(err, ErrorMatches, "zip: not a valid zip file")
+}
+
+func (s *XLSSuite) TestUnicodeString(c *C) {
+	// A Continue record may hold only the flags of the rest of the
+	// string.
+	data := &biffData{b: []byte{0, 'a'}, continues: [][]byte{{0}, {0, 'b', 'c'}}}
+	c.Assert(data.unicodeString(3), Equals, "abc")
+	c.Assert(data.empty(), Equals, true)
+
+	// Rich text runs and phonetic text longer than the record are cut
+	// short at its end.
+	data = &biffData{b: []byte{0x0C, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 'a', 'b', 0, 0}, continues: [][]byte{make([]byte, 10)}}
+	c.Assert(data.unicodeString(2), Equals, "ab")
+	c.Assert(data.empty(), Equals, true)
+}
+
+func (s *XLSSuite) TestPasswordProtected(c *C) {
+	encrypted := makeCompoundFile([]string{"EncryptionInfo", "EncryptedPackage"}, map[string][]byte{
+		"EncryptionInfo":   make([]byte, 200),
+		"EncryptedPackage": make([]byte, 200),
+	})
+	_, err := OpenBinary(encrypted)
+	c.Assert(err, ErrorMatches, "the workbook is password protected")
+
+	legacy := makeCompoundFile([]string{"Book"}, map[string][]byte{"Book": biffRecord(biffBOF, uint16(0x0500))})
+	_, err = OpenBinary(legacy)
+	c.Assert(err, ErrorMatches, "only XLS files of Excel 97 and later can be read")
+}
@@ -0,0 +1,412 @@
+package xlsx
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"unicode/utf16"
+)
+
+// The types of the records of XLSB parts that are read, as MS-XLSB
+// numbers them.
+const (
+	brtRowHdr           = 0
+	brtCellBlank        = 1
+	brtCellRk           = 2
+	brtCellError        = 3
+	brtCellBool         = 4
+	brtCellReal         = 5
+	brtCellSt           = 6
+	brtCellIsst         = 7
+	brtFmlaString       = 8
+	brtFmlaNum          = 9
+	brtFmlaBool         = 10
+	brtFmlaError        = 11
+	brtSSTItem          = 19
+	brtFmt              = 44
+	brtXF               = 47
+	brtColInfo          = 60
+	brtWbProp           = 153
+	brtBundleSh         = 156
+	brtMergeCell        = 176
+	brtBeginCellXFs     = 617
+	brtEndCellXFs       = 618
+	brtBeginCellStyleXF = 626
+)
+
+// xlsbErrors are the values of error cells, by their codes in XLSB.
+var xlsbErrors = map[byte]string{
+	0x00: "#NULL!",
+	0x07: "#DIV/0!",
+	0x0F: "#VALUE!",
+	0x17: "#REF!",
+	0x1D: "#NAME?",
+	0x24: "#NUM!",
+	0x2A: "#N/A",
+	0x2B: "#GETTING_DATA",
+}
+
+// readXLSB reads the workbook of an XLSB package, whose parts file
+// holds, into file.  XLSB is the binary form of the same workbook an
+// XLSX package holds, and is read into the same File, Sheets and
+// Cells: the values of cells, with their number formats, the rows'
+// heights, the columns' widths, merged cells and which sheets are
+// hidden.  The formulas of cells can't be read, so cells with formulas
+// hold just their values, and the other styles of cells are left out.
+func readXLSB(file *File) (*File, error) {
+	rels, err := file.readRelationships("xl/workbook.bin")
+	if err != nil {
+		return nil, err
+	}
+	var sst []string
+	var numFmts []string
+	for _, rel := range rels {
+		switch rel.Type {
+		case relTypeSharedStrings:
+			if sst, err = file.readXLSBSharedStrings(rel.Target); err != nil {
+				return nil, err
+			}
+		case relTypeStyles:
+			if numFmts, err = file.readXLSBNumberFormats(rel.Target); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	type bundle struct {
+		name, relID string
+		state       uint32
+	}
+	var bundles []bundle
+	err = file.readXLSBPart("xl/workbook.bin", func(typ int, data *xlsbData) {
+		switch typ {
+		case brtWbProp:
+			file.storedDate1904 = data.u32()&1 != 0
+		case brtBundleSh:
+			var b bundle
+			b.state = data.u32()
+			data.u32() // iTabID
+			b.relID = data.nullableString()
+			b.name = data.string()
+			bundles = append(bundles, b)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !file.defaults.dateSystem {
+		file.Date1904 = file.storedDate1904
+	}
+	if len(bundles) == 0 {
+		return nil, &XLSXReaderError{Err: "No sheets found in XLSX File"}
+	}
+	for _, b := range bundles {
+		sheet, err := file.AddSheet(b.name)
+		if err != nil {
+			return nil, err
+		}
+		sheet.Hidden = b.state == 1
+		sheet.VeryHidden = b.state == 2
+		rel, ok := rels[b.relID]
+		if !ok || rel.Type != relTypeWorksheet {
+			// Chart sheets and dialog sheets are read as empty
+			// worksheets.
+			continue
+		}
+		if err := file.readXLSBSheet(sheet, rel.Target, sst, numFmts); err != nil {
+			return nil, fmt.Errorf("sheet '%s': %s", b.name, err)
+		}
+		if file.Date1904 != file.storedDate1904 {
+			sheet.convertDates(file.storedDate1904, file.Date1904)
+		}
+	}
+	return file, nil
+}
+
+// readXLSBSharedStrings returns the shared strings in the part called
+// name.
+func (f *File) readXLSBSharedStrings(name string) ([]string, error) {
+	var sst []string
+	err := f.readXLSBPart(name, func(typ int, data *xlsbData) {
+		if typ == brtSSTItem {
+			data.u8() // Whether the string is rich or phonetic.
+			sst = append(sst, data.string())
+		}
+	})
+	return sst, err
+}
+
+// readXLSBNumberFormats returns the number formats of the cell formats
+// in the style sheet called name, by the index of each format.
+func (f *File) readXLSBNumberFormats(name string) ([]string, error) {
+	custom := make(map[int]string)
+	var xfs []int
+	inCellXFs := false
+	err := f.readXLSBPart(name, func(typ int, data *xlsbData) {
+		switch typ {
+		case brtFmt:
+			id := int(data.u16())
+			custom[id] = data.string()
+		case brtBeginCellXFs:
+			inCellXFs = true
+		case brtEndCellXFs, brtBeginCellStyleXF:
+			inCellXFs = false
+		case brtXF:
+			if inCellXFs {
+				data.u16() // ixfeParent
+				xfs = append(xfs, int(data.u16()))
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	numFmts := make([]string, len(xfs))
+	for i, id := range xfs {
+		if code, ok := custom[id]; ok {
+			numFmts[i] = code
+		} else {
+			numFmts[i] = builtInNumFmt[id]
+		}
+	}
+	return numFmts, nil
+}
+
+// readXLSBSheet reads the worksheet in the part called name into sheet.
+func (f *File) readXLSBSheet(sheet *Sheet, name string, sst, numFmts []string) error {
+	rowLimit, colLimit := f.readLimits()
+	row := -1
+	return f.readXLSBPart(name, func(typ int, data *xlsbData) {
+		switch typ {
+		case brtRowHdr:
+			row = int(data.u32())
+			data.u32() // ixfe
+			height := data.u16()
+			data.u8()
+			flags := data.u8()
+			if row < 0 || row >= rowLimit {
+				row = -1
+				return
+			}
+			for len(sheet.Rows) <= row {
+				sheet.AddRow()
+			}
+			r := sheet.Rows[row]
+			r.OutlineLevel = flags & 7
+			r.Hidden = flags&0x10 != 0
+			if flags&0x20 != 0 {
+				r.SetHeight(float64(height) / 20)
+			}
+		case brtColInfo:
+			first, last := int(data.u32()), int(data.u32())
+			width := data.u32()
+			data.u32() // ixfe
+			flags := data.u16()
+			if first < 0 || last < first || last >= SheetColLimit {
+				return
+			}
+			if last >= colLimit {
+				last = colLimit - 1
+			}
+			if first > last {
+				return
+			}
+			sheet.Cols.SetColWidth(first, last, float64(width)/256)
+			if flags&1 != 0 {
+				sheet.Cols.SetColHidden(first, last, true)
+			}
+		case brtMergeCell:
+			rowFirst, rowLast := int(data.u32()), int(data.u32())
+			colFirst, colLast := int(data.u32()), int(data.u32())
+			if rowFirst < 0 || colFirst < 0 || rowLast >= SheetRowLimit || colLast >= SheetColLimit ||
+				rowFirst >= rowLimit || colFirst >= colLimit || rowLast < rowFirst || colLast < colFirst {
+				return
+			}
+			cell := sheet.Cell(rowFirst, colFirst)
+			cell.HMerge, cell.VMerge = colLast-colFirst, rowLast-rowFirst
+		case brtCellBlank, brtCellRk, brtCellError, brtCellBool, brtCellReal, brtCellSt, brtCellIsst,
+			brtFmlaString, brtFmlaNum, brtFmlaBool, brtFmlaError:
+			col := int(data.u32())
+			style := int(data.u32() & 0xFFFFFF)
+			if row < 0 || col < 0 || col >= colLimit {
+				return
+			}
+			cell := sheet.Cell(row, col)
+			if style < len(numFmts) {
+				cell.NumFmt = numFmts[style]
+			}
+			cell.date1904 = f.storedDate1904
+			readXLSBCell(typ, data, cell, sst)
+		}
+	})
+}
+
+// readXLSBCell sets the value of cell from the record of type typ.
+func readXLSBCell(typ int, data *xlsbData, cell *Cell, sst []string) {
+	switch typ {
+	case brtCellRk:
+		cell.Value = strconv.FormatFloat(rkNumber(data.u32()), 'f', -1, 64)
+		cell.cellType = CellTypeNumeric
+	case brtCellReal, brtFmlaNum:
+		cell.Value = strconv.FormatFloat(data.f64(), 'f', -1, 64)
+		cell.cellType = CellTypeNumeric
+	case brtCellSt, brtFmlaString:
+		cell.Value = data.string()
+		cell.cellType = CellTypeString
+	case brtCellIsst:
+		if i := int(data.u32()); i >= 0 && i < len(sst) {
+			cell.Value = sst[i]
+		}
+		cell.cellType = CellTypeString
+	case brtCellBool, brtFmlaBool:
+		cell.Value = "0"
+		if data.u8() != 0 {
+			cell.Value = "1"
+		}
+		cell.cellType = CellTypeBool
+	case brtCellError, brtFmlaError:
+		cell.Value = xlsbErrors[data.u8()]
+		cell.cellType = CellTypeError
+	}
+}
+
+// rkNumber returns the number an RK value, XLSB's compact form of
+// numbers, stands for: either a 30 bit integer or the top 30 bits of
+// a float64, either of which may be a hundredth of the number.
+func rkNumber(rk uint32) float64 {
+	var n float64
+	if rk&2 != 0 {
+		n = float64(int32(rk) >> 2)
+	} else {
+		n = math.Float64frombits(uint64(rk&^3) << 32)
+	}
+	if rk&1 != 0 {
+		n /= 100
+	}
+	return n
+}
+
+// readXLSBPart calls record with the type and data of each record of
+// the XLSB part called name, in order.
+func (f *File) readXLSBPart(name string, record func(typ int, data *xlsbData)) error {
+	part, ok := f.parts[name]
+	if !ok {
+		return fmt.Errorf("%s not found in input xlsb", name)
+	}
+	// The part is binary, so it is read as it is even when
+	// repairing, which only fixes XML.
+	rc, err := f.openRawPart(part)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	r := bufio.NewReader(rc)
+	var buf []byte
+	for {
+		typ, err := readXLSBNumber(r, 2)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+		size, err := readXLSBNumber(r, 4)
+		if err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+		// A record can't be longer than the part, which is
+		// checked before making room for it.
+		if uint64(size) > part.UncompressedSize64 {
+			return fmt.Errorf("%s: record %d is cut short", name, typ)
+		}
+		if cap(buf) < size {
+			buf = make([]byte, size)
+		}
+		buf = buf[:size]
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return fmt.Errorf("%s: record %d is cut short", name, typ)
+		}
+		record(typ, &xlsbData{b: buf})
+	}
+}
+
+// readXLSBNumber reads the type or the size of a record, which is
+// written in up to n bytes, seven bits to each.
+func readXLSBNumber(r io.ByteReader, n int) (int, error) {
+	var value int
+	for i := 0; i < n; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			if i > 0 && err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		value |= int(b&0x7F) << (7 * uint(i))
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	return value, nil
+}
+
+// xlsbData reads the fields of a record in turn.  Reading past the end
+// of the record gives zeroes, so that records shorter than expected
+// are read as far as they go.
+type xlsbData struct {
+	b []byte
+}
+
+func (d *xlsbData) take(n int) []byte {
+	if len(d.b) < n {
+		d.b = nil
+		return make([]byte, n)
+	}
+	field := d.b[:n]
+	d.b = d.b[n:]
+	return field
+}
+
+func (d *xlsbData) u8() uint8 {
+	return d.take(1)[0]
+}
+
+func (d *xlsbData) u16() uint16 {
+	return binary.LittleEndian.Uint16(d.take(2))
+}
+
+func (d *xlsbData) u32() uint32 {
+	return binary.LittleEndian.Uint32(d.take(4))
+}
+
+func (d *xlsbData) f64() float64 {
+	return math.Float64frombits(binary.LittleEndian.Uint64(d.take(8)))
+}
+
+// string reads an XLWideString: a count of UTF-16 code units, then the
+// code units.
+func (d *xlsbData) string() string {
+	n := d.u32()
+	if uint64(n)*2 > uint64(len(d.b)) {
+		d.b = nil
+		return ""
+	}
+	units := make([]uint16, n)
+	for i := range units {
+		units[i] = d.u16()
+	}
+	return string(utf16.Decode(units))
+}
+
+// nullableString reads an XLNullableWideString, which is an
+// XLWideString but for a count of 0xFFFFFFFF, which stands for none.
+func (d *xlsbData) nullableString() string {
+	if len(d.b) >= 4 && binary.LittleEndian.Uint32(d.b) == math.MaxUint32 {
+		d.take(4)
+		return ""
+	}
+	return d.string()
+}
@@ -0,0 +1,233 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"unicode/utf16"
+
+	. "gopkg.in/check.v1"
+)
+
+type XLSBSuite struct{}
+
+var _ = Suite(&XLSBSuite{})
+
+// xlsbRecord returns a record of an XLSB part, of type typ, made from
+// fields, with strings written as XLWideStrings.
+func xlsbRecord(typ int, fields ...interface{}) string {
+	var data bytes.Buffer
+	for _, field := range fields {
+		switch v := field.(type) {
+		case string:
+			units := utf16.Encode([]rune(v))
+			binary.Write(&data, binary.LittleEndian, uint32(len(units)))
+			binary.Write(&data, binary.LittleEndian, units)
+		default:
+			binary.Write(&data, binary.LittleEndian, v)
+		}
+	}
+	var record bytes.Buffer
+	writeNumber := func(n, max int) {
+		for i := 0; i < max; i++ {
+			b := byte(n & 0x7F)
+			n >>= 7
+			if n == 0 {
+				record.WriteByte(b)
+				return
+			}
+			record.WriteByte(b | 0x80)
+		}
+	}
+	writeNumber(typ, 2)
+	writeNumber(data.Len(), 4)
+	record.Write(data.Bytes())
+	return record.String()
+}
+
+// xlsbParts returns the parts of an XLSB workbook with a visible sheet,
+// "Data", holding sheet, and a hidden one, "Hidden".
+func xlsbParts(sheet ...string) map[string]string {
+	var data string
+	for _, record := range sheet {
+		data += record
+	}
+	return map[string]string{
+		"[Content_Types].xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="bin" ContentType="application/vnd.ms-excel.sheet.binary.macroEnabled.main"/><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Override PartName="/xl/worksheets/sheet1.bin" ContentType="application/vnd.ms-excel.worksheet"/></Types>`,
+		"_rels/.rels": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="` + relTypeOfficeDocument + `" Target="xl/workbook.bin"/></Relationships>`,
+		"xl/_rels/workbook.bin.rels": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="` + relTypeWorksheet + `" Target="worksheets/sheet1.bin"/><Relationship Id="rId2" Type="` + relTypeWorksheet + `" Target="worksheets/sheet2.bin"/><Relationship Id="rId3" Type="` + relTypeSharedStrings + `" Target="sharedStrings.bin"/><Relationship Id="rId4" Type="` + relTypeStyles + `" Target="styles.bin"/></Relationships>`,
+		"xl/workbook.bin": xlsbRecord(131) +
+			xlsbRecord(brtWbProp, uint32(0), uint32(0), uint32(0), "") +
+			xlsbRecord(143) +
+			xlsbRecord(brtBundleSh, uint32(0), uint32(1), "rId1", "Data") +
+			xlsbRecord(brtBundleSh, uint32(1), uint32(2), "rId2", "Hidden") +
+			xlsbRecord(144) +
+			xlsbRecord(132),
+		"xl/sharedStrings.bin": xlsbRecord(159, uint32(2), uint32(2)) +
+			xlsbRecord(brtSSTItem, uint8(0), "Name") +
+			xlsbRecord(brtSSTItem, uint8(0), "Entrée") +
+			xlsbRecord(160),
+		"xl/styles.bin": xlsbRecord(278) +
+			xlsbRecord(615, uint32(1)) +
+			xlsbRecord(brtFmt, uint16(164), "yyyy-mm-dd") +
+			xlsbRecord(616) +
+			xlsbRecord(brtBeginCellStyleXF, uint32(1)) +
+			xlsbRecord(brtXF, uint16(0xFFFF), uint16(0), uint16(0), uint16(0), uint32(0), uint32(0)) +
+			xlsbRecord(627) +
+			xlsbRecord(brtBeginCellXFs, uint32(3)) +
+			xlsbRecord(brtXF, uint16(0), uint16(0), uint16(0), uint16(0), uint32(0), uint32(0)) +
+			xlsbRecord(brtXF, uint16(0), uint16(164), uint16(0), uint16(0), uint32(0), uint32(0)) +
+			xlsbRecord(brtXF, uint16(0), uint16(10), uint16(0), uint16(0), uint32(0), uint32(0)) +
+			xlsbRecord(brtEndCellXFs) +
+			xlsbRecord(279),
+		"xl/worksheets/sheet1.bin": xlsbRecord(129) + data + xlsbRecord(130),
+		"xl/worksheets/sheet2.bin": xlsbRecord(129) + xlsbRecord(130),
+	}
+}
+
+func (s *XLSBSuite) TestReadXLSB(c *C) {
+	parts := xlsbParts(
+		xlsbRecord(390),
+		xlsbRecord(brtColInfo, uint32(0), uint32(1), uint32(20*256), uint32(0), uint16(0)),
+		xlsbRecord(brtColInfo, uint32(3), uint32(3), uint32(10*256), uint32(0), uint16(1)),
+		xlsbRecord(391),
+		xlsbRecord(145),
+		xlsbRecord(brtRowHdr, uint32(0), uint32(0), uint16(600), uint8(0), uint8(0x20), uint8(0)),
+		xlsbRecord(brtCellIsst, uint32(0), uint32(0), uint32(0)),
+		xlsbRecord(brtCellIsst, uint32(1), uint32(0), uint32(1)),
+		xlsbRecord(brtCellBool, uint32(2), uint32(0), uint8(1)),
+		xlsbRecord(brtRowHdr, uint32(1), uint32(0), uint16(300), uint8(0), uint8(0x10), uint8(0)),
+		xlsbRecord(brtCellRk, uint32(0), uint32(0), uint32(42<<2|2)),
+		xlsbRecord(brtCellRk, uint32(1), uint32(2), uint32(1234<<2|3)),
+		xlsbRecord(brtCellReal, uint32(2), uint32(1), 45352.0),
+		xlsbRecord(brtCellSt, uint32(3), uint32(0), "inline"),
+		xlsbRecord(brtRowHdr, uint32(3), uint32(0), uint16(300), uint8(0), uint8(0), uint8(0)),
+		xlsbRecord(brtFmlaNum, uint32(0), uint32(0), 84.0, uint16(0), uint32(0)),
+		xlsbRecord(brtFmlaString, uint32(1), uint32(0), "cached", uint16(0), uint32(0)),
+		xlsbRecord(brtCellError, uint32(2), uint32(0), uint8(0x07)),
+		xlsbRecord(146),
+		xlsbRecord(177, uint32(1)),
+		xlsbRecord(brtMergeCell, uint32(4), uint32(5), uint32(0), uint32(2)),
+		xlsbRecord(178),
+	)
+	f, err := readZipReader(makeZipReader(c, parts), nil)
+	c.Assert(err, IsNil)
+	c.Assert(f.Sheets, HasLen, 2)
+	c.Assert(f.Sheets[1].Name, Equals, "Hidden")
+	c.Assert(f.Sheets[1].Hidden, Equals, true)
+	sheet := f.Sheet["Data"]
+	c.Assert(sheet, NotNil)
+	c.Assert(sheet.Hidden, Equals, false)
+
+	c.Assert(sheet.Cell(0, 0).Value, Equals, "Name")
+	c.Assert(sheet.Cell(0, 1).Value, Equals, "Entrée")
+	c.Assert(sheet.Cell(0, 1).Type(), Equals, CellTypeString)
+	c.Assert(sheet.Cell(0, 2).Type(), Equals, CellTypeBool)
+	c.Assert(sheet.Cell(0, 2).Bool(), Equals, true)
+	c.Assert(sheet.Rows[0].Height, Equals, 30.0)
+	c.Assert(sheet.Rows[0].HasCustomHeight(), Equals, true)
+
+	c.Assert(sheet.Rows[1].Hidden, Equals, true)
+	c.Assert(sheet.Cell(1, 0).Value, Equals, "42")
+	c.Assert(sheet.Cell(1, 0).Type(), Equals, CellTypeNumeric)
+	c.Assert(sheet.Cell(1, 1).Value, Equals, "12.34")
+	c.Assert(sheet.Cell(1, 1).NumFmt, Equals, "0.00%")
+	c.Assert(sheet.Cell(1, 2).Value, Equals, "45352")
+	c.Assert(sheet.Cell(1, 2).NumFmt, Equals, "yyyy-mm-dd")
+	formatted, err := sheet.Cell(1, 2).FormattedValue()
+	c.Assert(err, IsNil)
+	c.Assert(formatted, Equals, "2024-03-01")
+	c.Assert(sheet.Cell(1, 3).Value, Equals, "inline")
+
+	// Cells with formulas hold the values they were last worked out
+	// to.
+	c.Assert(sheet.Cell(3, 0).Value, Equals, "84")
+	c.Assert(sheet.Cell(3, 0).Formula(), Equals, "")
+	c.Assert(sheet.Cell(3, 1).Value, Equals, "cached")
+	c.Assert(sheet.Cell(3, 2).Value, Equals, "#DIV/0!")
+	c.Assert(sheet.Cell(3, 2).Type(), Equals, CellTypeError)
+
+	c.Assert(sheet.Cell(4, 0).HMerge, Equals, 2)
+	c.Assert(sheet.Cell(4, 0).VMerge, Equals, 1)
+	c.Assert(sheet.Cols.FindCol(1).Width, Equals, 20.0)
+	c.Assert(sheet.Cols.FindCol(3).Hidden, Equals, true)
+}
+
+func (s *XLSBSuite) TestReadXLSBReversedMerge(c *C) {
+	parts := xlsbParts(
+		xlsbRecord(145),
+		xlsbRecord(146),
+		xlsbRecord(177, uint32(2)),
+		xlsbRecord(brtMergeCell, uint32(5), uint32(4), uint32(0), uint32(2)),
+		xlsbRecord(brtMergeCell, uint32(1), uint32(2), uint32(3), uint32(1)),
+		xlsbRecord(178),
+	)
+	f, err := readZipReader(makeZipReader(c, parts), nil)
+	c.Assert(err, IsNil)
+	sheet := f.Sheet["Data"]
+	for _, cell := range []*Cell{sheet.Cell(5, 0), sheet.Cell(1, 3)} {
+		c.Assert(cell.HMerge, Equals, 0)
+		c.Assert(cell.VMerge, Equals, 0)
+	}
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+}
+
+func (s *XLSBSuite) TestRKNumber(c *C) {
+	c.Assert(rkNumber(42<<2|2), Equals, 42.0)
+	c.Assert(rkNumber(uint32(0xFFFFFFFF)&^1), Equals, -1.0)
+	c.Assert(rkNumber(1234<<2|3), Equals, 12.34)
+	c.Assert(rkNumber(uint32(math.Float64bits(1.5)>>32)), Equals, 1.5)
+	c.Assert(rkNumber(uint32(math.Float64bits(1.5)>>32)|1), Equals, 0.015)
+}
+
+func (s *XLSBSuite) TestReadXLSBDate1904(c *C) {
+	parts := xlsbParts(
+		xlsbRecord(145),
+		xlsbRecord(brtRowHdr, uint32(0), uint32(0), uint16(300), uint8(0), uint8(0), uint8(0)),
+		xlsbRecord(brtCellReal, uint32(0), uint32(1), 43890.0),
+		xlsbRecord(146),
+	)
+	parts["xl/workbook.bin"] = xlsbRecord(brtWbProp, uint32(1), uint32(0), uint32(0), "") +
+		xlsbRecord(brtBundleSh, uint32(0), uint32(1), "rId1", "Data")
+	f, err := readZipReader(makeZipReader(c, parts), nil)
+	c.Assert(err, IsNil)
+	c.Assert(f.Date1904, Equals, true)
+	formatted, err := f.Sheet["Data"].Cell(0, 0).FormattedValue()
+	c.Assert(err, IsNil)
+	c.Assert(formatted, Equals, "2024-03-01")
+
+	f, err = readZipReader(makeZipReader(c, parts), []FileOption{Date1900System()})
+	c.Assert(err, IsNil)
+	c.Assert(f.Date1904, Equals, false)
+	c.Assert(f.Sheet["Data"].Cell(0, 0).Value, Equals, "45352")
+}
+
+func (s *XLSBSuite) TestReadXLSBTruncated(c *C) {
+	parts := xlsbParts(xlsbRecord(brtCellReal, uint32(0), uint32(0), 1.0)[:5])
+	_, err := readZipReader(makeZipReader(c, parts), nil)
+	c.Assert(err, ErrorMatches, "sheet 'Data': xl/worksheets/sheet1.bin: record 5 is cut short")
+
+	// A record claiming to be longer than the part is refused before
+	// room is made for it.
+	parts = xlsbParts(string([]byte{brtCellReal, 0xFF, 0xFF, 0xFF, 0x7F}))
+	_, err = readZipReader(makeZipReader(c, parts), nil)
+	c.Assert(err, ErrorMatches, "sheet 'Data': xl/worksheets/sheet1.bin: record 5 is cut short")
+}
+
+func (s *XLSBSuite) TestReadXLSBRepair(c *C) {
+	// Repairing fixes XML parts, leaving the binary ones, full of
+	// bytes XML doesn't allow, as they are.
+	parts := xlsbParts(
+		xlsbRecord(145),
+		xlsbRecord(brtRowHdr, uint32(0), uint32(0), uint16(300), uint8(0), uint8(0), uint8(0)),
+		xlsbRecord(brtCellReal, uint32(0), uint32(0), 1.5),
+		xlsbRecord(146),
+	)
+	f, err := readZipReader(makeZipReader(c, parts), []FileOption{Repair()})
+	c.Assert(err, IsNil)
+	c.Assert(f.Sheet["Data"].Cell(0, 0).Value, Equals, "1.5")
+}
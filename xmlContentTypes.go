@@ -2,6 +2,9 @@ package xlsx
 
 import (
 	"encoding/xml"
+	"fmt"
+	"path"
+	"strings"
 )
 
 type xlsxTypes struct {
@@ -21,6 +24,9 @@ type xlsxDefault struct {
 	ContentType string `xml:",attr"`
 }
 
+// MakeDefaultContentTypes returns the content types of the parts every
+// workbook has.  Files aren't written with it, as the content types of
+// their parts are found from the parts written; see makeContentTypes.
 func MakeDefaultContentTypes() (types xlsxTypes) {
 	types.Overrides = make([]xlsxOverride, 8)
 	types.Defaults = make([]xlsxDefault, 5)
@@ -55,3 +61,148 @@ func MakeDefaultContentTypes() (types xlsxTypes) {
 
 	return
 }
+
+// addDefault adds the content type of parts with the given extension,
+// unless it is already there.
+func (types *xlsxTypes) addDefault(extension, contentType string) {
+	for _, d := range types.Defaults {
+		if strings.EqualFold(d.Extension, extension) {
+			return
+		}
+	}
+	types.Defaults = append(types.Defaults, xlsxDefault{Extension: extension, ContentType: contentType})
+}
+
+// addOverride gives the part called partName, such as
+// "/xl/workbook.xml", its content type, in place of any it has.
+func (types *xlsxTypes) addOverride(partName, contentType string) {
+	for i, o := range types.Overrides {
+		if strings.EqualFold(o.PartName, partName) {
+			types.Overrides[i].ContentType = contentType
+			return
+		}
+	}
+	types.Overrides = append(types.Overrides, xlsxOverride{PartName: partName, ContentType: contentType})
+}
+
+// contentType returns the content type of the part called name, such
+// as "xl/workbook.xml", or "" if it has none.
+func (types xlsxTypes) contentType(name string) string {
+	for _, o := range types.Overrides {
+		if strings.EqualFold(strings.TrimPrefix(o.PartName, "/"), name) {
+			return o.ContentType
+		}
+	}
+	ext := strings.TrimPrefix(path.Ext(name), ".")
+	for _, d := range types.Defaults {
+		if strings.EqualFold(d.Extension, ext) {
+			return d.ContentType
+		}
+	}
+	return ""
+}
+
+// partContentType is the content type of the parts whose names, in
+// lower case, start with prefix and have the extension given.
+type partContentType struct {
+	prefix, extension, contentType string
+}
+
+// partContentTypes are the content types of the kinds of part a
+// workbook is made of, which are given to each part by an Override.
+// Every kind of part that is written needs to be here, or to have its
+// content type given when the part is added, with
+// packageParts.setContentType.
+var partContentTypes = []partContentType{
+	{"docprops/app", "xml", "application/vnd.openxmlformats-officedocument.extended-properties+xml"},
+	{"docprops/core", "xml", "application/vnd.openxmlformats-package.core-properties+xml"},
+	{"docprops/custom", "xml", "application/vnd.openxmlformats-officedocument.custom-properties+xml"},
+	{"xl/workbook", "xml", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"},
+	{"xl/worksheets/sheet", "xml", "application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"},
+	{"xl/chartsheets/sheet", "xml", "application/vnd.openxmlformats-officedocument.spreadsheetml.chartsheet+xml"},
+	{"xl/dialogsheets/sheet", "xml", "application/vnd.openxmlformats-officedocument.spreadsheetml.dialogsheet+xml"},
+	{"xl/macrosheets/sheet", "xml", "application/vnd.ms-excel.macrosheet+xml"},
+	{"xl/sharedstrings", "xml", "application/vnd.openxmlformats-officedocument.spreadsheetml.sharedStrings+xml"},
+	{"xl/styles", "xml", "application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"},
+	{"xl/theme/theme", "xml", "application/vnd.openxmlformats-officedocument.theme+xml"},
+	{"xl/calcchain", "xml", "application/vnd.openxmlformats-officedocument.spreadsheetml.calcChain+xml"},
+	{"xl/metadata", "xml", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheetMetadata+xml"},
+	{"xl/connections", "xml", "application/vnd.openxmlformats-officedocument.spreadsheetml.connections+xml"},
+	{"xl/comments", "xml", "application/vnd.openxmlformats-officedocument.spreadsheetml.comments+xml"},
+	{"xl/tables/table", "xml", "application/vnd.openxmlformats-officedocument.spreadsheetml.table+xml"},
+	{"xl/querytables/querytable", "xml", "application/vnd.openxmlformats-officedocument.spreadsheetml.queryTable+xml"},
+	{"xl/pivottables/pivottable", "xml", "application/vnd.openxmlformats-officedocument.spreadsheetml.pivotTable+xml"},
+	{"xl/pivotcache/pivotcachedefinition", "xml", "application/vnd.openxmlformats-officedocument.spreadsheetml.pivotCacheDefinition+xml"},
+	{"xl/pivotcache/pivotcacherecords", "xml", "application/vnd.openxmlformats-officedocument.spreadsheetml.pivotCacheRecords+xml"},
+	{"xl/externallinks/externallink", "xml", "application/vnd.openxmlformats-officedocument.spreadsheetml.externalLink+xml"},
+	{"xl/drawings/drawing", "xml", "application/vnd.openxmlformats-officedocument.drawing+xml"},
+	{"xl/charts/chart", "xml", "application/vnd.openxmlformats-officedocument.drawingml.chart+xml"},
+	{"xl/printersettings/printersettings", "bin", "application/vnd.openxmlformats-officedocument.spreadsheetml.printerSettings"},
+	{"xl/vbaproject", "bin", "application/vnd.ms-office.vbaProject"},
+	{"customxml/itemprops", "xml", contentTypeCustomXMLProps},
+	{"_xmlsignatures/sig", "xml", contentTypeSignature},
+}
+
+// extensionContentTypes are the content types of parts by their
+// extensions, which are given to the parts partContentTypes doesn't
+// cover by a Default.
+var extensionContentTypes = map[string]string{
+	"rels": "application/vnd.openxmlformats-package.relationships+xml",
+	"xml":  "application/xml",
+	"vml":  "application/vnd.openxmlformats-officedocument.vmlDrawing",
+	"sigs": contentTypeSignatureOrigin,
+	"png":  "image/png",
+	"jpeg": "image/jpeg",
+	"jpg":  "image/jpeg",
+	"gif":  "image/gif",
+	"bmp":  "image/bmp",
+	"tif":  "image/tiff",
+	"tiff": "image/tiff",
+	"webp": "image/webp",
+	"svg":  "image/svg+xml",
+	"emf":  "image/x-emf",
+	"wmf":  "image/x-wmf",
+}
+
+// partContentTypeOf returns the content type of the part called name,
+// as partContentTypes and extensionContentTypes give it, or "".
+func partContentTypeOf(name string) string {
+	lower := strings.ToLower(name)
+	ext := strings.TrimPrefix(path.Ext(lower), ".")
+	for _, kind := range partContentTypes {
+		if ext == kind.extension && strings.HasPrefix(lower, kind.prefix) {
+			return kind.contentType
+		}
+	}
+	return extensionContentTypes[ext]
+}
+
+// makeContentTypes returns the content types of the parts called
+// names, the content type of each being given by contentTypes or
+// else by the kind of part it is.  The extensions of the parts whose
+// content types are those of their extensions are given by Defaults,
+// and every other part by an Override.  It returns an error if the
+// content type of a part isn't known, since packages with such parts
+// are invalid.
+func makeContentTypes(names []string, contentTypes map[string]string) (xlsxTypes, error) {
+	var types xlsxTypes
+	for _, name := range names {
+		if name == "[Content_Types].xml" {
+			continue
+		}
+		contentType := contentTypes[name]
+		if contentType == "" {
+			contentType = partContentTypeOf(name)
+		}
+		if contentType == "" {
+			return types, fmt.Errorf("no content type is known for part %s", name)
+		}
+		ext := strings.ToLower(strings.TrimPrefix(path.Ext(name), "."))
+		if ext != "" && extensionContentTypes[ext] == contentType {
+			types.addDefault(ext, contentType)
+			continue
+		}
+		types.addOverride("/"+name, contentType)
+	}
+	return types, nil
+}
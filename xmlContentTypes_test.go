@@ -48,3 +48,65 @@ func (l *ContentTypesSuite) TestMakeDefaultContentTypes(c *C) {
 	c.Assert(types.Defaults[1].ContentType, Equals, "application/xml")
 
 }
+
+func (l *ContentTypesSuite) TestMakeContentTypes(c *C) {
+	types, err := makeContentTypes([]string{
+		"[Content_Types].xml",
+		"_rels/.rels",
+		"xl/workbook.xml",
+		"xl/_rels/workbook.xml.rels",
+		"xl/worksheets/sheet1.xml",
+		"xl/Comments1.xml",
+		"xl/tables/table1.xml",
+		"xl/drawings/vmlDrawing1.vml",
+		"xl/media/image1.png",
+		"xl/media/image2.png",
+		"xl/media/image3.dat",
+		"customXml/item1.xml",
+	}, map[string]string{"xl/media/image3.dat": "image/png"})
+	c.Assert(err, IsNil)
+	c.Assert(types.Defaults, DeepEquals, []xlsxDefault{
+		{Extension: "rels", ContentType: "application/vnd.openxmlformats-package.relationships+xml"},
+		{Extension: "vml", ContentType: "application/vnd.openxmlformats-officedocument.vmlDrawing"},
+		{Extension: "png", ContentType: "image/png"},
+		{Extension: "xml", ContentType: "application/xml"},
+	})
+	c.Assert(types.Overrides, DeepEquals, []xlsxOverride{
+		{PartName: "/xl/workbook.xml", ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"},
+		{PartName: "/xl/worksheets/sheet1.xml", ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"},
+		{PartName: "/xl/Comments1.xml", ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.comments+xml"},
+		{PartName: "/xl/tables/table1.xml", ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.table+xml"},
+		{PartName: "/xl/media/image3.dat", ContentType: "image/png"},
+	})
+	c.Assert(types.contentType("xl/media/image2.png"), Equals, "image/png")
+	c.Assert(types.contentType("xl/comments1.xml"), Equals, "application/vnd.openxmlformats-officedocument.spreadsheetml.comments+xml")
+
+	_, err = makeContentTypes([]string{"xl/workbook.xml", "xl/unknown.dat"}, nil)
+	c.Assert(err, ErrorMatches, "no content type is known for part xl/unknown.dat")
+}
+
+// Every part written has a content type, and no content type is given
+// to a part that isn't written.
+func (l *ContentTypesSuite) TestWrittenContentTypes(c *C) {
+	f := NewFile()
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, IsNil)
+	sheet.Cell(0, 0).SetFormula("[Rates.xlsx]Rates!A1")
+	sheet.Background = blankPNG(1, 1)
+	sheet.BackgroundType = IMAGE_TYPE_PNG
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	var types xlsxTypes
+	c.Assert(xml.Unmarshal([]byte(parts["[Content_Types].xml"]), &types), IsNil)
+	for name := range parts {
+		if name != "[Content_Types].xml" {
+			c.Assert(types.contentType(name), Not(Equals), "", Commentf(name))
+		}
+	}
+	for _, o := range types.Overrides {
+		_, ok := parts[o.PartName[1:]]
+		c.Assert(ok, Equals, true, Commentf(o.PartName))
+	}
+	c.Assert(types.contentType("xl/externalLinks/externalLink1.xml"), Equals, "application/vnd.openxmlformats-officedocument.spreadsheetml.externalLink+xml")
+	c.Assert(types.contentType("xl/media/image1.png"), Equals, "image/png")
+}
@@ -5,10 +5,19 @@ import (
 )
 
 type xlsxDrawing struct {
-	XMLName        xml.Name                `xml:"xdr:wsDr"`
-	NameSpace_XDR  string                  `xml:"xmlns:xdr,attr"`
-	NameSpace_Main string                  `xml:"xmlns:a,attr"`
-	TwoCellAnchors []*drawingTwoCellAnchor ``
+	XMLName        xml.Name `xml:"xdr:wsDr"`
+	NameSpace_XDR  string   `xml:"xmlns:xdr,attr"`
+	NameSpace_Main string   `xml:"xmlns:a,attr"`
+	// Anchors holds a *drawingTwoCellAnchor, *drawingOneCellAnchor
+	// or *drawingAbsoluteAnchor for each picture and shape, in the
+	// order they are stacked.
+	Anchors []interface{} ``
+}
+
+// drawingObject is the *drawingPic or *drawingSp that an anchor
+// places.
+type drawingObject interface {
+	shapeProperties() *drawingSpPr
 }
 
 type drawingTwoCellAnchor struct {
@@ -16,10 +25,38 @@ type drawingTwoCellAnchor struct {
 	EditAs     string            `xml:"editAs,attr"`
 	From       drawingFrom       ``
 	To         drawingTo         ``
-	Pic        drawingPic        ``
+	Object     drawingObject     ``
+	ClientData DrawingClientData ``
+}
+
+type drawingOneCellAnchor struct {
+	XMLName    xml.Name          `xml:"xdr:oneCellAnchor"`
+	From       drawingFrom       ``
+	Ext        drawingExt        ``
+	Object     drawingObject     ``
 	ClientData DrawingClientData ``
 }
 
+type drawingAbsoluteAnchor struct {
+	XMLName    xml.Name          `xml:"xdr:absoluteAnchor"`
+	Pos        drawingPos        ``
+	Ext        drawingExt        ``
+	Object     drawingObject     ``
+	ClientData DrawingClientData ``
+}
+
+type drawingPos struct {
+	XMLName xml.Name `xml:"xdr:pos"`
+	X       int      `xml:"x,attr"`
+	Y       int      `xml:"y,attr"`
+}
+
+type drawingExt struct {
+	XMLName xml.Name `xml:"xdr:ext"`
+	CX      int      `xml:"cx,attr"`
+	CY      int      `xml:"cy,attr"`
+}
+
 type drawingFrom struct {
 	XMLName      xml.Name `xml:"xdr:from"`
 	Column       int      `xml:"xdr:col"`
@@ -73,9 +110,30 @@ type drawingBlipFill struct {
 }
 
 type mainBlip struct {
-	XMLName     xml.Name `xml:"a:blip"`
-	NameSpace_R string   `xml:"xmlns:r,attr"`
-	Embed       string   `xml:"r:embed,attr"`
+	XMLName     xml.Name         `xml:"a:blip"`
+	NameSpace_R string           `xml:"xmlns:r,attr"`
+	Embed       string           `xml:"r:embed,attr"`
+	ExtLst      *mainBlipExtList ``
+}
+
+// mainBlipExtList holds the extension giving an SVG image, which
+// applications that don't know it ignore in favour of the blip's own
+// image.
+type mainBlipExtList struct {
+	XMLName xml.Name    `xml:"a:extLst"`
+	Ext     mainBlipExt ``
+}
+
+type mainBlipExt struct {
+	XMLName xml.Name       `xml:"a:ext"`
+	URI     string         `xml:"uri,attr"`
+	SVGBlip drawingSVGBlip ``
+}
+
+type drawingSVGBlip struct {
+	XMLName        xml.Name `xml:"asvg:svgBlip"`
+	NameSpace_ASVG string   `xml:"xmlns:asvg,attr"`
+	Embed          string   `xml:"r:embed,attr"`
 }
 
 type mainStretch struct {
@@ -88,9 +146,112 @@ type mainFillRect struct {
 }
 
 type drawingSpPr struct {
-	XMLName  xml.Name     `xml:"xdr:spPr"`
-	Xfrm     mainXfrm     ``
-	PrstGeom mainPrstGeom ``
+	XMLName   xml.Name       `xml:"xdr:spPr"`
+	Xfrm      mainXfrm       ``
+	PrstGeom  mainPrstGeom   ``
+	NoFill    *mainNoFill    ``
+	SolidFill *mainSolidFill ``
+	Ln        *mainLn        ``
+}
+
+type mainNoFill struct {
+	XMLName xml.Name `xml:"a:noFill"`
+}
+
+type mainSolidFill struct {
+	XMLName xml.Name    `xml:"a:solidFill"`
+	SrgbClr mainSrgbClr ``
+}
+
+type mainSrgbClr struct {
+	XMLName xml.Name `xml:"a:srgbClr"`
+	Val     string   `xml:"val,attr"`
+}
+
+type mainLn struct {
+	XMLName   xml.Name       `xml:"a:ln"`
+	W         int            `xml:"w,attr,omitempty"`
+	NoFill    *mainNoFill    ``
+	SolidFill *mainSolidFill ``
+	TailEnd   *mainLineEnd   ``
+}
+
+type mainLineEnd struct {
+	XMLName xml.Name `xml:"a:tailEnd"`
+	Type    string   `xml:"type,attr"`
+}
+
+// drawingSp is a shape, which may hold text.
+type drawingSp struct {
+	XMLName  xml.Name       `xml:"xdr:sp"`
+	Macro    string         `xml:"macro,attr"`
+	TextLink string         `xml:"textlink,attr"`
+	NvSpPr   drawingNvSpPr  ``
+	SpPr     drawingSpPr    ``
+	TxBody   *drawingTxBody ``
+}
+
+type drawingNvSpPr struct {
+	XMLName xml.Name       `xml:"xdr:nvSpPr"`
+	CNvPr   drawingCNvPr   ``
+	CNvSpPr drawingCNvSpPr ``
+}
+
+type drawingCNvSpPr struct {
+	XMLName xml.Name `xml:"xdr:cNvSpPr"`
+	TxBox   int      `xml:"txBox,attr,omitempty"`
+}
+
+type drawingTxBody struct {
+	XMLName  xml.Name        `xml:"xdr:txBody"`
+	BodyPr   mainBodyPr      ``
+	LstStyle mainLstStyle    ``
+	P        []mainParagraph ``
+}
+
+type mainBodyPr struct {
+	XMLName      xml.Name `xml:"a:bodyPr"`
+	VertOverflow string   `xml:"vertOverflow,attr"`
+	Wrap         string   `xml:"wrap,attr"`
+	RtlCol       int      `xml:"rtlCol,attr"`
+	Anchor       string   `xml:"anchor,attr"`
+}
+
+type mainLstStyle struct {
+	XMLName xml.Name `xml:"a:lstStyle"`
+}
+
+type mainParagraph struct {
+	XMLName xml.Name `xml:"a:p"`
+	PPr     mainPPr  ``
+	R       *mainRun ``
+}
+
+type mainPPr struct {
+	XMLName xml.Name `xml:"a:pPr"`
+	Algn    string   `xml:"algn,attr"`
+}
+
+type mainRun struct {
+	XMLName xml.Name `xml:"a:r"`
+	RPr     mainRPr  ``
+	T       string   `xml:"a:t"`
+}
+
+type mainRPr struct {
+	XMLName   xml.Name       `xml:"a:rPr"`
+	Lang      string         `xml:"lang,attr"`
+	Sz        int            `xml:"sz,attr,omitempty"`
+	B         int            `xml:"b,attr,omitempty"`
+	I         int            `xml:"i,attr,omitempty"`
+	U         string         `xml:"u,attr,omitempty"`
+	SolidFill *mainSolidFill ``
+	Latin     *mainFont      ``
+}
+
+type mainFont struct {
+	XMLName  xml.Name `xml:"a:latin"`
+	Typeface string   `xml:"typeface,attr"`
 }
 
 type mainXfrm struct {
@@ -125,15 +286,112 @@ type DrawingClientData struct {
 	XMLName xml.Name `xml:"xdr:clientData"`
 }
 
+// xlsxReadDrawing is the wsDr element of a drawing part as it is
+// read.  The structs above are for writing, and their prefixed names
+// can't be used to read a drawing back.
+type xlsxReadDrawing struct {
+	Anchors []xlsxReadAnchor `xml:",any"`
+}
+
+// xlsxReadAnchor is any of the twoCellAnchor, oneCellAnchor and
+// absoluteAnchor elements, which differ in which of the elements
+// placing them they have.
+type xlsxReadAnchor struct {
+	XMLName xml.Name
+	From    *xlsxReadAnchorPoint `xml:"from"`
+	To      *xlsxReadAnchorPoint `xml:"to"`
+	Pos     *struct {
+		X int `xml:"x,attr"`
+		Y int `xml:"y,attr"`
+	} `xml:"pos"`
+	Ext *struct {
+		CX int `xml:"cx,attr"`
+		CY int `xml:"cy,attr"`
+	} `xml:"ext"`
+	Pic *xlsxReadPic `xml:"pic"`
+	Sp  *xlsxReadSp  `xml:"sp"`
+}
+
+type xlsxReadAnchorPoint struct {
+	Col       int `xml:"col"`
+	ColOffset int `xml:"colOff"`
+	Row       int `xml:"row"`
+	RowOffset int `xml:"rowOff"`
+}
+
+type xlsxReadPic struct {
+	CNvPr struct {
+		Name        string `xml:"name,attr"`
+		Description string `xml:"descr,attr"`
+	} `xml:"nvPicPr>cNvPr"`
+	Blip struct {
+		Embed   string `xml:"embed,attr"`
+		SVGBlip struct {
+			Embed string `xml:"embed,attr"`
+		} `xml:"extLst>ext>svgBlip"`
+	} `xml:"blipFill>blip"`
+}
+
+type xlsxReadSp struct {
+	CNvPr struct {
+		Name string `xml:"name,attr"`
+	} `xml:"nvSpPr>cNvPr"`
+	CNvSpPr struct {
+		TxBox bool `xml:"txBox,attr"`
+	} `xml:"nvSpPr>cNvSpPr"`
+	SpPr struct {
+		PrstGeom struct {
+			Prst string `xml:"prst,attr"`
+		} `xml:"prstGeom"`
+		SolidFill *xlsxReadSolidFill `xml:"solidFill"`
+		Ln        *struct {
+			W         int                `xml:"w,attr"`
+			SolidFill *xlsxReadSolidFill `xml:"solidFill"`
+			TailEnd   *struct {
+				Type string `xml:"type,attr"`
+			} `xml:"tailEnd"`
+		} `xml:"ln"`
+	} `xml:"spPr"`
+	TxBody *struct {
+		BodyPr struct {
+			Anchor string `xml:"anchor,attr"`
+		} `xml:"bodyPr"`
+		P []struct {
+			PPr struct {
+				Algn string `xml:"algn,attr"`
+			} `xml:"pPr"`
+			R []struct {
+				RPr struct {
+					Sz        int                `xml:"sz,attr"`
+					B         bool               `xml:"b,attr"`
+					I         bool               `xml:"i,attr"`
+					U         string             `xml:"u,attr"`
+					SolidFill *xlsxReadSolidFill `xml:"solidFill"`
+					Latin     *struct {
+						Typeface string `xml:"typeface,attr"`
+					} `xml:"latin"`
+				} `xml:"rPr"`
+				T string `xml:"t"`
+			} `xml:"r"`
+		} `xml:"p"`
+	} `xml:"txBody"`
+}
+
+type xlsxReadSolidFill struct {
+	SrgbClr struct {
+		Val string `xml:"val,attr"`
+	} `xml:"srgbClr"`
+}
+
 func newXlsxDrawing() *xlsxDrawing {
 	drawing := new(xlsxDrawing)
 	drawing.NameSpace_Main = "http://schemas.openxmlformats.org/drawingml/2006/main"
 	drawing.NameSpace_XDR = "http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing"
-	drawing.TwoCellAnchors = make([]*drawingTwoCellAnchor, 0)
+	drawing.Anchors = make([]interface{}, 0)
 	return drawing
 }
 
-func (drawing *xlsxDrawing) AddDrawingTwoCellAnchor(fromCol, fromColOff, fromRow, fromRowOff, toCol, toColOff, toRow, toRowOff int, embedId string) {
+func (drawing *xlsxDrawing) AddDrawingTwoCellAnchor(fromCol, fromColOff, fromRow, fromRowOff, toCol, toColOff, toRow, toRowOff int, object drawingObject) *drawingTwoCellAnchor {
 	anchor := new(drawingTwoCellAnchor)
 	anchor.EditAs = "oneCell"
 	anchor.From.Column = fromCol
@@ -144,10 +402,76 @@ func (drawing *xlsxDrawing) AddDrawingTwoCellAnchor(fromCol, fromColOff, fromRow
 	anchor.To.ColumnOffset = toColOff
 	anchor.To.Row = toRow
 	anchor.To.RowOffset = toRowOff
-	anchor.Pic.NvPicPr.CNvPr.Id = 0
-	anchor.Pic.NvPicPr.CNvPicPr.PicLocks.NoChangeAspect = 1
-	anchor.Pic.BlipFill.Blip.NameSpace_R = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
-	anchor.Pic.BlipFill.Blip.Embed = embedId
-	anchor.Pic.SpPr.PrstGeom.Prst = "rect"
-	drawing.TwoCellAnchors = append(drawing.TwoCellAnchors, anchor)
+	anchor.Object = object
+	drawing.Anchors = append(drawing.Anchors, anchor)
+	return anchor
+}
+
+// AddDrawingOneCellAnchor adds a picture or shape of size cx by cy
+// EMUs whose top left corner is fixed to a cell, so that it keeps its
+// size when the columns and rows are resized.
+func (drawing *xlsxDrawing) AddDrawingOneCellAnchor(fromCol, fromColOff, fromRow, fromRowOff, cx, cy int, object drawingObject) *drawingOneCellAnchor {
+	anchor := new(drawingOneCellAnchor)
+	anchor.From.Column = fromCol
+	anchor.From.ColumnOffset = fromColOff
+	anchor.From.Row = fromRow
+	anchor.From.RowOffset = fromRowOff
+	anchor.Ext.CX = cx
+	anchor.Ext.CY = cy
+	anchor.Object = object
+	object.shapeProperties().Xfrm.Ext.CX = cx
+	object.shapeProperties().Xfrm.Ext.CY = cy
+	drawing.Anchors = append(drawing.Anchors, anchor)
+	return anchor
+}
+
+// AddDrawingAbsoluteAnchor adds a picture or shape of size cx by cy
+// EMUs at x, y EMUs from the top left corner of the sheet, which
+// doesn't move or change size with the cells.
+func (drawing *xlsxDrawing) AddDrawingAbsoluteAnchor(x, y, cx, cy int, object drawingObject) *drawingAbsoluteAnchor {
+	anchor := new(drawingAbsoluteAnchor)
+	anchor.Pos.X = x
+	anchor.Pos.Y = y
+	anchor.Ext.CX = cx
+	anchor.Ext.CY = cy
+	anchor.Object = object
+	spPr := object.shapeProperties()
+	spPr.Xfrm.Off.X = x
+	spPr.Xfrm.Off.Y = y
+	spPr.Xfrm.Ext.CX = cx
+	spPr.Xfrm.Ext.CY = cy
+	drawing.Anchors = append(drawing.Anchors, anchor)
+	return anchor
+}
+
+// setSVG makes the picture show the SVG image with the given
+// relationship id, falling back to its own image.
+func (pic *drawingPic) setSVG(embedId string) {
+	pic.BlipFill.Blip.ExtLst = &mainBlipExtList{Ext: mainBlipExt{
+		URI: "{96DAC541-7B7A-43D3-8B79-37D633B846F1}",
+		SVGBlip: drawingSVGBlip{
+			NameSpace_ASVG: "http://schemas.microsoft.com/office/drawing/2016/SVG/main",
+			Embed:          embedId,
+		},
+	}}
+}
+
+// newDrawingPic returns a picture showing the image with the given
+// relationship id.
+func newDrawingPic(embedId string) *drawingPic {
+	pic := new(drawingPic)
+	pic.NvPicPr.CNvPr.Id = 0
+	pic.NvPicPr.CNvPicPr.PicLocks.NoChangeAspect = 1
+	pic.BlipFill.Blip.NameSpace_R = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	pic.BlipFill.Blip.Embed = embedId
+	pic.SpPr.PrstGeom.Prst = "rect"
+	return pic
+}
+
+func (pic *drawingPic) shapeProperties() *drawingSpPr {
+	return &pic.SpPr
+}
+
+func (sp *drawingSp) shapeProperties() *drawingSpPr {
+	return &sp.SpPr
 }
@@ -19,8 +19,39 @@ type xlsxSST struct {
 // currently I have not checked this for completeness - it does as
 // much as I need.
 type xlsxSI struct {
-	T string  `xml:"t"`
-	R []xlsxR `xml:"r"`
+	T          string            `xml:"t"`
+	R          []xlsxR           `xml:"r"`
+	RPh        []xlsxPhoneticRun `xml:"rPh"`
+	PhoneticPr *xlsxPhoneticPr   `xml:"phoneticPr"`
+}
+
+// text returns the string held by the si element, joining the runs of
+// rich text if it has any.
+func (si *xlsxSI) text() string {
+	if len(si.R) == 0 {
+		return si.T
+	}
+	var text string
+	for _, r := range si.R {
+		text += r.T
+	}
+	return text
+}
+
+// xlsxPhoneticRun directly maps the rPh element, a run of phonetic
+// text over the characters of the string from sb up to eb.
+type xlsxPhoneticRun struct {
+	Sb int    `xml:"sb,attr"`
+	Eb int    `xml:"eb,attr"`
+	T  string `xml:"t"`
+}
+
+// xlsxPhoneticPr directly maps the phoneticPr element, which says how
+// a string's phonetic text is shown.
+type xlsxPhoneticPr struct {
+	FontID    int    `xml:"fontId,attr"`
+	Type      string `xml:"type,attr,omitempty"`
+	Alignment string `xml:"alignment,attr,omitempty"`
 }
 
 // xlsxR directly maps the r element from the namespace
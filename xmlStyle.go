@@ -79,12 +79,23 @@ type xlsxStyleSheet struct {
 	CellStyleXfs *xlsxCellStyleXfs `xml:"cellStyleXfs,omitempty"`
 	CellXfs      xlsxCellXfs       `xml:"cellXfs,omitempty"`
 	NumFmts      xlsxNumFmts       `xml:"numFmts,omitempty"`
+	Dxfs         *xlsxDxfs         `xml:"dxfs,omitempty"`
+	TableStyles  *xlsxTableStyles  `xml:"tableStyles,omitempty"`
+	Colors       *xlsxStyleColors  `xml:"colors,omitempty"`
 
 	theme *theme
 
 	sync.RWMutex   // protects the following
 	styleCache     map[int]*Style
 	numFmtRefTable map[int]xlsxNumFmt
+
+	// The indexes of the fonts, fills, borders and cellXfs by their
+	// content, so that adding one already there is quick however
+	// many there are.  Each is built when first needed.
+	fontIndex   map[string]int
+	fillIndex   map[string]int
+	borderIndex map[string]int
+	cellXfIndex map[string]int
 }
 
 func newXlsxStyleSheet(t *theme) *xlsxStyleSheet {
@@ -98,6 +109,10 @@ func (styles *xlsxStyleSheet) reset() {
 	styles.Fonts = xlsxFonts{}
 	styles.Fills = xlsxFills{}
 	styles.Borders = xlsxBorders{}
+	styles.fontIndex = nil
+	styles.fillIndex = nil
+	styles.borderIndex = nil
+	styles.cellXfIndex = nil
 
 	// Microsoft seems to want an emtpy border to start with
 	styles.addBorder(
@@ -112,7 +127,31 @@ func (styles *xlsxStyleSheet) reset() {
 
 	// add default xf
 	styles.CellXfs = xlsxCellXfs{Count: 1, Xf: []xlsxXf{{}}}
+	styles.cellXfIndex = nil
 	styles.NumFmts = xlsxNumFmts{}
+	styles.Dxfs = nil
+	styles.TableStyles = nil
+}
+
+// readFont returns the Font a font element of the style sheet
+// describes.
+func (styles *xlsxStyleSheet) readFont(xfont xlsxFont) Font {
+	var font Font
+	font.Size, _ = strconv.Atoi(xfont.Sz.Val)
+	font.Name = xfont.Name.Val
+	font.Family, _ = strconv.Atoi(xfont.Family.Val)
+	font.Charset, _ = strconv.Atoi(xfont.Charset.Val)
+	font.Color = styles.argbValue(xfont.Color)
+	if bold := xfont.B; bold != nil && bold.Val != "0" {
+		font.Bold = true
+	}
+	if italic := xfont.I; italic != nil && italic.Val != "0" {
+		font.Italic = true
+	}
+	if underline := xfont.U; underline != nil && underline.Val != "0" {
+		font.Underline = true
+	}
+	return font
 }
 
 func (styles *xlsxStyleSheet) getStyle(styleIndex int) *Style {
@@ -124,6 +163,7 @@ func (styles *xlsxStyleSheet) getStyle(styleIndex int) *Style {
 	}
 
 	style = new(Style)
+	style.Protection = *DefaultProtection()
 
 	var namedStyleXf xlsxXf
 
@@ -131,7 +171,7 @@ func (styles *xlsxStyleSheet) getStyle(styleIndex int) *Style {
 	if styleIndex > -1 && xfCount > 0 && styleIndex <= xfCount {
 		xf := styles.CellXfs.Xf[styleIndex]
 
-		if xf.XfId != nil && styles.CellStyleXfs != nil {
+		if xf.XfId != nil && styles.CellStyleXfs != nil && *xf.XfId >= 0 && *xf.XfId < len(styles.CellStyleXfs.Xf) {
 			namedStyleXf = styles.CellStyleXfs.Xf[*xf.XfId]
 			style.NamedStyleIndex = xf.XfId
 		} else {
@@ -142,52 +182,10 @@ func (styles *xlsxStyleSheet) getStyle(styleIndex int) *Style {
 		style.ApplyFill = xf.ApplyFill || namedStyleXf.ApplyFill
 		style.ApplyFont = xf.ApplyFont || namedStyleXf.ApplyFont
 		style.ApplyAlignment = xf.ApplyAlignment || namedStyleXf.ApplyAlignment
+		style.ApplyProtection = xf.ApplyProtection || namedStyleXf.ApplyProtection
 
-		if xf.BorderId > -1 && xf.BorderId < styles.Borders.Count {
-			var border xlsxBorder
-			border = styles.Borders.Border[xf.BorderId]
-			style.Border.Left = border.Left.Style
-			style.Border.LeftColor = border.Left.Color.RGB
-			style.Border.Right = border.Right.Style
-			style.Border.RightColor = border.Right.Color.RGB
-			style.Border.Top = border.Top.Style
-			style.Border.TopColor = border.Top.Color.RGB
-			style.Border.Bottom = border.Bottom.Style
-			style.Border.BottomColor = border.Bottom.Color.RGB
-		}
-
-		if xf.FillId > -1 && xf.FillId < styles.Fills.Count {
-			xFill := styles.Fills.Fill[xf.FillId]
-			style.Fill.PatternType = xFill.PatternFill.PatternType
-			style.Fill.FgColor = styles.argbValue(xFill.PatternFill.FgColor)
-			style.Fill.BgColor = styles.argbValue(xFill.PatternFill.BgColor)
-		}
-
-		if xf.FontId > -1 && xf.FontId < styles.Fonts.Count {
-			xfont := styles.Fonts.Font[xf.FontId]
-			style.Font.Size, _ = strconv.Atoi(xfont.Sz.Val)
-			style.Font.Name = xfont.Name.Val
-			style.Font.Family, _ = strconv.Atoi(xfont.Family.Val)
-			style.Font.Charset, _ = strconv.Atoi(xfont.Charset.Val)
-			style.Font.Color = styles.argbValue(xfont.Color)
-
-			if bold := xfont.B; bold != nil && bold.Val != "0" {
-				style.Font.Bold = true
-			}
-			if italic := xfont.I; italic != nil && italic.Val != "0" {
-				style.Font.Italic = true
-			}
-			if underline := xfont.U; underline != nil && underline.Val != "0" {
-				style.Font.Underline = true
-			}
-		}
-		if xf.Alignment.Horizontal != "" {
-			style.Alignment.Horizontal = xf.Alignment.Horizontal
-		}
+		styles.readXf(style, xf)
 
-		if xf.Alignment.Vertical != "" {
-			style.Alignment.Vertical = xf.Alignment.Vertical
-		}
 		styles.Lock()
 		styles.styleCache[styleIndex] = style
 		styles.Unlock()
@@ -195,11 +193,103 @@ func (styles *xlsxStyleSheet) getStyle(styleIndex int) *Style {
 	return style
 }
 
+// readXf sets the protection, border, fill, font and alignment of the
+// style from those of an xf.
+func (styles *xlsxStyleSheet) readXf(style *Style, xf xlsxXf) {
+	if xf.Protection != nil {
+		style.Protection.Locked = xf.Protection.Locked
+		style.Protection.Hidden = xf.Protection.Hidden
+	}
+
+	if xf.BorderId > -1 && xf.BorderId < styles.Borders.Count {
+		var border xlsxBorder
+		border = styles.Borders.Border[xf.BorderId]
+		style.Border.Left = border.Left.Style
+		style.Border.LeftColor = styles.argbValue(border.Left.Color)
+		style.Border.Right = border.Right.Style
+		style.Border.RightColor = styles.argbValue(border.Right.Color)
+		style.Border.Top = border.Top.Style
+		style.Border.TopColor = styles.argbValue(border.Top.Color)
+		style.Border.Bottom = border.Bottom.Style
+		style.Border.BottomColor = styles.argbValue(border.Bottom.Color)
+	}
+
+	if xf.FillId > -1 && xf.FillId < styles.Fills.Count {
+		style.Fill = styles.readFill(styles.Fills.Fill[xf.FillId])
+	}
+
+	if xf.FontId > -1 && xf.FontId < styles.Fonts.Count {
+		style.Font = styles.readFont(styles.Fonts.Font[xf.FontId])
+	}
+	if xf.Alignment.Horizontal != "" {
+		style.Alignment.Horizontal = HorizontalAlignment(xf.Alignment.Horizontal)
+	}
+
+	if xf.Alignment.Vertical != "" {
+		style.Alignment.Vertical = VerticalAlignment(xf.Alignment.Vertical)
+	}
+	style.Alignment.Indent = xf.Alignment.Indent
+	style.Alignment.ShrinkToFit = xf.Alignment.ShrinkToFit
+	style.Alignment.TextRotation = xf.Alignment.TextRotation
+	style.Alignment.WrapText = xf.Alignment.WrapText
+}
+
+// readFill returns the Fill of a fill.
+func (styles *xlsxStyleSheet) readFill(xFill xlsxFill) Fill {
+	fill := Fill{
+		PatternType: xFill.PatternFill.PatternType,
+		FgColor:     styles.argbValue(xFill.PatternFill.FgColor),
+		BgColor:     styles.argbValue(xFill.PatternFill.BgColor),
+	}
+	if xGradient := xFill.GradientFill; xGradient != nil {
+		fill.Gradient = &GradientFill{
+			Type:   xGradient.Type,
+			Degree: xGradient.Degree,
+			Left:   xGradient.Left,
+			Right:  xGradient.Right,
+			Top:    xGradient.Top,
+			Bottom: xGradient.Bottom,
+		}
+		for _, stop := range xGradient.Stop {
+			fill.Gradient.Stops = append(fill.Gradient.Stops, GradientStop{
+				Position: stop.Position,
+				Color:    styles.argbValue(stop.Color),
+			})
+		}
+	}
+	return fill
+}
+
+// argbValue resolves a colour, which may be given by its index in the
+// theme or in the legacy palette and lightened or darkened by a tint,
+// to an ARGB hex string.  The style sheet may be nil.
 func (styles *xlsxStyleSheet) argbValue(color xlsxColor) string {
-	if color.Theme != nil && styles.theme != nil {
-		return styles.theme.themeColor(int64(*color.Theme), color.Tint)
+	switch {
+	case color.Theme != nil:
+		t := defaultTheme
+		if styles != nil && styles.theme != nil {
+			t = styles.theme
+		}
+		return t.themeColor(int64(*color.Theme), color.Tint)
+	case color.Indexed != nil:
+		palette := defaultIndexedColors
+		if styles != nil && styles.Colors != nil && len(styles.Colors.IndexedColors) > 0 {
+			palette = nil
+			for _, rgbColor := range styles.Colors.IndexedColors {
+				palette = append(palette, rgbColor.RGB)
+			}
+		}
+		index := *color.Indexed
+		if index >= len(palette) {
+			// The system colours follow a custom palette.
+			palette = defaultIndexedColors
+		}
+		if index < 0 || index >= len(palette) {
+			return ""
+		}
+		return applyTint(palette[index], color.Tint)
 	}
-	return color.RGB
+	return applyTint(color.RGB, color.Tint)
 }
 
 // Excel styles can reference number formats that are built-in, all of which
@@ -227,46 +317,83 @@ func (styles *xlsxStyleSheet) getNumberFormat(styleIndex int) string {
 	return strings.ToLower(numberFormat)
 }
 
+// contentIndex returns index, which maps the content of the entries
+// of a list in the style sheet to their position, building it from
+// the count entries got by key if it hasn't been built yet.  The
+// first of any duplicates read from a file is the one found.
+func contentIndex(index map[string]int, count int, key func(int) string) map[string]int {
+	if index != nil {
+		return index
+	}
+	index = make(map[string]int, count)
+	for i := count - 1; i >= 0; i-- {
+		index[key(i)] = i
+	}
+	return index
+}
+
 func (styles *xlsxStyleSheet) addFont(xFont xlsxFont) (index int) {
-	var font xlsxFont
 	if xFont.Name.Val == "" {
 		return 0
 	}
-	for index, font = range styles.Fonts.Font {
-		if font.Equals(xFont) {
-			return index
-		}
+	styles.fontIndex = contentIndex(styles.fontIndex, len(styles.Fonts.Font), func(i int) string {
+		return styles.Fonts.Font[i].key()
+	})
+	key := xFont.key()
+	if index, ok := styles.fontIndex[key]; ok {
+		return index
 	}
 	styles.Fonts.Font = append(styles.Fonts.Font, xFont)
 	index = styles.Fonts.Count
 	styles.Fonts.Count++
+	styles.fontIndex[key] = index
 	return
 }
 
 func (styles *xlsxStyleSheet) addFill(xFill xlsxFill) (index int) {
-	var fill xlsxFill
-	for index, fill = range styles.Fills.Fill {
-		if fill.Equals(xFill) {
-			return index
-		}
+	styles.fillIndex = contentIndex(styles.fillIndex, len(styles.Fills.Fill), func(i int) string {
+		return styles.Fills.Fill[i].key()
+	})
+	key := xFill.key()
+	if index, ok := styles.fillIndex[key]; ok {
+		return index
 	}
 	styles.Fills.Fill = append(styles.Fills.Fill, xFill)
 	index = styles.Fills.Count
 	styles.Fills.Count++
+	styles.fillIndex[key] = index
 	return
 }
 
 func (styles *xlsxStyleSheet) addBorder(xBorder xlsxBorder) (index int) {
-	var border xlsxBorder
-	for index, border = range styles.Borders.Border {
-		if border.Equals(xBorder) {
-			return index
-		}
+	styles.borderIndex = contentIndex(styles.borderIndex, len(styles.Borders.Border), func(i int) string {
+		return styles.Borders.Border[i].key()
+	})
+	key := xBorder.key()
+	if index, ok := styles.borderIndex[key]; ok {
+		return index
 	}
 	styles.Borders.Border = append(styles.Borders.Border, xBorder)
 	index = styles.Borders.Count
 
 	styles.Borders.Count++
+	styles.borderIndex[key] = index
+	return
+}
+
+func (styles *xlsxStyleSheet) addDxf(xDxf xlsxDxf) (index int) {
+	var dxf xlsxDxf
+	if styles.Dxfs == nil {
+		styles.Dxfs = &xlsxDxfs{}
+	}
+	for index, dxf = range styles.Dxfs.Dxf {
+		if dxf.Equals(xDxf) {
+			return index
+		}
+	}
+	styles.Dxfs.Dxf = append(styles.Dxfs.Dxf, xDxf)
+	index = styles.Dxfs.Count
+	styles.Dxfs.Count++
 	return
 }
 
@@ -287,16 +414,18 @@ func (styles *xlsxStyleSheet) addCellStyleXf(xCellStyleXf xlsxXf) (index int) {
 }
 
 func (styles *xlsxStyleSheet) addCellXf(xCellXf xlsxXf) (index int) {
-	var cellXf xlsxXf
-	for index, cellXf = range styles.CellXfs.Xf {
-		if cellXf.Equals(xCellXf) {
-			return index
-		}
+	styles.cellXfIndex = contentIndex(styles.cellXfIndex, len(styles.CellXfs.Xf), func(i int) string {
+		return styles.CellXfs.Xf[i].key()
+	})
+	key := xCellXf.key()
+	if index, ok := styles.cellXfIndex[key]; ok {
+		return index
 	}
 
 	styles.CellXfs.Xf = append(styles.CellXfs.Xf, xCellXf)
 	index = styles.CellXfs.Count
 	styles.CellXfs.Count++
+	styles.cellXfIndex[key] = index
 	return
 }
 
@@ -407,6 +536,22 @@ func (styles *xlsxStyleSheet) Marshal() (string, error) {
 		result += xcellStyles
 	}
 
+	if styles.Dxfs != nil {
+		xdxfs, err := styles.Dxfs.Marshal()
+		if err != nil {
+			return "", err
+		}
+		result += xdxfs
+	}
+
+	if styles.TableStyles != nil {
+		xtableStyles, err := styles.TableStyles.Marshal()
+		if err != nil {
+			return "", err
+		}
+		result += xtableStyles
+	}
+
 	return result + "</styleSheet>", nil
 }
 
@@ -511,6 +656,13 @@ func (font *xlsxFont) Equals(other xlsxFont) bool {
 	return font.Sz.Equals(other.Sz) && font.Name.Equals(other.Name) && font.Family.Equals(other.Family) && font.Charset.Equals(other.Charset) && font.Color.Equals(other.Color)
 }
 
+// key returns the content of the font as it is written, by which
+// fonts that look the same are shared.
+func (font *xlsxFont) key() string {
+	key, _ := font.Marshal()
+	return key
+}
+
 func (font *xlsxFont) Marshal() (result string, err error) {
 	result = "<font>"
 	if font.Sz.Val != "" {
@@ -589,15 +741,37 @@ func (fills *xlsxFills) Marshal(outputFillMap map[int]int) (string, error) {
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxFill struct {
-	PatternFill xlsxPatternFill `xml:"patternFill,omitempty"`
+	PatternFill  xlsxPatternFill   `xml:"patternFill,omitempty"`
+	GradientFill *xlsxGradientFill `xml:"gradientFill,omitempty"`
 }
 
 func (fill *xlsxFill) Equals(other xlsxFill) bool {
+	if (fill.GradientFill == nil) != (other.GradientFill == nil) {
+		return false
+	}
+	if fill.GradientFill != nil && !fill.GradientFill.Equals(*other.GradientFill) {
+		return false
+	}
 	return fill.PatternFill.Equals(other.PatternFill)
 }
 
+// key returns the content of the fill as it is written, by which
+// fills that look the same are shared.
+func (fill *xlsxFill) key() string {
+	key, _ := fill.Marshal()
+	return key
+}
+
 func (fill *xlsxFill) Marshal() (result string, err error) {
-	if fill.PatternFill.PatternType != "" {
+	if fill.GradientFill != nil {
+		var xgradientFill string
+		xgradientFill, err = fill.GradientFill.Marshal()
+		if err != nil {
+			return
+		}
+		return `<fill>` + xgradientFill + `</fill>`, nil
+	}
+	if fill.PatternFill.PatternType != "" || fill.PatternFill.FgColor.RGB != "" || fill.PatternFill.BgColor.RGB != "" {
 		var xpatternFill string
 		result = `<fill>`
 
@@ -611,6 +785,70 @@ func (fill *xlsxFill) Marshal() (result string, err error) {
 	return
 }
 
+// xlsxGradientFill directly maps the gradientFill element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxGradientFill struct {
+	Type   string             `xml:"type,attr,omitempty"`
+	Degree float64            `xml:"degree,attr,omitempty"`
+	Left   float64            `xml:"left,attr,omitempty"`
+	Right  float64            `xml:"right,attr,omitempty"`
+	Top    float64            `xml:"top,attr,omitempty"`
+	Bottom float64            `xml:"bottom,attr,omitempty"`
+	Stop   []xlsxGradientStop `xml:"stop"`
+}
+
+// xlsxGradientStop directly maps the stop element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxGradientStop struct {
+	Position float64   `xml:"position,attr"`
+	Color    xlsxColor `xml:"color"`
+}
+
+func (gradientFill *xlsxGradientFill) Equals(other xlsxGradientFill) bool {
+	if gradientFill.Type != other.Type || gradientFill.Degree != other.Degree ||
+		gradientFill.Left != other.Left || gradientFill.Right != other.Right ||
+		gradientFill.Top != other.Top || gradientFill.Bottom != other.Bottom ||
+		len(gradientFill.Stop) != len(other.Stop) {
+		return false
+	}
+	for i, stop := range gradientFill.Stop {
+		if stop.Position != other.Stop[i].Position || !stop.Color.Equals(other.Stop[i].Color) {
+			return false
+		}
+	}
+	return true
+}
+
+func (gradientFill *xlsxGradientFill) Marshal() (result string, err error) {
+	result = `<gradientFill`
+	if gradientFill.Type != "" {
+		result += fmt.Sprintf(` type="%s"`, gradientFill.Type)
+	}
+	for _, attr := range []struct {
+		name  string
+		value float64
+	}{
+		{"degree", gradientFill.Degree},
+		{"left", gradientFill.Left},
+		{"right", gradientFill.Right},
+		{"top", gradientFill.Top},
+		{"bottom", gradientFill.Bottom},
+	} {
+		if attr.value != 0 {
+			result += fmt.Sprintf(` %s="%s"`, attr.name, strconv.FormatFloat(attr.value, 'f', -1, 64))
+		}
+	}
+	result += `>`
+	for _, stop := range gradientFill.Stop {
+		result += fmt.Sprintf(`<stop position="%s"><color rgb="%s"/></stop>`, strconv.FormatFloat(stop.Position, 'f', -1, 64), stop.Color.RGB)
+	}
+	return result + `</gradientFill>`, nil
+}
+
 // xlsxPatternFill directly maps the patternFill element in the namespace
 // http://schemas.openxmlformats.org/spreadsheetml/2006/main -
 // currently I have not checked it for completeness - it does as much
@@ -626,7 +864,10 @@ func (patternFill *xlsxPatternFill) Equals(other xlsxPatternFill) bool {
 }
 
 func (patternFill *xlsxPatternFill) Marshal() (result string, err error) {
-	result = fmt.Sprintf(`<patternFill patternType="%s"`, patternFill.PatternType)
+	result = `<patternFill`
+	if patternFill.PatternType != "" {
+		result += fmt.Sprintf(` patternType="%s"`, patternFill.PatternType)
+	}
 	ending := `/>`
 	terminator := ""
 	subparts := ""
@@ -652,9 +893,10 @@ func (patternFill *xlsxPatternFill) Marshal() (result string, err error) {
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxColor struct {
-	RGB   string  `xml:"rgb,attr,omitempty"`
-	Theme *int    `xml:"theme,attr,omitempty"`
-	Tint  float64 `xml:"tint,attr,omitempty"`
+	RGB     string  `xml:"rgb,attr,omitempty"`
+	Theme   *int    `xml:"theme,attr,omitempty"`
+	Indexed *int    `xml:"indexed,attr,omitempty"`
+	Tint    float64 `xml:"tint,attr,omitempty"`
 }
 
 func (color *xlsxColor) Equals(other xlsxColor) bool {
@@ -713,6 +955,13 @@ func (border *xlsxBorder) Equals(other xlsxBorder) bool {
 // empty set of borders. There was logic in this function that would strip out
 // empty elements, but unfortunately that would cause the border to fail.
 
+// key returns the content of the border as it is written, by which
+// borders that look the same are shared.
+func (border *xlsxBorder) key() string {
+	key, _ := border.Marshal()
+	return key
+}
+
 func (border *xlsxBorder) Marshal() (result string, err error) {
 	subparts := ""
 	subparts += fmt.Sprintf(`<left style="%s">`, border.Left.Style)
@@ -783,8 +1032,8 @@ func (cellStyles *xlsxCellStyles) Marshal() (result string, err error) {
 
 type xlsxCellStyle struct {
 	XMLName       xml.Name `xml:"cellStyle"`
-	BuiltInId     *int     `xml:"builtInId,attr,omitempty"`
-	CustomBuiltIn *bool    `xml:"customBuiltIn,attr,omitempty"`
+	BuiltInId     *int     `xml:"builtinId,attr,omitempty"`
+	CustomBuiltIn *bool    `xml:"customBuiltin,attr,omitempty"`
 	Hidden        *bool    `xml:"hidden,attr,omitempty"`
 	ILevel        *bool    `xml:"iLevel,attr,omitempty"`
 	Name          string   `xml:"name,attr"`
@@ -846,18 +1095,19 @@ func (cellXfs *xlsxCellXfs) Marshal(outputBorderMap, outputFillMap, outputFontMa
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxXf struct {
-	ApplyAlignment    bool          `xml:"applyAlignment,attr"`
-	ApplyBorder       bool          `xml:"applyBorder,attr"`
-	ApplyFont         bool          `xml:"applyFont,attr"`
-	ApplyFill         bool          `xml:"applyFill,attr"`
-	ApplyNumberFormat bool          `xml:"applyNumberFormat,attr"`
-	ApplyProtection   bool          `xml:"applyProtection,attr"`
-	BorderId          int           `xml:"borderId,attr"`
-	FillId            int           `xml:"fillId,attr"`
-	FontId            int           `xml:"fontId,attr"`
-	NumFmtId          int           `xml:"numFmtId,attr"`
-	XfId              *int          `xml:"xfId,attr,omitempty"`
-	Alignment         xlsxAlignment `xml:"alignment"`
+	ApplyAlignment    bool            `xml:"applyAlignment,attr"`
+	ApplyBorder       bool            `xml:"applyBorder,attr"`
+	ApplyFont         bool            `xml:"applyFont,attr"`
+	ApplyFill         bool            `xml:"applyFill,attr"`
+	ApplyNumberFormat bool            `xml:"applyNumberFormat,attr"`
+	ApplyProtection   bool            `xml:"applyProtection,attr"`
+	BorderId          int             `xml:"borderId,attr"`
+	FillId            int             `xml:"fillId,attr"`
+	FontId            int             `xml:"fontId,attr"`
+	NumFmtId          int             `xml:"numFmtId,attr"`
+	XfId              *int            `xml:"xfId,attr,omitempty"`
+	Alignment         xlsxAlignment   `xml:"alignment"`
+	Protection        *xlsxProtection `xml:"protection,omitempty"`
 }
 
 func (xf *xlsxXf) Equals(other xlsxXf) bool {
@@ -873,7 +1123,27 @@ func (xf *xlsxXf) Equals(other xlsxXf) bool {
 		(xf.XfId == other.XfId ||
 			((xf.XfId != nil && other.XfId != nil) &&
 				*xf.XfId == *other.XfId)) &&
-		xf.Alignment.Equals(other.Alignment)
+		xf.Alignment.Equals(other.Alignment) &&
+		(xf.Protection == other.Protection ||
+			((xf.Protection != nil && other.Protection != nil) &&
+				*xf.Protection == *other.Protection))
+}
+
+// key returns what Equals compares of the xf, by which cells
+// formatted the same way share an xf.
+func (xf *xlsxXf) key() string {
+	a := xf.Alignment
+	key := fmt.Sprintf("%t %t %t %t %t %d %d %d %d %s %d %t %d %s %t",
+		xf.ApplyAlignment, xf.ApplyBorder, xf.ApplyFont, xf.ApplyFill, xf.ApplyProtection,
+		xf.BorderId, xf.FillId, xf.FontId, xf.NumFmtId,
+		a.Horizontal, a.Indent, a.ShrinkToFit, a.TextRotation, a.Vertical, a.WrapText)
+	if xf.XfId != nil {
+		key += fmt.Sprintf(" xfId=%d", *xf.XfId)
+	}
+	if xf.Protection != nil {
+		key += fmt.Sprintf(" locked=%t hidden=%t", xf.Protection.Locked, xf.Protection.Hidden)
+	}
+	return key
 }
 
 func (xf *xlsxXf) Marshal(outputBorderMap, outputFillMap, outputFontMap map[int]int) (result string, err error) {
@@ -886,7 +1156,32 @@ func (xf *xlsxXf) Marshal(outputBorderMap, outputFillMap, outputFontMap map[int]
 	if err != nil {
 		return result, err
 	}
-	return result + xAlignment + "</xf>", nil
+	result += xAlignment
+	if xf.Protection != nil {
+		result += fmt.Sprintf(`<protection locked="%b" hidden="%b"/>`, bool2Int(xf.Protection.Locked), bool2Int(xf.Protection.Hidden))
+	}
+	return result + "</xf>", nil
+}
+
+// xlsxProtection directly maps the protection element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxProtection struct {
+	Locked bool `xml:"locked,attr"`
+	Hidden bool `xml:"hidden,attr"`
+}
+
+// UnmarshalXML decodes a protection element, in which cells are locked
+// unless it says otherwise.
+func (p *xlsxProtection) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type protection xlsxProtection
+	decoded := protection{Locked: true}
+	if err := d.DecodeElement(&decoded, &start); err != nil {
+		return err
+	}
+	*p = xlsxProtection(decoded)
+	return nil
 }
 
 type xlsxAlignment struct {
@@ -923,3 +1218,151 @@ func bool2Int(b bool) int {
 	}
 	return 0
 }
+
+// xlsxStyleColors directly maps the colors element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.  It is only read, as the colours it gives are resolved
+// to RGB values.
+type xlsxStyleColors struct {
+	IndexedColors []xlsxColor `xml:"indexedColors>rgbColor"`
+}
+
+// xlsxDxfs directly maps the dxfs element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxDxfs struct {
+	Count int       `xml:"count,attr"`
+	Dxf   []xlsxDxf `xml:"dxf,omitempty"`
+}
+
+func (dxfs *xlsxDxfs) Marshal() (result string, err error) {
+	if dxfs.Count > 0 {
+		result = fmt.Sprintf(`<dxfs count="%d">`, dxfs.Count)
+		for _, dxf := range dxfs.Dxf {
+			var xdxf string
+			xdxf, err = dxf.Marshal()
+			if err != nil {
+				return
+			}
+			result += xdxf
+		}
+		result += `</dxfs>`
+	}
+	return
+}
+
+// xlsxDxf directly maps the dxf element, a differential format, in
+// the namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxDxf struct {
+	Font   *xlsxFont   `xml:"font,omitempty"`
+	NumFmt *xlsxNumFmt `xml:"numFmt,omitempty"`
+	Fill   *xlsxFill   `xml:"fill,omitempty"`
+	Border *xlsxBorder `xml:"border,omitempty"`
+}
+
+func (dxf *xlsxDxf) Equals(other xlsxDxf) bool {
+	if (dxf.Font == nil) != (other.Font == nil) || dxf.Font != nil && !dxf.Font.Equals(*other.Font) {
+		return false
+	}
+	if (dxf.NumFmt == nil) != (other.NumFmt == nil) || dxf.NumFmt != nil && *dxf.NumFmt != *other.NumFmt {
+		return false
+	}
+	if (dxf.Fill == nil) != (other.Fill == nil) || dxf.Fill != nil && !dxf.Fill.Equals(*other.Fill) {
+		return false
+	}
+	return (dxf.Border == nil) == (other.Border == nil) && (dxf.Border == nil || dxf.Border.Equals(*other.Border))
+}
+
+func (dxf *xlsxDxf) Marshal() (result string, err error) {
+	result = `<dxf>`
+	if dxf.Font != nil {
+		var xfont string
+		xfont, err = dxf.Font.Marshal()
+		if err != nil {
+			return
+		}
+		result += xfont
+	}
+	if dxf.NumFmt != nil {
+		var xnumFmt string
+		xnumFmt, err = dxf.NumFmt.Marshal()
+		if err != nil {
+			return
+		}
+		result += xnumFmt
+	}
+	if dxf.Fill != nil {
+		var xfill string
+		xfill, err = dxf.Fill.Marshal()
+		if err != nil {
+			return
+		}
+		result += xfill
+	}
+	if dxf.Border != nil {
+		// Unlike a cell's border, a differential border leaves
+		// out the sides it doesn't change.
+		result += `<border>`
+		for _, side := range []struct {
+			name string
+			line xlsxLine
+		}{
+			{"left", dxf.Border.Left},
+			{"right", dxf.Border.Right},
+			{"top", dxf.Border.Top},
+			{"bottom", dxf.Border.Bottom},
+		} {
+			if side.line.Style == "" {
+				continue
+			}
+			result += fmt.Sprintf(`<%s style="%s">`, side.name, side.line.Style)
+			if side.line.Color.RGB != "" {
+				result += fmt.Sprintf(`<color rgb="%s"/>`, side.line.Color.RGB)
+			}
+			result += fmt.Sprintf(`</%s>`, side.name)
+		}
+		result += `</border>`
+	}
+	return result + `</dxf>`, nil
+}
+
+// xlsxTableStyles directly maps the tableStyles element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxTableStyles struct {
+	Count             int              `xml:"count,attr"`
+	DefaultTableStyle string           `xml:"defaultTableStyle,attr,omitempty"`
+	DefaultPivotStyle string           `xml:"defaultPivotStyle,attr,omitempty"`
+	TableStyle        []xlsxTableStyle `xml:"tableStyle,omitempty"`
+}
+
+func (tableStyles *xlsxTableStyles) Marshal() (string, error) {
+	output, err := xml.Marshal(struct {
+		XMLName xml.Name `xml:"tableStyles"`
+		*xlsxTableStyles
+	}{xlsxTableStyles: tableStyles})
+	return string(output), err
+}
+
+// xlsxTableStyle directly maps the tableStyle element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxTableStyle struct {
+	Name    string                  `xml:"name,attr"`
+	Pivot   *bool                   `xml:"pivot,attr"`
+	Table   *bool                   `xml:"table,attr"`
+	Count   int                     `xml:"count,attr"`
+	Element []xlsxTableStyleElement `xml:"tableStyleElement"`
+}
+
+type xlsxTableStyleElement struct {
+	Type  string `xml:"type,attr"`
+	Size  int    `xml:"size,attr,omitempty"`
+	DxfId *int   `xml:"dxfId,attr"`
+}
@@ -2,6 +2,7 @@ package xlsx
 
 import (
 	"archive/zip"
+	"bufio"
 	"encoding/xml"
 	"fmt"
 	"io"
@@ -34,14 +35,16 @@ type xlsxWorkbookRelation struct {
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxWorkbook struct {
-	XMLName            xml.Name               `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main workbook"`
-	FileVersion        xlsxFileVersion        `xml:"fileVersion"`
-	WorkbookPr         xlsxWorkbookPr         `xml:"workbookPr"`
-	WorkbookProtection xlsxWorkbookProtection `xml:"workbookProtection"`
-	BookViews          xlsxBookViews          `xml:"bookViews"`
-	Sheets             xlsxSheets             `xml:"sheets"`
-	DefinedNames       xlsxDefinedNames       `xml:"definedNames"`
-	CalcPr             xlsxCalcPr             `xml:"calcPr"`
+	XMLName             xml.Name                 `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main workbook"`
+	FileVersion         xlsxFileVersion          `xml:"fileVersion"`
+	WorkbookPr          xlsxWorkbookPr           `xml:"workbookPr"`
+	WorkbookProtection  xlsxWorkbookProtection   `xml:"workbookProtection"`
+	BookViews           xlsxBookViews            `xml:"bookViews"`
+	Sheets              xlsxSheets               `xml:"sheets"`
+	ExternalReferences  *xlsxExternalReferences  `xml:"externalReferences"`
+	DefinedNames        xlsxDefinedNames         `xml:"definedNames"`
+	CalcPr              xlsxCalcPr               `xml:"calcPr"`
+	CustomWorkbookViews *xlsxCustomWorkbookViews `xml:"customWorkbookViews,omitempty"`
 }
 
 // xlsxWorkbookProtection directly maps the workbookProtection element from the
@@ -72,6 +75,7 @@ type xlsxWorkbookPr struct {
 	BackupFile          bool   `xml:"backupFile,attr,omitempty"`
 	ShowObjects         string `xml:"showObjects,attr,omitempty"`
 	Date1904            bool   `xml:"date1904,attr"`
+	CodeName            string `xml:"codeName,attr,omitempty"`
 }
 
 // xlsxBookViews directly maps the bookViews element from the
@@ -87,16 +91,19 @@ type xlsxBookViews struct {
 // - currently I have not checked it for completeness - it does as
 // much as I need.
 type xlsxWorkBookView struct {
-	ActiveTab            int    `xml:"activeTab,attr,omitempty"`
-	FirstSheet           int    `xml:"firstSheet,attr,omitempty"`
-	ShowHorizontalScroll bool   `xml:"showHorizontalScroll,attr,omitempty"`
-	ShowVerticalScroll   bool   `xml:"showVerticalScroll,attr,omitempty"`
-	ShowSheetTabs        bool   `xml:"showSheetTabs,attr,omitempty"`
-	TabRatio             int    `xml:"tabRatio,attr,omitempty"`
-	WindowHeight         int    `xml:"windowHeight,attr,omitempty"`
-	WindowWidth          int    `xml:"windowWidth,attr,omitempty"`
-	XWindow              string `xml:"xWindow,attr,omitempty"`
-	YWindow              string `xml:"yWindow,attr,omitempty"`
+	Visibility             string `xml:"visibility,attr,omitempty"`
+	Minimized              bool   `xml:"minimized,attr,omitempty"`
+	ActiveTab              int    `xml:"activeTab,attr,omitempty"`
+	FirstSheet             int    `xml:"firstSheet,attr,omitempty"`
+	ShowHorizontalScroll   *bool  `xml:"showHorizontalScroll,attr,omitempty"`
+	ShowVerticalScroll     *bool  `xml:"showVerticalScroll,attr,omitempty"`
+	ShowSheetTabs          *bool  `xml:"showSheetTabs,attr,omitempty"`
+	TabRatio               int    `xml:"tabRatio,attr,omitempty"`
+	WindowHeight           int    `xml:"windowHeight,attr,omitempty"`
+	WindowWidth            int    `xml:"windowWidth,attr,omitempty"`
+	XWindow                string `xml:"xWindow,attr,omitempty"`
+	YWindow                string `xml:"yWindow,attr,omitempty"`
+	AutoFilterDateGrouping *bool  `xml:"autoFilterDateGrouping,attr,omitempty"`
 }
 
 // xlsxSheets directly maps the sheets element from the namespace
@@ -149,6 +156,37 @@ type xlsxDefinedName struct {
 	PublishToServer   bool   `xml:"publishToServer,attr,omitempty"`
 	WorkbookParameter bool   `xml:"workbookParameter,attr,omitempty"`
 	Xlm               bool   `xml:"xml,attr,omitempty"`
+	// hasLocalSheetID records whether the localSheetId attribute was
+	// given, as a name local to the first sheet has the ID 0.
+	hasLocalSheetID bool
+}
+
+// UnmarshalXML decodes a definedName element, noting whether it has a
+// localSheetId attribute.
+func (n *xlsxDefinedName) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type definedName xlsxDefinedName
+	var name definedName
+	if err := d.DecodeElement(&name, &start); err != nil {
+		return err
+	}
+	*n = xlsxDefinedName(name)
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "localSheetId" {
+			n.hasLocalSheetID = true
+		}
+	}
+	return nil
+}
+
+// MarshalXML writes the definedName element.  The localSheetId
+// attribute can't simply be omitempty, as 0 is the ID of the first
+// sheet, so it is written whenever the name is local to a sheet.
+func (n xlsxDefinedName) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type definedName xlsxDefinedName
+	if n.LocalSheetID == 0 && n.hasLocalSheetID {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "localSheetId"}, Value: "0"})
+	}
+	return e.EncodeElement(definedName(n), start)
 }
 
 // xlsxCalcPr directly maps the calcPr element from the namespace
@@ -156,50 +194,70 @@ type xlsxDefinedName struct {
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxCalcPr struct {
-	CalcId       string  `xml:"calcId,attr,omitempty"`
-	IterateCount int     `xml:"iterateCount,attr,omitempty"`
-	RefMode      string  `xml:"refMode,attr,omitempty"`
-	Iterate      bool    `xml:"iterate,attr,omitempty"`
-	IterateDelta float64 `xml:"iterateDelta,attr,omitempty"`
+	CalcId         string  `xml:"calcId,attr,omitempty"`
+	CalcMode       string  `xml:"calcMode,attr,omitempty"`
+	FullCalcOnLoad bool    `xml:"fullCalcOnLoad,attr,omitempty"`
+	IterateCount   int     `xml:"iterateCount,attr,omitempty"`
+	RefMode        string  `xml:"refMode,attr,omitempty"`
+	Iterate        bool    `xml:"iterate,attr,omitempty"`
+	IterateDelta   float64 `xml:"iterateDelta,attr,omitempty"`
+	ForceFullCalc  bool    `xml:"forceFullCalc,attr,omitempty"`
 }
 
 // Helper function to lookup the file corresponding to a xlsxSheet object in the worksheets map
 func worksheetFileForSheet(sheet xlsxSheet, worksheets map[string]*zip.File, sheetXMLMap map[string]string) *zip.File {
-	sheetName, ok := sheetXMLMap[sheet.Id]
+	partName, ok := sheetXMLMap[sheet.Id]
 	if !ok {
-		if sheet.SheetId != "" {
-			sheetName = fmt.Sprintf("sheet%s", sheet.SheetId)
-		} else {
-			sheetName = fmt.Sprintf("sheet%s", sheet.Id)
+		id := sheet.SheetId
+		if id == "" {
+			id = sheet.Id
 		}
+		partName = normalizePartName(fmt.Sprintf("xl/worksheets/sheet%s.xml", id))
 	}
-	return worksheets[sheetName]
+	return worksheets[partName]
 }
 
 // getWorksheetFromSheet() is an internal helper function to open a
 // sheetN.xml file, refered to by an xlsx.xlsxSheet struct, from the XLSX
 // file and unmarshal it an xlsx.xlsxWorksheet struct
-func getWorksheetFromSheet(sheet xlsxSheet, worksheets map[string]*zip.File, sheetXMLMap map[string]string) (*xlsxWorksheet, error) {
+func getWorksheetFromSheet(sheet xlsxSheet, file *File, sheetXMLMap map[string]string) (*xlsxWorksheet, error) {
 	var rc io.ReadCloser
 	var decoder *xml.Decoder
 	var worksheet *xlsxWorksheet
 	var error error
 	worksheet = new(xlsxWorksheet)
 
-	f := worksheetFileForSheet(sheet, worksheets, sheetXMLMap)
+	f := worksheetFileForSheet(sheet, file.worksheets, sheetXMLMap)
 	if f == nil {
 		return nil, fmt.Errorf("Unable to find sheet '%s'", sheet)
 	}
-	rc, error = f.Open()
+	rc, error = file.openPart(f)
 	if error != nil {
 		return nil, error
 	}
 
-	decoder = xml.NewDecoder(rc)
+	limited := file.rowLimit > 0 || file.colLimit > 0
+	rows, cols := file.readLimits()
+	var data *sheetDataReader
+	if file.rowLimit > 0 || file.stats != nil {
+		data = &sheetDataReader{r: bufio.NewReader(rc)}
+		decoder = newPartDecoder(data)
+		defer func() {
+			file.stats.add(func(s *Stats) { s.BytesParsed += data.parsed })
+		}()
+	} else {
+		decoder = newPartDecoder(rc)
+	}
+	if limited {
+		decoder = xml.NewTokenDecoder(&sheetLimiter{d: decoder, data: data, rows: rows, cols: cols})
+	}
 	error = decoder.Decode(worksheet)
 	if error != nil {
 		return nil, error
 	}
+	if limited && worksheet.Dimension.Ref != "" {
+		worksheet.Dimension.Ref = limitDimension(worksheet.Dimension.Ref, rows, cols)
+	}
 
 	return worksheet, nil
 }
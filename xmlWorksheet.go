@@ -3,6 +3,7 @@ package xlsx
 import (
 	"encoding/xml"
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -11,19 +12,29 @@ import (
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxWorksheet struct {
-	XMLName       xml.Name          `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main worksheet"`
-	SheetPr       xlsxSheetPr       `xml:"sheetPr"`
-	Dimension     xlsxDimension     `xml:"dimension"`
-	SheetViews    xlsxSheetViews    `xml:"sheetViews"`
-	SheetFormatPr xlsxSheetFormatPr `xml:"sheetFormatPr"`
-	Cols          *xlsxCols         `xml:"cols,omitempty"`
-	SheetData     xlsxSheetData     `xml:"sheetData"`
-	MergeCells    *xlsxMergeCells   `xml:"mergeCells,omitempty"`
-	PrintOptions  xlsxPrintOptions  `xml:"printOptions"`
-	PageMargins   xlsxPageMargins   `xml:"pageMargins"`
-	PageSetUp     xlsxPageSetUp     `xml:"pageSetup"`
-	HeaderFooter  xlsxHeaderFooter  `xml:"headerFooter"`
-	Drawing       *worksheetDrawing `xml:"drawing,omitempty"`
+	XMLName          xml.Name                `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main worksheet"`
+	SheetPr          xlsxSheetPr             `xml:"sheetPr"`
+	Dimension        xlsxDimension           `xml:"dimension"`
+	SheetViews       xlsxSheetViews          `xml:"sheetViews"`
+	SheetFormatPr    xlsxSheetFormatPr       `xml:"sheetFormatPr"`
+	Cols             *xlsxCols               `xml:"cols,omitempty"`
+	SheetData        xlsxSheetData           `xml:"sheetData"`
+	SheetProtection  *xlsxSheetProtection    `xml:"sheetProtection,omitempty"`
+	CustomSheetViews *xlsxCustomSheetViews   `xml:"customSheetViews,omitempty"`
+	MergeCells       *xlsxMergeCells         `xml:"mergeCells,omitempty"`
+	Hyperlinks       *xlsxHyperlinks         `xml:"hyperlinks,omitempty"`
+	PrintOptions     xlsxPrintOptions        `xml:"printOptions"`
+	PageMargins      xlsxPageMargins         `xml:"pageMargins"`
+	PageSetUp        xlsxPageSetUp           `xml:"pageSetup"`
+	HeaderFooter     xlsxHeaderFooter        `xml:"headerFooter"`
+	IgnoredErrors    *xlsxIgnoredErrors      `xml:"ignoredErrors,omitempty"`
+	Drawing          *worksheetDrawing       `xml:"drawing,omitempty"`
+	LegacyDrawing    *worksheetLegacyDrawing `xml:"legacyDrawing,omitempty"`
+	Picture          *worksheetPicture       `xml:"picture,omitempty"`
+	OLEObjects       *xlsxOLEObjects         `xml:"oleObjects,omitempty"`
+	// Namespaces are the attributes of the worksheet element read,
+	// its namespace declarations among them.  They aren't written.
+	Namespaces []xml.Attr `xml:",any,attr"`
 }
 
 type worksheetDrawing struct {
@@ -36,6 +47,24 @@ func (d *worksheetDrawing) SetId(id int) {
 	d.DrawingIdStr = fmt.Sprintf("rId%d", id)
 }
 
+// worksheetPicture is the picture element giving the background image
+// of a worksheet.  RID is the id it is read with, and Id that it is
+// written with.
+type worksheetPicture struct {
+	RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr,omitempty"`
+	Id  string `xml:"r:id,attr"`
+}
+
+// xlsxSheetProtection directly maps the sheetProtection element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxSheetProtection struct {
+	Sheet     bool `xml:"sheet,attr,omitempty"`
+	Objects   bool `xml:"objects,attr,omitempty"`
+	Scenarios bool `xml:"scenarios,attr,omitempty"`
+}
+
 // xlsxHeaderFooter directly maps the headerFooter element in the namespace
 // http://schemas.openxmlformats.org/spreadsheetml/2006/main -
 // currently I have not checked it for completeness - it does as much
@@ -117,6 +146,7 @@ type xlsxPageMargins struct {
 type xlsxSheetFormatPr struct {
 	DefaultColWidth  float64 `xml:"defaultColWidth,attr,omitempty"`
 	DefaultRowHeight float64 `xml:"defaultRowHeight,attr"`
+	CustomHeight     bool    `xml:"customHeight,attr,omitempty"`
 	OutlineLevelCol  uint8   `xml:"outlineLevelCol,attr,omitempty"`
 	OutlineLevelRow  uint8   `xml:"outlineLevelRow,attr,omitempty"`
 }
@@ -134,24 +164,47 @@ type xlsxSheetViews struct {
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxSheetView struct {
-	WindowProtection        bool            `xml:"windowProtection,attr"`
-	ShowFormulas            bool            `xml:"showFormulas,attr"`
-	ShowGridLines           bool            `xml:"showGridLines,attr"`
-	ShowRowColHeaders       bool            `xml:"showRowColHeaders,attr"`
-	ShowZeros               bool            `xml:"showZeros,attr"`
-	RightToLeft             bool            `xml:"rightToLeft,attr"`
-	TabSelected             bool            `xml:"tabSelected,attr"`
-	ShowOutlineSymbols      bool            `xml:"showOutlineSymbols,attr"`
-	DefaultGridColor        bool            `xml:"defaultGridColor,attr"`
-	View                    string          `xml:"view,attr"`
-	TopLeftCell             string          `xml:"topLeftCell,attr"`
-	ColorId                 int             `xml:"colorId,attr"`
-	ZoomScale               float64         `xml:"zoomScale,attr"`
-	ZoomScaleNormal         float64         `xml:"zoomScaleNormal,attr"`
-	ZoomScalePageLayoutView float64         `xml:"zoomScalePageLayoutView,attr"`
-	WorkbookViewId          int             `xml:"workbookViewId,attr"`
-	Selection               []xlsxSelection `xml:"selection"`
-	Pane                    *xlsxPane       `xml:"pane"`
+	WindowProtection         bool            `xml:"windowProtection,attr"`
+	ShowFormulas             bool            `xml:"showFormulas,attr"`
+	ShowGridLines            bool            `xml:"showGridLines,attr"`
+	ShowRowColHeaders        bool            `xml:"showRowColHeaders,attr"`
+	ShowZeros                bool            `xml:"showZeros,attr"`
+	RightToLeft              bool            `xml:"rightToLeft,attr"`
+	TabSelected              bool            `xml:"tabSelected,attr"`
+	ShowOutlineSymbols       bool            `xml:"showOutlineSymbols,attr"`
+	DefaultGridColor         bool            `xml:"defaultGridColor,attr"`
+	View                     string          `xml:"view,attr"`
+	TopLeftCell              string          `xml:"topLeftCell,attr"`
+	ColorId                  int             `xml:"colorId,attr"`
+	ZoomScale                float64         `xml:"zoomScale,attr"`
+	ZoomScaleNormal          float64         `xml:"zoomScaleNormal,attr"`
+	ZoomScalePageLayoutView  float64         `xml:"zoomScalePageLayoutView,attr"`
+	ZoomScaleSheetLayoutView float64         `xml:"zoomScaleSheetLayoutView,attr,omitempty"`
+	WorkbookViewId           int             `xml:"workbookViewId,attr"`
+	Selection                []xlsxSelection `xml:"selection"`
+	Pane                     *xlsxPane       `xml:"pane"`
+}
+
+// UnmarshalXML decodes a sheetView element, giving the attributes it
+// leaves out their default values rather than Go's zero values, so
+// that, for example, gridlines are shown unless showGridLines="0".
+func (v *xlsxSheetView) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type sheetView xlsxSheetView
+	view := sheetView{
+		ShowGridLines:      true,
+		ShowRowColHeaders:  true,
+		ShowZeros:          true,
+		ShowOutlineSymbols: true,
+		DefaultGridColor:   true,
+		View:               "normal",
+		ColorId:            64,
+		ZoomScale:          100,
+	}
+	if err := d.DecodeElement(&view, &start); err != nil {
+		return err
+	}
+	*v = xlsxSheetView(view)
+	return nil
 }
 
 // xlsxSelection directly maps the selection element in the namespace
@@ -183,6 +236,9 @@ type xlsxPane struct {
 // as I need.
 type xlsxSheetPr struct {
 	FilterMode  bool              `xml:"filterMode,attr"`
+	CodeName    string            `xml:"codeName,attr,omitempty"`
+	TabColor    *xlsxColor        `xml:"tabColor,omitempty"`
+	OutlinePr   *xlsxOutlinePr    `xml:"outlinePr,omitempty"`
 	PageSetUpPr []xlsxPageSetUpPr `xml:"pageSetUpPr"`
 }
 
@@ -192,6 +248,28 @@ type xlsxSheetPr struct {
 // as I need.
 type xlsxPageSetUpPr struct {
 	FitToPage int `xml:"fitToPage,attr"`
+	// invalid is a fitToPage read that isn't a boolean, which the
+	// sheet can only be read with when repairing it.
+	invalid string
+}
+
+// UnmarshalXML decodes a pageSetUpPr element, whose fitToPage is a
+// boolean that may be written "true" and "false" as well as "1" and
+// "0".
+func (p *xlsxPageSetUpPr) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	*p = xlsxPageSetUpPr{}
+	for _, attr := range start.Attr {
+		if attr.Name.Local != "fitToPage" {
+			continue
+		}
+		fit, err := strconv.ParseBool(attr.Value)
+		if err != nil {
+			p.invalid = attr.Value
+		} else if fit {
+			p.FitToPage = 1
+		}
+	}
+	return d.Skip()
 }
 
 // xlsxCols directly maps the cols element in the namespace
@@ -246,6 +324,12 @@ type xlsxRow struct {
 	Ht           string  `xml:"ht,attr,omitempty"`
 	CustomHeight bool    `xml:"customHeight,attr,omitempty"`
 	OutlineLevel uint8   `xml:"outlineLevel,attr,omitempty"`
+	S            int     `xml:"s,attr,omitempty"`
+	CustomFormat bool    `xml:"customFormat,attr,omitempty"`
+	Collapsed    bool    `xml:"collapsed,attr,omitempty"`
+	ThickTop     bool    `xml:"thickTop,attr,omitempty"`
+	ThickBot     bool    `xml:"thickBot,attr,omitempty"`
+	Ph           bool    `xml:"ph,attr,omitempty"`
 }
 
 type xlsxMergeCell struct {
@@ -286,11 +370,15 @@ func (mc *xlsxMergeCells) getExtent(cellRef string) (int, int, error) {
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxC struct {
-	R string `xml:"r,attr"`           // Cell ID, e.g. A1
-	S int    `xml:"s,attr,omitempty"` // Style reference.
-	T string `xml:"t,attr,omitempty"` // Type.
-	F *xlsxF `xml:"f,omitempty"`      // Formula
-	V string `xml:"v,omitempty"`      // Value
+	R  string  `xml:"r,attr"`            // Cell ID, e.g. A1
+	S  int     `xml:"s,attr,omitempty"`  // Style reference.
+	T  string  `xml:"t,attr,omitempty"`  // Type.
+	F  *xlsxF  `xml:"f,omitempty"`       // Formula
+	V  string  `xml:"v,omitempty"`       // Value
+	Is *xlsxSI `xml:"is,omitempty"`      // Inline string
+	Cm int     `xml:"cm,attr,omitempty"` // Cell metadata index.
+	Vm int     `xml:"vm,attr,omitempty"` // Value metadata index.
+	Ph bool    `xml:"ph,attr,omitempty"` // Show phonetic text.
 }
 
 // xlsxF directly maps the f element in the namespace
@@ -304,6 +392,31 @@ type xlsxF struct {
 	Si      int    `xml:"si,attr,omitempty"`  // Shared formula index
 }
 
+// MarshalXML writes the f element.  The si attribute can't simply be
+// omitempty, as 0 is a perfectly valid shared formula index, so it is
+// written whenever the formula is shared.
+func (f *xlsxF) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Attr = nil
+	if f.T != "" {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "t"}, Value: f.T})
+	}
+	if f.Ref != "" {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "ref"}, Value: f.Ref})
+	}
+	if f.T == "shared" || f.Si != 0 {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "si"}, Value: strconv.Itoa(f.Si)})
+	}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if f.Content != "" {
+		if err := e.EncodeToken(xml.CharData(f.Content)); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
 type xlsxWorksheetDrawing struct {
 	Id int `xml:"r:id,attr"`
 }
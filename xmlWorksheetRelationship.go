@@ -2,7 +2,6 @@ package xlsx
 
 import (
 	"encoding/xml"
-	"fmt"
 )
 
 type xlsxWorksheetRelationships struct {
@@ -11,10 +10,11 @@ type xlsxWorksheetRelationships struct {
 }
 
 type xlsxWorksheetRelationship struct {
-	XMLName xml.Name `xml:"Relationship"`
-	Id      string   `xml:",attr"`
-	Type    string   `xml:",attr"`
-	Target  string   `xml:",attr"`
+	XMLName    xml.Name `xml:"Relationship"`
+	Id         string   `xml:",attr"`
+	Type       string   `xml:",attr"`
+	Target     string   `xml:",attr"`
+	TargetMode string   `xml:",attr,omitempty"`
 }
 
 func newXlsxWorksheetRelationships() *xlsxWorksheetRelationships {
@@ -22,12 +22,3 @@ func newXlsxWorksheetRelationships() *xlsxWorksheetRelationships {
 	relationships.Relationships = make([]*xlsxWorksheetRelationship, 0)
 	return relationships
 }
-
-func (relationships *xlsxWorksheetRelationships) AddWorksheetDrawingRelationship(drawingXML string) string {
-	relationship := new(xlsxWorksheetRelationship)
-	relationship.Id = fmt.Sprintf("rId%d", len(relationships.Relationships)+1)
-	relationship.Type = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/drawing"
-	relationship.Target = fmt.Sprintf("../drawings/%s", drawingXML)
-	relationships.Relationships = append(relationships.Relationships, relationship)
-	return relationship.Id
-}
@@ -0,0 +1,258 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The XML declaration written at the start of each part.
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+
+// xmlWriter writes XML by hand, escaping text as encoding/xml does,
+// for the parts of a file that can be too big to marshal quickly.  The
+// first error it meets is kept, and it writes nothing after it.
+type xmlWriter struct {
+	w   io.Writer
+	buf []byte
+	err error
+}
+
+// The size of the buffer an xmlWriter fills before writing it out.
+const xmlWriterBufferSize = 64 * 1024
+
+func newXMLWriter(w io.Writer) *xmlWriter {
+	return &xmlWriter{w: w, buf: make([]byte, 0, xmlWriterBufferSize+1024)}
+}
+
+// raw writes s as it is.
+func (x *xmlWriter) raw(s string) {
+	x.buf = append(x.buf, s...)
+	if len(x.buf) >= xmlWriterBufferSize {
+		x.writeOut()
+	}
+}
+
+// text writes s escaped as character data or an attribute value.
+func (x *xmlWriter) text(s string) {
+	if !needsEscaping(s) {
+		x.raw(s)
+		return
+	}
+	escaped := bytes.NewBuffer(x.buf)
+	xml.EscapeText(escaped, []byte(s))
+	x.buf = escaped.Bytes()
+	if len(x.buf) >= xmlWriterBufferSize {
+		x.writeOut()
+	}
+}
+
+// int writes n in decimal.
+func (x *xmlWriter) int(n int) {
+	x.buf = strconv.AppendInt(x.buf, int64(n), 10)
+}
+
+// writeOut writes the buffer to the underlying writer and empties it.
+func (x *xmlWriter) writeOut() {
+	if x.err == nil {
+		_, x.err = x.w.Write(x.buf)
+	}
+	x.buf = x.buf[:0]
+}
+
+// attr writes the attribute name="value".
+func (x *xmlWriter) attr(name, value string) {
+	x.raw(" ")
+	x.raw(name)
+	x.raw(`="`)
+	x.text(value)
+	x.raw(`"`)
+}
+
+// intAttr writes the attribute name="n".
+func (x *xmlWriter) intAttr(name string, n int) {
+	x.raw(" ")
+	x.raw(name)
+	x.raw(`="`)
+	x.int(n)
+	x.raw(`"`)
+}
+
+// element writes <name>text</name>.
+func (x *xmlWriter) element(name, text string) {
+	x.raw("<")
+	x.raw(name)
+	x.raw(">")
+	x.text(text)
+	x.raw("</")
+	x.raw(name)
+	x.raw(">")
+}
+
+// flush writes out whatever is buffered, returning the first error.
+func (x *xmlWriter) flush() error {
+	x.writeOut()
+	return x.err
+}
+
+// needsEscaping reports whether s has characters that xml.EscapeText
+// would change, so that the common case can be written directly.
+func needsEscaping(s string) bool {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"', c == '\'', c == '&', c == '<', c == '>', c < 0x20, c >= 0x80:
+			return true
+		}
+	}
+	return false
+}
+
+// writeWorksheet writes the worksheet part, exactly as marshalling it
+// with encoding/xml would, except that the rows and cells of its
+// sheetData, which are nearly all of a big worksheet, are written by
+// hand, which is many times quicker and makes far less garbage.
+func writeWorksheet(w io.Writer, worksheet *xlsxWorksheet) error {
+	rows := worksheet.SheetData.Row
+	worksheet.SheetData.Row = nil
+	body, err := xml.Marshal(worksheet)
+	worksheet.SheetData.Row = rows
+	if err != nil {
+		return err
+	}
+	marshalled := replaceWorksheetNameSpace(string(body))
+	const empty = "<sheetData></sheetData>"
+	split := strings.Index(marshalled, empty)
+	if split < 0 {
+		return fmt.Errorf("worksheet has no sheetData element")
+	}
+	x := newXMLWriter(w)
+	x.raw(xmlHeader)
+	x.raw(marshalled[:split])
+	x.raw("<sheetData>")
+	for i := range rows {
+		x.row(&rows[i])
+	}
+	x.raw("</sheetData>")
+	x.raw(marshalled[split+len(empty):])
+	return x.flush()
+}
+
+// row writes a row element of sheetData.
+func (x *xmlWriter) row(row *xlsxRow) {
+	x.raw("<row")
+	x.intAttr("r", row.R)
+	if row.Spans != "" {
+		x.attr("spans", row.Spans)
+	}
+	if row.Hidden {
+		x.raw(` hidden="true"`)
+	}
+	if row.Ht != "" {
+		x.attr("ht", row.Ht)
+	}
+	if row.CustomHeight {
+		x.raw(` customHeight="true"`)
+	}
+	if row.OutlineLevel != 0 {
+		x.intAttr("outlineLevel", int(row.OutlineLevel))
+	}
+	if row.S != 0 {
+		x.intAttr("s", row.S)
+	}
+	if row.CustomFormat {
+		x.raw(` customFormat="true"`)
+	}
+	if row.Collapsed {
+		x.raw(` collapsed="true"`)
+	}
+	if row.ThickTop {
+		x.raw(` thickTop="true"`)
+	}
+	if row.ThickBot {
+		x.raw(` thickBot="true"`)
+	}
+	if row.Ph {
+		x.raw(` ph="true"`)
+	}
+	x.raw(">")
+	for i := range row.C {
+		x.cell(&row.C[i])
+	}
+	x.raw("</row>")
+}
+
+// cell writes a c element of a row.
+func (x *xmlWriter) cell(c *xlsxC) {
+	x.raw("<c")
+	x.attr("r", c.R)
+	if c.S != 0 {
+		x.intAttr("s", c.S)
+	}
+	if c.T != "" {
+		x.attr("t", c.T)
+	}
+	if c.Cm != 0 {
+		x.intAttr("cm", c.Cm)
+	}
+	if c.Vm != 0 {
+		x.intAttr("vm", c.Vm)
+	}
+	if c.Ph {
+		x.raw(` ph="true"`)
+	}
+	x.raw(">")
+	if c.F != nil {
+		x.raw("<f")
+		if c.F.T != "" {
+			x.attr("t", c.F.T)
+		}
+		if c.F.Ref != "" {
+			x.attr("ref", c.F.Ref)
+		}
+		if c.F.T == "shared" || c.F.Si != 0 {
+			x.intAttr("si", c.F.Si)
+		}
+		x.raw(">")
+		x.text(c.F.Content)
+		x.raw("</f>")
+	}
+	if c.V != "" {
+		x.element("v", c.V)
+	}
+	if c.Is != nil {
+		x.raw("<is>")
+		x.element("t", c.Is.T)
+		for _, r := range c.Is.R {
+			x.raw("<r>")
+			x.element("t", r.T)
+			x.raw("</r>")
+		}
+		x.phonetic(c.Is.RPh, c.Is.PhoneticPr)
+		x.raw("</is>")
+	}
+	x.raw("</c>")
+}
+
+// writeSharedStrings writes the shared string table part straight from
+// the RefTable, exactly as marshalling its xlsxSST would.
+func writeSharedStrings(w io.Writer, rt *RefTable) error {
+	x := newXMLWriter(w)
+	x.raw(xmlHeader)
+	x.raw(`<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"`)
+	x.intAttr("count", len(rt.indexedStrings))
+	x.intAttr("uniqueCount", len(rt.indexedStrings))
+	x.raw(">")
+	for i, str := range rt.indexedStrings {
+		x.raw("<si>")
+		x.element("t", str)
+		if p := rt.phonetics[i]; p != nil {
+			x.phonetic(p.xlsx())
+		}
+		x.raw("</si>")
+	}
+	x.raw("</sst>")
+	return x.flush()
+}
@@ -0,0 +1,111 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+type XMLWriterSuite struct{}
+
+var _ = Suite(&XMLWriterSuite{})
+
+// marshalWorksheet marshals the worksheet with encoding/xml, as the
+// worksheet part used to be made.
+func marshalWorksheet(c *C, worksheet *xlsxWorksheet) string {
+	body, err := xml.Marshal(worksheet)
+	c.Assert(err, IsNil)
+	return xmlHeader + replaceWorksheetNameSpace(string(body))
+}
+
+func (s *XMLWriterSuite) TestWriteWorksheetMatchesMarshal(c *C) {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	row := sheet.AddRow()
+	row.SetHeight(20)
+	row.OutlineLevel = 2
+	row.Hidden = true
+	row.SetStyle(NewStyle())
+	row.AddCell().SetString(`a "quoted" <tag> & 'apostrophe'` + "\ttab\nnewline\r\x01")
+	row.AddCell().SetFormula(`IF(A1="x",1,2)`)
+	row.AddCell().SetBool(true)
+	row.AddCell().SetFloat(1.5)
+	row.AddCell().SetString("")
+	second := sheet.AddRow()
+	for i := 0; i < 3; i++ {
+		second.AddCell().SetFormula("A1+1")
+	}
+	sheet.AddRow()
+
+	worksheet := sheet.makeXLSXSheet(NewSharedStringRefTable(), newXlsxStyleSheet(nil))
+	worksheet.SheetData.Row[2].C = append(worksheet.SheetData.Row[2].C, xlsxC{
+		R: "A3", T: "inlineStr",
+		Is: &xlsxSI{T: "plain", R: []xlsxR{{T: "rich"}, {T: "text & more"}}},
+	}, xlsxC{
+		R: "B3", T: "inlineStr", Ph: true,
+		Is: &xlsxSI{T: "東京", RPh: []xlsxPhoneticRun{{Sb: 0, Eb: 2, T: "トウキョウ"}}, PhoneticPr: &xlsxPhoneticPr{Type: "Hiragana"}},
+	})
+	var written strings.Builder
+	c.Assert(writeWorksheet(&written, worksheet), IsNil)
+	c.Assert(written.String(), Equals, marshalWorksheet(c, worksheet))
+	c.Assert(worksheet.SheetData.Row, HasLen, 3)
+}
+
+func (s *XMLWriterSuite) TestWriteSharedStringsMatchesMarshal(c *C) {
+	refTable := NewSharedStringRefTable()
+	refTable.isWrite = true
+	for _, str := range []string{"Foo", "", "<b>&amp;</b>", "日本語", "Foo"} {
+		refTable.AddString(str)
+	}
+	refTable.addPhoneticString("東京", &Phonetic{Runs: []PhoneticRun{{0, 1, "トウ"}, {1, 2, "キョウ"}}, Alignment: "center"})
+	var written strings.Builder
+	c.Assert(writeSharedStrings(&written, refTable), IsNil)
+	body, err := xml.Marshal(refTable.makeXLSXSST())
+	c.Assert(err, IsNil)
+	c.Assert(written.String(), Equals, xmlHeader+string(body))
+
+	var empty strings.Builder
+	c.Assert(writeSharedStrings(&empty, NewSharedStringRefTable()), IsNil)
+	c.Assert(empty.String(), Equals, xmlHeader+`<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="0" uniqueCount="0"></sst>`)
+}
+
+func makeBenchmarkWorksheet() *xlsxWorksheet {
+	file := NewFile()
+	sheet, _ := file.AddSheet("Sheet1")
+	for r := 0; r < 2000; r++ {
+		row := sheet.AddRow()
+		for col := 0; col < 10; col++ {
+			if col%2 == 0 {
+				row.AddCell().SetInt(r * col)
+			} else {
+				row.AddCell().SetString("text")
+			}
+		}
+	}
+	return sheet.makeXLSXSheet(NewSharedStringRefTable(), newXlsxStyleSheet(nil))
+}
+
+func BenchmarkWriteWorksheet(b *testing.B) {
+	worksheet := makeBenchmarkWorksheet()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := writeWorksheet(ioutil.Discard, worksheet); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshalWorksheet(b *testing.B) {
+	worksheet := makeBenchmarkWorksheet()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		body, err := xml.Marshal(worksheet)
+		if err != nil {
+			b.Fatal(err)
+		}
+		_ = replaceWorksheetNameSpace(string(body))
+	}
+}